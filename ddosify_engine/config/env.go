@@ -0,0 +1,78 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// envRegexp matches ${VAR} and ${VAR:-default} references, $${VAR} escapes a literal ${VAR}.
+var envRegexp = regexp.MustCompile(`\$(\$?)\{([a-zA-Z_][a-zA-Z0-9_]*)(:-[^}]*)?\}`)
+
+// InterpolateEnv replaces the ${VAR} references in the config by the values of lookup, ${VAR:-default} uses
+// the default if the variable is unset or empty. The values are escaped for json strings, so they can be used
+// both inside strings like "${BASE_URL}/login" and as numbers like "duration": ${DURATION}.
+// Returns an error listing the variables that are unset and have no default.
+func InterpolateEnv(data []byte, lookup func(string) (string, bool)) ([]byte, error) {
+	var missing []string
+	seen := map[string]bool{}
+
+	res := envRegexp.ReplaceAllFunc(data, func(m []byte) []byte {
+		sub := envRegexp.FindSubmatch(m)
+		if len(sub[1]) > 0 {
+			return m[1:]
+		}
+
+		name := string(sub[2])
+		val, ok := lookup(name)
+		if len(sub[3]) > 0 {
+			if val == "" {
+				val = string(sub[3][2:])
+			}
+		} else if !ok {
+			if !seen[name] {
+				seen[name] = true
+				missing = append(missing, name)
+			}
+			return m
+		}
+		return escapeJsonString(val)
+	})
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing environment variables: %s", strings.Join(missing, ", "))
+	}
+	return res, nil
+}
+
+// escapeJsonString escapes the value to be used inside a json string, without the quotes.
+func escapeJsonString(val string) []byte {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(val)
+	b := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	return b[1 : len(b)-1]
+}
@@ -0,0 +1,72 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import "testing"
+
+func TestInterpolateEnv(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{"BASE_URL": "https://test.com", "API_KEY": `k"e\y`, "DURATION": "20", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		val, ok := env[name]
+		return val, ok
+	}
+
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{"String", `{"url": "${BASE_URL}/login"}`, `{"url": "https://test.com/login"}`},
+		{"Escaped", `{"key": "${API_KEY}"}`, `{"key": "k\"e\\y"}`},
+		{"Number", `{"duration": ${DURATION}}`, `{"duration": 20}`},
+		{"Default", `{"user": "${USERNAME:-admin}", "pass": "${EMPTY:-secret}"}`,
+			`{"user": "admin", "pass": "secret"}`},
+		{"EmptyDefault", `{"user": "${USERNAME:-}"}`, `{"user": ""}`},
+		{"DefaultNotUsed", `{"duration": ${DURATION:-10}}`, `{"duration": 20}`},
+		{"EmptyValue", `{"a": "${EMPTY}"}`, `{"a": ""}`},
+		{"Literal", `{"a": "$${BASE_URL}", "b": "{{$BASE_URL}}"}`, `{"a": "${BASE_URL}", "b": "{{$BASE_URL}}"}`},
+	}
+
+	for _, test := range tests {
+		res, err := InterpolateEnv([]byte(test.config), lookup)
+		if err != nil {
+			t.Errorf("%s error occurred: %v", test.name, err)
+		}
+		if string(res) != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expected, string(res))
+		}
+	}
+}
+
+func TestInterpolateEnvMissing(t *testing.T) {
+	t.Parallel()
+
+	lookup := func(name string) (string, bool) { return "", false }
+	_, err := InterpolateEnv([]byte(`{"url": "${BASE_URL}", "key": "${API_KEY}", "u": "${BASE_URL}", "a": "${A:-b}"}`),
+		lookup)
+
+	expected := "missing environment variables: BASE_URL, API_KEY"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %v, Found: %v", expected, err)
+	}
+}
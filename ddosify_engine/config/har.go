@@ -0,0 +1,151 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// Content types of the static assets, requests of them are skipped if ImportOpts.SkipStatic is set.
+var staticContentTypes = []string{
+	"image/", "font/", "audio/", "video/", "text/css",
+	"application/javascript", "text/javascript", "application/x-javascript",
+	"application/font-woff", "application/font-woff2", "application/vnd.ms-fontobject",
+}
+
+type har struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		PostData *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func importHar(data []byte, opts ImportOpts) (s types.Scenario, err error) {
+	var h har
+	if err = json.Unmarshal(data, &h); err != nil {
+		return s, fmt.Errorf("har file is not valid: %v", err)
+	}
+
+	for _, e := range h.Log.Entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue // data urls, websockets, browser extensions etc.
+		}
+		if opts.SkipStatic && isStaticContent(e.Response.Content.MimeType) {
+			continue
+		}
+		if len(s.Steps) == math.MaxUint16 {
+			return s, fmt.Errorf("har file has more than %d requests", math.MaxUint16)
+		}
+
+		id := uint16(len(s.Steps) + 1)
+		step := types.ScenarioStep{
+			ID:      id,
+			Name:    fmt.Sprintf("%s %s", strings.ToUpper(e.Request.Method), u.Path),
+			Method:  strings.ToUpper(e.Request.Method),
+			URL:     e.Request.URL,
+			Headers: harHeaders(e.Request.Headers),
+			Timeout: types.DefaultTimeout,
+		}
+		if p := e.Request.PostData; p != nil {
+			step.Payload = p.Text
+			if p.Text == "" && len(p.Params) > 0 {
+				form := url.Values{}
+				for _, param := range p.Params {
+					form.Add(param.Name, param.Value)
+				}
+				step.Payload = form.Encode()
+			}
+			if _, ok := step.Headers["Content-Type"]; !ok && p.MimeType != "" {
+				step.Headers["Content-Type"] = p.MimeType
+			}
+		}
+		s.Steps = append(s.Steps, step)
+	}
+
+	if len(s.Steps) == 0 {
+		return s, fmt.Errorf("har file has no http requests to replay")
+	}
+	return s, nil
+}
+
+// harHeaders returns the recorded request headers, excluding the ones set by the client itself.
+func harHeaders(headers []harNameValue) map[string]string {
+	hs := make(map[string]string, len(headers))
+	for _, h := range headers {
+		if strings.HasPrefix(h.Name, ":") { // http2 pseudo headers
+			continue
+		}
+		name := http.CanonicalHeaderKey(h.Name)
+		if name == "Content-Length" || name == "Host" || name == "Connection" {
+			continue
+		}
+		if v, ok := hs[name]; ok {
+			sep := ", "
+			if name == "Cookie" {
+				sep = "; "
+			}
+			hs[name] = v + sep + h.Value
+		} else {
+			hs[name] = h.Value
+		}
+	}
+	return hs
+}
+
+func isStaticContent(mimeType string) bool {
+	mimeType = strings.ToLower(mimeType)
+	for _, t := range staticContentTypes {
+		if strings.HasPrefix(mimeType, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestImportHar(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("config_testdata/session.har")
+	if err != nil {
+		t.Fatalf("TestImportHar error occurred: %v", err)
+	}
+
+	login := types.ScenarioStep{
+		ID:      1,
+		Name:    "GET /accounts/login/",
+		Method:  "GET",
+		URL:     "https://app.servdown.com/accounts/login/?next=/",
+		Headers: map[string]string{"Accept": "text/html", "Cookie": "a=1; b=2"},
+		Timeout: types.DefaultTimeout,
+	}
+	css := types.ScenarioStep{
+		ID:      2,
+		Name:    "GET /static/app.css",
+		Method:  "GET",
+		URL:     "https://app.servdown.com/static/app.css",
+		Headers: map[string]string{"Accept": "text/css"},
+		Timeout: types.DefaultTimeout,
+	}
+	form := types.ScenarioStep{
+		ID:      3,
+		Name:    "POST /accounts/login/",
+		Method:  "POST",
+		URL:     "https://app.servdown.com/accounts/login/",
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Payload: "password=123&username=test",
+		Timeout: types.DefaultTimeout,
+	}
+	profile := types.ScenarioStep{
+		ID:      4,
+		Name:    "PUT /api/profile",
+		Method:  "PUT",
+		URL:     "https://app.servdown.com/api/profile",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Payload: `{"name": "test"}`,
+		Timeout: types.DefaultTimeout,
+	}
+
+	s, err := Import(data, ImportTypeHar, ImportOpts{})
+	if err != nil {
+		t.Fatalf("TestImportHar error occurred: %v", err)
+	}
+	expected := []types.ScenarioStep{login, css, form, profile}
+	if !reflect.DeepEqual(s.Steps, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, s.Steps)
+	}
+
+	// static assets skipped
+	s, err = Import(data, "HAR", ImportOpts{SkipStatic: true})
+	if err != nil {
+		t.Fatalf("TestImportHar error occurred: %v", err)
+	}
+	form.ID, profile.ID = 2, 3
+	expected = []types.ScenarioStep{login, form, profile}
+	if !reflect.DeepEqual(s.Steps, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, s.Steps)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		data       string
+		importType string
+	}{
+		{"UnsupportedType", `{}`, "postman"},
+		{"InvalidJson", `{"log": `, ImportTypeHar},
+		{"NoEntries", `{"log": {"entries": []}}`, ImportTypeHar},
+		{"NoHttpEntries", `{"log": {"entries": [{"request": {"method": "GET", "url": "wss://test.com"}}]}}`, ImportTypeHar},
+	}
+
+	for _, test := range tests {
+		if _, err := Import([]byte(test.data), test.importType, ImportOpts{}); err == nil {
+			t.Errorf("%s Expected %v, Found: %v", test.name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+const (
+	ImportTypeHar     = "har"
+	ImportTypePostman = "postman"
+)
+
+var SupportedImportTypes = [...]string{ImportTypeHar, ImportTypePostman}
+
+type ImportOpts struct {
+	// Skips the requests of the static assets like images, scripts and stylesheets, by the response content type.
+	// Only the recorded traffic has the responses, ignored by the postman imports.
+	SkipStatic bool
+
+	// Content of the Postman environment file, its variables are added to the environment variables of the scenario.
+	PostmanEnvironment []byte
+}
+
+// Import creates a scenario from the recorded traffic or the request collection in the given import type,
+// requests are sent in order.
+func Import(data []byte, importType string, opts ImportOpts) (types.Scenario, error) {
+	switch strings.ToLower(importType) {
+	case ImportTypeHar:
+		return importHar(data, opts)
+	case ImportTypePostman:
+		return importPostman(data, opts.PostmanEnvironment)
+	}
+	return types.Scenario{}, fmt.Errorf("unsupported import type: %s, supported types are %v",
+		importType, SupportedImportTypes)
+}
@@ -0,0 +1,200 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeKey references the json files composed into a config, like {"$include": "common/headers.json"}.
+// The value is a file path or a list of them, relative to the directory of the including file.
+// The object is replaced by the content of the files, other keys of the object override the included keys.
+const IncludeKey = "$include"
+
+// ReadConfigFile reads the json config at the given path, interpolates the environment variables and resolves
+// its includes, so the returned config is self-contained. Returns an error on a missing environment variable
+// or a cyclic include.
+func ReadConfigFile(path string) ([]byte, error) {
+	data, err := readConfigData(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(data, []byte(`"`+IncludeKey+`"`)) {
+		return data, nil
+	}
+
+	v, err := readIncluded(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// readConfigData reads the file at path and interpolates the environment variables in it.
+func readConfigData(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err = InterpolateEnv(data, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return data, nil
+}
+
+// readIncluded reads the json file at path, stack is the chain of the files including it.
+func readIncluded(path string, stack []string) (interface{}, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("cyclic include: %s", strings.Join(append(stack[i:], abs), " -> "))
+		}
+	}
+
+	data, err := readConfigData(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("provided json is invalid: %s", path)
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep the large integers like the seed as they are
+	if err = dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return resolveIncludes(v, filepath.Dir(abs), append(stack, abs))
+}
+
+func resolveIncludes(v interface{}, dir string, stack []string) (interface{}, error) {
+	switch val := v.(type) {
+	case []interface{}:
+		for i, item := range val {
+			resolved, err := resolveIncludes(item, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = resolved
+		}
+		return val, nil
+	case map[string]interface{}:
+		for k, item := range val {
+			resolved, err := resolveIncludes(item, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = resolved
+		}
+
+		ref, ok := val[IncludeKey]
+		if !ok {
+			return val, nil
+		}
+		delete(val, IncludeKey)
+		paths, err := includePaths(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		var included interface{}
+		for _, p := range paths {
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+			inc, err := readIncluded(p, stack)
+			if err != nil {
+				return nil, err
+			}
+			if included, err = mergeIncluded(included, inc, p); err != nil {
+				return nil, err
+			}
+		}
+		if len(val) == 0 {
+			return included, nil
+		}
+		return mergeIncluded(included, val, "")
+	default:
+		return v, nil
+	}
+}
+
+// includePaths returns the file paths of the $include value, a path or a list of paths.
+func includePaths(ref interface{}) ([]string, error) {
+	switch r := ref.(type) {
+	case string:
+		return []string{r}, nil
+	case []interface{}:
+		paths := make([]string, 0, len(r))
+		for _, p := range r {
+			s, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s should be a file path or a list of file paths", IncludeKey)
+			}
+			paths = append(paths, s)
+		}
+		return paths, nil
+	default:
+		return nil, fmt.Errorf("%s should be a file path or a list of file paths", IncludeKey)
+	}
+}
+
+// mergeIncluded merges the override onto the base, only json objects can be merged.
+// path is the file of the override for the error message, empty if the override is the including object.
+func mergeIncluded(base, override interface{}, path string) (interface{}, error) {
+	if base == nil {
+		return override, nil
+	}
+	b, bOk := base.(map[string]interface{})
+	o, oOk := override.(map[string]interface{})
+	if !bOk || !oOk {
+		if path != "" {
+			return nil, fmt.Errorf("included %s can't be merged, only json objects can be merged", path)
+		}
+		return nil, fmt.Errorf("included file can't be merged with the keys next to the %s, it is not a json object", IncludeKey)
+	}
+	return mergeObjects(b, o), nil
+}
+
+// mergeObjects merges the nested objects recursively, other values of the override replace the base values.
+func mergeObjects(base, override map[string]interface{}) map[string]interface{} {
+	for k, v := range override {
+		bv, bOk := base[k].(map[string]interface{})
+		ov, oOk := v.(map[string]interface{})
+		if bOk && oOk {
+			base[k] = mergeObjects(bv, ov)
+		} else {
+			base[k] = v
+		}
+	}
+	return base
+}
@@ -0,0 +1,125 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestReadConfigFileIncludes(t *testing.T) {
+	t.Parallel()
+
+	dir := writeConfigFiles(t, map[string]string{
+		"main.json": `{"$include": "common/base.json", "duration": 30, "seed": 9007199254740993,
+			"steps": [{"id": 1, "url": "https://test.com", "headers": {"$include": ["common/headers.json", "common/auth.json"],
+			"X-Step": "1"}, "auth": {"$include": "common/basic.json", "password": "override"}}]}`,
+		"common/base.json":    `{"iteration_count": 100, "duration": 10, "global_headers": {"$include": "headers.json"}}`,
+		"common/headers.json": `{"Accept": "application/json", "X-Common": "a"}`,
+		"common/auth.json":    `{"Authorization": "Bearer token", "X-Common": "b"}`,
+		"common/basic.json":   `{"type": "basic", "username": "user", "password": "pass"}`,
+	})
+
+	data, err := ReadConfigFile(filepath.Join(dir, "main.json"))
+	if err != nil {
+		t.Fatalf("TestReadConfigFileIncludes error occurred: %v", err)
+	}
+	jsonReader, err := NewConfigReader(data, ConfigTypeJson)
+	if err != nil {
+		t.Fatalf("TestReadConfigFileIncludes error occurred: %v", err)
+	}
+	h, err := jsonReader.CreateHammer()
+	if err != nil {
+		t.Fatalf("TestReadConfigFileIncludes error occurred: %v", err)
+	}
+
+	if h.IterationCount != 100 || h.TestDuration != 30 || h.Seed != 9007199254740993 {
+		t.Errorf("Expected %v, Found: %v", []int64{100, 30, 9007199254740993},
+			[]int64{int64(h.IterationCount), int64(h.TestDuration), h.Seed})
+	}
+	expectedHeaders := map[string]string{
+		"Accept": "application/json", "X-Common": "b", "Authorization": "Bearer token", "X-Step": "1",
+	}
+	if !reflect.DeepEqual(h.Scenario.Steps[0].Headers, expectedHeaders) {
+		t.Errorf("Expected %v, Found: %v", expectedHeaders, h.Scenario.Steps[0].Headers)
+	}
+	if a := h.Scenario.Steps[0].Auth; a.Username != "user" || a.Password != "override" {
+		t.Errorf("Expected %v, Found: %v", "user:override", a.Username+":"+a.Password)
+	}
+}
+
+func TestReadConfigFileWithoutIncludes(t *testing.T) {
+	t.Parallel()
+
+	config := `{"iteration_count": 5,  "steps": [{"id": 1, "url": "https://test.com"}]}`
+	dir := writeConfigFiles(t, map[string]string{"main.json": config})
+
+	data, err := ReadConfigFile(filepath.Join(dir, "main.json"))
+	if err != nil || string(data) != config {
+		t.Errorf("Expected %v, Found: %v %v", config, string(data), err)
+	}
+}
+
+func TestReadConfigFileIncludeErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{"Cyclic", map[string]string{
+			"main.json": `{"$include": "a.json"}`,
+			"a.json":    `{"steps": {"$include": "b.json"}}`,
+			"b.json":    `{"$include": "a.json"}`,
+		}, "cyclic include: "},
+		{"SelfInclude", map[string]string{"main.json": `{"$include": "main.json"}`}, "cyclic include: "},
+		{"Missing", map[string]string{"main.json": `{"$include": "missing.json"}`}, "missing.json"},
+		{"InvalidJson", map[string]string{"main.json": `{"$include": "a.json"}`, "a.json": `{"a": `},
+			"provided json is invalid"},
+		{"InvalidPath", map[string]string{"main.json": `{"$include": 1}`}, "should be a file path"},
+		{"MergeArray", map[string]string{"main.json": `{"$include": "a.json", "duration": 1}`, "a.json": `[1]`},
+			"not a json object"},
+	}
+
+	for _, test := range tests {
+		dir := writeConfigFiles(t, test.files)
+		_, err := ReadConfigFile(filepath.Join(dir, "main.json"))
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expected, err)
+		}
+	}
+}
@@ -26,13 +26,16 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 	"unsafe"
 
 	"go.ddosify.com/ddosify/core/proxy"
@@ -54,18 +57,109 @@ type auth struct {
 	Type     string `json:"type"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	User     string `json:"user"` // short for username
+	Pass     string `json:"pass"` // short for password
+
+	// bearer
+	Token string `json:"token"`
+
+	// oauth2_cc
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// authNone is the auth type of the steps that don't use the global auth.
+const authNone = "none"
+
+func (a auth) empty() bool {
+	return a.Type == "" && a.Username == "" && a.Password == "" && a.User == "" && a.Pass == "" && a.Token == "" &&
+		a.TokenURL == "" && a.ClientID == "" && a.ClientSecret == "" && len(a.Scopes) == 0
+}
+
+type grpcConf struct {
+	Method       string `json:"method"`
+	ProtoSet     string `json:"proto_set"`
+	StreamCount  int    `json:"stream_count"`
+	MessageCount int    `json:"message_count"`
+	SendInterval int    `json:"send_interval"`
+}
+
+type graphqlConf struct {
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables"`
+	OperationName string          `json:"operation_name"`
+}
+
+// variables returns the variables object of the operation. It can be given as a JSON string too,
+// for the environment variables that are not quoted like {"id": {{userId}}}.
+func (g graphqlConf) variables() string {
+	var s string
+	if err := json.Unmarshal(g.Variables, &s); err == nil {
+		return s
+	}
+	if string(g.Variables) == "null" {
+		return ""
+	}
+	return string(g.Variables)
+}
+
+type webSocketConf struct {
+	MessageCount int `json:"message_count"`
+	ReadDuration int `json:"read_duration"`
+}
+
+type sseConf struct {
+	EventCount   int `json:"event_count"`
+	ReadDuration int `json:"read_duration"`
+}
+
+type socketConf struct {
+	Hex       bool   `json:"hex"`
+	ReadBytes int    `json:"read_bytes"`
+	WriteOnly bool   `json:"write_only"`
+	Match     string `json:"match"`
+}
+
+type dnsConf struct {
+	Name        string `json:"name"`
+	QType       string `json:"type"`
+	Transport   string `json:"transport"`
+	NoRecursion bool   `json:"no_recursion"`
+}
+
+type tlsConf struct {
+	InsecureSkipVerify *bool  `json:"insecure_skip_verify"` // default true
+	CertPath           string `json:"cert_path"`
+	KeyPath            string `json:"key_path"`
+	CAPath             string `json:"ca_path"`
+	MinVersion         string `json:"min_version"`
+}
+
+type retryConf struct {
+	MaxAttempts int      `json:"max_attempts"`
+	StatusCodes []int    `json:"status_codes"`
+	Errors      []string `json:"errors"`
+	Backoff     string   `json:"backoff"`   // fixed, exponential
+	Delay       int      `json:"delay"`     // in ms
+	MaxDelay    int      `json:"max_delay"` // in ms
+	Jitter      bool     `json:"jitter"`
 }
 
 type multipartFormData struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
-	Type  string `json:"type"`
-	Src   string `json:"src"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Type     string `json:"type"`
+	Src      string `json:"src"`      // local, remote or inline for the files
+	FileName string `json:"filename"` // file name sent for the file, base of the value by default
+	Template bool   `json:"template"` // variables are injected into the part of the streamed bodies
 }
 
 type RegexCaptureConf struct {
-	Exp *string `json:"exp"`
-	No  int     `json:"matchNo"`
+	Exp   *string `json:"exp"`
+	No    int     `json:"matchNo"`
+	Fatal bool    `json:"fatal"`
 }
 type capturePath struct {
 	JsonPath   *string           `json:"json_path"`
@@ -83,24 +177,83 @@ type step struct {
 	Url              string                 `json:"url"`
 	Auth             auth                   `json:"auth"`
 	Method           string                 `json:"method"`
-	Headers          map[string]string      `json:"headers"`
+	Headers          stepHeaders            `json:"headers"`
 	Payload          string                 `json:"payload"`
 	PayloadFile      string                 `json:"payload_file"`
 	PayloadMultipart []multipartFormData    `json:"payload_multipart"`
-	Timeout          int                    `json:"timeout"`
-	Sleep            string                 `json:"sleep"`
+	MultipartStream  bool                   `json:"payload_multipart_stream"` // build the body per request, streaming the files
+	Timeout          jsonDuration           `json:"timeout"`
+	DialTimeout      jsonDuration           `json:"dial_timeout"`
+	TLSTimeout       jsonDuration           `json:"tls_handshake_timeout"`
+	Sleep            sleepConf              `json:"sleep"`
 	Others           map[string]interface{} `json:"others"`
 	CertPath         string                 `json:"cert_path"`
 	CertKeyPath      string                 `json:"cert_key_path"`
 	CaptureEnv       map[string]capturePath `json:"capture_env"`
 	Assertions       []string               `json:"assertion"`
+	Type             string                 `json:"type"`
+	Grpc             grpcConf               `json:"grpc"`
+	WebSocket        webSocketConf          `json:"websocket"`
+	SSE              sseConf                `json:"sse"`
+	Socket           socketConf             `json:"socket"`
+	DNS              dnsConf                `json:"dns"`
+	GraphQL          graphqlConf            `json:"graphql"`
+	Protocol         string                 `json:"protocol"`
+	TLS              *tlsConf               `json:"tls"`
+	Retry            retryConf              `json:"retry"`
+	MaxResponseBody  *int64                 `json:"max_response_body_bytes"` // overrides the global one
+	If               string                 `json:"if"`                      // condition of sending the step
+	DependsOn        []uint16               `json:"depends_on"`              // ids of the steps done before the step
+	Parallel         int                    `json:"parallel"`                // copies of the step sent concurrently
+	ResponseSchema   string                 `json:"response_schema"`         // json schema file of the responses
+	ExpectedStatus   expectedStatus         `json:"expected_status"`         // status codes of the successful responses
+	Tags             []string               `json:"tags"`
+	Redirect         redirectConf           `json:"redirect"`
+	ReqCompression   string                 `json:"request_compression"`
+	ReqFallback      *bool                  `json:"request_compression_fallback"` // overrides the global one
+	ChunkedBody      *chunkedBody           `json:"chunked_body"`
+	Targets          []weightedTarget       `json:"targets"` // sent instead of the url, picked by weight
+	CSRF             *csrfConf              `json:"csrf"`    // token of the form page sent with the step
+}
+
+// csrfConf is the config of the types.CSRF
+type csrfConf struct {
+	FormURL string `json:"form_url"`
+	Field   string `json:"field"`
+	Header  string `json:"header"`
+}
+
+// weightedTarget is a target of a step, picked for a request by its weight
+type weightedTarget struct {
+	Name   string `json:"name"`
+	Url    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+func (wt *weightedTarget) UnmarshalJSON(data []byte) error {
+	// default values
+	wt.Weight = 1
+	type tempTarget weightedTarget
+	return json.Unmarshal(data, (*tempTarget)(wt))
+}
+
+// chunkedBody is the config of the types.ChunkedBody, chunk_size is types.DefaultChunkSize if not given
+type chunkedBody struct {
+	Size      int64        `json:"size"`
+	ChunkSize int          `json:"chunk_size"`
+	Delay     jsonDuration `json:"delay"`
+}
+
+type redirectConf struct {
+	Disabled bool `json:"disabled"`
+	Max      int  `json:"max"`
 }
 
 func (s *step) UnmarshalJSON(data []byte) error {
 	type stepAlias step
 	defaultFields := &stepAlias{
 		Method:  types.DefaultMethod,
-		Timeout: types.DefaultTimeout,
+		Timeout: jsonDuration(types.DefaultTimeout * time.Second),
 	}
 
 	err := json.Unmarshal(data, defaultFields)
@@ -112,6 +265,20 @@ func (s *step) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// weightedScenario is a named flow of steps, picked for an iteration by its weight
+type weightedScenario struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	Steps  []step `json:"steps"`
+}
+
+func (ws *weightedScenario) UnmarshalJSON(data []byte) error {
+	// default values
+	ws.Weight = 1
+	type tempScenario weightedScenario
+	return json.Unmarshal(data, (*tempScenario)(ws))
+}
+
 type Tag struct {
 	Tag  string `json:"tag"`
 	Type string `json:"type"`
@@ -132,6 +299,7 @@ type CsvConf struct {
 	SkipEmptyLine bool           `json:"skip_empty_line"`
 	AllowQuota    bool           `json:"allow_quota"`
 	Order         string         `json:"order"`
+	Circular      *bool          `json:"circular"` // default of types.CsvConf if nil
 }
 
 func (c *CsvConf) UnmarshalJSON(data []byte) error {
@@ -151,17 +319,289 @@ type JsonReader struct {
 	IterCount    *int                   `json:"iteration_count"`
 	LoadType     string                 `json:"load_type"`
 	Duration     int                    `json:"duration"`
+	GracePeriod  jsonDuration           `json:"grace_period"`
+	MaxDuration  jsonDuration           `json:"max_duration"`
+	RetryAfter   jsonDuration           `json:"honor_retry_after"`
+	Warmup       jsonDuration           `json:"warmup"`
+	Jitter       jsonDuration           `json:"jitter"`
+	StartSpread  jsonDuration           `json:"startup_spread"`
+	StopOn       []string               `json:"stop_on"`
+	SLA          []string               `json:"sla"`
+	RPS          int                    `json:"rps"`
+	CoCorrect    bool                   `json:"correct_omission"`
+	MaxRequests  int64                  `json:"max_requests"`
 	Assertions   []TestAssertion        `json:"success_criterias"`
 	TimeRunCount timeRunCount           `json:"manual_load"`
 	Steps        []step                 `json:"steps"`
+	Scenarios    []weightedScenario     `json:"scenarios"`
+	Cleanup      []step                 `json:"cleanup"`  // run at the end of each iteration
+	Setup        []step                 `json:"setup"`    // run once before the load
+	Teardown     []step                 `json:"teardown"` // run once after the load
+	Seed         int64                  `json:"seed"`
+	MaxRespBody  int64                  `json:"max_response_body_bytes"`      // default of the steps
+	ReqFallback  bool                   `json:"request_compression_fallback"` // default of the steps
+	Headers      map[string]string      `json:"global_headers"`               // sent by all the steps
+	Auth         *auth                  `json:"auth"`                         // auth of the steps without auth
 	Output       string                 `json:"output"`
 	Proxy        string                 `json:"proxy"`
+	NoProxy      []string               `json:"no_proxy"`
+	DNSCacheTTL  jsonDuration           `json:"dns_cache_ttl"`
+	Resolve      []string               `json:"resolve"`
+	SourceAddrs  []string               `json:"source_addrs"`
+	MaxHostConns int                    `json:"max_host_conns"`
+	NoKeepAlive  bool                   `json:"disable_keep_alive"`
+	Revalidate   bool                   `json:"revalidate"`
+	PreWarm      bool                   `json:"pre_warm"`
+	RequestID    string                 `json:"request_id_header"`
+	SuccessWhen  string                 `json:"success_when"`
+	UserAgents   userAgentConf          `json:"user_agents"`
 	Envs         map[string]interface{} `json:"env"`
 	Data         map[string]CsvConf     `json:"data"`
 	Debug        bool                   `json:"debug"`
 	SamplingRate *int                   `json:"sampling_rate"`
 	EngineMode   string                 `json:"engine_mode"`
+	StickyUsers  int                    `json:"sticky_users"`
+	CapPool      bool                   `json:"cap_client_pool"`
+	AdaptivePool bool                   `json:"adaptive_client_pool"`
+	Affinity     bool                   `json:"client_affinity"`
+	PerHost      bool                   `json:"client_per_host"`
+	ClientTTL    jsonDuration           `json:"client_ttl"`
+	HealthCheck  jsonDuration           `json:"client_health_check"`
+	Transport    transportConf          `json:"transport"`
+	OnlyTags     []string               `json:"only_tags"`
 	Cookies      CookieConf             `json:"cookie_jar"`
+	Load         *loadPattern           `json:"load"`
+	Adaptive     *adaptiveLoad          `json:"adaptive"`
+	UserQuota    *userQuota             `json:"user_quota"`
+	Backpressure *backpressure          `json:"backpressure"`
+	CertAudit    *certAudit             `json:"cert_audit"`
+	OTel         otelConf               `json:"otel"`
+	TimeSeries   timeSeriesConf         `json:"timeseries"`
+	Failures     failureSamplesConf     `json:"failure_samples"`
+
+	Percentiles    []float64       `json:"percentiles"`
+	PercentileMode string          `json:"percentile_mode"`
+	SampleRate     float64         `json:"sample_rate"`
+	LatencyBuckets *latencyBuckets `json:"latency_buckets"`
+
+	durationGiven bool // duration is set explicitly, not defaulted
+}
+
+// otelConf is the config of the types.OTelConf, flush_interval can be given in seconds or as a duration string like "5s"
+type otelConf struct {
+	Endpoint      string            `json:"endpoint"`
+	Headers       map[string]string `json:"headers"`
+	ServiceName   string            `json:"service_name"`
+	BatchSize     int               `json:"batch_size"`
+	FlushInterval jsonDuration      `json:"flush_interval"`
+}
+
+// timeSeriesConf is the config of the types.TimeSeriesConf, interval can be given in seconds or as a duration string like "5s"
+type timeSeriesConf struct {
+	File     string       `json:"file"`
+	Format   string       `json:"format"`
+	Interval jsonDuration `json:"interval"`
+}
+
+// failureSamplesConf is the config of the types.FailureSamplesConf
+type failureSamplesConf struct {
+	File         string `json:"file"`
+	PerCategory  int    `json:"per_category"`
+	MaxBodyBytes int64  `json:"max_body_bytes"`
+}
+
+// userAgentConf is the config of the types.UserAgentConf
+type userAgentConf struct {
+	Values  []string `json:"values"`
+	Builtin bool     `json:"builtin"`
+	Order   string   `json:"order"`
+	Per     string   `json:"per"`
+}
+
+// userQuota is the config of the types.UserQuota
+type userQuota struct {
+	Users      int `json:"users"`
+	Iterations int `json:"iterations"`
+}
+
+// backpressure is the config of the types.Backpressure
+type backpressure struct {
+	Policy      string `json:"policy"`
+	MaxInFlight int    `json:"max_in_flight"`
+	QueueSize   int    `json:"queue_size"`
+}
+
+// latencyBuckets is the config of the types.LatencyBuckets, min and max can be given in seconds or as duration
+// strings like "1ms"
+type latencyBuckets struct {
+	Min        jsonDuration `json:"min"`
+	Max        jsonDuration `json:"max"`
+	SubBuckets int          `json:"sub_buckets"`
+}
+
+// certAudit is the config of the types.CertAudit, expiry_days is types.DefaultCertExpiryDays if not given
+type certAudit struct {
+	ExpiryDays *int `json:"expiry_days"`
+}
+
+// transportConf is the config of the types.TransportConf, idle_conn_timeout and keep_alive_ping can be given in
+// seconds or as a duration string like "90s"
+type transportConf struct {
+	MaxIdleConns        int          `json:"max_idle_conns"`
+	MaxIdleConnsPerHost int          `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int          `json:"max_conns_per_host"`
+	IdleConnTimeout     jsonDuration `json:"idle_conn_timeout"`
+	KeepAlivePing       jsonDuration `json:"keep_alive_ping"`
+}
+
+// adaptiveLoad is the config of the types.AdaptiveLoad, max_error_rate is a percentage
+type adaptiveLoad struct {
+	MinUsers     int          `json:"min_users"`
+	MaxUsers     int          `json:"max_users"`
+	Step         int          `json:"step"`
+	Interval     jsonDuration `json:"interval"`
+	MaxP95       jsonDuration `json:"max_p95"`
+	MaxErrorRate float64      `json:"max_error_rate"`
+	OnBreach     string       `json:"on_breach"`
+}
+
+// loadPattern is the config of the types.LoadPattern, durations can be given in seconds or as a duration string like "60s"
+type loadPattern struct {
+	Type          string       `json:"type"`
+	Duration      jsonDuration `json:"duration"`
+	Rate          int          `json:"rate"`
+	Start         int          `json:"start"`
+	End           int          `json:"end"`
+	Step          int          `json:"step"`
+	StepDuration  jsonDuration `json:"step_duration"`
+	Peak          int          `json:"peak"`
+	SpikeAt       jsonDuration `json:"spike_at"`
+	SpikeDuration jsonDuration `json:"spike_duration"`
+
+	RampDown        jsonDuration `json:"ramp_down"`
+	ExcludeRampDown bool         `json:"exclude_ramp_down"`
+}
+
+type jsonDuration time.Duration
+
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case float64:
+		*d = jsonDuration(time.Duration(val * float64(time.Second)))
+	case string:
+		dur, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("invalid duration %s, %v", val, err)
+		}
+		*d = jsonDuration(dur)
+	default:
+		return fmt.Errorf("invalid duration %v", v)
+	}
+	return nil
+}
+
+// sleepConf is the sleep expression of a step. It can be given as a string like "300-500" or "1s", a number in ms,
+// or as an object like {"min": "1s", "max": "3s"} and {"distribution": "exponential", "mean": "500ms"}.
+// expectedStatus is a status code expression or a list of them, like 401, "2xx" or ["200-204", 304]
+type expectedStatus []string
+
+func (es *expectedStatus) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+	*es = nil
+	for _, val := range values {
+		switch s := val.(type) {
+		case string:
+			*es = append(*es, s)
+		case float64:
+			*es = append(*es, strconv.Itoa(int(s)))
+		default:
+			return fmt.Errorf("invalid expected status %v", val)
+		}
+	}
+	return nil
+}
+
+type sleepConf struct {
+	expr string
+	dist types.SleepDistribution
+}
+
+func (sc *sleepConf) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case string:
+		*sc = sleepConf{expr: val}
+	case float64:
+		*sc = sleepConf{expr: strconv.Itoa(int(val))}
+	case map[string]interface{}:
+		min, err := sleepBound(val["min"])
+		if err != nil {
+			return err
+		}
+		max, err := sleepBound(val["max"])
+		if err != nil {
+			return err
+		}
+		if min == "" || max == "" {
+			*sc = sleepConf{expr: min + max}
+		} else {
+			*sc = sleepConf{expr: min + "-" + max}
+		}
+		if dist, ok := val["distribution"]; ok {
+			if sc.dist.Type, ok = dist.(string); !ok {
+				return fmt.Errorf("invalid sleep distribution %v", dist)
+			}
+			sc.dist.Type = strings.ToLower(sc.dist.Type)
+			if sc.dist.Mean, err = sleepMs(val["mean"]); err != nil {
+				return err
+			}
+			if sc.dist.StdDev, err = sleepMs(val["stddev"]); err != nil {
+				return err
+			}
+		}
+	case nil:
+		*sc = sleepConf{}
+	default:
+		return fmt.Errorf("invalid sleep %v", v)
+	}
+	return nil
+}
+
+func sleepBound(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case float64:
+		return strconv.Itoa(int(val)), nil
+	default:
+		return "", fmt.Errorf("invalid sleep bound %v", v)
+	}
+}
+
+// sleepMs returns the duration of the sleep distribution parameter in ms, zero if it is not given.
+func sleepMs(v interface{}) (int, error) {
+	b, err := sleepBound(v)
+	if err != nil || b == "" {
+		return 0, err
+	}
+	ms, _, err := types.ParseSleep(b)
+	return ms, err
 }
 
 type CookieConf struct {
@@ -201,6 +641,14 @@ func (j *JsonReader) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	var given struct {
+		Duration *int `json:"duration"`
+	}
+	if err = json.Unmarshal(data, &given); err != nil {
+		return err
+	}
+	defaultFields.durationGiven = given.Duration != nil
+
 	*j = JsonReader(*defaultFields)
 	return nil
 }
@@ -218,6 +666,71 @@ func (j *JsonReader) Init(jsonByte []byte) (err error) {
 	return
 }
 
+// toScenarioStep converts the step, applying the global defaults that are not overridden by the step.
+func (j *JsonReader) toScenarioStep(s step) (types.ScenarioStep, error) {
+	if j.Auth != nil && s.Auth.empty() {
+		s.Auth = *j.Auth
+	}
+	item, err := stepToScenarioStep(s)
+	if err != nil {
+		return item, err
+	}
+	item.MaxResponseBodyBytes = j.MaxRespBody
+	if s.MaxResponseBody != nil {
+		item.MaxResponseBodyBytes = *s.MaxResponseBody
+	}
+	item.RequestCompressionFallback = j.ReqFallback
+	if s.ReqFallback != nil {
+		item.RequestCompressionFallback = *s.ReqFallback
+	}
+	item.Headers = mergeHeaders(j.Headers, item.Headers)
+	return item, nil
+}
+
+// removedHeader is the value of the step headers given as null, they remove the global header of the same name.
+const removedHeader = "\x00"
+
+// stepHeaders are the headers of a step, null values remove the global headers.
+type stepHeaders map[string]string
+
+func (h *stepHeaders) UnmarshalJSON(data []byte) error {
+	var m map[string]*string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*h = make(stepHeaders, len(m))
+	for k, v := range m {
+		if v == nil {
+			(*h)[k] = removedHeader
+		} else {
+			(*h)[k] = *v
+		}
+	}
+	return nil
+}
+
+// mergeHeaders returns the global headers overridden by the step headers, header names are case insensitive.
+func mergeHeaders(global, step map[string]string) map[string]string {
+	if len(global) == 0 && len(step) == 0 {
+		return step
+	}
+	merged := make(map[string]string, len(global)+len(step))
+	for k, v := range global {
+		merged[k] = v
+	}
+	for k, v := range step {
+		for g := range global {
+			if strings.EqualFold(g, k) {
+				delete(merged, g)
+			}
+		}
+		if v != removedHeader {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
 func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 	// Scenario
 	s := types.Scenario{
@@ -225,7 +738,7 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 	}
 	var si types.ScenarioStep
 	for _, step := range j.Steps {
-		si, err = stepToScenarioStep(step)
+		si, err = j.toScenarioStep(step)
 		if err != nil {
 			return
 		}
@@ -233,6 +746,51 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 		s.Steps = append(s.Steps, si)
 	}
 
+	// Weighted scenarios, their steps are flattened into the scenario steps
+	if len(j.Scenarios) > 0 && len(j.Steps) > 0 {
+		return h, fmt.Errorf("steps and scenarios can not be used together, define the steps under the scenarios")
+	}
+	for _, ws := range j.Scenarios {
+		wScenario := types.WeightedScenario{
+			Name:   ws.Name,
+			Weight: ws.Weight,
+		}
+		for _, step := range ws.Steps {
+			si, err = j.toScenarioStep(step)
+			if err != nil {
+				return
+			}
+
+			s.Steps = append(s.Steps, si)
+			wScenario.StepIDs = append(wScenario.StepIDs, si.ID)
+		}
+		s.WeightedScenarios = append(s.WeightedScenarios, wScenario)
+	}
+	for _, step := range j.Cleanup {
+		si, err = j.toScenarioStep(step)
+		if err != nil {
+			return
+		}
+
+		s.Cleanup = append(s.Cleanup, si)
+	}
+	for _, step := range j.Setup {
+		si, err = j.toScenarioStep(step)
+		if err != nil {
+			return
+		}
+
+		s.Setup = append(s.Setup, si)
+	}
+	for _, step := range j.Teardown {
+		si, err = j.toScenarioStep(step)
+		if err != nil {
+			return
+		}
+
+		s.Teardown = append(s.Teardown, si)
+	}
+
 	// Proxy
 	var proxyURL *url.URL
 	if j.Proxy != "" {
@@ -244,6 +802,41 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 	p := proxy.Proxy{
 		Strategy: proxy.ProxyTypeSingle,
 		Addr:     proxyURL,
+		NoProxy:  j.NoProxy,
+	}
+
+	var quota *types.UserQuota
+	if j.UserQuota != nil {
+		if j.durationGiven || j.IterCount != nil || j.ReqCount != nil {
+			return h, fmt.Errorf("user_quota can't be used with duration and iteration_count")
+		}
+		quota = &types.UserQuota{Users: j.UserQuota.Users, Iterations: j.UserQuota.Iterations}
+	}
+
+	var bp *types.Backpressure
+	if j.Backpressure != nil {
+		bp = &types.Backpressure{
+			Policy:      j.Backpressure.Policy,
+			MaxInFlight: j.Backpressure.MaxInFlight,
+			QueueSize:   j.Backpressure.QueueSize,
+		}
+	}
+
+	var latencyBuckets *types.LatencyBuckets
+	if j.LatencyBuckets != nil {
+		latencyBuckets = &types.LatencyBuckets{
+			Min:        time.Duration(j.LatencyBuckets.Min),
+			Max:        time.Duration(j.LatencyBuckets.Max),
+			SubBuckets: j.LatencyBuckets.SubBuckets,
+		}
+	}
+
+	var certAudit *types.CertAudit
+	if j.CertAudit != nil {
+		certAudit = &types.CertAudit{ExpiryDays: types.DefaultCertExpiryDays}
+		if j.CertAudit.ExpiryDays != nil {
+			certAudit.ExpiryDays = *j.CertAudit.ExpiryDays
+		}
 	}
 
 	// for backwards compatibility
@@ -252,6 +845,9 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 		iterationCount = *j.IterCount
 	} else if j.ReqCount != nil {
 		iterationCount = *j.ReqCount
+	} else if j.RPS > 0 {
+		// start an iteration per request of the rate, the limiter paces them
+		iterationCount = j.RPS * j.Duration
 	} else {
 		iterationCount = types.DefaultIterCount
 	}
@@ -266,6 +862,56 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 		}
 	}
 
+	// Load pattern
+	var loadPattern types.LoadPattern
+	if j.Load != nil {
+		loadPattern, err = types.NewLoadPattern(types.LoadPatternConf{
+			Type:          strings.ToLower(j.Load.Type),
+			Duration:      time.Duration(j.Load.Duration),
+			Rate:          j.Load.Rate,
+			Start:         j.Load.Start,
+			End:           j.Load.End,
+			Step:          j.Load.Step,
+			StepDuration:  time.Duration(j.Load.StepDuration),
+			Peak:          j.Load.Peak,
+			SpikeAt:       time.Duration(j.Load.SpikeAt),
+			SpikeDuration: time.Duration(j.Load.SpikeDuration),
+			RampDown:      time.Duration(j.Load.RampDown),
+		})
+		if err != nil {
+			return
+		}
+		j.Duration = int(math.Ceil(time.Duration(j.Load.Duration + j.Load.RampDown).Seconds()))
+		*j.IterCount = types.TotalIterations(loadPattern, j.Duration)
+	}
+
+	var rampDown time.Duration
+	if j.Load != nil && j.Load.ExcludeRampDown {
+		rampDown = time.Duration(j.Load.RampDown)
+	}
+
+	var adaptive *types.AdaptiveLoad
+	if j.Adaptive != nil {
+		adaptive = &types.AdaptiveLoad{
+			MinUsers:     j.Adaptive.MinUsers,
+			MaxUsers:     j.Adaptive.MaxUsers,
+			Step:         j.Adaptive.Step,
+			Interval:     time.Duration(j.Adaptive.Interval),
+			MaxP95:       time.Duration(j.Adaptive.MaxP95),
+			MaxErrorRate: j.Adaptive.MaxErrorRate / 100,
+			OnBreach:     strings.ToLower(j.Adaptive.OnBreach),
+		}
+		if adaptive.Step == 0 {
+			adaptive.Step = 1
+		}
+		if adaptive.Interval == 0 {
+			adaptive.Interval = types.DefaultAdaptiveInterval
+		}
+		if adaptive.OnBreach == "" {
+			adaptive.OnBreach = types.DefaultAdaptiveOnBreach
+		}
+	}
+
 	var samplingRate int
 	if j.SamplingRate != nil {
 		samplingRate = *j.SamplingRate
@@ -290,6 +936,7 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 			SkipEmptyLine: val.SkipEmptyLine,
 			AllowQuota:    val.AllowQuota,
 			Order:         val.Order,
+			Circular:      val.Circular,
 		}
 	}
 
@@ -310,21 +957,90 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 
 	// Hammer
 	h = types.Hammer{
-		IterationCount:    *j.IterCount,
-		LoadType:          strings.ToLower(j.LoadType),
-		TestDuration:      j.Duration,
-		TimeRunCountMap:   types.TimeRunCount(j.TimeRunCount),
-		Scenario:          s,
-		Proxy:             p,
-		ReportDestination: j.Output,
-		Debug:             j.Debug,
-		SamplingRate:      samplingRate,
-		EngineMode:        j.EngineMode,
-		TestDataConf:      testDataConf,
-		Cookies:           *(*[]types.CustomCookie)(unsafe.Pointer(&j.Cookies.Cookies)),
-		CookiesEnabled:    j.Cookies.Enabled,
-		Assertions:        testAssertions,
-		SingleMode:        types.DefaultSingleMode,
+		IterationCount:   *j.IterCount,
+		LoadType:         strings.ToLower(j.LoadType),
+		TestDuration:     j.Duration,
+		GracePeriod:      time.Duration(j.GracePeriod),
+		MaxDuration:      time.Duration(j.MaxDuration),
+		HonorRetryAfter:  time.Duration(j.RetryAfter),
+		Warmup:           time.Duration(j.Warmup),
+		RampDown:         rampDown,
+		Jitter:           time.Duration(j.Jitter),
+		StartupSpread:    time.Duration(j.StartSpread),
+		StopOn:           j.StopOn,
+		SLA:              j.SLA,
+		RPS:              j.RPS,
+		CorrectOmission:  j.CoCorrect,
+		MaxRequests:      j.MaxRequests,
+		TimeRunCountMap:  types.TimeRunCount(j.TimeRunCount),
+		LoadPattern:      loadPattern,
+		Adaptive:         adaptive,
+		UserQuota:        quota,
+		Backpressure:     bp,
+		Scenario:         s,
+		Proxy:            p,
+		DNSCacheTTL:      time.Duration(j.DNSCacheTTL),
+		Resolve:          j.Resolve,
+		SourceAddrs:      j.SourceAddrs,
+		MaxHostConns:     j.MaxHostConns,
+		DisableKeepAlive: j.NoKeepAlive,
+		Revalidate:       j.Revalidate,
+		PreWarm:          j.PreWarm,
+		OTel: types.OTelConf{
+			Endpoint:      j.OTel.Endpoint,
+			Headers:       j.OTel.Headers,
+			ServiceName:   j.OTel.ServiceName,
+			BatchSize:     j.OTel.BatchSize,
+			FlushInterval: time.Duration(j.OTel.FlushInterval),
+		},
+		TimeSeries: types.TimeSeriesConf{
+			File:     j.TimeSeries.File,
+			Format:   j.TimeSeries.Format,
+			Interval: time.Duration(j.TimeSeries.Interval),
+		},
+		FailureSamples: types.FailureSamplesConf{
+			File:         j.Failures.File,
+			PerCategory:  j.Failures.PerCategory,
+			MaxBodyBytes: j.Failures.MaxBodyBytes,
+		},
+		UserAgents: types.UserAgentConf{
+			Values:  j.UserAgents.Values,
+			Builtin: j.UserAgents.Builtin,
+			Order:   j.UserAgents.Order,
+			Per:     j.UserAgents.Per,
+		},
+		RequestIDHeader:    j.RequestID,
+		SuccessWhen:        j.SuccessWhen,
+		ReportDestination:  j.Output,
+		Debug:              j.Debug,
+		SamplingRate:       samplingRate,
+		Seed:               j.Seed,
+		EngineMode:         j.EngineMode,
+		StickyUsers:        j.StickyUsers,
+		CapClientPool:      j.CapPool,
+		AdaptiveClientPool: j.AdaptivePool,
+		ClientAffinity:     j.Affinity,
+		ClientPerHost:      j.PerHost,
+		ClientTTL:          time.Duration(j.ClientTTL),
+		ClientHealthCheck:  time.Duration(j.HealthCheck),
+		CertAudit:          certAudit,
+		Transport: types.TransportConf{
+			MaxIdleConns:        j.Transport.MaxIdleConns,
+			MaxIdleConnsPerHost: j.Transport.MaxIdleConnsPerHost,
+			MaxConnsPerHost:     j.Transport.MaxConnsPerHost,
+			IdleConnTimeout:     time.Duration(j.Transport.IdleConnTimeout),
+			KeepAlivePing:       time.Duration(j.Transport.KeepAlivePing),
+		},
+		OnlyTags:       j.OnlyTags,
+		Percentiles:    j.Percentiles,
+		PercentileMode: j.PercentileMode,
+		LatencyBuckets: latencyBuckets,
+		SampleRate:     j.SampleRate,
+		TestDataConf:   testDataConf,
+		Cookies:        *(*[]types.CustomCookie)(unsafe.Pointer(&j.Cookies.Cookies)),
+		CookiesEnabled: j.Cookies.Enabled,
+		Assertions:     testAssertions,
+		SingleMode:     types.DefaultSingleMode,
 	}
 	return
 }
@@ -332,7 +1048,14 @@ func (j *JsonReader) CreateHammer() (h types.Hammer, err error) {
 func stepToScenarioStep(s step) (types.ScenarioStep, error) {
 	var payload string
 	var err error
-	if len(s.PayloadMultipart) > 0 {
+	var multipartStream []types.MultipartPart
+	if len(s.PayloadMultipart) > 0 && s.MultipartStream {
+		// Content-Type is set by the requester with the boundary of the body
+		multipartStream, err = prepareMultipartStream(s.PayloadMultipart)
+		if err != nil {
+			return types.ScenarioStep{}, err
+		}
+	} else if len(s.PayloadMultipart) > 0 {
 		if s.Headers == nil {
 			s.Headers = make(map[string]string)
 		}
@@ -361,14 +1084,40 @@ func stepToScenarioStep(s step) (types.ScenarioStep, error) {
 		payload = s.Payload
 	}
 
+	if s.Auth.Username == "" {
+		s.Auth.Username = s.Auth.User
+	}
+	if s.Auth.Password == "" {
+		s.Auth.Password = s.Auth.Pass
+	}
 	// Set default Auth type if not set
-	if s.Auth != (auth{}) && s.Auth.Type == "" {
+	if s.Auth.Type == "" && (s.Auth.Username != "" || s.Auth.Password != "") {
 		s.Auth.Type = types.AuthHttpBasic
 	}
+	if strings.EqualFold(s.Auth.Type, authNone) {
+		// global auth is disabled for the step
+		s.Auth = auth{}
+	}
 
-	err = types.IsTargetValid(s.Url)
-	if err != nil {
-		return types.ScenarioStep{}, err
+	stepType := strings.ToLower(s.Type)
+	if stepType == types.StepTypeGraphQL {
+		// operations are always posted, the body is built by the requester
+		s.Method = http.MethodPost
+	}
+	if stepType == types.StepTypeDNS {
+		if s.DNS.QType == "" {
+			s.DNS.QType = types.DefaultDNSQueryType
+		}
+		if s.DNS.Transport == "" {
+			s.DNS.Transport = types.DefaultDNSTransport
+		}
+	}
+	if (stepType == "" || stepType == types.StepTypeHTTP || stepType == types.StepTypeGraphQL) && len(s.Targets) == 0 {
+		// other step types have their own target schemes, validated in types.ScenarioStep
+		err = types.IsTargetValid(s.Url)
+		if err != nil {
+			return types.ScenarioStep{}, err
+		}
 	}
 
 	var capturedEnvs []types.EnvCaptureConf
@@ -385,27 +1134,97 @@ func stepToScenarioStep(s step) (types.ScenarioStep, error) {
 
 		if path.RegExp != nil {
 			capConf.RegExp = &types.RegexCaptureConf{
-				Exp: path.RegExp.Exp,
-				No:  path.RegExp.No,
+				Exp:   path.RegExp.Exp,
+				No:    path.RegExp.No,
+				Fatal: path.RegExp.Fatal,
 			}
 		}
 
 		capturedEnvs = append(capturedEnvs, capConf)
 	}
 
+	expected, err := types.ParseExpectedStatus(s.ExpectedStatus)
+	if err != nil {
+		return types.ScenarioStep{}, err
+	}
+
 	item := types.ScenarioStep{
-		ID:            s.Id,
-		Name:          s.Name,
-		URL:           s.Url,
-		Auth:          types.Auth(s.Auth),
-		Method:        strings.ToUpper(s.Method),
-		Headers:       s.Headers,
-		Payload:       payload,
-		Timeout:       s.Timeout,
-		Sleep:         strings.ReplaceAll(s.Sleep, " ", ""),
-		Custom:        s.Others,
-		EnvsToCapture: capturedEnvs,
-		Assertions:    s.Assertions,
+		ID:   s.Id,
+		Name: s.Name,
+		URL:  s.Url,
+		Auth: types.Auth{
+			Type:         s.Auth.Type,
+			Username:     s.Auth.Username,
+			Password:     s.Auth.Password,
+			TokenURL:     s.Auth.TokenURL,
+			ClientID:     s.Auth.ClientID,
+			ClientSecret: s.Auth.ClientSecret,
+			Scopes:       strings.Join(s.Auth.Scopes, " "),
+			Token:        s.Auth.Token,
+		},
+		Method:            strings.ToUpper(s.Method),
+		Headers:           s.Headers,
+		Payload:           payload,
+		Timeout:           int(math.Ceil(time.Duration(s.Timeout).Seconds())),
+		Sleep:             strings.ReplaceAll(s.Sleep.expr, " ", ""),
+		SleepDistribution: s.Sleep.dist,
+		Custom:            s.Others,
+		EnvsToCapture:     capturedEnvs,
+		Assertions:        s.Assertions,
+		Type:              stepType,
+		Grpc:              types.GrpcConf(s.Grpc),
+		WebSocket:         types.WebSocketConf(s.WebSocket),
+		SSE:               types.SSEConf(s.SSE),
+		Socket:            types.SocketConf(s.Socket),
+		DNS: types.DNSConf{
+			Name:        s.DNS.Name,
+			QType:       strings.ToUpper(s.DNS.QType),
+			Transport:   strings.ToLower(s.DNS.Transport),
+			NoRecursion: s.DNS.NoRecursion,
+		},
+		GraphQL: types.GraphQLConf{
+			Query:         s.GraphQL.Query,
+			Variables:     s.GraphQL.variables(),
+			OperationName: s.GraphQL.OperationName,
+		},
+		Protocol: strings.ToUpper(s.Protocol),
+		Retry:    types.RetryConf(s.Retry),
+		Redirect: types.RedirectConf(s.Redirect),
+		If:       s.If,
+		Tags:     s.Tags,
+
+		MultipartStream:    multipartStream,
+		ResponseSchema:     s.ResponseSchema,
+		ExpectedStatus:     expected,
+		RequestCompression: strings.ToLower(s.ReqCompression),
+		DependsOn:          s.DependsOn,
+		Parallel:           s.Parallel,
+
+		DialTimeout:         time.Duration(s.DialTimeout),
+		TLSHandshakeTimeout: time.Duration(s.TLSTimeout),
+	}
+	if timeout := time.Duration(s.Timeout); timeout%time.Second != 0 {
+		// sub-second precision
+		item.RequestTimeout = timeout
+	}
+
+	if s.ChunkedBody != nil {
+		item.ChunkedBody = &types.ChunkedBody{
+			Size:      s.ChunkedBody.Size,
+			ChunkSize: s.ChunkedBody.ChunkSize,
+			Delay:     time.Duration(s.ChunkedBody.Delay),
+		}
+		if item.ChunkedBody.ChunkSize == 0 {
+			item.ChunkedBody.ChunkSize = types.DefaultChunkSize
+		}
+	}
+
+	if s.CSRF != nil {
+		item.CSRF = &types.CSRF{FormURL: s.CSRF.FormURL, Field: s.CSRF.Field, Header: s.CSRF.Header}
+	}
+
+	for _, t := range s.Targets {
+		item.Targets = append(item.Targets, types.WeightedTarget{Name: t.Name, URL: t.Url, Weight: t.Weight})
 	}
 
 	if s.CertPath != "" && s.CertKeyPath != "" {
@@ -418,6 +1237,25 @@ func stepToScenarioStep(s step) (types.ScenarioStep, error) {
 		item.CertPool = pool
 	}
 
+	if s.TLS != nil {
+		tlsConf := types.TLSConf{
+			InsecureSkipVerify: true,
+			CertFile:           s.TLS.CertPath,
+			KeyFile:            s.TLS.KeyPath,
+			CAFile:             s.TLS.CAPath,
+			MinVersion:         s.TLS.MinVersion,
+		}
+		if s.TLS.InsecureSkipVerify != nil {
+			tlsConf.InsecureSkipVerify = *s.TLS.InsecureSkipVerify
+		}
+
+		// certificates are loaded once here, requesters share the loaded config
+		item.TLSConfig, err = tlsConf.Load()
+		if err != nil {
+			return item, err
+		}
+	}
+
 	return item, nil
 }
 
@@ -428,7 +1266,18 @@ func prepareMultipartPayload(parts []multipartFormData) (body string, contentTyp
 	for _, part := range parts {
 		var multipartError RemoteMultipartError
 		if strings.EqualFold(part.Type, "file") {
-			if strings.EqualFold(part.Src, "remote") {
+			if strings.EqualFold(part.Src, "inline") {
+				if part.FileName == "" {
+					return "", "", fmt.Errorf("filename should be given for the inline multipart file %s", part.Name)
+				}
+				formPart, err := writer.CreateFormFile(part.Name, part.FileName)
+				if err != nil {
+					return "", "", err
+				}
+				if _, err = io.WriteString(formPart, part.Value); err != nil {
+					return "", "", err
+				}
+			} else if strings.EqualFold(part.Src, "remote") {
 				response, err := http.Get(part.Value)
 				if err != nil {
 					multipartError.wrappedErr = err
@@ -438,7 +1287,7 @@ func prepareMultipartPayload(parts []multipartFormData) (body string, contentTyp
 				defer response.Body.Close()
 
 				u, _ := url.Parse(part.Value)
-				formPart, err := writer.CreateFormFile(part.Name, path.Base(u.Path))
+				formPart, err := writer.CreateFormFile(part.Name, fileNameOr(part.FileName, path.Base(u.Path)))
 				if err != nil {
 					multipartError.wrappedErr = err
 					multipartError.msg = "Error while creating form file"
@@ -464,7 +1313,7 @@ func prepareMultipartPayload(parts []multipartFormData) (body string, contentTyp
 				}
 				defer file.Close()
 
-				formPart, err := writer.CreateFormFile(part.Name, filepath.Base(file.Name()))
+				formPart, err := writer.CreateFormFile(part.Name, fileNameOr(part.FileName, filepath.Base(file.Name())))
 				if err != nil {
 					return "", "", err
 				}
@@ -488,6 +1337,41 @@ func prepareMultipartPayload(parts []multipartFormData) (body string, contentTyp
 	return byteBody.String(), writer.FormDataContentType(), err
 }
 
+// prepareMultipartStream converts the parts to the ones streamed by the requester. Remote files can not be streamed.
+func prepareMultipartStream(parts []multipartFormData) ([]types.MultipartPart, error) {
+	streamParts := make([]types.MultipartPart, 0, len(parts))
+	for _, part := range parts {
+		p := types.MultipartPart{Name: part.Name, Value: part.Value, Template: part.Template}
+		if strings.EqualFold(part.Type, "file") {
+			switch strings.ToLower(part.Src) {
+			case "remote":
+				return nil, fmt.Errorf("remote multipart file %s can not be streamed", part.Value)
+			case "inline":
+				if part.FileName == "" {
+					return nil, fmt.Errorf("filename should be given for the inline multipart file %s", part.Name)
+				}
+				p.FileName = part.FileName
+			default:
+				if _, err := os.Stat(part.Value); err != nil {
+					return nil, err
+				}
+				p.Value = ""
+				p.FilePath = part.Value
+				p.FileName = part.FileName
+			}
+		}
+		streamParts = append(streamParts, p)
+	}
+	return streamParts, nil
+}
+
+func fileNameOr(fileName string, defaultName string) string {
+	if fileName != "" {
+		return fileName
+	}
+	return defaultName
+}
+
 func preparePayloadFile(url string) (body string, err error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
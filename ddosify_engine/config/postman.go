@@ -0,0 +1,392 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+var (
+	postmanVariableRegexp = regexp.MustCompile(`{{\s*([^{}]+?)\s*}}`)
+	invalidEnvCharsRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// Content types of the raw bodies by the language option of Postman
+var postmanRawContentTypes = map[string]string{
+	"json":       "application/json",
+	"xml":        "application/xml",
+	"html":       "text/html",
+	"text":       "text/plain",
+	"javascript": "application/javascript",
+}
+
+type postmanCollection struct {
+	Info struct {
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     *postmanAuth      `json:"auth"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// postmanItem is a request or a folder of the items.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Auth    *postmanAuth    `json:"auth"`
+	Request *postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	URL    postmanURL        `json:"url"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *postmanBody      `json:"body"`
+	Auth   *postmanAuth      `json:"auth"`
+}
+
+// UnmarshalJSON accepts the request given as a url string.
+func (r *postmanRequest) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*r = postmanRequest{URL: postmanURL{Raw: raw}}
+		return nil
+	}
+	type request postmanRequest
+	return json.Unmarshal(data, (*request)(r))
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// UnmarshalJSON accepts the url given as a string.
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err == nil {
+		*u = postmanURL{Raw: raw}
+		return nil
+	}
+	type pURL postmanURL
+	return json.Unmarshal(data, (*pURL)(u))
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw"`
+	URLEncoded []postmanKeyValue `json:"urlencoded"`
+	FormData   []postmanKeyValue `json:"formdata"`
+	GraphQL    *struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Basic  []postmanKeyValue `json:"basic"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	APIKey []postmanKeyValue `json:"apikey"`
+}
+
+type postmanEnvironment struct {
+	Values []struct {
+		Key     string      `json:"key"`
+		Value   interface{} `json:"value"`
+		Enabled *bool       `json:"enabled"`
+	} `json:"values"`
+}
+
+type postmanKeyValue struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Type     string      `json:"type"` // text or file for the form data
+	Src      interface{} `json:"src"`  // path of the form data file
+	Disabled bool        `json:"disabled"`
+}
+
+func (kv postmanKeyValue) value() string {
+	return postmanTemplate(postmanString(kv.Value))
+}
+
+func postmanString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// importPostman creates the steps from the requests of a Postman v2 collection, in the order of the folders and
+// the requests in them. Collection variables and the variables of the environment, if given, are added to the
+// environment variables of the scenario.
+func importPostman(data []byte, environment []byte) (s types.Scenario, err error) {
+	var c postmanCollection
+	if err = json.Unmarshal(data, &c); err != nil {
+		return s, fmt.Errorf("postman collection is not valid: %v", err)
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "/v2.") {
+		return s, fmt.Errorf("unsupported postman collection schema: %s, v2.0 or v2.1 collections are supported",
+			c.Info.Schema)
+	}
+
+	s.Envs = make(map[string]interface{}, len(c.Variable))
+	for _, v := range c.Variable {
+		if !v.Disabled && v.Key != "" {
+			s.Envs[postmanEnvName(v.Key)] = v.value()
+		}
+	}
+	if environment != nil {
+		var env postmanEnvironment
+		if err = json.Unmarshal(environment, &env); err != nil {
+			return s, fmt.Errorf("postman environment is not valid: %v", err)
+		}
+		// environment overrides the collection variables like in Postman
+		for _, v := range env.Values {
+			if (v.Enabled == nil || *v.Enabled) && v.Key != "" {
+				s.Envs[postmanEnvName(v.Key)] = postmanTemplate(postmanString(v.Value))
+			}
+		}
+	}
+
+	if err = addPostmanItems(&s, c.Item, nil, c.Auth); err != nil {
+		return s, err
+	}
+	if len(s.Steps) == 0 {
+		return s, fmt.Errorf("postman collection has no requests")
+	}
+	return s, nil
+}
+
+// addPostmanItems adds the requests of the items depth first, folder auths are inherited by their items.
+func addPostmanItems(s *types.Scenario, items []postmanItem, folders []string, auth *postmanAuth) error {
+	for _, item := range items {
+		itemAuth := auth
+		if item.Auth != nil {
+			itemAuth = item.Auth
+		}
+
+		if item.Request == nil {
+			if err := addPostmanItems(s, item.Item, append(folders, item.Name), itemAuth); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(s.Steps) == math.MaxUint16 {
+			return fmt.Errorf("postman collection has more than %d requests", math.MaxUint16)
+		}
+		step, err := postmanStep(uint16(len(s.Steps)+1), strings.Join(append(folders, item.Name), " / "),
+			item.Request, itemAuth)
+		if err != nil {
+			return err
+		}
+		s.Steps = append(s.Steps, step)
+	}
+	return nil
+}
+
+func postmanStep(id uint16, name string, r *postmanRequest, auth *postmanAuth) (step types.ScenarioStep, err error) {
+	if r.URL.Raw == "" {
+		return step, fmt.Errorf("url of the postman request %q is empty", name)
+	}
+
+	method := strings.ToUpper(r.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	step = types.ScenarioStep{
+		ID:      id,
+		Name:    name,
+		Method:  method,
+		URL:     postmanURLString(r.URL),
+		Headers: make(map[string]string),
+		Timeout: types.DefaultTimeout,
+	}
+	for _, h := range r.Header {
+		if !h.Disabled {
+			step.Headers[h.Key] = h.value()
+		}
+	}
+
+	if r.Auth != nil {
+		auth = r.Auth
+	}
+	if err = setPostmanAuth(&step, auth); err != nil {
+		return step, fmt.Errorf("postman request %q: %v", name, err)
+	}
+
+	if r.Body != nil {
+		setPostmanBody(&step, r.Body)
+	}
+	return step, nil
+}
+
+// postmanURLString returns the raw url, path variables like /users/:id are replaced by their values.
+func postmanURLString(u postmanURL) string {
+	raw := u.Raw
+	for _, v := range u.Variable {
+		raw = strings.ReplaceAll(raw, "/:"+v.Key, "/"+postmanString(v.Value))
+	}
+	return postmanTemplate(raw)
+}
+
+func setPostmanAuth(step *types.ScenarioStep, auth *postmanAuth) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case "noauth", "":
+	case "basic":
+		step.Auth = types.Auth{
+			Type:     types.AuthHttpBasic,
+			Username: postmanParam(auth.Basic, "username"),
+			Password: postmanParam(auth.Basic, "password"),
+		}
+	case "bearer":
+		step.Headers["Authorization"] = "Bearer " + postmanParam(auth.Bearer, "token")
+	case "apikey":
+		if in := postmanParam(auth.APIKey, "in"); in != "" && in != "header" {
+			return fmt.Errorf("api key in %s is not supported, only headers are supported", in)
+		}
+		step.Headers[postmanParam(auth.APIKey, "key")] = postmanParam(auth.APIKey, "value")
+	default:
+		return fmt.Errorf("unsupported auth type: %s", auth.Type)
+	}
+	return nil
+}
+
+func postmanParam(params []postmanKeyValue, key string) string {
+	for _, p := range params {
+		if p.Key == key {
+			return p.value()
+		}
+	}
+	return ""
+}
+
+func setPostmanBody(step *types.ScenarioStep, body *postmanBody) {
+	contentType := ""
+	switch body.Mode {
+	case "raw":
+		step.Payload = postmanTemplate(body.Raw)
+		contentType = postmanRawContentTypes[body.Options.Raw.Language]
+	case "urlencoded":
+		parts := make([]string, 0, len(body.URLEncoded))
+		for _, p := range body.URLEncoded {
+			if !p.Disabled {
+				parts = append(parts, escapeTemplate(p.Key)+"="+escapeTemplate(p.value()))
+			}
+		}
+		step.Payload = strings.Join(parts, "&")
+		contentType = "application/x-www-form-urlencoded"
+	case "formdata":
+		for _, p := range body.FormData {
+			if p.Disabled {
+				continue
+			}
+			part := types.MultipartPart{Name: p.Key}
+			if p.Type == "file" {
+				part.FilePath = postmanString(p.Src)
+			} else {
+				part.Value = p.value()
+			}
+			step.MultipartStream = append(step.MultipartStream, part)
+		}
+	case "graphql":
+		if body.GraphQL != nil {
+			step.Type = types.StepTypeGraphQL
+			step.Method = http.MethodPost
+			step.GraphQL = types.GraphQLConf{
+				Query:     postmanTemplate(body.GraphQL.Query),
+				Variables: postmanTemplate(body.GraphQL.Variables),
+			}
+		}
+	}
+
+	if _, ok := headerValue(step.Headers, "Content-Type"); !ok && contentType != "" {
+		step.Headers["Content-Type"] = contentType
+	}
+}
+
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// postmanTemplate translates the Postman variables to the environment variables,
+// and the Postman dynamic variables like {{$randomInt}} to the dynamic variables like {{_randomInt}}.
+func postmanTemplate(s string) string {
+	return postmanVariableRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		name := postmanVariableRegexp.FindStringSubmatch(m)[1]
+		if strings.HasPrefix(name, "$") {
+			return "{{_" + name[1:] + "}}"
+		}
+		return "{{" + postmanEnvName(name) + "}}"
+	})
+}
+
+// postmanEnvName replaces the characters not allowed in the environment variable names, like spaces.
+// Names should start with a letter, "v" is prepended otherwise.
+func postmanEnvName(name string) string {
+	name = invalidEnvCharsRegexp.ReplaceAllString(name, "_")
+	if name == "" {
+		return name
+	}
+	if c := name[0]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+		name = "v" + name
+	}
+	return name
+}
+
+// escapeTemplate query escapes the text except the variables in it, they are injected as is.
+func escapeTemplate(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range postmanVariableRegexp.FindAllStringIndex(s, -1) {
+		b.WriteString(url.QueryEscape(s[last:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(url.QueryEscape(s[last:]))
+	return b.String()
+}
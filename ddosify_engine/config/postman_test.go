@@ -0,0 +1,159 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestImportPostman(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("config_testdata/collection.json")
+	if err != nil {
+		t.Fatalf("TestImportPostman error occurred: %v", err)
+	}
+
+	env := []byte(`{"name": "Staging", "values": [{"key": "baseUrl", "value": "https://staging.servdown.com", "enabled": true},
+		{"key": "userId", "value": "42"}, {"key": "password", "value": "123", "enabled": false}]}`)
+	s, err := Import(data, ImportTypePostman, ImportOpts{PostmanEnvironment: env})
+	if err != nil {
+		t.Fatalf("TestImportPostman error occurred: %v", err)
+	}
+
+	expectedEnvs := map[string]interface{}{"baseUrl": "https://staging.servdown.com", "api_token": "secret", "userId": "42"}
+	if !reflect.DeepEqual(s.Envs, expectedEnvs) {
+		t.Errorf("Expected %v, Found: %v", expectedEnvs, s.Envs)
+	}
+
+	expected := []types.ScenarioStep{
+		{
+			ID:      1,
+			Name:    "Accounts / Login",
+			Method:  "POST",
+			URL:     "{{baseUrl}}/accounts/login/",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Auth:    types.Auth{Type: types.AuthHttpBasic, Username: "test", Password: "{{password}}"},
+			Payload: "email={{_randomEmail}}&next=%2Fhome+page",
+			Timeout: types.DefaultTimeout,
+		},
+		{
+			ID:      2,
+			Name:    "Accounts / Avatar",
+			Method:  "PUT",
+			URL:     "{{baseUrl}}/accounts/avatar",
+			Headers: map[string]string{},
+			MultipartStream: []types.MultipartPart{
+				{Name: "name", Value: "avatar"},
+				{Name: "file", FilePath: "config/config_testdata/test.png"},
+			},
+			Timeout: types.DefaultTimeout,
+		},
+		{
+			ID:      3,
+			Name:    "Get User",
+			Method:  "GET",
+			URL:     "{{baseUrl}}/users/{{userId}}?fields=name",
+			Headers: map[string]string{"Accept": "application/json", "Authorization": "Bearer {{api_token}}"},
+			Timeout: types.DefaultTimeout,
+		},
+		{
+			ID:      4,
+			Name:    "Update User",
+			Method:  "PATCH",
+			URL:     "{{baseUrl}}/users/1",
+			Headers: map[string]string{"Content-Type": "application/json", "Authorization": "Bearer {{api_token}}"},
+			Payload: `{"name": "{{_randomFirstName}}"}`,
+			Timeout: types.DefaultTimeout,
+		},
+		{
+			ID:      5,
+			Name:    "Users",
+			Type:    types.StepTypeGraphQL,
+			Method:  "POST",
+			URL:     "{{baseUrl}}/graphql",
+			Headers: map[string]string{"Authorization": "Bearer {{api_token}}"},
+			GraphQL: types.GraphQLConf{Query: "{ users { id } }", Variables: `{"limit": 10}`},
+			Timeout: types.DefaultTimeout,
+		},
+	}
+	if len(s.Steps) != len(expected) {
+		t.Fatalf("Expected %v, Found: %v", len(expected), len(s.Steps))
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(s.Steps[i], expected[i]) {
+			t.Errorf("Expected %v, Found: %v", expected[i], s.Steps[i])
+		}
+	}
+}
+
+func TestImportPostmanInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"InvalidJson", `{"item": `},
+		{"V1Schema", `{"info": {"schema": "https://schema.getpostman.com/json/collection/v1.0.0/collection.json"}}`},
+		{"NoRequests", `{"item": [{"name": "Empty Folder", "item": []}]}`},
+		{"EmptyUrl", `{"item": [{"name": "Req", "request": {"method": "GET", "url": ""}}]}`},
+		{"UnsupportedAuth", `{"auth": {"type": "awsv4"}, "item": [{"name": "Req", "request": "https://test.com"}]}`},
+		{"InvalidEnvironment", `{"item": [{"name": "Req", "request": "https://test.com"}]}`},
+		{"ApiKeyInQuery", `{"item": [{"name": "Req", "request": {"url": "https://test.com", "auth": {"type": "apikey",
+			"apikey": [{"key": "in", "value": "query"}]}}}]}`},
+	}
+
+	for _, test := range tests {
+		opts := ImportOpts{}
+		if test.name == "InvalidEnvironment" {
+			opts.PostmanEnvironment = []byte(`{"values": `)
+		}
+		if _, err := Import([]byte(test.data), ImportTypePostman, opts); err == nil {
+			t.Errorf("%s Expected %v, Found: %v", test.name, "error", err)
+		}
+	}
+}
+
+func TestPostmanTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"{{baseUrl}}/users", "{{baseUrl}}/users"},
+		{"{{ base url }}", "{{base_url}}"},
+		{"{{$guid}}-{{$randomInt}}", "{{_guid}}-{{_randomInt}}"},
+		{"{{1st}}", "{{v1st}}"},
+		{"no variables", "no variables"},
+	}
+
+	for _, test := range tests {
+		if res := postmanTemplate(test.in); res != test.expected {
+			t.Errorf("Expected %v, Found: %v", test.expected, res)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// ParseTargetList returns the weighted targets of the URL list, one URL per line. Empty lines and the lines
+// starting with # are skipped. Targets are named by their URLs in the order of their first lines, and weighted
+// by the number of their lines.
+func ParseTargetList(data []byte) ([]types.WeightedTarget, error) {
+	var targets []types.WeightedTarget
+	index := make(map[string]int)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		u := strings.TrimSpace(scanner.Text())
+		if u == "" || strings.HasPrefix(u, "#") {
+			continue
+		}
+		if i, ok := index[u]; ok {
+			targets[i].Weight++
+			continue
+		}
+		if err := types.IsTargetValid(u); err != nil {
+			return nil, fmt.Errorf("line %d of the targets file: %v", line, err)
+		}
+		index[u] = len(targets)
+		targets = append(targets, types.WeightedTarget{Name: u, URL: u, Weight: 1})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("targets file has no urls")
+	}
+	return targets, nil
+}
@@ -0,0 +1,72 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestParseTargetList(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		data      string
+		shouldErr bool
+		expected  []types.WeightedTarget
+	}{
+		{"Valid", "https://test.com/a\n\n# comment\n  https://test.com/b  \nhttps://test.com/a\n", false,
+			[]types.WeightedTarget{
+				{Name: "https://test.com/a", URL: "https://test.com/a", Weight: 2},
+				{Name: "https://test.com/b", URL: "https://test.com/b", Weight: 1},
+			}},
+		{"CRLF", "https://test.com/a\r\nhttps://test.com/b\r\n", false,
+			[]types.WeightedTarget{
+				{Name: "https://test.com/a", URL: "https://test.com/a", Weight: 1},
+				{Name: "https://test.com/b", URL: "https://test.com/b", Weight: 1},
+			}},
+		{"InvalidURL", "https://test.com/a\nasds.x.x.x\n", true, nil},
+		{"Empty", "\n# comment\n", true, nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			targets, err := ParseTargetList([]byte(test.data))
+			if test.shouldErr {
+				if err == nil {
+					t.Errorf("Should be errored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Errored: %v", err)
+			}
+			if !reflect.DeepEqual(test.expected, targets) {
+				t.Errorf("Expected %v, Found: %v", test.expected, targets)
+			}
+		})
+	}
+}
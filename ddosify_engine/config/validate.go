@@ -0,0 +1,300 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// ValidationError is a problem of the config at the json path of the field like "steps[0].url".
+// Path is empty if the problem is not about a single field.
+type ValidationError struct {
+	Path   string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Reason
+	}
+	return e.Path + ": " + e.Reason
+}
+
+// ValidationErrors are all the problems found by Validate.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return "invalid config, " + e[0].Error()
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "invalid config, %d problems:", len(e))
+	for _, v := range e {
+		sb.WriteString("\n  ")
+		sb.WriteString(v.Error())
+	}
+	return sb.String()
+}
+
+// Validate checks the json config before the test, so that all the problems are reported at once instead of
+// the first one, or none if the config is silently misread. Unknown fields, the options that can not be used
+// together and the invalid steps like the undefined variables are reported by their paths.
+// Includes should be resolved, like the output of ReadConfigFile.
+func Validate(config []byte) error {
+	if !json.Valid(config) {
+		return fmt.Errorf("provided json is invalid")
+	}
+	var raw interface{}
+	if err := json.Unmarshal(config, &raw); err != nil {
+		return err
+	}
+
+	errs := unknownFields(raw, reflect.TypeOf(JsonReader{}), "")
+	j := &JsonReader{}
+	if err := j.Init(config); err != nil {
+		return append(errs, decodeError(err))
+	}
+	// unknown fields are ignored by the decoding, the steps are still validated
+	if conflicts := j.conflicts(); len(conflicts) > 0 {
+		return append(errs, conflicts...)
+	}
+
+	h, err := j.CreateHammer()
+	if err != nil {
+		return append(errs, ValidationError{Reason: err.Error()})
+	}
+	stepErrs, err := h.ValidateSteps()
+	if err != nil {
+		return append(errs, ValidationError{Reason: err.Error()})
+	}
+	for i, path := range j.stepPaths() {
+		if err, ok := stepErrs[i]; ok {
+			errs = append(errs, ValidationError{Path: path, Reason: err.Error()})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// conflicts returns the options that can not be used together and the bounds that are out of order. The documented
+// overrides like manual_load filling the duration and the iteration_count are not conflicts.
+func (j *JsonReader) conflicts() (errs ValidationErrors) {
+	add := func(path, reason string, args ...interface{}) {
+		errs = append(errs, ValidationError{Path: path, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if len(j.Steps) == 0 && len(j.Scenarios) == 0 {
+		add("steps", "at least one step is required")
+	}
+	if len(j.Steps) > 0 && len(j.Scenarios) > 0 {
+		add("scenarios", "can not be used with steps, define the steps under the scenarios")
+	}
+	if j.UserQuota != nil && (j.durationGiven || j.IterCount != nil || j.ReqCount != nil) {
+		add("user_quota", "can not be used with duration and iteration_count")
+	}
+	if a := j.Adaptive; a != nil && a.MinUsers > a.MaxUsers {
+		add("adaptive.min_users", "should be less than or equal to max_users (%d), got %d", a.MaxUsers, a.MinUsers)
+	}
+	if t := j.Transport; t.MaxIdleConns > 0 && t.MaxIdleConnsPerHost > t.MaxIdleConns {
+		add("transport.max_idle_conns_per_host", "should be less than or equal to max_idle_conns (%d), got %d",
+			t.MaxIdleConns, t.MaxIdleConnsPerHost)
+	}
+	if j.StickyUsers > 0 && j.EngineMode != types.EngineModeRepeatedUser {
+		add("sticky_users", "can only be used in repeated-user engine mode, engine_mode is %s", j.EngineMode)
+	}
+	if j.Cookies.Enabled && j.EngineMode == types.EngineModeDdosify {
+		add("cookie_jar.enabled", "cookies are not supported in ddosify engine mode, use distinct-user or repeated-user mode")
+	}
+	return errs
+}
+
+// stepPaths returns the paths of the steps in the order of the AllSteps of the hammer scenario, followed by the
+// setup and the teardown steps.
+func (j *JsonReader) stepPaths() []string {
+	paths := make([]string, 0, len(j.Steps))
+	for i := range j.Steps {
+		paths = append(paths, fmt.Sprintf("steps[%d]", i))
+	}
+	for i, ws := range j.Scenarios {
+		for k := range ws.Steps {
+			paths = append(paths, fmt.Sprintf("scenarios[%d].steps[%d]", i, k))
+		}
+	}
+	for i := range j.Cleanup {
+		paths = append(paths, fmt.Sprintf("cleanup[%d]", i))
+	}
+	for i := range j.Setup {
+		paths = append(paths, fmt.Sprintf("setup[%d]", i))
+	}
+	for i := range j.Teardown {
+		paths = append(paths, fmt.Sprintf("teardown[%d]", i))
+	}
+	return paths
+}
+
+// decodeError returns the path of the mistyped field if err is a type error of the json decoding.
+func decodeError(err error) ValidationError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return ValidationError{
+			Path:   typeErr.Field,
+			Reason: fmt.Sprintf("should be %s, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+	return ValidationError{Reason: err.Error()}
+}
+
+// unknownFields returns the keys of the json objects in v that are not the fields of the config type t, they would
+// be ignored by the decoding. Types without json fields are decoded by their own UnmarshalJSON, they are not checked.
+func unknownFields(v interface{}, t reflect.Type, path string) (errs ValidationErrors) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			if len(fields) == 0 {
+				return nil
+			}
+			for _, k := range keys {
+				ft, ok := lookupField(fields, k)
+				if !ok {
+					errs = append(errs, ValidationError{Path: joinPath(path, k), Reason: unknownFieldReason(k, fields)})
+					continue
+				}
+				errs = append(errs, unknownFields(val[k], ft, joinPath(path, k))...)
+			}
+		case reflect.Map:
+			for _, k := range keys {
+				errs = append(errs, unknownFields(val[k], t.Elem(), joinPath(path, k))...)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, e := range val {
+				errs = append(errs, unknownFields(e, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+// jsonFields returns the types of the exported fields of the struct by their json names.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupField finds the field of the key, case insensitive like the json decoding.
+func lookupField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func unknownFieldReason(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3 // suggest only the close ones
+	for name := range fields {
+		if d := editDistance(strings.ToLower(key), name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return "unknown field"
+	}
+	return fmt.Sprintf("unknown field, did you mean %s?", best)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// editDistance is the levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for k := range prev {
+		prev[k] = k
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for k := 1; k <= len(b); k++ {
+			cost := 1
+			if a[i-1] == b[k-1] {
+				cost = 0
+			}
+			cur[k] = min3(prev[k]+1, cur[k-1]+1, prev[k-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
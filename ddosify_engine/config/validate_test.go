@@ -0,0 +1,155 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		config   string
+		expected ValidationErrors
+	}{
+		{
+			name:   "Valid",
+			config: `{"Duration": 10, "env": {"HOST": "test.com"}, "steps": [{"id": 1, "url": "https://{{HOST}}"}]}`,
+		},
+		{
+			name: "UnknownFields",
+			config: `{"duraton": 10, "comment": "x", "steps": [{"id": 1, "url": "https://test.com", "methd": "GET",
+				"capture_env": {"TOKEN": {"from": "body", "json_path": "token", "regex": "x"}}}]}`,
+			expected: ValidationErrors{
+				{Path: "comment", Reason: "unknown field"},
+				{Path: "duraton", Reason: "unknown field, did you mean duration?"},
+				{Path: "steps[0].capture_env.TOKEN.regex", Reason: "unknown field, did you mean regexp?"},
+				{Path: "steps[0].methd", Reason: "unknown field, did you mean method?"},
+			},
+		},
+		{
+			name:     "Type",
+			config:   `{"duration": "10", "steps": [{"id": 1, "url": "https://test.com"}]}`,
+			expected: ValidationErrors{{Path: "duration", Reason: "should be int, got string"}},
+		},
+		{
+			name: "Conflicts",
+			config: `{"steps": [{"id": 1, "url": "https://test.com"}],
+				"scenarios": [{"name": "a", "steps": [{"id": 2, "url": "https://test.com"}]}],
+				"adaptive": {"min_users": 10, "max_users": 5},
+				"transport": {"max_idle_conns": 10, "max_idle_conns_per_host": 20}}`,
+			expected: ValidationErrors{
+				{Path: "scenarios", Reason: "can not be used with steps, define the steps under the scenarios"},
+				{Path: "adaptive.min_users", Reason: "should be less than or equal to max_users (5), got 10"},
+				{Path: "transport.max_idle_conns_per_host", Reason: "should be less than or equal to max_idle_conns (10), got 20"},
+			},
+		},
+		{
+			name:     "NoSteps",
+			config:   `{"duration": 10}`,
+			expected: ValidationErrors{{Path: "steps", Reason: "at least one step is required"}},
+		},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := Validate([]byte(tf.config))
+			if tf.expected == nil {
+				if err != nil {
+					t.Errorf("Expected %v, Found: %v", nil, err)
+				}
+				return
+			}
+			var errs ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Expected %v, Found: %v", tf.expected, err)
+			}
+			if !reflect.DeepEqual(errs, tf.expected) {
+				t.Errorf("Expected %v, Found: %v", tf.expected, errs)
+			}
+		})
+	}
+}
+
+func TestValidateSteps(t *testing.T) {
+	t.Parallel()
+
+	// all the invalid steps are reported, captured envs are defined for the following steps
+	config := `{"scenarios": [
+		{"name": "a", "steps": [{"id": 1, "url": "https://test.com/{{USER}}"}]},
+		{"name": "b", "steps": [
+			{"id": 2, "url": "https://test.com", "capture_env": {"TOKEN": {"from": "body", "json_path": "token"}}},
+			{"id": 3, "url": "https://test.com", "headers": {"Authorization": "{{TOKEN}}"}},
+			{"id": 4, "url": "https://test.com", "payload": "{{MISSING}}"}
+		]}
+	]}`
+	var errs ValidationErrors
+	if !errors.As(Validate([]byte(config)), &errs) {
+		t.Fatalf("Expected %v, Found: %v", "validation errors", errs)
+	}
+
+	expectedPaths := []string{"scenarios[0].steps[0]", "scenarios[1].steps[2]"}
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Expected %v, Found: %v", expectedPaths, paths)
+	}
+	if !strings.Contains(errs[0].Reason, "{{USER}}") || !strings.Contains(errs[1].Reason, "{{MISSING}}") {
+		t.Errorf("Expected %v, Found: %v", "undefined variables", errs)
+	}
+	if msg := errs.Error(); !strings.HasPrefix(msg, "invalid config, 2 problems:\n  scenarios[0].steps[0]: ") {
+		t.Errorf("Expected %v, Found: %v", "invalid config, 2 problems:...", msg)
+	}
+
+	// cleanup steps are reported after the steps
+	errs = nil
+	config = `{"steps": [{"id": 1, "url": "https://test.com"}],
+		"cleanup": [{"id": 2, "url": "https://test.com/{{ID}}"}]}`
+	if !errors.As(Validate([]byte(config)), &errs) || len(errs) != 1 || errs[0].Path != "cleanup[0]" {
+		t.Errorf("Expected %v, Found: %v", "cleanup[0]", errs)
+	}
+
+	// setup and teardown steps are reported after the cleanup steps
+	errs = nil
+	config = `{"steps": [{"id": 1, "url": "https://test.com"}],
+		"setup": [{"id": 2, "url": "https://test.com/{{ID}}"}], "teardown": [{"id": 3, "url": "https://test.com/{{ID}}"}]}`
+	if !errors.As(Validate([]byte(config)), &errs) || len(errs) != 2 || errs[0].Path != "setup[0]" ||
+		errs[1].Path != "teardown[0]" {
+		t.Errorf("Expected %v, Found: %v", []string{"setup[0]", "teardown[0]"}, errs)
+	}
+
+	// unknown fields don't stop the validation of the steps
+	errs = nil
+	config = `{"duraton": 10, "steps": [{"id": 1, "url": "https://test.com/{{USER}}"}]}`
+	if !errors.As(Validate([]byte(config)), &errs) || len(errs) != 2 || errs[1].Path != "steps[0]" {
+		t.Errorf("Expected %v, Found: %v", "duraton and steps[0]", errs)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// adaptiveController adjusts the concurrent users of the adaptive load by the requests completed in each interval.
+type adaptiveController struct {
+	conf types.AdaptiveLoad
+
+	mu      sync.Mutex
+	samples []requestSample // completed in the current interval
+
+	users   int
+	peak    int // max users reached
+	ceiling int // users are not increased beyond it, lowered on a breach
+
+	// first breach of the thresholds, zero users if the thresholds are not breached
+	breach adaptiveBreach
+}
+
+type requestSample struct {
+	failed   bool
+	duration time.Duration
+}
+
+type adaptiveBreach struct {
+	users     int
+	elapsed   time.Duration
+	p95       time.Duration
+	errorRate float64
+}
+
+func newAdaptiveController(conf types.AdaptiveLoad) *adaptiveController {
+	return &adaptiveController{
+		conf:    conf,
+		users:   conf.MinUsers,
+		peak:    conf.MinUsers,
+		ceiling: conf.MaxUsers,
+	}
+}
+
+// observe adds the requests of the completed iteration to the current interval.
+func (c *adaptiveController) observe(r *types.ScenarioResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, sr := range r.StepResults {
+		if sr.Skipped {
+			continue
+		}
+		c.samples = append(c.samples, requestSample{
+			failed:   sr.Err.Type != "" || len(sr.FailedAssertions) > 0 || len(sr.SchemaErrors) > 0,
+			duration: sr.Duration,
+		})
+	}
+}
+
+// adjust evaluates the thresholds on the requests of the ended interval and returns the users of the next one.
+// Users are held if no request is completed in the interval.
+func (c *adaptiveController) adjust(elapsed time.Duration) int {
+	c.mu.Lock()
+	samples := c.samples
+	c.samples = nil
+	c.mu.Unlock()
+
+	if len(samples) == 0 {
+		return c.users
+	}
+
+	p95, errorRate := intervalStats(samples)
+	breached := (c.conf.MaxP95 > 0 && p95 > c.conf.MaxP95) ||
+		(c.conf.MaxErrorRate > 0 && errorRate > c.conf.MaxErrorRate)
+
+	if breached {
+		if c.breach.users == 0 {
+			c.breach = adaptiveBreach{users: c.users, elapsed: elapsed, p95: p95, errorRate: errorRate}
+		}
+		if c.conf.OnBreach == types.AdaptiveOnBreachBackOff && c.users-c.conf.Step >= c.conf.MinUsers {
+			c.users -= c.conf.Step
+		}
+		c.ceiling = c.users
+		return c.users
+	}
+
+	c.users += c.conf.Step
+	if c.users > c.ceiling {
+		c.users = c.ceiling
+	}
+	if c.users > c.peak {
+		c.peak = c.users
+	}
+	return c.users
+}
+
+// summary reports the users at which the thresholds are first breached.
+func (c *adaptiveController) summary() string {
+	if c.breach.users == 0 {
+		return fmt.Sprintf("thresholds are not crossed up to %d users", c.peak)
+	}
+	return fmt.Sprintf("thresholds are first crossed at %d users after %v (p95 %v, error rate %.2f%%), ended with %d users",
+		c.breach.users, c.breach.elapsed.Round(time.Second), c.breach.p95.Round(time.Millisecond),
+		c.breach.errorRate*100, c.users)
+}
+
+// intervalStats returns the nearest rank p95 latency and the error rate of the samples.
+func intervalStats(samples []requestSample) (p95 time.Duration, errorRate float64) {
+	durations := make([]time.Duration, len(samples))
+	var failed int
+	for i, s := range samples {
+		durations[i] = s.duration
+		if s.failed {
+			failed++
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	rank := int(math.Ceil(0.95*float64(len(durations)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return durations[rank], float64(failed) / float64(len(samples))
+}
+
+// runAdaptive runs the virtual users of the adaptive load until the end of the test duration,
+// adjusting their number at each interval.
+func (e *engine) runAdaptive(stopChan <-chan struct{}) string {
+	var users []chan struct{} // stop channels of the running users
+	setUsers := func(n int) {
+		for len(users) < n {
+			stop := make(chan struct{})
+			users = append(users, stop)
+			e.wg.Add(1)
+			go e.runUser(stop)
+		}
+		for len(users) > n {
+			close(users[len(users)-1])
+			users = users[:len(users)-1]
+		}
+	}
+	defer setUsers(0)
+
+	start := time.Now()
+	end := time.NewTimer(time.Duration(e.hammer.TestDuration) * time.Second)
+	defer end.Stop()
+	ticker := time.NewTicker(e.hammer.Adaptive.Interval)
+	defer ticker.Stop()
+
+	setUsers(e.adaptive.users)
+	for {
+		select {
+		case <-e.ctx.Done():
+			return resultStopped
+		case <-e.abortChan:
+			e.testSuccess = false
+			return resultAborted
+		case <-stopChan:
+			return resultAborted
+		case <-e.maxRequestsChan:
+			return resultDone
+		case <-end.C:
+			return resultDone
+		case now := <-ticker.C:
+			setUsers(e.adaptive.adjust(now.Sub(start)))
+		}
+	}
+}
+
+// runUser runs the iterations of a virtual user back to back until stop is closed.
+func (e *engine) runUser(stop <-chan struct{}) {
+	defer e.wg.Done()
+	u, ok := e.userClient()
+	if !ok {
+		return
+	}
+	defer e.scenarioService.ReleaseUserClient(u)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-e.ctx.Done():
+			return
+		default:
+		}
+		e.iterationStarted()
+		start := time.Now()
+		e.runWorker(start, u, e.inRampDown(start))
+		atomic.AddInt64(&e.activeIterations, -1)
+	}
+}
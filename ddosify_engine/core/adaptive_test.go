@@ -0,0 +1,140 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func adaptiveResult(d time.Duration, failed bool) *types.ScenarioResult {
+	sr := &types.ScenarioStepResult{Duration: d}
+	if failed {
+		sr.Err = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}
+	}
+	return &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}
+}
+
+func TestAdaptiveControllerAdjust(t *testing.T) {
+	t.Parallel()
+
+	fast, slow := 10*time.Millisecond, time.Second
+	tests := []struct {
+		name      string
+		onBreach  string
+		intervals []time.Duration // latency of the requests of each interval, zero if no request is completed
+		expected  []int
+		breachAt  int
+	}{
+		{"Increase", types.AdaptiveOnBreachHold, []time.Duration{fast, fast, fast, fast}, []int{4, 6, 7, 7}, 0},
+		{"NoRequest", types.AdaptiveOnBreachHold, []time.Duration{0, fast}, []int{2, 4}, 0},
+		{"Hold", types.AdaptiveOnBreachHold, []time.Duration{fast, slow, fast, fast}, []int{4, 4, 4, 4}, 4},
+		{"BackOff", types.AdaptiveOnBreachBackOff, []time.Duration{fast, fast, slow, fast, slow, slow},
+			[]int{4, 6, 4, 4, 2, 2}, 6},
+	}
+
+	for _, test := range tests {
+		c := newAdaptiveController(types.AdaptiveLoad{
+			MinUsers: 2, MaxUsers: 7, Step: 2, Interval: time.Second, MaxP95: 100 * time.Millisecond, OnBreach: test.onBreach,
+		})
+
+		var users []int
+		for i, d := range test.intervals {
+			if d > 0 {
+				for j := 0; j < 10; j++ {
+					c.observe(adaptiveResult(d, false))
+				}
+			}
+			users = append(users, c.adjust(time.Duration(i+1)*time.Second))
+		}
+		if !reflect.DeepEqual(users, test.expected) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expected, users)
+		}
+		if c.breach.users != test.breachAt {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.breachAt, c.breach.users)
+		}
+	}
+}
+
+func TestAdaptiveControllerErrorRate(t *testing.T) {
+	t.Parallel()
+
+	c := newAdaptiveController(types.AdaptiveLoad{
+		MinUsers: 1, MaxUsers: 10, Step: 1, Interval: time.Second, MaxErrorRate: 0.1, OnBreach: types.AdaptiveOnBreachHold,
+	})
+	for i := 0; i < 10; i++ {
+		c.observe(adaptiveResult(time.Millisecond, i < 2)) // 20% failed
+	}
+	if users := c.adjust(time.Second); users != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, users)
+	}
+	expected := "thresholds are first crossed at 1 users after 1s (p95 1ms, error rate 20.00%), ended with 1 users"
+	if s := c.summary(); s != expected {
+		t.Errorf("Expected %v, Found: %v", expected, s)
+	}
+}
+
+func TestAdaptiveLoadFindsCapacity(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.TestDuration = 2
+	h.Scenario.Steps[0].URL = server.URL
+	h.Adaptive = &types.AdaptiveLoad{
+		MinUsers: 2, MaxUsers: 10, Step: 2, Interval: time.Second, MaxP95: 10 * time.Millisecond,
+		OnBreach: types.AdaptiveOnBreachHold,
+	}
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestAdaptiveLoadFindsCapacity error occurred %v", err)
+	}
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestAdaptiveLoadFindsCapacity error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestAdaptiveLoadFindsCapacity error occurred %v", err)
+	}
+
+	start := time.Now()
+	if r := e.Start(); r != resultDone {
+		t.Errorf("Expected %v, Found: %v", resultDone, r)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Errorf("Expected the test to end after its duration, Found: %v", elapsed)
+	}
+	if r := e.AdaptiveResult(); !strings.HasPrefix(r, "thresholds are first crossed at 2 users") {
+		t.Errorf("Expected %v, Found: %v", "first crossed at 2 users", r)
+	}
+}
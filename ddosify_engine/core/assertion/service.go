@@ -1,7 +1,10 @@
 package assertion
 
 import (
+	"net/http"
+	"regexp"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +16,9 @@ import (
 
 var tickerInterval = 100 // interval in millisecond
 
+// headerRateRgx finds the headers referenced by the header_rate funcs of the rules, only their values are counted
+var headerRateRgx = regexp.MustCompile(`header_rate\(\s*"([^"]+)"`)
+
 type DefaultAssertionService struct {
 	assertions map[string]types.TestAssertionOpt // Rule -> Opts
 	abortChan  chan struct{}
@@ -46,6 +52,14 @@ func (as *DefaultAssertionService) Init(assertions map[string]types.TestAssertio
 	as.resChan = make(chan TestAssertionResult, 1)
 	totalTime := make([]int64, 0)
 	as.assertEnv = &evaluator.AssertEnv{TotalTime: totalTime}
+	for rule := range assertions {
+		for _, m := range headerRateRgx.FindAllStringSubmatch(rule, -1) {
+			if as.assertEnv.HeaderValues == nil {
+				as.assertEnv.HeaderValues = make(map[string]map[string]int64)
+			}
+			as.assertEnv.HeaderValues[http.CanonicalHeaderKey(m[1])] = make(map[string]int64)
+		}
+	}
 	as.abortTick = make(map[string]int)
 	as.mu = sync.Mutex{}
 	return as.abortChan
@@ -82,9 +96,14 @@ func (as *DefaultAssertionService) aggregate(r *types.ScenarioResult) {
 	as.iterCount++
 	for _, sr := range r.StepResults {
 		iterationTime += sr.Duration.Milliseconds()
-		if sr.Err.Type != "" || len(sr.FailedAssertions) > 0 {
+		if sr.Err.Type != "" || len(sr.FailedAssertions) > 0 || len(sr.SchemaErrors) > 0 {
 			iterFailed = true
 		}
+		for header, counts := range as.assertEnv.HeaderValues {
+			if values := sr.RespHeaders.Values(header); len(values) > 0 {
+				counts[strings.ToLower(strings.TrimSpace(values[0]))]++
+			}
+		}
 	}
 	if iterFailed {
 		as.assertEnv.FailCount++
@@ -111,8 +130,15 @@ func (as *DefaultAssertionService) applyAssertions() {
 		var totalTime []int64
 		totalTime = append(totalTime, as.assertEnv.TotalTime...)
 		assertEnv := evaluator.AssertEnv{
-			TotalTime: totalTime,
-			FailCount: as.assertEnv.FailCount,
+			TotalTime:    totalTime,
+			FailCount:    as.assertEnv.FailCount,
+			HeaderValues: make(map[string]map[string]int64, len(as.assertEnv.HeaderValues)),
+		}
+		for header, counts := range as.assertEnv.HeaderValues {
+			assertEnv.HeaderValues[header] = make(map[string]int64, len(counts))
+			for v, c := range counts {
+				assertEnv.HeaderValues[header][v] = c
+			}
 		}
 		as.mu.Unlock()
 
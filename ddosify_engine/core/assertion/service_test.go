@@ -1,6 +1,7 @@
 package assertion
 
 import (
+	"net/http"
 	"reflect"
 	"sort"
 	"sync"
@@ -147,3 +148,39 @@ type SortableInt64Slice []int64
 func (a SortableInt64Slice) Len() int           { return len(a) }
 func (a SortableInt64Slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a SortableInt64Slice) Less(i, j int) bool { return a[i] < a[j] }
+
+func TestServiceHeaderRate(t *testing.T) {
+	service := NewDefaultAssertionService()
+	assertions := map[string]types.TestAssertionOpt{
+		`header_rate("x-cache", "HIT") >= 0.75`: {},
+		`header_rate("X-Cache", "miss") > 0.5`:  {},
+		`header_rate("Cache-Control", "x") > 0`: {},
+	}
+	_ = service.Init(assertions)
+
+	inputChan := make(chan *types.ScenarioResult)
+	go service.Start(inputChan)
+
+	for _, xCache := range []string{"HIT", "hit", "MISS", "", "HIT"} {
+		header := http.Header{}
+		if xCache != "" {
+			header.Set("X-Cache", xCache)
+		}
+		inputChan <- &types.ScenarioResult{
+			StepResults: []*types.ScenarioStepResult{{StepID: 1, RespHeaders: header}},
+		}
+	}
+	close(inputChan)
+	result := <-service.ResultChan()
+	<-service.DoneChan()
+
+	failed := make([]string, 0)
+	for _, r := range result.FailedRules {
+		failed = append(failed, r.Rule)
+	}
+	sort.Strings(failed)
+	expected := []string{`header_rate("Cache-Control", "x") > 0`, `header_rate("X-Cache", "miss") > 0.5`}
+	if !reflect.DeepEqual(failed, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, failed)
+	}
+}
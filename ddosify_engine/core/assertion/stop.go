@@ -0,0 +1,210 @@
+package assertion
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+var stopCheckInterval = 500 * time.Millisecond
+
+// StopWatcher aborts the test when one of the stop conditions is met,
+// conditions are evaluated on the iterations completed in their windows.
+type StopWatcher struct {
+	conditions []types.StopCondition
+	window     time.Duration // longest window of the conditions
+	start      time.Time
+
+	mu      sync.Mutex
+	samples []iterationSample // in completion order
+
+	abortChan chan struct{}
+	doneChan  chan struct{}
+	finished  chan struct{}
+
+	// met condition and its received value, set before the abortChan is closed
+	met   types.StopCondition
+	value float64
+}
+
+type iterationSample struct {
+	at       time.Time
+	failed   bool
+	duration time.Duration
+}
+
+func NewStopWatcher(conditions []types.StopCondition) *StopWatcher {
+	w := &StopWatcher{
+		conditions: conditions,
+		abortChan:  make(chan struct{}),
+		doneChan:   make(chan struct{}),
+		finished:   make(chan struct{}),
+	}
+	for _, c := range conditions {
+		if c.Window > w.window {
+			w.window = c.Window
+		}
+	}
+	return w
+}
+
+// Observe adds the result of the completed iteration to the window.
+func (w *StopWatcher) Observe(r *types.ScenarioResult) {
+	s := iterationSample{at: time.Now()}
+	for _, sr := range r.StepResults {
+		if sr.Skipped {
+			continue
+		}
+		s.duration += sr.Duration
+		if sr.Err.Type != "" || len(sr.FailedAssertions) > 0 || len(sr.SchemaErrors) > 0 {
+			s.failed = true
+		}
+	}
+
+	w.mu.Lock()
+	w.samples = append(w.samples, s)
+	w.mu.Unlock()
+}
+
+// Start evaluates the conditions periodically until one of them is met or Done is called.
+func (w *StopWatcher) Start() {
+	defer close(w.finished)
+	w.start = time.Now()
+	ticker := time.NewTicker(stopCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.doneChan:
+			return
+		case now := <-ticker.C:
+			if c, v, met := w.check(now); met {
+				w.met, w.value = c, v
+				close(w.abortChan)
+				return
+			}
+		}
+	}
+}
+
+// AbortChan is closed when one of the conditions is met.
+func (w *StopWatcher) AbortChan() <-chan struct{} {
+	return w.abortChan
+}
+
+// Reason returns the met condition and its received value, empty if the test isn't stopped by the watcher.
+func (w *StopWatcher) Reason() string {
+	if !w.aborted() {
+		return ""
+	}
+	return fmt.Sprintf("%s, received %s", w.met.Expr, w.met.Format(w.value))
+}
+
+// Result adds the met condition to the given test-wide assertion result as a failed rule.
+// Should be called after the evaluation is finished, see DoneChan.
+func (w *StopWatcher) Result(r TestAssertionResult) TestAssertionResult {
+	if !w.aborted() {
+		return r
+	}
+	r.Fail = true
+	r.Aborted = true
+	r.FailedRules = append(r.FailedRules, FailedRule{
+		Rule:        w.met.Expr,
+		ReceivedMap: map[string]interface{}{w.met.Metric: w.met.Format(w.value)},
+	})
+	return r
+}
+
+// Done stops the evaluation of the conditions.
+func (w *StopWatcher) Done() {
+	select {
+	case <-w.abortChan:
+	default:
+		close(w.doneChan)
+	}
+}
+
+// DoneChan is closed when the evaluation of the conditions is finished, by Done or a met condition.
+func (w *StopWatcher) DoneChan() <-chan struct{} {
+	return w.finished
+}
+
+func (w *StopWatcher) aborted() bool {
+	select {
+	case <-w.abortChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *StopWatcher) check(now time.Time) (types.StopCondition, float64, bool) {
+	w.mu.Lock()
+	// drop the samples out of the longest window
+	i := sort.Search(len(w.samples), func(i int) bool { return !w.samples[i].at.Before(now.Add(-w.window)) })
+	w.samples = w.samples[i:]
+	samples := make([]iterationSample, len(w.samples))
+	copy(samples, w.samples)
+	w.mu.Unlock()
+
+	for _, c := range w.conditions {
+		if now.Sub(w.start) < c.Window {
+			continue // window is not full yet
+		}
+		i := sort.Search(len(samples), func(i int) bool { return !samples[i].at.Before(now.Add(-c.Window)) })
+		value, ok := metricValue(c.Metric, samples[i:])
+		if ok && c.Met(value) {
+			return c, value, true
+		}
+	}
+	return types.StopCondition{}, 0, false
+}
+
+// metricValue calculates the metric on the samples, ok is false if there is no sample.
+func metricValue(metric string, samples []iterationSample) (value float64, ok bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	if metric == types.StopMetricErrorRate {
+		var failed int
+		for _, s := range samples {
+			if s.failed {
+				failed++
+			}
+		}
+		return float64(failed) / float64(len(samples)), true
+	}
+
+	durations := make([]float64, len(samples))
+	var total float64
+	for i, s := range samples {
+		durations[i] = s.duration.Seconds()
+		total += durations[i]
+	}
+	if metric == types.StopMetricAvg {
+		return total / float64(len(durations)), true
+	}
+
+	sort.Float64s(durations)
+	var p float64
+	switch metric {
+	case types.StopMetricP50:
+		p = 0.50
+	case types.StopMetricP90:
+		p = 0.90
+	case types.StopMetricP95:
+		p = 0.95
+	case types.StopMetricP99:
+		p = 0.99
+	}
+	// nearest rank
+	rank := int(math.Ceil(p*float64(len(durations)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return durations[rank], true
+}
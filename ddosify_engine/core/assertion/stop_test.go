@@ -0,0 +1,136 @@
+package assertion
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func newTestStopWatcher(t *testing.T, exprs ...string) *StopWatcher {
+	conditions, err := types.ParseStopConditions(exprs)
+	if err != nil {
+		t.Fatalf("newTestStopWatcher error occurred: %v", err)
+	}
+	return NewStopWatcher(conditions)
+}
+
+func TestStopWatcherErrorRate(t *testing.T) {
+	t.Parallel()
+
+	w := newTestStopWatcher(t, "error_rate > 50% over 10s")
+	now := time.Now()
+	w.start = now.Add(-time.Minute)
+	w.samples = []iterationSample{
+		// out of the window
+		{at: now.Add(-15 * time.Second), failed: false},
+		{at: now.Add(-12 * time.Second), failed: false},
+		{at: now.Add(-12 * time.Second), failed: false},
+
+		{at: now.Add(-5 * time.Second), failed: true},
+		{at: now.Add(-3 * time.Second), failed: true},
+		{at: now.Add(-1 * time.Second), failed: false},
+	}
+
+	c, v, met := w.check(now)
+	if !met || c.Expr != "error_rate > 50% over 10s" || c.Format(v) != "66.7%" {
+		t.Errorf("Expected %v, Found: %v", "error_rate > 50% over 10s", c.Expr)
+	}
+	if len(w.samples) != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, len(w.samples))
+	}
+}
+
+func TestStopWatcherWindowNotFull(t *testing.T) {
+	t.Parallel()
+
+	w := newTestStopWatcher(t, "error_rate > 50% over 10s")
+	now := time.Now()
+	w.start = now.Add(-5 * time.Second)
+	w.samples = []iterationSample{{at: now, failed: true}}
+
+	if _, _, met := w.check(now); met {
+		t.Errorf("Expected %v, Found: %v", false, met)
+	}
+
+	// no results in the window
+	w.start = now.Add(-time.Minute)
+	w.samples = nil
+	if _, _, met := w.check(now); met {
+		t.Errorf("Expected %v, Found: %v", false, met)
+	}
+}
+
+func TestStopWatcherLatency(t *testing.T) {
+	t.Parallel()
+
+	w := newTestStopWatcher(t, "avg > 1s", "p99 > 5s over 5s")
+	now := time.Now()
+	w.start = now.Add(-time.Minute)
+	for i := 0; i < 99; i++ {
+		w.samples = append(w.samples, iterationSample{at: now.Add(-8 * time.Second), duration: 10 * time.Millisecond})
+	}
+	w.samples = append(w.samples, iterationSample{at: now, duration: 6 * time.Second})
+
+	// average of the last 10s is below 1s, only the slow sample is in the 5s window of p99
+	c, v, met := w.check(now)
+	if !met || c.Expr != "p99 > 5s over 5s" || c.Format(v) != "6s" {
+		t.Errorf("Expected %v, Found: %v", "p99 > 5s over 5s", c.Expr)
+	}
+}
+
+func TestStopWatcherObserve(t *testing.T) {
+	t.Parallel()
+
+	w := newTestStopWatcher(t, "error_rate > 0")
+	w.Observe(&types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+		{StepID: 1, Duration: time.Second},
+		{StepID: 2, Duration: time.Second, FailedAssertions: []types.FailedAssertion{{Rule: "false"}}},
+		{StepID: 3, Skipped: true, Duration: time.Second},
+	}})
+	w.Observe(&types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+		{StepID: 1, Duration: time.Second},
+	}})
+
+	if len(w.samples) != 2 || !w.samples[0].failed || w.samples[1].failed {
+		t.Errorf("Expected %v, Found: %v", "first iteration failed", w.samples)
+	}
+	if w.samples[0].duration != 2*time.Second {
+		t.Errorf("Expected %v, Found: %v", 2*time.Second, w.samples[0].duration)
+	}
+}
+
+func TestStopWatcherAborts(t *testing.T) {
+	t.Parallel()
+
+	w := newTestStopWatcher(t, "error_rate > 0 over 100ms")
+	go w.Start()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for aborted := false; !aborted; {
+		select {
+		case <-ticker.C:
+			w.Observe(&types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+				{StepID: 1, Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnRefused}},
+			}})
+		case <-w.AbortChan():
+			aborted = true
+		case <-timeout:
+			t.Fatalf("Expected %v, Found: %v", "abort", "timeout")
+		}
+	}
+	if r := w.Reason(); r != "error_rate > 0 over 100ms, received 100.0%" {
+		t.Errorf("Expected %v, Found: %v", "error_rate > 0 over 100ms, received 100.0%", r)
+	}
+	w.Done()
+	<-w.DoneChan()
+
+	r := w.Result(TestAssertionResult{})
+	expected := []FailedRule{{Rule: "error_rate > 0 over 100ms", ReceivedMap: map[string]interface{}{"error_rate": "100.0%"}}}
+	if !r.Fail || !r.Aborted || !reflect.DeepEqual(r.FailedRules, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, r.FailedRules)
+	}
+}
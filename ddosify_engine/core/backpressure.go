@@ -0,0 +1,115 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// backpressure limits the running iterations by the types.Backpressure of the hammer.
+type backpressure struct {
+	conf types.Backpressure
+
+	// holds a slot for each running iteration
+	slots chan struct{}
+
+	waiting int64 // iterations waiting in the queue
+	dropped int64
+	waited  int64 // iterations queued or blocking the scheduler
+
+	// max wait of the queued iterations, total wait of the blocked scheduler, in nanoseconds
+	maxWait   int64
+	totalWait int64
+}
+
+func newBackpressure(conf types.Backpressure) *backpressure {
+	return &backpressure{conf: conf, slots: make(chan struct{}, conf.MaxInFlight)}
+}
+
+// blocks returns true if the scheduler acquires the slots of the iterations, instead of the iterations themselves.
+func (b *backpressure) blocks() bool {
+	return b.conf.Policy == types.BackpressureBlock
+}
+
+// acquire takes a slot for an iteration by the policy, returns false if the iteration is dropped or ctx is done.
+// The slot should be released by release once the iteration completes.
+func (b *backpressure) acquire(ctx context.Context) bool {
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	switch b.conf.Policy {
+	case types.BackpressureDrop:
+		atomic.AddInt64(&b.dropped, 1)
+		return false
+	case types.BackpressureQueue:
+		if atomic.AddInt64(&b.waiting, 1) > int64(b.conf.QueueSize) {
+			atomic.AddInt64(&b.waiting, -1)
+			atomic.AddInt64(&b.dropped, 1)
+			return false
+		}
+		defer atomic.AddInt64(&b.waiting, -1)
+	}
+
+	atomic.AddInt64(&b.waited, 1)
+	start := time.Now()
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	wait := int64(time.Since(start))
+	atomic.AddInt64(&b.totalWait, wait)
+	for {
+		max := atomic.LoadInt64(&b.maxWait)
+		if wait <= max || atomic.CompareAndSwapInt64(&b.maxWait, max, wait) {
+			return true
+		}
+	}
+}
+
+func (b *backpressure) release() {
+	<-b.slots
+}
+
+// summary returns the active policy and the iterations affected by it.
+func (b *backpressure) summary() string {
+	s := fmt.Sprintf("%s policy at %d in-flight iterations", b.conf.Policy, b.conf.MaxInFlight)
+	dropped, waited := atomic.LoadInt64(&b.dropped), atomic.LoadInt64(&b.waited)
+	switch b.conf.Policy {
+	case types.BackpressureDrop:
+		return fmt.Sprintf("%s, %d iterations are dropped", s, dropped)
+	case types.BackpressureQueue:
+		return fmt.Sprintf("%s, %d iterations are queued for up to %v, %d are dropped over the queue size of %d",
+			s, waited, time.Duration(atomic.LoadInt64(&b.maxWait)).Round(time.Millisecond), dropped,
+			b.conf.QueueSize)
+	default:
+		return fmt.Sprintf("%s, the scheduler is blocked for %v by %d iterations", s,
+			time.Duration(atomic.LoadInt64(&b.totalWait)).Round(time.Millisecond), waited)
+	}
+}
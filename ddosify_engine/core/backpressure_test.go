@@ -0,0 +1,97 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestBackpressureDrop(t *testing.T) {
+	t.Parallel()
+
+	b := newBackpressure(types.Backpressure{Policy: types.BackpressureDrop, MaxInFlight: 2})
+	var acquired int
+	for i := 0; i < 5; i++ {
+		if b.acquire(context.Background()) {
+			acquired++
+		}
+	}
+	if acquired != 2 || b.dropped != 3 {
+		t.Errorf("Expected %v acquired and %v dropped, Found: %v, %v", 2, 3, acquired, b.dropped)
+	}
+
+	// released slots are acquired again
+	b.release()
+	if !b.acquire(context.Background()) {
+		t.Errorf("Expected the released slot to be acquired")
+	}
+	if s := b.summary(); s != "drop policy at 2 in-flight iterations, 3 iterations are dropped" {
+		t.Errorf("Unexpected summary: %s", s)
+	}
+}
+
+func TestBackpressureQueue(t *testing.T) {
+	t.Parallel()
+
+	b := newBackpressure(types.Backpressure{Policy: types.BackpressureQueue, MaxInFlight: 1, QueueSize: 1})
+	b.acquire(context.Background())
+
+	queued := make(chan bool)
+	go func() { queued <- b.acquire(context.Background()) }()
+	for atomic.LoadInt64(&b.waiting) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the queue is full
+	if b.acquire(context.Background()) || b.dropped != 1 {
+		t.Errorf("Expected %v dropped, Found: %v", 1, b.dropped)
+	}
+
+	b.release()
+	if !<-queued {
+		t.Errorf("Expected the queued iteration to acquire the released slot")
+	}
+	if s := b.summary(); !strings.Contains(s, "1 iterations are queued") || !strings.Contains(s, "1 are dropped") {
+		t.Errorf("Unexpected summary: %s", s)
+	}
+}
+
+func TestBackpressureBlockStopped(t *testing.T) {
+	t.Parallel()
+
+	b := newBackpressure(types.Backpressure{Policy: types.BackpressureBlock, MaxInFlight: 1})
+	b.acquire(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if b.acquire(ctx) {
+		t.Errorf("Expected the blocked acquire to be stopped by the ctx")
+	}
+	if b.dropped != 0 || b.waited != 1 {
+		t.Errorf("Expected %v dropped and %v waited, Found: %v, %v", 0, 1, b.dropped, b.waited)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+const (
+	// Max number of the results buffered by a shard of the batcher before they are passed to the report service
+	resultBatchSize = 64
+
+	// Max wait of a buffered result, keeps the live results of the report service up to date at low rates
+	resultFlushInterval = 100 * time.Millisecond
+)
+
+// resultBatcher passes the results of the iterations to the report service in batches. Iterations record their
+// results in the buffers of the shards picked in turn, instead of all of them contending on a channel send per
+// result, and the report service aggregates a batch under a single lock.
+type resultBatcher struct {
+	shards []resultShard
+	next   uint32
+	out    chan<- []*types.ScenarioResult
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+type resultShard struct {
+	mu  sync.Mutex
+	buf []*types.ScenarioResult
+	_   [32]byte // keeps the shards on separate cache lines
+}
+
+// newResultBatcher returns a batcher passing the batches to out, flushed at the resultFlushInterval until it
+// is closed. out should have a room for the full batches of the test, so the iterations are not blocked by it.
+func newResultBatcher(out chan<- []*types.ScenarioResult) *resultBatcher {
+	b := &resultBatcher{
+		shards: make([]resultShard, runtime.GOMAXPROCS(0)),
+		out:    out,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for i := range b.shards {
+		b.shards[i].buf = make([]*types.ScenarioResult, 0, resultBatchSize)
+	}
+	go b.flushPeriodically()
+	return b
+}
+
+// add records the result, it is passed to the report service when the buffer of its shard is full or at the
+// next flush.
+func (b *resultBatcher) add(r *types.ScenarioResult) {
+	s := &b.shards[atomic.AddUint32(&b.next, 1)%uint32(len(b.shards))]
+	s.mu.Lock()
+	s.buf = append(s.buf, r)
+	if len(s.buf) < resultBatchSize {
+		s.mu.Unlock()
+		return
+	}
+	batch := s.buf
+	s.buf = make([]*types.ScenarioResult, 0, resultBatchSize)
+	s.mu.Unlock()
+	b.out <- batch
+}
+
+// flush passes the buffered results of all the shards to the report service.
+func (b *resultBatcher) flush() {
+	for i := range b.shards {
+		s := &b.shards[i]
+		s.mu.Lock()
+		batch := s.buf
+		if len(batch) > 0 {
+			s.buf = make([]*types.ScenarioResult, 0, resultBatchSize)
+		}
+		s.mu.Unlock()
+		if len(batch) > 0 {
+			b.out <- batch
+		}
+	}
+}
+
+func (b *resultBatcher) flushPeriodically() {
+	defer close(b.done)
+	ticker := time.NewTicker(resultFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// close passes the remaining results to the report service, no result should be added after it.
+func (b *resultBatcher) close() {
+	close(b.stop)
+	<-b.done
+	b.flush()
+}
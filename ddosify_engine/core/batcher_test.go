@@ -0,0 +1,119 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"sync"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestResultBatcher(t *testing.T) {
+	t.Parallel()
+
+	out := make(chan []*types.ScenarioResult, 10)
+	var received, batches int
+	consumed := make(chan struct{})
+	go func() {
+		for batch := range out {
+			received += len(batch)
+			batches++
+		}
+		close(consumed)
+	}()
+
+	b := newResultBatcher(out)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 101; j++ {
+				b.add(&types.ScenarioResult{})
+			}
+		}()
+	}
+	wg.Wait()
+	b.close()
+	close(out)
+	<-consumed
+
+	if received != 5050 {
+		t.Errorf("Expected %v, Found: %v", 5050, received)
+	}
+	if batches > 5050/resultBatchSize+len(b.shards)*2 {
+		t.Errorf("Expected at most %v, Found: %v", 5050/resultBatchSize+len(b.shards)*2, batches)
+	}
+}
+
+// BenchmarkRecordResults compares recording the results of the concurrent iterations by a channel send per
+// result, like the report services started by Start, with the batcher of the report.BatchReporter services.
+// Consumers take the lock of the aggregation per result and per batch, like the report services.
+func BenchmarkRecordResults(b *testing.B) {
+	res := &types.ScenarioResult{}
+	var mu sync.Mutex
+	var aggregated int
+
+	b.Run("Channel", func(b *testing.B) {
+		out := make(chan *types.ScenarioResult, 1024)
+		done := make(chan struct{})
+		go func() {
+			for range out {
+				mu.Lock()
+				aggregated++
+				mu.Unlock()
+			}
+			close(done)
+		}()
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				out <- res
+			}
+		})
+		close(out)
+		<-done
+	})
+
+	b.Run("Batched", func(b *testing.B) {
+		out := make(chan []*types.ScenarioResult, 1024)
+		done := make(chan struct{})
+		go func() {
+			for batch := range out {
+				mu.Lock()
+				aggregated += len(batch)
+				mu.Unlock()
+			}
+			close(done)
+		}()
+		batcher := newResultBatcher(out)
+		b.SetParallelism(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				batcher.add(res)
+			}
+		})
+		batcher.close()
+		close(out)
+		<-done
+	})
+}
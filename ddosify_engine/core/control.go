@@ -0,0 +1,170 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// pauseGate holds the new iterations of the test while it is paused. Running iterations are not interrupted,
+// their requests complete and are reported as usual.
+type pauseGate struct {
+	mu       sync.Mutex
+	resumed  chan struct{} // closed while the test is running, replaced on pause
+	pausedAt time.Time     // start of the current pause, zero if the test is running
+	total    time.Duration // total duration of the finished pauses
+}
+
+func newPauseGate() *pauseGate {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &pauseGate{resumed: resumed}
+}
+
+// pause holds the new iterations until resume is called, returns false if the test is already paused.
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.pausedAt.IsZero() {
+		return false
+	}
+	g.pausedAt = time.Now()
+	g.resumed = make(chan struct{})
+	return true
+}
+
+// resume releases the held iterations, returns false if the test is not paused.
+func (g *pauseGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.pausedAt.IsZero() {
+		return false
+	}
+	g.total += time.Since(g.pausedAt)
+	g.pausedAt = time.Time{}
+	close(g.resumed)
+	return true
+}
+
+func (g *pauseGate) paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return !g.pausedAt.IsZero()
+}
+
+// wait blocks while the test is paused, returns false if the ctx is done before it is resumed.
+func (g *pauseGate) wait(ctx context.Context) bool {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pausedDuration returns the total duration of the pauses, including the current one.
+func (g *pauseGate) pausedDuration() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.pausedAt.IsZero() {
+		return g.total
+	}
+	return g.total + time.Since(g.pausedAt)
+}
+
+// controlState is the response of the control API.
+type controlState struct {
+	State          string  `json:"state"`
+	PausedDuration float64 `json:"paused_duration"` // in seconds
+}
+
+// controlServer serves the control API of a running test:
+// POST /pause and POST /resume change the state of the test, GET /status returns it.
+type controlServer struct {
+	addr     string
+	listener net.Listener
+	server   *http.Server
+	gate     *pauseGate
+}
+
+func newControlServer(addr string, gate *pauseGate) *controlServer {
+	c := &controlServer{addr: addr, gate: gate}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pause", c.handle(http.MethodPost, func() { gate.pause() }))
+	mux.HandleFunc("/resume", c.handle(http.MethodPost, func() { gate.resume() }))
+	mux.HandleFunc("/status", c.handle(http.MethodGet, func() {}))
+	c.server = &http.Server{Handler: mux}
+
+	return c
+}
+
+// handle runs the action for the requests of the method and responds with the state after it.
+// Pausing a paused test and resuming a running one are no-ops, so the calls can be retried.
+func (c *controlServer) handle(method string, action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		action()
+
+		s := controlState{State: "running", PausedDuration: c.gate.pausedDuration().Seconds()}
+		if c.gate.paused() {
+			s.State = "paused"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	}
+}
+
+// start binds the listen address and serves the API in background.
+func (c *controlServer) start() error {
+	l, err := net.Listen("tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	c.listener = l
+
+	go c.server.Serve(l)
+	return nil
+}
+
+// listenAddr returns the address the server listens on. Useful when it is started with port 0.
+func (c *controlServer) listenAddr() string {
+	if c.listener == nil {
+		return c.addr
+	}
+	return c.listener.Addr().String()
+}
+
+func (c *controlServer) shutdown(ctx context.Context) error {
+	return c.server.Shutdown(ctx)
+}
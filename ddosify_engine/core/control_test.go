@@ -0,0 +1,119 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPauseGate(t *testing.T) {
+	t.Parallel()
+
+	g := newPauseGate()
+	if !g.wait(context.Background()) || g.paused() {
+		t.Errorf("Expected the gate to be open")
+	}
+	if g.resume() {
+		t.Errorf("Expected %v, Found: %v", false, true)
+	}
+	if !g.pause() || g.pause() {
+		t.Errorf("Expected only the first pause to succeed")
+	}
+
+	waited := make(chan bool)
+	go func() { waited <- g.wait(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-waited:
+		t.Errorf("Expected wait to block while paused")
+	default:
+	}
+
+	if !g.resume() {
+		t.Errorf("Expected %v, Found: %v", true, false)
+	}
+	if ok := <-waited; !ok {
+		t.Errorf("Expected %v, Found: %v", true, ok)
+	}
+	if d := g.pausedDuration(); d < 50*time.Millisecond {
+		t.Errorf("Expected at least %v, Found: %v", 50*time.Millisecond, d)
+	}
+
+	// canceled while paused
+	g.pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if g.wait(ctx) {
+		t.Errorf("Expected %v, Found: %v", false, true)
+	}
+}
+
+func TestControlServer(t *testing.T) {
+	t.Parallel()
+
+	g := newPauseGate()
+	c := newControlServer("127.0.0.1:0", g)
+	if err := c.start(); err != nil {
+		t.Fatalf("TestControlServer start error: %v", err)
+	}
+	defer c.shutdown(context.Background())
+
+	base := "http://" + c.listenAddr()
+	tests := []struct {
+		method        string
+		path          string
+		expectedCode  int
+		expectedState string
+	}{
+		{http.MethodGet, "/status", http.StatusOK, "running"},
+		{http.MethodPost, "/pause", http.StatusOK, "paused"},
+		{http.MethodPost, "/pause", http.StatusOK, "paused"},
+		{http.MethodGet, "/pause", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/status", http.StatusOK, "paused"},
+		{http.MethodPost, "/resume", http.StatusOK, "running"},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, base+test.path, strings.NewReader(""))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("TestControlServer request error: %v", err)
+		}
+		var s controlState
+		json.NewDecoder(resp.Body).Decode(&s)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.expectedCode {
+			t.Errorf("%s %s Expected %v, Found: %v", test.method, test.path, test.expectedCode, resp.StatusCode)
+		}
+		if s.State != test.expectedState {
+			t.Errorf("%s %s Expected %v, Found: %v", test.method, test.path, test.expectedState, s.State)
+		}
+	}
+	if g.paused() {
+		t.Errorf("Expected the test to be resumed")
+	}
+}
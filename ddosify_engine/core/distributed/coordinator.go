@@ -0,0 +1,231 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/report"
+	"go.ddosify.com/ddosify/core/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultListenAddr is the default listen address of the coordinator.
+const DefaultListenAddr = ":7777"
+
+// startDelay is the time between the join of the last worker and the start of the test. Workers initialize their
+// engines in the meantime, so they all start at the same time.
+var startDelay = 3 * time.Second
+
+// Coordinator distributes a test to the workers, starts them at the same time and merges their results.
+type Coordinator struct {
+	config  []byte
+	hammer  types.Hammer
+	workers int
+	now     func() time.Time // clock of the coordinator
+
+	mu      sync.Mutex
+	waiting int           // workers joined and waiting for the others
+	nextID  int           // id of the next job
+	ready   chan struct{} // closed when all the workers joined
+	startAt time.Time
+
+	reported map[int]bool
+	done     chan struct{} // closed when all the workers reported
+	result   *report.Result
+	errs     []string
+}
+
+// NewCoordinator returns a Coordinator that distributes the test in the config to the given number of workers.
+// h is the hammer created from the config, with the overrides of the command line flags applied.
+// The iteration count and the rps of the test are shared by the workers.
+func NewCoordinator(config []byte, h types.Hammer, workers int) (*Coordinator, error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("worker count should be greater than 0")
+	}
+	if h.LoadPattern != nil || len(h.TimeRunCountMap) > 0 || h.Adaptive != nil || h.UserQuota != nil ||
+		h.CertAudit != nil {
+		return nil, fmt.Errorf("load patterns, manual_load, adaptive load, user quota and cert audit are not supported in distributed mode")
+	}
+	if h.IterationCount < workers {
+		return nil, fmt.Errorf("iteration count %d should be greater than or equal to the worker count %d",
+			h.IterationCount, workers)
+	}
+	if h.RPS > 0 && h.RPS < workers {
+		return nil, fmt.Errorf("rps %d should be greater than or equal to the worker count %d", h.RPS, workers)
+	}
+
+	return &Coordinator{
+		config:   config,
+		hammer:   h,
+		workers:  workers,
+		now:      time.Now,
+		ready:    make(chan struct{}),
+		reported: make(map[int]bool, workers),
+		done:     make(chan struct{}),
+		result:   report.NewResult(),
+	}, nil
+}
+
+// Serve accepts the workers on lis until all of them report their results, then returns the merged result.
+// The workers that couldn't run the test are returned as an error, along with the result of the others.
+func (c *Coordinator) Serve(ctx context.Context, lis net.Listener) (*report.Result, error) {
+	srv := grpc.NewServer()
+	srv.RegisterService(&coordinatorServiceDesc, c)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(lis)
+	}()
+
+	select {
+	case <-c.done:
+		srv.GracefulStop()
+	case err := <-serveErr:
+		return nil, err
+	case <-ctx.Done():
+		// workers lose the coordinator and stop their tests
+		srv.Stop()
+		return nil, fmt.Errorf("coordinator is stopped before the workers reported")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errs) > 0 {
+		return c.result, fmt.Errorf("%d of %d workers failed: %s", len(c.errs), c.workers, strings.Join(c.errs, ", "))
+	}
+	return c.result, nil
+}
+
+func (c *Coordinator) Sync(ctx context.Context, req *SyncRequest) (*SyncResponse, error) {
+	return &SyncResponse{Time: c.now()}, nil
+}
+
+// Join blocks until all the workers joined, then returns the job of the worker.
+func (c *Coordinator) Join(ctx context.Context, req *JoinRequest) (*Job, error) {
+	c.mu.Lock()
+	select {
+	case <-c.ready:
+		c.mu.Unlock()
+		return nil, status.Errorf(codes.ResourceExhausted, "all the %d workers are already joined", c.workers)
+	default:
+	}
+	c.waiting++
+	if c.waiting == c.workers {
+		c.startAt = c.now().Add(startDelay)
+		close(c.ready)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.ready:
+	case <-ctx.Done():
+		c.mu.Lock()
+		select {
+		case <-c.ready: // left right after the others joined, its share is reported as failed
+			c.errs = append(c.errs, fmt.Sprintf("%s left before the test started", req.Hostname))
+			c.markReported(c.nextID)
+			c.nextID++
+		default:
+			c.waiting--
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	id := c.nextID
+	c.nextID++
+	c.mu.Unlock()
+	return c.job(id), nil
+}
+
+// job returns the share of the worker with the given id from the test.
+func (c *Coordinator) job(id int) *Job {
+	h := c.hammer
+	seed := h.Seed
+	if seed != 0 {
+		seed += int64(id) // workers should not pick the same scenario sequence
+	}
+	return &Job{
+		WorkerID:         id,
+		Config:           c.config,
+		IterationCount:   share(h.IterationCount, c.workers, id),
+		RPS:              share(h.RPS, c.workers, id),
+		Seed:             seed,
+		GracePeriod:      h.GracePeriod,
+		Warmup:           h.Warmup,
+		Jitter:           h.Jitter,
+		StartupSpread:    h.StartupSpread,
+		StopOn:           h.StopOn,
+		DNSCacheTTL:      h.DNSCacheTTL,
+		Resolve:          h.Resolve,
+		DisableKeepAlive: h.DisableKeepAlive,
+		RequestIDHeader:  h.RequestIDHeader,
+		OnlyTags:         h.OnlyTags,
+		StartAt:          c.startAt,
+	}
+}
+
+// Report merges the results of a worker.
+func (c *Coordinator) Report(ctx context.Context, req *ReportRequest) (*ReportResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if req.WorkerID < 0 || req.WorkerID >= c.nextID {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown worker: %d", req.WorkerID)
+	}
+	if c.reported[req.WorkerID] {
+		return nil, status.Errorf(codes.AlreadyExists, "worker %d already reported", req.WorkerID)
+	}
+
+	if req.Error != "" {
+		c.errs = append(c.errs, fmt.Sprintf("worker %d: %s", req.WorkerID, req.Error))
+	} else {
+		c.result.Merge(req.Snapshot)
+	}
+	c.markReported(req.WorkerID)
+	return &ReportResponse{}, nil
+}
+
+// markReported should be called with c.mu held.
+func (c *Coordinator) markReported(id int) {
+	c.reported[id] = true
+	if len(c.reported) == c.workers {
+		close(c.done)
+	}
+}
+
+// share returns the share of the worker i from total, the remainder is shared by the first workers.
+func share(total, workers, i int) int {
+	s := total / workers
+	if i < total%workers {
+		s++
+	}
+	return s
+}
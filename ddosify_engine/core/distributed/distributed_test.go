@@ -0,0 +1,162 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestShare(t *testing.T) {
+	t.Parallel()
+
+	shares := make([]int, 3)
+	total := 0
+	for i := range shares {
+		shares[i] = share(10, 3, i)
+		total += shares[i]
+	}
+
+	expected := []int{4, 3, 3}
+	for i := range expected {
+		if shares[i] != expected[i] {
+			t.Errorf("Expected %v, Found: %v", expected, shares)
+		}
+	}
+	if total != 10 {
+		t.Errorf("Expected %v, Found: %v", 10, total)
+	}
+}
+
+func TestNewCoordinatorInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		hammer  types.Hammer
+		workers int
+	}{
+		{"NoWorkers", types.Hammer{IterationCount: 10}, 0},
+		{"IterationsLessThanWorkers", types.Hammer{IterationCount: 2}, 3},
+		{"RPSLessThanWorkers", types.Hammer{IterationCount: 10, RPS: 2}, 3},
+		{"ManualLoad", types.Hammer{IterationCount: 10, TimeRunCountMap: types.TimeRunCount{{Duration: 1, Count: 10}}}, 2},
+	}
+
+	for _, test := range tests {
+		if _, err := NewCoordinator(nil, test.hammer, test.workers); err == nil {
+			t.Errorf("%s: Expected error, Found: nil", test.name)
+		}
+	}
+}
+
+func TestClockOffset(t *testing.T) {
+	t.Parallel()
+
+	c, _ := NewCoordinator(nil, types.Hammer{IterationCount: 1}, 1)
+	c.now = func() time.Time { return time.Now().Add(time.Hour) } // coordinator clock is an hour ahead
+
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	srv := grpc.NewServer()
+	srv.RegisterService(&coordinatorServiceDesc, c)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})))
+	if err != nil {
+		t.Fatalf("TestClockOffset dial error: %v", err)
+	}
+	defer conn.Close()
+
+	offset, err := clockOffset(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("TestClockOffset error occurred: %v", err)
+	}
+	if d := offset - time.Hour; d < -100*time.Millisecond || d > 100*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v", time.Hour, offset)
+	}
+}
+
+func TestDistributedTest(t *testing.T) {
+	startDelay = 500 * time.Millisecond
+
+	var reqCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqCount, 1)
+		time.Sleep(time.Duration(atomic.LoadInt32(&reqCount)%3) * time.Millisecond)
+	}))
+	defer server.Close()
+
+	conf := []byte(fmt.Sprintf(`{"iteration_count": 9, "duration": 1, "steps": [{"id": 1, "url": %q}]}`, server.URL))
+	h := types.Hammer{IterationCount: 9, TestDuration: 1, ReportDestination: "stdout"}
+
+	workers := 3
+	c, err := NewCoordinator(conf, h, workers)
+	if err != nil {
+		t.Fatalf("TestDistributedTest error occurred: %v", err)
+	}
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := RunWorker(context.Background(), lis.Addr().String()); err != nil {
+				t.Errorf("TestDistributedTest worker error: %v", err)
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := c.Serve(ctx, lis)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("TestDistributedTest serve error: %v", err)
+	}
+
+	if result.SuccessCount != 9 || atomic.LoadInt32(&reqCount) != 9 {
+		t.Errorf("Expected %v, Found: %v, %v", 9, result.SuccessCount, reqCount)
+	}
+	sr := result.StepResults[1]
+	if sr == nil || sr.SuccessCount != 9 || sr.StatusCodeDist[http.StatusOK] != 9 {
+		t.Fatalf("Expected %v, Found: %v", 9, sr)
+	}
+	if result.TestStatus != "success" {
+		t.Errorf("Expected %v, Found: %v", "success", result.TestStatus)
+	}
+	// latency histograms of the workers are merged
+	if l := result.Snapshot().Latencies[1]; l.Total != 9 {
+		t.Errorf("Expected %v, Found: %v", 9, l.Total)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.ddosify.com/ddosify/core/report"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// Messages are encoded as json by a grpc codec, so the coordinator service doesn't need generated protobuf code.
+const (
+	serviceName  = "ddosify.distributed.Coordinator"
+	methodSync   = "/" + serviceName + "/Sync"
+	methodJoin   = "/" + serviceName + "/Join"
+	methodReport = "/" + serviceName + "/Report"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// SyncRequest and SyncResponse are used by the workers to estimate their clock offset to the coordinator.
+type SyncRequest struct{}
+
+type SyncResponse struct {
+	Time time.Time `json:"time"` // clock of the coordinator
+}
+
+// JoinRequest registers a worker to the coordinator.
+type JoinRequest struct {
+	Hostname string `json:"hostname"`
+}
+
+// Job is the share of a worker from the test. It is sent when all the workers joined.
+type Job struct {
+	WorkerID int `json:"worker_id"`
+
+	// Config file content of the test, parsed by the workers
+	Config []byte `json:"config"`
+
+	// Values overridden by the coordinator over the config, like the share of the worker from the iteration count
+	IterationCount   int           `json:"iteration_count"`
+	RPS              int           `json:"rps"`
+	Seed             int64         `json:"seed"`
+	GracePeriod      time.Duration `json:"grace_period"`
+	Warmup           time.Duration `json:"warmup"`
+	Jitter           time.Duration `json:"jitter"`
+	StartupSpread    time.Duration `json:"startup_spread"`
+	StopOn           []string      `json:"stop_on"`
+	DNSCacheTTL      time.Duration `json:"dns_cache_ttl"`
+	Resolve          []string      `json:"resolve"`
+	DisableKeepAlive bool          `json:"disable_keep_alive"`
+	RequestIDHeader  string        `json:"request_id_header"`
+	OnlyTags         []string      `json:"only_tags"`
+
+	// Start time of the test, in the clock of the coordinator
+	StartAt time.Time `json:"start_at"`
+}
+
+// ReportRequest carries the aggregated results of a worker, Error is set if the worker couldn't run the test.
+type ReportRequest struct {
+	WorkerID int             `json:"worker_id"`
+	Snapshot report.Snapshot `json:"snapshot"`
+	Error    string          `json:"error,omitempty"`
+}
+
+type ReportResponse struct{}
+
+// coordinatorServer is implemented by the Coordinator.
+type coordinatorServer interface {
+	Sync(context.Context, *SyncRequest) (*SyncResponse, error)
+	Join(context.Context, *JoinRequest) (*Job, error)
+	Report(context.Context, *ReportRequest) (*ReportResponse, error)
+}
+
+var coordinatorServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*coordinatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Sync", Handler: unaryHandler(methodSync, func(s coordinatorServer, ctx context.Context, req *SyncRequest) (interface{}, error) {
+			return s.Sync(ctx, req)
+		})},
+		{MethodName: "Join", Handler: unaryHandler(methodJoin, func(s coordinatorServer, ctx context.Context, req *JoinRequest) (interface{}, error) {
+			return s.Join(ctx, req)
+		})},
+		{MethodName: "Report", Handler: unaryHandler(methodReport, func(s coordinatorServer, ctx context.Context, req *ReportRequest) (interface{}, error) {
+			return s.Report(ctx, req)
+		})},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// unaryHandler returns the grpc handler of a method, it decodes the request of type Req and calls the method.
+func unaryHandler[Req any](fullMethod string,
+	call func(coordinatorServer, context.Context, *Req) (interface{}, error),
+) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(coordinatorServer), ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(coordinatorServer), ctx, req.(*Req))
+		})
+	}
+}
@@ -0,0 +1,180 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.ddosify.com/ddosify/config"
+	"go.ddosify.com/ddosify/core"
+	"go.ddosify.com/ddosify/core/report"
+	"go.ddosify.com/ddosify/core/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// clock offset is estimated from the sample with the lowest round trip time
+const syncSampleCount = 5
+
+// RunWorker joins the coordinator at addr, runs the share of the worker from the test at the start time given by
+// the coordinator and reports the results back. The test is stopped if the connection to the coordinator is lost.
+func RunWorker(ctx context.Context, addr string) error {
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})),
+		grpc.WithBlock())
+	if err != nil {
+		return fmt.Errorf("could not connect to the coordinator %s: %v", addr, err)
+	}
+	defer conn.Close()
+
+	offset, err := clockOffset(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("clock sync: %v", err)
+	}
+
+	hostname, _ := os.Hostname()
+	job := &Job{}
+	if err = conn.Invoke(ctx, methodJoin, &JoinRequest{Hostname: hostname}, job); err != nil {
+		return fmt.Errorf("join: %v", err)
+	}
+
+	snapshot, runErr := runJob(watchCoordinator(ctx, conn), job, offset)
+	req := &ReportRequest{WorkerID: job.WorkerID, Snapshot: snapshot}
+	if runErr != nil {
+		req.Error = runErr.Error()
+	}
+	if err = conn.Invoke(ctx, methodReport, req, &ReportResponse{}); err != nil {
+		return fmt.Errorf("report: %v", err)
+	}
+	return runErr
+}
+
+// clockOffset returns the duration to add to the local clock to get the clock of the coordinator.
+func clockOffset(ctx context.Context, conn *grpc.ClientConn) (time.Duration, error) {
+	var offset time.Duration
+	minRTT := time.Duration(-1)
+	for i := 0; i < syncSampleCount; i++ {
+		res := &SyncResponse{}
+		sent := time.Now()
+		if err := conn.Invoke(ctx, methodSync, &SyncRequest{}, res); err != nil {
+			return 0, err
+		}
+		rtt := time.Since(sent)
+		if minRTT < 0 || rtt < minRTT {
+			// coordinator clock is read at the half of the round trip
+			minRTT = rtt
+			offset = res.Time.Sub(sent.Add(rtt / 2))
+		}
+	}
+	return offset, nil
+}
+
+// watchCoordinator returns a ctx that is canceled if the connection to the coordinator is lost.
+func watchCoordinator(ctx context.Context, conn *grpc.ClientConn) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		for {
+			state := conn.GetState()
+			if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+				return
+			}
+			if !conn.WaitForStateChange(ctx, state) { // ctx is done
+				return
+			}
+		}
+	}()
+	return ctx
+}
+
+// runJob initializes the engine with the job, waits for the start time and runs the test.
+var runJob = func(ctx context.Context, job *Job, offset time.Duration) (report.Snapshot, error) {
+	h, err := jobHammer(job)
+	if err != nil {
+		return report.Snapshot{}, err
+	}
+
+	es, err := core.InitEngineServices(h)
+	if err != nil {
+		return report.Snapshot{}, err
+	}
+	collector := report.NewCollector()
+	collector.Init(h.Debug, h.SamplingRate, h.RPS)
+	es.ReportServ = collector
+
+	engine, err := core.NewEngine(ctx, h, es)
+	if err != nil {
+		return report.Snapshot{}, err
+	}
+	if err = engine.Init(); err != nil {
+		return report.Snapshot{}, err
+	}
+
+	// start time is in the clock of the coordinator
+	t := time.NewTimer(time.Until(job.StartAt.Add(-offset)))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		return report.Snapshot{}, fmt.Errorf("stopped before the test started")
+	}
+
+	engine.Start()
+	return collector.Snapshot(), nil
+}
+
+// jobHammer creates the hammer of the worker from the config of the job.
+func jobHammer(job *Job) (h types.Hammer, err error) {
+	c, err := config.NewConfigReader(job.Config, config.ConfigTypeJson)
+	if err != nil {
+		return
+	}
+	h, err = c.CreateHammer()
+	if err != nil {
+		return
+	}
+
+	h.IterationCount = job.IterationCount
+	h.RPS = job.RPS
+	h.Seed = job.Seed
+	h.GracePeriod = job.GracePeriod
+	h.Warmup = job.Warmup
+	h.Jitter = job.Jitter
+	h.StartupSpread = job.StartupSpread
+	h.StopOn = job.StopOn
+	h.DNSCacheTTL = job.DNSCacheTTL
+	h.Resolve = job.Resolve
+	h.DisableKeepAlive = job.DisableKeepAlive
+	h.RequestIDHeader = job.RequestIDHeader
+	h.OnlyTags = job.OnlyTags
+	// live metrics, the control api and per request outputs are not distributed
+	h.MetricsAddr, h.ControlAddr = "", ""
+	h.OutputFormat, h.OutputFile = "", ""
+	h.Debug = false
+
+	err = h.Validate()
+	return
+}
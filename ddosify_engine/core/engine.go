@@ -24,9 +24,14 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/rand"
 	"net/http"
+	"os"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.ddosify.com/ddosify/core/assertion"
@@ -35,7 +40,9 @@ import (
 	"go.ddosify.com/ddosify/core/report"
 	"go.ddosify.com/ddosify/core/scenario"
 	"go.ddosify.com/ddosify/core/scenario/data"
+	"go.ddosify.com/ddosify/core/scenario/requester"
 	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
 )
 
 const (
@@ -46,6 +53,15 @@ const (
 	resultDone    = "done"
 	resultStopped = "stopped"
 	resultAborted = "aborted"
+
+	// max wait time for the in-flight scrapes at the end of the test
+	metricsShutdownTimeout = 5 * time.Second
+
+	// max wait time for the in-flight control requests at the end of the test
+	controlShutdownTimeout = time.Second
+
+	// max delay of the results streamed to stdout
+	streamFlushInterval = 200 * time.Millisecond
 )
 
 type engine struct {
@@ -54,12 +70,48 @@ type engine struct {
 	proxyService    proxy.ProxyService
 	reportService   report.ReportService
 	scenarioService *scenario.ScenarioService
+	metricsServer   *report.MetricsServer
+
+	// per request results, written to the output of the output format if given and to the Hammer.ResultSinks
+	sinks []*report.SinkRunner
+
+	// per request results, passed to the Hammer.OnResult callback if given
+	resultHook *report.ResultHook
+
+	// spans of the requests and the request metrics, exported if an otel endpoint is given
+	otelExporter *report.OTelExporter
+
+	// stats of the requests per interval, written if a timeseries file is given
+	timeSeries *report.TimeSeriesWriter
+
+	// writes the first failing requests of each error category, if a failure samples file is given
+	failureSampler *report.FailureSampler
 
 	// for assertion
 	aborter     assertion.Aborter
 	asserter    assertion.Asserter
 	resListener assertion.ResultListener
 
+	// aborts the test when one of the Hammer.StopOn conditions is met, nil if there is no condition
+	stopWatcher *assertion.StopWatcher
+
+	// adjusts the users of the Hammer.Adaptive load, nil if the load is not adaptive
+	adaptive *adaptiveController
+
+	// limits the running iterations by the Hammer.Backpressure, nil if they are unlimited
+	backpressure *backpressure
+
+	// samples the file descriptors and the connections of the load generator, nil in debug mode
+	resources *resourceMonitor
+
+	// holds the new iterations while the test is paused by the control API, nil if it is not enabled
+	pause         *pauseGate
+	controlServer *controlServer
+
+	// number of the running iterations and the max of it during the test
+	activeIterations    int64
+	maxActiveIterations int64
+
 	tickCounter int
 	reqCountArr []int
 	wg          sync.WaitGroup
@@ -67,9 +119,43 @@ type engine struct {
 	resultReportChan chan *types.ScenarioResult
 	resultAssertChan chan *types.ScenarioResult
 
+	// passes the results to the report service in batches if it is a report.BatchReporter, nil otherwise
+	batcher         *resultBatcher
+	resultBatchChan chan []*types.ScenarioResult
+
+	// iterations started before it are in the warm-up period, zero if there is no warm-up
+	warmupEnd time.Time
+	// iterations of the user quota and adaptive loads started after it are in the ramp-down period, zero if there
+	// is no ramp-down. The iterations of the ticks are tagged by rampDownTick instead.
+	rampDownStart time.Time
+
+	// derives the random streams of the start delays from the seed
+	rng *util.RandFactory
+
+	// closed once the Hammer.MaxRequests are sent, nil if the requests are unlimited
+	maxRequestsChan <-chan struct{}
+
+	// error of the first failed teardown step, nil if the teardown succeeded
+	teardownErr error
+
+	// failed steps of the Hammer.DryRun iteration
+	dryRunFailures []string
+
+	// canceled when the Hammer.MaxDuration is exceeded, stops the test, its requests in the grace period and its
+	// teardown. Never done if the duration is unlimited.
+	hardCtx    context.Context
+	hardCancel context.CancelFunc
+	hardTimer  *time.Timer
+	// set once the Hammer.MaxDuration is exceeded
+	hardTimedOut int32
+
+	// number of the iterations reported, for the Hammer.SampleRate
+	sampleCount uint64
+
 	abortChan   <-chan struct{}
 	testSuccess bool
 	ctx         context.Context
+	cancel      context.CancelFunc
 }
 
 type EngineServices struct {
@@ -102,7 +188,11 @@ var InitEngineServices = func(h types.Hammer) (*EngineServices, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = rs.Init(h.Debug, h.SamplingRate); err != nil {
+	if o, ok := rs.(report.OutputRedirector); ok && h.StreamJSON {
+		// stdout is left to the streamed results
+		o.SetOutput(os.Stderr)
+	}
+	if err = rs.Init(h.Debug, h.SamplingRate, h.RPS); err != nil {
 		return nil, err
 	}
 
@@ -124,9 +214,15 @@ func NewEngine(ctx context.Context, h types.Hammer,
 	services *EngineServices) (e *engine, err error) {
 	ss := scenario.NewScenarioService()
 
+	ctx, cancel := context.WithCancel(ctx)
+	hardCtx, hardCancel := context.WithCancel(context.Background())
 	e = &engine{
 		hammer:          h,
 		ctx:             ctx,
+		cancel:          cancel,
+		hardCtx:         hardCtx,
+		hardCancel:      hardCancel,
+		rng:             util.NewRandFactory(h.Seed),
 		proxyService:    services.ProxyServ,
 		scenarioService: ss,
 		reportService:   services.ReportServ,
@@ -145,14 +241,29 @@ func (e *engine) IsTestFailed() bool {
 }
 
 func (e *engine) Init() (err error) {
+	if e.hammer.MaxDuration > 0 {
+		e.hardTimer = time.AfterFunc(e.hammer.MaxDuration, e.hardTimeout)
+		defer func() {
+			if err != nil {
+				e.hardTimer.Stop()
+			}
+		}()
+	}
+
 	// read test data
 	readData, err := readTestData(e.hammer.TestDataConf)
 	if err != nil {
 		return err
 	}
 	e.hammer.Scenario.Data = readData
+	if len(e.hammer.OnlyTags) > 0 {
+		e.hammer.Scenario = e.hammer.Scenario.FilterTags(e.hammer.OnlyTags)
+	}
 
 	e.initReqCountArr()
+	if e.hammer.UserQuota != nil && !e.hammer.Debug {
+		e.hammer.IterationCount = e.hammer.UserQuota.Total()
+	}
 
 	var initialCookies []*http.Cookie
 	if e.hammer.CookiesEnabled && len(e.hammer.Cookies) > 0 {
@@ -162,24 +273,190 @@ func (e *engine) Init() (err error) {
 		}
 	}
 
+	resolve, err := types.ParseResolve(e.hammer.Resolve)
+	if err != nil {
+		return err
+	}
+	sourceAddrs, err := types.ParseSourceAddrs(e.hammer.SourceAddrs)
+	if err != nil {
+		return err
+	}
+
+	var conns *requester.ConnTracker
+	if !e.hammer.Debug {
+		conns = requester.NewConnTracker()
+	}
+	var limiter *requester.ConnLimiter
+	if e.hammer.MaxHostConns > 0 {
+		limiter = requester.NewConnLimiter(e.hammer.MaxHostConns)
+	}
+
 	if err = e.scenarioService.Init(e.ctx, e.hammer.Scenario, e.proxyService.GetAll(), scenario.ScenarioOpts{
 		Debug:                  e.hammer.Debug,
 		IterationCount:         e.hammer.IterationCount,
 		MaxConcurrentIterCount: e.getMaxConcurrentIterCount(),
 		EngineMode:             e.hammer.EngineMode,
 		InitialCookies:         initialCookies,
+		NoProxy:                e.hammer.Proxy.NoProxy,
+		Seed:                   e.hammer.Seed,
+		DNSCacheTTL:            e.hammer.DNSCacheTTL,
+		Resolve:                resolve,
+		SourceAddrs:            sourceAddrs,
+		GracePeriod:            e.hammer.GracePeriod,
+		RequestCtx:             e.hardCtx,
+		RPS:                    e.hammer.RPS,
+		CorrectOmission:        e.hammer.CorrectOmission,
+		MaxRequests:            e.hammer.MaxRequests,
+		HonorRetryAfter:        e.hammer.HonorRetryAfter,
+		DisableKeepAlive:       e.hammer.DisableKeepAlive,
+		Revalidate:             e.hammer.Revalidate,
+		Transport:              e.hammer.Transport,
+		RequestIDHeader:        e.hammer.RequestIDHeader,
+		SuccessWhen:            e.hammer.SuccessWhen,
+		SampleBodyBytes:        e.hammer.FailureSamples.BodyBytes(),
+		Traceparent:            e.hammer.OTel.Endpoint != "",
+		StickyUsers:            e.hammer.StickyUsers,
+		CapClientPool:          e.hammer.CapClientPool,
+		AdaptiveClientPool:     e.hammer.AdaptiveClientPool,
+		ClientPerHost:          e.hammer.ClientPerHost,
+		ClientTTL:              e.hammer.ClientTTL,
+		ClientHealthCheck:      e.hammer.ClientHealthCheck,
+		CaptureCert:            e.hammer.CertAudit != nil,
+		PreWarm:                e.hammer.PreWarm,
+		ClientFactory:          e.hammer.ClientFactory,
+		UserAgents:             e.hammer.UserAgents,
+		RequestInterceptors:    e.hammer.RequestInterceptors,
+		ResponseInterceptors:   e.hammer.ResponseInterceptors,
+		ConnTracker:            conns,
+		ConnLimiter:            limiter,
 	}); err != nil {
 		return
 	}
+	if err = e.scenarioService.Setup(e.ctx, e.proxyService.GetProxy()); err != nil {
+		return fmt.Errorf("setup: %w", err)
+	}
 
 	e.abortChan = e.aborter.AbortChan()
+	e.maxRequestsChan = e.scenarioService.MaxRequestsReached()
+
+	if d, ok := e.reportService.(report.LiveDashboard); ok && e.hammer.Dashboard && !e.hammer.Debug {
+		d.EnableDashboard(func() int64 { return atomic.LoadInt64(&e.activeIterations) })
+	}
+
+	if lr, ok := e.reportService.(report.LoadReconciler); ok && e.hammer.Adaptive == nil && !e.hammer.Debug {
+		lr.SetRequestedLoad(e.requestedLoad())
+	}
+
+	if ca, ok := e.reportService.(report.CertAuditor); ok && e.hammer.CertAudit != nil {
+		ca.SetCertExpiryDays(e.hammer.CertAudit.ExpiryDays)
+	}
+
+	exactPercentiles := e.hammer.PercentileMode == types.PercentileModeExact
+	if pr, ok := e.reportService.(report.PercentileReporter); ok && (len(e.hammer.Percentiles) > 0 || exactPercentiles) {
+		pr.SetPercentiles(e.hammer.Percentiles, exactPercentiles)
+	}
+
+	var latencyBuckets []time.Duration
+	if e.hammer.LatencyBuckets != nil {
+		latencyBuckets = e.hammer.LatencyBuckets.Bounds()
+		if hr, ok := e.reportService.(report.HistogramReporter); ok {
+			hr.SetLatencyBuckets(latencyBuckets)
+		}
+	}
+
+	if sr, ok := e.reportService.(report.SampleReporter); ok && e.hammer.SampleRate > 0 && e.hammer.SampleRate < 1 &&
+		!e.hammer.Debug {
+		sr.SetSampleRate(e.hammer.SampleRate)
+	}
+
+	if len(e.hammer.StopOn) > 0 && !e.hammer.Debug {
+		conditions, err := types.ParseStopConditions(e.hammer.StopOn)
+		if err != nil {
+			return err
+		}
+		e.stopWatcher = assertion.NewStopWatcher(conditions)
+	}
+
+	if e.hammer.Adaptive != nil && !e.hammer.Debug {
+		e.adaptive = newAdaptiveController(*e.hammer.Adaptive)
+	}
+
+	if e.hammer.Backpressure != nil && !e.hammer.Debug {
+		e.backpressure = newBackpressure(*e.hammer.Backpressure)
+	}
+
+	if e.hammer.MetricsAddr != "" {
+		e.metricsServer = report.NewMetricsServerWithBuckets(e.hammer.MetricsAddr, latencyBuckets)
+		e.metricsServer.ObservePool(e.scenarioService.PoolStats)
+		e.metricsServer.ObserveHostPools(e.scenarioService.HostPoolStats)
+		if err = e.metricsServer.Start(); err != nil {
+			return fmt.Errorf("metrics server: %w", err)
+		}
+	}
+
+	if conns != nil {
+		e.resources = newResourceMonitor(conns)
+		e.resources.limiter = limiter
+		if rr, ok := e.reportService.(report.ResourceReporter); ok {
+			rr.SetResourceUsage(e.resources.peak)
+			e.resources.warn = rr.WarnResourceUsage
+		}
+		if e.metricsServer != nil {
+			e.resources.observe = e.metricsServer.ObserveResources
+		}
+	}
+
+	if e.hammer.ControlAddr != "" && !e.hammer.Debug {
+		e.pause = newPauseGate()
+		if pt, ok := e.reportService.(report.PauseTracker); ok {
+			pt.SetPausedDuration(e.pause.pausedDuration)
+		}
+		e.controlServer = newControlServer(e.hammer.ControlAddr, e.pause)
+		if err = e.controlServer.start(); err != nil {
+			return fmt.Errorf("control server: %w", err)
+		}
+	}
+
+	if e.hammer.OutputFormat != "" {
+		if err = e.initOutputSink(); err != nil {
+			return err
+		}
+	}
+	for _, conf := range e.hammer.ResultSinks {
+		e.sinks = append(e.sinks, report.NewSinkRunner(conf))
+	}
+	if e.hammer.StreamJSON {
+		e.sinks = append(e.sinks, report.NewSinkRunner(types.ResultSinkConf{
+			Sink: report.NewStreamSink(os.Stdout, streamFlushInterval), Policy: types.SinkPolicyDrop}))
+	}
+
+	if e.hammer.OnResult != nil {
+		e.resultHook = report.NewResultHook(e.hammer.OnResult, report.DefaultResultHookBufferSize)
+	}
+
+	if e.hammer.OTel.Endpoint != "" {
+		if e.otelExporter, err = report.NewOTelExporter(e.hammer.OTel); err != nil {
+			return fmt.Errorf("otel exporter: %w", err)
+		}
+	}
+
+	if e.hammer.TimeSeries.File != "" {
+		if e.timeSeries, err = report.NewTimeSeriesWriter(e.hammer.TimeSeries); err != nil {
+			return fmt.Errorf("timeseries: %w", err)
+		}
+	}
+
+	if e.hammer.FailureSamples.File != "" {
+		if e.failureSampler, err = report.NewFailureSampler(e.hammer.FailureSamples); err != nil {
+			return fmt.Errorf("failure samples: %w", err)
+		}
+	}
 
 	return
 }
 
 func (e *engine) Start() string {
 	ticker := time.NewTicker(time.Duration(tickerInterval) * time.Millisecond)
-	e.resultReportChan = make(chan *types.ScenarioResult, e.hammer.IterationCount)
 	e.resultAssertChan = make(chan *types.ScenarioResult, e.hammer.IterationCount)
 
 	var testResultChan <-chan assertion.TestAssertionResult
@@ -192,7 +469,32 @@ func (e *engine) Start() string {
 		go e.resListener.Start(e.resultAssertChan)
 	}
 
-	go e.reportService.Start(e.resultReportChan, testResultChan)
+	var stopChan <-chan struct{}
+	if e.stopWatcher != nil {
+		stopChan = e.stopWatcher.AbortChan()
+		testResultChan = e.stopResultChan(testResultChan)
+		go e.stopWatcher.Start()
+	}
+
+	if br, ok := e.reportService.(report.BatchReporter); ok {
+		e.resultBatchChan = make(chan []*types.ScenarioResult, e.hammer.IterationCount/resultBatchSize+1)
+		e.batcher = newResultBatcher(e.resultBatchChan)
+		go br.StartBatched(e.resultBatchChan, testResultChan)
+	} else {
+		e.resultReportChan = make(chan *types.ScenarioResult, e.hammer.IterationCount)
+		go e.reportService.Start(e.resultReportChan, testResultChan)
+	}
+
+	if e.resources != nil {
+		go e.resources.run()
+	}
+
+	if e.hammer.Warmup > 0 {
+		e.warmupEnd = time.Now().Add(e.hammer.Warmup)
+	}
+	if e.hammer.RampDown > 0 {
+		e.rampDownStart = time.Now().Add(time.Duration(e.hammer.TestDuration)*time.Second - e.hammer.RampDown)
+	}
 
 	defer func() {
 		ticker.Stop()
@@ -201,6 +503,12 @@ func (e *engine) Start() string {
 
 	e.tickCounter = 0
 	e.wg = sync.WaitGroup{}
+	if e.adaptive != nil {
+		return e.runAdaptive(stopChan)
+	}
+	if e.hammer.UserQuota != nil && !e.hammer.Debug {
+		return e.runUserQuota(stopChan)
+	}
 	var mutex = &sync.Mutex{}
 	for range ticker.C {
 		if e.tickCounter >= len(e.reqCountArr) {
@@ -213,10 +521,23 @@ func (e *engine) Start() string {
 		case <-e.abortChan:
 			e.testSuccess = false
 			return resultAborted
+		case <-stopChan:
+			return resultAborted
+		case <-e.maxRequestsChan:
+			return resultDone
 		default:
+			if e.pause != nil && e.pause.paused() {
+				// the schedule is shifted by the pause, the remaining load is sent after the resume
+				continue
+			}
 			mutex.Lock()
 			e.wg.Add(e.reqCountArr[e.tickCounter])
-			go e.runWorkers(e.tickCounter)
+			if e.backpressure != nil && e.backpressure.blocks() {
+				// the missed ticks are dropped by the ticker, the rest of the schedule is shifted
+				e.runWorkers(e.tickCounter)
+			} else {
+				go e.runWorkers(e.tickCounter)
+			}
 			e.tickCounter++
 			mutex.Unlock()
 		}
@@ -225,23 +546,147 @@ func (e *engine) Start() string {
 }
 
 func (e *engine) runWorkers(c int) {
+	elapsed := time.Duration(c*tickerInterval) * time.Millisecond
+	rnd := e.rng.Stream("start", uint64(c))
+	rampDown := e.hammer.RampDown > 0 && c >= e.rampDownTick()
 	for i := 1; i <= e.reqCountArr[c]; i++ {
+		if e.backpressure != nil && e.backpressure.blocks() && !e.backpressure.acquire(e.ctx) {
+			// stopped, the remaining iterations of the tick are not started
+			e.wg.Add(i - 1 - e.reqCountArr[c])
+			return
+		}
 		scenarioStartTime := time.Now()
+		delay := e.startDelay(rnd, elapsed)
 		go func(t time.Time) {
-			e.runWorker(t)
-			e.wg.Done()
+			defer e.wg.Done()
+			// slot of the iteration, taken by the scheduler in the block policy
+			held := e.backpressure != nil && e.backpressure.blocks()
+			defer func() {
+				if held {
+					e.backpressure.release()
+				}
+			}()
+			if delay > 0 {
+				if !sleepContext(e.ctx, delay) {
+					return // stopped before the iteration starts
+				}
+				t = time.Now()
+			}
+			if e.pause != nil && e.pause.paused() {
+				// delayed into a pause, started after the resume
+				if !e.pause.wait(e.ctx) {
+					return
+				}
+				t = time.Now()
+			}
+			if e.backpressure != nil && !held {
+				if !e.backpressure.acquire(e.ctx) {
+					return // dropped or stopped
+				}
+				held = true
+			}
+			e.iterationStarted()
+			e.runWorker(t, nil, rampDown)
+			atomic.AddInt64(&e.activeIterations, -1)
 		}(scenarioStartTime)
 	}
 }
 
-func (e *engine) runWorker(scenarioStartTime time.Time) {
+// iterationStarted increments the running iterations and updates the max of them.
+func (e *engine) iterationStarted() {
+	active := atomic.AddInt64(&e.activeIterations, 1)
+	for {
+		max := atomic.LoadInt64(&e.maxActiveIterations)
+		if active <= max || atomic.CompareAndSwapInt64(&e.maxActiveIterations, max, active) {
+			return
+		}
+	}
+}
+
+// requestedLoad returns the iterations requested by the load pattern between the warm-up and the ramp-down periods.
+func (e *engine) requestedLoad() report.RequestedLoad {
+	if e.hammer.UserQuota != nil {
+		return report.RequestedLoad{
+			Iterations:  e.hammer.UserQuota.Total(),
+			Closed:      true,
+			MaxInFlight: func() int64 { return atomic.LoadInt64(&e.maxActiveIterations) },
+		}
+	}
+	warmupTicks := int(e.hammer.Warmup / (tickerInterval * time.Millisecond))
+	rampDownStart := e.rampDownTick()
+	if warmupTicks > rampDownStart {
+		warmupTicks = rampDownStart
+	}
+	return report.RequestedLoad{
+		Iterations:  arraySum(e.reqCountArr[warmupTicks:rampDownStart]),
+		Duration:    time.Duration(rampDownStart-warmupTicks) * tickerInterval * time.Millisecond,
+		MaxInFlight: func() int64 { return atomic.LoadInt64(&e.maxActiveIterations) },
+	}
+}
+
+// rampDownTick returns the index of the first tick of the ramp-down period, the number of the ticks if there is
+// no ramp-down. Ticks are counted, so the ramp-down is not moved by a pause or the backpressure shifting the schedule.
+func (e *engine) rampDownTick() int {
+	tick := len(e.reqCountArr) - int(e.hammer.RampDown/(tickerInterval*time.Millisecond))
+	if tick < 0 {
+		return 0
+	}
+	return tick
+}
+
+// inRampDown reports whether an iteration of the user quota and adaptive loads started at t is in the ramp-down period.
+func (e *engine) inRampDown(t time.Time) bool {
+	return !e.rampDownStart.IsZero() && !t.Before(e.rampDownStart)
+}
+
+// startDelay returns the random delay of an iteration scheduled at the elapsed time of the test,
+// by the jitter and the startup spread of the test. Delays are drawn from the stream of the tick.
+func (e *engine) startDelay(rnd *rand.Rand, elapsed time.Duration) time.Duration {
+	if e.hammer.Debug {
+		return 0
+	}
+	var d time.Duration
+	if e.hammer.Jitter > 0 {
+		d += time.Duration(rnd.Int63n(int64(e.hammer.Jitter)))
+	}
+	if spread := e.hammer.StartupSpread; elapsed < spread {
+		d += time.Duration(rnd.Int63n(int64(spread - elapsed)))
+	}
+	return d
+}
+
+// sleepContext waits for the duration, returns false if the ctx is done before it.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// userClient takes the client kept by a virtual user for its lifetime, nil if the users don't keep their clients.
+// Returns false if the test is stopped while waiting for a client of the capped pool.
+func (e *engine) userClient() (*scenario.UserClient, bool) {
+	if !e.hammer.ClientAffinity {
+		return nil, true
+	}
+	u, err := e.scenarioService.AcquireUserClient()
+	return u, err == nil
+}
+
+// runWorker runs an iteration with the client of the user u, or a client of the pool if u is nil. rampDown tags
+// the result of the iteration started in the ramp-down period.
+func (e *engine) runWorker(scenarioStartTime time.Time, u *scenario.UserClient, rampDown bool) {
 	var res *types.ScenarioResult
 	var err *types.RequestError
 
 	p := e.proxyService.GetProxy()
 	retryCount := 3
 	for i := 1; i <= retryCount; i++ {
-		res, err = e.scenarioService.Do(p, scenarioStartTime)
+		res, err = e.scenarioService.DoAsUser(p, scenarioStartTime, u)
 
 		if err != nil && err.Type == types.ErrorProxy {
 			p = e.proxyService.ReportProxy(p, err.Reason)
@@ -255,24 +700,116 @@ func (e *engine) runWorker(scenarioStartTime time.Time) {
 		break
 	}
 
+	res.Warmup = scenarioStartTime.Before(e.warmupEnd)
+	res.RampDown = rampDown
+	res.Unsampled = !e.sampled()
 	res.Others = make(map[string]interface{})
 	res.Others["hammerOthers"] = e.hammer.Others
 	res.Others["proxyCountry"] = e.proxyService.GetProxyCountry(p)
-	e.resultReportChan <- res
+	if e.metricsServer != nil {
+		e.metricsServer.Observe(res)
+	}
+	if len(e.sinks) > 0 && !res.Unsampled {
+		for _, sr := range res.StepResults {
+			if sr.Skipped {
+				continue
+			}
+			for _, s := range e.sinks {
+				s.Send(sr)
+			}
+		}
+	}
+	if e.resultHook != nil {
+		for _, sr := range res.StepResults {
+			e.resultHook.Send(sr)
+		}
+	}
+	if e.otelExporter != nil && !res.Unsampled {
+		for _, sr := range res.StepResults {
+			if !sr.Skipped {
+				e.otelExporter.WriteResult(sr)
+			}
+		}
+	}
+	if e.timeSeries != nil {
+		for _, sr := range res.StepResults {
+			if sr.Skipped {
+				continue
+			}
+			if res.Unsampled {
+				e.timeSeries.WriteUnsampledResult(sr)
+			} else {
+				e.timeSeries.WriteResult(sr)
+			}
+		}
+	}
+	if e.failureSampler != nil && !res.Unsampled {
+		for _, sr := range res.StepResults {
+			if !sr.Skipped {
+				e.failureSampler.WriteResult(sr)
+			}
+		}
+	}
+	if e.hammer.DryRun {
+		e.checkDryRun(res)
+	}
+	if e.stopWatcher != nil {
+		e.stopWatcher.Observe(res)
+	}
+	if e.adaptive != nil {
+		e.adaptive.observe(res)
+	}
+	if e.batcher != nil {
+		e.batcher.add(res)
+	} else {
+		e.resultReportChan <- res
+	}
 
-	if len(e.hammer.Assertions) > 0 {
+	if len(e.hammer.Assertions) > 0 && !res.Warmup && !res.RampDown {
 		e.resultAssertChan <- res
 	}
 }
 
+// stopResultChan passes the test-wide assertion results to the report service,
+// adding the met stop condition as a failed rule.
+func (e *engine) stopResultChan(in <-chan assertion.TestAssertionResult) <-chan assertion.TestAssertionResult {
+	out := make(chan assertion.TestAssertionResult, 1)
+	go func() {
+		var r assertion.TestAssertionResult
+		if in != nil {
+			r = <-in
+		}
+		<-e.stopWatcher.DoneChan()
+		out <- e.stopWatcher.Result(r)
+	}()
+	return out
+}
+
 func (e *engine) runAssertionsInEngine() bool {
 	return e.hammer.SingleMode && len(e.hammer.Assertions) > 0
 }
 
 func (e *engine) stop() {
+	if e.pause != nil {
+		// release the iterations held by a pause, so they don't block the end of the test
+		e.pause.resume()
+	}
+	if e.stopWatcher != nil {
+		e.stopWatcher.Done()
+	}
 	e.wg.Wait()
-	close(e.resultReportChan)
+	if e.resources != nil {
+		e.resources.stop()
+	}
+	if e.batcher != nil {
+		e.batcher.close()
+		close(e.resultBatchChan)
+	} else {
+		close(e.resultReportChan)
+	}
 	close(e.resultAssertChan)
+	// teardown runs after the iterations even if the test is stopped, its requests are only canceled by the hard timeout
+	e.teardownErr = e.scenarioService.Teardown(e.hardCtx, e.proxyService.GetProxy())
 	e.proxyService.Done()
 	e.scenarioService.Done()
 
@@ -280,11 +817,212 @@ func (e *engine) stop() {
 		<-e.resListener.DoneChan()
 	}
 
+	if e.hardTimer != nil {
+		// the iterations and the teardown are done, the report is not cut
+		e.hardTimer.Stop()
+	}
+	e.cancel()
+	e.hardCancel()
+
 	e.testSuccess = <-e.reportService.DoneChan()
+	if len(e.dryRunFailures) > 0 || atomic.LoadInt32(&e.hardTimedOut) == 1 {
+		e.testSuccess = false
+	}
+
+	if e.metricsServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+		defer cancel()
+		e.metricsServer.Shutdown(ctx)
+	}
+
+	if e.controlServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), controlShutdownTimeout)
+		defer cancel()
+		e.controlServer.shutdown(ctx)
+	}
+
+	for _, s := range e.sinks {
+		s.Close()
+	}
+
+	if e.resultHook != nil {
+		e.resultHook.Close()
+	}
+
+	if e.otelExporter != nil {
+		e.otelExporter.Close()
+	}
+
+	if e.timeSeries != nil {
+		e.timeSeries.Close()
+	}
+
+	if e.failureSampler != nil {
+		e.failureSampler.Close()
+	}
+}
+
+// DroppedResults returns the number of results not passed to the Hammer.OnResult callback and the
+// Hammer.ResultSinks because they couldn't keep up with the load.
+func (e *engine) DroppedResults() int64 {
+	var dropped int64
+	if e.resultHook != nil {
+		dropped += e.resultHook.Dropped()
+	}
+	for _, s := range e.sinks {
+		dropped += s.Dropped()
+	}
+	return dropped
+}
+
+// sampled reports whether the next reported iteration is sampled by the Hammer.SampleRate. Iterations are
+// sampled evenly, the sampled ones are the given fraction of all the iterations at any point of the test.
+func (e *engine) sampled() bool {
+	rate := e.hammer.SampleRate
+	if rate <= 0 || rate >= 1 || e.hammer.Debug {
+		return true
+	}
+	n := atomic.AddUint64(&e.sampleCount, 1)
+	return uint64(float64(n)*rate) != uint64(float64(n-1)*rate)
+}
+
+// hardTimeout stops the test exceeding the Hammer.MaxDuration.
+func (e *engine) hardTimeout() {
+	atomic.StoreInt32(&e.hardTimedOut, 1)
+	e.cancel()
+	e.hardCancel()
+}
+
+// HardTimeout returns the reason of the hard timeout if the test is stopped by the Hammer.MaxDuration,
+// empty otherwise.
+func (e *engine) HardTimeout() string {
+	if atomic.LoadInt32(&e.hardTimedOut) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("hard timeout, the test exceeded the max duration of %s", e.hammer.MaxDuration)
+}
+
+// StopReason returns the stop condition that aborted the test and its received value,
+// empty if the test isn't stopped by a stop condition.
+func (e *engine) StopReason() string {
+	if e.stopWatcher == nil {
+		return ""
+	}
+	return e.stopWatcher.Reason()
+}
 
+// Result returns the aggregated result of the test, nil if the report service doesn't keep it.
+// It should be called after the test is done.
+func (e *engine) Result() *report.Result {
+	if rp, ok := e.reportService.(report.ResultProvider); ok {
+		return rp.Result()
+	}
+	return nil
+}
+
+// ClientPoolWarning returns the warning about the live clients exceeding the capacity of the client pool,
+// empty if the capacity is not exceeded.
+func (e *engine) ClientPoolWarning() string {
+	return e.scenarioService.ClientPoolWarning()
+}
+
+// MaxRequestsResult returns the number of the requests sent if the test is stopped by the Hammer.MaxRequests,
+// empty otherwise.
+func (e *engine) MaxRequestsResult() string {
+	return e.scenarioService.MaxRequestsResult()
+}
+
+// TeardownWarning returns the error of the first failed teardown step, empty if the teardown succeeded.
+// It should be called after the test is done.
+func (e *engine) TeardownWarning() string {
+	if e.teardownErr == nil {
+		return ""
+	}
+	return fmt.Sprintf("teardown: %v", e.teardownErr)
+}
+
+// checkDryRun records the failed steps of the dry run iteration, the only iteration of the test.
+func (e *engine) checkDryRun(res *types.ScenarioResult) {
+	for _, sr := range res.StepResults {
+		if sr.Skipped {
+			continue
+		}
+		name := fmt.Sprintf("step %d", sr.StepID)
+		if sr.StepName != "" {
+			name = fmt.Sprintf("step %d (%s)", sr.StepID, sr.StepName)
+		}
+		if sr.Err.Type != "" {
+			e.dryRunFailures = append(e.dryRunFailures, fmt.Sprintf("%s: %s, %s", name, sr.Err.Type, sr.Err.Reason))
+		}
+		captures := make([]string, 0, len(sr.FailedCaptures))
+		for env := range sr.FailedCaptures {
+			captures = append(captures, env)
+		}
+		sort.Strings(captures)
+		for _, env := range captures {
+			e.dryRunFailures = append(e.dryRunFailures,
+				fmt.Sprintf("%s: capture %s failed, %s", name, env, sr.FailedCaptures[env]))
+		}
+		for _, fa := range sr.FailedAssertions {
+			e.dryRunFailures = append(e.dryRunFailures, fmt.Sprintf("%s: assertion %s failed", name, fa.Rule))
+		}
+		if len(sr.SchemaErrors) > 0 {
+			e.dryRunFailures = append(e.dryRunFailures,
+				fmt.Sprintf("%s: response schema violated, %s", name, sr.SchemaErrors[0]))
+		}
+	}
+}
+
+// DryRunFailures returns the failed steps of the Hammer.DryRun iteration with their reasons, empty if the steps
+// passed or the test is not a dry run. It should be called after the test is done.
+func (e *engine) DryRunFailures() []string {
+	return e.dryRunFailures
+}
+
+// BackpressureResult returns the policy of the Hammer.Backpressure and the iterations dropped, queued or
+// blocked by it, empty if the running iterations are unlimited.
+func (e *engine) BackpressureResult() string {
+	if e.backpressure == nil {
+		return ""
+	}
+	return e.backpressure.summary()
+}
+
+// AdaptiveResult returns the users at which the thresholds of the adaptive load are first crossed,
+// empty if the load is not adaptive.
+func (e *engine) AdaptiveResult() string {
+	if e.adaptive == nil {
+		return ""
+	}
+	return e.adaptive.summary()
+}
+
+// initOutputSink adds the sink of the output format, it waits for the output instead of dropping the results.
+func (e *engine) initOutputSink() error {
+	var sink types.ResultSink
+	if strings.EqualFold(e.hammer.OutputFormat, report.OutputFormatInflux) && e.hammer.Influx.URL != "" {
+		w, err := report.NewInfluxHTTPWriter(e.hammer.Influx)
+		if err != nil {
+			return err
+		}
+		sink = report.NewOutputSink(w, nil)
+	} else {
+		var err error
+		if sink, err = report.NewFileSink(e.hammer.OutputFormat, e.hammer.OutputFile); err != nil {
+			return err
+		}
+	}
+	e.sinks = append(e.sinks, report.NewSinkRunner(types.ResultSinkConf{Sink: sink, Policy: types.SinkPolicyBlock}))
+	return nil
 }
 
 func (e *engine) getMaxConcurrentIterCount() int {
+	if e.hammer.Adaptive != nil && !e.hammer.Debug {
+		return e.hammer.Adaptive.MaxUsers
+	}
+	if e.hammer.UserQuota != nil && !e.hammer.Debug {
+		return e.hammer.UserQuota.Users
+	}
 	max := 0
 	for _, v := range e.reqCountArr {
 		if v > max {
@@ -302,7 +1040,9 @@ func (e *engine) initReqCountArr() {
 	length := int(e.hammer.TestDuration * int(time.Second/(tickerInterval*time.Millisecond)))
 	e.reqCountArr = make([]int, length)
 
-	if e.hammer.TimeRunCountMap != nil {
+	if e.hammer.LoadPattern != nil {
+		e.createPatternReqCountArr()
+	} else if e.hammer.TimeRunCountMap != nil {
 		e.createManualReqCountArr()
 	} else {
 		switch e.hammer.LoadType {
@@ -333,6 +1073,19 @@ func (e *engine) createManualReqCountArr() {
 	}
 }
 
+func (e *engine) createPatternReqCountArr() {
+	tickPerSecond := int(time.Second / (tickerInterval * time.Millisecond))
+	for i := 0; i < e.hammer.TestDuration; i++ {
+		tickArrStartIndex := i * tickPerSecond
+		tickArrEndIndex := tickArrStartIndex + tickPerSecond
+		segment := e.reqCountArr[tickArrStartIndex:tickArrEndIndex]
+		createLinearDistArr(e.hammer.LoadPattern.RequestsAt(time.Duration(i)*time.Second), segment)
+	}
+
+	// iteration count is determined by the pattern, result channels and client pools are sized by it
+	e.hammer.IterationCount = arraySum(e.reqCountArr)
+}
+
 func (e *engine) createLinearReqCountArr() {
 	steps := make([]int, e.hammer.TestDuration)
 	createLinearDistArr(e.hammer.IterationCount, steps)
@@ -467,6 +1220,8 @@ var readTestData = func(testDataConf map[string]types.CsvConf) (map[string]types
 		if conf.Order == "random" {
 			csvData.Random = true
 		}
+		// round-robin is the sequential order that always wraps around
+		csvData.Circular = conf.IsCircular() || conf.Order == "round-robin"
 		readData[k] = csvData
 	}
 
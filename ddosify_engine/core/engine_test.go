@@ -30,14 +30,18 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -155,6 +159,30 @@ func TestReqCountArrDebugMode(t *testing.T) {
 	}
 }
 
+func TestReqCountArrLoadPattern(t *testing.T) {
+	t.Parallel()
+
+	hammer := newDummyHammer()
+	hammer.TestDuration = 2
+	hammer.IterationCount = 1 // overridden by the pattern
+	hammer.LoadPattern = &types.RampPattern{Start: 10, End: 30, Duration: 2 * time.Second}
+
+	es, err := InitEngineServices(hammer)
+	e, err := NewEngine(context.TODO(), hammer, es)
+	if err != nil {
+		t.Fatalf("TestReqCountArrLoadPattern error occurred %v", err)
+	}
+	e.Init()
+
+	expected := []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+	if !reflect.DeepEqual(e.reqCountArr, expected) {
+		t.Errorf("Expected: %v, Found: %v", expected, e.reqCountArr)
+	}
+	if e.hammer.IterationCount != 30 {
+		t.Errorf("Expected: %v, Found: %v", 30, e.hammer.IterationCount)
+	}
+}
+
 // TODO: Add other load types as you implement
 func TestRequestCount(t *testing.T) {
 	t.Parallel()
@@ -947,6 +975,175 @@ func TestCapturedEnvsFromJsonBody(t *testing.T) {
 
 }
 
+func TestCapturedTokenInjectedToHeaderAndUrl(t *testing.T) {
+	t.Parallel()
+
+	// login step returns a token, the next step passes it in the url and the Authorization header
+	token := "eyJhbGciOiJIUzI1NiJ9"
+	var gotAuthHeader, gotPath string
+
+	loginHandler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-User-Id", "user-42")
+		w.Write([]byte(`{"data":{"token":"` + token + `"}}`))
+	}
+	ordersHandler := func(w http.ResponseWriter, r *http.Request) {
+		gotAuthHeader = r.Header.Get("Authorization")
+		gotPath = r.URL.Path
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", loginHandler)
+	mux.HandleFunc("/users/", ordersHandler)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Prepare
+	h := newDummyHammer()
+	h.Scenario.Steps = make([]types.ScenarioStep, 2)
+	jsonPath := "$.data.token"
+	headerKey := "X-User-Id"
+	h.Scenario.Steps[0] = types.ScenarioStep{
+		ID:     1,
+		Method: "POST",
+		URL:    server.URL + "/login",
+		EnvsToCapture: []types.EnvCaptureConf{
+			{Name: "token", From: types.Body, JsonPath: &jsonPath},
+			{Name: "userId", From: types.Header, Key: &headerKey},
+		},
+	}
+	h.Scenario.Steps[1] = types.ScenarioStep{
+		ID:     2,
+		Method: "GET",
+		URL:    server.URL + "/users/{{userId}}/orders",
+		Headers: map[string]string{
+			"Authorization": "Bearer {{token}}",
+		},
+	}
+
+	// Act
+	es, err := InitEngineServices(h)
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Errorf("TestCapturedTokenInjectedToHeaderAndUrl error occurred %v", err)
+	}
+
+	err = e.Init()
+	if err != nil {
+		t.Errorf("TestCapturedTokenInjectedToHeaderAndUrl error occurred %v", err)
+	}
+
+	e.Start()
+
+	if gotAuthHeader != "Bearer "+token {
+		t.Errorf("TestCapturedTokenInjectedToHeaderAndUrl expected: %s, got: %s", "Bearer "+token, gotAuthHeader)
+	}
+	if gotPath != "/users/user-42/orders" {
+		t.Errorf("TestCapturedTokenInjectedToHeaderAndUrl expected: %s, got: %s", "/users/user-42/orders", gotPath)
+	}
+}
+
+func TestProxyAndNoProxy(t *testing.T) {
+	t.Parallel()
+
+	var targetHit, proxyHit int32
+	var proxyAuth atomic.Value
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&targetHit, 1)
+	}))
+	defer target.Close()
+
+	// acts as a forward proxy for http targets, requests come with absolute urls
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxyHit, 1)
+		proxyAuth.Store(r.Header.Get("Proxy-Authorization"))
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, _ := url.Parse(proxyServer.URL)
+	proxyURL.User = url.UserPassword("user", "pass")
+	targetURL, _ := url.Parse(target.URL)
+
+	tests := []struct {
+		name              string
+		noProxy           []string
+		expectedTargetHit int32
+		expectedProxyHit  int32
+	}{
+		{"OverProxy", nil, 0, 1},
+		{"NoProxy", []string{targetURL.Host}, 1, 0},
+	}
+
+	for _, test := range tests {
+		atomic.StoreInt32(&targetHit, 0)
+		atomic.StoreInt32(&proxyHit, 0)
+
+		h := newDummyHammer()
+		h.Scenario.Steps[0].URL = target.URL
+		h.Proxy = proxy.Proxy{Strategy: proxy.ProxyTypeSingle, Addr: proxyURL, NoProxy: test.noProxy}
+
+		es, err := InitEngineServices(h)
+		e, err := NewEngine(context.TODO(), h, es)
+		if err != nil {
+			t.Fatalf("TestProxyAndNoProxy error occurred %v", err)
+		}
+		if err = e.Init(); err != nil {
+			t.Fatalf("TestProxyAndNoProxy error occurred %v", err)
+		}
+		e.Start()
+
+		if atomic.LoadInt32(&targetHit) != test.expectedTargetHit {
+			t.Errorf("%s target hit, Expected: %v, Found: %v", test.name, test.expectedTargetHit, targetHit)
+		}
+		if atomic.LoadInt32(&proxyHit) != test.expectedProxyHit {
+			t.Errorf("%s proxy hit, Expected: %v, Found: %v", test.name, test.expectedProxyHit, proxyHit)
+		}
+	}
+
+	expectedAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	if proxyAuth.Load() != expectedAuth {
+		t.Errorf("Expected: %v, Found: %v", expectedAuth, proxyAuth.Load())
+	}
+}
+
+func TestOutputFileWrittenPerRequest(t *testing.T) {
+	t.Parallel()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 3
+	h.Scenario.Steps[0].URL = server.URL
+	h.OutputFormat = "json"
+	h.OutputFile = filepath.Join(t.TempDir(), "results.json")
+
+	es, err := InitEngineServices(h)
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestOutputFileWrittenPerRequest error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestOutputFileWrittenPerRequest error occurred %v", err)
+	}
+	e.Start()
+
+	content, err := ioutil.ReadFile(h.OutputFile)
+	if err != nil {
+		t.Fatalf("TestOutputFileWrittenPerRequest error occurred %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 {
+		t.Errorf("Expected: %v, Found: %v", 3, len(lines))
+	}
+	for _, l := range lines {
+		if !strings.Contains(l, `"status_code":200`) {
+			t.Errorf("Expected status code 200 in record, Found: %s", l)
+		}
+	}
+}
+
 func TestContinueTestOnCaptureError(t *testing.T) {
 	t.Parallel()
 
@@ -1871,6 +2068,10 @@ func TestDataCsv(t *testing.T) {
 	if !reflect.DeepEqual(csvData.Random, expectedRandom) {
 		t.Errorf("TestCreateHammerDataCsv got: %t expected: %t", csvData.Random, expectedRandom)
 	}
+	// circular unless set to false
+	if !csvData.Circular {
+		t.Errorf("TestCreateHammerDataCsv got: %t expected: %t", csvData.Circular, true)
+	}
 
 	expectedRow := map[string]interface{}{
 		"name": "Kenan",
@@ -2631,3 +2832,693 @@ z0xcfR+tEmGlvxaHqXlQg9o=
 
 	return cert, certKey
 }
+
+func TestOnResultCalledPerRequest(t *testing.T) {
+	t.Parallel()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 3
+	h.Scenario.Steps = []types.ScenarioStep{
+		{ID: 1, Name: "ok", Method: http.MethodGet, URL: server.URL + "/ok"},
+		{ID: 2, Name: "fail", Method: http.MethodPost, URL: server.URL + "/fail"},
+	}
+
+	var mu sync.Mutex
+	statusCounts := map[string]int{}
+	h.OnResult = func(r *types.ScenarioStepResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		statusCounts[fmt.Sprintf("%s %s %s %d", r.StepName, r.Method, r.Url, r.StatusCode)]++
+	}
+
+	es, err := InitEngineServices(h)
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestOnResultCalledPerRequest error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestOnResultCalledPerRequest error occurred %v", err)
+	}
+	e.Start()
+
+	// all the results are delivered when Start returns
+	mu.Lock()
+	defer mu.Unlock()
+	expected := map[string]int{
+		fmt.Sprintf("ok GET %s/ok 200", server.URL):      3,
+		fmt.Sprintf("fail POST %s/fail 500", server.URL): 3,
+	}
+	if !reflect.DeepEqual(statusCounts, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, statusCounts)
+	}
+	if e.DroppedResults() != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, e.DroppedResults())
+	}
+}
+
+type countingSink struct {
+	mu     sync.Mutex
+	counts map[string]int
+	closed bool
+}
+
+func (s *countingSink) Write(r *types.ScenarioStepResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[fmt.Sprintf("%s %d", r.StepName, r.StatusCode)]++
+	return nil
+}
+
+func (s *countingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestResultSinksReceiveResults(t *testing.T) {
+	t.Parallel()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 3
+	h.Scenario.Steps = []types.ScenarioStep{
+		{ID: 1, Name: "ok", Method: http.MethodGet, URL: server.URL + "/ok"},
+		{ID: 2, Name: "fail", Method: http.MethodPost, URL: server.URL + "/fail"},
+	}
+
+	blocking := &countingSink{counts: map[string]int{}}
+	dropping := &countingSink{counts: map[string]int{}}
+	h.ResultSinks = []types.ResultSinkConf{
+		{Sink: blocking, Policy: types.SinkPolicyBlock},
+		{Sink: dropping, BufferSize: 16, Policy: types.SinkPolicyDrop},
+	}
+
+	es, err := InitEngineServices(h)
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestResultSinksReceiveResults error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestResultSinksReceiveResults error occurred %v", err)
+	}
+	e.Start()
+
+	// the sinks are drained and closed when Start returns
+	expected := map[string]int{"ok 200": 3, "fail 500": 3}
+	for _, s := range []*countingSink{blocking, dropping} {
+		s.mu.Lock()
+		if !reflect.DeepEqual(s.counts, expected) {
+			t.Errorf("Expected %v, Found: %v", expected, s.counts)
+		}
+		if !s.closed {
+			t.Errorf("Expected the sink to be closed")
+		}
+		s.mu.Unlock()
+	}
+	if e.DroppedResults() != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, e.DroppedResults())
+	}
+}
+
+func TestWarmupExcludedFromResults(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 20
+	h.TestDuration = 2
+	h.Warmup = time.Second
+	h.Scenario.Steps[0].URL = server.URL
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestWarmupExcludedFromResults error occurred %v", err)
+	}
+	collector := report.NewCollector()
+	collector.Init(false, 0, 0)
+	es.ReportServ = collector
+
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestWarmupExcludedFromResults error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestWarmupExcludedFromResults error occurred %v", err)
+	}
+	e.Start()
+
+	// iterations are spread linearly over the duration, about half of them start in the warm-up
+	r := collector.Snapshot().Result
+	if r.WarmupCount < 5 || r.SuccessCount < 5 || r.WarmupCount+r.SuccessCount != 20 {
+		t.Errorf("Expected %v, Found: %v", "10 warm-up and 10 aggregated iterations",
+			[]int64{r.WarmupCount, r.SuccessCount})
+	}
+}
+
+func TestRequestedLoad(t *testing.T) {
+	t.Parallel()
+
+	h := newDummyHammer()
+	h.Warmup = 500 * time.Millisecond
+	e := &engine{hammer: h, reqCountArr: []int{1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 3, 3, 3, 3, 3}}
+
+	e.iterationStarted()
+	e.iterationStarted()
+	atomic.AddInt64(&e.activeIterations, -1)
+	e.iterationStarted()
+	atomic.AddInt64(&e.activeIterations, -2)
+
+	load := e.requestedLoad()
+	if load.Iterations != 25 || load.Duration != time.Second {
+		t.Errorf("Expected %v, Found: %v", "25 iterations in 1s", []interface{}{load.Iterations, load.Duration})
+	}
+	if load.MaxInFlight() != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, load.MaxInFlight())
+	}
+}
+
+func TestRequestedLoadRampDown(t *testing.T) {
+	t.Parallel()
+
+	h := newDummyHammer()
+	h.Warmup = 500 * time.Millisecond
+	h.RampDown = 500 * time.Millisecond
+	e := &engine{hammer: h, reqCountArr: []int{1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 3, 3, 3, 3, 3}}
+
+	load := e.requestedLoad()
+	if load.Iterations != 10 || load.Duration != 500*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v", "10 iterations in 500ms", []interface{}{load.Iterations, load.Duration})
+	}
+}
+
+func TestOnlyTagsSkipsSteps(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	paths := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 3
+	h.Scenario.Steps = []types.ScenarioStep{
+		{ID: 1, Method: "GET", URL: server.URL + "/login"},
+		{ID: 2, Method: "GET", URL: server.URL + "/pay", Tags: []string{"payments"}},
+	}
+	h.OnlyTags = []string{"payments"}
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestOnlyTagsSkipsSteps error occurred %v", err)
+	}
+	collector := report.NewCollector()
+	collector.Init(false, 0, 0)
+	es.ReportServ = collector
+
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestOnlyTagsSkipsSteps error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestOnlyTagsSkipsSteps error occurred %v", err)
+	}
+	e.Start()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if paths["/login"] != 0 || paths["/pay"] != 3 {
+		t.Errorf("Expected %v, Found: %v", "3 requests to /pay only", paths)
+	}
+	r := collector.Snapshot().Result
+	if sr, ok := r.StepResults[2]; len(r.StepResults) != 1 || !ok || !reflect.DeepEqual(sr.Tags, []string{"payments"}) {
+		t.Errorf("Expected %v, Found: %v", "only the tagged step in the result", r.StepResults)
+	}
+}
+
+func TestStartDelay(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		jitter  time.Duration
+		spread  time.Duration
+		elapsed time.Duration
+		max     time.Duration
+	}{
+		{"None", 0, 0, 0, 0},
+		{"Jitter", 500 * time.Millisecond, 0, time.Second, 500 * time.Millisecond},
+		{"Spread", 0, 2 * time.Second, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"SpreadEnded", 0, 2 * time.Second, 3 * time.Second, 0},
+		{"Both", 100 * time.Millisecond, time.Second, 0, 1100 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			h := newDummyHammer()
+			h.Jitter = test.jitter
+			h.StartupSpread = test.spread
+			e := &engine{hammer: h}
+			rnd := rand.New(rand.NewSource(1))
+
+			for i := 0; i < 100; i++ {
+				d := e.startDelay(rnd, test.elapsed)
+				if d < 0 || d > test.max {
+					t.Errorf("Expected %v, Found: %v", fmt.Sprintf("delay in [0, %v]", test.max), d)
+				}
+			}
+		})
+	}
+}
+
+func TestJitterRunsAllIterations(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 10
+	h.Jitter = 300 * time.Millisecond
+	h.StartupSpread = 500 * time.Millisecond
+	h.Scenario.Steps[0].URL = server.URL
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestJitterRunsAllIterations error occurred %v", err)
+	}
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestJitterRunsAllIterations error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestJitterRunsAllIterations error occurred %v", err)
+	}
+	e.Start()
+
+	if c := atomic.LoadInt64(&count); c != 10 {
+		t.Errorf("Expected %v, Found: %v", 10, c)
+	}
+}
+
+func TestStopOnAbortsTest(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 100
+	h.TestDuration = 10
+	h.StopOn = []string{"error_rate > 50% over 1s"}
+	h.Scenario.Steps[0].URL = server.URL
+	h.Scenario.Steps[0].Assertions = []string{"status_code == 200"}
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestStopOnAbortsTest error occurred %v", err)
+	}
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestStopOnAbortsTest error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestStopOnAbortsTest error occurred %v", err)
+	}
+
+	if res := e.Start(); res != resultAborted {
+		t.Errorf("Expected %v, Found: %v", resultAborted, res)
+	}
+	if r := e.StopReason(); r != "error_rate > 50% over 1s, received 100.0%" {
+		t.Errorf("Expected %v, Found: %v", "error_rate > 50% over 1s, received 100.0%", r)
+	}
+	if !e.IsTestFailed() {
+		t.Errorf("Expected %v, Found: %v", true, e.IsTestFailed())
+	}
+}
+
+func TestPauseHoldsIterations(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 10
+	h.ControlAddr = "127.0.0.1:0"
+	h.Scenario.Steps[0].URL = server.URL
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestPauseHoldsIterations error occurred %v", err)
+	}
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestPauseHoldsIterations error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestPauseHoldsIterations error occurred %v", err)
+	}
+
+	e.pause.pause()
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		if c := atomic.LoadInt64(&count); c != 0 {
+			t.Errorf("Expected %v, Found: %v", 0, c)
+		}
+		e.pause.resume()
+	}()
+	e.Start()
+
+	if c := atomic.LoadInt64(&count); c != 10 {
+		t.Errorf("Expected %v, Found: %v", 10, c)
+	}
+	if p := e.Result().PausedDuration; p < 0.5 {
+		t.Errorf("Expected at least %v, Found: %v", 0.5, p)
+	}
+}
+
+func TestPauseKeepsRampDown(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 20
+	h.TestDuration = 2
+	h.RampDown = 500 * time.Millisecond
+	h.ControlAddr = "127.0.0.1:0"
+	h.Scenario.Steps[0].URL = server.URL
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestPauseKeepsRampDown error occurred %v", err)
+	}
+	collector := report.NewCollector()
+	collector.Init(false, 0, 0)
+	es.ReportServ = collector
+
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestPauseKeepsRampDown error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestPauseKeepsRampDown error occurred %v", err)
+	}
+
+	e.pause.pause()
+	time.AfterFunc(time.Second, func() { e.pause.resume() })
+	e.Start()
+
+	// the schedule is shifted by the pause, only the iterations of the last quarter of the ticks are in the ramp-down
+	r := collector.Snapshot().Result
+	if r.RampDownCount < 2 || r.RampDownCount > 8 || r.RampDownCount+r.SuccessCount != 20 {
+		t.Errorf("Expected %v, Found: %v", "5 ramp-down and 15 aggregated iterations",
+			[]int64{r.RampDownCount, r.SuccessCount})
+	}
+}
+
+func TestUserQuotaRunsAllIterations(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.UserQuota = &types.UserQuota{Users: 3, Iterations: 4}
+	h.Scenario.Steps[0].URL = server.URL
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestUserQuotaRunsAllIterations error occurred %v", err)
+	}
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestUserQuotaRunsAllIterations error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestUserQuotaRunsAllIterations error occurred %v", err)
+	}
+
+	if res := e.Start(); res != resultDone {
+		t.Errorf("Expected %v, Found: %v", resultDone, res)
+	}
+	if c := atomic.LoadInt64(&count); c != 12 {
+		t.Errorf("Expected %v, Found: %v", 12, c)
+	}
+}
+
+func TestMaxRequestsStopsTest(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 100
+	h.TestDuration = 10
+	h.MaxRequests = 20
+	h.Scenario.Steps[0].URL = server.URL
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestMaxRequestsStopsTest error occurred %v", err)
+	}
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestMaxRequestsStopsTest error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestMaxRequestsStopsTest error occurred %v", err)
+	}
+
+	start := time.Now()
+	if res := e.Start(); res != resultDone {
+		t.Errorf("Expected %v, Found: %v", resultDone, res)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Expected the test to stop early, Found: %v", d)
+	}
+	if c := atomic.LoadInt64(&count); c != 20 {
+		t.Errorf("Expected %v, Found: %v", 20, c)
+	}
+	if c := e.Result().SuccessCount; c != 20 {
+		t.Errorf("Expected %v, Found: %v", 20, c)
+	}
+	if r := e.MaxRequestsResult(); r != "20 requests are sent" {
+		t.Errorf("Expected %v, Found: %v", "20 requests are sent", r)
+	}
+}
+
+func TestBackpressureDropsIterations(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	h := newDummyHammer()
+	h.IterationCount = 50
+	h.TestDuration = 1
+	h.Backpressure = &types.Backpressure{Policy: types.BackpressureDrop, MaxInFlight: 2}
+	h.Scenario.Steps[0].URL = server.URL
+
+	es, err := InitEngineServices(h)
+	if err != nil {
+		t.Fatalf("TestBackpressureDropsIterations error occurred %v", err)
+	}
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestBackpressureDropsIterations error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestBackpressureDropsIterations error occurred %v", err)
+	}
+	e.Start()
+
+	if max := atomic.LoadInt64(&maxInFlight); max > 2 {
+		t.Errorf("Expected at most %v in-flight requests, Found: %v", 2, max)
+	}
+	if r := e.Result(); r.SuccessCount+atomic.LoadInt64(&e.backpressure.dropped) != 50 {
+		t.Errorf("Expected %v iterations sent or dropped, Found: %v sent, %v dropped", 50, r.SuccessCount,
+			e.backpressure.dropped)
+	}
+	if r := e.BackpressureResult(); !strings.HasPrefix(r, "drop policy at 2 in-flight iterations") {
+		t.Errorf("Unexpected backpressure result: %s", r)
+	}
+}
+
+func TestEngineDryRun(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+
+	jsonPath := "token"
+	steps := []types.ScenarioStep{
+		{ID: 1, Name: "login", Method: "GET", URL: server.URL, Assertions: []string{"status_code == 200"},
+			EnvsToCapture: []types.EnvCaptureConf{{Name: "token", From: types.Body, JsonPath: &jsonPath}}},
+		{ID: 2, Method: "GET", URL: server.URL, Assertions: []string{"status_code == 201"}},
+	}
+	tests := []struct {
+		name     string
+		steps    []types.ScenarioStep
+		failures []string // prefixes of the failures
+	}{
+		{"Passed", steps[1:1], nil},
+		{"Failed", steps, []string{
+			"step 1 (login): capture token failed, ",
+			"step 2: assertion status_code == 201 failed",
+		}},
+	}
+
+	for _, test := range tests {
+		h := newDummyHammer()
+		h.Debug, h.DryRun = true, true
+		h.Scenario.Steps = append([]types.ScenarioStep{{ID: 3, Method: "GET", URL: server.URL}}, test.steps...)
+
+		es, _ := InitEngineServices(h)
+		e, err := NewEngine(context.TODO(), h, es)
+		if err != nil {
+			t.Fatalf("%s error occurred %v", test.name, err)
+		}
+		if err = e.Init(); err != nil {
+			t.Fatalf("%s error occurred %v", test.name, err)
+		}
+		e.Start()
+
+		if e.IsTestFailed() != (test.failures != nil) {
+			t.Errorf("%s Expected failed: %v, Found: %v", test.name, test.failures != nil, e.IsTestFailed())
+		}
+		failures := e.DryRunFailures()
+		if len(failures) != len(test.failures) {
+			t.Fatalf("%s Expected %v, Found: %v", test.name, test.failures, failures)
+		}
+		for i, f := range failures {
+			if !strings.HasPrefix(f, test.failures[i]) {
+				t.Errorf("%s Expected %v, Found: %v", test.name, test.failures[i], f)
+			}
+		}
+	}
+}
+
+func TestEngineMaxDuration(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// wedged target, the requests are only completed by their cancellation
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	h := newDummyHammer()
+	h.ReportDestination = report.OutputTypeNone
+	h.TestDuration = 30
+	h.IterationCount = 30
+	h.GracePeriod = time.Minute
+	h.MaxDuration = 500 * time.Millisecond
+	h.Scenario.Steps = []types.ScenarioStep{{ID: 1, Method: "GET", URL: server.URL, Timeout: 60}}
+	h.Scenario.Teardown = []types.ScenarioStep{{ID: 2, Method: "GET", URL: server.URL, Timeout: 60}}
+
+	es, _ := InitEngineServices(h)
+	e, err := NewEngine(context.TODO(), h, es)
+	if err != nil {
+		t.Fatalf("TestEngineMaxDuration error occurred %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("TestEngineMaxDuration error occurred %v", err)
+	}
+
+	start := time.Now()
+	e.Start()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the test to stop in %v, Found: %v", 5*time.Second, elapsed)
+	}
+	if !e.IsTestFailed() {
+		t.Errorf("Expected the test to fail")
+	}
+	if reason := e.HardTimeout(); !strings.HasPrefix(reason, "hard timeout") {
+		t.Errorf("Expected %v, Found: %v", "hard timeout", reason)
+	}
+}
+
+func TestEngineSampled(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rate     float64
+		expected int
+	}{
+		{0, 1000},
+		{0.1, 100},
+		{0.25, 250},
+		{1, 1000},
+	}
+	for _, test := range tests {
+		e := &engine{hammer: types.Hammer{SampleRate: test.rate}}
+		sampled := 0
+		for i := 0; i < 1000; i++ {
+			if e.sampled() {
+				sampled++
+			}
+		}
+		if sampled != test.expected {
+			t.Errorf("Expected %v, Found: %v", test.expected, sampled)
+		}
+	}
+}
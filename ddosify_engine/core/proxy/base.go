@@ -36,6 +36,9 @@ type Proxy struct {
 	// Set this field if ProxyStrategy is single
 	Addr *url.URL
 
+	// Targets that are reached directly instead of over the proxy, see BypassProxy for the rule syntax.
+	NoProxy []string
+
 	// Dynamic field for other proxy strategies.
 	Others map[string]interface{}
 }
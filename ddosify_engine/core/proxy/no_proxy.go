@@ -0,0 +1,103 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package proxy
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// SupportedSchemes are the proxy url schemes supported by the http transport.
+var SupportedSchemes = [...]string{"http", "https", "socks5", "socks5h"}
+
+// BypassProxy reports whether the target should be reached directly, without the proxy, according to noProxy rules.
+// A rule can be "*" for all hosts, a domain name that matches itself and its subdomains ("example.com"),
+// a subdomain only pattern (".example.com"), an ip address or a cidr block. Rules can be suffixed by a port.
+// Target can be given as a full url or as host[:port].
+func BypassProxy(target string, noProxy []string) bool {
+	if len(noProxy) == 0 {
+		return false
+	}
+
+	host, port := splitTarget(target)
+	if host == "" {
+		return false
+	}
+
+	for _, rule := range noProxy {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" {
+			continue
+		}
+		if rule == "*" {
+			return true
+		}
+
+		if _, cidr, err := net.ParseCIDR(rule); err == nil {
+			if ip := net.ParseIP(host); ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		ruleHost, rulePort := rule, ""
+		if h, p, err := net.SplitHostPort(rule); err == nil {
+			ruleHost, rulePort = h, p
+		}
+		if rulePort != "" && rulePort != port {
+			continue
+		}
+
+		if strings.HasPrefix(ruleHost, ".") {
+			if strings.HasSuffix(host, ruleHost) {
+				return true
+			}
+			continue
+		}
+		if host == ruleHost || strings.HasSuffix(host, "."+ruleHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTarget returns the lower cased host and the port of the target, port is filled by the scheme if missing.
+func splitTarget(target string) (host, port string) {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", ""
+	}
+
+	host, port = strings.ToLower(u.Hostname()), u.Port()
+	if port == "" {
+		switch strings.ToLower(u.Scheme) {
+		case "https", "wss", "grpcs":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return host, port
+}
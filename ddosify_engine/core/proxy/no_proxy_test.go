@@ -0,0 +1,44 @@
+package proxy
+
+import "testing"
+
+func TestBypassProxy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		target   string
+		noProxy  []string
+		expected bool
+	}{
+		{"NoRules", "https://example.com", nil, false},
+		{"Wildcard", "https://example.com", []string{"*"}, true},
+		{"ExactDomain", "https://example.com/path", []string{"example.com"}, true},
+		{"Subdomain", "https://api.example.com", []string{"example.com"}, true},
+		{"SubdomainOnlyRule", "https://example.com", []string{".example.com"}, false},
+		{"SubdomainOnlyRuleMatch", "https://api.example.com", []string{".example.com"}, true},
+		{"SuffixIsNotSubdomain", "https://notexample.com", []string{"example.com"}, false},
+		{"CaseInsensitive", "https://API.Example.com", []string{" example.COM "}, true},
+		{"OtherDomain", "https://ddosify.com", []string{"example.com", "test.com"}, false},
+		{"SecondRule", "https://test.com", []string{"example.com", "test.com"}, true},
+		{"IP", "http://10.0.0.5:8080", []string{"10.0.0.5"}, true},
+		{"Cidr", "http://10.0.0.5:8080", []string{"10.0.0.0/8"}, true},
+		{"CidrNoMatch", "http://192.168.1.5", []string{"10.0.0.0/8"}, false},
+		{"CidrDomainTarget", "http://example.com", []string{"10.0.0.0/8"}, false},
+		{"PortMatch", "http://example.com:8080", []string{"example.com:8080"}, true},
+		{"PortMismatch", "http://example.com:9090", []string{"example.com:8080"}, false},
+		{"DefaultPortByScheme", "https://example.com", []string{"example.com:443"}, true},
+		{"WithoutScheme", "example.com:80", []string{"example.com:80"}, true},
+		{"EmptyRule", "http://example.com", []string{""}, false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			if found := BypassProxy(test.target, test.noProxy); found != test.expected {
+				t.Errorf("Expected %v, Found: %v", test.expected, found)
+			}
+		})
+	}
+}
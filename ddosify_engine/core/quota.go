@@ -0,0 +1,91 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runUserQuota runs the users of the Hammer.UserQuota, each one runs its iterations back to back.
+// The test is done when all the users complete their iterations.
+func (e *engine) runUserQuota(stopChan <-chan struct{}) string {
+	stop := make(chan struct{}) // stops the users when the test is stopped or aborted
+	defer close(stop)
+
+	var users sync.WaitGroup
+	rnd := e.rng.Stream("start", 0)
+	for u := 0; u < e.hammer.UserQuota.Users; u++ {
+		users.Add(1)
+		e.wg.Add(1)
+		go e.runQuotaUser(stop, e.startDelay(rnd, 0), &users)
+	}
+	done := make(chan struct{})
+	go func() {
+		users.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-e.ctx.Done():
+		return resultStopped
+	case <-e.abortChan:
+		e.testSuccess = false
+		return resultAborted
+	case <-stopChan:
+		return resultAborted
+	case <-e.maxRequestsChan:
+		return resultDone
+	case <-done:
+		return resultDone
+	}
+}
+
+// runQuotaUser runs the iterations of a user of the quota after the start delay, until stop is closed.
+func (e *engine) runQuotaUser(stop <-chan struct{}, delay time.Duration, users *sync.WaitGroup) {
+	defer e.wg.Done()
+	defer users.Done()
+	if delay > 0 && !sleepContext(e.ctx, delay) {
+		return
+	}
+	u, ok := e.userClient()
+	if !ok {
+		return
+	}
+	defer e.scenarioService.ReleaseUserClient(u)
+	for i := 0; i < e.hammer.UserQuota.Iterations; i++ {
+		select {
+		case <-stop:
+			return
+		case <-e.ctx.Done():
+			return
+		default:
+		}
+		if e.pause != nil && e.pause.paused() && !e.pause.wait(e.ctx) {
+			return
+		}
+		e.iterationStarted()
+		start := time.Now()
+		e.runWorker(start, u, e.inRampDown(start))
+		atomic.AddInt64(&e.activeIterations, -1)
+	}
+}
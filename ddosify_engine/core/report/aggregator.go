@@ -21,6 +21,8 @@
 package report
 
 import (
+	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -29,9 +31,27 @@ import (
 )
 
 func aggregate(result *Result, scr *types.ScenarioResult, samplingCount map[uint16]map[string]int, samplingRate int) {
+	if scr.Warmup {
+		result.WarmupCount++
+		return
+	}
+	if scr.RampDown {
+		result.RampDownCount++
+		return
+	}
+	if result.measureStart.IsZero() || scr.StartTime.Before(result.measureStart) {
+		result.measureStart = scr.StartTime
+	}
+	for _, sr := range scr.StepResults {
+		if end := sr.RequestTime.Add(sr.Duration); !sr.RequestTime.IsZero() && end.After(result.measureEnd) {
+			result.measureEnd = end
+		}
+	}
+
 	var scenarioDuration float32
 	errOccured := false
 	assertionFail := false
+	localFail := false
 	for _, sr := range scr.StepResults {
 		scenarioDuration += float32(sr.Duration.Seconds())
 
@@ -42,18 +62,78 @@ func aggregate(result *Result, scr *types.ScenarioResult, samplingCount map[uint
 		if _, ok := result.StepResults[sr.StepID]; !ok {
 			result.StepResults[sr.StepID] = &ScenarioStepResultSummary{
 				Name:           sr.StepName,
+				Tags:           sr.Tags,
 				StatusCodeDist: make(map[int]int, 0),
 				Fail:           fv,
 				Durations:      map[string]float32{},
 				SuccessCount:   0,
+				latencies:      result.newLatencies(),
 			}
 		}
 		stepResult := result.StepResults[sr.StepID]
+		if sr.Skipped {
+			stepResult.SkippedCount++
+			continue
+		}
+		stepResult.RetryCount += int64(sr.Retries)
+		if sr.Revalidated {
+			stepResult.RevalidatedCount++
+			if sr.StatusCode == http.StatusNotModified {
+				stepResult.NotModifiedCount++
+			}
+		}
+		if sr.Target != "" {
+			stepResult.addTarget(sr, !scr.Unsampled)
+		}
+		if sr.Cert != nil {
+			result.addCert(sr.StepID, sr.Cert)
+		}
+		if sr.RespBodyTruncated {
+			stepResult.TruncatedCount++
+		}
+		switch sr.Conn {
+		case types.ConnNew:
+			stepResult.NewConnCount++
+		case types.ConnReused:
+			stepResult.ReusedConnCount++
+		}
+		if n := len(sr.EventIntervals); n > 0 {
+			var total float32
+			for _, i := range sr.EventIntervals {
+				total += float32(i.Seconds())
+			}
+			stepResult.AvgEventInterval = (stepResult.AvgEventInterval*float32(stepResult.EventCount) + total) /
+				float32(stepResult.EventCount+int64(n))
+			stepResult.EventCount += int64(n)
+		}
+		stepResult.SentMessageCount += int64(sr.MessagesSent)
+		if sr.DecompressedLength > 0 {
+			stepResult.CompressedCount++
+			stepResult.CompressedBytes += sr.ContentLength
+			stepResult.DecompressedBytes += sr.DecompressedLength
+		}
+		if sr.ReqCompressedBodyLength > 0 {
+			stepResult.CompressedReqCount++
+			stepResult.ReqBodyBytes += sr.ReqBodyLength
+			stepResult.CompressedReqBodyBytes += sr.ReqCompressedBodyLength
+		}
+		if sr.CompressionFallback {
+			stepResult.CompressionFallbackCount++
+		}
+		if sr.Throttled {
+			stepResult.ThrottledCount++
+		}
+		if sr.UploadedBytes > 0 {
+			stepResult.UploadCount++
+			stepResult.UploadedBytes += sr.UploadedBytes
+			stepResult.UploadTime += sr.UploadDuration.Seconds()
+		}
 
 		if len(sr.FailedAssertions) > 0 { // assertion error
 			errOccured = true
 			assertionFail = true
 			stepResult.Fail.Count++
+			stepResult.Fail.addCategory(sr.ErrCategory)
 			stepResult.Fail.AssertionErrorDist.Count++
 			stepResult.StatusCodeDist[sr.StatusCode]++
 			for _, fa := range sr.FailedAssertions {
@@ -93,15 +173,47 @@ func aggregate(result *Result, scr *types.ScenarioResult, samplingCount map[uint
 			}
 			totalDur := float32(stepResult.SuccessCount+stepResult.Fail.Count-1)*stepResult.Durations["duration"] + float32(sr.Duration.Seconds())
 			stepResult.Durations["duration"] = totalDur / float32(stepResult.SuccessCount+stepResult.Fail.Count)
+			if !scr.Unsampled {
+				stepResult.latencies.record(sr.Duration)
+				stepResult.recordStatusLatency(sr.StatusCode, sr.Duration)
+			}
+			for k, v := range sr.Custom {
+				if strings.Contains(k, "Duration") {
+					totalDur := float32(stepResult.SuccessCount+stepResult.Fail.Count-1)*stepResult.Durations[k] + float32(v.(time.Duration).Seconds())
+					stepResult.Durations[k] = float32(totalDur / float32(stepResult.SuccessCount+stepResult.Fail.Count))
+				}
+			}
+		} else if len(sr.SchemaErrors) > 0 { // response schema violation, counted as an assertion error in iterations
+			errOccured = true
+			assertionFail = true
+			stepResult.Fail.Count++
+			stepResult.Fail.addCategory(sr.ErrCategory)
+			stepResult.StatusCodeDist[sr.StatusCode]++
+			if stepResult.Fail.SchemaErrorDist == nil {
+				stepResult.Fail.SchemaErrorDist = &SchemaErrVerbose{Messages: make(map[string]int)}
+			}
+			stepResult.Fail.SchemaErrorDist.add(sr.SchemaErrors)
+
+			totalDur := float32(stepResult.SuccessCount+stepResult.Fail.Count-1)*stepResult.Durations["duration"] + float32(sr.Duration.Seconds())
+			stepResult.Durations["duration"] = totalDur / float32(stepResult.SuccessCount+stepResult.Fail.Count)
+			if !scr.Unsampled {
+				stepResult.latencies.record(sr.Duration)
+				stepResult.recordStatusLatency(sr.StatusCode, sr.Duration)
+			}
 			for k, v := range sr.Custom {
 				if strings.Contains(k, "Duration") {
 					totalDur := float32(stepResult.SuccessCount+stepResult.Fail.Count-1)*stepResult.Durations[k] + float32(v.(time.Duration).Seconds())
 					stepResult.Durations[k] = float32(totalDur / float32(stepResult.SuccessCount+stepResult.Fail.Count))
 				}
 			}
+		} else if sr.Err.Local() { // load generator error, not counted as a failure of the target
+			localFail = true
+			stepResult.LocalErrorCount++
+			result.LocalErrorCount++
 		} else if sr.Err.Type != "" { // server error
 			errOccured = true
 			stepResult.Fail.Count++
+			stepResult.Fail.addCategory(sr.ErrCategory)
 			stepResult.Fail.ServerErrorDist.Count++
 			stepResult.Fail.ServerErrorDist.Reasons[sr.Err.Reason]++
 		} else { // success
@@ -110,9 +222,13 @@ func aggregate(result *Result, scr *types.ScenarioResult, samplingCount map[uint
 
 			totalDur := float32(stepResult.SuccessCount+stepResult.Fail.Count-1)*stepResult.Durations["duration"] + float32(sr.Duration.Seconds())
 			stepResult.Durations["duration"] = totalDur / float32(stepResult.SuccessCount+stepResult.Fail.Count)
+			if !scr.Unsampled {
+				stepResult.latencies.record(sr.Duration)
+				stepResult.recordStatusLatency(sr.StatusCode, sr.Duration)
+			}
 			for k, v := range sr.Custom {
 				if strings.Contains(k, "Duration") {
-					totalDur := float32(stepResult.SuccessCount-1)*stepResult.Durations[k] + float32(v.(time.Duration).Seconds())
+					totalDur := float32(stepResult.SuccessCount+stepResult.Fail.Count-1)*stepResult.Durations[k] + float32(v.(time.Duration).Seconds())
 					stepResult.Durations[k] = float32(totalDur / float32(stepResult.SuccessCount+stepResult.Fail.Count))
 				}
 			}
@@ -120,6 +236,11 @@ func aggregate(result *Result, scr *types.ScenarioResult, samplingCount map[uint
 
 	}
 
+	// Iterations failed only by the load generator are excluded
+	if localFail && !errOccured {
+		return
+	}
+
 	// Don't change avg duration if there is a error
 	if !errOccured {
 		totalDuration := float32(result.SuccessCount)*result.AvgDuration + scenarioDuration
@@ -134,6 +255,179 @@ func aggregate(result *Result, scr *types.ScenarioResult, samplingCount map[uint
 	}
 }
 
+// calculateRPS fills the achieved rate of the requests in the elapsed time, against the requested rate.
+// Retries are requests too, they are paced by the limiter like the others.
+func (r *Result) calculateRPS(requested int, elapsed time.Duration) {
+	if requested <= 0 || elapsed <= 0 {
+		return
+	}
+	r.RequestedRPS = requested
+	r.AchievedRPS = float32(float64(r.requestCount()) / elapsed.Seconds())
+}
+
+// requestCount returns the number of the sent requests, including the retries.
+func (r *Result) requestCount() int64 {
+	var total int64
+	for _, sr := range r.StepResults {
+		total += sr.SuccessCount + sr.Fail.Count + sr.RetryCount
+	}
+	return total
+}
+
+// calculateLoad fills the load summary of the requested load. Achieved rates are computed in the window
+// from the start of the first aggregated iteration to the end of the last request.
+func (r *Result) calculateLoad(load *RequestedLoad) {
+	if load == nil || (load.Duration <= 0 && !load.Closed) {
+		return
+	}
+	iterations := r.SuccessCount + r.ServerFailedCount + r.AssertionFailCount
+	summary := &LoadSummary{}
+	if load.Closed {
+		planned := int64(load.Iterations)
+		summary.PlannedIterations = &planned
+		summary.ExecutedIterations = iterations + r.WarmupCount + r.RampDownCount
+	} else {
+		summary.RequestedIterationRate = float32(float64(load.Iterations) / load.Duration.Seconds())
+	}
+	if window := r.measureWindow(); window > 0 {
+		summary.AchievedIterationRate = float32(float64(iterations) / window.Seconds())
+		summary.CompletedRPS = float32(float64(r.requestCount()) / window.Seconds())
+	}
+	if !load.Closed {
+		summary.Difference = summary.AchievedIterationRate - summary.RequestedIterationRate
+	}
+	if load.MaxInFlight != nil {
+		summary.MaxInFlight = load.MaxInFlight()
+	}
+	r.Load = summary
+}
+
+// elapsed returns the duration since the start of the test, or since the end of the warm-up if there is one.
+// Paused periods of the test are excluded.
+func (r *Result) elapsed(testStart time.Time) time.Duration {
+	if r.WarmupCount > 0 && r.measureStart.After(testStart) {
+		return time.Since(r.measureStart) - r.paused
+	}
+	return time.Since(testStart) - r.paused
+}
+
+// measureWindow returns the duration from the start of the first aggregated iteration to the end of
+// the last aggregated request, excluding the paused periods of the test.
+func (r *Result) measureWindow() time.Duration {
+	return r.measureEnd.Sub(r.measureStart) - r.paused
+}
+
+// setPaused sets the total paused duration of the test, it should be called before the achieved rates are calculated.
+func (r *Result) setPaused(d time.Duration) {
+	r.paused = d
+	r.PausedDuration = float32(d.Seconds())
+}
+
+// rpsReached reports whether the achieved rate is close enough to the requested rate, allowing a small ramp-up loss.
+func (r *Result) rpsReached() bool {
+	return r.AchievedRPS >= 0.95*float32(r.RequestedRPS)
+}
+
+// SetPercentiles sets the custom percentiles of the latencies, like 99.9. The latencies are recorded by exact
+// histograms if exact is true, it should be called before the results are aggregated.
+func (r *Result) SetPercentiles(percentiles []float64, exact bool) {
+	r.percentiles = percentiles
+	r.exactPercentiles = exact
+}
+
+// SetSampleRate sets the fraction of the iterations recorded into the latency histograms, the unsampled ones
+// are marked by the engine and only counted.
+func (r *Result) SetSampleRate(rate float64) {
+	r.SampleRate = float32(rate)
+}
+
+// SetLatencyBuckets enables the latency histograms of the steps by the upper bounds of the buckets.
+func (r *Result) SetLatencyBuckets(bounds []time.Duration) {
+	r.latencyBuckets = bounds
+}
+
+// newLatencies returns the histogram of the latencies of a step, an exact one if the percentiles are exact.
+func (r *Result) newLatencies() *latencyHistogram {
+	if r.exactPercentiles {
+		return newExactLatencyHistogram()
+	}
+	return newLatencyHistogram()
+}
+
+// calculatePercentiles fills the latency percentiles and the latency histograms of the steps from their histograms.
+// It should be called before reporting, since the percentiles are not updated on each aggregation.
+func (r *Result) calculatePercentiles() {
+	for _, sr := range r.StepResults {
+		if sr.latencies != nil && sr.latencies.total > 0 {
+			sr.Percentiles = sr.latencies.percentiles(r.percentiles)
+		}
+		if sr.latencies != nil && len(r.latencyBuckets) > 0 {
+			sr.Histogram = sr.latencies.buckets(r.latencyBuckets)
+		}
+		sr.calculateStatusPercentiles(r.percentiles)
+		for _, ts := range sr.Targets {
+			if ts.latencies != nil && ts.latencies.total > 0 {
+				ts.Percentiles = ts.latencies.percentiles(r.percentiles)
+			}
+		}
+	}
+}
+
+// calculateTags fills the combined results of the steps by their tags. Like the percentiles, it should be
+// called before reporting.
+func (r *Result) calculateTags() {
+	tags := make(map[string]*TagSummary)
+	histograms := make(map[string]*latencyHistogram)
+	for id, sr := range r.StepResults {
+		for _, tag := range sr.Tags {
+			ts, ok := tags[tag]
+			if !ok {
+				ts = &TagSummary{}
+				tags[tag] = ts
+				histograms[tag] = r.newLatencies()
+			}
+			ts.Steps = append(ts.Steps, id)
+
+			// durations are averaged over the success and fail counts, see aggregate
+			n, sn := float32(ts.SuccessCount+ts.FailCount), float32(sr.SuccessCount+sr.Fail.Count)
+			if n+sn > 0 {
+				ts.AvgDuration = (n*ts.AvgDuration + sn*sr.Durations["duration"]) / (n + sn)
+			}
+			ts.SuccessCount += sr.SuccessCount
+			ts.FailCount += sr.Fail.Count
+			if sr.latencies != nil {
+				histograms[tag].merge(sr.latencies.snapshot())
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return
+	}
+
+	for tag, ts := range tags {
+		sort.Slice(ts.Steps, func(i, j int) bool { return ts.Steps[i] < ts.Steps[j] })
+		if h := histograms[tag]; h.total > 0 {
+			ts.Percentiles = h.percentiles(r.percentiles)
+		}
+	}
+	r.Tags = tags
+}
+
+// calculateConnReuse fills the connection reuse ratio of all the steps. Like the percentiles, it should be
+// called before reporting.
+func (r *Result) calculateConnReuse() {
+	var reused, total int64
+	for _, sr := range r.StepResults {
+		reused += sr.ReusedConnCount
+		total += sr.ReusedConnCount + sr.NewConnCount
+	}
+	if total == 0 {
+		return
+	}
+	ratio := float32(reused) / float32(total)
+	r.ConnReuseRatio = &ratio
+}
+
 // Total test result, all scenario iterations combined
 type Result struct {
 	TestStatus           string                                `json:"test_status"`
@@ -143,6 +437,112 @@ type Result struct {
 	AssertionFailCount   int64                                 `json:"assertion_fail_count"`
 	AvgDuration          float32                               `json:"avg_duration"`
 	StepResults          map[uint16]*ScenarioStepResultSummary `json:"steps"`
+
+	// Requests per second of the test against the requested --rps, set only if there is a rps limit
+	RequestedRPS int     `json:"requested_rps,omitempty"`
+	AchievedRPS  float32 `json:"achieved_rps,omitempty"`
+
+	// Number of the iterations started in the warm-up period, they are not aggregated
+	WarmupCount int64 `json:"warmup_count,omitempty"`
+
+	// Number of the iterations started in the ramp-down period, they are not aggregated
+	RampDownCount int64 `json:"ramp_down_count,omitempty"`
+
+	// Number of the requests of all steps failed by the resource limits of the load generator. The iterations
+	// failed only by them are not counted in success and fail counts.
+	LocalErrorCount int64 `json:"local_error_count,omitempty"`
+
+	// Achieved load against the load pattern, nil if the report service is not given the requested load
+	Load *LoadSummary `json:"load,omitempty"`
+
+	// Combined results of the steps by their tags
+	Tags map[string]*TagSummary `json:"tags,omitempty"`
+
+	// Ratio of the requests sent over a reused keep-alive connection, between 0 and 1.
+	// Nil if no step reports its connections.
+	ConnReuseRatio *float32 `json:"conn_reuse_ratio,omitempty"`
+
+	// Total duration of the pauses of the test in seconds, they are excluded from the achieved rates
+	PausedDuration float32 `json:"paused_duration,omitempty"`
+
+	// Peer certificates captured by the cert audit, the ones having issues first. Nil if the audit is disabled.
+	Certs []*CertSummary `json:"certs,omitempty"`
+
+	// Peak usage of the file descriptors and the connections of the load generator, nil if it is not monitored
+	Resources *ResourceUsage `json:"resources,omitempty"`
+
+	// Fraction of the iterations recorded into the latency histograms, the percentiles are estimated from them.
+	// Zero if all the iterations are recorded.
+	SampleRate float32 `json:"sample_rate,omitempty"`
+
+	// certificates captured by the cert audit by their infos
+	certs map[types.CertInfo]*CertSummary
+
+	// custom percentiles of the latencies like 99.9, and whether they are computed from all the recorded latencies
+	percentiles      []float64
+	exactPercentiles bool
+
+	// upper bounds of the buckets of the latency histograms of the steps, see SetLatencyBuckets
+	latencyBuckets []time.Duration
+
+	// start time of the first aggregated iteration and end time of the last aggregated request
+	measureStart time.Time
+	measureEnd   time.Time
+	paused       time.Duration
+}
+
+// TagSummary is the combined result of the steps having the tag.
+type TagSummary struct {
+	Steps        []uint16            `json:"steps"`
+	SuccessCount int64               `json:"success_count"`
+	FailCount    int64               `json:"fail_count"`
+	AvgDuration  float32             `json:"avg_duration"`
+	Percentiles  *LatencyPercentiles `json:"percentiles,omitempty"`
+}
+
+func (t *TagSummary) successPercentage() int {
+	if t.SuccessCount+t.FailCount == 0 {
+		return 0
+	}
+	return int(float32(t.SuccessCount) / float32(t.SuccessCount+t.FailCount) * 100)
+}
+
+// LoadSummary reconciles the iterations per second requested by the load pattern with the achieved ones.
+type LoadSummary struct {
+	RequestedIterationRate float32 `json:"requested_iteration_rate"`
+	AchievedIterationRate  float32 `json:"achieved_iteration_rate"`
+	Difference             float32 `json:"difference"` // achieved - requested
+
+	// Requests per second completed in the achieved window, including the retries
+	CompletedRPS float32 `json:"completed_rps"`
+
+	// Max number of the concurrently running iterations
+	MaxInFlight int64 `json:"max_in_flight"`
+
+	// Iterations planned by the user quota of a closed load and the executed ones including the warm-up,
+	// nil and zero otherwise
+	PlannedIterations  *int64 `json:"planned_iterations,omitempty"`
+	ExecutedIterations int64  `json:"executed_iterations,omitempty"`
+}
+
+// reached reports whether the achieved rate is close enough to the requested rate, like rpsReached.
+// For a closed load, it reports whether all the planned iterations are executed.
+func (l *LoadSummary) reached() bool {
+	if l.PlannedIterations != nil {
+		return l.ExecutedIterations >= *l.PlannedIterations
+	}
+	return l.AchievedIterationRate >= 0.95*l.RequestedIterationRate
+}
+
+// errorCategories sums the failed requests of the steps by their categories.
+func (r *Result) errorCategories() map[types.ErrorCategory]int64 {
+	categories := make(map[types.ErrorCategory]int64)
+	for _, sr := range r.StepResults {
+		for c, count := range sr.Fail.Categories {
+			categories[c] += count
+		}
+	}
+	return categories
 }
 
 func (r *Result) successPercentage() int {
@@ -170,18 +570,165 @@ type ServerErrVerbose struct {
 	Reasons map[string]int `json:"reasons"`
 }
 
+// Max number of the distinct schema violation messages kept per step
+const maxSchemaMessages = 20
+
+// SchemaErrVerbose is the distribution of the response schema violations of a step.
+type SchemaErrVerbose struct {
+	Count    int64          `json:"count"`
+	Messages map[string]int `json:"messages"` // first distinct messages, up to maxSchemaMessages
+}
+
+// add counts the response with the given violation messages.
+func (s *SchemaErrVerbose) add(messages []string) {
+	s.Count++
+	s.addMessages(messages, 1)
+}
+
+func (s *SchemaErrVerbose) addMessages(messages []string, count int) {
+	for _, m := range messages {
+		if _, ok := s.Messages[m]; ok || len(s.Messages) < maxSchemaMessages {
+			s.Messages[m] += count
+		}
+	}
+}
+
 type FailVerbose struct {
 	Count              int64               `json:"count"`
 	AssertionErrorDist AssertionErrVerbose `json:"assertions"`
 	ServerErrorDist    ServerErrVerbose    `json:"server"`
+	SchemaErrorDist    *SchemaErrVerbose   `json:"schema,omitempty"` // nil if the responses conform to the schema
+
+	// Failed requests by their categories like dns, tls, timeout or http_5xx
+	Categories map[types.ErrorCategory]int64 `json:"categories,omitempty"`
+}
+
+func (f *FailVerbose) addCategory(c types.ErrorCategory) {
+	f.addCategoryCount(c, 1)
+}
+
+func (f *FailVerbose) addCategoryCount(c types.ErrorCategory, count int64) {
+	if c == "" {
+		return
+	}
+	if f.Categories == nil {
+		f.Categories = make(map[types.ErrorCategory]int64)
+	}
+	f.Categories[c] += count
 }
 
 type ScenarioStepResultSummary struct {
 	Name           string             `json:"name"`
+	Tags           []string           `json:"tags,omitempty"`
 	StatusCodeDist map[int]int        `json:"status_code_dist"`
 	Fail           FailVerbose        `json:"fail"`
 	Durations      map[string]float32 `json:"durations"`
 	SuccessCount   int64              `json:"success_count"`
+
+	// Number of the requests sent again by the retry policy of the step, not counted in success and fail counts
+	RetryCount int64 `json:"retry_count,omitempty"`
+
+	// Number of the requests failed by the resource limits of the load generator, like the exhausted local
+	// ports. They are not the failures of the target, so they are not counted in success and fail counts.
+	LocalErrorCount int64 `json:"local_error_count,omitempty"`
+
+	// Number of the responses with a body larger than the max response body bytes of the step
+	TruncatedCount int64 `json:"truncated_count,omitempty"`
+
+	// Number of the compressed responses, their bytes on the wire and after the decompression
+	CompressedCount   int64 `json:"compressed_count,omitempty"`
+	CompressedBytes   int64 `json:"compressed_bytes,omitempty"`
+	DecompressedBytes int64 `json:"decompressed_bytes,omitempty"`
+
+	// Number of the requests sent with a compressed body, their body bytes before and after the compression
+	CompressedReqCount     int64 `json:"compressed_req_count,omitempty"`
+	ReqBodyBytes           int64 `json:"req_body_bytes,omitempty"`
+	CompressedReqBodyBytes int64 `json:"compressed_req_body_bytes,omitempty"`
+
+	// Number of the compressed requests rejected by 415 Unsupported Media Type and sent again uncompressed
+	CompressionFallbackCount int64 `json:"compression_fallback_count,omitempty"`
+
+	// Number of the responses throttled by 429 Too Many Requests, or by 503 Service Unavailable with a Retry-After
+	ThrottledCount int64 `json:"throttled_count,omitempty"`
+
+	// Number of the chunked body uploads, their total bytes and the total time of writing them in seconds
+	UploadCount   int64   `json:"upload_count,omitempty"`
+	UploadedBytes int64   `json:"uploaded_bytes,omitempty"`
+	UploadTime    float64 `json:"upload_time,omitempty"`
+
+	// Number of the requests sent over a new and a reused keep-alive connection
+	NewConnCount    int64 `json:"new_conn_count,omitempty"`
+	ReusedConnCount int64 `json:"reused_conn_count,omitempty"`
+
+	// Number of the events received by a sse step and the average of their inter-arrival times in seconds.
+	// Messages received from the grpc streaming methods are counted as events.
+	EventCount       int64   `json:"event_count,omitempty"`
+	AvgEventInterval float32 `json:"avg_event_interval,omitempty"`
+
+	// Number of the messages sent on the grpc client or bidi streams
+	SentMessageCount int64 `json:"sent_message_count,omitempty"`
+
+	// Number of the conditional requests revalidating the stored validators, and the 304 responses to them
+	RevalidatedCount int64 `json:"revalidated_count,omitempty"`
+	NotModifiedCount int64 `json:"not_modified_count,omitempty"`
+
+	// Number of the iterations that the step is not sent because its condition is not met
+	SkippedCount int64 `json:"skipped_count,omitempty"`
+
+	// Response time percentiles, in seconds. Calculated from latencies at the end of the test.
+	Percentiles *LatencyPercentiles `json:"percentiles,omitempty"`
+
+	// Response time histogram by the latency buckets of the test, nil if the test has no latency buckets.
+	// Calculated from latencies at the end of the test like the percentiles.
+	Histogram []LatencyBucket `json:"histogram,omitempty"`
+
+	// Response time percentiles by the status codes of the responses, in seconds. Nil if the step received a single
+	// status code, since they are the same as the percentiles then. Calculated from latencies at the end of the test.
+	StatusPercentiles map[int]*LatencyPercentiles `json:"status_percentiles,omitempty"`
+
+	// Results of the requests by the names of the weighted targets of the step, nil if the step has no targets
+	Targets map[string]*TargetSummary `json:"targets,omitempty"`
+
+	latencies       *latencyHistogram
+	statusLatencies map[int]*latencyHistogram
+}
+
+// LatencyBucket is a bucket of the latency histogram of a step, see types.LatencyBuckets. Counts are not cumulative,
+// a bucket counts the latencies above the upper bound of the previous one. The bucket after the max bound counts
+// the latencies above it, its upper bound is the max latency.
+type LatencyBucket struct {
+	UpperBound float64 `json:"le"` // in seconds
+	Count      uint64  `json:"count"`
+}
+
+// LatencyPercentiles of the response times of a step, in seconds.
+type LatencyPercentiles struct {
+	P50 float32 `json:"p50"`
+	P90 float32 `json:"p90"`
+	P95 float32 `json:"p95"`
+	P99 float32 `json:"p99"`
+	Max float32 `json:"max"`
+
+	// Custom percentiles of the test by their labels like p99.9, nil if the test has no custom percentiles
+	Custom map[string]float32 `json:"custom,omitempty"`
+}
+
+// rounded returns the percentiles rounded by round.
+func (p *LatencyPercentiles) rounded(round func(float32) float32) *LatencyPercentiles {
+	r := &LatencyPercentiles{
+		P50: round(p.P50),
+		P90: round(p.P90),
+		P95: round(p.P95),
+		P99: round(p.P99),
+		Max: round(p.Max),
+	}
+	if p.Custom != nil {
+		r.Custom = make(map[string]float32, len(p.Custom))
+		for k, v := range p.Custom {
+			r.Custom[k] = round(v)
+		}
+	}
+	return r
 }
 
 func (s *ScenarioStepResultSummary) successPercentage() int {
@@ -192,6 +739,31 @@ func (s *ScenarioStepResultSummary) successPercentage() int {
 	return int(t * 100)
 }
 
+// connReusePercentage is the percentage of the requests sent over a reused connection for the step.
+func (s *ScenarioStepResultSummary) connReusePercentage() int {
+	if s.ReusedConnCount+s.NewConnCount == 0 {
+		return 0
+	}
+	return int(float32(s.ReusedConnCount) / float32(s.ReusedConnCount+s.NewConnCount) * 100)
+}
+
+// retryPercentage is the percentage of the retried requests in all the requests sent for the step.
+func (s *ScenarioStepResultSummary) retryPercentage() int {
+	total := s.SuccessCount + s.Fail.Count + s.RetryCount
+	if total == 0 {
+		return 0
+	}
+	return int(float32(s.RetryCount) / float32(total) * 100)
+}
+
+// uploadThroughput returns the average bytes per second of the chunked body uploads.
+func (s *ScenarioStepResultSummary) uploadThroughput() float64 {
+	if s.UploadTime <= 0 {
+		return 0
+	}
+	return float64(s.UploadedBytes) / s.UploadTime
+}
+
 func (s *ScenarioStepResultSummary) failedPercentage() int {
 	if s.SuccessCount+s.Fail.Count == 0 {
 		return 0
@@ -1,6 +1,7 @@
 package report
 
 import (
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -156,7 +157,7 @@ func TestStart(t *testing.T) {
 
 	s := &stdout{}
 	debug := false
-	s.Init(debug, 0)
+	s.Init(debug, 0, 0)
 
 	responseChan := make(chan *types.ScenarioResult, len(responses))
 	go s.Start(responseChan, nil)
@@ -219,3 +220,531 @@ func compareStepResults(s1, s2 *ScenarioStepResultSummary) bool {
 	}
 	return true
 }
+
+func TestAggregateRetryCount(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	stepResults := []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200, Retries: 2},
+		{StepID: 1, StatusCode: 200},
+		{StepID: 1, StatusCode: 503, Retries: 3, Err: types.RequestError{}},
+		{StepID: 1, Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}, Retries: 3},
+	}
+	for _, sr := range stepResults {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	s := result.StepResults[1]
+	if s.RetryCount != 8 {
+		t.Errorf("Expected %d, Found: %d", 8, s.RetryCount)
+	}
+	if s.SuccessCount != 3 || s.Fail.Count != 1 {
+		t.Errorf("Expected retries not to be counted as requests, Found: %d success %d fail", s.SuccessCount, s.Fail.Count)
+	}
+	// 8 retried of 12 requests sent in total
+	if p := s.retryPercentage(); p != 66 {
+		t.Errorf("Expected %d, Found: %d", 66, p)
+	}
+}
+
+func TestAggregateTruncatedCount(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, truncated := range []bool{true, false, true} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200, RespBodyTruncated: truncated},
+		}}, samplingCount, 0)
+	}
+
+	if c := result.StepResults[1].TruncatedCount; c != 2 {
+		t.Errorf("Expected %d, Found: %d", 2, c)
+	}
+}
+
+func TestAggregateCompressedBytes(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200, ContentLength: 100, DecompressedLength: 400},
+		{StepID: 1, StatusCode: 200, ContentLength: 50},
+		{StepID: 1, StatusCode: 200, ContentLength: 200, DecompressedLength: 1000},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	sr := result.StepResults[1]
+	if sr.CompressedCount != 2 || sr.CompressedBytes != 300 || sr.DecompressedBytes != 1400 {
+		t.Errorf("Expected %v, Found: %v", []int64{2, 300, 1400},
+			[]int64{sr.CompressedCount, sr.CompressedBytes, sr.DecompressedBytes})
+	}
+}
+
+func TestAggregateCompressedRequests(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200, ReqBodyLength: 1000, ReqCompressedBodyLength: 100},
+		{StepID: 1, StatusCode: 200, CompressionFallback: true},
+		{StepID: 1, StatusCode: 200, ReqBodyLength: 500, ReqCompressedBodyLength: 80},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	sr := result.StepResults[1]
+	if sr.CompressedReqCount != 2 || sr.ReqBodyBytes != 1500 || sr.CompressedReqBodyBytes != 180 {
+		t.Errorf("Expected %v, Found: %v", []int64{2, 1500, 180},
+			[]int64{sr.CompressedReqCount, sr.ReqBodyBytes, sr.CompressedReqBodyBytes})
+	}
+	if sr.CompressionFallbackCount != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, sr.CompressionFallbackCount)
+	}
+}
+
+func TestAggregateUnsampled(t *testing.T) {
+	t.Parallel()
+
+	result := NewResult()
+	result.SetSampleRate(0.5)
+	samplingCount := make(map[uint16]map[string]int)
+	for i, d := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond,
+		400 * time.Millisecond} {
+		scr := &types.ScenarioResult{
+			StepResults: []*types.ScenarioStepResult{{StepID: 1, StatusCode: 200, Target: "eu", Duration: d}},
+			Unsampled:   i%2 == 1,
+		}
+		aggregate(result, scr, samplingCount, 0)
+	}
+	result.calculatePercentiles()
+
+	// counters are exact, the latencies are of the sampled iterations
+	sr := result.StepResults[1]
+	if result.SuccessCount != 4 || sr.SuccessCount != 4 || sr.StatusCodeDist[200] != 4 ||
+		sr.Targets["eu"].SuccessCount != 4 {
+		t.Errorf("Expected %v, Found: %v", 4, []int64{result.SuccessCount, sr.SuccessCount,
+			int64(sr.StatusCodeDist[200]), sr.Targets["eu"].SuccessCount})
+	}
+	if sr.latencies.total != 2 || sr.Percentiles.Max != 0.3 || sr.Targets["eu"].Percentiles.Max != 0.3 {
+		t.Errorf("Expected %v, Found: %v %v", 0.3, sr.latencies.total, sr.Percentiles)
+	}
+	if d := sr.Targets["eu"].AvgDuration - 0.2; d > 1e-6 || d < -1e-6 {
+		t.Errorf("Expected %v, Found: %v", 0.2, sr.Targets["eu"].AvgDuration)
+	}
+	if result.SampleRate != 0.5 {
+		t.Errorf("Expected %v, Found: %v", 0.5, result.SampleRate)
+	}
+}
+
+func TestAggregateThrottled(t *testing.T) {
+	t.Parallel()
+
+	result := NewResult()
+	samplingCount := make(map[uint16]map[string]int)
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200},
+		{StepID: 1, StatusCode: 429, Throttled: true, RetryAfter: time.Second},
+		{StepID: 1, StatusCode: 503, Throttled: true},
+		{StepID: 1, StatusCode: 503},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	if c := result.StepResults[1].ThrottledCount; c != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, c)
+	}
+
+	merged := NewResult()
+	merged.Merge(result.Snapshot())
+	merged.Merge(result.Snapshot())
+	if c := merged.StepResults[1].ThrottledCount; c != 4 {
+		t.Errorf("Expected %v, Found: %v", 4, c)
+	}
+}
+
+func TestAggregateUploads(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200, UploadedBytes: 3000, UploadDuration: time.Second},
+		{StepID: 1, StatusCode: 200},
+		{StepID: 1, StatusCode: 200, UploadedBytes: 1000, UploadDuration: time.Second},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	sr := result.StepResults[1]
+	if sr.UploadCount != 2 || sr.UploadedBytes != 4000 || sr.UploadTime != 2 {
+		t.Errorf("Expected %v, Found: %v", []interface{}{2, 4000, 2}, []interface{}{sr.UploadCount, sr.UploadedBytes, sr.UploadTime})
+	}
+	if tp := sr.uploadThroughput(); tp != 2000 {
+		t.Errorf("Expected %v, Found: %v", 2000, tp)
+	}
+}
+
+func TestAggregateConnReuse(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, conn := range []types.ConnState{types.ConnNew, types.ConnReused, types.ConnReused, types.ConnUnknown} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200, Conn: conn},
+			{StepID: 2, StatusCode: 200, Conn: types.ConnNew},
+		}}, samplingCount, 0)
+	}
+	result.calculateConnReuse()
+
+	s1, s2 := result.StepResults[1], result.StepResults[2]
+	if s1.NewConnCount != 1 || s1.ReusedConnCount != 2 || s2.NewConnCount != 4 || s2.ReusedConnCount != 0 {
+		t.Errorf("Expected %v, Found: %v", []int64{1, 2, 4, 0},
+			[]int64{s1.NewConnCount, s1.ReusedConnCount, s2.NewConnCount, s2.ReusedConnCount})
+	}
+	if s1.connReusePercentage() != 66 {
+		t.Errorf("Expected %v, Found: %v", 66, s1.connReusePercentage())
+	}
+	if result.ConnReuseRatio == nil || *result.ConnReuseRatio != float32(2)/7 {
+		t.Errorf("Expected %v, Found: %v", float32(2)/7, result.ConnReuseRatio)
+	}
+
+	empty := &Result{StepResults: map[uint16]*ScenarioStepResultSummary{1: {SuccessCount: 1}}}
+	empty.calculateConnReuse()
+	if empty.ConnReuseRatio != nil {
+		t.Errorf("Expected %v, Found: %v", nil, *empty.ConnReuseRatio)
+	}
+}
+
+func TestAggregateEvents(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, intervals := range [][]time.Duration{
+		{100 * time.Millisecond, 200 * time.Millisecond},
+		nil,
+		{600 * time.Millisecond},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200, EventIntervals: intervals},
+		}}, samplingCount, 0)
+	}
+
+	sr := result.StepResults[1]
+	if sr.EventCount != 3 || math.Abs(float64(sr.AvgEventInterval)-0.3) > 1e-6 {
+		t.Errorf("Expected %v, Found: %v", []float32{3, 0.3}, []float32{float32(sr.EventCount), sr.AvgEventInterval})
+	}
+}
+
+func TestAggregateSkippedCount(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, skipped := range []bool{true, false, true} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200},
+			{StepID: 2, StatusCode: 200, Skipped: skipped},
+		}}, samplingCount, 0)
+	}
+
+	step := result.StepResults[2]
+	if step.SkippedCount != 2 || step.SuccessCount != 1 || step.Fail.Count != 0 {
+		t.Errorf("Expected %v, Found: %v", []int64{2, 1, 0}, []int64{step.SkippedCount, step.SuccessCount, step.Fail.Count})
+	}
+	// skipped steps don't fail the iteration
+	if result.SuccessCount != 3 {
+		t.Errorf("Expected %d, Found: %d", 3, result.SuccessCount)
+	}
+}
+
+func TestAggregateRevalidated(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200},
+		{StepID: 1, StatusCode: 304, Revalidated: true},
+		{StepID: 1, StatusCode: 200, Revalidated: true},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	step := result.StepResults[1]
+	if step.RevalidatedCount != 2 || step.NotModifiedCount != 1 || step.SuccessCount != 3 {
+		t.Errorf("Expected %v, Found: %v", []int64{2, 1, 3},
+			[]int64{step.RevalidatedCount, step.NotModifiedCount, step.SuccessCount})
+	}
+}
+
+func TestAggregateErrorCategories(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	failed := []types.FailedAssertion{{Rule: "equals(status_code,200)"}}
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200},
+		{StepID: 1, Err: types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonTLSTimeout}},
+		{StepID: 1, Err: types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonReadTimeout}},
+		{StepID: 1, StatusCode: 503, FailedAssertions: failed},
+		{StepID: 2, StatusCode: 503, FailedAssertions: failed},
+	} {
+		sr.ErrCategory = sr.Categorize()
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	expected := map[types.ErrorCategory]int64{types.ErrorCategoryTimeout: 2, types.ErrorCategoryHTTP5xx: 1}
+	if !reflect.DeepEqual(result.StepResults[1].Fail.Categories, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, result.StepResults[1].Fail.Categories)
+	}
+
+	expected = map[types.ErrorCategory]int64{types.ErrorCategoryTimeout: 2, types.ErrorCategoryHTTP5xx: 2}
+	if c := result.errorCategories(); !reflect.DeepEqual(c, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, c)
+	}
+}
+
+func TestAggregateLocalErrors(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	local := types.RequestError{Type: types.ErrorLocal, Reason: types.ReasonPortExhausted}
+	for _, srs := range [][]*types.ScenarioStepResult{
+		{{StepID: 1, StatusCode: 200}},
+		{{StepID: 1, Err: local}},
+		{{StepID: 1, Err: local}},
+		{{StepID: 1, StatusCode: 200}, {StepID: 2, Err: local}},
+		{{StepID: 1, Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnRefused}}},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: srs}, samplingCount, 0)
+	}
+
+	// iterations failed only by the local errors are excluded
+	if result.LocalErrorCount != 3 || result.SuccessCount != 1 || result.ServerFailedCount != 1 {
+		t.Errorf("Unexpected counts: local %d, success %d, server failed %d",
+			result.LocalErrorCount, result.SuccessCount, result.ServerFailedCount)
+	}
+	if s := result.StepResults[1]; s.LocalErrorCount != 2 || s.SuccessCount != 2 || s.Fail.Count != 1 {
+		t.Errorf("Unexpected step counts: local %d, success %d, fail %d", s.LocalErrorCount, s.SuccessCount, s.Fail.Count)
+	}
+	if s := result.StepResults[2]; s.LocalErrorCount != 1 || s.Fail.Count != 0 || s.Fail.ServerErrorDist.Count != 0 {
+		t.Errorf("Unexpected step counts: local %d, fail %d", s.LocalErrorCount, s.Fail.Count)
+	}
+}
+
+func TestAggregateSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, errs := range [][]string{nil, {"$.id: missing"}, {"$.id: missing", "$.name: too short"}} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200, SchemaErrors: errs},
+		}}, samplingCount, 0)
+	}
+
+	step := result.StepResults[1]
+	expected := &SchemaErrVerbose{Count: 2, Messages: map[string]int{"$.id: missing": 2, "$.name: too short": 1}}
+	if !reflect.DeepEqual(step.Fail.SchemaErrorDist, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, step.Fail.SchemaErrorDist)
+	}
+	if step.SuccessCount != 1 || step.Fail.Count != 2 || step.Fail.ServerErrorDist.Count != 0 || step.StatusCodeDist[200] != 3 {
+		t.Errorf("Expected %v, Found: %v", "1 success and 2 schema fails", step)
+	}
+	if result.SuccessCount != 1 || result.AssertionFailCount != 2 {
+		t.Errorf("Expected %v, Found: %v", []int64{1, 2}, []int64{result.SuccessCount, result.AssertionFailCount})
+	}
+}
+
+func TestAggregateWarmup(t *testing.T) {
+	t.Parallel()
+
+	testStart := time.Now().Add(-10 * time.Second)
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for i, warmup := range []bool{true, true, false, false} {
+		aggregate(result, &types.ScenarioResult{
+			StartTime:   testStart.Add(time.Duration(i) * 2 * time.Second),
+			Warmup:      warmup,
+			StepResults: []*types.ScenarioStepResult{{StepID: 1, StatusCode: 200, Duration: time.Second}},
+		}, samplingCount, 0)
+	}
+
+	if result.WarmupCount != 2 || result.SuccessCount != 2 || result.StepResults[1].SuccessCount != 2 {
+		t.Errorf("Expected %v, Found: %v", []int64{2, 2, 2},
+			[]int64{result.WarmupCount, result.SuccessCount, result.StepResults[1].SuccessCount})
+	}
+
+	// rate is measured after the warm-up, from the first aggregated iteration
+	elapsed := result.elapsed(testStart)
+	if elapsed < 6*time.Second || elapsed > 7*time.Second {
+		t.Errorf("Expected %v, Found: %v", 6*time.Second, elapsed)
+	}
+}
+
+func TestAggregateRampDown(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, rampDown := range []bool{false, false, false, true} {
+		aggregate(result, &types.ScenarioResult{
+			RampDown:    rampDown,
+			StepResults: []*types.ScenarioStepResult{{StepID: 1, StatusCode: 200, Duration: time.Second}},
+		}, samplingCount, 0)
+	}
+
+	if result.RampDownCount != 1 || result.SuccessCount != 3 || result.StepResults[1].SuccessCount != 3 {
+		t.Errorf("Expected %v, Found: %v", []int64{1, 3, 3},
+			[]int64{result.RampDownCount, result.SuccessCount, result.StepResults[1].SuccessCount})
+	}
+}
+
+func TestAggregatePhaseDurations(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	stepResults := []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 500, Duration: 4 * time.Second,
+			FailedAssertions: []types.FailedAssertion{{Rule: "status_code == 200"}},
+			Custom:           map[string]interface{}{"tlsDuration": 1 * time.Second, "resDuration": 3 * time.Second}},
+		{StepID: 1, StatusCode: 200, Duration: 8 * time.Second,
+			Custom: map[string]interface{}{"tlsDuration": 3 * time.Second, "resDuration": 5 * time.Second}},
+	}
+	for _, sr := range stepResults {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	expected := map[string]float32{"duration": 6, "tlsDuration": 2, "resDuration": 4}
+	if !reflect.DeepEqual(result.StepResults[1].Durations, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, result.StepResults[1].Durations)
+	}
+}
+
+func TestCalculateRPS(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200},
+		{StepID: 1, StatusCode: 200, Retries: 1},
+		{StepID: 2, Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+
+	// no rps limit
+	result.calculateRPS(0, time.Second)
+	if result.RequestedRPS != 0 || result.AchievedRPS != 0 {
+		t.Errorf("Expected %v, Found: %v, %v", 0, result.RequestedRPS, result.AchievedRPS)
+	}
+
+	// 4 requests including the retry in 2 seconds
+	result.calculateRPS(10, 2*time.Second)
+	if result.RequestedRPS != 10 || result.AchievedRPS != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, result.AchievedRPS)
+	}
+	if result.rpsReached() {
+		t.Errorf("Expected %v, Found: %v", false, result.rpsReached())
+	}
+}
+
+func TestCalculateLoad(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	start := time.Now()
+	// 8 iterations of 2 requests, started every 250ms, completed in 2 seconds
+	for i := 0; i < 8; i++ {
+		st := start.Add(time.Duration(i) * 250 * time.Millisecond)
+		aggregate(result, &types.ScenarioResult{StartTime: st, StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200, RequestTime: st, Duration: 100 * time.Millisecond},
+			{StepID: 2, StatusCode: 200, RequestTime: st.Add(100 * time.Millisecond), Duration: 150 * time.Millisecond},
+		}}, samplingCount, 0)
+	}
+
+	result.calculateLoad(nil)
+	if result.Load != nil {
+		t.Errorf("Expected %v, Found: %v", nil, result.Load)
+	}
+
+	result.calculateLoad(&RequestedLoad{Iterations: 10, Duration: 2 * time.Second,
+		MaxInFlight: func() int64 { return 3 }})
+	expected := LoadSummary{RequestedIterationRate: 5, AchievedIterationRate: 4, Difference: -1, CompletedRPS: 8,
+		MaxInFlight: 3}
+	if *result.Load != expected {
+		t.Errorf("Expected %v, Found: %v", expected, *result.Load)
+	}
+	if result.Load.reached() {
+		t.Errorf("Expected %v, Found: %v", false, result.Load.reached())
+	}
+
+	// half of the window is paused
+	result.setPaused(time.Second)
+	result.calculateLoad(&RequestedLoad{Iterations: 10, Duration: 2 * time.Second})
+	expected = LoadSummary{RequestedIterationRate: 5, AchievedIterationRate: 8, Difference: 3, CompletedRPS: 16}
+	if *result.Load != expected {
+		t.Errorf("Expected %v, Found: %v", expected, *result.Load)
+	}
+	if result.PausedDuration != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, result.PausedDuration)
+	}
+
+	// closed load of a user quota, planned iterations are compared instead of the rates
+	result.calculateLoad(&RequestedLoad{Iterations: 10, Closed: true})
+	if result.Load.PlannedIterations == nil || *result.Load.PlannedIterations != 10 {
+		t.Errorf("Expected %v, Found: %v", 10, result.Load.PlannedIterations)
+	}
+	if result.Load.ExecutedIterations != 8 || result.Load.RequestedIterationRate != 0 || result.Load.Difference != 0 {
+		t.Errorf("Expected %v, Found: %v", 8, *result.Load)
+	}
+	if result.Load.reached() {
+		t.Errorf("Expected %v, Found: %v", false, result.Load.reached())
+	}
+}
+
+func TestCalculateTags(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for i := 0; i < 2; i++ {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200, Duration: 100 * time.Millisecond, Tags: []string{"payments", "critical"}},
+			{StepID: 2, StatusCode: 200, Duration: 300 * time.Millisecond, Tags: []string{"payments"}},
+			{StepID: 3, StatusCode: 200, Duration: time.Second},
+			{StepID: 4, Duration: time.Second, Tags: []string{"critical"},
+				Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}},
+		}}, samplingCount, 0)
+	}
+	result.calculateTags()
+
+	if len(result.Tags) != 2 {
+		t.Fatalf("Expected %v, Found: %v", 2, len(result.Tags))
+	}
+	payments := result.Tags["payments"]
+	if !reflect.DeepEqual(payments.Steps, []uint16{1, 2}) || payments.SuccessCount != 4 || payments.FailCount != 0 {
+		t.Errorf("Expected %v, Found: %v", "steps [1 2] with 4 successes", *payments)
+	}
+	if math.Abs(float64(payments.AvgDuration)-0.2) > 1e-6 || payments.Percentiles == nil {
+		t.Errorf("Expected %v, Found: %v", 0.2, payments.AvgDuration)
+	}
+	critical := result.Tags["critical"]
+	if !reflect.DeepEqual(critical.Steps, []uint16{1, 4}) || critical.SuccessCount != 2 || critical.FailCount != 2 ||
+		critical.successPercentage() != 50 {
+		t.Errorf("Expected %v, Found: %v", "steps [1 4] with 2 successes and 2 fails", *critical)
+	}
+}
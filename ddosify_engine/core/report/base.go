@@ -22,7 +22,9 @@ package report
 
 import (
 	"fmt"
+	"os"
 	"reflect"
+	"time"
 
 	"go.ddosify.com/ddosify/core/assertion"
 	"go.ddosify.com/ddosify/core/types"
@@ -33,10 +35,88 @@ var AvailableOutputServices = make(map[string]ReportService)
 // ReportService is the interface that abstracts different report implementations.
 type ReportService interface {
 	DoneChan() <-chan bool
-	Init(debug bool, samplingRate int) error
+	Init(debug bool, samplingRate int, targetRPS int) error
 	Start(input chan *types.ScenarioResult, assertionResultChan <-chan assertion.TestAssertionResult)
 }
 
+// BatchReporter is implemented by the report services that aggregate the results in batches. The engine starts
+// them by StartBatched instead of Start, so the iterations record their results without a channel send per
+// result at high rates.
+type BatchReporter interface {
+	StartBatched(input <-chan []*types.ScenarioResult, assertionResultChan <-chan assertion.TestAssertionResult)
+}
+
+// RequestedLoad is the load requested by the load pattern of the test, excluding the warm-up period.
+type RequestedLoad struct {
+	Iterations int
+	Duration   time.Duration
+
+	// MaxInFlight returns the max number of the concurrently running iterations reached in the test.
+	MaxInFlight func() int64
+
+	// Iterations are the planned quota of the users of a closed load, there is no requested rate and Duration is zero.
+	Closed bool
+}
+
+// LoadReconciler is implemented by the report services that report the achieved load against the requested load.
+type LoadReconciler interface {
+	SetRequestedLoad(load RequestedLoad)
+}
+
+// PauseTracker is implemented by the report services that exclude the paused periods of the test from the achieved rates.
+type PauseTracker interface {
+	// SetPausedDuration gives the func returning the total paused duration of the test, called when reporting.
+	SetPausedDuration(paused func() time.Duration)
+}
+
+// CertAuditor is implemented by the report services that summarize the peer certificates captured by the cert audit.
+type CertAuditor interface {
+	// SetCertExpiryDays enables the certificate summary, the ones expiring in the given days are reported.
+	SetCertExpiryDays(days int)
+}
+
+// PercentileReporter is implemented by the report services that report the custom latency percentiles.
+type PercentileReporter interface {
+	// SetPercentiles sets the percentiles reported instead of the default ones, like 99.9. The percentiles are
+	// computed from all the recorded latencies if exact is true, estimated by the histograms otherwise.
+	SetPercentiles(percentiles []float64, exact bool)
+}
+
+// HistogramReporter is implemented by the report services that report the latency histograms of the steps.
+type HistogramReporter interface {
+	// SetLatencyBuckets sets the upper bounds of the histogram buckets in ascending order, see types.LatencyBuckets.
+	SetLatencyBuckets(bounds []time.Duration)
+}
+
+// SampleReporter is implemented by the report services that note the sample rate of the latency histograms.
+type SampleReporter interface {
+	// SetSampleRate sets the fraction of the iterations recorded into the latency histograms, see Hammer.SampleRate.
+	SetSampleRate(rate float64)
+}
+
+// ResourceReporter is implemented by the report services that report the file descriptors and the connections
+// of the load generator.
+type ResourceReporter interface {
+	// SetResourceUsage gives the func returning the peak usage of the test, called when reporting.
+	SetResourceUsage(peak func() ResourceUsage)
+
+	// WarnResourceUsage warns about the current usage approaching the file descriptor limit, during the test.
+	WarnResourceUsage(current ResourceUsage)
+}
+
+// OutputRedirector is implemented by the report services printing the result to stdout.
+type OutputRedirector interface {
+	// SetOutput prints the result to the file instead, like stderr when stdout is left to the results streamed by
+	// types.Hammer.StreamJSON. It is called before Init.
+	SetOutput(f *os.File)
+}
+
+// ResultProvider is implemented by the report services that keep the aggregated result of the test.
+type ResultProvider interface {
+	// Result returns the aggregated result, it should be called after the report service is done.
+	Result() *Result
+}
+
 // NewReportService is the factory method of the ReportService.
 func NewReportService(s string) (service ReportService, err error) {
 	if val, ok := AvailableOutputServices[s]; ok {
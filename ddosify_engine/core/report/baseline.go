@@ -0,0 +1,294 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Default tolerances of the baseline comparison
+const (
+	DefaultBaselineP95Tolerance        = 0.10 // p95 can be 10% slower
+	DefaultBaselineErrorRateTolerance  = 0.01 // error rate can be 1 percentage point higher
+	DefaultBaselineThroughputTolerance = 0.10 // throughput can be 10% lower
+)
+
+// Baseline is the summary of a test result saved by --save-baseline, the results of the later runs are compared
+// against it by --compare-baseline.
+type Baseline struct {
+	// Response time p95 of all the steps combined, in seconds
+	P95 float32 `json:"p95"`
+
+	// Failed iterations over all the iterations, between 0 and 1
+	ErrorRate float32 `json:"error_rate"`
+
+	// Completed requests per second, zero if it is not measured
+	Throughput float32 `json:"throughput"`
+
+	Steps map[uint16]*StepBaseline `json:"steps"`
+}
+
+// StepBaseline is the summary of a step in the Baseline.
+type StepBaseline struct {
+	Name      string  `json:"name"`
+	P95       float32 `json:"p95"`
+	ErrorRate float32 `json:"error_rate"`
+}
+
+// BaselineTolerance is the allowed regression of a run against the baseline.
+type BaselineTolerance struct {
+	// Relative increase of the p95, 0.1 is 10%
+	P95 float64
+
+	// Absolute increase of the error rate, 0.01 is 1 percentage point
+	ErrorRate float64
+
+	// Relative decrease of the throughput, 0.1 is 10%
+	Throughput float64
+}
+
+// DefaultBaselineTolerance returns the default tolerances.
+func DefaultBaselineTolerance() BaselineTolerance {
+	return BaselineTolerance{
+		P95:        DefaultBaselineP95Tolerance,
+		ErrorRate:  DefaultBaselineErrorRateTolerance,
+		Throughput: DefaultBaselineThroughputTolerance,
+	}
+}
+
+// ParseBaselineTolerance parses the tolerances like "p95=10%,error_rate=1%,throughput=5%", the tolerances that are
+// not given keep their defaults. Values can be given as ratios too, like "p95=0.1".
+func ParseBaselineTolerance(s string) (BaselineTolerance, error) {
+	t := DefaultBaselineTolerance()
+	if strings.TrimSpace(s) == "" {
+		return t, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return t, fmt.Errorf("baseline tolerance should be like metric=10%%: %s", part)
+		}
+		v, err := parseRatio(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return t, fmt.Errorf("baseline tolerance of %s is not valid: %v", kv[0], err)
+		}
+		switch strings.ToLower(strings.TrimSpace(kv[0])) {
+		case "p95":
+			t.P95 = v
+		case "error_rate":
+			t.ErrorRate = v
+		case "throughput":
+			t.Throughput = v
+		default:
+			return t, fmt.Errorf("unsupported baseline metric: %s, should be one of p95, error_rate, throughput", kv[0])
+		}
+	}
+	return t, nil
+}
+
+// parseRatio parses "10%" or "0.1" as 0.1.
+func parseRatio(s string) (float64, error) {
+	percent := strings.HasSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if percent {
+		v /= 100
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("can not be negative: %s", s)
+	}
+	return v, nil
+}
+
+// Baseline returns the baseline of the result.
+func (r *Result) Baseline() *Baseline {
+	b := &Baseline{Steps: make(map[uint16]*StepBaseline, len(r.StepResults))}
+
+	all := newLatencyHistogram()
+	for id, sr := range r.StepResults {
+		sb := &StepBaseline{Name: sr.Name}
+		if n := sr.SuccessCount + sr.Fail.Count; n > 0 {
+			sb.ErrorRate = float32(sr.Fail.Count) / float32(n)
+		}
+		if sr.latencies != nil {
+			sb.P95 = float32(sr.latencies.quantile(0.95).Seconds())
+			all.merge(sr.latencies.snapshot())
+		}
+		b.Steps[id] = sb
+	}
+	b.P95 = float32(all.quantile(0.95).Seconds())
+
+	if iterations := r.SuccessCount + r.ServerFailedCount + r.AssertionFailCount; iterations > 0 {
+		b.ErrorRate = float32(r.ServerFailedCount+r.AssertionFailCount) / float32(iterations)
+	}
+
+	if window := r.measureWindow(); window > 0 {
+		b.Throughput = float32(float64(r.requestCount()) / window.Seconds())
+	} else {
+		// merged results of a distributed test, measured only if there is a rps limit
+		b.Throughput = r.AchievedRPS
+	}
+	return b
+}
+
+// SaveBaseline writes the baseline as json to the file at path.
+func SaveBaseline(path string, b *Baseline) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// LoadBaseline reads the baseline saved by SaveBaseline from the file at path.
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("baseline could not be read: %v", err)
+	}
+	b := &Baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("baseline %s is not valid: %v", path, err)
+	}
+	return b, nil
+}
+
+// BaselineDiff is the comparison of a metric of the run against the baseline.
+type BaselineDiff struct {
+	Metric    string
+	Baseline  float32
+	Current   float32
+	Regressed bool
+
+	unit string
+}
+
+// CompareBaseline compares the current run against the baseline. P95 and error rate are compared for the whole
+// test and for each step found in both, throughput is compared if it is measured in both.
+func CompareBaseline(base, current *Baseline, t BaselineTolerance) []BaselineDiff {
+	diffs := []BaselineDiff{
+		latencyDiff("p95", base.P95, current.P95, t.P95),
+		errorRateDiff("error_rate", base.ErrorRate, current.ErrorRate, t.ErrorRate),
+	}
+	if base.Throughput > 0 && current.Throughput > 0 {
+		diffs = append(diffs, BaselineDiff{
+			Metric:    "throughput",
+			Baseline:  base.Throughput,
+			Current:   current.Throughput,
+			Regressed: float64(current.Throughput) < float64(base.Throughput)*(1-t.Throughput),
+			unit:      "rps",
+		})
+	}
+
+	ids := make([]int, 0, len(current.Steps))
+	for id := range current.Steps {
+		if _, ok := base.Steps[id]; ok {
+			ids = append(ids, int(id))
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		bs, cs := base.Steps[uint16(id)], current.Steps[uint16(id)]
+		name := fmt.Sprintf("step %d", id)
+		if cs.Name != "" {
+			name = fmt.Sprintf("step %d (%s)", id, cs.Name)
+		}
+		diffs = append(diffs,
+			latencyDiff(name+" p95", bs.P95, cs.P95, t.P95),
+			errorRateDiff(name+" error_rate", bs.ErrorRate, cs.ErrorRate, t.ErrorRate))
+	}
+	return diffs
+}
+
+func latencyDiff(metric string, base, current float32, tolerance float64) BaselineDiff {
+	return BaselineDiff{
+		Metric:    metric,
+		Baseline:  base,
+		Current:   current,
+		Regressed: float64(current) > float64(base)*(1+tolerance),
+		unit:      "s",
+	}
+}
+
+func errorRateDiff(metric string, base, current float32, tolerance float64) BaselineDiff {
+	return BaselineDiff{
+		Metric:    metric,
+		Baseline:  base,
+		Current:   current,
+		Regressed: float64(current) > float64(base)+tolerance+1e-9, // float32 rates of equal counts
+		unit:      "%",
+	}
+}
+
+// BaselineRegressed reports whether any of the metrics regressed.
+func BaselineRegressed(diffs []BaselineDiff) bool {
+	for _, d := range diffs {
+		if d.Regressed {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintBaselineDiff writes the comparison as a table.
+func PrintBaselineDiff(out io.Writer, diffs []BaselineDiff) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Metric\tBaseline\tCurrent\tChange\tStatus")
+	for _, d := range diffs {
+		status := "ok"
+		if d.Regressed {
+			status = "REGRESSION"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Metric, d.format(d.Baseline), d.format(d.Current), d.change(), status)
+	}
+	w.Flush()
+}
+
+func (d BaselineDiff) format(v float32) string {
+	switch d.unit {
+	case "%":
+		return fmt.Sprintf("%.2f%%", v*100)
+	case "rps":
+		return fmt.Sprintf("%.2f rps", v)
+	}
+	return fmt.Sprintf("%.4fs", v)
+}
+
+// change is the relative change of the metric, the percentage points for the error rates.
+func (d BaselineDiff) change() string {
+	if d.unit == "%" {
+		return fmt.Sprintf("%+.2fpp", (d.Current-d.Baseline)*100)
+	}
+	if d.Baseline == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (d.Current-d.Baseline)/d.Baseline*100)
+}
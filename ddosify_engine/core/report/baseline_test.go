@@ -0,0 +1,183 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestParseBaselineTolerance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tolerance string
+		expected  BaselineTolerance
+		valid     bool
+	}{
+		{"", DefaultBaselineTolerance(), true},
+		{"p95=20%", BaselineTolerance{P95: 0.2, ErrorRate: DefaultBaselineErrorRateTolerance,
+			Throughput: DefaultBaselineThroughputTolerance}, true},
+		{"p95=0.05, error_rate=0.5%,throughput=0", BaselineTolerance{P95: 0.05, ErrorRate: 0.005}, true},
+		{"p99=10%", BaselineTolerance{}, false},
+		{"p95", BaselineTolerance{}, false},
+		{"p95=-10%", BaselineTolerance{}, false},
+		{"p95=ten", BaselineTolerance{}, false},
+	}
+
+	for _, test := range tests {
+		tol, err := ParseBaselineTolerance(test.tolerance)
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.tolerance, test.valid, err)
+		}
+		if test.valid && tol != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.tolerance, test.expected, tol)
+		}
+	}
+}
+
+func TestResultBaseline(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	start := time.Now()
+	// 4 iterations of 2 requests completed in 1 second, the last iteration fails at the second step
+	for i := 0; i < 4; i++ {
+		st := start.Add(time.Duration(i) * 250 * time.Millisecond)
+		second := &types.ScenarioStepResult{StepID: 2, StatusCode: 200, RequestTime: st.Add(100 * time.Millisecond),
+			Duration: 150 * time.Millisecond}
+		if i == 3 {
+			second.Err = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnRefused}
+		}
+		aggregate(result, &types.ScenarioResult{StartTime: st, StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StepName: "home", StatusCode: 200, RequestTime: st, Duration: 100 * time.Millisecond},
+			second,
+		}}, samplingCount, 0)
+	}
+
+	b := result.Baseline()
+	p95 := float32(result.StepResults[2].latencies.quantile(0.95).Seconds())
+	expected := &Baseline{
+		P95:        p95, // slowest step
+		ErrorRate:  0.25,
+		Throughput: 8, // 8 requests in the 1 second from the first start to the last end
+		Steps: map[uint16]*StepBaseline{
+			1: {Name: "home", P95: float32(result.StepResults[1].latencies.quantile(0.95).Seconds())},
+			2: {P95: p95, ErrorRate: 0.25},
+		},
+	}
+	if !reflect.DeepEqual(b, expected) {
+		t.Errorf("Expected %+v, Found: %+v", expected, b)
+	}
+}
+
+func TestSaveLoadBaseline(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	b := &Baseline{P95: 0.25, ErrorRate: 0.01, Throughput: 100, Steps: map[uint16]*StepBaseline{1: {Name: "home", P95: 0.2}}}
+	if err := SaveBaseline(path, b); err != nil {
+		t.Fatalf("TestSaveLoadBaseline save error occurred: %v", err)
+	}
+	loaded, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("TestSaveLoadBaseline load error occurred: %v", err)
+	}
+	if !reflect.DeepEqual(b, loaded) {
+		t.Errorf("Expected %+v, Found: %+v", b, loaded)
+	}
+
+	if _, err := LoadBaseline(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Expected an error for the missing baseline")
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	t.Parallel()
+
+	base := &Baseline{P95: 0.2, ErrorRate: 0.01, Throughput: 100, Steps: map[uint16]*StepBaseline{
+		1: {Name: "home", P95: 0.1},
+		2: {Name: "removed", P95: 0.1},
+	}}
+	tests := []struct {
+		name      string
+		current   *Baseline
+		regressed []string
+	}{
+		{"WithinTolerance", &Baseline{P95: 0.21, ErrorRate: 0.02, Throughput: 91,
+			Steps: map[uint16]*StepBaseline{1: {Name: "home", P95: 0.1}}}, nil},
+		{"P95", &Baseline{P95: 0.3, ErrorRate: 0.01, Throughput: 100,
+			Steps: map[uint16]*StepBaseline{1: {Name: "home", P95: 0.2}}}, []string{"p95", "step 1 (home) p95"}},
+		{"ErrorRate", &Baseline{P95: 0.2, ErrorRate: 0.05, Throughput: 100}, []string{"error_rate"}},
+		{"Throughput", &Baseline{P95: 0.2, ErrorRate: 0.01, Throughput: 80}, []string{"throughput"}},
+		{"ThroughputNotMeasured", &Baseline{P95: 0.2, ErrorRate: 0.01}, nil},
+	}
+
+	for _, test := range tests {
+		diffs := CompareBaseline(base, test.current, DefaultBaselineTolerance())
+		var regressed []string
+		for _, d := range diffs {
+			if d.Regressed {
+				regressed = append(regressed, d.Metric)
+			}
+		}
+		if !reflect.DeepEqual(regressed, test.regressed) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.regressed, regressed)
+		}
+		if BaselineRegressed(diffs) != (test.regressed != nil) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.regressed != nil, BaselineRegressed(diffs))
+		}
+	}
+}
+
+func TestPrintBaselineDiff(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	PrintBaselineDiff(buf, []BaselineDiff{
+		latencyDiff("p95", 0.2, 0.3, 0.1),
+		errorRateDiff("error_rate", 0.01, 0.015, 0.01),
+		{Metric: "throughput", Baseline: 100, Current: 95, unit: "rps"},
+	})
+
+	expected := []string{
+		"Metric      Baseline  Current   Change  Status",
+		"p95         0.2000s   0.3000s   +50.0%  REGRESSION",
+		"error_rate  1.00%     1.50%     +0.50pp  ok",
+		"throughput  100.00 rps  95.00 rps  -5.0%  ok",
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %v, Found: %v", expected, lines)
+	}
+	for i, l := range lines {
+		if strings.Join(strings.Fields(l), " ") != strings.Join(strings.Fields(expected[i]), " ") {
+			t.Errorf("Expected %v, Found: %v", expected[i], l)
+		}
+	}
+}
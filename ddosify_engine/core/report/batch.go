@@ -0,0 +1,61 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import "go.ddosify.com/ddosify/core/types"
+
+// Max number of the results waiting in the input of Start that are aggregated as a batch
+const maxInputBatchSize = 64
+
+// batchChan passes the results of the input of Start to StartBatched. Results already waiting in the input are
+// passed together, up to maxInputBatchSize.
+func batchChan(input <-chan *types.ScenarioResult) <-chan []*types.ScenarioResult {
+	out := make(chan []*types.ScenarioResult, 1)
+	go func() {
+		defer close(out)
+		for r := range input {
+			batch := []*types.ScenarioResult{r}
+		waiting:
+			for len(batch) < maxInputBatchSize {
+				select {
+				case r, ok := <-input:
+					if !ok {
+						break waiting
+					}
+					batch = append(batch, r)
+				default:
+					break waiting
+				}
+			}
+			out <- batch
+		}
+	}()
+	return out
+}
+
+// collect returns the results of all the batches of the input, after it is closed.
+func collect(input <-chan []*types.ScenarioResult) []*types.ScenarioResult {
+	var results []*types.ScenarioResult
+	for batch := range input {
+		results = append(results, batch...)
+	}
+	return results
+}
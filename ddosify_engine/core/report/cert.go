@@ -0,0 +1,137 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// CertSummary is a peer certificate captured by the cert audit, with the issues of it.
+type CertSummary struct {
+	Host        string    `json:"host"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotAfter    time.Time `json:"not_after"`
+	DaysLeft    int       `json:"days_left"` // negative if expired
+	Version     string    `json:"tls_version"`
+	CipherSuite string    `json:"cipher_suite"`
+
+	// Steps that received the certificate, and the number of the responses over it
+	Steps []uint16 `json:"steps"`
+	Count int64    `json:"count"`
+
+	// Expiry, hostname, chain and protocol issues of the certificate, empty if there is none
+	Issues []string `json:"issues,omitempty"`
+
+	info types.CertInfo
+}
+
+func (r *Result) addCert(stepID uint16, info *types.CertInfo) {
+	if r.certs == nil {
+		r.certs = make(map[types.CertInfo]*CertSummary)
+	}
+	cs, ok := r.certs[*info]
+	if !ok {
+		cs = &CertSummary{
+			Host:        info.Host,
+			Subject:     info.Subject,
+			Issuer:      info.Issuer,
+			NotAfter:    info.NotAfter,
+			Version:     info.Version,
+			CipherSuite: info.CipherSuite,
+			info:        *info,
+		}
+		r.certs[*info] = cs
+	}
+	cs.Count++
+	for _, id := range cs.Steps {
+		if id == stepID {
+			return
+		}
+	}
+	cs.Steps = append(cs.Steps, stepID)
+}
+
+// calculateCerts fills the certificate summary with the issues of the certificates, the ones expiring in the
+// expiryDays after now are reported. Like the percentiles, it should be called before reporting.
+func (r *Result) calculateCerts(expiryDays int, now time.Time) {
+	r.Certs = make([]*CertSummary, 0, len(r.certs))
+	for _, cs := range r.certs {
+		cs.DaysLeft = int(math.Floor(cs.NotAfter.Sub(now).Hours() / 24))
+		cs.Issues = nil
+		if cs.DaysLeft < 0 {
+			cs.Issues = append(cs.Issues, fmt.Sprintf("expired %d days ago", -cs.DaysLeft))
+		} else if cs.DaysLeft < expiryDays {
+			cs.Issues = append(cs.Issues, fmt.Sprintf("expires in %d days", cs.DaysLeft))
+		}
+		if cs.info.HostnameError != "" {
+			cs.Issues = append(cs.Issues, "hostname mismatch: "+cs.info.HostnameError)
+		}
+		if cs.info.ChainError != "" {
+			cs.Issues = append(cs.Issues, "unverified chain: "+cs.info.ChainError)
+		}
+		if cs.info.WeakVersion {
+			cs.Issues = append(cs.Issues, "weak protocol: "+cs.Version)
+		}
+		sort.Slice(cs.Steps, func(i, j int) bool { return cs.Steps[i] < cs.Steps[j] })
+		r.Certs = append(r.Certs, cs)
+	}
+	sort.Slice(r.Certs, func(i, j int) bool {
+		a, b := r.Certs[i], r.Certs[j]
+		if (len(a.Issues) > 0) != (len(b.Issues) > 0) {
+			return len(a.Issues) > 0
+		}
+		if a.DaysLeft != b.DaysLeft {
+			return a.DaysLeft < b.DaysLeft
+		}
+		return a.Host < b.Host
+	})
+}
+
+// printCerts prints the certificates having issues, and the number of the certificates without any.
+func printCerts(w io.Writer, certs []*CertSummary, expiryDays int) {
+	fmt.Fprintf(w, "Certificates (expiry window %d days):\n", expiryDays)
+	if len(certs) == 0 {
+		fmt.Fprintln(w, "  No TLS connection")
+	}
+	ok := 0
+	for _, cs := range certs {
+		if len(cs.Issues) == 0 {
+			ok++
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s, expires %s, %s %s\n", cs.Host, cs.Subject, cs.NotAfter.Format("2006-01-02"),
+			cs.Version, cs.CipherSuite)
+		for _, issue := range cs.Issues {
+			fmt.Fprintf(w, "    %s\n", yellow(issue))
+		}
+	}
+	if ok > 0 {
+		fmt.Fprintf(w, "  %d\tcertificates without any issue\n", ok)
+	}
+	fmt.Fprintln(w)
+}
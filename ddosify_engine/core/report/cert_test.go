@@ -0,0 +1,71 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestCalculateCerts(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	healthy := &types.CertInfo{Host: "a.com", NotAfter: now.Add(365 * 24 * time.Hour), Version: "TLS 1.3"}
+	expiring := &types.CertInfo{Host: "b.com", NotAfter: now.Add(10*24*time.Hour + time.Hour), Version: "TLS 1.2"}
+	broken := &types.CertInfo{Host: "c.com", NotAfter: now.Add(-48 * time.Hour), Version: "TLS 1.0", WeakVersion: true,
+		HostnameError: "x509: certificate is valid for d.com, not c.com"}
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for _, sr := range []*types.ScenarioStepResult{
+		{StepID: 2, StatusCode: 200, Cert: healthy},
+		{StepID: 1, StatusCode: 200, Cert: healthy},
+		{StepID: 1, StatusCode: 200, Cert: expiring},
+		{StepID: 1, StatusCode: 200, Cert: broken},
+		{StepID: 1, StatusCode: 200},
+	} {
+		aggregate(result, &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}, samplingCount, 0)
+	}
+	result.calculateCerts(30, now)
+
+	if len(result.Certs) != 3 {
+		t.Fatalf("Expected %v, Found: %v", 3, len(result.Certs))
+	}
+	hosts := []string{result.Certs[0].Host, result.Certs[1].Host, result.Certs[2].Host}
+	if !reflect.DeepEqual(hosts, []string{"c.com", "b.com", "a.com"}) {
+		t.Errorf("Expected %v, Found: %v", []string{"c.com", "b.com", "a.com"}, hosts)
+	}
+	expectedIssues := []string{"expired 2 days ago", "hostname mismatch: x509: certificate is valid for d.com, not c.com",
+		"weak protocol: TLS 1.0"}
+	if !reflect.DeepEqual(result.Certs[0].Issues, expectedIssues) {
+		t.Errorf("Expected %v, Found: %v", expectedIssues, result.Certs[0].Issues)
+	}
+	if !reflect.DeepEqual(result.Certs[1].Issues, []string{"expires in 10 days"}) {
+		t.Errorf("Expected %v, Found: %v", "expires in 10 days", result.Certs[1].Issues)
+	}
+	if a := result.Certs[2]; a.Issues != nil || a.Count != 2 || !reflect.DeepEqual(a.Steps, []uint16{1, 2}) {
+		t.Errorf("Expected %v, Found: %v", "2 responses of the steps 1, 2 without issues", *a)
+	}
+}
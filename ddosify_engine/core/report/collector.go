@@ -0,0 +1,120 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/assertion"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// Collector is a ReportService that aggregates the results without printing them. Workers of a distributed test
+// use it to send the Snapshot of their results to the coordinator.
+type Collector struct {
+	doneChan     chan bool
+	result       *Result
+	samplingRate int
+	targetRPS    int
+	startTime    time.Time
+	mu           sync.Mutex
+}
+
+// NewCollector is the constructor of the Collector.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+func (c *Collector) Init(debug bool, samplingRate int, targetRPS int) (err error) {
+	c.doneChan = make(chan bool, 1)
+	c.result = NewResult()
+	c.samplingRate = samplingRate
+	c.targetRPS = targetRPS
+	return
+}
+
+func (c *Collector) Start(input chan *types.ScenarioResult, assertionResultChan <-chan assertion.TestAssertionResult) {
+	c.StartBatched(batchChan(input), assertionResultChan)
+}
+
+// StartBatched is like Start, aggregating each batch of the input under a single lock.
+func (c *Collector) StartBatched(input <-chan []*types.ScenarioResult,
+	assertionResultChan <-chan assertion.TestAssertionResult) {
+	c.startTime = time.Now()
+
+	// sampling counts are never reset, the coordinator gets the first samples of the failed assertions
+	samplingCount := make(map[uint16]map[string]int)
+	for batch := range input {
+		c.mu.Lock()
+		for _, r := range batch {
+			aggregate(c.result, r, samplingCount, c.samplingRate)
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	c.result.TestStatus = "success"
+	if assertionResultChan != nil {
+		result := <-assertionResultChan
+		if result.Fail {
+			c.result.TestStatus = "failed"
+			c.result.TestFailedAssertions = result.FailedRules
+		}
+	}
+	c.result.calculateRPS(c.targetRPS, c.result.elapsed(c.startTime))
+	success := c.result.TestStatus == "success"
+	c.mu.Unlock()
+
+	c.doneChan <- success
+}
+
+func (c *Collector) DoneChan() <-chan bool {
+	return c.doneChan
+}
+
+// Snapshot returns the mergeable state of the aggregated results, it should be called after the collector is done.
+func (c *Collector) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.result.Snapshot()
+}
+
+// Result returns the aggregated result, it should be called after the collector is done.
+func (c *Collector) Result() *Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.result
+}
+
+// PrintResult prints the given result, like a merged distributed test result, in the format of the output type.
+func PrintResult(outputType string, r *Result) error {
+	switch outputType {
+	case OutputTypeStdout:
+		(&stdout{result: r}).report()
+	case OutputTypeStdoutJson:
+		(&stdoutJson{result: r}).report()
+	default:
+		return fmt.Errorf("unsupported output type: %s", outputType)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/enescakir/emoji"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+const (
+	dashboardInterval = time.Second
+	dashboardWindow   = 10 // seconds of the rolling latency, error rate and status code stats
+)
+
+// LiveDashboard is implemented by the report services that can show a live dashboard during the test.
+type LiveDashboard interface {
+	// EnableDashboard replaces the live result lines with the dashboard,
+	// activeUsers returns the number of the running iterations.
+	EnableDashboard(activeUsers func() int64)
+}
+
+// dashboard prints the live stats of the test once a second. It is updated in place on a terminal,
+// printed as a plain line per second otherwise, like when the output is redirected to a file.
+type dashboard struct {
+	w           io.Writer
+	tty         bool
+	activeUsers func() int64
+	start       time.Time
+
+	mu      sync.Mutex
+	buckets [dashboardWindow]dashboardBucket // by second % dashboardWindow
+	lines   int                              // lines of the last frame, cleared before the next one on a terminal
+	warning string                           // shown under the stats until the end of the test, empty if none
+	warned  bool                             // warning is printed once if not a terminal
+
+	done     chan struct{}
+	finished chan struct{}
+}
+
+// dashboardBucket keeps the requests completed in a second.
+type dashboardBucket struct {
+	sec       int64 // unix time
+	requests  int
+	failed    int
+	durations []float64      // in seconds
+	statuses  map[string]int // status code or error type -> count
+}
+
+type dashboardStats struct {
+	elapsed       time.Duration
+	rps           int // requests completed in the last second
+	activeUsers   int64
+	p50, p95, p99 time.Duration
+	errorRate     float64 // ratio of the failed requests
+	statuses      []statusCount
+}
+
+type statusCount struct {
+	status string
+	count  int
+}
+
+func newDashboard(w io.Writer, tty bool, activeUsers func() int64) *dashboard {
+	return &dashboard{
+		w:           w,
+		tty:         tty,
+		activeUsers: activeUsers,
+		start:       time.Now(),
+		done:        make(chan struct{}),
+		finished:    make(chan struct{}),
+	}
+}
+
+// observe adds the requests of the completed iteration to the bucket of the current second.
+func (d *dashboard) observe(r *types.ScenarioResult, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sec := now.Unix()
+	b := &d.buckets[sec%dashboardWindow]
+	if b.sec != sec {
+		*b = dashboardBucket{sec: sec, statuses: make(map[string]int)}
+	}
+	for _, sr := range r.StepResults {
+		if sr.Skipped {
+			continue
+		}
+		b.requests++
+		b.durations = append(b.durations, sr.Duration.Seconds())
+		if sr.Err.Type != "" || len(sr.FailedAssertions) > 0 || len(sr.SchemaErrors) > 0 {
+			b.failed++
+		}
+		status := sr.Err.Type
+		if status == "" {
+			status = strconv.Itoa(sr.StatusCode)
+		}
+		b.statuses[status]++
+	}
+}
+
+// stats calculates the rolling stats of the buckets in the window ending at now.
+func (d *dashboard) stats(now time.Time) dashboardStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st := dashboardStats{elapsed: now.Sub(d.start).Truncate(time.Second)}
+	if d.activeUsers != nil {
+		st.activeUsers = d.activeUsers()
+	}
+
+	sec := now.Unix()
+	var requests, failed int
+	var durations []float64
+	statuses := make(map[string]int)
+	for i := range d.buckets {
+		b := &d.buckets[i]
+		if b.sec <= sec-dashboardWindow || b.sec > sec {
+			continue // out of the window
+		}
+		if b.sec == sec-1 {
+			st.rps = b.requests
+		}
+		requests += b.requests
+		failed += b.failed
+		durations = append(durations, b.durations...)
+		for s, c := range b.statuses {
+			statuses[s] += c
+		}
+	}
+
+	if requests > 0 {
+		st.errorRate = float64(failed) / float64(requests)
+	}
+	sort.Float64s(durations)
+	st.p50 = percentileDuration(durations, 0.50)
+	st.p95 = percentileDuration(durations, 0.95)
+	st.p99 = percentileDuration(durations, 0.99)
+
+	for s, c := range statuses {
+		st.statuses = append(st.statuses, statusCount{status: s, count: c})
+	}
+	sort.Slice(st.statuses, func(i, j int) bool { return st.statuses[i].status < st.statuses[j].status })
+	return st
+}
+
+// percentileDuration returns the nearest rank percentile of the sorted durations in seconds.
+func percentileDuration(sorted []float64, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return time.Duration(sorted[i] * float64(time.Second)).Round(time.Millisecond)
+}
+
+// frame returns the lines of the dashboard on a terminal.
+func (st dashboardStats) frame() []string {
+	codes := make([]string, 0, len(st.statuses))
+	for _, s := range st.statuses {
+		c := fmt.Sprintf("%s: %d", s.status, s.count)
+		if !strings.HasPrefix(s.status, "2") {
+			c = red(c)
+		}
+		codes = append(codes, c)
+	}
+
+	return []string{
+		fmt.Sprintf("%s  Elapsed: %-8s RPS: %-8d Active Users: %d", emoji.Stopwatch, st.elapsed, st.rps, st.activeUsers),
+		blue(fmt.Sprintf("%s  Latency (last %ds)     p50: %-8s p95: %-8s p99: %s",
+			emoji.HourglassNotDone, dashboardWindow, st.p50, st.p95, st.p99)),
+		red(fmt.Sprintf("%s Error Rate (last %ds):  %.1f%%", emoji.CrossMark, dashboardWindow, st.errorRate*100)),
+		fmt.Sprintf("%s  Status Codes (last %ds): %s", emoji.BarChart, dashboardWindow, strings.Join(codes, "  ")),
+	}
+}
+
+// line returns the plain line of the dashboard when the output is not a terminal.
+func (st dashboardStats) line() string {
+	codes := make([]string, 0, len(st.statuses))
+	for _, s := range st.statuses {
+		codes = append(codes, fmt.Sprintf("%s: %d", s.status, s.count))
+	}
+	return fmt.Sprintf("elapsed: %s  rps: %d  users: %d  p50: %s  p95: %s  p99: %s  errors: %.1f%%  status: [%s]",
+		st.elapsed, st.rps, st.activeUsers, st.p50, st.p95, st.p99, st.errorRate*100, strings.Join(codes, ", "))
+}
+
+// warn shows the warning under the stats, it replaces the last warning.
+func (d *dashboard) warn(warning string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.warning = warning
+	d.warned = false
+}
+
+// print writes the stats, replacing the last frame on a terminal.
+func (d *dashboard) print(now time.Time) {
+	st := d.stats(now)
+	d.mu.Lock()
+	warning, warned := d.warning, d.warned
+	d.warned = true
+	d.mu.Unlock()
+	if !d.tty {
+		fmt.Fprintln(d.w, st.line())
+		if warning != "" && !warned {
+			fmt.Fprintf(d.w, "warning: %s\n", warning)
+		}
+		return
+	}
+
+	var b strings.Builder
+	if d.lines > 0 {
+		// move the cursor up to the first line of the last frame and clear the rest of the screen
+		fmt.Fprintf(&b, "\033[%dA\033[J", d.lines)
+	}
+	lines := st.frame()
+	if warning != "" {
+		lines = append(lines, yellow(fmt.Sprintf("%s  Warning: %s", emoji.Warning, warning)))
+	}
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	d.lines = len(lines)
+	fmt.Fprint(d.w, b.String())
+}
+
+// run prints the dashboard every second until stop is called, then prints the last frame.
+func (d *dashboard) run() {
+	defer close(d.finished)
+	if util.IsSystemInTestMode() {
+		return
+	}
+
+	fmt.Fprint(d.w, cyan("%s Engine fired. \n\n", emoji.Fire))
+	fmt.Fprint(d.w, cyan("%s CTRL+C to gracefully stop.\n", emoji.StopSign))
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			d.print(now)
+		case <-d.done:
+			d.print(time.Now())
+			return
+		}
+	}
+}
+
+// stop stops the updates, waits for the last frame to be printed.
+func (d *dashboard) stop() {
+	close(d.done)
+	<-d.finished
+}
@@ -0,0 +1,113 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func dashboardResult(statusCode int, d time.Duration, errType string) *types.ScenarioResult {
+	return &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: statusCode, Duration: d, Err: types.RequestError{Type: errType}},
+		{StepID: 2, Skipped: true},
+	}}
+}
+
+func TestDashboardStats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	d := newDashboard(nil, false, func() int64 { return 7 })
+	d.start = now.Add(-20 * time.Second)
+
+	// out of the window
+	d.observe(dashboardResult(200, 5*time.Second, ""), now.Add(-15*time.Second))
+	// last second
+	for i := 1; i <= 8; i++ {
+		d.observe(dashboardResult(200, time.Duration(i)*100*time.Millisecond, ""), now.Add(-time.Second))
+	}
+	d.observe(dashboardResult(500, 900*time.Millisecond, ""), now.Add(-time.Second))
+	d.observe(dashboardResult(0, time.Second, types.ErrorConn), now.Add(-5*time.Second))
+
+	st := d.stats(now)
+	if st.elapsed != 20*time.Second || st.rps != 9 || st.activeUsers != 7 {
+		t.Errorf("Expected %v, Found: %v", []interface{}{20 * time.Second, 9, 7}, []interface{}{st.elapsed, st.rps, st.activeUsers})
+	}
+	if st.p50 != 500*time.Millisecond || st.p95 != time.Second || st.p99 != time.Second {
+		t.Errorf("Expected %v, Found: %v", []time.Duration{500 * time.Millisecond, time.Second, time.Second},
+			[]time.Duration{st.p50, st.p95, st.p99})
+	}
+	if st.errorRate != 0.1 {
+		t.Errorf("Expected %v, Found: %v", 0.1, st.errorRate)
+	}
+	expected := []statusCount{{"200", 8}, {"500", 1}, {types.ErrorConn, 1}}
+	if !reflect.DeepEqual(st.statuses, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, st.statuses)
+	}
+}
+
+func TestDashboardPrintPlainLines(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	var b bytes.Buffer
+	d := newDashboard(&b, false, nil)
+	d.start = now.Add(-3 * time.Second)
+	d.observe(dashboardResult(200, 150*time.Millisecond, ""), now.Add(-time.Second))
+
+	d.print(now)
+	d.print(now.Add(time.Second))
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	expected := "elapsed: 3s  rps: 1  users: 0  p50: 150ms  p95: 150ms  p99: 150ms  errors: 0.0%  status: [200: 1]"
+	if len(lines) != 2 || lines[0] != expected {
+		t.Errorf("Expected %v, Found: %v", expected, lines)
+	}
+	if strings.Contains(b.String(), "\033[") {
+		t.Errorf("Expected %v, Found: %v", "no escape sequences", b.String())
+	}
+}
+
+func TestDashboardPrintInPlace(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	d := newDashboard(&b, true, nil)
+	now := time.Now()
+
+	d.print(now)
+	first := b.String()
+	d.print(now)
+
+	if strings.Contains(first, "\033[") {
+		t.Errorf("Expected %v, Found: %v", "no escape sequences in the first frame", first)
+	}
+	// second frame replaces the lines of the first one
+	if second := strings.TrimPrefix(b.String(), first); !strings.HasPrefix(second, "\033[4A\033[J") {
+		t.Errorf("Expected %v, Found: %q", "\\033[4A\\033[J", second)
+	}
+}
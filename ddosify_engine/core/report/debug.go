@@ -20,7 +20,17 @@ type verboseResponse struct {
 	StatusCode   int               `json:"status_code"`
 	Headers      map[string]string `json:"headers"`
 	Body         interface{}       `json:"body"`
-	ResponseTime int64             `json:"response_time"` // in milliseconds
+	ResponseTime int64             `json:"response_time"`            // in milliseconds
+	Trailers     map[string]string `json:"trailers,omitempty"`       // sent after the body
+	Truncated    bool              `json:"body_truncated,omitempty"` // body exceeded the max response body bytes
+	Redirects    []verboseRedirect `json:"redirects,omitempty"`      // followed before the response
+	Protocol     string            `json:"protocol,omitempty"`       // negotiated protocol version
+}
+
+type verboseRedirect struct {
+	Url          string `json:"url"`
+	StatusCode   int    `json:"status_code"`
+	ResponseTime int64  `json:"response_time"` // in milliseconds
 }
 
 type verboseHttpRequestInfo struct {
@@ -33,6 +43,8 @@ type verboseHttpRequestInfo struct {
 	FailedCaptures   map[string]string       `json:"failed_captures"`
 	FailedAssertions []types.FailedAssertion `json:"failed_assertions"`
 	Error            string                  `json:"error"`
+	Skipped          bool                    `json:"skipped,omitempty"` // condition of the step is not met
+	SchemaErrors     []string                `json:"schema_errors,omitempty"`
 }
 
 func ScenarioStepResultToVerboseHttpRequestInfo(sr *types.ScenarioStepResult) verboseHttpRequestInfo {
@@ -41,6 +53,12 @@ func ScenarioStepResultToVerboseHttpRequestInfo(sr *types.ScenarioStepResult) ve
 	verboseInfo.StepId = sr.StepID
 	verboseInfo.StepName = sr.StepName
 
+	if sr.Skipped {
+		// no request is sent
+		verboseInfo.Skipped = true
+		return verboseInfo
+	}
+
 	if sr.Err.Type == types.ErrorInvalidRequest {
 		// could not prepare request at all
 		verboseInfo.Error = sr.Err.Error()
@@ -63,7 +81,8 @@ func ScenarioStepResultToVerboseHttpRequestInfo(sr *types.ScenarioStepResult) ve
 
 	if sr.Err.Type != "" {
 		verboseInfo.Error = sr.Err.Error()
-	} else {
+	}
+	if sr.Err.Type == "" || sr.Err.Type == types.ErrorGraphQL { // graphql errors are in the received response
 		responseHeaders, responseBody, _ := decode(sr.RespHeaders,
 			sr.RespBody)
 		// TODO what to do with error
@@ -72,6 +91,18 @@ func ScenarioStepResultToVerboseHttpRequestInfo(sr *types.ScenarioStepResult) ve
 			Headers:      responseHeaders,
 			Body:         responseBody,
 			ResponseTime: sr.Duration.Milliseconds(),
+			Truncated:    sr.RespBodyTruncated,
+			Protocol:     sr.Proto,
+		}
+		for _, hop := range sr.Redirects {
+			verboseInfo.Response.Redirects = append(verboseInfo.Response.Redirects, verboseRedirect{
+				Url:          hop.URL,
+				StatusCode:   hop.StatusCode,
+				ResponseTime: hop.Duration.Milliseconds(),
+			})
+		}
+		if len(sr.RespTrailers) > 0 {
+			verboseInfo.Response.Trailers, _, _ = decode(sr.RespTrailers, nil)
 		}
 	}
 
@@ -89,6 +120,7 @@ func ScenarioStepResultToVerboseHttpRequestInfo(sr *types.ScenarioStepResult) ve
 	verboseInfo.TestData = testData
 	verboseInfo.FailedCaptures = sr.FailedCaptures
 	verboseInfo.FailedAssertions = sr.FailedAssertions
+	verboseInfo.SchemaErrors = sr.SchemaErrors
 
 	return verboseInfo
 }
@@ -0,0 +1,176 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// FailureSampler writes the complete request and response of the first failing requests of each error category as
+// JSON lines, to reproduce the failures of a test. Only the counts of the categories are kept in the memory, so the
+// file and the memory are bounded by the number of the categories under a flood of failures.
+type FailureSampler struct {
+	mu          sync.Mutex
+	perCategory int
+	maxBody     int64
+	counts      map[types.ErrorCategory]int
+
+	file *os.File // nil if the samples are not written to a file
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	err  error // first write error, the samples after it are dropped
+}
+
+// failureSample is the JSON line of a failing request, durations are in milliseconds.
+type failureSample struct {
+	Timestamp        time.Time `json:"timestamp"`
+	StepID           uint16    `json:"step_id"`
+	StepName         string    `json:"step_name"`
+	RequestID        string    `json:"request_id,omitempty"`
+	ErrorCategory    string    `json:"error_category"`
+	Error            string    `json:"error,omitempty"`
+	FailedAssertions []string  `json:"failed_assertions,omitempty"`
+	SchemaErrors     []string  `json:"schema_errors,omitempty"`
+	ResponseTime     float64   `json:"response_time"`
+
+	Request  failureSampleRequest   `json:"request"`
+	Response *failureSampleResponse `json:"response,omitempty"` // nil if no response is received
+}
+
+type failureSampleRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type failureSampleResponse struct {
+	StatusCode    int         `json:"status_code"`
+	Protocol      string      `json:"protocol,omitempty"`
+	Headers       http.Header `json:"headers,omitempty"`
+	Body          string      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"body_truncated,omitempty"`
+	Trailers      http.Header `json:"trailers,omitempty"`
+}
+
+// NewFailureSampler creates the file of the conf.
+func NewFailureSampler(conf types.FailureSamplesConf) (*FailureSampler, error) {
+	f, err := os.Create(conf.File)
+	if err != nil {
+		return nil, err
+	}
+	s := newFailureSampler(f, conf.PerCategory, conf.BodyBytes())
+	s.file = f
+	return s, nil
+}
+
+func newFailureSampler(w io.Writer, perCategory int, maxBody int64) *FailureSampler {
+	if perCategory <= 0 {
+		perCategory = types.DefaultFailureSamplesPerCategory
+	}
+	if maxBody <= 0 {
+		maxBody = types.DefaultFailureSampleBodyBytes
+	}
+	buf := bufio.NewWriter(w)
+	return &FailureSampler{
+		perCategory: perCategory,
+		maxBody:     maxBody,
+		counts:      make(map[types.ErrorCategory]int),
+		buf:         buf,
+		enc:         json.NewEncoder(buf),
+	}
+}
+
+// WriteResult writes the request if it failed and the samples of its error category are not full yet.
+func (s *FailureSampler) WriteResult(r *types.ScenarioStepResult) error {
+	rec := newOutputRecord(r)
+	if rec.result() == "success" {
+		return nil
+	}
+	category := r.ErrCategory
+	if category == "" {
+		category = r.Categorize()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil || s.counts[category] >= s.perCategory {
+		return s.err
+	}
+	s.counts[category]++
+
+	sample := failureSample{
+		Timestamp:        r.RequestTime,
+		StepID:           r.StepID,
+		StepName:         r.StepName,
+		RequestID:        rec.RequestID,
+		ErrorCategory:    string(category),
+		Error:            rec.Error,
+		FailedAssertions: rec.FailedAssertions,
+		SchemaErrors:     rec.SchemaErrors,
+		ResponseTime:     rec.ResponseTime,
+		Request: failureSampleRequest{
+			Method:  r.Method,
+			URL:     r.Url,
+			Headers: r.ReqHeaders,
+			Body:    string(r.ReqBody),
+		},
+	}
+	if r.StatusCode != 0 {
+		body, truncated := r.RespBody, r.RespBodyTruncated
+		if int64(len(body)) > s.maxBody {
+			body, truncated = body[:s.maxBody], true
+		}
+		sample.Response = &failureSampleResponse{
+			StatusCode:    r.StatusCode,
+			Protocol:      r.Proto,
+			Headers:       r.RespHeaders,
+			Body:          string(body),
+			BodyTruncated: truncated,
+			Trailers:      r.RespTrailers,
+		}
+	}
+	s.err = s.enc.Encode(sample)
+	return s.err
+}
+
+// Close flushes the samples and closes the file.
+func (s *FailureSampler) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.buf.Flush(); s.err == nil {
+		s.err = err
+	}
+	if s.file != nil {
+		if err := s.file.Close(); s.err == nil {
+			s.err = err
+		}
+	}
+	return s.err
+}
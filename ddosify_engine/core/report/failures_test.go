@@ -0,0 +1,100 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestFailureSampler(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	s := newFailureSampler(buf, 2, 8)
+
+	server5xx := func(i int) *types.ScenarioStepResult {
+		return &types.ScenarioStepResult{
+			StepID:           1,
+			StepName:         "order",
+			StatusCode:       503,
+			Method:           http.MethodPost,
+			Url:              "https://test.com/orders",
+			ReqHeaders:       http.Header{"Content-Type": {"application/json"}},
+			ReqBody:          []byte(`{"id": 1}`),
+			RespHeaders:      http.Header{"Retry-After": {"1"}},
+			RespBody:         []byte("service unavailable"),
+			Duration:         time.Duration(i+1) * time.Millisecond,
+			FailedAssertions: []types.FailedAssertion{{Rule: "status_code < 500"}},
+			ErrCategory:      types.ErrorCategoryHTTP5xx,
+		}
+	}
+	for i := 0; i < 5; i++ {
+		s.WriteResult(server5xx(i))
+	}
+	// successes are not sampled, the connection errors have their own category
+	s.WriteResult(&types.ScenarioStepResult{StepID: 1, StatusCode: 200})
+	s.WriteResult(&types.ScenarioStepResult{
+		StepID: 2,
+		Method: http.MethodGet,
+		Url:    "https://test.com",
+		Err:    types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnRefused},
+	})
+	if err := s.Close(); err != nil {
+		t.Fatalf("TestFailureSampler close error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected %v, Found: %v, %s", 3, len(lines), buf.String())
+	}
+	samples := make([]failureSample, len(lines))
+	for i, l := range lines {
+		if err := json.Unmarshal([]byte(l), &samples[i]); err != nil {
+			t.Fatalf("TestFailureSampler unmarshal error: %v", err)
+		}
+	}
+
+	first := samples[0]
+	if first.ErrorCategory != string(types.ErrorCategoryHTTP5xx) || first.ResponseTime != 1 ||
+		first.Request.Method != http.MethodPost || first.Request.URL != "https://test.com/orders" ||
+		first.Request.Body != `{"id": 1}` || first.Request.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected sample: %+v", first)
+	}
+	if r := first.Response; r == nil || r.StatusCode != 503 || r.Body != "service " || !r.BodyTruncated ||
+		r.Headers.Get("Retry-After") != "1" {
+		t.Errorf("Unexpected response: %+v", first.Response)
+	}
+	if samples[1].ResponseTime != 2 {
+		t.Errorf("Expected the second failure, Found: %+v", samples[1])
+	}
+
+	conn := samples[2]
+	if conn.ErrorCategory != string(types.ErrorCategoryConnect) || conn.Response != nil || conn.Error == "" {
+		t.Errorf("Unexpected sample: %+v", conn)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// DefaultResultHookBufferSize is the number of results queued for a slow ResultHook callback before they are dropped.
+const DefaultResultHookBufferSize = 4096
+
+// ResultHook passes the result of each request to a user callback, in a single separate goroutine.
+// Send never blocks: results are dropped and counted if the callback can't keep up with the load.
+type ResultHook struct {
+	fn      func(*types.ScenarioStepResult)
+	results chan *types.ScenarioStepResult
+	dropped int64
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewResultHook starts the goroutine calling fn for the sent results. Results are queued up to bufferSize.
+func NewResultHook(fn func(*types.ScenarioStepResult), bufferSize int) *ResultHook {
+	if bufferSize <= 0 {
+		bufferSize = DefaultResultHookBufferSize
+	}
+	h := &ResultHook{
+		fn:      fn,
+		results: make(chan *types.ScenarioStepResult, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go h.run()
+	return h
+}
+
+func (h *ResultHook) run() {
+	defer close(h.done)
+	for r := range h.results {
+		h.fn(r)
+	}
+}
+
+// Send queues the result for the callback. Returns false if the queue is full and the result is dropped.
+// Must not be called after Close.
+func (h *ResultHook) Send(r *types.ScenarioStepResult) bool {
+	select {
+	case h.results <- r:
+		return true
+	default:
+		atomic.AddInt64(&h.dropped, 1)
+		return false
+	}
+}
+
+// Dropped returns the number of results that are not passed to the callback because the queue was full.
+func (h *ResultHook) Dropped() int64 {
+	return atomic.LoadInt64(&h.dropped)
+}
+
+// Close waits for the callback to process the queued results. It is safe to call Close multiple times.
+func (h *ResultHook) Close() {
+	h.closeOnce.Do(func() { close(h.results) })
+	<-h.done
+}
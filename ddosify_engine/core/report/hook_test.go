@@ -0,0 +1,81 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestResultHook(t *testing.T) {
+	t.Parallel()
+
+	var got []uint16
+	h := NewResultHook(func(r *types.ScenarioStepResult) {
+		got = append(got, r.StepID)
+	}, 10)
+	for i := 1; i <= 3; i++ {
+		if !h.Send(&types.ScenarioStepResult{StepID: uint16(i)}) {
+			t.Errorf("Expected %v, Found: %v", true, false)
+		}
+	}
+	h.Close()
+	h.Close()
+
+	if len(got) != 3 || got[0] != 1 || got[2] != 3 {
+		t.Errorf("Expected %v, Found: %v", []uint16{1, 2, 3}, got)
+	}
+	if h.Dropped() != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, h.Dropped())
+	}
+}
+
+func TestResultHookDropsWhenFull(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	calls := 0
+	h := NewResultHook(func(r *types.ScenarioStepResult) {
+		if calls == 0 {
+			close(started)
+			<-release
+		}
+		calls++
+	}, 2)
+
+	h.Send(&types.ScenarioStepResult{})
+	<-started // callback is blocked with the first result, queue is empty
+
+	for i := 0; i < 5; i++ {
+		h.Send(&types.ScenarioStepResult{})
+	}
+	if h.Dropped() != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, h.Dropped())
+	}
+
+	close(release)
+	h.Close()
+	if calls != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, calls)
+	}
+}
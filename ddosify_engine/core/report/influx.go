@@ -0,0 +1,190 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+const (
+	influxMeasurement = "ddosify"
+
+	DefaultInfluxBatchSize     = 5000
+	DefaultInfluxFlushInterval = time.Second
+
+	influxWriteTimeout = 10 * time.Second
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	influxStringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// appendInfluxLine appends the result as a line of the InfluxDB line protocol, with a nanosecond timestamp.
+// Tags are the step, the status of the result and the tags of the step if any, fields are the response time (ms), the bytes and the error if any.
+func appendInfluxLine(b []byte, r *types.ScenarioStepResult) []byte {
+	rec := newOutputRecord(r)
+
+	step := rec.StepName
+	if step == "" {
+		step = strconv.Itoa(int(rec.StepID))
+	}
+	result := rec.result()
+
+	b = append(b, influxMeasurementEscaper.Replace(influxMeasurement)...)
+	b = append(b, ",step="...)
+	b = append(b, influxTagEscaper.Replace(step)...)
+	b = append(b, ",step_id="...)
+	b = strconv.AppendUint(b, uint64(rec.StepID), 10)
+	b = append(b, ",status="...)
+	b = strconv.AppendInt(b, int64(rec.StatusCode), 10)
+	b = append(b, ",result="...)
+	b = append(b, result...)
+	if len(rec.Tags) > 0 {
+		b = append(b, ",tags="...)
+		b = append(b, influxTagEscaper.Replace(strings.Join(rec.Tags, ","))...)
+	}
+	if rec.Target != "" {
+		b = append(b, ",target="...)
+		b = append(b, influxTagEscaper.Replace(rec.Target)...)
+	}
+
+	b = append(b, " response_time="...)
+	b = strconv.AppendFloat(b, rec.ResponseTime, 'f', 3, 64)
+	b = append(b, ",bytes="...)
+	b = strconv.AppendInt(b, rec.Bytes, 10)
+	b = append(b, 'i')
+	if rec.Error != "" {
+		b = append(b, `,error="`...)
+		b = append(b, influxStringEscaper.Replace(rec.Error)...)
+		b = append(b, '"')
+	}
+
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, rec.Timestamp.UnixNano(), 10)
+	return append(b, '\n')
+}
+
+// influxLineWriter writes the results in the InfluxDB line protocol, like to a file.
+type influxLineWriter struct {
+	mu   sync.Mutex
+	buf  *bufio.Writer
+	line []byte
+}
+
+func newInfluxLineWriter(w io.Writer) *influxLineWriter {
+	return &influxLineWriter{buf: bufio.NewWriter(w)}
+}
+
+func (l *influxLineWriter) WriteResult(r *types.ScenarioStepResult) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.line = appendInfluxLine(l.line[:0], r)
+	_, err := l.buf.Write(l.line)
+	return err
+}
+
+func (l *influxLineWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Flush()
+}
+
+// InfluxHTTPWriter posts the results to the write endpoint of an InfluxDB v2 in batches.
+// A batch is posted when it is full or at each flush interval, by a separate goroutine.
+// WriteResult never blocks on the InfluxDB, batches are dropped if too many of them are waiting to be posted.
+type InfluxHTTPWriter struct {
+	*batchPoster
+
+	writeURL string
+	token    string
+	client   *http.Client
+}
+
+// NewInfluxHTTPWriter starts the goroutine posting the batches to the InfluxDB of the conf.
+func NewInfluxHTTPWriter(conf types.InfluxConf) (*InfluxHTTPWriter, error) {
+	u, err := url.Parse(strings.TrimSuffix(conf.URL, "/") + "/api/v2/write")
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("org", conf.Org)
+	q.Set("bucket", conf.Bucket)
+	q.Set("precision", "ns")
+	u.RawQuery = q.Encode()
+
+	batchSize := conf.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultInfluxBatchSize
+	}
+	interval := conf.FlushInterval
+	if interval <= 0 {
+		interval = DefaultInfluxFlushInterval
+	}
+
+	w := &InfluxHTTPWriter{
+		writeURL: u.String(),
+		token:    conf.Token,
+		client:   &http.Client{Timeout: influxWriteTimeout},
+	}
+	w.batchPoster = newBatchPoster(batchSize, interval, w.write, nil)
+	return w, nil
+}
+
+func (w *InfluxHTTPWriter) WriteResult(r *types.ScenarioStepResult) error {
+	w.add(func(b []byte) []byte { return appendInfluxLine(b, r) })
+	return nil
+}
+
+func (w *InfluxHTTPWriter) write(b []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.writeURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("influx write: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("influx write failed with status code %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, res.Body)
+	return nil
+}
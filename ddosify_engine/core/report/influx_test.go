@@ -0,0 +1,206 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestInfluxLine(t *testing.T) {
+	t.Parallel()
+
+	ts := time.Unix(1700000000, 5)
+	tests := []struct {
+		name     string
+		result   *types.ScenarioStepResult
+		expected string
+	}{
+		{
+			name: "Success",
+			result: &types.ScenarioStepResult{StepID: 1, StepName: "login page", StatusCode: 200,
+				RequestTime: ts, Duration: 12500 * time.Microsecond, ContentLength: 512},
+			expected: `ddosify,step=login\ page,step_id=1,status=200,result=success response_time=12.500,bytes=512i 1700000000000000005` + "\n",
+		},
+		{
+			name: "AssertionError",
+			result: &types.ScenarioStepResult{StepID: 2, StatusCode: 500, RequestTime: ts, Duration: time.Millisecond,
+				FailedAssertions: []types.FailedAssertion{{Rule: "status_code == 200"}}},
+			expected: `ddosify,step=2,step_id=2,status=500,result=assertion_error response_time=1.000,bytes=0i 1700000000000000005` + "\n",
+		},
+		{
+			name: "ServerError",
+			result: &types.ScenarioStepResult{StepID: 3, StepName: "a,b=c", RequestTime: ts,
+				Err: types.RequestError{Type: types.ErrorConn, Reason: `dial "x"`}},
+			expected: `ddosify,step=a\,b\=c,step_id=3,status=0,result=server_error response_time=0.000,bytes=0i,error="connectionError: dial \"x\"" 1700000000000000005` + "\n",
+		},
+		{
+			name: "Tags",
+			result: &types.ScenarioStepResult{StepID: 4, StatusCode: 200, RequestTime: ts,
+				Tags: []string{"payments", "critical"}},
+			expected: `ddosify,step=4,step_id=4,status=200,result=success,tags=payments\,critical response_time=0.000,bytes=0i 1700000000000000005` + "\n",
+		},
+		{
+			name:     "Target",
+			result:   &types.ScenarioStepResult{StepID: 5, StatusCode: 200, RequestTime: ts, Target: "eu west"},
+			expected: `ddosify,step=5,step_id=5,status=200,result=success,target=eu\ west response_time=0.000,bytes=0i 1700000000000000005` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+			if got := string(appendInfluxLine(nil, tf.result)); got != tf.expected {
+				t.Errorf("Expected %v, Found: %v", tf.expected, got)
+			}
+		})
+	}
+}
+
+func TestInfluxLineWriter(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, err := NewOutputWriter(OutputFormatInflux, buf)
+	if err != nil {
+		t.Fatalf("TestInfluxLineWriter error occurred %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		w.WriteResult(&types.ScenarioStepResult{StepID: 1, StatusCode: 200})
+	}
+	w.Flush()
+
+	if c := strings.Count(buf.String(), "\n"); c != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, c)
+	}
+}
+
+func TestInfluxHTTPWriter(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var bodies []string
+	var query, authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(b))
+		query = r.URL.Path + "?" + r.URL.RawQuery
+		authHeader = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w, err := NewInfluxHTTPWriter(types.InfluxConf{
+		URL:           server.URL + "/",
+		Org:           "ddosify",
+		Bucket:        "load test",
+		Token:         "secret",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("TestInfluxHTTPWriter error occurred %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		w.WriteResult(&types.ScenarioStepResult{StepID: 1, StatusCode: 200})
+	}
+	if err = w.Flush(); err != nil {
+		t.Errorf("TestInfluxHTTPWriter flush error occurred %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Errorf("TestInfluxHTTPWriter close error occurred %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// 2 full batches and the rest at flush
+	if len(bodies) != 3 || strings.Count(strings.Join(bodies, ""), "\n") != 5 {
+		t.Errorf("Expected %v, Found: %v", 3, bodies)
+	}
+	expectedQuery := "/api/v2/write?bucket=load+test&org=ddosify&precision=ns"
+	if query != expectedQuery {
+		t.Errorf("Expected %v, Found: %v", expectedQuery, query)
+	}
+	if authHeader != "Token secret" {
+		t.Errorf("Expected %v, Found: %v", "Token secret", authHeader)
+	}
+	if w.Dropped() != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, w.Dropped())
+	}
+}
+
+func TestInfluxHTTPWriterFlushInterval(t *testing.T) {
+	t.Parallel()
+
+	posted := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		posted <- string(b)
+	}))
+	defer server.Close()
+
+	w, _ := NewInfluxHTTPWriter(types.InfluxConf{URL: server.URL, Bucket: "b", FlushInterval: 20 * time.Millisecond})
+	defer w.Close()
+	w.WriteResult(&types.ScenarioStepResult{StepID: 1, StatusCode: 200})
+
+	select {
+	case b := <-posted:
+		if !strings.HasPrefix(b, "ddosify,step=1") {
+			t.Errorf("Expected %v, Found: %v", "ddosify,step=1...", b)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected the batch to be posted at the flush interval")
+	}
+}
+
+func TestInfluxHTTPWriterError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":"unauthorized"}`))
+	}))
+	defer server.Close()
+
+	w, _ := NewInfluxHTTPWriter(types.InfluxConf{URL: server.URL, Bucket: "b", FlushInterval: time.Hour})
+	defer w.Close()
+	w.WriteResult(&types.ScenarioStepResult{StepID: 1, StatusCode: 200})
+
+	err := w.Flush()
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected %v, Found: %v", "status code 401 error", err)
+	}
+	// error is reported once
+	if err = w.Flush(); err != nil {
+		t.Errorf("Expected %v, Found: %v", nil, err)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// lowest and highest trackable latencies, values out of this range are clamped into the edge buckets
+	histMinValue = time.Microsecond
+	histMaxValue = time.Hour
+
+	// relative error of the estimated quantiles
+	histPrecision = 0.01
+)
+
+var (
+	histLogGrowth   = math.Log1p(histPrecision)
+	histBucketCount = int(math.Log(float64(histMaxValue/histMinValue))/histLogGrowth) + 2
+)
+
+// latencyHistogram is a streaming quantile estimator with logarithmic buckets, similar to HDR histogram.
+// Memory usage is constant regardless of the recorded value count, so it is safe for long running tests.
+// An exact histogram keeps all the recorded values too, its quantiles are computed from them.
+type latencyHistogram struct {
+	counts []uint64
+	total  uint64
+	max    time.Duration
+
+	exact   bool
+	samples []time.Duration // all the recorded values of an exact histogram
+	sorted  bool
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, histBucketCount)}
+}
+
+func newExactLatencyHistogram() *latencyHistogram {
+	h := newLatencyHistogram()
+	h.exact = true
+	return h
+}
+
+// empty returns a new histogram of the same mode, an approximate one if h is nil.
+func (h *latencyHistogram) empty() *latencyHistogram {
+	if h != nil && h.exact {
+		return newExactLatencyHistogram()
+	}
+	return newLatencyHistogram()
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	h.counts[bucketIndex(d)]++
+	h.total++
+	if d > h.max {
+		h.max = d
+	}
+	if h.exact {
+		h.samples = append(h.samples, d)
+		h.sorted = false
+	}
+}
+
+// quantile returns the estimated value at q, 0 < q <= 1. The exact value is returned if the histogram keeps
+// all the recorded values.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	// the epsilon keeps the rank of the percentiles like 99.9 from rounding up, 0.999 * 10000 is 9990.000000000002
+	rank := uint64(math.Ceil(q*float64(h.total) - 1e-9))
+	if rank == 0 {
+		rank = 1
+	}
+	if h.exact && uint64(len(h.samples)) == h.total {
+		if !h.sorted {
+			sort.Slice(h.samples, func(i, j int) bool { return h.samples[i] < h.samples[j] })
+			h.sorted = true
+		}
+		return h.samples[rank-1]
+	}
+
+	var cum uint64
+	for i, c := range h.counts {
+		cum += c
+		if cum >= rank {
+			v := bucketValue(i)
+			if v > h.max || i == histBucketCount-1 { // last bucket holds the clamped values
+				return h.max
+			}
+			return v
+		}
+	}
+	return h.max
+}
+
+// percentiles returns the p50, p90, p95 and p99 of the recorded values, with the custom percentiles like 99.9
+// if any.
+func (h *latencyHistogram) percentiles(custom []float64) *LatencyPercentiles {
+	p := &LatencyPercentiles{
+		P50: float32(h.quantile(0.50).Seconds()),
+		P90: float32(h.quantile(0.90).Seconds()),
+		P95: float32(h.quantile(0.95).Seconds()),
+		P99: float32(h.quantile(0.99).Seconds()),
+		Max: float32(h.max.Seconds()),
+	}
+	if len(custom) > 0 {
+		p.Custom = make(map[string]float32, len(custom))
+		for _, c := range custom {
+			p.Custom[percentileLabel(c)] = float32(h.quantile(c / 100).Seconds())
+		}
+	}
+	return p
+}
+
+// buckets returns the counts of the recorded values in the buckets of the given upper bounds, the last bucket counts
+// the values above the bounds if any. Counts of an approximate histogram are placed by the upper bounds of its own
+// buckets, so they are off by at most its precision.
+func (h *latencyHistogram) buckets(bounds []time.Duration) []LatencyBucket {
+	counts := make([]uint64, len(bounds)+1)
+	index := func(d time.Duration) int {
+		return sort.Search(len(bounds), func(i int) bool { return bounds[i] >= d })
+	}
+	if h.exact && uint64(len(h.samples)) == h.total {
+		for _, d := range h.samples {
+			counts[index(d)]++
+		}
+	} else {
+		for i, c := range h.counts {
+			if c == 0 {
+				continue
+			}
+			v := bucketValue(i)
+			if v > h.max || i == histBucketCount-1 { // last bucket holds the clamped values
+				v = h.max
+			}
+			counts[index(v)] += c
+		}
+	}
+
+	buckets := make([]LatencyBucket, len(bounds), len(bounds)+1)
+	for i, b := range bounds {
+		buckets[i] = LatencyBucket{UpperBound: b.Seconds(), Count: counts[i]}
+	}
+	if over := counts[len(bounds)]; over > 0 {
+		buckets = append(buckets, LatencyBucket{UpperBound: h.max.Seconds(), Count: over})
+	}
+	return buckets
+}
+
+// percentileLabel returns the label of the percentile in the result, like p99.9.
+func percentileLabel(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+func bucketIndex(d time.Duration) int {
+	if d <= histMinValue {
+		return 0
+	}
+	i := int(math.Log(float64(d)/float64(histMinValue))/histLogGrowth) + 1
+	if i >= histBucketCount {
+		return histBucketCount - 1
+	}
+	return i
+}
+
+// bucketValue returns the upper bound of the bucket at index i.
+func bucketValue(i int) time.Duration {
+	return time.Duration(float64(histMinValue) * math.Exp(float64(i)*histLogGrowth))
+}
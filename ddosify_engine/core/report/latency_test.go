@@ -0,0 +1,218 @@
+package report
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestLatencyHistogramQuantiles(t *testing.T) {
+	t.Parallel()
+
+	h := newLatencyHistogram()
+	for i := 1; i <= 10000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		q        float64
+		expected time.Duration
+	}{
+		{0.50, 5000 * time.Millisecond},
+		{0.90, 9000 * time.Millisecond},
+		{0.95, 9500 * time.Millisecond},
+		{0.99, 9900 * time.Millisecond},
+		{1, 10000 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		found := h.quantile(test.q)
+		relErr := math.Abs(float64(found-test.expected)) / float64(test.expected)
+		if relErr > histPrecision {
+			t.Errorf("Quantile %v, Expected %v, Found: %v", test.q, test.expected, found)
+		}
+	}
+
+	if h.max != 10000*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v", 10000*time.Millisecond, h.max)
+	}
+}
+
+func TestLatencyHistogramBoundedMemory(t *testing.T) {
+	t.Parallel()
+
+	h := newLatencyHistogram()
+	h.record(0)
+	h.record(2 * histMaxValue)
+	for i := 0; i < 100000; i++ {
+		h.record(time.Duration(i) * time.Microsecond)
+	}
+
+	if len(h.counts) != histBucketCount {
+		t.Errorf("Expected %v, Found: %v", histBucketCount, len(h.counts))
+	}
+	if h.quantile(1) != 2*histMaxValue {
+		t.Errorf("Expected %v, Found: %v", 2*histMaxValue, h.quantile(1))
+	}
+}
+
+func TestLatencyHistogramEmpty(t *testing.T) {
+	t.Parallel()
+
+	h := newLatencyHistogram()
+	if h.quantile(0.99) != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, h.quantile(0.99))
+	}
+}
+
+func TestLatencyHistogramExact(t *testing.T) {
+	t.Parallel()
+
+	h := newExactLatencyHistogram()
+	for i := 10000; i >= 1; i-- {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		q        float64
+		expected time.Duration
+	}{
+		{0.50, 5000 * time.Millisecond},
+		{0.999, 9990 * time.Millisecond},
+		{0.9999, 9999 * time.Millisecond},
+		{1, 10000 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		if found := h.quantile(test.q); found != test.expected {
+			t.Errorf("Quantile %v, Expected %v, Found: %v", test.q, test.expected, found)
+		}
+	}
+
+	// the recorded values after a quantile are sorted again
+	h.record(time.Microsecond)
+	if found := h.quantile(0.00001); found != time.Microsecond {
+		t.Errorf("Expected %v, Found: %v", time.Microsecond, found)
+	}
+}
+
+func TestLatencyHistogramCustomPercentiles(t *testing.T) {
+	t.Parallel()
+
+	h := newExactLatencyHistogram()
+	for i := 1; i <= 10000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	p := h.percentiles([]float64{50, 99.9, 99.99})
+	expected := map[string]float32{"p50": 5, "p99.9": 9.99, "p99.99": 9.999}
+	if len(p.Custom) != len(expected) {
+		t.Fatalf("Expected %v, Found: %v", expected, p.Custom)
+	}
+	for label, v := range expected {
+		if p.Custom[label] != v {
+			t.Errorf("Percentile %s, Expected %v, Found: %v", label, v, p.Custom[label])
+		}
+	}
+	if p.Max != 10 {
+		t.Errorf("Expected %v, Found: %v", 10, p.Max)
+	}
+
+	if p := newLatencyHistogram().percentiles(nil); p.Custom != nil {
+		t.Errorf("Expected no custom percentiles, Found: %v", p.Custom)
+	}
+}
+
+func TestLatencyHistogramExactMerge(t *testing.T) {
+	t.Parallel()
+
+	a, b := newExactLatencyHistogram(), newExactLatencyHistogram()
+	for i := 1; i <= 100; i++ {
+		if i%2 == 0 {
+			a.record(time.Duration(i) * time.Millisecond)
+		} else {
+			b.record(time.Duration(i) * time.Millisecond)
+		}
+	}
+
+	m := a.empty()
+	m.merge(a.snapshot())
+	m.merge(b.snapshot())
+	if found := m.quantile(0.99); found != 99*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v", 99*time.Millisecond, found)
+	}
+
+	// samples of an approximate snapshot are missing, the buckets are used
+	m.merge(newLatencyHistogram().snapshot())
+	approx := newLatencyHistogram()
+	approx.record(time.Second)
+	m.merge(approx.snapshot())
+	if len(m.samples) == int(m.total) {
+		t.Errorf("Expected the samples to be incomplete")
+	}
+	if found := m.quantile(1); found != time.Second {
+		t.Errorf("Expected %v, Found: %v", time.Second, found)
+	}
+}
+
+func TestLatencyHistogramBuckets(t *testing.T) {
+	t.Parallel()
+
+	bounds := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}
+	expected := []LatencyBucket{{0.01, 1}, {0.02, 2}, {0.04, 1}, {0.1, 1}}
+	for _, h := range []*latencyHistogram{newLatencyHistogram(), newExactLatencyHistogram()} {
+		for _, ms := range []int{5, 12, 15, 30, 100} {
+			h.record(time.Duration(ms) * time.Millisecond)
+		}
+		if buckets := h.buckets(bounds); !reflect.DeepEqual(buckets, expected) {
+			t.Errorf("Expected %v, Found: %v", expected, buckets)
+		}
+	}
+
+	// no overflow bucket if all the latencies are in the bounds
+	h := newLatencyHistogram()
+	h.record(5 * time.Millisecond)
+	expected = []LatencyBucket{{0.01, 1}, {0.02, 0}, {0.04, 0}}
+	if buckets := h.buckets(bounds); !reflect.DeepEqual(buckets, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, buckets)
+	}
+}
+
+func TestAggregatePercentiles(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	for i := 1; i <= 100; i++ {
+		aggregate(result, &types.ScenarioResult{
+			StepResults: []*types.ScenarioStepResult{
+				{StepID: 1, StatusCode: 200, Duration: time.Duration(i) * time.Millisecond},
+			},
+		}, samplingCount, 0)
+	}
+	// server errors have no response time, should be ignored
+	aggregate(result, &types.ScenarioResult{
+		StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, Duration: time.Minute, Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}},
+		},
+	}, samplingCount, 0)
+
+	result.calculatePercentiles()
+
+	p := result.StepResults[1].Percentiles
+	if p == nil {
+		t.Fatalf("Expected percentiles to be calculated")
+	}
+	if p.Max != 0.1 {
+		t.Errorf("Expected %v, Found: %v", 0.1, p.Max)
+	}
+	if math.Abs(float64(p.P99)-0.099) > 0.099*histPrecision {
+		t.Errorf("Expected %v, Found: %v", 0.099, p.P99)
+	}
+	if math.Abs(float64(p.P50)-0.05) > 0.05*histPrecision {
+		t.Errorf("Expected %v, Found: %v", 0.05, p.P50)
+	}
+}
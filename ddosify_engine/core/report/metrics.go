@@ -0,0 +1,320 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+const metricsNamespace = "ddosify"
+
+// MetricsServer exposes the results of a running test on /metrics in Prometheus text format.
+// It is independent from the ReportService, so it can be used together with any output type.
+type MetricsServer struct {
+	addr     string
+	listener net.Listener
+	server   *http.Server
+
+	requests  *prometheus.CounterVec
+	responses *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+
+	// results of the tagged steps by tag, a request is counted for each tag of its step
+	tagRequests *prometheus.CounterVec
+	tagErrors   *prometheus.CounterVec
+	tagLatency  *prometheus.HistogramVec
+
+	// resource usage of the load generator, set by ObserveResources
+	openFDs     prometheus.Gauge
+	fdLimit     prometheus.Gauge
+	connections *prometheus.GaugeVec
+	// waits at the max host conns, counted up from the totals of the last ObserveResources
+	connWaits        prometheus.Counter
+	connWaitTime     prometheus.Counter
+	lastConnWaits    int64
+	lastConnWaitTime float64
+
+	// client pool statistics, read on each scrape. See ObservePool and ObserveHostPools.
+	pools *poolCollector
+}
+
+// NewMetricsServer creates a metrics server that will listen on the given address, like ":9090".
+func NewMetricsServer(addr string) *MetricsServer {
+	return NewMetricsServerWithBuckets(addr, nil)
+}
+
+// NewMetricsServerWithBuckets returns a new metrics server like NewMetricsServer, but the buckets of the latency
+// histograms end at the given upper bounds, see types.LatencyBuckets. The default buckets are used if bounds is empty.
+func NewMetricsServerWithBuckets(addr string, bounds []time.Duration) *MetricsServer {
+	buckets := prometheus.ExponentialBuckets(0.001, 2, 16) // 1ms to ~32s
+	if len(bounds) > 0 {
+		buckets = make([]float64, len(bounds))
+		for i, b := range bounds {
+			buckets[i] = b.Seconds()
+		}
+	}
+
+	m := &MetricsServer{
+		addr: addr,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_total",
+			Help:      "Number of requests sent per step.",
+		}, []string{"step"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "responses_total",
+			Help:      "Number of responses received per step and status code.",
+		}, []string{"step", "status_code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "errors_total",
+			Help:      "Number of failed requests per step and error type.",
+		}, []string{"step", "type"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "response_duration_seconds",
+			Help:      "Response time of the successful requests per step and status code.",
+			Buckets:   buckets,
+		}, []string{"step", "status_code"}),
+		tagRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "tag_requests_total",
+			Help:      "Number of requests sent by the steps of the tag.",
+		}, []string{"tag"}),
+		tagErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "tag_errors_total",
+			Help:      "Number of failed requests of the steps of the tag per error type.",
+		}, []string{"tag", "type"}),
+		tagLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "tag_response_duration_seconds",
+			Help:      "Response time of the successful requests of the steps of the tag.",
+			Buckets:   buckets,
+		}, []string{"tag"}),
+		openFDs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "open_fds",
+			Help:      "Number of the open file descriptors of the load generator.",
+		}),
+		fdLimit: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "fd_limit",
+			Help:      "Limit of the open file descriptors of the load generator, zero if it is unlimited.",
+		}),
+		connections: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "open_connections",
+			Help:      "Number of the open connections of the steps per host, including the idle ones in the pools.",
+		}, []string{"host"}),
+		connWaits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "conn_waits_total",
+			Help:      "Number of the dials waited for a connection at the max host conns limit.",
+		}),
+		connWaitTime: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "conn_wait_seconds_total",
+			Help:      "Total time the dials waited for a connection at the max host conns limit.",
+		}),
+		pools: newPoolCollector(),
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m.requests, m.responses, m.errors, m.latency, m.tagRequests, m.tagErrors, m.tagLatency,
+		m.openFDs, m.fdLimit, m.connections, m.connWaits, m.connWaitTime, m.pools)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	m.server = &http.Server{Handler: mux}
+
+	return m
+}
+
+// Start binds the listen address and serves the metrics in background.
+// Binding errors are returned immediately, so the test does not start without the metrics.
+func (m *MetricsServer) Start() error {
+	l, err := net.Listen("tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	m.listener = l
+
+	go m.server.Serve(l)
+	return nil
+}
+
+// Addr returns the address the server listens on. Useful when it is started with port 0.
+func (m *MetricsServer) Addr() string {
+	if m.listener == nil {
+		return m.addr
+	}
+	return m.listener.Addr().String()
+}
+
+// ObserveResources sets the current resource usage of the load generator, the hosts without any open connections
+// are removed.
+func (m *MetricsServer) ObserveResources(u ResourceUsage) {
+	m.openFDs.Set(float64(u.OpenFDs))
+	m.fdLimit.Set(float64(u.FDLimit))
+	m.connections.Reset()
+	for host, n := range u.ConnectionsPerHost {
+		m.connections.WithLabelValues(host).Set(float64(n))
+	}
+	if u.ConnWaits > m.lastConnWaits {
+		m.connWaits.Add(float64(u.ConnWaits - m.lastConnWaits))
+		m.lastConnWaits = u.ConnWaits
+	}
+	if u.ConnWaitTime > m.lastConnWaitTime {
+		m.connWaitTime.Add(u.ConnWaitTime - m.lastConnWaitTime)
+		m.lastConnWaitTime = u.ConnWaitTime
+	}
+}
+
+// ObservePool exposes the statistics of the client pool returned by stats, read on each scrape. The pool metrics
+// are not exposed while stats returns false.
+func (m *MetricsServer) ObservePool(stats func() (util.PoolStats, bool)) {
+	m.pools.mu.Lock()
+	defer m.pools.mu.Unlock()
+	m.pools.stats = stats
+}
+
+// ObserveHostPools exposes the sizes of the per host client pools returned by hosts, labeled by host, read on each
+// scrape. The host pool metrics are not exposed while hosts returns no pool.
+func (m *MetricsServer) ObserveHostPools(hosts func() map[string]util.PoolStats) {
+	m.pools.mu.Lock()
+	defer m.pools.mu.Unlock()
+	m.pools.hosts = hosts
+}
+
+// Observe records the step results of an iteration. The latencies of an unsampled iteration are left out of the
+// histograms, it is only counted.
+func (m *MetricsServer) Observe(r *types.ScenarioResult) {
+	for _, sr := range r.StepResults {
+		if sr.Skipped {
+			continue
+		}
+		step := sr.StepName
+		if step == "" {
+			step = strconv.Itoa(int(sr.StepID))
+		}
+
+		m.requests.WithLabelValues(step).Inc()
+		m.observeTags(sr, !r.Unsampled)
+		if sr.Err.Type != "" {
+			m.errors.WithLabelValues(step, sr.Err.Type).Inc()
+			continue
+		}
+
+		code := strconv.Itoa(sr.StatusCode)
+		m.responses.WithLabelValues(step, code).Inc()
+		if !r.Unsampled {
+			m.latency.WithLabelValues(step, code).Observe(sr.Duration.Seconds())
+		}
+	}
+}
+
+// observeTags records the step result for each tag of the step.
+func (m *MetricsServer) observeTags(sr *types.ScenarioStepResult, sampled bool) {
+	for _, tag := range sr.Tags {
+		m.tagRequests.WithLabelValues(tag).Inc()
+		if sr.Err.Type != "" {
+			m.tagErrors.WithLabelValues(tag, sr.Err.Type).Inc()
+		} else if sampled {
+			m.tagLatency.WithLabelValues(tag).Observe(sr.Duration.Seconds())
+		}
+	}
+}
+
+// Shutdown stops the server gracefully, in-flight scrapes are completed until ctx is done.
+func (m *MetricsServer) Shutdown(ctx context.Context) error {
+	if m.listener == nil {
+		return nil
+	}
+	return m.server.Shutdown(ctx)
+}
+
+// poolCollector collects the client pool metrics from the current statistics of the pools on each scrape.
+type poolCollector struct {
+	mu    sync.Mutex
+	stats func() (util.PoolStats, bool)
+	hosts func() map[string]util.PoolStats
+
+	idle      *prometheus.Desc
+	inUse     *prometheus.Desc
+	created   *prometheus.Desc
+	reused    *prometheus.Desc
+	hostIdle  *prometheus.Desc
+	hostInUse *prometheus.Desc
+}
+
+func newPoolCollector() *poolCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "pool", name), help, labels, nil)
+	}
+	return &poolCollector{
+		idle:      desc("idle_clients", "Number of the idle clients waiting in the client pool."),
+		inUse:     desc("in_use_clients", "Number of the clients of the pool in use by the iterations."),
+		created:   desc("created_clients_total", "Number of the clients created by the pool."),
+		reused:    desc("reused_clients_total", "Number of the idle clients served again by the pool."),
+		hostIdle:  desc("host_idle_clients", "Number of the idle clients waiting in the client pool of the host.", "host"),
+		hostInUse: desc("host_in_use_clients", "Number of the clients of the pool of the host in use.", "host"),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.idle, c.inUse, c.created, c.reused, c.hostIdle, c.hostInUse} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	stats, hosts := c.stats, c.hosts
+	c.mu.Unlock()
+
+	if stats != nil {
+		if s, ok := stats(); ok {
+			ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle))
+			ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse))
+			ch <- prometheus.MustNewConstMetric(c.created, prometheus.CounterValue, float64(s.Created))
+			ch <- prometheus.MustNewConstMetric(c.reused, prometheus.CounterValue, float64(s.Reused))
+		}
+	}
+	if hosts != nil {
+		for host, s := range hosts() {
+			ch <- prometheus.MustNewConstMetric(c.hostIdle, prometheus.GaugeValue, float64(s.Idle), host)
+			ch <- prometheus.MustNewConstMetric(c.hostInUse, prometheus.GaugeValue, float64(s.InUse), host)
+		}
+	}
+}
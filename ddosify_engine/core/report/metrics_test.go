@@ -0,0 +1,237 @@
+package report
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+func TestMetricsServer(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetricsServer("127.0.0.1:0")
+	if err := m.Start(); err != nil {
+		t.Fatalf("TestMetricsServer start error: %v", err)
+	}
+
+	m.Observe(&types.ScenarioResult{
+		StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StepName: "login", StatusCode: 200, Duration: 20 * time.Millisecond},
+			{StepID: 2, StatusCode: 500, Duration: 5 * time.Millisecond, Tags: []string{"payments", "critical"}},
+		},
+	})
+	m.Observe(&types.ScenarioResult{
+		StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StepName: "login", Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}},
+		},
+	})
+
+	resp, err := http.Get("http://" + m.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("TestMetricsServer scrape error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	expected := []string{
+		`ddosify_requests_total{step="login"} 2`,
+		`ddosify_requests_total{step="2"} 1`,
+		`ddosify_responses_total{status_code="200",step="login"} 1`,
+		`ddosify_responses_total{status_code="500",step="2"} 1`,
+		`ddosify_errors_total{step="login",type="connectionError"} 1`,
+		`ddosify_response_duration_seconds_count{status_code="200",step="login"} 1`,
+		`ddosify_response_duration_seconds_sum{status_code="200",step="login"} 0.02`,
+		`ddosify_tag_requests_total{tag="payments"} 1`,
+		`ddosify_tag_requests_total{tag="critical"} 1`,
+		`ddosify_tag_response_duration_seconds_count{tag="payments"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Errorf("Expected %v in metrics, Found: %s", e, body)
+		}
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Errorf("TestMetricsServer shutdown error: %v", err)
+	}
+	if _, err := http.Get("http://" + m.Addr() + "/metrics"); err == nil {
+		t.Errorf("Expected server to be closed after Shutdown")
+	}
+}
+
+func TestMetricsServerConnWaits(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetricsServer("127.0.0.1:0")
+	if err := m.Start(); err != nil {
+		t.Fatalf("TestMetricsServerConnWaits start error: %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	// usages are the totals so far, the counters are not added twice
+	m.ObserveResources(ResourceUsage{ConnectionsPerHost: map[string]int64{"a.com:443": 2}, ConnWaits: 2, ConnWaitTime: 0.5})
+	m.ObserveResources(ResourceUsage{ConnectionsPerHost: map[string]int64{"a.com:443": 2}, ConnWaits: 3, ConnWaitTime: 1.5})
+
+	resp, err := http.Get("http://" + m.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("TestMetricsServerConnWaits scrape error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	expected := []string{
+		`ddosify_open_connections{host="a.com:443"} 2`,
+		`ddosify_conn_waits_total 3`,
+		`ddosify_conn_wait_seconds_total 1.5`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Errorf("Expected %v in metrics, Found: %s", e, body)
+		}
+	}
+}
+
+func TestMetricsServerUnsampled(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetricsServer("127.0.0.1:0")
+	if err := m.Start(); err != nil {
+		t.Fatalf("TestMetricsServerUnsampled start error: %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	m.Observe(&types.ScenarioResult{
+		StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StepName: "login", StatusCode: 200, Duration: 20 * time.Millisecond, Tags: []string{"auth"}},
+		},
+	})
+	// counted, but its latency is not in the histograms
+	m.Observe(&types.ScenarioResult{
+		Unsampled: true,
+		StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StepName: "login", StatusCode: 200, Duration: 80 * time.Millisecond, Tags: []string{"auth"}},
+		},
+	})
+
+	resp, err := http.Get("http://" + m.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("TestMetricsServerUnsampled scrape error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	expected := []string{
+		`ddosify_requests_total{step="login"} 2`,
+		`ddosify_responses_total{status_code="200",step="login"} 2`,
+		`ddosify_tag_requests_total{tag="auth"} 2`,
+		`ddosify_response_duration_seconds_count{status_code="200",step="login"} 1`,
+		`ddosify_response_duration_seconds_sum{status_code="200",step="login"} 0.02`,
+		`ddosify_tag_response_duration_seconds_count{tag="auth"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Errorf("Expected %v in metrics, Found: %s", e, body)
+		}
+	}
+}
+
+func TestMetricsServerWithBuckets(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetricsServerWithBuckets("127.0.0.1:0", []time.Duration{10 * time.Millisecond, 15 * time.Millisecond})
+	if err := m.Start(); err != nil {
+		t.Fatalf("TestMetricsServerWithBuckets start error: %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	m.Observe(&types.ScenarioResult{
+		StepResults: []*types.ScenarioStepResult{
+			{StepID: 1, StatusCode: 200, Duration: 12 * time.Millisecond},
+		},
+	})
+
+	resp, err := http.Get("http://" + m.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("TestMetricsServerWithBuckets scrape error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	expected := []string{
+		`ddosify_response_duration_seconds_bucket{status_code="200",step="1",le="0.01"} 0`,
+		`ddosify_response_duration_seconds_bucket{status_code="200",step="1",le="0.015"} 1`,
+		`ddosify_response_duration_seconds_bucket{status_code="200",step="1",le="+Inf"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Errorf("Expected %v in metrics, Found: %s", e, body)
+		}
+	}
+}
+
+func TestMetricsServerPools(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetricsServer("127.0.0.1:0")
+	if err := m.Start(); err != nil {
+		t.Fatalf("TestMetricsServerPools start error: %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	scrape := func() string {
+		resp, err := http.Get("http://" + m.Addr() + "/metrics")
+		if err != nil {
+			t.Fatalf("TestMetricsServerPools scrape error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return string(body)
+	}
+
+	// no pool metrics until a pool is observed
+	if body := scrape(); strings.Contains(body, "ddosify_pool_") {
+		t.Errorf("Expected no pool metrics, Found: %s", body)
+	}
+
+	m.ObservePool(func() (util.PoolStats, bool) {
+		return util.PoolStats{Idle: 3, InUse: 7, Created: 10, Reused: 25}, true
+	})
+	m.ObserveHostPools(func() map[string]util.PoolStats {
+		return map[string]util.PoolStats{"https://a.com:443": {Idle: 1, InUse: 2}}
+	})
+
+	body := scrape()
+	expected := []string{
+		`ddosify_pool_idle_clients 3`,
+		`ddosify_pool_in_use_clients 7`,
+		`ddosify_pool_created_clients_total 10`,
+		`ddosify_pool_reused_clients_total 25`,
+		`ddosify_pool_host_idle_clients{host="https://a.com:443"} 1`,
+		`ddosify_pool_host_in_use_clients{host="https://a.com:443"} 2`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("Expected %v in metrics, Found: %s", e, body)
+		}
+	}
+}
+
+func TestMetricsServerAddrInUse(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetricsServer("127.0.0.1:0")
+	if err := m.Start(); err != nil {
+		t.Fatalf("TestMetricsServerAddrInUse start error: %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	if err := NewMetricsServer(m.Addr()).Start(); err == nil {
+		t.Errorf("Expected error for the address in use, Found: nil")
+	}
+}
@@ -0,0 +1,38 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+// OutputTypeNone aggregates the results like OutputTypeStdoutJson without printing them,
+// for the programmatic runs reading the result by ResultProvider.
+const OutputTypeNone = "none"
+
+func init() {
+	AvailableOutputServices[OutputTypeNone] = &none{}
+}
+
+type none struct {
+	stdoutJson
+}
+
+func (n *none) Init(debug bool, samplingRate int, targetRPS int) error {
+	n.quiet = true
+	return n.stdoutJson.Init(debug, samplingRate, targetRPS)
+}
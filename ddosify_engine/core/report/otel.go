@@ -0,0 +1,448 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+const (
+	DefaultOTelServiceName   = "ddosify"
+	DefaultOTelBatchSize     = 512
+	DefaultOTelFlushInterval = 5 * time.Second
+
+	otelScopeName     = "go.ddosify.com/ddosify"
+	otelExportTimeout = 10 * time.Second
+
+	// span kind and status codes of the OTLP
+	otelSpanKindClient  = 3
+	otelStatusCodeError = 2
+
+	// cumulative aggregation temporality of the OTLP metrics
+	otelTemporalityCumulative = 2
+)
+
+// otelDurationBounds are the explicit bucket bounds (ms) of the request duration histogram, the OTel SDK defaults.
+var otelDurationBounds = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+// otelPhaseOrder is the order of the connection phases of a request, the span events are laid out in it.
+var otelPhaseOrder = []string{"dns", "connection", "tls", "request_write", "server_processing", "response_read", "decompression"}
+
+// OTelExporter exports a span per request and the request metrics to an OpenTelemetry collector by the OTLP/HTTP
+// protocol with the JSON encoding. Spans are exported in batches like the InfluxHTTPWriter, the cumulative metrics
+// at each flush interval. WriteResult never blocks on the collector.
+type OTelExporter struct {
+	*batchPoster
+
+	tracesURL  string
+	metricsURL string
+	headers    map[string]string
+	resource   json.RawMessage
+	client     *http.Client
+	start      time.Time
+
+	mu     sync.Mutex
+	series map[otelSeriesKey]*otelSeries
+}
+
+type otelSeriesKey struct {
+	step   string
+	status int
+	result string
+}
+
+// otelSeries is the cumulative request count and duration histogram of a step, status and result.
+type otelSeries struct {
+	count    uint64
+	sum      float64
+	min, max float64
+	buckets  []uint64
+}
+
+// NewOTelExporter starts the goroutine exporting the spans and the metrics to the collector of the conf.
+func NewOTelExporter(conf types.OTelConf) (*OTelExporter, error) {
+	endpoint := strings.TrimSuffix(conf.Endpoint, "/")
+	serviceName := conf.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultOTelServiceName
+	}
+	resource, err := json.Marshal(otelResource{Attributes: []otelAttr{strAttr("service.name", serviceName)}})
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := conf.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultOTelBatchSize
+	}
+	interval := conf.FlushInterval
+	if interval <= 0 {
+		interval = DefaultOTelFlushInterval
+	}
+
+	e := &OTelExporter{
+		tracesURL:  endpoint + "/v1/traces",
+		metricsURL: endpoint + "/v1/metrics",
+		headers:    conf.Headers,
+		resource:   resource,
+		client:     &http.Client{Timeout: otelExportTimeout},
+		start:      time.Now(),
+		series:     make(map[otelSeriesKey]*otelSeries),
+	}
+	e.batchPoster = newBatchPoster(batchSize, interval, e.exportSpans, e.exportMetrics)
+	return e, nil
+}
+
+// WriteResult records the request into the metrics and buffers its span.
+func (e *OTelExporter) WriteResult(r *types.ScenarioStepResult) error {
+	rec := newOutputRecord(r)
+	e.observe(&rec)
+
+	span, err := json.Marshal(newOTelSpan(r, &rec))
+	if err != nil {
+		return err
+	}
+	e.add(func(b []byte) []byte {
+		if len(b) > 0 {
+			b = append(b, ',')
+		}
+		return append(b, span...)
+	})
+	return nil
+}
+
+func (e *OTelExporter) observe(rec *outputRecord) {
+	key := otelSeriesKey{step: stepLabel(rec), status: rec.StatusCode, result: rec.result()}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.series[key]
+	if !ok {
+		s = &otelSeries{min: rec.ResponseTime, max: rec.ResponseTime, buckets: make([]uint64, len(otelDurationBounds)+1)}
+		e.series[key] = s
+	}
+	s.count++
+	s.sum += rec.ResponseTime
+	if rec.ResponseTime < s.min {
+		s.min = rec.ResponseTime
+	}
+	if rec.ResponseTime > s.max {
+		s.max = rec.ResponseTime
+	}
+	s.buckets[sort.SearchFloat64s(otelDurationBounds, rec.ResponseTime)]++
+}
+
+func stepLabel(rec *outputRecord) string {
+	if rec.StepName != "" {
+		return rec.StepName
+	}
+	return strconv.Itoa(int(rec.StepID))
+}
+
+func newOTelSpan(r *types.ScenarioStepResult, rec *outputRecord) otelSpan {
+	traceID, spanID := types.TraceIDs(r.RequestID)
+	if r.RequestID == uuid.Nil { // results without a request like the skipped ones
+		traceID, spanID = types.TraceIDs(uuid.New())
+	}
+	start := r.RequestTime
+	end := start.Add(r.Duration)
+
+	attrs := []otelAttr{
+		intAttr("ddosify.step.id", int64(rec.StepID)),
+		strAttr("ddosify.step.name", stepLabel(rec)),
+		strAttr("ddosify.result", rec.result()),
+	}
+	if rec.RequestID != "" {
+		attrs = append(attrs, strAttr("ddosify.request_id", rec.RequestID))
+	}
+	if r.Method != "" {
+		attrs = append(attrs, strAttr("http.request.method", r.Method))
+	}
+	if r.Url != "" {
+		attrs = append(attrs, strAttr("url.full", r.Url))
+	}
+	if rec.StatusCode != 0 {
+		attrs = append(attrs, intAttr("http.response.status_code", int64(rec.StatusCode)))
+	}
+	if len(rec.Tags) > 0 {
+		attrs = append(attrs, strAttr("ddosify.tags", strings.Join(rec.Tags, ",")))
+	}
+	if rec.Target != "" {
+		attrs = append(attrs, strAttr("ddosify.target", rec.Target))
+	}
+	if rec.ErrorCategory != "" {
+		attrs = append(attrs, strAttr("error.type", rec.ErrorCategory))
+	}
+
+	// phases end one after the other from the start of the request
+	var events []otelEvent
+	at := start
+	for _, phase := range otelPhaseOrder {
+		ms, ok := rec.Phases[phase]
+		if !ok {
+			continue
+		}
+		if at = at.Add(time.Duration(ms * float64(time.Millisecond))); at.After(end) {
+			at = end
+		}
+		events = append(events, otelEvent{
+			TimeUnixNano: unixNano(at),
+			Name:         phase,
+			Attributes:   []otelAttr{doubleAttr("duration_ms", ms)},
+		})
+	}
+
+	span := otelSpan{
+		TraceID:           traceID,
+		SpanID:            spanID,
+		Name:              stepLabel(rec),
+		Kind:              otelSpanKindClient,
+		StartTimeUnixNano: unixNano(start),
+		EndTimeUnixNano:   unixNano(end),
+		Attributes:        attrs,
+		Events:            events,
+	}
+	switch {
+	case rec.Error != "":
+		span.Status = &otelStatus{Code: otelStatusCodeError, Message: rec.Error}
+	case len(rec.FailedAssertions) > 0:
+		span.Status = &otelStatus{Code: otelStatusCodeError, Message: rec.FailedAssertions[0]}
+	case len(rec.SchemaErrors) > 0:
+		span.Status = &otelStatus{Code: otelStatusCodeError, Message: rec.SchemaErrors[0]}
+	}
+	return span
+}
+
+func (e *OTelExporter) exportSpans(b []byte) error {
+	body := make([]byte, 0, len(b)+len(e.resource)+128)
+	body = append(body, `{"resourceSpans":[{"resource":`...)
+	body = append(body, e.resource...)
+	body = append(body, `,"scopeSpans":[{"scope":{"name":"`+otelScopeName+`"},"spans":[`...)
+	body = append(body, b...)
+	body = append(body, "]}]}]}"...)
+	return e.export(e.tracesURL, body)
+}
+
+// exportMetrics exports the cumulative request count and duration histogram of each series.
+func (e *OTelExporter) exportMetrics() error {
+	now := unixNano(time.Now())
+	start := unixNano(e.start)
+
+	e.mu.Lock()
+	if len(e.series) == 0 {
+		e.mu.Unlock()
+		return nil
+	}
+	keys := make([]otelSeriesKey, 0, len(e.series))
+	for k := range e.series {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].step != keys[j].step {
+			return keys[i].step < keys[j].step
+		}
+		if keys[i].status != keys[j].status {
+			return keys[i].status < keys[j].status
+		}
+		return keys[i].result < keys[j].result
+	})
+	counts := make([]otelNumberPoint, 0, len(keys))
+	durations := make([]otelHistogramPoint, 0, len(keys))
+	for _, k := range keys {
+		s := e.series[k]
+		attrs := []otelAttr{strAttr("ddosify.step.name", k.step), intAttr("http.response.status_code", int64(k.status)),
+			strAttr("ddosify.result", k.result)}
+		counts = append(counts, otelNumberPoint{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now,
+			AsInt: strconv.FormatUint(s.count, 10)})
+		buckets := make([]string, len(s.buckets))
+		for i, c := range s.buckets {
+			buckets[i] = strconv.FormatUint(c, 10)
+		}
+		durations = append(durations, otelHistogramPoint{Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now,
+			Count: strconv.FormatUint(s.count, 10), Sum: s.sum, Min: s.min, Max: s.max,
+			BucketCounts: buckets, ExplicitBounds: otelDurationBounds})
+	}
+	e.mu.Unlock()
+
+	body, err := json.Marshal(otelMetricsRequest{ResourceMetrics: []otelResourceMetrics{{
+		Resource: e.resource,
+		ScopeMetrics: []otelScopeMetrics{{
+			Scope: otelScope{Name: otelScopeName},
+			Metrics: []otelMetric{
+				{Name: "ddosify.requests", Unit: "{request}", Description: "Number of the requests",
+					Sum: &otelSum{DataPoints: counts, AggregationTemporality: otelTemporalityCumulative, IsMonotonic: true}},
+				{Name: "ddosify.request.duration", Unit: "ms", Description: "Duration of the requests",
+					Histogram: &otelHistogram{DataPoints: durations, AggregationTemporality: otelTemporalityCumulative}},
+			},
+		}},
+	}}})
+	if err != nil {
+		return err
+	}
+	return e.export(e.metricsURL, body)
+}
+
+func (e *OTelExporter) export(url string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("otel export: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("otel export failed with status code %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// JSON encoding of the OTLP messages, 64 bit integers are encoded as strings.
+
+type otelResource struct {
+	Attributes []otelAttr `json:"attributes"`
+}
+
+type otelScope struct {
+	Name string `json:"name"`
+}
+
+type otelAttr struct {
+	Key   string    `json:"key"`
+	Value otelValue `json:"value"`
+}
+
+type otelValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func strAttr(key, v string) otelAttr {
+	return otelAttr{Key: key, Value: otelValue{StringValue: &v}}
+}
+
+func intAttr(key string, v int64) otelAttr {
+	s := strconv.FormatInt(v, 10)
+	return otelAttr{Key: key, Value: otelValue{IntValue: &s}}
+}
+
+func doubleAttr(key string, v float64) otelAttr {
+	return otelAttr{Key: key, Value: otelValue{DoubleValue: &v}}
+}
+
+type otelSpan struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []otelAttr  `json:"attributes,omitempty"`
+	Events            []otelEvent `json:"events,omitempty"`
+	Status            *otelStatus `json:"status,omitempty"`
+}
+
+type otelEvent struct {
+	TimeUnixNano string     `json:"timeUnixNano"`
+	Name         string     `json:"name"`
+	Attributes   []otelAttr `json:"attributes,omitempty"`
+}
+
+type otelStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otelMetricsRequest struct {
+	ResourceMetrics []otelResourceMetrics `json:"resourceMetrics"`
+}
+
+type otelResourceMetrics struct {
+	Resource     json.RawMessage    `json:"resource"`
+	ScopeMetrics []otelScopeMetrics `json:"scopeMetrics"`
+}
+
+type otelScopeMetrics struct {
+	Scope   otelScope    `json:"scope"`
+	Metrics []otelMetric `json:"metrics"`
+}
+
+type otelMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Unit        string         `json:"unit,omitempty"`
+	Sum         *otelSum       `json:"sum,omitempty"`
+	Histogram   *otelHistogram `json:"histogram,omitempty"`
+}
+
+type otelSum struct {
+	DataPoints             []otelNumberPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type otelNumberPoint struct {
+	Attributes        []otelAttr `json:"attributes"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             string     `json:"asInt"`
+}
+
+type otelHistogram struct {
+	DataPoints             []otelHistogramPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type otelHistogramPoint struct {
+	Attributes        []otelAttr `json:"attributes"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Sum               float64    `json:"sum"`
+	Min               float64    `json:"min"`
+	Max               float64    `json:"max"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+}
@@ -0,0 +1,118 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestOTelExporter(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	bodies := make(map[string][]string)
+	var authHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies[r.URL.Path] = append(bodies[r.URL.Path], string(b))
+		authHeader = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	e, err := NewOTelExporter(types.OTelConf{
+		Endpoint:      server.URL + "/",
+		Headers:       map[string]string{"Authorization": "Bearer secret"},
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("TestOTelExporter error occurred %v", err)
+	}
+	requestID := uuid.New()
+	e.WriteResult(&types.ScenarioStepResult{StepID: 1, StepName: "login", RequestID: requestID, StatusCode: 200,
+		Method: http.MethodGet, Url: "http://test.com/login", RequestTime: time.Now(), Duration: 30 * time.Millisecond,
+		Custom: map[string]interface{}{"dnsDuration": 10 * time.Millisecond, "connDuration": 5 * time.Millisecond}})
+	e.WriteResult(&types.ScenarioStepResult{StepID: 1, StepName: "login", RequestID: uuid.New(), RequestTime: time.Now(),
+		Err: types.RequestError{Type: types.ErrorConn, Reason: "connection refused"}})
+	if err = e.Close(); err != nil {
+		t.Errorf("TestOTelExporter close error occurred %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if authHeader != "Bearer secret" {
+		t.Errorf("Expected %v, Found: %v", "Bearer secret", authHeader)
+	}
+
+	if len(bodies["/v1/traces"]) != 1 {
+		t.Fatalf("Expected %v, Found: %v", 1, bodies["/v1/traces"])
+	}
+	var traces struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otelSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err = json.Unmarshal([]byte(bodies["/v1/traces"][0]), &traces); err != nil {
+		t.Fatalf("TestOTelExporter traces error occurred %v", err)
+	}
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected %v, Found: %v", 2, spans)
+	}
+	traceID, spanID := types.TraceIDs(requestID)
+	if spans[0].TraceID != traceID || spans[0].SpanID != spanID || spans[0].Name != "login" || spans[0].Status != nil {
+		t.Errorf("Expected %v, Found: %v", []string{traceID, spanID, "login"}, spans[0])
+	}
+	if len(spans[0].Events) != 2 || spans[0].Events[0].Name != "dns" || spans[0].Events[1].Name != "connection" {
+		t.Errorf("Expected %v, Found: %v", "dns and connection events", spans[0].Events)
+	}
+	if spans[1].Status == nil || spans[1].Status.Code != otelStatusCodeError {
+		t.Errorf("Expected %v, Found: %v", "error status", spans[1].Status)
+	}
+
+	if len(bodies["/v1/metrics"]) != 1 {
+		t.Fatalf("Expected %v, Found: %v", 1, bodies["/v1/metrics"])
+	}
+	var metrics otelMetricsRequest
+	if err = json.Unmarshal([]byte(bodies["/v1/metrics"][0]), &metrics); err != nil {
+		t.Fatalf("TestOTelExporter metrics error occurred %v", err)
+	}
+	m := metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	// a series for the success and the server error
+	if len(m) != 2 || len(m[0].Sum.DataPoints) != 2 || len(m[1].Histogram.DataPoints) != 2 {
+		t.Fatalf("Expected %v, Found: %v", "2 series of 2 metrics", m)
+	}
+	if m[0].Sum.DataPoints[1].AsInt != "1" || m[1].Histogram.DataPoints[1].BucketCounts[4] != "1" {
+		t.Errorf("Expected %v, Found: %v", "1 request of 30ms", []interface{}{m[0].Sum.DataPoints[1], m[1].Histogram.DataPoints[1]})
+	}
+}
@@ -0,0 +1,199 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// max number of the batches waiting to be posted, the new ones are dropped if the destination can't keep up with them
+const maxPendingBatches = 16
+
+// batchPoster buffers the encoded results and posts them in batches by a separate goroutine.
+// A batch is posted when it is full or at each flush interval.
+// add never blocks on the posts, batches are dropped if too many of them are waiting to be posted.
+type batchPoster struct {
+	batchSize int
+	post      func(b []byte) error
+	tick      func() error // called at each flush interval and before stopping, optional
+
+	mu    sync.Mutex
+	batch []byte
+	count int
+	err   error // first post error since the last Flush
+
+	batches chan []byte
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	dropped int64 // number of the results in the dropped batches
+}
+
+// newBatchPoster starts the goroutine posting the batches by the post func.
+func newBatchPoster(batchSize int, interval time.Duration, post func(b []byte) error, tick func() error) *batchPoster {
+	p := &batchPoster{
+		batchSize: batchSize,
+		post:      post,
+		tick:      tick,
+		batches:   make(chan []byte, maxPendingBatches),
+		flushes:   make(chan chan struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go p.run(interval)
+	return p
+}
+
+// add appends a result to the current batch by the encode func, and queues the batch if it is full.
+func (p *batchPoster) add(encode func(b []byte) []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batch = encode(p.batch)
+	p.count++
+	if p.count < p.batchSize {
+		return
+	}
+
+	b, n := p.takeBatchLocked()
+	select {
+	case p.batches <- b:
+	default:
+		atomic.AddInt64(&p.dropped, int64(n))
+	}
+}
+
+// takeBatchLocked returns the current batch and its result count, and starts a new one.
+func (p *batchPoster) takeBatchLocked() ([]byte, int) {
+	b, n := p.batch, p.count
+	p.batch = nil
+	p.count = 0
+	return b, n
+}
+
+// sendBatch passes the current batch to the posting goroutine, waiting for a free slot in the queue.
+func (p *batchPoster) sendBatch() {
+	p.mu.Lock()
+	b, n := p.takeBatchLocked()
+	p.mu.Unlock()
+	if n == 0 {
+		return
+	}
+	select {
+	case p.batches <- b:
+	case <-p.done: // closed
+		atomic.AddInt64(&p.dropped, int64(n))
+	}
+}
+
+func (p *batchPoster) run(interval time.Duration) {
+	defer close(p.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case b := <-p.batches:
+			p.record(p.post(b))
+		case <-ticker.C:
+			p.mu.Lock()
+			b, n := p.takeBatchLocked()
+			p.mu.Unlock()
+			if n > 0 {
+				p.record(p.post(b))
+			}
+			p.runTick()
+		case flushed := <-p.flushes:
+			p.postPending()
+			p.runTick()
+			close(flushed)
+		case <-p.stop:
+			p.postPending()
+			p.runTick()
+			return
+		}
+	}
+}
+
+// postPending posts the queued batches.
+func (p *batchPoster) postPending() {
+	for {
+		select {
+		case b := <-p.batches:
+			p.record(p.post(b))
+		default:
+			return
+		}
+	}
+}
+
+func (p *batchPoster) runTick() {
+	if p.tick != nil {
+		p.record(p.tick())
+	}
+}
+
+func (p *batchPoster) record(err error) {
+	if err != nil {
+		p.mu.Lock()
+		if p.err == nil {
+			p.err = err
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Flush posts the buffered results and waits for them. Returns the first post error since the last Flush.
+func (p *batchPoster) Flush() error {
+	p.sendBatch()
+
+	flushed := make(chan struct{})
+	select {
+	case p.flushes <- flushed:
+		<-flushed
+	case <-p.done: // closed
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	err := p.err
+	p.err = nil
+	return err
+}
+
+// Close posts the buffered results and stops the posting goroutine. It is safe to call Close multiple times.
+func (p *batchPoster) Close() error {
+	p.sendBatch()
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Dropped returns the number of the results not posted because the destination couldn't keep up with them.
+func (p *batchPoster) Dropped() int64 {
+	return atomic.LoadInt64(&p.dropped)
+}
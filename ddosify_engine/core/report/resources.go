@@ -0,0 +1,116 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// resourceWarnRatio is the ratio of the open file descriptors to their limit that the resource usage is warned at.
+const resourceWarnRatio = 0.8
+
+// ResourceUsage is the usage of the file descriptors and the connections of the load generator.
+type ResourceUsage struct {
+	// Open file descriptors of the process, zero if they can't be listed on the platform
+	OpenFDs int `json:"open_fds,omitempty"`
+
+	// Soft limit of the open file descriptors of the process, zero if it is unlimited or unknown
+	FDLimit uint64 `json:"fd_limit,omitempty"`
+
+	// Open connections of the steps, the ones idle in the connection pools are included
+	Connections int64 `json:"connections"`
+
+	// Open connections per host:port. In the peak usage, each host has its own peak.
+	ConnectionsPerHost map[string]int64 `json:"connections_per_host,omitempty"`
+
+	// Dials waited for a connection at the max host conns limit and their total wait in seconds, so far
+	ConnWaits    int64   `json:"conn_waits,omitempty"`
+	ConnWaitTime float64 `json:"conn_wait_time,omitempty"`
+}
+
+// FDRatio returns the ratio of the open file descriptors to their limit, zero if either is unknown.
+func (u ResourceUsage) FDRatio() float64 {
+	if u.FDLimit == 0 {
+		return 0
+	}
+	return float64(u.OpenFDs) / float64(u.FDLimit)
+}
+
+// NearFDLimit reports whether the open file descriptors approach their limit.
+func (u ResourceUsage) NearFDLimit() bool {
+	return u.FDRatio() >= resourceWarnRatio
+}
+
+// warning returns the warning of the usage approaching the file descriptor limit, with the hosts having the most
+// connections.
+func (u ResourceUsage) warning() string {
+	return fmt.Sprintf("open file descriptors are at %.0f%% of the limit (%d of %d), the requests will fail at the "+
+		"limit. Raise it by ulimit -n, or lower the concurrency. Open connections: %s",
+		u.FDRatio()*100, u.OpenFDs, u.FDLimit, u.connections())
+}
+
+// connections returns the connections with the top hosts, like "120 (api.test:443: 100, cdn.test:443: 20)".
+func (u ResourceUsage) connections() string {
+	hosts := make([]string, 0, len(u.ConnectionsPerHost))
+	for h := range u.ConnectionsPerHost {
+		hosts = append(hosts, h)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if u.ConnectionsPerHost[hosts[i]] != u.ConnectionsPerHost[hosts[j]] {
+			return u.ConnectionsPerHost[hosts[i]] > u.ConnectionsPerHost[hosts[j]]
+		}
+		return hosts[i] < hosts[j]
+	})
+
+	const maxHosts = 5
+	top := make([]string, 0, maxHosts+1)
+	for i, h := range hosts {
+		if i == maxHosts {
+			top = append(top, fmt.Sprintf("%d more", len(hosts)-maxHosts))
+			break
+		}
+		top = append(top, fmt.Sprintf("%s: %d", h, u.ConnectionsPerHost[h]))
+	}
+	if len(top) == 0 {
+		return fmt.Sprint(u.Connections)
+	}
+	return fmt.Sprintf("%d (%s)", u.Connections, strings.Join(top, ", "))
+}
+
+// printResources prints the peak usage of the file descriptors and the connections.
+func printResources(w io.Writer, u *ResourceUsage) {
+	switch {
+	case u.OpenFDs > 0 && u.FDLimit > 0:
+		fmt.Fprintf(w, "Peak Open FDs:\t%d of %d (%.0f%%)\n", u.OpenFDs, u.FDLimit, u.FDRatio()*100)
+		if u.NearFDLimit() {
+			fmt.Fprintln(w, yellow("  Open file descriptors approached the limit, raise it by ulimit -n."))
+		}
+	case u.OpenFDs > 0:
+		fmt.Fprintf(w, "Peak Open FDs:\t%d\n", u.OpenFDs)
+	}
+	fmt.Fprintf(w, "Peak Connections:\t%s\n", u.connections())
+	if u.ConnWaits > 0 {
+		fmt.Fprintf(w, "Connection Waits:\t%d (%.3fs waited at the max host conns)\n", u.ConnWaits, u.ConnWaitTime)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResourceUsageWarning(t *testing.T) {
+	t.Parallel()
+
+	u := ResourceUsage{
+		OpenFDs:     850,
+		FDLimit:     1000,
+		Connections: 846,
+		ConnectionsPerHost: map[string]int64{
+			"a.test:443": 1, "b.test:443": 500, "c.test:443": 300, "d.test:443": 20,
+			"e.test:443": 20, "f.test:443": 5,
+		},
+	}
+	if !u.NearFDLimit() {
+		t.Errorf("Expected the usage to be near the limit")
+	}
+
+	w := u.warning()
+	for _, expected := range []string{
+		"85% of the limit (850 of 1000)",
+		"846 (b.test:443: 500, c.test:443: 300, d.test:443: 20, e.test:443: 20, f.test:443: 5, 1 more)",
+	} {
+		if !strings.Contains(w, expected) {
+			t.Errorf("Expected %v in %v", expected, w)
+		}
+	}
+
+	if (ResourceUsage{OpenFDs: 850}).NearFDLimit() {
+		t.Errorf("Expected the usage of an unknown limit not to be near the limit")
+	}
+}
+
+func TestPrintResources(t *testing.T) {
+	t.Parallel()
+
+	b := strings.Builder{}
+	printResources(&b, &ResourceUsage{OpenFDs: 120, FDLimit: 1024, Connections: 100,
+		ConnectionsPerHost: map[string]int64{"a.test:443": 100}})
+	for _, expected := range []string{"Peak Open FDs:\t120 of 1024 (12%)", "Peak Connections:\t100 (a.test:443: 100)"} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Expected %v in %v", expected, b.String())
+		}
+	}
+
+	// descriptors can't be listed on the platform
+	b.Reset()
+	printResources(&b, &ResourceUsage{Connections: 0})
+	if strings.Contains(b.String(), "FDs") {
+		t.Errorf("Expected no open FDs, Found: %v", b.String())
+	}
+}
@@ -0,0 +1,190 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package report
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// SinkRunner writes the results to a types.ResultSink in a single separate goroutine, so a slow sink doesn't hold
+// the iterations. Results are queued up to the buffer size of the conf, then they are dropped and counted or Send
+// waits for the sink, by the policy of the conf.
+type SinkRunner struct {
+	sink    types.ResultSink
+	block   bool
+	results chan *types.ScenarioStepResult
+	dropped int64
+
+	// first error of the sink, set by the goroutine of the sink and read after done is closed
+	err error
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// NewSinkRunner starts the goroutine writing the sent results to the sink of the conf.
+func NewSinkRunner(conf types.ResultSinkConf) *SinkRunner {
+	size := conf.BufferSize
+	if size <= 0 {
+		size = types.DefaultSinkBufferSize
+	}
+	s := &SinkRunner{
+		sink:    conf.Sink,
+		block:   strings.EqualFold(conf.Policy, types.SinkPolicyBlock),
+		results: make(chan *types.ScenarioStepResult, size),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *SinkRunner) run() {
+	defer close(s.done)
+	for r := range s.results {
+		if err := s.sink.Write(r); err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+	if err := s.sink.Close(); err != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// Send queues the result for the sink. Returns false if the queue is full and the result is dropped, it waits for
+// the sink instead with the block policy. Must not be called after Close.
+func (s *SinkRunner) Send(r *types.ScenarioStepResult) bool {
+	if s.block {
+		s.results <- r
+		return true
+	}
+	select {
+	case s.results <- r:
+		return true
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+		return false
+	}
+}
+
+// Dropped returns the number of results that are not passed to the sink because the queue was full.
+func (s *SinkRunner) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Close waits for the sink to write the queued results and closes it. Returns the first error of the sink, the
+// results after a failed write are still passed to it. It is safe to call Close multiple times.
+func (s *SinkRunner) Close() error {
+	s.closeOnce.Do(func() { close(s.results) })
+	<-s.done
+	return s.err
+}
+
+// outputSink is the types.ResultSink of an OutputWriter.
+type outputSink struct {
+	w      OutputWriter
+	closer io.Closer
+}
+
+// NewOutputSink returns the types.ResultSink writing the results as the records of the OutputWriter. The writer
+// is flushed and closed if it is an io.Closer, then closer is closed if it is not nil, like the file of the writer.
+func NewOutputSink(w OutputWriter, closer io.Closer) types.ResultSink {
+	return &outputSink{w: w, closer: closer}
+}
+
+// NewFileSink returns the types.ResultSink writing the results to the file of the path in the format, one of the
+// SupportedOutputFormats. The file is created, or truncated if it exists.
+func NewFileSink(format, path string) (types.ResultSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewOutputWriter(format, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return NewOutputSink(w, f), nil
+}
+
+// streamSink is the types.ResultSink writing the results as json lines, flushed at the interval.
+type streamSink struct {
+	w    *jsonLinesWriter
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStreamSink returns the types.ResultSink streaming the results to w as the records of the json output format.
+// The records are buffered and flushed at the interval, so the consumers like jq see them during the test without
+// a write per result.
+func NewStreamSink(w io.Writer, interval time.Duration) types.ResultSink {
+	s := &streamSink{w: newJsonLinesWriter(w), stop: make(chan struct{}), done: make(chan struct{})}
+	go s.flush(interval)
+	return s
+}
+
+func (s *streamSink) flush(interval time.Duration) {
+	defer close(s.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.w.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *streamSink) Write(r *types.ScenarioStepResult) error {
+	return s.w.WriteResult(r)
+}
+
+func (s *streamSink) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.w.Flush()
+}
+
+func (s *outputSink) Write(r *types.ScenarioStepResult) error {
+	return s.w.WriteResult(r)
+}
+
+func (s *outputSink) Close() error {
+	err := s.w.Flush()
+	if c, ok := s.w.(io.Closer); ok {
+		if cErr := c.Close(); err == nil {
+			err = cErr
+		}
+	}
+	if s.closer != nil {
+		if cErr := s.closer.Close(); err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// blockingSink holds the first result until it is released, like a slow backend.
+type blockingSink struct {
+	started chan struct{}
+	release chan struct{}
+	written []uint16
+	failAt  uint16
+	closed  bool
+}
+
+func newBlockingSink() *blockingSink {
+	return &blockingSink{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *blockingSink) Write(r *types.ScenarioStepResult) error {
+	if len(s.written) == 0 {
+		close(s.started)
+		<-s.release
+	}
+	s.written = append(s.written, r.StepID)
+	if r.StepID == s.failAt {
+		return errors.New("broker unavailable")
+	}
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestSinkRunnerDropsWhenFull(t *testing.T) {
+	t.Parallel()
+
+	sink := newBlockingSink()
+	s := NewSinkRunner(types.ResultSinkConf{Sink: sink, BufferSize: 2})
+
+	s.Send(&types.ScenarioStepResult{StepID: 1})
+	<-sink.started // sink is blocked with the first result, queue is empty
+
+	for i := 2; i <= 6; i++ {
+		s.Send(&types.ScenarioStepResult{StepID: uint16(i)})
+	}
+	if s.Dropped() != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, s.Dropped())
+	}
+
+	close(sink.release)
+	if err := s.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	s.Close()
+	if len(sink.written) != 3 || sink.written[2] != 3 || !sink.closed {
+		t.Errorf("Expected %v written and closed, Found: %v %v", []uint16{1, 2, 3}, sink.written, sink.closed)
+	}
+}
+
+func TestSinkRunnerBlocksWhenFull(t *testing.T) {
+	t.Parallel()
+
+	sink := newBlockingSink()
+	sink.failAt = 2
+	s := NewSinkRunner(types.ResultSinkConf{Sink: sink, BufferSize: 1, Policy: types.SinkPolicyBlock})
+
+	s.Send(&types.ScenarioStepResult{StepID: 1})
+	<-sink.started
+
+	sent := make(chan struct{})
+	go func() {
+		for i := 2; i <= 4; i++ {
+			s.Send(&types.ScenarioStepResult{StepID: uint16(i)})
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Errorf("Expected Send to wait for the sink")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(sink.release)
+	<-sent
+	// the results after the failed write are still written
+	if err := s.Close(); err == nil || err.Error() != "broker unavailable" {
+		t.Errorf("Expected %v, Found: %v", "broker unavailable", err)
+	}
+	if len(sink.written) != 4 || s.Dropped() != 0 {
+		t.Errorf("Expected %v written, Found: %v dropped %v", 4, sink.written, s.Dropped())
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "results.csv")
+	sink, err := NewFileSink(OutputFormatCsv, path)
+	if err != nil {
+		t.Fatalf("NewFileSink: %v", err)
+	}
+	s := NewSinkRunner(types.ResultSinkConf{Sink: sink, Policy: types.SinkPolicyBlock})
+	s.Send(&types.ScenarioStepResult{StepID: 1, StatusCode: 200, Url: "https://test.com"})
+	s.Send(&types.ScenarioStepResult{StepID: 2, StatusCode: 500, Url: "https://test.com/b"})
+	if err = s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	// header and a row per result
+	if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 3 {
+		t.Errorf("Expected %v lines, Found: %v", 3, lines)
+	}
+
+	if _, err = NewFileSink("xml", filepath.Join(t.TempDir(), "results.xml")); err == nil {
+		t.Errorf("Expected an error for the unsupported format")
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes and reads.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStreamSink(t *testing.T) {
+	t.Parallel()
+
+	var buf lockedBuffer
+	sink := NewStreamSink(&buf, 10*time.Millisecond)
+	if err := sink.Write(&types.ScenarioStepResult{StepID: 1, StatusCode: 200}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// the buffered record is flushed during the test
+	deadline := time.Now().Add(time.Second)
+	for buf.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), `"step_id":1`) {
+		t.Errorf("Expected %v, Found: %v", "the streamed record", buf.String())
+	}
+
+	if err := sink.Write(&types.ScenarioStepResult{StepID: 2, StatusCode: 500}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"status_code":500`) {
+		t.Errorf("Expected %v lines, Found: %v", 2, lines)
+	}
+}
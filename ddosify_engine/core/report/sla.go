@@ -0,0 +1,95 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package report
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// SLAResult is the evaluation of a SLA check on the result of the test.
+type SLAResult struct {
+	Check  types.SLACheck
+	Actual float64
+	Passed bool
+}
+
+// EvaluateSLA evaluates the checks on the result. Percentiles are of the response times of all the steps combined,
+// avg is the average iteration duration like in the summary.
+func (r *Result) EvaluateSLA(checks []types.SLACheck) []SLAResult {
+	all := newLatencyHistogram()
+	for _, sr := range r.StepResults {
+		if sr.latencies != nil {
+			all.merge(sr.latencies.snapshot())
+		}
+	}
+	b := r.Baseline()
+
+	results := make([]SLAResult, 0, len(checks))
+	for _, c := range checks {
+		var v float64
+		switch c.Metric {
+		case types.StopMetricErrorRate:
+			v = float64(b.ErrorRate)
+		case types.StopMetricAvg:
+			v = float64(r.AvgDuration)
+		case types.StopMetricP50:
+			v = all.quantile(0.50).Seconds()
+		case types.StopMetricP90:
+			v = all.quantile(0.90).Seconds()
+		case types.StopMetricP95:
+			v = all.quantile(0.95).Seconds()
+		case types.StopMetricP99:
+			v = all.quantile(0.99).Seconds()
+		case types.SLAMetricThroughput:
+			v = float64(b.Throughput)
+		}
+		results = append(results, SLAResult{Check: c, Actual: v, Passed: c.Passed(v)})
+	}
+	return results
+}
+
+// SLAFailed reports whether any of the checks is not met.
+func SLAFailed(results []SLAResult) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintSLA writes the checks with their actual values as a table.
+func PrintSLA(out io.Writer, results []SLAResult) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Check\tActual\tThreshold\tStatus")
+	for _, r := range results {
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s %s\t%s\n", r.Check.Expr, r.Check.Format(r.Actual), r.Check.Op,
+			r.Check.Format(r.Check.Threshold), status)
+	}
+	w.Flush()
+}
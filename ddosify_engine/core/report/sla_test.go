@@ -0,0 +1,76 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestEvaluateSLA(t *testing.T) {
+	t.Parallel()
+
+	result := &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+	samplingCount := make(map[uint16]map[string]int)
+	start := time.Now()
+	// 4 iterations of a request completed in 1 second, the last one fails
+	for i := 0; i < 4; i++ {
+		st := start.Add(time.Duration(i) * 300 * time.Millisecond)
+		sr := &types.ScenarioStepResult{StepID: 1, StatusCode: 200, RequestTime: st, Duration: 100 * time.Millisecond}
+		if i == 3 {
+			sr.Err = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnRefused}
+		}
+		aggregate(result, &types.ScenarioResult{StartTime: st, StepResults: []*types.ScenarioStepResult{sr}},
+			samplingCount, 0)
+	}
+
+	checks, err := types.ParseSLAChecks([]string{"p99 < 800ms", "error_rate <= 1%", "throughput >= 1000rps"})
+	if err != nil {
+		t.Fatalf("TestEvaluateSLA error occurred %v", err)
+	}
+	results := result.EvaluateSLA(checks)
+
+	expectedPassed := []bool{true, false, false}
+	expectedActual := []float64{result.StepResults[1].latencies.quantile(0.99).Seconds(), 0.25, 4}
+	for i, r := range results {
+		if r.Passed != expectedPassed[i] {
+			t.Errorf("%s Expected %v, Found: %v", r.Check.Expr, expectedPassed[i], r.Passed)
+		}
+		if r.Actual != expectedActual[i] {
+			t.Errorf("%s Expected %v, Found: %v", r.Check.Expr, expectedActual[i], r.Actual)
+		}
+	}
+	if !SLAFailed(results) {
+		t.Errorf("Expected %v, Found: %v", true, false)
+	}
+
+	buf := &bytes.Buffer{}
+	PrintSLA(buf, results)
+	out := buf.String()
+	for _, expected := range []string{"p99 < 800ms", "PASS", "25.00%", "<= 1.00%", "4.00 rps", ">= 1000.00 rps", "FAIL"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %v, Found: %v", expected, out)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"time"
+)
+
+// Snapshot is the mergeable state of a test result. Workers of a distributed test send their snapshots to the
+// coordinator, which merges them into one result. Latencies are kept as histograms, so the percentiles of the merged
+// result are calculated over all the recorded values, not averaged.
+type Snapshot struct {
+	Result    *Result                    `json:"result"`
+	Latencies map[uint16]LatencySnapshot `json:"latencies"`
+
+	// Latencies of the weighted targets of the steps by their names
+	TargetLatencies map[uint16]map[string]LatencySnapshot `json:"target_latencies,omitempty"`
+
+	// Latencies of the steps by the status codes of the responses
+	StatusLatencies map[uint16]map[int]LatencySnapshot `json:"status_latencies,omitempty"`
+}
+
+// LatencySnapshot is the transferable form of a latency histogram, only the non-empty buckets are kept.
+type LatencySnapshot struct {
+	Counts map[int]uint64 `json:"counts"`
+	Total  uint64         `json:"total"`
+	Max    time.Duration  `json:"max"`
+
+	// All the recorded values of an exact histogram, nil otherwise
+	Samples []time.Duration `json:"samples,omitempty"`
+}
+
+func (h *latencyHistogram) snapshot() LatencySnapshot {
+	s := LatencySnapshot{Counts: make(map[int]uint64), Total: h.total, Max: h.max}
+	for i, c := range h.counts {
+		if c > 0 {
+			s.Counts[i] = c
+		}
+	}
+	if h.exact {
+		s.Samples = append([]time.Duration(nil), h.samples...)
+	}
+	return s
+}
+
+// merge adds the recorded values of the snapshot to the histogram.
+func (h *latencyHistogram) merge(s LatencySnapshot) {
+	for i, c := range s.Counts {
+		if i < 0 || i >= histBucketCount {
+			continue
+		}
+		h.counts[i] += c
+	}
+	h.total += s.Total
+	if s.Max > h.max {
+		h.max = s.Max
+	}
+	if h.exact {
+		// an exact histogram merged with an approximate snapshot falls back to the buckets, see quantile
+		h.samples = append(h.samples, s.Samples...)
+		h.sorted = false
+	}
+}
+
+// NewResult returns an empty result, snapshots are merged into it.
+func NewResult() *Result {
+	return &Result{StepResults: make(map[uint16]*ScenarioStepResultSummary)}
+}
+
+// Snapshot returns the mergeable state of the result.
+func (r *Result) Snapshot() Snapshot {
+	s := Snapshot{Result: r, Latencies: make(map[uint16]LatencySnapshot, len(r.StepResults))}
+	for id, sr := range r.StepResults {
+		if sr.latencies != nil {
+			s.Latencies[id] = sr.latencies.snapshot()
+		}
+		for name, ts := range sr.Targets {
+			if ts.latencies == nil {
+				continue
+			}
+			if s.TargetLatencies == nil {
+				s.TargetLatencies = make(map[uint16]map[string]LatencySnapshot)
+			}
+			if s.TargetLatencies[id] == nil {
+				s.TargetLatencies[id] = make(map[string]LatencySnapshot, len(sr.Targets))
+			}
+			s.TargetLatencies[id][name] = ts.latencies.snapshot()
+		}
+		for code, h := range sr.statusLatencies {
+			if s.StatusLatencies == nil {
+				s.StatusLatencies = make(map[uint16]map[int]LatencySnapshot)
+			}
+			if s.StatusLatencies[id] == nil {
+				s.StatusLatencies[id] = make(map[int]LatencySnapshot, len(sr.statusLatencies))
+			}
+			s.StatusLatencies[id][code] = h.snapshot()
+		}
+	}
+	return s
+}
+
+// Merge adds the results of the snapshot to r. Counts are summed, average durations are weighted by the counts
+// they are calculated over, and the latency histograms are merged. Test fails if the test of the snapshot failed.
+func (r *Result) Merge(s Snapshot) {
+	o := s.Result
+	if o == nil {
+		return
+	}
+
+	if r.SuccessCount+o.SuccessCount > 0 {
+		r.AvgDuration = (float32(r.SuccessCount)*r.AvgDuration + float32(o.SuccessCount)*o.AvgDuration) /
+			float32(r.SuccessCount+o.SuccessCount)
+	}
+	r.SuccessCount += o.SuccessCount
+	r.ServerFailedCount += o.ServerFailedCount
+	r.AssertionFailCount += o.AssertionFailCount
+	r.WarmupCount += o.WarmupCount
+	r.RampDownCount += o.RampDownCount
+	r.LocalErrorCount += o.LocalErrorCount
+	r.RequestedRPS += o.RequestedRPS
+	if o.SampleRate > 0 {
+		r.SampleRate = o.SampleRate
+	}
+	r.AchievedRPS += o.AchievedRPS
+
+	if r.TestStatus == "" || o.TestStatus == "failed" {
+		r.TestStatus = o.TestStatus
+	}
+	r.TestFailedAssertions = append(r.TestFailedAssertions, o.TestFailedAssertions...)
+
+	for id, osr := range o.StepResults {
+		sr, ok := r.StepResults[id]
+		if !ok {
+			sr = &ScenarioStepResultSummary{
+				Name:           osr.Name,
+				Tags:           osr.Tags,
+				StatusCodeDist: make(map[int]int),
+				Fail: FailVerbose{
+					AssertionErrorDist: AssertionErrVerbose{Conditions: make(map[string]*AssertInfo)},
+					ServerErrorDist:    ServerErrVerbose{Reasons: make(map[string]int)},
+				},
+				Durations: make(map[string]float32),
+				latencies: r.newLatencies(),
+			}
+			r.StepResults[id] = sr
+		}
+		sr.merge(osr)
+		if ls, ok := s.Latencies[id]; ok {
+			sr.latencies.merge(ls)
+		}
+		sr.mergeTargets(osr.Targets, s.TargetLatencies[id])
+		sr.mergeStatusLatencies(s.StatusLatencies[id])
+	}
+}
+
+func (s *ScenarioStepResultSummary) merge(o *ScenarioStepResultSummary) {
+	// durations are averaged over the success and fail counts, see aggregate
+	n, on := float32(s.SuccessCount+s.Fail.Count), float32(o.SuccessCount+o.Fail.Count)
+	if n+on > 0 {
+		for k, v := range o.Durations {
+			s.Durations[k] = (n*s.Durations[k] + on*v) / (n + on)
+		}
+	}
+
+	s.SuccessCount += o.SuccessCount
+	s.RetryCount += o.RetryCount
+	s.LocalErrorCount += o.LocalErrorCount
+	s.TruncatedCount += o.TruncatedCount
+	s.NewConnCount += o.NewConnCount
+	s.ReusedConnCount += o.ReusedConnCount
+	if s.EventCount+o.EventCount > 0 {
+		s.AvgEventInterval = (s.AvgEventInterval*float32(s.EventCount) + o.AvgEventInterval*float32(o.EventCount)) /
+			float32(s.EventCount+o.EventCount)
+	}
+	s.EventCount += o.EventCount
+	s.SentMessageCount += o.SentMessageCount
+	s.CompressedCount += o.CompressedCount
+	s.CompressedBytes += o.CompressedBytes
+	s.DecompressedBytes += o.DecompressedBytes
+	s.CompressedReqCount += o.CompressedReqCount
+	s.ReqBodyBytes += o.ReqBodyBytes
+	s.CompressedReqBodyBytes += o.CompressedReqBodyBytes
+	s.CompressionFallbackCount += o.CompressionFallbackCount
+	s.ThrottledCount += o.ThrottledCount
+	s.UploadCount += o.UploadCount
+	s.UploadedBytes += o.UploadedBytes
+	s.UploadTime += o.UploadTime
+	s.SkippedCount += o.SkippedCount
+	s.RevalidatedCount += o.RevalidatedCount
+	s.NotModifiedCount += o.NotModifiedCount
+	for code, c := range o.StatusCodeDist {
+		s.StatusCodeDist[code] += c
+	}
+
+	s.Fail.Count += o.Fail.Count
+	for c, count := range o.Fail.Categories {
+		s.Fail.addCategoryCount(c, count)
+	}
+	s.Fail.ServerErrorDist.Count += o.Fail.ServerErrorDist.Count
+	for reason, c := range o.Fail.ServerErrorDist.Reasons {
+		s.Fail.ServerErrorDist.Reasons[reason] += c
+	}
+	if o.Fail.SchemaErrorDist != nil {
+		if s.Fail.SchemaErrorDist == nil {
+			s.Fail.SchemaErrorDist = &SchemaErrVerbose{Messages: make(map[string]int)}
+		}
+		s.Fail.SchemaErrorDist.Count += o.Fail.SchemaErrorDist.Count
+		for m, c := range o.Fail.SchemaErrorDist.Messages {
+			s.Fail.SchemaErrorDist.addMessages([]string{m}, c)
+		}
+	}
+	s.Fail.AssertionErrorDist.Count += o.Fail.AssertionErrorDist.Count
+	for rule, oai := range o.Fail.AssertionErrorDist.Conditions {
+		ai, ok := s.Fail.AssertionErrorDist.Conditions[rule]
+		if !ok {
+			ai = &AssertInfo{Received: make(map[string][]interface{}), Reason: oai.Reason}
+			s.Fail.AssertionErrorDist.Conditions[rule] = ai
+		}
+		ai.Count += oai.Count
+		for ident, values := range oai.Received {
+			ai.Received[ident] = append(ai.Received[ident], values...)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestResultMerge(t *testing.T) {
+	t.Parallel()
+
+	var stepResults []*types.ScenarioStepResult
+	for i := 1; i <= 100; i++ {
+		sr := &types.ScenarioStepResult{StepID: 1, StepName: "login", StatusCode: 200, Duration: time.Duration(i) * time.Millisecond}
+		if i%10 == 0 {
+			sr.Err = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}
+		}
+		stepResults = append(stepResults, sr)
+	}
+
+	// all the results in one report vs. half of them in each of the two workers
+	single := NewResult()
+	workers := []*Result{NewResult(), NewResult()}
+	samplingCount := make(map[uint16]map[string]int)
+	for i, sr := range stepResults {
+		scr := &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}
+		aggregate(single, scr, samplingCount, 0)
+		aggregate(workers[i%2], scr, samplingCount, 0)
+	}
+
+	merged := NewResult()
+	for _, w := range workers {
+		w.TestStatus = "success"
+		// snapshots are sent over the wire as json
+		b, _ := json.Marshal(w.Snapshot())
+		var s Snapshot
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatalf("TestResultMerge unmarshal error: %v", err)
+		}
+		merged.Merge(s)
+	}
+
+	single.calculatePercentiles()
+	merged.calculatePercentiles()
+
+	if merged.SuccessCount != single.SuccessCount || merged.ServerFailedCount != single.ServerFailedCount {
+		t.Errorf("Expected %v, %v, Found: %v, %v", single.SuccessCount, single.ServerFailedCount,
+			merged.SuccessCount, merged.ServerFailedCount)
+	}
+	if d := merged.AvgDuration - single.AvgDuration; d > 1e-6 || d < -1e-6 {
+		t.Errorf("Expected %v, Found: %v", single.AvgDuration, merged.AvgDuration)
+	}
+
+	s, m := single.StepResults[1], merged.StepResults[1]
+	if m.Name != "login" || m.Fail.ServerErrorDist.Reasons[types.ReasonConnTimeout] != 10 ||
+		m.StatusCodeDist[200] != s.StatusCodeDist[200] {
+		t.Errorf("Expected %v, Found: %v", s, m)
+	}
+	if !reflect.DeepEqual(m.Percentiles, s.Percentiles) {
+		t.Errorf("Expected %v, Found: %v", *s.Percentiles, *m.Percentiles)
+	}
+	if merged.TestStatus != "success" {
+		t.Errorf("Expected %v, Found: %v", "success", merged.TestStatus)
+	}
+}
+
+func TestResultMergeFailedStatus(t *testing.T) {
+	t.Parallel()
+
+	merged := NewResult()
+	for _, status := range []string{"success", "failed", "success"} {
+		merged.Merge(Snapshot{Result: &Result{TestStatus: status, StepResults: map[uint16]*ScenarioStepResultSummary{}}})
+	}
+	if merged.TestStatus != "failed" {
+		t.Errorf("Expected %v, Found: %v", "failed", merged.TestStatus)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// recordStatusLatency records the response time into the histogram of the status code of the response.
+func (s *ScenarioStepResultSummary) recordStatusLatency(code int, d time.Duration) {
+	if s.statusLatencies == nil {
+		s.statusLatencies = make(map[int]*latencyHistogram)
+	}
+	h, ok := s.statusLatencies[code]
+	if !ok {
+		h = s.latencies.empty()
+		s.statusLatencies[code] = h
+	}
+	h.record(d)
+}
+
+// calculateStatusPercentiles fills the percentiles of the status codes, only if the step received more than one
+// status code. Otherwise they are the same as the percentiles of the step.
+func (s *ScenarioStepResultSummary) calculateStatusPercentiles(custom []float64) {
+	if len(s.statusLatencies) < 2 {
+		s.StatusPercentiles = nil
+		return
+	}
+	s.StatusPercentiles = make(map[int]*LatencyPercentiles, len(s.statusLatencies))
+	for code, h := range s.statusLatencies {
+		if h.total > 0 {
+			s.StatusPercentiles[code] = h.percentiles(custom)
+		}
+	}
+}
+
+// mergeStatusLatencies merges the latency snapshots of the status codes of a snapshot.
+func (s *ScenarioStepResultSummary) mergeStatusLatencies(latencies map[int]LatencySnapshot) {
+	for code, ls := range latencies {
+		if s.statusLatencies == nil {
+			s.statusLatencies = make(map[int]*latencyHistogram)
+		}
+		h, ok := s.statusLatencies[code]
+		if !ok {
+			h = s.latencies.empty()
+			s.statusLatencies[code] = h
+		}
+		h.merge(ls)
+	}
+}
+
+// printStatusPercentiles prints the percentiles of the status codes of a step, in the order of the status codes.
+func printStatusPercentiles(w io.Writer, percentiles map[int]*LatencyPercentiles) {
+	codes := make([]int, 0, len(percentiles))
+	for code := range percentiles {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	fmt.Fprintln(w, "\nDurations by Status Code (p50, p95, p99, Max):")
+	for _, code := range codes {
+		p := percentiles[code]
+		desc := fmt.Sprintf("%3d (%s)", code, http.StatusText(code))
+		fmt.Fprintf(w, "  %s\t:%.4fs, %.4fs, %.4fs, %.4fs\n", desc, p.P50, p.P95, p.P99, p.Max)
+	}
+}
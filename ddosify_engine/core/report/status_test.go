@@ -0,0 +1,86 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestAggregateStatusPercentiles(t *testing.T) {
+	t.Parallel()
+
+	results := []*types.ScenarioStepResult{
+		{StepID: 1, StatusCode: 200, Duration: 800 * time.Millisecond},
+		{StepID: 1, StatusCode: 200, Duration: 900 * time.Millisecond},
+		{StepID: 1, StatusCode: 429, Duration: 5 * time.Millisecond,
+			FailedAssertions: []types.FailedAssertion{{Rule: "status_code == 200"}}},
+		{StepID: 1, StatusCode: 429, Duration: 10 * time.Millisecond,
+			FailedAssertions: []types.FailedAssertion{{Rule: "status_code == 200"}}},
+		{StepID: 2, StatusCode: 200, Duration: 100 * time.Millisecond},
+	}
+
+	single := NewResult()
+	workers := []*Result{NewResult(), NewResult()}
+	samplingCount := make(map[uint16]map[string]int)
+	for i, sr := range results {
+		scr := &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}
+		aggregate(single, scr, samplingCount, 0)
+		aggregate(workers[i%2], scr, samplingCount, 0)
+	}
+	merged := NewResult()
+	for _, w := range workers {
+		b, _ := json.Marshal(w.Snapshot())
+		var s Snapshot
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatalf("TestAggregateStatusPercentiles unmarshal error: %v", err)
+		}
+		merged.Merge(s)
+	}
+
+	for _, r := range []*Result{single, merged} {
+		r.calculatePercentiles()
+		sp := r.StepResults[1].StatusPercentiles
+		if sp[200] == nil || sp[200].Max != 0.9 {
+			t.Errorf("Expected %v, Found: %v", 0.9, sp[200])
+		}
+		if sp[429] == nil || sp[429].Max != 0.01 {
+			t.Errorf("Expected %v, Found: %v", 0.01, sp[429])
+		}
+		// a single status code has the same percentiles as the step
+		if sp := r.StepResults[2].StatusPercentiles; sp != nil {
+			t.Errorf("Expected %v, Found: %v", nil, sp)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	printStatusPercentiles(buf, single.StepResults[1].StatusPercentiles)
+	out := buf.String()
+	if !strings.Contains(out, "200 (OK)\t:") || !strings.Contains(out, "429 (Too Many Requests)\t:") ||
+		strings.Index(out, "200 (OK)") > strings.Index(out, "429 (Too Many Requests)") {
+		t.Errorf("Expected %v, Found: %v", "durations of the 200 and 429 responses in order", out)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"sort"
 	"strings"
 	"sync"
@@ -34,6 +35,7 @@ import (
 	"github.com/enescakir/emoji"
 	"github.com/fatih/color"
 	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
 	"go.ddosify.com/ddosify/core/assertion"
 	"go.ddosify.com/ddosify/core/types"
 	"go.ddosify.com/ddosify/core/util"
@@ -54,6 +56,25 @@ type stdout struct {
 	mu           sync.Mutex
 	debug        bool
 	samplingRate int
+	targetRPS    int
+	startTime    time.Time
+	load         *RequestedLoad
+
+	// certificates expiring in the days are reported, nil if the cert audit is disabled
+	certExpiryDays *int
+
+	// returns the total paused duration of the test, nil if the test can't be paused
+	paused func() time.Duration
+
+	// replaces the live result lines if enabled, nil otherwise
+	dashboard *dashboard
+
+	// returns the peak resource usage of the test, nil if it is not monitored
+	resources func() ResourceUsage
+
+	// file that the report is printed to and its colorable writer, set by SetOutput, stdout if nil
+	outFile *os.File
+	out     io.Writer
 }
 
 var white = color.New(color.FgHiWhite).SprintFunc()
@@ -61,24 +82,32 @@ var blue = color.New(color.FgHiBlue).SprintFunc()
 var green = color.New(color.FgHiGreen).SprintFunc()
 var yellow = color.New(color.FgHiYellow).SprintFunc()
 var red = color.New(color.FgHiRed).SprintFunc()
+var cyan = color.New(color.FgCyan).SprintfFunc()
 var realTimePrintInterval = time.Duration(1500) * time.Millisecond
 
-func (s *stdout) Init(debug bool, samplingRate int) (err error) {
+func (s *stdout) Init(debug bool, samplingRate int, targetRPS int) (err error) {
 	s.doneChan = make(chan bool, 1)
 	s.result = &Result{
 		StepResults: make(map[uint16]*ScenarioStepResultSummary),
 	}
 	s.debug = debug
 	s.samplingRate = samplingRate
+	s.targetRPS = targetRPS
 
-	color.Cyan("%s  Initializing... \n", emoji.Gear)
+	fmt.Fprint(s.output(), cyan("%s  Initializing... \n", emoji.Gear))
 	if s.debug {
-		color.Cyan("%s Running in debug mode, 1 iteration will be played... \n", emoji.Bug)
+		fmt.Fprint(s.output(), cyan("%s Running in debug mode, 1 iteration will be played... \n", emoji.Bug))
 	}
 	return
 }
 
 func (s *stdout) Start(input chan *types.ScenarioResult, assertionResultChan <-chan assertion.TestAssertionResult) {
+	s.StartBatched(batchChan(input), assertionResultChan)
+}
+
+// StartBatched is like Start, aggregating each batch of the input under a single lock.
+func (s *stdout) StartBatched(input <-chan []*types.ScenarioResult,
+	assertionResultChan <-chan assertion.TestAssertionResult) {
 	if s.debug {
 		s.result.TestStatus = "success"
 		if assertionResultChan != nil {
@@ -96,16 +125,30 @@ func (s *stdout) Start(input chan *types.ScenarioResult, assertionResultChan <-c
 		}
 		return
 	}
-	go s.realTimePrintStart()
+	s.startTime = time.Now()
+	if s.dashboard != nil {
+		s.dashboard.start = s.startTime
+		go s.dashboard.run()
+	} else {
+		go s.realTimePrintStart()
+	}
 
 	stopSampling := make(chan struct{})
 	samplingCount := make(map[uint16]map[string]int)
 	go s.cleanSamplingCount(samplingCount, stopSampling, s.samplingRate)
 
-	for r := range input {
+	for batch := range input {
 		s.mu.Lock() // avoid race around samplingCount
-		aggregate(s.result, r, samplingCount, s.samplingRate)
+		for _, r := range batch {
+			aggregate(s.result, r, samplingCount, s.samplingRate)
+		}
 		s.mu.Unlock()
+		if s.dashboard != nil {
+			now := time.Now()
+			for _, r := range batch {
+				s.dashboard.observe(r, now)
+			}
+		}
 	}
 
 	// listen for assertion result
@@ -117,7 +160,11 @@ func (s *stdout) Start(input chan *types.ScenarioResult, assertionResultChan <-c
 			s.result.TestFailedAssertions = result.FailedRules
 		}
 	}
-	s.realTimePrintStop()
+	if s.dashboard != nil {
+		s.dashboard.stop()
+	} else {
+		s.realTimePrintStop()
+	}
 	s.report()
 	stopSampling <- struct{}{}
 
@@ -149,9 +196,103 @@ func (s *stdout) cleanSamplingCount(samplingCount map[uint16]map[string]int, sto
 }
 
 func (s *stdout) report() {
+	s.result.calculatePercentiles()
+	if s.paused != nil {
+		s.result.setPaused(s.paused())
+	}
+	s.result.calculateRPS(s.targetRPS, s.result.elapsed(s.startTime))
+	s.result.calculateLoad(s.load)
+	s.result.calculateTags()
+	s.result.calculateConnReuse()
+	if s.certExpiryDays != nil {
+		s.result.calculateCerts(*s.certExpiryDays, time.Now())
+	}
+	if s.resources != nil {
+		u := s.resources()
+		s.result.Resources = &u
+	}
 	s.printDetails()
 }
 
+// SetCertExpiryDays enables the certificate summary of the result.
+func (s *stdout) SetCertExpiryDays(days int) {
+	s.certExpiryDays = &days
+}
+
+// SetPercentiles sets the custom percentiles of the result.
+func (s *stdout) SetPercentiles(percentiles []float64, exact bool) {
+	s.result.SetPercentiles(percentiles, exact)
+}
+
+// SetSampleRate notes the sample rate of the latency histograms in the result.
+func (s *stdout) SetSampleRate(rate float64) {
+	s.result.SetSampleRate(rate)
+}
+
+// SetRequestedLoad enables the load summary of the result.
+func (s *stdout) SetRequestedLoad(load RequestedLoad) {
+	s.load = &load
+}
+
+// SetPausedDuration excludes the paused periods of the test from the achieved rates of the result.
+func (s *stdout) SetPausedDuration(paused func() time.Duration) {
+	s.paused = paused
+}
+
+// SetResourceUsage enables the resource usage summary of the result.
+func (s *stdout) SetResourceUsage(peak func() ResourceUsage) {
+	s.resources = peak
+}
+
+// WarnResourceUsage prints the warning between the live result lines, or under the dashboard.
+func (s *stdout) WarnResourceUsage(current ResourceUsage) {
+	if util.IsSystemInTestMode() {
+		return
+	}
+	if s.dashboard != nil {
+		s.dashboard.warn(current.warning())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.output(), "%s\n", yellow(fmt.Sprintf("%s  Warning: %s", emoji.Warning, current.warning())))
+}
+
+// EnableDashboard replaces the live result lines with the dashboard, updated in place if stdout is a terminal.
+func (s *stdout) EnableDashboard(activeUsers func() int64) {
+	f := s.file()
+	s.dashboard = newDashboard(s.output(), isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()), activeUsers)
+}
+
+// SetOutput prints the report to the file instead of stdout, it should be called before Init.
+func (s *stdout) SetOutput(f *os.File) {
+	s.outFile = f
+	s.out = colorable.NewColorable(f)
+}
+
+// output returns the writer of the report, the colorable stdout unless SetOutput is called.
+func (s *stdout) output() io.Writer {
+	if s.out != nil {
+		return s.out
+	}
+	return out
+}
+
+// file returns the file that the report is printed to.
+func (s *stdout) file() *os.File {
+	if s.outFile != nil {
+		return s.outFile
+	}
+	return os.Stdout
+}
+
+// Result returns the aggregated result, it should be called after the test is done.
+func (s *stdout) Result() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.result
+}
+
 func (s *stdout) DoneChan() <-chan bool {
 	return s.doneChan
 }
@@ -163,8 +304,8 @@ func (s *stdout) realTimePrintStart() {
 
 	s.printTicker = time.NewTicker(realTimePrintInterval)
 
-	color.Cyan("%s Engine fired. \n\n", emoji.Fire)
-	color.Cyan("%s CTRL+C to gracefully stop.\n", emoji.StopSign)
+	fmt.Fprint(s.output(), cyan("%s Engine fired. \n\n", emoji.Fire))
+	fmt.Fprint(s.output(), cyan("%s CTRL+C to gracefully stop.\n", emoji.StopSign))
 
 	for range s.printTicker.C {
 		go func() {
@@ -176,7 +317,7 @@ func (s *stdout) realTimePrintStart() {
 }
 
 func (s *stdout) liveResultPrint() {
-	fmt.Fprintf(out, "%s %s %s\n",
+	fmt.Fprintf(s.output(), "%s %s %s\n",
 		green(fmt.Sprintf("%s  Successful Run: %-6d %3d%% %5s",
 			emoji.CheckMark, s.result.SuccessCount, s.result.successPercentage(), "")),
 		red(fmt.Sprintf("%s Failed Run: %-6d %3d%% %5s",
@@ -193,18 +334,18 @@ func (s *stdout) realTimePrintStop() {
 	s.printTicker.Stop()
 }
 
-func (s *stdout) printInDebugMode(input chan *types.ScenarioResult) {
-	color.Cyan("%s Engine fired. \n\n", emoji.Fire)
-	color.Cyan("%s CTRL+C to gracefully stop.\n", emoji.StopSign)
+func (s *stdout) printInDebugMode(input <-chan []*types.ScenarioResult) {
+	fmt.Fprint(s.output(), cyan("%s Engine fired. \n\n", emoji.Fire))
+	fmt.Fprint(s.output(), cyan("%s CTRL+C to gracefully stop.\n", emoji.StopSign))
 
-	for r := range input { // only 1 ScenarioResult expected
+	for _, r := range collect(input) { // only 1 ScenarioResult expected
 		for _, sr := range r.StepResults {
 			verboseInfo := ScenarioStepResultToVerboseHttpRequestInfo(sr)
 
 			b := strings.Builder{}
 			w := tabwriter.NewWriter(&b, 0, 0, 4, ' ', 0)
-			color.Cyan("\n\nSTEP (%d) %-5s\n", verboseInfo.StepId, verboseInfo.StepName)
-			color.Cyan("-------------------------------------")
+			fmt.Fprint(s.output(), cyan("\n\nSTEP (%d) %-5s\n", verboseInfo.StepId, verboseInfo.StepName))
+			fmt.Fprint(s.output(), cyan("-------------------------------------\n"))
 			if len(verboseInfo.Envs) > 0 {
 				fmt.Fprintf(w, "%s\n", blue("- Environment Variables"))
 				for eKey, eVal := range verboseInfo.Envs {
@@ -254,10 +395,17 @@ func (s *stdout) printInDebugMode(input chan *types.ScenarioResult) {
 				fmt.Fprintf(w, "\n")
 			}
 
+			if verboseInfo.Skipped {
+				fmt.Fprintf(w, "%s\n", yellow("- Skipped, the step condition is not met"))
+				fmt.Fprintln(w)
+				fmt.Fprint(s.output(), b.String())
+				continue
+			}
+
 			if verboseInfo.Error != "" && isVerboseInfoRequestEmpty(verboseInfo.Request) {
 				fmt.Fprintf(w, "%s Error: \t%-5s \n", emoji.SosButton, verboseInfo.Error)
 				fmt.Fprintln(w)
-				fmt.Fprint(out, b.String())
+				fmt.Fprint(s.output(), b.String())
 				break
 			}
 			fmt.Fprintf(w, "%s\n", blue("- Request"))
@@ -276,6 +424,12 @@ func (s *stdout) printInDebugMode(input chan *types.ScenarioResult) {
 
 			if verboseInfo.Error == "" {
 				// response
+				if len(verboseInfo.Response.Redirects) > 0 {
+					fmt.Fprintf(w, "\n%s\n", blue("- Redirects"))
+					for _, hop := range verboseInfo.Response.Redirects {
+						fmt.Fprintf(w, "\t%d\t%s\t%d(ms) \n", hop.StatusCode, hop.Url, hop.ResponseTime)
+					}
+				}
 				fmt.Fprintf(w, "\n%s\n", blue("- Response"))
 				fmt.Fprintf(w, "\tStatusCode:\t%-5d \n", verboseInfo.Response.StatusCode)
 				fmt.Fprintf(w, "\tResponseTime:\t%-5d(ms) \n", verboseInfo.Response.ResponseTime)
@@ -288,6 +442,9 @@ func (s *stdout) printInDebugMode(input chan *types.ScenarioResult) {
 				fmt.Fprintf(w, "\t%s", "Body: ")
 				printBody(w, contentType, verboseInfo.Response.Body)
 				fmt.Fprintf(w, "\n")
+				if verboseInfo.Response.Truncated {
+					fmt.Fprintf(w, "\t%s\n", yellow("Body is truncated at the max response body bytes"))
+				}
 			}
 
 			if len(verboseInfo.FailedCaptures) > 0 {
@@ -307,12 +464,19 @@ func (s *stdout) printInDebugMode(input chan *types.ScenarioResult) {
 				}
 			}
 
+			if len(verboseInfo.SchemaErrors) > 0 {
+				fmt.Fprintf(w, "%s\n", yellow("- Schema Errors"))
+				for _, e := range verboseInfo.SchemaErrors {
+					fmt.Fprintf(w, "\t\t%s\n", e)
+				}
+			}
+
 			if verboseInfo.Error != "" { // server error
 				fmt.Fprintf(w, "\n%s Error: \t%-5s \n", emoji.SosButton, verboseInfo.Error)
 			}
 
 			fmt.Fprintln(w)
-			fmt.Fprint(out, b.String())
+			fmt.Fprint(s.output(), b.String())
 		}
 	}
 
@@ -333,7 +497,7 @@ func (s *stdout) printInDebugMode(input chan *types.ScenarioResult) {
 		}
 	}
 	fmt.Fprintln(w)
-	fmt.Fprint(out, b.String())
+	fmt.Fprint(s.output(), b.String())
 
 }
 
@@ -353,8 +517,6 @@ func printBody(w io.Writer, contentType string, body interface{}) {
 
 // TODO:REFACTOR use template
 func (s *stdout) printDetails() {
-	color.Set(color.FgHiCyan)
-	defer color.Unset()
 
 	b := strings.Builder{}
 	w := tabwriter.NewWriter(&b, 0, 0, 4, ' ', 0)
@@ -362,6 +524,56 @@ func (s *stdout) printDetails() {
 	fmt.Fprintln(w, "\n\nRESULT")
 	fmt.Fprintln(w, "-------------------------------------")
 
+	if s.result.WarmupCount > 0 {
+		fmt.Fprintf(w, "Warm-up Iterations:\t%d (excluded)\n", s.result.WarmupCount)
+	}
+	if s.result.RampDownCount > 0 {
+		fmt.Fprintf(w, "Ramp-down Iterations:\t%d (excluded)\n", s.result.RampDownCount)
+	}
+	if s.result.paused > 0 {
+		fmt.Fprintf(w, "Paused:\t%s (excluded)\n", s.result.paused.Round(time.Second))
+	}
+	if s.result.SampleRate > 0 {
+		fmt.Fprintf(w, "Sample Rate:\t%g%% (percentiles are estimated from the sampled iterations)\n",
+			s.result.SampleRate*100)
+	}
+	if s.result.RequestedRPS > 0 {
+		fmt.Fprintf(w, "RPS:\t%.2f (requested %d)\n", s.result.AchievedRPS, s.result.RequestedRPS)
+		if !s.result.rpsReached() {
+			fmt.Fprintln(w, yellow("  Requested rps could not be reached"))
+		}
+	}
+	if l := s.result.Load; l != nil {
+		if l.PlannedIterations != nil {
+			fmt.Fprintf(w, "Iterations:\texecuted %d of the planned %d, achieved %.2f it/s\n",
+				l.ExecutedIterations, *l.PlannedIterations, l.AchievedIterationRate)
+			if !l.reached() {
+				fmt.Fprintln(w, yellow("  Planned iterations could not be completed"))
+			}
+		} else {
+			diff := 0.0
+			if l.RequestedIterationRate > 0 {
+				diff = float64(l.Difference/l.RequestedIterationRate) * 100
+			}
+			fmt.Fprintf(w, "Load:\trequested %.2f it/s, achieved %.2f it/s (%+.2f, %+.1f%%)\n",
+				l.RequestedIterationRate, l.AchievedIterationRate, l.Difference, diff)
+			if !l.reached() {
+				fmt.Fprintln(w, yellow("  Requested load could not be reached"))
+			}
+		}
+		fmt.Fprintf(w, "Completed RPS:\t%.2f\n", l.CompletedRPS)
+		fmt.Fprintf(w, "Max In-Flight Iterations:\t%d\n", l.MaxInFlight)
+	}
+	if r := s.result.ConnReuseRatio; r != nil {
+		fmt.Fprintf(w, "Connection Reuse:\t%.1f%%\n", *r*100)
+	}
+	if s.result.LocalErrorCount > 0 {
+		printLocalErrors(w, s.result.LocalErrorCount)
+	}
+	if u := s.result.Resources; u != nil {
+		printResources(w, u)
+	}
+
 	keys := make([]int, 0)
 	for k := range s.result.StepResults {
 		keys = append(keys, int(k))
@@ -371,6 +583,11 @@ func (s *stdout) printDetails() {
 	// We should sort scenarioItemIDs to traverse itemReports
 	sort.Ints(keys)
 
+	if categories := s.result.errorCategories(); len(keys) > 1 && len(categories) > 0 {
+		fmt.Fprintln(w, "\nError Categories of All Steps (Count:Category):")
+		printErrorCategories(w, categories)
+	}
+
 	for _, k := range keys {
 		v := s.result.StepResults[uint16(k)]
 
@@ -385,6 +602,48 @@ func (s *stdout) printDetails() {
 
 		fmt.Fprintf(w, "Success Count:\t%-5d (%d%%)\n", v.SuccessCount, v.successPercentage())
 		fmt.Fprintf(w, "Failed Count:\t%-5d (%d%%)\n", v.Fail.Count, v.failedPercentage())
+		if v.RetryCount > 0 {
+			fmt.Fprintf(w, "Retry Count:\t%-5d (%d%%)\n", v.RetryCount, v.retryPercentage())
+		}
+		if v.LocalErrorCount > 0 {
+			fmt.Fprintf(w, "Local Error Count:\t%-5d (excluded)\n", v.LocalErrorCount)
+		}
+		if v.TruncatedCount > 0 {
+			fmt.Fprintf(w, "Truncated Body Count:\t%-5d\n", v.TruncatedCount)
+		}
+		if v.NewConnCount+v.ReusedConnCount > 0 && len(keys) > 1 {
+			fmt.Fprintf(w, "Reused Connections:\t%-5d (%d%%)\n", v.ReusedConnCount, v.connReusePercentage())
+		}
+		if v.CompressedCount > 0 {
+			fmt.Fprintf(w, "Compressed Responses:\t%-5d (%d bytes on the wire, %d bytes decompressed)\n",
+				v.CompressedCount, v.CompressedBytes, v.DecompressedBytes)
+		}
+		if v.CompressedReqCount > 0 {
+			fmt.Fprintf(w, "Compressed Requests:\t%-5d (%d bytes uncompressed, %d bytes on the wire)\n",
+				v.CompressedReqCount, v.ReqBodyBytes, v.CompressedReqBodyBytes)
+		}
+		if v.CompressionFallbackCount > 0 {
+			fmt.Fprintf(w, "Compression Fallbacks:\t%-5d (sent uncompressed after 415)\n", v.CompressionFallbackCount)
+		}
+		if v.ThrottledCount > 0 {
+			fmt.Fprintf(w, "Throttled:\t%-5d (429 or 503 with Retry-After)\n", v.ThrottledCount)
+		}
+		if v.UploadCount > 0 {
+			fmt.Fprintf(w, "Upload Throughput:\t%.2f KB/s (%d uploads, %d bytes)\n",
+				v.uploadThroughput()/1024, v.UploadCount, v.UploadedBytes)
+		}
+		if v.SkippedCount > 0 {
+			fmt.Fprintf(w, "Skipped Count:\t%-5d\n", v.SkippedCount)
+		}
+		if v.RevalidatedCount > 0 {
+			fmt.Fprintf(w, "Revalidations:\t%-5d (%d not modified)\n", v.RevalidatedCount, v.NotModifiedCount)
+		}
+		if v.EventCount > 0 {
+			fmt.Fprintf(w, "Events Received:\t%-5d (avg interval %.4fs)\n", v.EventCount, v.AvgEventInterval)
+		}
+		if v.SentMessageCount > 0 {
+			fmt.Fprintf(w, "Messages Sent:\t%-5d\n", v.SentMessageCount)
+		}
 
 		fmt.Fprintln(w, "\nDurations (Avg):")
 		var durationList = make([]duration, 0)
@@ -400,6 +659,29 @@ func (s *stdout) printDetails() {
 			fmt.Fprintf(w, "  %s\t:%.4fs\n", v.name, v.duration)
 		}
 
+		if p := v.Percentiles; p != nil && len(s.result.percentiles) > 0 {
+			// custom percentiles replace the default ones, in their given order
+			fmt.Fprintln(w, "\nDurations (Percentiles):")
+			for _, c := range s.result.percentiles {
+				label := percentileLabel(c)
+				fmt.Fprintf(w, "  %s\t:%.4fs\n", label, p.Custom[label])
+			}
+			fmt.Fprintf(w, "  %s\t:%.4fs\n", "Max", p.Max)
+		} else if p != nil {
+			fmt.Fprintln(w, "\nDurations (Percentiles):")
+			fmt.Fprintf(w, "  %s\t:%.4fs\n", "p50", p.P50)
+			fmt.Fprintf(w, "  %s\t:%.4fs\n", "p90", p.P90)
+			fmt.Fprintf(w, "  %s\t:%.4fs\n", "p95", p.P95)
+			fmt.Fprintf(w, "  %s\t:%.4fs\n", "p99", p.P99)
+			fmt.Fprintf(w, "  %s\t:%.4fs\n", "Max", p.Max)
+		}
+		if len(v.StatusPercentiles) > 0 {
+			printStatusPercentiles(w, v.StatusPercentiles)
+		}
+		if len(v.Targets) > 0 {
+			printTargets(w, v.Targets)
+		}
+
 		if len(v.StatusCodeDist) > 0 {
 			fmt.Fprintln(w, "\nStatus Code (Message) :Count")
 			for s, c := range v.StatusCodeDist {
@@ -425,15 +707,35 @@ func (s *stdout) printDetails() {
 			}
 		}
 
+		if v.Fail.SchemaErrorDist != nil {
+			fmt.Fprintf(w, "\nSchema Error Distribution (%d responses, Count:Message):\n", v.Fail.SchemaErrorDist.Count)
+			for m, c := range v.Fail.SchemaErrorDist.Messages {
+				fmt.Fprintf(w, "  %d\t :%s\n", c, m)
+			}
+		}
+
 		if v.Fail.ServerErrorDist.Count > 0 {
 			fmt.Fprintln(w, "\nServer Error Distribution (Count:Reason):")
 			for e, c := range v.Fail.ServerErrorDist.Reasons {
 				fmt.Fprintf(w, "  %d\t :%s\n", c, e)
 			}
 		}
+
+		if len(v.Fail.Categories) > 0 {
+			fmt.Fprintln(w, "\nError Categories (Count:Category):")
+			printErrorCategories(w, v.Fail.Categories)
+		}
 		fmt.Fprintln(w)
 	}
 
+	if len(s.result.Tags) > 0 {
+		printTags(w, s.result.Tags)
+	}
+
+	if s.certExpiryDays != nil {
+		printCerts(w, s.result.Certs, *s.certExpiryDays)
+	}
+
 	if s.result.TestStatus == "success" {
 		fmt.Fprintf(w, "%s", green("Test Status : Success\n"))
 
@@ -450,7 +752,62 @@ func (s *stdout) printDetails() {
 	}
 
 	w.Flush()
-	fmt.Fprint(out, b.String())
+	color.New(color.FgHiCyan).Fprint(s.output(), b.String())
+}
+
+// printTags prints the combined results of the steps by their tags, in the order of the tag names.
+func printTags(w io.Writer, tags map[string]*TagSummary) {
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Results by Tag:")
+	for _, tag := range names {
+		ts := tags[tag]
+		steps := make([]string, 0, len(ts.Steps))
+		for _, id := range ts.Steps {
+			steps = append(steps, fmt.Sprint(id))
+		}
+		fmt.Fprintf(w, "  %s (steps %s)\n", tag, strings.Join(steps, ", "))
+		fmt.Fprintf(w, "    Success Count:\t%-5d (%d%%)\n", ts.SuccessCount, ts.successPercentage())
+		fmt.Fprintf(w, "    Failed Count:\t%-5d\n", ts.FailCount)
+		fmt.Fprintf(w, "    Avg Duration:\t%.4fs\n", ts.AvgDuration)
+		if p := ts.Percentiles; p != nil {
+			fmt.Fprintf(w, "    p95 / p99:\t%.4fs / %.4fs\n", p.P95, p.P99)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// printLocalErrors warns about the requests failed by the exhausted local ports, with the ways to mitigate it.
+// They look like the connection errors of the target, but the target never receives them.
+func printLocalErrors(w io.Writer, count int64) {
+	fmt.Fprintf(w, "Local Errors:\t%d (excluded)\n", count)
+	fmt.Fprintln(w, yellow("  Local ports of this machine are exhausted, these requests are not failures of the target."))
+	fmt.Fprintln(w, yellow("  Keep the connections alive, spread them over more local IPs by source_addrs or"))
+	fmt.Fprintln(w, yellow("  widen the ephemeral port range, like the net.ipv4.ip_local_port_range on Linux."))
+}
+
+// printErrorCategories prints the categories by their counts in descending order, with their percentages in
+// all the failed requests.
+func printErrorCategories(w io.Writer, categories map[types.ErrorCategory]int64) {
+	var total int64
+	sorted := make([]types.ErrorCategory, 0, len(categories))
+	for c, count := range categories {
+		sorted = append(sorted, c)
+		total += count
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if categories[sorted[i]] != categories[sorted[j]] {
+			return categories[sorted[i]] > categories[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	for _, c := range sorted {
+		fmt.Fprintf(w, "  %d\t :%s (%d%%)\n", categories[c], c, categories[c]*100/total)
+	}
 }
 
 func deduplicate(values []interface{}) []interface{} {
@@ -480,5 +837,6 @@ var keyToStr = map[string]duration{
 	"reqDuration":           {name: "Request Write", order: 4},
 	"serverProcessDuration": {name: "Server Processing", order: 5},
 	"resDuration":           {name: "Response Read", order: 6},
-	"duration":              {name: "Total", order: 7},
+	"decompressDuration":    {name: "Decompression", order: 7},
+	"duration":              {name: "Total", order: 8},
 }
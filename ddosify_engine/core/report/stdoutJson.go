@@ -25,11 +25,14 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/mattn/go-colorable"
 	"go.ddosify.com/ddosify/core/assertion"
 	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
 )
 
 const OutputTypeStdoutJson = "stdout-json"
@@ -43,20 +46,45 @@ type stdoutJson struct {
 	result       *Result
 	debug        bool
 	samplingRate int
-	mu           sync.Mutex
+	targetRPS    int
+	startTime    time.Time
+	load         *RequestedLoad
+
+	// certificates expiring in the days are reported, nil if the cert audit is disabled
+	certExpiryDays *int
+
+	// returns the total paused duration of the test, nil if the test can't be paused
+	paused func() time.Duration
+	mu     sync.Mutex
+
+	// aggregates without printing the result, set by OutputTypeNone
+	quiet bool
+
+	// returns the peak resource usage of the test, nil if it is not monitored
+	resources func() ResourceUsage
+
+	// file that the result is printed to, set by SetOutput, stdout if nil
+	outFile *os.File
 }
 
-func (s *stdoutJson) Init(debug bool, samplingRate int) (err error) {
+func (s *stdoutJson) Init(debug bool, samplingRate int, targetRPS int) (err error) {
 	s.doneChan = make(chan bool)
 	s.result = &Result{
 		StepResults: make(map[uint16]*ScenarioStepResultSummary),
 	}
 	s.debug = debug
 	s.samplingRate = samplingRate
+	s.targetRPS = targetRPS
 	return
 }
 
 func (s *stdoutJson) Start(input chan *types.ScenarioResult, assertionResultChan <-chan assertion.TestAssertionResult) {
+	s.StartBatched(batchChan(input), assertionResultChan)
+}
+
+// StartBatched is like Start, aggregating each batch of the input under a single lock.
+func (s *stdoutJson) StartBatched(input <-chan []*types.ScenarioResult,
+	assertionResultChan <-chan assertion.TestAssertionResult) {
 	if s.debug {
 		s.result.TestStatus = "success"
 		if assertionResultChan != nil {
@@ -74,6 +102,7 @@ func (s *stdoutJson) Start(input chan *types.ScenarioResult, assertionResultChan
 		}
 		return
 	}
+	s.startTime = time.Now()
 	s.listenAndAggregate(input, assertionResultChan)
 	s.report()
 
@@ -87,7 +116,43 @@ func (s *stdoutJson) Start(input chan *types.ScenarioResult, assertionResultChan
 func (s *stdoutJson) report() {
 	p := 1e3
 
+	s.result.calculatePercentiles()
+	if s.paused != nil {
+		s.result.setPaused(s.paused())
+	}
+	s.result.calculateRPS(s.targetRPS, s.result.elapsed(s.startTime))
+	s.result.calculateLoad(s.load)
+	s.result.calculateTags()
+	s.result.calculateConnReuse()
+	if s.certExpiryDays != nil {
+		s.result.calculateCerts(*s.certExpiryDays, time.Now())
+	}
+	if s.resources != nil {
+		u := s.resources()
+		s.result.Resources = &u
+	}
+
 	s.result.AvgDuration = float32(math.Round(float64(s.result.AvgDuration)*p) / p)
+	if l := s.result.Load; l != nil {
+		round := func(f float32) float32 { return float32(math.Round(float64(f)*p) / p) }
+		l.RequestedIterationRate = round(l.RequestedIterationRate)
+		l.AchievedIterationRate = round(l.AchievedIterationRate)
+		l.Difference = round(l.Difference)
+		l.CompletedRPS = round(l.CompletedRPS)
+	}
+
+	if r := s.result.ConnReuseRatio; r != nil {
+		ratio := float32(math.Round(float64(*r)*p) / p)
+		s.result.ConnReuseRatio = &ratio
+	}
+
+	for _, ts := range s.result.Tags {
+		round := func(f float32) float32 { return float32(math.Round(float64(f)*p) / p) }
+		ts.AvgDuration = round(ts.AvgDuration)
+		if pc := ts.Percentiles; pc != nil {
+			ts.Percentiles = pc.rounded(round)
+		}
+	}
 
 	for _, itemReport := range s.result.StepResults {
 		durations := make(map[string]float32)
@@ -97,23 +162,102 @@ func (s *stdoutJson) report() {
 			durations[strKeyToJsonKey[d]] = float32(t)
 		}
 		itemReport.Durations = durations
+		itemReport.AvgEventInterval = float32(math.Round(float64(itemReport.AvgEventInterval)*p) / p)
+
+		if pc := itemReport.Percentiles; pc != nil {
+			round := func(f float32) float32 { return float32(math.Round(float64(f)*p) / p) }
+			itemReport.Percentiles = pc.rounded(round)
+		}
+		for code, pc := range itemReport.StatusPercentiles {
+			round := func(f float32) float32 { return float32(math.Round(float64(f)*p) / p) }
+			itemReport.StatusPercentiles[code] = pc.rounded(round)
+		}
 	}
 
+	if s.quiet {
+		return
+	}
 	j, _ := json.Marshal(s.result)
-	printJson(j)
+	printJson(s.file(), j)
+}
+
+// SetOutput prints the result to the file instead of stdout, it should be called before Init.
+func (s *stdoutJson) SetOutput(f *os.File) {
+	s.outFile = f
+}
+
+// file returns the file that the result is printed to.
+func (s *stdoutJson) file() *os.File {
+	if s.outFile != nil {
+		return s.outFile
+	}
+	return os.Stdout
+}
+
+// SetResourceUsage enables the resource usage summary of the result.
+func (s *stdoutJson) SetResourceUsage(peak func() ResourceUsage) {
+	s.resources = peak
+}
+
+// WarnResourceUsage prints the warning to stderr, so the JSON result on stdout stays valid.
+func (s *stdoutJson) WarnResourceUsage(current ResourceUsage) {
+	if s.quiet || util.IsSystemInTestMode() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", current.warning())
+}
+
+// SetRequestedLoad enables the load summary of the result.
+func (s *stdoutJson) SetRequestedLoad(load RequestedLoad) {
+	s.load = &load
+}
+
+// SetPercentiles sets the custom percentiles of the result.
+func (s *stdoutJson) SetPercentiles(percentiles []float64, exact bool) {
+	s.result.SetPercentiles(percentiles, exact)
+}
+
+// SetSampleRate notes the sample rate of the latency histograms in the result.
+func (s *stdoutJson) SetSampleRate(rate float64) {
+	s.result.SetSampleRate(rate)
+}
+
+// SetLatencyBuckets enables the latency histograms of the steps in the result.
+func (s *stdoutJson) SetLatencyBuckets(bounds []time.Duration) {
+	s.result.SetLatencyBuckets(bounds)
+}
+
+// SetCertExpiryDays enables the certificate summary of the result.
+func (s *stdoutJson) SetCertExpiryDays(days int) {
+	s.certExpiryDays = &days
+}
+
+// SetPausedDuration excludes the paused periods of the test from the achieved rates of the result.
+func (s *stdoutJson) SetPausedDuration(paused func() time.Duration) {
+	s.paused = paused
+}
+
+// Result returns the aggregated result, it should be called after the test is done.
+func (s *stdoutJson) Result() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.result
 }
 
 func (s *stdoutJson) DoneChan() <-chan bool {
 	return s.doneChan
 }
 
-func (s *stdoutJson) listenAndAggregate(input chan *types.ScenarioResult, assertionResultChan <-chan assertion.TestAssertionResult) {
+func (s *stdoutJson) listenAndAggregate(input <-chan []*types.ScenarioResult,
+	assertionResultChan <-chan assertion.TestAssertionResult) {
 	stopSampling := make(chan struct{})
 	samplingCount := make(map[uint16]map[string]int)
 	go s.cleanSamplingCount(samplingCount, stopSampling, s.samplingRate)
-	for r := range input {
+	for batch := range input {
 		s.mu.Lock() // avoid race around samplingCount
-		aggregate(s.result, r, samplingCount, s.samplingRate)
+		for _, r := range batch {
+			aggregate(s.result, r, samplingCount, s.samplingRate)
+		}
 		s.mu.Unlock()
 	}
 	// listen for assertion result, add to json
@@ -147,7 +291,7 @@ func (s *stdoutJson) cleanSamplingCount(samplingCount map[uint16]map[string]int,
 	}
 }
 
-func (s *stdoutJson) printInDebugMode(input chan *types.ScenarioResult) {
+func (s *stdoutJson) printInDebugMode(input <-chan []*types.ScenarioResult) {
 	stepDebugResults := struct {
 		DebugResults         map[uint16]verboseHttpRequestInfo "json:\"steps\""
 		TestStatus           string                            "json:\"test_status\""
@@ -155,7 +299,7 @@ func (s *stdoutJson) printInDebugMode(input chan *types.ScenarioResult) {
 	}{
 		DebugResults: map[uint16]verboseHttpRequestInfo{},
 	}
-	for r := range input { // only 1 sc ScenarioResult expected
+	for _, r := range collect(input) { // only 1 sc ScenarioResult expected
 		for _, sr := range r.StepResults {
 			verboseInfo := ScenarioStepResultToVerboseHttpRequestInfo(sr)
 			stepDebugResults.DebugResults[verboseInfo.StepId] = verboseInfo
@@ -169,12 +313,16 @@ func (s *stdoutJson) printInDebugMode(input chan *types.ScenarioResult) {
 		stepDebugResults.TestStatus = "success"
 	}
 
-	printPretty(out, stepDebugResults)
+	w := out
+	if s.outFile != nil {
+		w = colorable.NewColorable(s.outFile)
+	}
+	printPretty(w, stepDebugResults)
 }
 
 func printPretty(w io.Writer, info any) {
 	valPretty, _ := json.MarshalIndent(info, "", "  ")
-	fmt.Fprintf(out, "%s \n",
+	fmt.Fprintf(w, "%s \n",
 		white(fmt.Sprintf(" %-6s",
 			valPretty)))
 }
@@ -209,8 +357,8 @@ func (s ScenarioStepResultSummary) MarshalJSON() ([]byte, error) {
 	})
 }
 
-var printJson = func(j []byte) {
-	fmt.Println(string(j))
+var printJson = func(w io.Writer, j []byte) {
+	fmt.Fprintln(w, string(j))
 }
 
 var strKeyToJsonKey = map[string]string{
@@ -220,6 +368,7 @@ var strKeyToJsonKey = map[string]string{
 	"reqDuration":           "request_write",
 	"serverProcessDuration": "server_processing",
 	"resDuration":           "response_read",
+	"decompressDuration":    "decompression",
 	"duration":              "total",
 }
 
@@ -292,7 +441,8 @@ func (v verboseHttpRequestInfo) MarshalJSON() ([]byte, error) {
 			Headers map[string]string `json:"headers"`
 			Body    interface{}       `json:"body"`
 		} `json:"request"`
-		Response verboseResponse `json:"response"`
+		Response     verboseResponse `json:"response"`
+		SchemaErrors []string        `json:"schema_errors,omitempty"`
 	}
 
 	a := alias{
@@ -300,6 +450,7 @@ func (v verboseHttpRequestInfo) MarshalJSON() ([]byte, error) {
 		StepName:         v.StepName,
 		Request:          v.Request,
 		Response:         v.Response,
+		SchemaErrors:     v.SchemaErrors,
 		FailedCaptures:   v.FailedCaptures,
 		FailedAssertions: v.FailedAssertions,
 		Envs:             v.Envs,
@@ -22,6 +22,7 @@ package report
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -34,7 +35,7 @@ import (
 func TestInitStdoutJson(t *testing.T) {
 	sj := &stdoutJson{}
 	debug := false
-	sj.Init(debug, 0)
+	sj.Init(debug, 0, 0)
 
 	if sj.doneChan == nil {
 		t.Errorf("DoneChan should be initialized")
@@ -144,7 +145,7 @@ func TestStdoutJsonAggregate(t *testing.T) {
 
 	s := &stdoutJson{}
 	debug := false
-	s.Init(debug, 0)
+	s.Init(debug, 0, 0)
 
 	for _, r := range responses {
 		aggregate(s.result, r, make(map[uint16]map[string]int), 3)
@@ -200,7 +201,7 @@ func TestStdoutJsonOutput(t *testing.T) {
 	}
 
 	var output string
-	printJson = func(j []byte) {
+	printJson = func(w io.Writer, j []byte) {
 		output = string(j)
 	}
 
@@ -275,7 +276,7 @@ func TestStdoutJsonOutput(t *testing.T) {
 
 func TestStdoutJsonDebugModePrintsValidJson(t *testing.T) {
 	s := &stdoutJson{}
-	s.Init(true, 0)
+	s.Init(true, 0, 0)
 	testDoneChan := make(chan struct{}, 1)
 
 	realOut := out
@@ -309,6 +310,29 @@ func TestStdoutJsonDebugModePrintsValidJson(t *testing.T) {
 
 }
 
+func TestStdoutJsonSetOutput(t *testing.T) {
+	s := &stdoutJson{}
+	r, w, _ := os.Pipe()
+	s.SetOutput(w)
+	s.Init(true, 0, 0)
+
+	inputChan := make(chan *types.ScenarioResult, 1)
+	inputChan <- &types.ScenarioResult{}
+	close(inputChan)
+
+	go func() {
+		s.Start(inputChan, nil)
+		w.Close()
+	}()
+	<-s.DoneChan()
+
+	// the debug result is printed to the given file instead of stdout
+	printedOutput, _ := ioutil.ReadAll(r)
+	if !json.Valid(printedOutput) || !bytes.Contains(printedOutput, []byte("test_status")) {
+		t.Errorf("Printed output is not the valid json result: %v", string(printedOutput))
+	}
+}
+
 func TestVerboseHttpInfoMarshallingErrorCaseEmptyReq(t *testing.T) {
 	errorStr := "there is error"
 	vError := verboseHttpRequestInfo{
@@ -411,7 +435,7 @@ func TestVerboseHttpInfoMarshallingSuccessCase(t *testing.T) {
 
 func TestStdoutJsonTestResultStatusShouldBeTrueWhenNoAssertion(t *testing.T) {
 	s := &stdoutJson{}
-	s.Init(false, 0)
+	s.Init(false, 0, 0)
 
 	inputChan := make(chan *types.ScenarioResult, 1)
 	inputChan <- &types.ScenarioResult{}
@@ -431,7 +455,7 @@ func TestStdoutJsonTestResultStatusShouldBeTrueWhenNoAssertion(t *testing.T) {
 
 func TestStdoutJsonTestResultStatusShouldBeFalseWhenAssertionsFail(t *testing.T) {
 	s := &stdoutJson{}
-	s.Init(false, 0)
+	s.Init(false, 0, 0)
 
 	inputChan := make(chan *types.ScenarioResult, 1)
 	inputChan <- &types.ScenarioResult{}
@@ -97,7 +97,7 @@ func TestResult(t *testing.T) {
 func TestInit(t *testing.T) {
 	s := &stdout{}
 	debug := false
-	s.Init(debug, 0)
+	s.Init(debug, 0, 0)
 
 	if s.doneChan == nil {
 		t.Errorf("DoneChan should be initialized")
@@ -138,9 +138,25 @@ func TestPrintBodyAsString(t *testing.T) {
 	}
 }
 
+func TestPrintErrorCategories(t *testing.T) {
+	t.Parallel()
+
+	buffer := &bytes.Buffer{}
+	printErrorCategories(buffer, map[types.ErrorCategory]int64{
+		types.ErrorCategoryHTTP5xx: 1,
+		types.ErrorCategoryTLS:     8,
+		types.ErrorCategoryTimeout: 1,
+	})
+
+	expected := "  8\t :tls (80%)\n  1\t :http_5xx (10%)\n  1\t :timeout (10%)\n"
+	if buffer.String() != expected {
+		t.Errorf("Expected %q, Found: %q", expected, buffer.String())
+	}
+}
+
 func TestStdoutPrintsHeadlinesInDebugMode(t *testing.T) {
 	s := &stdout{}
-	s.Init(true, 0)
+	s.Init(true, 0, 0)
 	testDoneChan := make(chan struct{}, 1)
 
 	// listen to output
@@ -198,3 +214,28 @@ func TestStdoutPrintsHeadlinesInDebugMode(t *testing.T) {
 	<-testDoneChan
 
 }
+
+func TestStdoutSetOutput(t *testing.T) {
+	s := &stdout{}
+	r, w, _ := os.Pipe()
+	s.SetOutput(w)
+
+	realOut := out
+	var stdout bytes.Buffer
+	out = &stdout
+	defer func() {
+		out = realOut
+	}()
+
+	s.Init(true, 0, 0)
+	w.Close()
+
+	// the report is printed to the given file, nothing is left on stdout
+	printedOutput, _ := ioutil.ReadAll(r)
+	if !strings.Contains(string(printedOutput), "Initializing...") {
+		t.Errorf("Expected %v, Found: %v", "Initializing...", string(printedOutput))
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected nothing on stdout, Found: %v", stdout.String())
+	}
+}
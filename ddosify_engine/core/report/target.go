@@ -0,0 +1,118 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// TargetSummary is the result of the requests of a step sent to one of its weighted targets.
+type TargetSummary struct {
+	SuccessCount int64 `json:"success_count"`
+	FailCount    int64 `json:"fail_count"`
+
+	// Average response time of the requests that received a response, in seconds
+	AvgDuration float32 `json:"avg_duration"`
+
+	// Response time percentiles, in seconds. Calculated from latencies at the end of the test.
+	Percentiles *LatencyPercentiles `json:"percentiles,omitempty"`
+
+	latencies *latencyHistogram
+}
+
+func (t *TargetSummary) successPercentage() int {
+	if t.SuccessCount+t.FailCount == 0 {
+		return 0
+	}
+	return int(float32(t.SuccessCount) / float32(t.SuccessCount+t.FailCount) * 100)
+}
+
+func (s *ScenarioStepResultSummary) target(name string) *TargetSummary {
+	if s.Targets == nil {
+		s.Targets = make(map[string]*TargetSummary)
+	}
+	ts, ok := s.Targets[name]
+	if !ok {
+		ts = &TargetSummary{latencies: s.latencies.empty()}
+		s.Targets[name] = ts
+	}
+	return ts
+}
+
+// addTarget aggregates the result into the summary of its target. Like the step durations, the response time is
+// not recorded if the request failed without a response, or if the result is not sampled.
+func (s *ScenarioStepResultSummary) addTarget(sr *types.ScenarioStepResult, sampled bool) {
+	ts := s.target(sr.Target)
+	assertionFail := len(sr.FailedAssertions) > 0 || len(sr.SchemaErrors) > 0
+	if assertionFail || sr.Err.Type != "" {
+		ts.FailCount++
+	} else {
+		ts.SuccessCount++
+	}
+	if sampled && (assertionFail || sr.Err.Type == "") {
+		n := float32(ts.latencies.total)
+		ts.AvgDuration = (n*ts.AvgDuration + float32(sr.Duration.Seconds())) / (n + 1)
+		ts.latencies.record(sr.Duration)
+	}
+}
+
+// mergeTargets merges the target summaries of a snapshot, averages are weighted by the recorded response times.
+func (s *ScenarioStepResultSummary) mergeTargets(targets map[string]*TargetSummary,
+	latencies map[string]LatencySnapshot) {
+	for name, o := range targets {
+		ts := s.target(name)
+		ts.SuccessCount += o.SuccessCount
+		ts.FailCount += o.FailCount
+
+		ls, ok := latencies[name]
+		if !ok {
+			continue
+		}
+		n, on := float32(ts.latencies.total), float32(ls.Total)
+		if n+on > 0 {
+			ts.AvgDuration = (n*ts.AvgDuration + on*o.AvgDuration) / (n + on)
+		}
+		ts.latencies.merge(ls)
+	}
+}
+
+// printTargets prints the results of the weighted targets of a step, in the order of the target names.
+func printTargets(w io.Writer, targets map[string]*TargetSummary) {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "\nTargets:")
+	for _, name := range names {
+		ts := targets[name]
+		fmt.Fprintf(w, "  %s\t:%d requests (%d%% success), avg %.4fs", name, ts.SuccessCount+ts.FailCount,
+			ts.successPercentage(), ts.AvgDuration)
+		if p := ts.Percentiles; p != nil {
+			fmt.Fprintf(w, ", p95 %.4fs, p99 %.4fs", p.P95, p.P99)
+		}
+		fmt.Fprintln(w)
+	}
+}
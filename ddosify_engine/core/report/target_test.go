@@ -0,0 +1,83 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestAggregateTargets(t *testing.T) {
+	t.Parallel()
+
+	results := []*types.ScenarioStepResult{
+		{StepID: 1, Target: "eu", StatusCode: 200, Duration: 100 * time.Millisecond},
+		{StepID: 1, Target: "eu", StatusCode: 200, Duration: 300 * time.Millisecond},
+		{StepID: 1, Target: "us", StatusCode: 500, Duration: 50 * time.Millisecond,
+			FailedAssertions: []types.FailedAssertion{{Rule: "status_code == 200"}}},
+		{StepID: 1, Target: "us", Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}},
+	}
+
+	single := NewResult()
+	workers := []*Result{NewResult(), NewResult()}
+	samplingCount := make(map[uint16]map[string]int)
+	for i, sr := range results {
+		scr := &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{sr}}
+		aggregate(single, scr, samplingCount, 0)
+		aggregate(workers[i%2], scr, samplingCount, 0)
+	}
+	merged := NewResult()
+	for _, w := range workers {
+		b, _ := json.Marshal(w.Snapshot())
+		var s Snapshot
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatalf("TestAggregateTargets unmarshal error: %v", err)
+		}
+		merged.Merge(s)
+	}
+
+	for _, r := range []*Result{single, merged} {
+		r.calculatePercentiles()
+		eu, us := r.StepResults[1].Targets["eu"], r.StepResults[1].Targets["us"]
+		if eu.SuccessCount != 2 || eu.FailCount != 0 || us.SuccessCount != 0 || us.FailCount != 2 {
+			t.Errorf("Expected %v, Found: %v", []int64{2, 0, 0, 2},
+				[]int64{eu.SuccessCount, eu.FailCount, us.SuccessCount, us.FailCount})
+		}
+		// the request failed without a response is not in the durations
+		if d := eu.AvgDuration - 0.2; d > 1e-6 || d < -1e-6 || us.AvgDuration != 0.05 {
+			t.Errorf("Expected %v, Found: %v", []float32{0.2, 0.05}, []float32{eu.AvgDuration, us.AvgDuration})
+		}
+		if eu.Percentiles == nil || eu.Percentiles.Max != 0.3 {
+			t.Errorf("Expected %v, Found: %v", 0.3, eu.Percentiles)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	printTargets(buf, single.StepResults[1].Targets)
+	out := buf.String()
+	if !strings.Contains(out, "eu\t:2 requests (100% success)") || !strings.Contains(out, "us\t:2 requests (0% success)") {
+		t.Errorf("Expected %v, Found: %v", "results of the eu and us targets", out)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+const DefaultTimeSeriesInterval = time.Second
+
+var timeSeriesCsvHeader = []string{"timestamp", "elapsed", "requests", "rps", "failed", "error_rate",
+	"avg", "p50", "p90", "p95", "p99", "max"}
+
+// TimeSeriesWriter writes the stats of the requests completed in each interval of the test as a row, to see how
+// the latency and the error rate evolved as the load changed. Only the current bucket is kept in the memory, it is
+// written when a result of a later bucket is recorded or the writer is closed. The intervals without any completed
+// request are written as empty rows, so the stalls of the target are visible in the series.
+type TimeSeriesWriter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	start    time.Time
+	index    int64 // of the current bucket since the start
+	bucket   timeSeriesBucket
+
+	file *os.File // nil if the rows are not written to a file
+	buf  *bufio.Writer
+	enc  *json.Encoder // nil for the csv format
+	csv  *csv.Writer   // nil for the json format
+	err  error         // first write error, the rows after it are dropped
+}
+
+type timeSeriesBucket struct {
+	requests int64
+	failed   int64
+	sum      time.Duration
+	hist     *latencyHistogram
+}
+
+// timeSeriesRow is the stats of a bucket, durations are in milliseconds.
+type timeSeriesRow struct {
+	Timestamp time.Time `json:"timestamp"` // start of the bucket
+	Elapsed   float64   `json:"elapsed"`   // seconds from the start of the test to the start of the bucket
+	Requests  int64     `json:"requests"`
+	RPS       float64   `json:"rps"`
+	Failed    int64     `json:"failed"`
+	ErrorRate float64   `json:"error_rate"` // ratio of the failed requests
+	Avg       float64   `json:"avg"`
+	P50       float64   `json:"p50"`
+	P90       float64   `json:"p90"`
+	P95       float64   `json:"p95"`
+	P99       float64   `json:"p99"`
+	Max       float64   `json:"max"`
+}
+
+// NewTimeSeriesWriter creates the file of the conf, the buckets start from now.
+func NewTimeSeriesWriter(conf types.TimeSeriesConf) (*TimeSeriesWriter, error) {
+	f, err := os.Create(conf.File)
+	if err != nil {
+		return nil, err
+	}
+	t, err := newTimeSeriesWriter(conf.Format, f, conf.Interval, time.Now())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.file = f
+	return t, nil
+}
+
+func newTimeSeriesWriter(format string, w io.Writer, interval time.Duration, start time.Time) (*TimeSeriesWriter, error) {
+	if interval <= 0 {
+		interval = DefaultTimeSeriesInterval
+	}
+	t := &TimeSeriesWriter{
+		interval: interval,
+		start:    start,
+		bucket:   timeSeriesBucket{hist: newLatencyHistogram()},
+		buf:      bufio.NewWriter(w),
+	}
+	switch strings.ToLower(format) {
+	case "", OutputFormatJson:
+		t.enc = json.NewEncoder(t.buf)
+	case OutputFormatCsv:
+		t.csv = csv.NewWriter(t.buf)
+		t.err = t.csv.Write(timeSeriesCsvHeader)
+	default:
+		return nil, fmt.Errorf("unsupported timeseries format: %s", format)
+	}
+	return t, nil
+}
+
+// WriteResult records the request into the bucket of the current time.
+func (t *TimeSeriesWriter) WriteResult(r *types.ScenarioStepResult) error {
+	return t.record(r, time.Now(), true)
+}
+
+// WriteUnsampledResult records the request of an unsampled iteration into the bucket of the current time. It is
+// counted like WriteResult, but its latency is left out of the percentiles. See Hammer.SampleRate.
+func (t *TimeSeriesWriter) WriteUnsampledResult(r *types.ScenarioStepResult) error {
+	return t.record(r, time.Now(), false)
+}
+
+func (t *TimeSeriesWriter) record(r *types.ScenarioStepResult, now time.Time, sampled bool) error {
+	rec := newOutputRecord(r)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.advance(now)
+
+	b := &t.bucket
+	b.requests++
+	if rec.result() != "success" {
+		b.failed++
+	}
+	b.sum += r.Duration
+	if sampled {
+		b.hist.record(r.Duration)
+	}
+	return t.err
+}
+
+// advance writes the buckets ended before now, the current one is reset for the next interval.
+func (t *TimeSeriesWriter) advance(now time.Time) {
+	i := int64(now.Sub(t.start) / t.interval)
+	for t.index < i {
+		t.writeRow(t.interval)
+		t.index++
+	}
+}
+
+func (t *TimeSeriesWriter) writeRow(length time.Duration) {
+	b := &t.bucket
+	bucketStart := time.Duration(t.index) * t.interval
+	row := timeSeriesRow{
+		Timestamp: t.start.Add(bucketStart),
+		Elapsed:   bucketStart.Seconds(),
+		Requests:  b.requests,
+		Failed:    b.failed,
+	}
+	if length > 0 {
+		row.RPS = float64(b.requests) / length.Seconds()
+	}
+	if b.requests > 0 {
+		row.ErrorRate = float64(b.failed) / float64(b.requests)
+		row.Avg = toMs(b.sum / time.Duration(b.requests))
+		row.P50 = toMs(b.hist.quantile(0.50))
+		row.P90 = toMs(b.hist.quantile(0.90))
+		row.P95 = toMs(b.hist.quantile(0.95))
+		row.P99 = toMs(b.hist.quantile(0.99))
+		row.Max = toMs(b.hist.max)
+
+		*b = timeSeriesBucket{hist: newLatencyHistogram()}
+	}
+
+	if t.err != nil {
+		return
+	}
+	if t.enc != nil {
+		t.err = t.enc.Encode(row)
+		return
+	}
+	t.err = t.csv.Write([]string{
+		row.Timestamp.Format(time.RFC3339Nano),
+		strconv.FormatFloat(row.Elapsed, 'f', -1, 64),
+		strconv.FormatInt(row.Requests, 10),
+		strconv.FormatFloat(row.RPS, 'f', 3, 64),
+		strconv.FormatInt(row.Failed, 10),
+		strconv.FormatFloat(row.ErrorRate, 'f', 4, 64),
+		strconv.FormatFloat(row.Avg, 'f', 3, 64),
+		strconv.FormatFloat(row.P50, 'f', 3, 64),
+		strconv.FormatFloat(row.P90, 'f', 3, 64),
+		strconv.FormatFloat(row.P95, 'f', 3, 64),
+		strconv.FormatFloat(row.P99, 'f', 3, 64),
+		strconv.FormatFloat(row.Max, 'f', 3, 64),
+	})
+}
+
+// Close writes the buckets up to now, the last one is partial and its rps is calculated by its elapsed part.
+func (t *TimeSeriesWriter) Close() error {
+	return t.close(time.Now())
+}
+
+func (t *TimeSeriesWriter) close(now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.advance(now)
+	if rest := now.Sub(t.start) - time.Duration(t.index)*t.interval; rest > 0 || t.bucket.requests > 0 {
+		t.writeRow(rest)
+	}
+
+	if t.csv != nil {
+		t.csv.Flush()
+		if t.err == nil {
+			t.err = t.csv.Error()
+		}
+	}
+	if err := t.buf.Flush(); t.err == nil {
+		t.err = err
+	}
+	if t.file != nil {
+		if err := t.file.Close(); t.err == nil {
+			t.err = err
+		}
+	}
+	return t.err
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
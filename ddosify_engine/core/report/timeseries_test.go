@@ -0,0 +1,153 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestTimeSeriesWriter(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	w, err := newTimeSeriesWriter(OutputFormatJson, buf, time.Second, start)
+	if err != nil {
+		t.Fatalf("TestTimeSeriesWriter error: %v", err)
+	}
+
+	// first bucket
+	w.record(&types.ScenarioStepResult{StatusCode: 200, Duration: 10 * time.Millisecond}, start.Add(100*time.Millisecond), true)
+	w.record(&types.ScenarioStepResult{StatusCode: 200, Duration: 30 * time.Millisecond}, start.Add(900*time.Millisecond), true)
+	// the second bucket is empty, a failed request in the third one
+	w.record(&types.ScenarioStepResult{Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout},
+		Duration: 50 * time.Millisecond}, start.Add(2100*time.Millisecond), true)
+	if err := w.close(start.Add(2500 * time.Millisecond)); err != nil {
+		t.Fatalf("TestTimeSeriesWriter close error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected %v, Found: %v, %s", 3, len(lines), buf.String())
+	}
+	rows := make([]timeSeriesRow, len(lines))
+	for i, l := range lines {
+		if err := json.Unmarshal([]byte(l), &rows[i]); err != nil {
+			t.Fatalf("TestTimeSeriesWriter unmarshal error: %v", err)
+		}
+	}
+
+	if rows[0].Requests != 2 || rows[0].RPS != 2 || rows[0].Failed != 0 || rows[0].Avg != 20 {
+		t.Errorf("Expected 2 requests with 20ms avg, Found: %+v", rows[0])
+	}
+	if rows[0].Max != 30 || rows[0].P50 < 9.9 || rows[0].P50 > 10.1 {
+		t.Errorf("Expected p50 10ms and max 30ms, Found: %+v", rows[0])
+	}
+	if !rows[0].Timestamp.Equal(start) || rows[1].Elapsed != 1 {
+		t.Errorf("Expected the buckets to start at %v, Found: %v, %v", start, rows[0].Timestamp, rows[1].Elapsed)
+	}
+	if rows[1].Requests != 0 || rows[1].RPS != 0 || rows[1].P99 != 0 {
+		t.Errorf("Expected an empty bucket, Found: %+v", rows[1])
+	}
+	// the last bucket is partial, its rps is calculated by 500ms
+	if rows[2].Requests != 1 || rows[2].RPS != 2 || rows[2].Failed != 1 || rows[2].ErrorRate != 1 {
+		t.Errorf("Expected a failed request at 2 rps, Found: %+v", rows[2])
+	}
+}
+
+func TestTimeSeriesWriterCsv(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	w, err := newTimeSeriesWriter("CSV", buf, 5*time.Second, start)
+	if err != nil {
+		t.Fatalf("TestTimeSeriesWriterCsv error: %v", err)
+	}
+	w.record(&types.ScenarioStepResult{StatusCode: 200, Duration: 2 * time.Millisecond}, start.Add(time.Second), true)
+	w.record(&types.ScenarioStepResult{StatusCode: 500, Duration: 2 * time.Millisecond,
+		FailedAssertions: []types.FailedAssertion{{Rule: "equals(status_code,200)"}}}, start.Add(6*time.Second), true)
+	if err := w.close(start.Add(10 * time.Second)); err != nil {
+		t.Fatalf("TestTimeSeriesWriterCsv close error: %v", err)
+	}
+
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("TestTimeSeriesWriterCsv read error: %v", err)
+	}
+	expected := [][]string{
+		timeSeriesCsvHeader,
+		{"2023-01-02T03:04:05Z", "0", "1", "0.200", "0", "0.0000", "2.000", "2.000", "2.000", "2.000", "2.000", "2.000"},
+		{"2023-01-02T03:04:10Z", "5", "1", "0.200", "1", "1.0000", "2.000", "2.000", "2.000", "2.000", "2.000", "2.000"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %v, Found: %v", expected, records)
+	}
+	for i := range expected {
+		if strings.Join(records[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("Expected %v, Found: %v", expected[i], records[i])
+		}
+	}
+}
+
+func TestTimeSeriesWriterUnsampled(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	w, err := newTimeSeriesWriter(OutputFormatJson, buf, time.Second, start)
+	if err != nil {
+		t.Fatalf("TestTimeSeriesWriterUnsampled error: %v", err)
+	}
+	w.record(&types.ScenarioStepResult{StatusCode: 200, Duration: 10 * time.Millisecond}, start.Add(100*time.Millisecond), true)
+	w.record(&types.ScenarioStepResult{StatusCode: 500, Duration: 90 * time.Millisecond,
+		FailedAssertions: []types.FailedAssertion{{Rule: "equals(status_code,200)"}}}, start.Add(200*time.Millisecond), false)
+	if err := w.close(start.Add(time.Second)); err != nil {
+		t.Fatalf("TestTimeSeriesWriterUnsampled close error: %v", err)
+	}
+
+	var row timeSeriesRow
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &row); err != nil {
+		t.Fatalf("TestTimeSeriesWriterUnsampled unmarshal error: %v", err)
+	}
+	// the counts and the average are exact, the percentiles are of the sampled request only
+	if row.Requests != 2 || row.Failed != 1 || row.Avg != 50 {
+		t.Errorf("Expected 2 requests, 1 failed with 50ms avg, Found: %+v", row)
+	}
+	if row.Max != 10 || row.P99 < 9.9 || row.P99 > 10.1 {
+		t.Errorf("Expected p99 and max 10ms, Found: %+v", row)
+	}
+}
+
+func TestTimeSeriesWriterUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newTimeSeriesWriter("xml", &bytes.Buffer{}, 0, time.Now()); err == nil {
+		t.Errorf("Expected error, Found: %v", err)
+	}
+}
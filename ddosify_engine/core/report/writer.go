@@ -0,0 +1,273 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+const (
+	OutputFormatJson   = "json"
+	OutputFormatCsv    = "csv"
+	OutputFormatInflux = "influxdb"
+)
+
+var SupportedOutputFormats = [...]string{OutputFormatJson, OutputFormatCsv, OutputFormatInflux}
+
+// OutputWriter writes the result of each request to a destination, as a record.
+// Implementations are safe for concurrent use.
+type OutputWriter interface {
+	WriteResult(r *types.ScenarioStepResult) error
+	Flush() error
+}
+
+// NewOutputWriter is the factory method of the OutputWriter.
+func NewOutputWriter(format string, w io.Writer) (OutputWriter, error) {
+	switch strings.ToLower(format) {
+	case OutputFormatJson:
+		return newJsonLinesWriter(w), nil
+	case OutputFormatCsv:
+		return newCsvWriter(w), nil
+	case OutputFormatInflux:
+		return newInfluxLineWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// outputRecord is the flat representation of a request result.
+type outputRecord struct {
+	Timestamp         time.Time `json:"timestamp"`
+	StepID            uint16    `json:"step_id"`
+	StepName          string    `json:"step_name"`
+	Tags              []string  `json:"tags,omitempty"`
+	Target            string    `json:"target,omitempty"` // weighted target of the step that the request is sent to
+	RequestID         string    `json:"request_id,omitempty"`
+	StatusCode        int       `json:"status_code"`
+	ResponseTime      float64   `json:"response_time"`          // in milliseconds
+	ScheduleLag       float64   `json:"schedule_lag,omitempty"` // in milliseconds, included in the response time
+	Bytes             int64     `json:"bytes"`
+	DecompressedBytes int64     `json:"decompressed_bytes,omitempty"`
+	ReqBodyBytes      int64     `json:"req_body_bytes,omitempty"`
+	ReqCompressedBody int64     `json:"req_compressed_body_bytes,omitempty"`
+	UploadedBytes     int64     `json:"uploaded_bytes,omitempty"`
+	UploadThroughput  float64   `json:"upload_throughput,omitempty"` // in bytes per second
+	Error             string    `json:"error,omitempty"`
+	ErrorCategory     string    `json:"error_category,omitempty"`
+	FailedAssertions  []string  `json:"failed_assertions,omitempty"`
+	SchemaErrors      []string  `json:"schema_errors,omitempty"`
+	ConnReused        *bool     `json:"conn_reused,omitempty"` // nil if no connection is obtained
+	Protocol          string    `json:"protocol,omitempty"`    // negotiated protocol version of the response
+	TLSVersion        string    `json:"tls_version,omitempty"` // negotiated TLS version, only captured by the cert audit
+	CertNotAfter      string    `json:"cert_not_after,omitempty"`
+
+	// Redirects followed by the request, only written by the json writer.
+	Redirects []outputRedirect `json:"redirects,omitempty"`
+
+	// Inter-arrival times of the events of a sse step in milliseconds, only written by the json writer.
+	EventIntervals []float64 `json:"event_intervals,omitempty"`
+
+	// Phases is the latency breakdown of the request in milliseconds, like dns, connection, tls, server_processing.
+	// Only written by the json writer.
+	Phases map[string]float64 `json:"phases,omitempty"`
+}
+
+// result returns the outcome of the request, like success or server_error.
+func (rec *outputRecord) result() string {
+	switch {
+	case rec.ErrorCategory == string(types.ErrorCategoryLocal):
+		return "local_error"
+	case rec.Error != "":
+		return "server_error"
+	case len(rec.FailedAssertions) > 0:
+		return "assertion_error"
+	case len(rec.SchemaErrors) > 0:
+		return "schema_error"
+	default:
+		return "success"
+	}
+}
+
+type outputRedirect struct {
+	URL          string  `json:"url"`
+	StatusCode   int     `json:"status_code"`
+	ResponseTime float64 `json:"response_time"` // in milliseconds
+}
+
+// phaseKeys maps the duration keys in the custom result fields to the record phase names.
+var phaseKeys = map[string]string{
+	"dnsDuration":           "dns",
+	"connDuration":          "connection",
+	"tlsDuration":           "tls",
+	"reqDuration":           "request_write",
+	"serverProcessDuration": "server_processing",
+	"resDuration":           "response_read",
+	"decompressDuration":    "decompression",
+}
+
+func newOutputRecord(r *types.ScenarioStepResult) outputRecord {
+	rec := outputRecord{
+		Timestamp:         r.RequestTime,
+		StepID:            r.StepID,
+		StepName:          r.StepName,
+		Tags:              r.Tags,
+		Target:            r.Target,
+		StatusCode:        r.StatusCode,
+		ResponseTime:      float64(r.Duration) / float64(time.Millisecond),
+		Bytes:             r.ContentLength,
+		DecompressedBytes: r.DecompressedLength,
+		ReqBodyBytes:      r.ReqBodyLength,
+		ReqCompressedBody: r.ReqCompressedBodyLength,
+		ScheduleLag:       float64(r.ScheduleLag) / float64(time.Millisecond),
+		ErrorCategory:     string(r.ErrCategory),
+		Protocol:          r.Proto,
+	}
+	if r.RequestID != uuid.Nil {
+		rec.RequestID = r.RequestID.String()
+	}
+	if rec.Bytes < 0 { // unknown content length, like chunked responses
+		rec.Bytes = int64(len(r.RespBody))
+	}
+	if r.Err.Type != "" {
+		rec.Error = r.Err.Error()
+	}
+	if r.UploadedBytes > 0 {
+		rec.UploadedBytes = r.UploadedBytes
+		if r.UploadDuration > 0 {
+			rec.UploadThroughput = float64(r.UploadedBytes) / r.UploadDuration.Seconds()
+		}
+	}
+	if r.Cert != nil {
+		rec.TLSVersion = r.Cert.Version
+		rec.CertNotAfter = r.Cert.NotAfter.UTC().Format(time.RFC3339)
+	}
+	for _, fa := range r.FailedAssertions {
+		rec.FailedAssertions = append(rec.FailedAssertions, fa.Rule)
+	}
+	rec.SchemaErrors = r.SchemaErrors
+	if r.Conn != types.ConnUnknown {
+		reused := r.Conn == types.ConnReused
+		rec.ConnReused = &reused
+	}
+	for _, hop := range r.Redirects {
+		rec.Redirects = append(rec.Redirects, outputRedirect{
+			URL:          hop.URL,
+			StatusCode:   hop.StatusCode,
+			ResponseTime: float64(hop.Duration) / float64(time.Millisecond),
+		})
+	}
+	for _, i := range r.EventIntervals {
+		rec.EventIntervals = append(rec.EventIntervals, float64(i)/float64(time.Millisecond))
+	}
+	for k, v := range r.Custom {
+		name, ok := phaseKeys[k]
+		d, isDur := v.(time.Duration)
+		if !ok || !isDur {
+			continue
+		}
+		if rec.Phases == nil {
+			rec.Phases = make(map[string]float64, len(phaseKeys))
+		}
+		rec.Phases[name] = float64(d) / float64(time.Millisecond)
+	}
+	return rec
+}
+
+// jsonLinesWriter writes a json object per line.
+type jsonLinesWriter struct {
+	mu  sync.Mutex
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJsonLinesWriter(w io.Writer) *jsonLinesWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonLinesWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (j *jsonLinesWriter) WriteResult(r *types.ScenarioStepResult) error {
+	rec := newOutputRecord(r)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.enc.Encode(rec) // Encode appends a newline
+}
+
+func (j *jsonLinesWriter) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.buf.Flush()
+}
+
+var csvHeader = []string{"timestamp", "step_id", "step_name", "status_code", "response_time", "bytes", "error", "failed_assertions"}
+
+// csvWriter writes a header line followed by a line per record. Failed assertion rules are separated by ';'.
+type csvWriter struct {
+	mu            sync.Mutex
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCsvWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteResult(r *types.ScenarioStepResult) error {
+	rec := newOutputRecord(r)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.headerWritten {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.headerWritten = true
+	}
+
+	return c.w.Write([]string{
+		rec.Timestamp.Format(time.RFC3339Nano),
+		strconv.Itoa(int(rec.StepID)),
+		rec.StepName,
+		strconv.Itoa(rec.StatusCode),
+		strconv.FormatFloat(rec.ResponseTime, 'f', 3, 64),
+		strconv.FormatInt(rec.Bytes, 10),
+		rec.Error,
+		strings.Join(rec.FailedAssertions, ";"),
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,278 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+var writerTestResults = []*types.ScenarioStepResult{
+	{
+		StepID:        1,
+		StepName:      "login",
+		StatusCode:    200,
+		RequestTime:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:      1500 * time.Microsecond,
+		ContentLength: 42,
+	},
+	{
+		StepID:        2,
+		StepName:      "order",
+		StatusCode:    400,
+		RequestTime:   time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC),
+		Duration:      2 * time.Millisecond,
+		ContentLength: -1,
+		RespBody:      []byte("bad"),
+		FailedAssertions: []types.FailedAssertion{
+			{Rule: "equals(status_code,200)"},
+			{Rule: "has(headers.X-Id)"},
+		},
+	},
+	{
+		StepID:   3,
+		StepName: "logout",
+		Err:      types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout},
+	},
+}
+
+func TestJsonLinesWriter(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, err := NewOutputWriter(OutputFormatJson, buf)
+	if err != nil {
+		t.Fatalf("TestJsonLinesWriter error: %v", err)
+	}
+	for _, r := range writerTestResults {
+		if err := w.WriteResult(r); err != nil {
+			t.Errorf("TestJsonLinesWriter write error: %v", err)
+		}
+	}
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(writerTestResults) {
+		t.Fatalf("Expected %v, Found: %v", len(writerTestResults), len(lines))
+	}
+
+	expected := []string{
+		`{"timestamp":"2023-01-02T03:04:05Z","step_id":1,"step_name":"login","status_code":200,"response_time":1.5,"bytes":42}`,
+		`{"timestamp":"2023-01-02T03:04:06Z","step_id":2,"step_name":"order","status_code":400,"response_time":2,"bytes":3,` +
+			`"failed_assertions":["equals(status_code,200)","has(headers.X-Id)"]}`,
+		`{"timestamp":"0001-01-01T00:00:00Z","step_id":3,"step_name":"logout","status_code":0,"response_time":0,"bytes":0,` +
+			`"error":"connectionError: connection timeout"}`,
+	}
+	for i, l := range lines {
+		if !json.Valid([]byte(l)) {
+			t.Errorf("Expected valid json line, Found: %s", l)
+		}
+		if l != expected[i] {
+			t.Errorf("Expected %v, Found: %v", expected[i], l)
+		}
+	}
+}
+
+func TestJsonLinesWriterPhases(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, _ := NewOutputWriter(OutputFormatJson, buf)
+	w.WriteResult(&types.ScenarioStepResult{
+		StepID:      1,
+		StepName:    "login",
+		StatusCode:  200,
+		RequestTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Duration:    10 * time.Millisecond,
+		Custom: map[string]interface{}{
+			"dnsDuration":           1 * time.Millisecond,
+			"tlsDuration":           2500 * time.Microsecond,
+			"serverProcessDuration": 5 * time.Millisecond,
+			"hostname":              "ddosify.com", // not a phase
+		},
+	})
+	w.Flush()
+
+	expected := `{"timestamp":"2023-01-02T03:04:05Z","step_id":1,"step_name":"login","status_code":200,"response_time":10,"bytes":0,` +
+		`"phases":{"dns":1,"server_processing":5,"tls":2.5}}`
+	if l := strings.TrimSpace(buf.String()); l != expected {
+		t.Errorf("Expected %v, Found: %v", expected, l)
+	}
+}
+
+func TestJsonLinesWriterRequestID(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	buf := &bytes.Buffer{}
+	w, _ := NewOutputWriter(OutputFormatJson, buf)
+	w.WriteResult(&types.ScenarioStepResult{
+		StepID:      1,
+		StepName:    "login",
+		RequestID:   id,
+		StatusCode:  200,
+		RequestTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	w.Flush()
+
+	expected := `{"timestamp":"2023-01-02T03:04:05Z","step_id":1,"step_name":"login","request_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8",` +
+		`"status_code":200,"response_time":0,"bytes":0}`
+	if l := strings.TrimSpace(buf.String()); l != expected {
+		t.Errorf("Expected %v, Found: %v", expected, l)
+	}
+}
+
+func TestJsonLinesWriterRedirects(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, _ := NewOutputWriter(OutputFormatJson, buf)
+	w.WriteResult(&types.ScenarioStepResult{
+		StepID:      1,
+		StepName:    "cdn",
+		StatusCode:  200,
+		RequestTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Redirects: []types.RedirectHop{
+			{URL: "http://test.com/a", StatusCode: 301, Duration: 1500 * time.Microsecond},
+			{URL: "https://test.com/a", StatusCode: 302, Duration: 2 * time.Millisecond},
+		},
+	})
+	w.Flush()
+
+	expected := `{"timestamp":"2023-01-02T03:04:05Z","step_id":1,"step_name":"cdn","status_code":200,"response_time":0,"bytes":0,` +
+		`"redirects":[{"url":"http://test.com/a","status_code":301,"response_time":1.5},` +
+		`{"url":"https://test.com/a","status_code":302,"response_time":2}]}`
+	if l := strings.TrimSpace(buf.String()); l != expected {
+		t.Errorf("Expected %v, Found: %v", expected, l)
+	}
+}
+
+func TestJsonLinesWriterConnReused(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, _ := NewOutputWriter(OutputFormatJson, buf)
+	for _, conn := range []types.ConnState{types.ConnUnknown, types.ConnNew, types.ConnReused} {
+		w.WriteResult(&types.ScenarioStepResult{StepID: 1, StatusCode: 200, Conn: conn,
+			RequestTime: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)})
+	}
+	w.Flush()
+
+	record := `{"timestamp":"2023-01-02T03:04:05Z","step_id":1,"step_name":"","status_code":200,"response_time":0,"bytes":0`
+	expected := []string{record + "}", record + `,"conn_reused":false}`, record + `,"conn_reused":true}`}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, lines)
+	}
+}
+
+func TestJsonLinesWriterProtocol(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, _ := NewOutputWriter(OutputFormatJson, buf)
+	w.WriteResult(&types.ScenarioStepResult{StepID: 1, StatusCode: 200, Proto: "HTTP/2.0"})
+	w.Flush()
+
+	if !strings.Contains(buf.String(), `"protocol":"HTTP/2.0"`) {
+		t.Errorf("Expected %v, Found: %v", `"protocol":"HTTP/2.0"`, buf.String())
+	}
+}
+
+func TestJsonLinesWriterScheduleLag(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, _ := NewOutputWriter(OutputFormatJson, buf)
+	w.WriteResult(&types.ScenarioStepResult{StepID: 1, Duration: 250 * time.Millisecond,
+		ScheduleLag: 200 * time.Millisecond})
+	w.WriteResult(&types.ScenarioStepResult{StepID: 2, Duration: 50 * time.Millisecond})
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"response_time":250,"schedule_lag":200`) {
+		t.Errorf("Expected %v, Found: %v", `"schedule_lag":200`, lines[0])
+	}
+	if strings.Contains(lines[1], "schedule_lag") {
+		t.Errorf("Expected no schedule_lag, Found: %v", lines[1])
+	}
+}
+
+func TestCsvWriter(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	w, err := NewOutputWriter(OutputFormatCsv, buf)
+	if err != nil {
+		t.Fatalf("TestCsvWriter error: %v", err)
+	}
+	for _, r := range writerTestResults {
+		w.WriteResult(r)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("TestCsvWriter flush error: %v", err)
+	}
+
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("TestCsvWriter read error: %v", err)
+	}
+
+	expected := [][]string{
+		csvHeader,
+		{"2023-01-02T03:04:05Z", "1", "login", "200", "1.500", "42", "", ""},
+		{"2023-01-02T03:04:06Z", "2", "order", "400", "2.000", "3", "", "equals(status_code,200);has(headers.X-Id)"},
+		{"0001-01-01T00:00:00Z", "3", "logout", "0", "0.000", "0", "connectionError: connection timeout", ""},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %v, Found: %v", len(expected), len(records))
+	}
+	for i := range expected {
+		if strings.Join(records[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("Expected %v, Found: %v", expected[i], records[i])
+		}
+	}
+}
+
+func TestOutputWriterConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range SupportedOutputFormats {
+		buf := &bytes.Buffer{}
+		w, _ := NewOutputWriter(format, buf)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.WriteResult(writerTestResults[0])
+			}()
+		}
+		wg.Wait()
+		w.Flush()
+
+		lineCount := strings.Count(buf.String(), "\n")
+		expected := 100
+		if format == OutputFormatCsv {
+			expected++ // header
+		}
+		if lineCount != expected {
+			t.Errorf("Format %s, Expected %v, Found: %v", format, expected, lineCount)
+		}
+	}
+}
+
+func TestOutputWriterUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewOutputWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Errorf("Expected error for unsupported format, Found: nil")
+	}
+}
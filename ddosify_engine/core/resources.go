@@ -0,0 +1,150 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/report"
+	"go.ddosify.com/ddosify/core/scenario/requester"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+const (
+	// interval of the samples of the resource usage
+	resourceSampleInterval = time.Second
+
+	// once warned, the usage is warned again after it drops below this ratio of the file descriptor limit
+	resourceRewarnRatio = 0.7
+)
+
+// resourceMonitor samples the open file descriptors and the connections of the load generator during the test.
+// It keeps the peaks of them for the report and warns once the descriptors approach the limit of the process,
+// so running out of them doesn't end the test with a flood of cryptic errors.
+type resourceMonitor struct {
+	conns   *requester.ConnTracker
+	limiter *requester.ConnLimiter // nil if the connections are not limited
+	limit   uint64
+	openFDs func() (int, bool)
+
+	// called with the current usage once it approaches the file descriptor limit, nil if not warned
+	warn func(report.ResourceUsage)
+	// called with each sample, nil if the samples are not observed
+	observe func(report.ResourceUsage)
+
+	mu      sync.Mutex
+	peakFDs int
+	warned  bool
+
+	done     chan struct{}
+	finished chan struct{}
+}
+
+func newResourceMonitor(conns *requester.ConnTracker) *resourceMonitor {
+	limit, _ := util.FDLimit()
+	return &resourceMonitor{
+		conns:    conns,
+		limit:    limit,
+		openFDs:  util.OpenFDs,
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+}
+
+// current returns the current usage.
+func (m *resourceMonitor) current() report.ResourceUsage {
+	u := report.ResourceUsage{FDLimit: m.limit, ConnectionsPerHost: m.conns.Open()}
+	u.OpenFDs, _ = m.openFDs()
+	for _, n := range u.ConnectionsPerHost {
+		u.Connections += n
+	}
+	m.setConnWaits(&u)
+	return u
+}
+
+// sample records the current usage, it warns if the usage approaches the file descriptor limit for the first time
+// since it was warned.
+func (m *resourceMonitor) sample() {
+	u := m.current()
+	if m.observe != nil {
+		m.observe(u)
+	}
+
+	m.mu.Lock()
+	if u.OpenFDs > m.peakFDs {
+		m.peakFDs = u.OpenFDs
+	}
+	warn := false
+	if u.NearFDLimit() && !m.warned {
+		m.warned, warn = true, true
+	} else if u.FDRatio() < resourceRewarnRatio {
+		m.warned = false
+	}
+	m.mu.Unlock()
+
+	if warn && m.warn != nil {
+		m.warn(u)
+	}
+}
+
+// peak returns the peak usage of the test, each host has its own peak of the connections.
+func (m *resourceMonitor) peak() report.ResourceUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := report.ResourceUsage{OpenFDs: m.peakFDs, FDLimit: m.limit}
+	u.Connections, u.ConnectionsPerHost = m.conns.Peak()
+	m.setConnWaits(&u)
+	return u
+}
+
+// setConnWaits sets the waits of the dials at the connection limit so far.
+func (m *resourceMonitor) setConnWaits(u *report.ResourceUsage) {
+	if m.limiter == nil {
+		return
+	}
+	var d time.Duration
+	u.ConnWaits, d = m.limiter.Waits()
+	u.ConnWaitTime = d.Seconds()
+}
+
+// run samples the usage every resourceSampleInterval until stop is called.
+func (m *resourceMonitor) run() {
+	defer close(m.finished)
+	ticker := time.NewTicker(resourceSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.sample()
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// stop stops the samples of run, then takes the last sample for the peaks without warning.
+func (m *resourceMonitor) stop() {
+	close(m.done)
+	<-m.finished
+	m.warn = nil
+	m.sample()
+}
@@ -0,0 +1,62 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package core
+
+import (
+	"testing"
+
+	"go.ddosify.com/ddosify/core/report"
+	"go.ddosify.com/ddosify/core/scenario/requester"
+)
+
+func TestResourceMonitorWarn(t *testing.T) {
+	t.Parallel()
+
+	m := newResourceMonitor(requester.NewConnTracker())
+	m.limit = 100
+	var warnings []int
+	m.warn = func(u report.ResourceUsage) { warnings = append(warnings, u.OpenFDs) }
+
+	// warned once above 80%, again only after dropping below 70%
+	for _, fds := range []int{50, 80, 90, 75, 60, 85, 40} {
+		fds := fds
+		m.openFDs = func() (int, bool) { return fds, true }
+		m.sample()
+	}
+
+	expected := []int{80, 85}
+	if len(warnings) != len(expected) || warnings[0] != expected[0] || warnings[1] != expected[1] {
+		t.Errorf("Expected %v, Found: %v", expected, warnings)
+	}
+	if p := m.peak(); p.OpenFDs != 90 || p.FDLimit != 100 {
+		t.Errorf("Expected %v, Found: %v", 90, p.OpenFDs)
+	}
+}
+
+func TestResourceMonitorUnknownLimit(t *testing.T) {
+	t.Parallel()
+
+	m := newResourceMonitor(requester.NewConnTracker())
+	m.limit = 0
+	m.openFDs = func() (int, bool) { return 1 << 20, true }
+	m.warn = func(u report.ResourceUsage) { t.Errorf("Expected no warning, Found: %v", u) }
+	m.sample()
+}
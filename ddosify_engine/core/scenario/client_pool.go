@@ -2,10 +2,15 @@ package scenario
 
 import (
 	"errors"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
+	"go.ddosify.com/ddosify/core/scenario/requester"
 	"go.ddosify.com/ddosify/core/types"
 	"go.ddosify.com/ddosify/core/util"
 )
@@ -19,7 +24,8 @@ type ClientCloseMethod func(*http.Client)
 // greater than zero to fill the pool. A zero initialCap doesn't fill the Pool
 // until a new Get() is called. During a Get(), If there is no new client
 // available in the pool, a new client will be created via the Factory()
-// method.
+// method. So maxCap limits only the idle clients kept by Put(), the live
+// clients can outnumber it. GetContext() waits for a client at maxCap instead.
 func NewClientPool(initialCap, maxCap int, engineMode string, factory ClientFactoryMethod, close ClientCloseMethod) (*util.Pool[*http.Client], error) {
 	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
 		return nil, errors.New("invalid capacity settings")
@@ -32,22 +38,217 @@ func NewClientPool(initialCap, maxCap int, engineMode string, factory ClientFact
 		AfterPut: func(client *http.Client) {
 			// if engine is in repeated mode, notify jar that cookies are already set
 			// to avoid setting them again in the next iteration
-			if engineMode == types.EngineModeRepeatedUser && client.Jar != nil && !client.Jar.(*cookieJarRepeated).firstIterPassed {
-				client.Jar.(*cookieJarRepeated).firstIterPassed = true
+			// jars of the custom client factories are left as they are
+			if jar, ok := client.Jar.(*cookieJarRepeated); ok && engineMode == types.EngineModeRepeatedUser && !jar.firstIterPassed {
+				jar.firstIterPassed = true
 			}
 		},
 	}
 
-	// create initial clients, if something goes wrong,
-	// just close the pool error out.
-	for i := 0; i < initialCap; i++ {
-		client := pool.Factory()
-		pool.Items <- client
+	// create initial clients
+	pool.Fill(initialCap)
+
+	return pool, nil
+}
+
+// NewAdaptiveClientPool returns a new pool like NewClientPool, but the number of the idle clients it keeps is sized by
+// the demand between minCap and maxCap, see util.AdaptiveCap. It is filled with minCap clients.
+func NewAdaptiveClientPool(minCap, maxCap int, engineMode string, factory ClientFactoryMethod, close ClientCloseMethod) (*util.Pool[*http.Client], error) {
+	pool, err := NewClientPool(minCap, maxCap, engineMode, factory, close)
+	if err != nil {
+		return nil, err
+	}
+	pool.Adaptive = &util.AdaptiveCap{Min: minCap}
+	return pool, nil
+}
+
+// NewClientPoolWithTTL returns a new pool like NewClientPool, but clients older than the given ttl are
+// not handed out anymore. During a Get(), expired clients are closed via close() and dropped, a new client is
+// created via the factory instead.
+func NewClientPoolWithTTL(initialCap, maxCap int, ttl time.Duration, engineMode string, factory ClientFactoryMethod, close ClientCloseMethod) (*util.Pool[*http.Client], error) {
+	if ttl <= 0 {
+		return nil, errors.New("ttl should be greater than zero")
+	}
+
+	var createdAt sync.Map // *http.Client -> time.Time
+	ttlFactory := func() *http.Client {
+		c := factory()
+		createdAt.Store(c, time.Now())
+		return c
+	}
+	ttlClose := func(c *http.Client) {
+		createdAt.Delete(c)
+		close(c)
+	}
+
+	pool, err := NewClientPool(initialCap, maxCap, engineMode, ttlFactory, ttlClose)
+	if err != nil {
+		return nil, err
 	}
 
+	pool.Valid = func(c *http.Client) bool {
+		t, ok := createdAt.Load(c)
+		return !ok || time.Since(t.(time.Time)) < ttl
+	}
 	return pool, nil
 }
 
+// setIdleHealthCheck sets the HealthCheck of the pool to the given probe for the clients idle in the pool for
+// longer than the interval. Get() closes a client failing the probe and takes the next one, or creates a new
+// client. The clients idle for a shorter time and the clients that were never put back are not probed.
+func setIdleHealthCheck(pool *util.Pool[*http.Client], interval time.Duration, probe func(*http.Client) bool) {
+	var putAt sync.Map // *http.Client -> time.Time
+	afterPut, close := pool.AfterPut, pool.Close
+	pool.AfterPut = func(c *http.Client) {
+		if afterPut != nil {
+			afterPut(c)
+		}
+		putAt.Store(c, time.Now())
+	}
+	pool.Close = func(c *http.Client) {
+		putAt.Delete(c)
+		close(c)
+	}
+	pool.HealthCheck = func(c *http.Client) bool {
+		t, ok := putAt.Load(c)
+		return !ok || time.Since(t.(time.Time)) < interval || probe(c)
+	}
+}
+
+// HostClientPool keeps a separate client pool per target host, so that keep-alive connections
+// of a client are always reused against the same host.
+type HostClientPool struct {
+	pools map[string]*util.Pool[*http.Client]
+	mu    sync.Mutex
+
+	initialCap int
+	maxCap     int
+	engineMode string
+	factory    ClientFactoryMethod
+	close      ClientCloseMethod
+
+	// TTL of the clients of the sub-pools, see NewClientPoolWithTTL. Clients don't expire if zero.
+	TTL time.Duration
+
+	// Configure is called with each sub-pool and the key of its host once the sub-pool is created, like to set
+	// its SingleUse. Optional.
+	Configure func(host string, p *util.Pool[*http.Client])
+}
+
+// NewHostClientPool returns a new HostClientPool. Capacity settings are applied to each sub-pool,
+// sub-pools are created lazily the first time their host is seen.
+func NewHostClientPool(initialCap, maxCap int, engineMode string, factory ClientFactoryMethod, close ClientCloseMethod) (*HostClientPool, error) {
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	return &HostClientPool{
+		pools:      make(map[string]*util.Pool[*http.Client]),
+		initialCap: initialCap,
+		maxCap:     maxCap,
+		engineMode: engineMode,
+		factory:    factory,
+		close:      close,
+	}, nil
+}
+
+// GetForHost returns a client from the pool of the given host. Host can be given as a full url.
+func (h *HostClientPool) GetForHost(host string) *http.Client {
+	return h.poolOf(host).Get()
+}
+
+// PutForHost puts the client back to the pool of the given host.
+func (h *HostClientPool) PutForHost(host string, client *http.Client) error {
+	return h.poolOf(host).Put(client)
+}
+
+// PutBadForHost closes the client instead of putting it back to the pool of the given host.
+func (h *HostClientPool) PutBadForHost(host string, client *http.Client) {
+	h.poolOf(host).PutBad(client)
+}
+
+// DoneAll drains and closes every sub-pool.
+func (h *HostClientPool) DoneAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, p := range h.pools {
+		p.Done()
+		delete(h.pools, key)
+	}
+}
+
+// ResetAll resets every sub-pool, see util.Pool.Reset. If factory is not nil, it replaces the factory of the
+// sub-pools, including the ones created later.
+func (h *HostClientPool) ResetAll(factory ClientFactoryMethod) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if factory != nil {
+		h.factory = factory
+	}
+	for _, p := range h.pools {
+		p.Reset(factory)
+	}
+}
+
+// Stats returns the statistics of the sub-pools by their hosts, keyed like scheme://host:port.
+func (h *HostClientPool) Stats() map[string]util.PoolStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := make(map[string]util.PoolStats, len(h.pools))
+	for key, p := range h.pools {
+		stats[key] = p.Stats()
+	}
+	return stats
+}
+
+func (h *HostClientPool) poolOf(host string) *util.Pool[*http.Client] {
+	key := hostKey(host)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.pools[key]
+	if !ok {
+		// capacity settings are already validated in NewHostClientPool
+		if h.TTL > 0 {
+			p, _ = NewClientPoolWithTTL(h.initialCap, h.maxCap, h.TTL, h.engineMode, h.factory, h.close)
+		} else {
+			p, _ = NewClientPool(h.initialCap, h.maxCap, h.engineMode, h.factory, h.close)
+		}
+		if h.Configure != nil {
+			h.Configure(key, p)
+		}
+		h.pools[key] = p
+	}
+	return p
+}
+
+// hostKey normalizes the given target to scheme://host:port, default ports are filled by scheme.
+func hostKey(target string) string {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+
+	return scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
 type cookieJarRepeated struct {
 	defaultCookieJar *cookiejar.Jar
 	firstIterPassed  bool
@@ -85,30 +286,59 @@ var defaultClose = func(c *http.Client) {
 
 // createClientFactoryMethod returns a Factory function based on the engine mode.
 func createClientFactoryMethod(mode string, opts ...func(http.CookieJar)) ClientFactoryMethod {
+	return func() *http.Client {
+		jar, err := createCookieJar(mode, opts...)
+		if err != nil {
+			return defaultFactory() // no cookie jar, use default factory
+		}
+		return &http.Client{Jar: jar}
+	}
+}
+
+// createCookieJar returns an empty cookie jar based on the engine mode, opts are applied to the new jar.
+func createCookieJar(mode string, opts ...func(http.CookieJar)) (http.CookieJar, error) {
+	var jar http.CookieJar
+	var err error
 	if mode == types.EngineModeRepeatedUser {
-		return func() *http.Client {
-			jar, err := NewCookieJarRepeated()
-			if err != nil {
-				return defaultFactory() // no cookie jar, use default factory
-			}
+		jar, err = NewCookieJarRepeated()
+	} else { // distinct users mode
+		jar, err = cookiejar.New(nil)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-			for _, opt := range opts {
-				opt(jar)
-			}
-			return &http.Client{Jar: jar}
-		}
+	for _, opt := range opts {
+		opt(jar)
 	}
+	return jar, nil
+}
 
-	// distinct users mode
+// withCookieJar wraps the given factory so that the created clients without a jar get a jar created by newJar.
+func withCookieJar(factory ClientFactoryMethod, newJar func() (http.CookieJar, error)) ClientFactoryMethod {
 	return func() *http.Client {
-		jar, err := cookiejar.New(nil)
-		if err != nil {
-			return defaultFactory() // no cookie jar, use default factory
+		c := factory()
+		if c.Jar == nil {
+			if jar, err := newJar(); err == nil {
+				c.Jar = jar
+			}
 		}
+		return c
+	}
+}
 
-		for _, opt := range opts {
-			opt(jar)
+// withH2C wraps the given factory so that the created clients speak HTTP/2 cleartext with prior knowledge.
+// Jar and other settings of the wrapped factory are kept. Connections are dialed by dial if it is not nil, and
+// the idle ones are pinged at the keepAlivePing interval if it is positive.
+func withH2C(factory ClientFactoryMethod, dial requester.DialContextFunc,
+	keepAlivePing time.Duration) ClientFactoryMethod {
+	return func() *http.Client {
+		c := factory()
+		tr := requester.NewH2CTransport(dial)
+		if keepAlivePing > 0 {
+			tr.ReadIdleTimeout = keepAlivePing
 		}
-		return &http.Client{Jar: jar}
+		c.Transport = tr
+		return c
 	}
 }
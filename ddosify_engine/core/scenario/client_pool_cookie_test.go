@@ -1,11 +1,14 @@
 package scenario
 
 import (
+	"context"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"testing"
+	"time"
 
 	"go.ddosify.com/ddosify/core/types"
 )
@@ -252,3 +255,58 @@ func TestPutInitialCookiesInJarFactory(t *testing.T) {
 		t.Errorf("TestPutInitialCookiesInJarFactory, expected cookie value 'test', got %s", cookies[0].Value)
 	}
 }
+
+func TestCookieJarIsolatedPerIterationInDistinctMode(t *testing.T) {
+	t.Parallel()
+
+	cookieName := "session"
+	loginCallCount := 0
+	var sessionCookies []string
+
+	loginHandler := func(w http.ResponseWriter, r *http.Request) {
+		// only the first user logs in
+		if loginCallCount == 0 {
+			http.SetCookie(w, &http.Cookie{Name: cookieName, Value: "user1"})
+		}
+		loginCallCount++
+	}
+	orderHandler := func(w http.ResponseWriter, r *http.Request) {
+		val := ""
+		if ck, err := r.Cookie(cookieName); err == nil {
+			val = ck.Value
+		}
+		sessionCookies = append(sessionCookies, val)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", loginHandler)
+	mux.HandleFunc("/order", orderHandler)
+	host := httptest.NewServer(mux)
+	defer host.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: host.URL + "/login", Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: host.URL + "/order", Timeout: types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	err := service.Init(context.TODO(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode:             types.EngineModeDistinctUser,
+		IterationCount:         2,
+		MaxConcurrentIterCount: 1, // same pooled client is used in both iterations
+	})
+	if err != nil {
+		t.Fatalf("TestCookieJarIsolatedPerIterationInDistinctMode init error: %v", err)
+	}
+	defer service.Done()
+
+	service.Do(nil, time.Now())
+	service.Do(nil, time.Now())
+
+	expected := []string{"user1", ""}
+	if !reflect.DeepEqual(sessionCookies, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, sessionCookies)
+	}
+}
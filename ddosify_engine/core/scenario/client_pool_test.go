@@ -0,0 +1,238 @@
+package scenario
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+	"golang.org/x/net/http2"
+)
+
+func TestHostKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"https://test.com/path?q=1", "https://test.com:443"},
+		{"http://test.com", "http://test.com:80"},
+		{"HTTP://Test.com:8080/a", "http://test.com:8080"},
+		{"test.com:9090", "http://test.com:9090"},
+		{"http://[::1]:80", "http://[::1]:80"},
+	}
+
+	for _, tc := range tests {
+		if got := hostKey(tc.target); got != tc.expected {
+			t.Errorf("hostKey(%s) Expected %s, Found: %s", tc.target, tc.expected, got)
+		}
+	}
+}
+
+func TestHostClientPool(t *testing.T) {
+	t.Parallel()
+
+	factoryCalls := 0
+	factory := func() *http.Client {
+		factoryCalls++
+		return &http.Client{}
+	}
+
+	hp, err := NewHostClientPool(1, 2, types.EngineModeDistinctUser, factory, defaultClose)
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	if factoryCalls != 0 {
+		t.Errorf("Expected sub-pools to be created lazily, Found %d factory calls", factoryCalls)
+	}
+
+	a := hp.GetForHost("https://a.com/login")
+	hp.PutForHost("https://a.com/logout", a)
+	if got := hp.GetForHost("https://a.com:443"); got != a {
+		t.Errorf("Expected the client of the same host to be reused")
+	}
+
+	b := hp.GetForHost("https://b.com")
+	if b == a {
+		t.Errorf("Expected different hosts to use different clients")
+	}
+
+	if len(hp.pools) != 2 {
+		t.Errorf("Expected 2 sub-pools, Found: %d", len(hp.pools))
+	}
+
+	hp.DoneAll()
+	if len(hp.pools) != 0 {
+		t.Errorf("Expected all sub-pools to be drained, Found: %d", len(hp.pools))
+	}
+
+	if _, err := NewHostClientPool(2, 1, types.EngineModeDistinctUser, factory, defaultClose); err == nil {
+		t.Errorf("Expected invalid capacity error")
+	}
+}
+
+func TestHostClientPoolResetAll(t *testing.T) {
+	t.Parallel()
+
+	hp, err := NewHostClientPool(0, 2, types.EngineModeDistinctUser, defaultFactory, defaultClose)
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	a := hp.GetForHost("https://a.com")
+	hp.PutForHost("https://a.com", a)
+
+	timeout := 3 * time.Second
+	hp.ResetAll(func() *http.Client { return &http.Client{Timeout: timeout} })
+
+	// idle clients are closed, the new factory creates the clients of the existing and the new hosts
+	if c := hp.GetForHost("https://a.com"); c == a || c.Timeout != timeout {
+		t.Errorf("Expected %v, Found: %v", "a client of the new factory", c)
+	}
+	if c := hp.GetForHost("https://b.com"); c.Timeout != timeout {
+		t.Errorf("Expected %v, Found: %v", timeout, c.Timeout)
+	}
+	hp.DoneAll()
+}
+
+func TestClientPoolWithTTL(t *testing.T) {
+	t.Parallel()
+
+	closed := 0
+	closeFunc := func(c *http.Client) {
+		closed++
+		c.CloseIdleConnections()
+	}
+
+	ttl := 50 * time.Millisecond
+	pool, err := NewClientPoolWithTTL(1, 1, ttl, types.EngineModeDistinctUser, defaultFactory, closeFunc)
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+
+	c := pool.Get()
+	pool.Put(c)
+	if got := pool.Get(); got != c {
+		t.Errorf("Expected fresh client to be reused")
+	}
+	pool.Put(c)
+
+	time.Sleep(ttl)
+	if got := pool.Get(); got == c {
+		t.Errorf("Expected expired client to be rotated")
+	}
+	if closed != 1 {
+		t.Errorf("Expected expired client to be closed, Found %d close calls", closed)
+	}
+
+	if _, err := NewClientPoolWithTTL(1, 1, 0, types.EngineModeDistinctUser, defaultFactory, closeFunc); err == nil {
+		t.Errorf("Expected error for zero ttl")
+	}
+}
+
+func TestSetIdleHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	closed := 0
+	closeFunc := func(c *http.Client) {
+		closed++
+		c.CloseIdleConnections()
+	}
+	pool, err := NewClientPool(1, 1, types.EngineModeRepeatedUser, defaultFactory, closeFunc)
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	healthy := true
+	probes := 0
+	interval := 50 * time.Millisecond
+	setIdleHealthCheck(pool, interval, func(*http.Client) bool {
+		probes++
+		return healthy
+	})
+
+	// fresh clients and the clients idle for a short time are not probed
+	c := pool.Get()
+	pool.Put(c)
+	if got := pool.Get(); got != c || probes != 0 {
+		t.Errorf("Expected the client to be reused without a probe, Found %d probes", probes)
+	}
+	pool.Put(c)
+
+	time.Sleep(interval)
+	if got := pool.Get(); got != c || probes != 1 {
+		t.Errorf("Expected the healthy client to be reused after a probe, Found %d probes", probes)
+	}
+	pool.Put(c)
+
+	time.Sleep(interval)
+	healthy = false
+	if got := pool.Get(); got == c || probes != 2 {
+		t.Errorf("Expected the unhealthy client to be replaced, Found %d probes", probes)
+	}
+	if closed != 1 {
+		t.Errorf("Expected the unhealthy client to be closed, Found %d close calls", closed)
+	}
+}
+
+func TestWithH2C(t *testing.T) {
+	t.Parallel()
+
+	factory := withH2C(createClientFactoryMethod(types.EngineModeDistinctUser), nil, 0)
+	c := factory()
+
+	if _, ok := c.Transport.(*http2.Transport); !ok {
+		t.Errorf("Expected h2c transport, Found: %T", c.Transport)
+	}
+	if c.Jar == nil {
+		t.Errorf("Expected jar of the wrapped factory to be kept")
+	}
+
+	c = withH2C(createClientFactoryMethod(types.EngineModeDistinctUser), nil, 15*time.Second)()
+	if tr := c.Transport.(*http2.Transport); tr.ReadIdleTimeout != 15*time.Second {
+		t.Errorf("Expected %v, Found: %v", 15*time.Second, tr.ReadIdleTimeout)
+	}
+}
+
+func TestClientPoolRepeatedUserCustomJar(t *testing.T) {
+	t.Parallel()
+
+	jar, _ := cookiejar.New(nil)
+	newJar := func() (http.CookieJar, error) { return createCookieJar(types.EngineModeRepeatedUser) }
+	factory := withCookieJar(func() *http.Client { return &http.Client{Jar: jar} }, newJar)
+	pool, err := NewClientPool(1, 1, types.EngineModeRepeatedUser, factory, defaultClose)
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	defer pool.Done()
+
+	// jar of the factory is kept, it is not a repeated user jar
+	c := pool.Get()
+	if c.Jar != jar {
+		t.Errorf("Expected the jar of the factory to be kept")
+	}
+	pool.Put(c)
+
+	c = withCookieJar(func() *http.Client { return &http.Client{} }, newJar)()
+	if _, ok := c.Jar.(*cookieJarRepeated); !ok {
+		t.Errorf("Expected a repeated user jar, Found: %T", c.Jar)
+	}
+}
+
+func TestNewAdaptiveClientPool(t *testing.T) {
+	t.Parallel()
+
+	pool, err := NewAdaptiveClientPool(2, 10, types.EngineModeDistinctUser, defaultFactory, defaultClose)
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	defer pool.Done()
+
+	if s := pool.Stats(); s.Idle != 2 || s.IdleCap != 2 || s.MaxCap != 10 {
+		t.Errorf("Expected %d idle, %d idle cap and %d max cap, Found: %+v", 2, 2, 10, s)
+	}
+
+	if _, err := NewAdaptiveClientPool(3, 2, types.EngineModeDistinctUser, defaultFactory, defaultClose); err == nil {
+		t.Errorf("Expected error for the min capacity greater than the max capacity")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/evaluator"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/lexer"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/parser"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// stepCondition decides whether a step is sent in an iteration, by the If expression of the step.
+type stepCondition struct {
+	expr     string
+	stepID   uint16
+	stepName string
+}
+
+// newStepCondition returns nil if the step has no condition.
+func newStepCondition(si types.ScenarioStep) (*stepCondition, error) {
+	if si.If == "" {
+		return nil, nil
+	}
+	if err := parseExpression(si.If); err != nil {
+		return nil, fmt.Errorf("invalid condition of step %d: %v", si.ID, err)
+	}
+	return &stepCondition{expr: si.If, stepID: si.ID, stepName: si.Name}, nil
+}
+
+// parseExpression returns the syntax errors of the assertion expression.
+func parseExpression(expr string) error {
+	p := parser.New(lexer.New(expr))
+	p.ParseExpressionStatement()
+	if len(p.Errors()) > 0 {
+		return errors.New(strings.Join(p.Errors(), ","))
+	}
+	return nil
+}
+
+// met evaluates the condition against the result of the previous step and the envs of the iteration.
+// Evaluation errors, like an undefined variable, are treated as false.
+func (c *stepCondition) met(prev *types.ScenarioStepResult, envs map[string]interface{}) bool {
+	env := &evaluator.AssertEnv{Variables: envs}
+	if prev != nil {
+		env.StatusCode = int64(prev.StatusCode)
+		env.ResponseSize = int64(len(prev.RespBody))
+		env.ResponseTime = prev.Duration.Milliseconds()
+		env.Body = string(prev.RespBody)
+		env.Headers = prev.RespHeaders
+		env.Trailers = prev.RespTrailers
+	}
+	ok, _ := assertion.Assert(c.expr, env)
+	return ok
+}
+
+// skipped is the result of the step when its condition is not met, no request is sent for it.
+func (c *stepCondition) skipped() *types.ScenarioStepResult {
+	return &types.ScenarioStepResult{
+		StepID:      c.stepID,
+		StepName:    c.stepName,
+		RequestID:   uuid.New(),
+		RequestTime: time.Now(),
+		Skipped:     true,
+	}
+}
@@ -14,8 +14,8 @@ import (
 )
 
 func validateConf(conf types.CsvConf) error {
-	if !(conf.Order == "random" || conf.Order == "sequential") {
-		return fmt.Errorf("unsupported order %s, should be random|sequential", conf.Order)
+	if !(conf.Order == "random" || conf.Order == "sequential" || conf.Order == "round-robin") {
+		return fmt.Errorf("unsupported order %s, should be random|sequential|round-robin", conf.Order)
 	}
 	return nil
 }
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// DataFeeder hands out the rows of a CSV data to the iterations. It is safe for concurrent use,
+// in sequential order every row is handed out exactly once per round.
+type DataFeeder struct {
+	rows     []map[string]interface{}
+	random   bool
+	circular bool
+
+	// index of the next row in sequential order
+	next uint64
+}
+
+// NewDataFeeder returns a feeder for the given rows and order settings.
+func NewDataFeeder(d types.CsvData) *DataFeeder {
+	return &DataFeeder{
+		rows:     d.Rows,
+		random:   d.Random,
+		circular: d.Circular,
+	}
+}
+
+// Next returns the row for the next iteration, random rows are picked by rnd. It returns false if there is
+// no row to hand out, that is the case when the rows run out in non-circular sequential order.
+func (f *DataFeeder) Next(rnd *rand.Rand) (map[string]interface{}, bool) {
+	lenRows := uint64(len(f.rows))
+	if lenRows == 0 {
+		return nil, false
+	}
+
+	if f.random {
+		return f.rows[rnd.Int63n(int64(lenRows))], true
+	}
+
+	i := atomic.AddUint64(&f.next, 1) - 1
+	if i >= lenRows {
+		if !f.circular {
+			return nil, false
+		}
+		i %= lenRows
+	}
+	return f.rows[i], true
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func testRows(n int) []map[string]interface{} {
+	rows := make([]map[string]interface{}, n)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i}
+	}
+	return rows
+}
+
+func TestDataFeederSequentialDistinctRows(t *testing.T) {
+	t.Parallel()
+	rowCount := 1000
+	f := NewDataFeeder(types.CsvData{Rows: testRows(rowCount)})
+
+	var mu sync.Mutex
+	seen := make(map[int]int, rowCount)
+	var wg sync.WaitGroup
+	for w := 0; w < 10; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				row, ok := f.Next(nil)
+				if !ok {
+					return
+				}
+				mu.Lock()
+				seen[row["id"].(int)]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != rowCount {
+		t.Errorf("Expected %d distinct rows, Found: %d", rowCount, len(seen))
+	}
+	for id, count := range seen {
+		if count != 1 {
+			t.Errorf("Expected row %d to be handed out once, Found: %d", id, count)
+		}
+	}
+}
+
+func TestDataFeederCircular(t *testing.T) {
+	t.Parallel()
+	f := NewDataFeeder(types.CsvData{Rows: testRows(3), Circular: true})
+
+	expected := []int{0, 1, 2, 0, 1}
+	for _, e := range expected {
+		row, ok := f.Next(nil)
+		if !ok || row["id"] != e {
+			t.Errorf("Expected %v, Found: %v", e, row)
+		}
+	}
+}
+
+func TestDataFeederRandomAndEmpty(t *testing.T) {
+	t.Parallel()
+	f := NewDataFeeder(types.CsvData{Rows: testRows(3), Random: true})
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		if _, ok := f.Next(rnd); !ok {
+			t.Errorf("Expected random order to never run out")
+		}
+	}
+
+	f = NewDataFeeder(types.CsvData{})
+	if _, ok := f.Next(rnd); ok {
+		t.Errorf("Expected no row from empty data")
+	}
+}
+
+func TestDataFeederRandomSeed(t *testing.T) {
+	t.Parallel()
+	f := NewDataFeeder(types.CsvData{Rows: testRows(100), Random: true})
+
+	ids := func(seed int64) []interface{} {
+		rnd := rand.New(rand.NewSource(seed))
+		ids := make([]interface{}, 20)
+		for i := range ids {
+			row, _ := f.Next(rnd)
+			ids[i] = row["id"]
+		}
+		return ids
+	}
+
+	first, second := ids(7), ids(7)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected %v, Found: %v", first, second)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"fmt"
+	"sync"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// stepGraph holds the dependencies of the scenario steps by their indexes, which are the indexes of their
+// requesters too. See types.Scenario.HasDependencies.
+type stepGraph struct {
+	deps       [][]int // dependencies of each step, in the order of its DependsOn
+	dependents [][]int // steps depending on each step
+	roots      []int   // steps without dependencies, they start with the iteration
+
+	// names of the random streams of the steps, each running step has its own stream
+	streams []string
+}
+
+func newStepGraph(steps []types.ScenarioStep) *stepGraph {
+	indexes := make(map[uint16]int, len(steps))
+	for i, si := range steps {
+		indexes[si.ID] = i
+	}
+
+	g := &stepGraph{
+		deps:       make([][]int, len(steps)),
+		dependents: make([][]int, len(steps)),
+		streams:    make([]string, len(steps)),
+	}
+	for i, si := range steps {
+		for _, id := range si.DependsOn {
+			d := indexes[id]
+			g.deps[i] = append(g.deps[i], d)
+			g.dependents[d] = append(g.dependents[d], i)
+		}
+		if len(si.DependsOn) == 0 {
+			g.roots = append(g.roots, i)
+		}
+		g.streams[i] = fmt.Sprintf("iteration.step.%d", si.ID)
+	}
+	return g
+}
+
+// prev returns the result that the condition of the step is evaluated against, which is the result of its last
+// dependency that is sent. Nil for the steps without a sent dependency.
+func (g *stepGraph) prev(i int, results []*types.ScenarioStepResult) *types.ScenarioStepResult {
+	for j := len(g.deps[i]) - 1; j >= 0; j-- {
+		if res := results[g.deps[i][j]]; res != nil && !res.Skipped {
+			return res
+		}
+	}
+	return nil
+}
+
+// stepDone is the result of a step run by doGraph, by the index of the step.
+type stepDone struct {
+	i      int
+	res    *types.ScenarioStepResult
+	copies []*types.ScenarioStepResult // results of the other parallel copies of the step, see sendCopies
+}
+
+// doGraph runs the steps of the iteration by their dependencies, a step starts once all of its dependencies are
+// done and the independent steps run concurrently. Conditions, captures and results are handled in the calling
+// goroutine, the running steps get the variables of the scope when they start. HTTP steps of the user modes share
+// the client of the virtual user, so they are sent one at a time. Results are reported in the order of the steps.
+func (s *ScenarioService) doGraph(requesters []scenarioItemRequester, iter uint64, scope *iterationScope,
+	clients *stepClients, response *types.ScenarioResult) (err *types.RequestError, connFailed bool) {
+	results := make([]*types.ScenarioStepResult, len(requesters))
+	copies := make([][]*types.ScenarioStepResult, len(requesters))
+	waiting := make([]int, len(requesters))
+	for i, deps := range s.graph.deps {
+		waiting[i] = len(deps)
+	}
+	ready := append([]int(nil), s.graph.roots...)
+	release := func(i int) {
+		for _, d := range s.graph.dependents[i] {
+			waiting[d]--
+			if waiting[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	var clientMu sync.Mutex
+	done := make(chan stepDone, len(requesters))
+	running := 0
+	var stopErr *types.RequestError // reported if no step is done, like in the sequential run
+	for {
+		for len(ready) > 0 && stopErr == nil {
+			i := ready[0]
+			ready = ready[1:]
+			if s.ctx.Err() != nil {
+				// stopped, don't start the remaining steps. Steps completed until now are reported.
+				stopErr = &types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled}
+				break
+			}
+
+			sr := requesters[i]
+			if sr.condition != nil && !sr.condition.met(s.graph.prev(i, results), scope.envs()) {
+				skipped := sr.condition.skipped()
+				skipped.Tags = sr.tags
+				results[i] = skipped
+				release(i)
+				continue
+			}
+
+			vars := scope.envs()
+			rnd := s.rng.Stream(s.graph.streams[i], iter)
+			running++
+			go func(i int) {
+				client := clients.of(sr)
+				if client != nil && sr.requester.Type() == "HTTP" {
+					clientMu.Lock()
+				}
+				res, copies := s.sendCopies(sr, rnd, client, withUserAgent(vars))
+				if client != nil && sr.requester.Type() == "HTTP" {
+					clientMu.Unlock()
+				}
+				if sr.sleeper != nil && res.Err.Type != types.ErrorIntented {
+					sr.sleeper.sleep(s.ctx, rnd)
+				}
+				done <- stepDone{i: i, res: res, copies: copies}
+			}(i)
+		}
+		if running == 0 {
+			break
+		}
+
+		d := <-done
+		running--
+		sr, res := requesters[d.i], d.res
+		copies[d.i] = d.copies
+		if copiesConnFailed(sr, d.copies) {
+			connFailed = true
+			clients.failed(sr)
+		}
+		if res.Err.Reason == types.ReasonMaxRequests {
+			// the requests of the run are sent, the steps completed until now are reported
+			stopErr = &res.Err
+			continue
+		}
+		res.ErrCategory = res.Categorize()
+		res.Tags = sr.tags
+		if res.Err.ConnFailed() && sr.requester.Type() == "HTTP" {
+			connFailed = true
+			clients.failed(sr)
+		}
+		if res.Err.Type == types.ErrorProxy || res.Err.Type == types.ErrorIntented {
+			err = &res.Err
+			if res.Err.Type == types.ErrorIntented {
+				// don't start the remaining steps, wait for the running ones
+				stopErr = &res.Err
+				continue
+			}
+		}
+		results[d.i] = res
+		scope.setAll(res.ExtractedEnvs)
+		release(d.i)
+	}
+
+	for i, res := range results {
+		if res != nil {
+			response.StepResults = append(response.StepResults, res)
+		}
+		response.StepResults = append(response.StepResults, copies[i]...)
+	}
+	if err == nil && stopErr != nil && len(response.StepResults) == 0 {
+		err = stopErr
+	}
+	return
+}
@@ -0,0 +1,168 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// Setup runs the setup steps of the scenario once in order, it should be called after Init and before the
+// iterations. Captures of the setup steps are added to the global envs of the iterations. Returns the error of
+// the first failed step, the remaining ones are not sent.
+func (s *ScenarioService) Setup(ctx context.Context, proxy *url.URL) error {
+	if len(s.scenario.Setup) == 0 {
+		return nil
+	}
+	captures, err := s.runOnce(ctx, "setup", s.scenario.Setup, proxy, false)
+	if err != nil {
+		return err
+	}
+	envs := make(map[string]interface{}, len(s.scenario.Envs)+len(captures))
+	for k, v := range s.scenario.Envs {
+		envs[k] = v
+	}
+	for k, v := range captures {
+		envs[k] = v
+	}
+	s.scenario.Envs = envs
+	return nil
+}
+
+// Teardown runs the teardown steps of the scenario once in order, it should be called after the iterations.
+// They see the captures of the setup steps. All the steps are sent even if some of them fail, the error of the
+// first failed step is returned.
+func (s *ScenarioService) Teardown(ctx context.Context, proxy *url.URL) error {
+	if len(s.scenario.Teardown) == 0 {
+		return nil
+	}
+	_, err := s.runOnce(ctx, "teardown", s.scenario.Teardown, proxy, true)
+	return err
+}
+
+// runOnce sends the steps once in order, outside of the iterations. Their requesters are created for the run and
+// their requests live as long as ctx. Requests are not limited by the rps limit and the max requests of the run.
+// Stops at the first failed step unless all is true. Returns the captures of the steps.
+func (s *ScenarioService) runOnce(ctx context.Context, kind string, steps []types.ScenarioStep, proxy *url.URL,
+	all bool) (captures map[string]interface{}, err error) {
+	// the steps run as a single user, the user modes give it a client of its own
+	var client *http.Client
+	if s.newOnceClient != nil {
+		client = s.newOnceClient()
+		defer client.CloseIdleConnections()
+	}
+	rnd := s.rng.Stream(kind, 0)
+	scope := newGlobalScope(s.scenario.Envs).newIterationScope(s.ei)
+	if s.userAgents != nil && s.userAgents.perUser {
+		scope.set(userAgentEnv, s.userAgents.ofUser(s.rng, 0))
+	}
+
+	captures = make(map[string]interface{})
+	var prev *types.ScenarioStepResult // result of the last sent step
+	for _, st := range steps {
+		if ctx.Err() != nil {
+			if err == nil {
+				err = fmt.Errorf("%s is stopped before the step %d", kind, st.ID)
+			}
+			return
+		}
+
+		res, e := s.sendOnce(ctx, st, proxy, rnd, client, scope, prev)
+		if e == nil && res == nil {
+			// condition of the step is not met
+			continue
+		}
+		if e == nil {
+			e = onceFailure(res)
+		}
+		if e != nil {
+			if err == nil {
+				err = fmt.Errorf("%s step %d failed: %v", kind, st.ID, e)
+			}
+			if !all {
+				return
+			}
+		}
+		if res != nil {
+			prev = res
+			scope.setAll(res.ExtractedEnvs)
+			for k, v := range res.ExtractedEnvs {
+				captures[k] = v
+			}
+		}
+	}
+	return
+}
+
+// sendOnce sends the step by its retry policy with a requester created for it, the result is nil if the
+// condition of the step is not met.
+func (s *ScenarioService) sendOnce(ctx context.Context, st types.ScenarioStep, proxy *url.URL, rnd *rand.Rand,
+	client *http.Client, scope *iterationScope, prev *types.ScenarioStepResult) (*types.ScenarioStepResult, error) {
+	sr, err := s.newItemRequester(ctx, st, proxy)
+	if err != nil {
+		return nil, err
+	}
+	defer sr.done()
+
+	if sr.condition != nil && !sr.condition.met(prev, scope.envs()) {
+		return nil, nil
+	}
+	send := func() *types.ScenarioStepResult {
+		if sr.userAgent && !s.userAgents.perUser {
+			scope.set(userAgentEnv, s.userAgents.ofRequest(rnd))
+		}
+		if sr.targets != nil {
+			return sr.targets.send(rnd, client, scope.envs())
+		}
+		return sendStep(sr.requester, client, scope.envs())
+	}
+	if sr.retry != nil {
+		return sr.retry.do(ctx, rnd, send), nil
+	}
+	return send(), nil
+}
+
+// onceFailure returns the reason of the failed setup or teardown step, nil if the step succeeded. Besides the
+// failures of the iterations, a failed capture fails the step since the following steps depend on it.
+func onceFailure(res *types.ScenarioStepResult) error {
+	switch {
+	case res.Err.Type != "":
+		return &res.Err
+	case len(res.FailedAssertions) > 0:
+		return fmt.Errorf("assertion %s failed", res.FailedAssertions[0].Rule)
+	case len(res.SchemaErrors) > 0:
+		return fmt.Errorf("response doesn't conform to the schema: %s", res.SchemaErrors[0])
+	case len(res.FailedCaptures) > 0:
+		names := make([]string, 0, len(res.FailedCaptures))
+		for name := range res.FailedCaptures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("capture %s failed: %s", names[0], res.FailedCaptures[names[0]])
+	}
+	return nil
+}
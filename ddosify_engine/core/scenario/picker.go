@@ -0,0 +1,53 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// weightedPicker picks an index with the probability of its weight / sum of all the weights.
+// It is safe for concurrent use, randomness comes from the stream of the caller.
+type weightedPicker struct {
+	// cumulative sums of the weights
+	cumWeights []int
+	total      int
+}
+
+// newWeightedPicker creates a picker for the given positive weights.
+func newWeightedPicker(weights []int) *weightedPicker {
+	wp := &weightedPicker{
+		cumWeights: make([]int, len(weights)),
+	}
+	for i, w := range weights {
+		wp.total += w
+		wp.cumWeights[i] = wp.total
+	}
+	return wp
+}
+
+// pick returns the picked index, picks are reproducible for the same sequence of rnd.
+func (wp *weightedPicker) pick(rnd *rand.Rand) int {
+	r := rnd.Intn(wp.total)
+
+	return sort.Search(len(wp.cumWeights), func(i int) bool { return wp.cumWeights[i] > r })
+}
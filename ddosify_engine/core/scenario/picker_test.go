@@ -0,0 +1,150 @@
+package scenario
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestWeightedPickerDistribution(t *testing.T) {
+	t.Parallel()
+
+	weights := []int{70, 20, 10}
+	wp := newWeightedPicker(weights)
+	rnd := rand.New(rand.NewSource(1))
+
+	n := 100000
+	counts := make([]int, len(weights))
+	for i := 0; i < n; i++ {
+		counts[wp.pick(rnd)]++
+	}
+
+	for i, w := range weights {
+		ratio := float64(counts[i]) / float64(n) * 100
+		if ratio < float64(w)-1 || ratio > float64(w)+1 {
+			t.Errorf("Weight %d, Expected ratio around %d%%, Found: %.2f%%", w, w, ratio)
+		}
+	}
+}
+
+func TestWeightedPickerSeed(t *testing.T) {
+	t.Parallel()
+
+	weights := []int{5, 3, 2}
+	picks := func(seed int64) []int {
+		wp := newWeightedPicker(weights)
+		rnd := rand.New(rand.NewSource(seed))
+		p := make([]int, 50)
+		for i := range p {
+			p[i] = wp.pick(rnd)
+		}
+		return p
+	}
+
+	first, second := picks(42), picks(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected same picks for the same seed, Found: %v and %v", first, second)
+	}
+	if reflect.DeepEqual(first, picks(43)) {
+		t.Errorf("Expected different picks for different seeds")
+	}
+}
+
+func TestDoRunsPickedWeightedScenario(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	paths := map[string]int{}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.Path]++
+		mu.Unlock()
+	}
+	host := httptest.NewServer(http.HandlerFunc(handler))
+	defer host.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: host.URL + "/browse", Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: host.URL + "/search", Timeout: types.DefaultTimeout},
+			{ID: 3, Method: http.MethodGet, URL: host.URL + "/cart", Timeout: types.DefaultTimeout},
+		},
+		WeightedScenarios: []types.WeightedScenario{
+			{Name: "browse", Weight: 1, StepIDs: []uint16{1}},
+			{Name: "checkout", Weight: 1, StepIDs: []uint16{3, 2}},
+		},
+	}
+
+	service := NewScenarioService()
+	err := service.Init(context.TODO(), scenario, []*url.URL{nil}, ScenarioOpts{Seed: 3})
+	if err != nil {
+		t.Fatalf("TestDoRunsPickedWeightedScenario init error: %v", err)
+	}
+	defer service.Done()
+
+	iterations := 200
+	for i := 0; i < iterations; i++ {
+		response, _ := service.Do(nil, time.Now())
+
+		var ids []uint16
+		for _, sr := range response.StepResults {
+			ids = append(ids, sr.StepID)
+		}
+		if !reflect.DeepEqual(ids, []uint16{1}) && !reflect.DeepEqual(ids, []uint16{3, 2}) {
+			t.Fatalf("Expected steps of a single weighted scenario, Found: %v", ids)
+		}
+	}
+
+	if paths["/browse"]+paths["/cart"] != iterations || paths["/cart"] != paths["/search"] {
+		t.Errorf("Expected each iteration to run one scenario, Found: %v", paths)
+	}
+	if paths["/browse"] == 0 || paths["/cart"] == 0 {
+		t.Errorf("Expected both scenarios to be picked, Found: %v", paths)
+	}
+}
+
+func TestDoSeededScenarioMix(t *testing.T) {
+	t.Parallel()
+
+	host := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer host.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: host.URL + "/browse", Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: host.URL + "/cart", Timeout: types.DefaultTimeout},
+		},
+		WeightedScenarios: []types.WeightedScenario{
+			{Name: "browse", Weight: 1, StepIDs: []uint16{1}},
+			{Name: "checkout", Weight: 1, StepIDs: []uint16{2}},
+		},
+	}
+
+	mix := func(seed int64) []uint16 {
+		service := NewScenarioService()
+		if err := service.Init(context.TODO(), scenario, []*url.URL{nil}, ScenarioOpts{Seed: seed}); err != nil {
+			t.Fatalf("TestDoSeededScenarioMix init error: %v", err)
+		}
+		defer service.Done()
+
+		ids := make([]uint16, 30)
+		for i := range ids {
+			response, _ := service.Do(nil, time.Now())
+			ids[i] = response.StepResults[0].StepID
+		}
+		return ids
+	}
+
+	first := mix(11)
+	if second := mix(11); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected %v, Found: %v", first, second)
+	}
+}
@@ -41,8 +41,58 @@ type HttpRequesterI interface {
 	Send(client *http.Client, envs map[string]interface{}) *types.ScenarioStepResult // should use its own client if client is nil
 }
 
+// Warmer is implemented by the requesters that can open their connections before the test, so the handshakes
+// are not in the latencies of the first requests.
+type Warmer interface {
+	// Warm opens a connection of the client to the target of the step, its own client is used if client is nil.
+	Warm(client *http.Client) error
+}
+
+// WebSocketRequesterI is implemented by the WebSocketRequester, shares the same signature with the GrpcRequesterI
+// since both of them manage their own connection pools.
+type WebSocketRequesterI interface {
+	Init(ctx context.Context, ss types.ScenarioStep, url *url.URL, debug bool, ei *injection.EnvironmentInjector) error
+	Send(envs map[string]interface{}) *types.ScenarioStepResult
+}
+
+type GrpcRequesterI interface {
+	Init(ctx context.Context, ss types.ScenarioStep, url *url.URL, debug bool, ei *injection.EnvironmentInjector) error
+	Send(envs map[string]interface{}) *types.ScenarioStepResult
+}
+
+// DNSRequesterI is implemented by the DNSRequester of the dns steps.
+type DNSRequesterI interface {
+	Init(ctx context.Context, ss types.ScenarioStep, url *url.URL, debug bool, ei *injection.EnvironmentInjector) error
+	Send(envs map[string]interface{}) *types.ScenarioStepResult
+}
+
+// SSERequesterI is implemented by the SSERequester of the sse steps.
+type SSERequesterI interface {
+	Init(ctx context.Context, ss types.ScenarioStep, url *url.URL, debug bool, ei *injection.EnvironmentInjector) error
+	Send(envs map[string]interface{}) *types.ScenarioStepResult
+}
+
+// SocketRequesterI is implemented by the SocketRequester of the tcp and udp steps.
+type SocketRequesterI interface {
+	Init(ctx context.Context, ss types.ScenarioStep, url *url.URL, debug bool, ei *injection.EnvironmentInjector) error
+	Send(envs map[string]interface{}) *types.ScenarioStepResult
+}
+
 // NewRequester is the factory method of the Requester.
 func NewRequester(s types.ScenarioStep) (requester Requester, err error) {
-	requester = &HttpRequester{} // we have only HttpRequester type for now, add check for rpc in future
+	switch s.Type {
+	case types.StepTypeGRPC:
+		requester = &GrpcRequester{}
+	case types.StepTypeWebSocket:
+		requester = &WebSocketRequester{}
+	case types.StepTypeTCP, types.StepTypeUDP:
+		requester = &SocketRequester{}
+	case types.StepTypeDNS:
+		requester = &DNSRequester{}
+	case types.StepTypeSSE:
+		requester = &SSERequester{}
+	default:
+		requester = &HttpRequester{}
+	}
 	return
 }
@@ -0,0 +1,105 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"sync"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// certCache keeps the verified peer certificates of a step. Connections of a step mostly have the same
+// certificate and parameters, so each one is verified once instead of on each response.
+type certCache struct {
+	roots *x509.CertPool // system roots if nil
+
+	mu    sync.Mutex
+	certs map[certKey]*types.CertInfo
+}
+
+type certKey struct {
+	signature   string // of the leaf certificate
+	host        string
+	version     uint16
+	cipherSuite uint16
+}
+
+func newCertCache(s types.ScenarioStep) *certCache {
+	c := &certCache{certs: make(map[certKey]*types.CertInfo)}
+	if s.TLSConfig != nil {
+		c.roots = s.TLSConfig.RootCAs
+	} else if s.CertPool != nil && s.Cert.Certificate != nil {
+		c.roots = s.CertPool
+	}
+	return c
+}
+
+// get returns the CertInfo of the connection state, nil if the peer has no certificate. The certificate is
+// verified for the server name of the connection, or the given host if there is no server name, like an ip.
+func (c *certCache) get(cs *tls.ConnectionState, host string) *types.CertInfo {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+	if cs.ServerName != "" {
+		host = cs.ServerName
+	}
+	leaf := cs.PeerCertificates[0]
+	key := certKey{signature: string(leaf.Signature), host: host, version: cs.Version, cipherSuite: cs.CipherSuite}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info, ok := c.certs[key]; ok {
+		return info
+	}
+	info := &types.CertInfo{
+		Host:        host,
+		Subject:     leaf.Subject.String(),
+		Issuer:      leaf.Issuer.String(),
+		NotAfter:    leaf.NotAfter,
+		Version:     tlsVersionNames[cs.Version],
+		CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+		WeakVersion: cs.Version < tls.VersionTLS12,
+	}
+	if info.Version == "" {
+		info.Version = "unknown"
+	}
+	if err := leaf.VerifyHostname(host); err != nil {
+		info.HostnameError = err.Error()
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: c.roots, Intermediates: intermediates}); err != nil {
+		info.ChainError = err.Error()
+	}
+	c.certs[key] = info
+	return info
+}
@@ -0,0 +1,135 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// chunkedBody generates the types.ChunkedBody of a step for each request. The chunks are written to the
+// transport one by one, so each one is sent as a chunk of the Transfer-Encoding: chunked body.
+type chunkedBody struct {
+	conf  types.ChunkedBody
+	chunk []byte // content of a full chunk, shared by all the requests
+}
+
+func newChunkedBody(conf types.ChunkedBody) *chunkedBody {
+	return &chunkedBody{
+		conf:  conf,
+		chunk: bytes.Repeat([]byte("x"), conf.ChunkSize),
+	}
+}
+
+// setBody sets the streamed body of the request, the returned progress measures the written chunks.
+// Bodies sent again for the redirects restart the upload.
+func (c *chunkedBody) setBody(req *http.Request) *uploadProgress {
+	u := &uploadProgress{}
+	req.Body = c.reader(u)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return c.reader(u), nil
+	}
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	return u
+}
+
+// reader returns a new body, written by a separate goroutine while it is read.
+// Closing the reader before EOF stops the writer.
+func (c *chunkedBody) reader(u *uploadProgress) io.ReadCloser {
+	pr, pw := io.Pipe()
+	r := &chunkedReader{PipeReader: pr, closed: make(chan struct{})}
+	go c.write(pw, r.closed, u)
+	return r
+}
+
+func (c *chunkedBody) write(pw *io.PipeWriter, closed <-chan struct{}, u *uploadProgress) {
+	u.start()
+	remaining := c.conf.Size
+	for i := 0; remaining > 0; i++ {
+		if i > 0 && c.conf.Delay > 0 {
+			t := time.NewTimer(c.conf.Delay)
+			select {
+			case <-t.C:
+			case <-closed:
+				t.Stop()
+				return
+			}
+		}
+		n := int64(len(c.chunk))
+		if remaining < n {
+			n = remaining
+		}
+		// returns after the transport reads the chunk
+		if _, err := pw.Write(c.chunk[:n]); err != nil {
+			return
+		}
+		remaining -= n
+		u.wrote(n)
+	}
+	pw.Close()
+}
+
+type chunkedReader struct {
+	*io.PipeReader
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (r *chunkedReader) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return r.PipeReader.Close()
+}
+
+// uploadProgress is the progress of a chunked body, from the start of writing it to the last written chunk.
+type uploadProgress struct {
+	mu    sync.Mutex
+	bytes int64
+	begin time.Time
+	last  time.Time
+}
+
+func (u *uploadProgress) start() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.bytes = 0
+	u.begin = time.Now()
+	u.last = u.begin
+}
+
+func (u *uploadProgress) wrote(n int64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.bytes += n
+	u.last = time.Now()
+}
+
+// result returns the uploaded bytes and the duration of the upload.
+func (u *uploadProgress) result() (int64, time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.bytes, u.last.Sub(u.begin)
+}
@@ -0,0 +1,81 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestSendChunkedBody(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		transferEncoding []string
+		contentLength    int64
+		length           int
+	}
+	reqs := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		reqs <- received{transferEncoding: r.TransferEncoding, contentLength: r.ContentLength, length: len(b)}
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:          1,
+		Method:      http.MethodPost,
+		URL:         server.URL,
+		Timeout:     types.DefaultTimeout,
+		ChunkedBody: &types.ChunkedBody{Size: 10000, ChunkSize: 4000, Delay: 50 * time.Millisecond},
+	}
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	res := h.Send(nil, map[string]interface{}{})
+	if res.Err.Type != "" {
+		t.Fatalf("Send: %v", res.Err)
+	}
+	rec := <-reqs
+	if len(rec.transferEncoding) != 1 || rec.transferEncoding[0] != "chunked" || rec.contentLength != -1 {
+		t.Errorf("Expected %v, Found: %v", "chunked", rec)
+	}
+	if rec.length != 10000 {
+		t.Errorf("Expected %v, Found: %v", 10000, rec.length)
+	}
+
+	// 3 chunks, delayed twice
+	if res.UploadedBytes != 10000 {
+		t.Errorf("Expected %v, Found: %v", 10000, res.UploadedBytes)
+	}
+	if res.UploadDuration < 100*time.Millisecond {
+		t.Errorf("Expected at least %v, Found: %v", 100*time.Millisecond, res.UploadDuration)
+	}
+}
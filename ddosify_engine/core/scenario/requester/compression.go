@@ -0,0 +1,175 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// acceptEncoding is sent by the steps that don't set the Accept-Encoding header, unless the compression is
+// disabled by the disable-compression option. Responses of these encodings are decompressed by the requester
+// instead of the transport, so the compressed and decompressed sizes and the decompression time are measured.
+const acceptEncoding = "gzip, deflate, br"
+
+// compressionDisabled reports whether the disable-compression option of the step is set.
+func (h *HttpRequester) compressionDisabled() bool {
+	val, ok := h.packet.Custom["disable-compression"].(bool)
+	return ok && val
+}
+
+// setAcceptEncoding sets the Accept-Encoding header like the transport does when it handles the compression.
+func (h *HttpRequester) setAcceptEncoding(req *http.Request) {
+	if h.compressionDisabled() || req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" ||
+		req.Method == http.MethodHead {
+		return
+	}
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+}
+
+// compressBody compresses the body by the given request compression of the step.
+func compressBody(encoding string, body []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	var w io.WriteCloser
+	if encoding == types.RequestCompressionDeflate {
+		w = zlib.NewWriter(buf) // the deflate content coding is the zlib format
+	} else {
+		w = gzip.NewWriter(buf)
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressReqBody replaces the body of the request with its compressed form and sets the Content-Encoding header.
+// Static payloads are compressed once at Init, see compressedPayload. Returns the length of the uncompressed body,
+// zero if the body is empty and left uncompressed.
+func (h *HttpRequester) compressReqBody(req *http.Request) (int64, error) {
+	compressed := h.compressedPayload
+	length := int64(len(h.packet.Payload))
+	if compressed == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return 0, err
+		}
+		length = int64(len(body))
+		if length == 0 {
+			req.Body = http.NoBody
+			return 0, nil
+		}
+		if compressed, err = compressBody(h.packet.RequestCompression, body); err != nil {
+			return 0, err
+		}
+	} else if length == 0 {
+		return 0, nil
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(compressed))
+	req.ContentLength = int64(len(compressed))
+	req.GetBody = func() (io.ReadCloser, error) { // for redirects
+		return io.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.Header.Set("Content-Encoding", h.packet.RequestCompression)
+	return length, nil
+}
+
+// timedReader counts the bytes read from the underlying reader and the time spent on the reads.
+type timedReader struct {
+	r   io.Reader
+	n   int64
+	dur time.Duration
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(p)
+	t.dur += time.Since(start)
+	t.n += int64(n)
+	return n, err
+}
+
+// decompressor decompresses a response body. The time spent on reading the compressed body is excluded
+// from the decompression time, so dur is the cpu cost of the decompression.
+type decompressor struct {
+	wire     *timedReader // compressed body
+	encoding string
+	r        io.Reader // created on the first read, since the readers read the headers of the stream
+	n        int64     // decompressed bytes
+	dur      time.Duration
+}
+
+// newDecompressor returns the decompressor of the body for the given Content-Encoding,
+// nil if the encoding is not supported.
+func newDecompressor(body io.Reader, contentEncoding string) *decompressor {
+	encoding := strings.ToLower(strings.TrimSpace(contentEncoding))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "br":
+		return &decompressor{wire: &timedReader{r: body}, encoding: encoding}
+	}
+	return nil
+}
+
+func (d *decompressor) Read(p []byte) (n int, err error) {
+	start := time.Now()
+	wireDur := d.wire.dur
+	defer func() {
+		d.dur += time.Since(start) - (d.wire.dur - wireDur)
+	}()
+
+	if d.r == nil {
+		if d.r, err = d.newReader(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = d.r.Read(p)
+	d.n += int64(n)
+	return n, err
+}
+
+func (d *decompressor) newReader() (io.Reader, error) {
+	switch d.encoding {
+	case "br":
+		return brotli.NewReader(d.wire), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(d.wire)
+	}
+	// deflate should be in the zlib format, but some servers send the raw deflate stream
+	br := bufio.NewReader(d.wire)
+	if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,366 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "deflate":
+		w, _ = zlib.NewWriterLevel(buf, zlib.DefaultCompression)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(buf)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("compress: %v", err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecompressor(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(strings.Repeat(`{"name": "ddosify"}`, 100))
+	tests := []struct {
+		encoding        string
+		contentEncoding string
+	}{
+		{"gzip", "gzip"},
+		{"gzip", "X-Gzip"},
+		{"deflate", "deflate"},
+		{"raw-deflate", "deflate"},
+		{"br", "br"},
+	}
+
+	for _, test := range tests {
+		compressed := compress(t, test.encoding, body)
+		dec := newDecompressor(bytes.NewReader(compressed), test.contentEncoding)
+		if dec == nil {
+			t.Fatalf("%s Expected a decompressor", test.encoding)
+		}
+		got, err := io.ReadAll(dec)
+		if err != nil || !bytes.Equal(got, body) {
+			t.Errorf("%s Expected %v, Found: %v %v", test.encoding, string(body), string(got), err)
+		}
+		if dec.wire.n != int64(len(compressed)) || dec.n != int64(len(body)) {
+			t.Errorf("%s Expected %v, Found: %v", test.encoding, []int{len(compressed), len(body)},
+				[]int64{dec.wire.n, dec.n})
+		}
+		if dec.dur <= 0 {
+			t.Errorf("%s Expected a decompression duration, Found: %v", test.encoding, dec.dur)
+		}
+	}
+
+	for _, enc := range []string{"", "identity", "zstd", "gzip, br"} {
+		if dec := newDecompressor(bytes.NewReader(body), enc); dec != nil {
+			t.Errorf("Expected no decompressor for %q", enc)
+		}
+	}
+}
+
+func TestSendCompressedResponse(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(strings.Repeat(`{"name": "ddosify"}`, 100))
+	tests := []struct {
+		encoding string
+		maxBody  int64
+	}{
+		{"gzip", 0},
+		{"br", 0},
+		{"br", 10}, // the limit is applied to the decompressed body
+	}
+
+	for _, test := range tests {
+		compressed := compress(t, test.encoding, body)
+		acceptEncodings := make(chan string, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			acceptEncodings <- r.Header.Get("Accept-Encoding")
+			w.Header().Set("Content-Encoding", test.encoding)
+			w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+			w.Write(compressed)
+		}))
+
+		s := types.ScenarioStep{
+			ID:                   1,
+			Method:               http.MethodGet,
+			URL:                  server.URL,
+			Timeout:              types.DefaultTimeout,
+			Assertions:           []string{`contains(body, "name")`},
+			MaxResponseBodyBytes: test.maxBody,
+		}
+
+		ei := &injection.EnvironmentInjector{}
+		ei.Init()
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+
+		res := h.Send(nil, map[string]interface{}{})
+		h.Done()
+		server.Close()
+		if res.Err.Type != "" || len(res.FailedAssertions) > 0 {
+			t.Fatalf("%s Send: %v %v", test.encoding, res.Err, res.FailedAssertions)
+		}
+		if got := <-acceptEncodings; got != acceptEncoding {
+			t.Errorf("%s Expected %v, Found: %v", test.encoding, acceptEncoding, got)
+		}
+		expected := body
+		if test.maxBody > 0 {
+			expected = body[:test.maxBody]
+			if !res.RespBodyTruncated {
+				t.Errorf("%s Expected the body truncated", test.encoding)
+			}
+		} else if res.DecompressedLength != int64(len(body)) {
+			t.Errorf("%s Expected %v, Found: %v", test.encoding, len(body), res.DecompressedLength)
+		}
+		if !bytes.Equal(res.RespBody, expected) {
+			t.Errorf("%s Expected %v, Found: %v", test.encoding, string(expected), string(res.RespBody))
+		}
+		if res.ContentLength != int64(len(compressed)) {
+			t.Errorf("%s Expected %v, Found: %v", test.encoding, len(compressed), res.ContentLength)
+		}
+		if _, ok := res.Custom["decompressDuration"].(time.Duration); !ok {
+			t.Errorf("%s Expected %v, Found: %v", test.encoding, "decompressDuration", res.Custom)
+		}
+	}
+}
+
+func TestSendCompressionDisabled(t *testing.T) {
+	t.Parallel()
+
+	compressed := compress(t, "gzip", []byte("ddosify"))
+	acceptEncodings := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncodings <- r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		acceptEncoding string
+	}{
+		{"NoHeader", nil, ""},
+		{"Header", map[string]string{"Accept-Encoding": "gzip"}, "gzip"},
+	}
+
+	for _, test := range tests {
+		s := types.ScenarioStep{
+			ID:         1,
+			Method:     http.MethodGet,
+			URL:        server.URL,
+			Timeout:    types.DefaultTimeout,
+			Headers:    test.headers,
+			Custom:     map[string]interface{}{"disable-compression": true},
+			Assertions: []string{`status_code == 200`},
+		}
+
+		ei := &injection.EnvironmentInjector{}
+		ei.Init()
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+
+		res := h.Send(nil, map[string]interface{}{})
+		h.Done()
+		if res.Err.Type != "" {
+			t.Fatalf("%s Send: %v", test.name, res.Err)
+		}
+		if got := <-acceptEncodings; got != test.acceptEncoding {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.acceptEncoding, got)
+		}
+		// raw body is kept
+		if !bytes.Equal(res.RespBody, compressed) || res.DecompressedLength != 0 {
+			t.Errorf("%s Expected %v, Found: %v %v", test.name, compressed, res.RespBody, res.DecompressedLength)
+		}
+	}
+}
+
+func TestSendCompressedRequest(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		encoding string
+		length   int64
+		body     string
+	}
+	reqs := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := received{encoding: r.Header.Get("Content-Encoding"), length: r.ContentLength}
+		if d := newDecompressor(r.Body, rec.encoding); d != nil {
+			b, _ := io.ReadAll(d)
+			rec.body = string(b)
+		}
+		reqs <- rec
+	}))
+	defer server.Close()
+
+	static := strings.Repeat(`{"name": "ddosify"}`, 50)
+	tests := []struct {
+		name     string
+		encoding string
+		payload  string
+		static   bool
+	}{
+		{"StaticGzip", types.RequestCompressionGzip, static, true},
+		{"TemplatedDeflate", types.RequestCompressionDeflate, strings.Repeat(`{"name": "{{NAME}}"}`, 50), false},
+	}
+
+	for _, test := range tests {
+		s := types.ScenarioStep{
+			ID:                 1,
+			Method:             http.MethodPost,
+			URL:                server.URL,
+			Timeout:            types.DefaultTimeout,
+			Payload:            test.payload,
+			RequestCompression: test.encoding,
+		}
+
+		ei := &injection.EnvironmentInjector{}
+		ei.Init()
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+			t.Fatalf("%s Init: %v", test.name, err)
+		}
+		if cached := h.compressedPayload != nil; cached != test.static {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.static, cached)
+		}
+
+		res := h.Send(nil, map[string]interface{}{"NAME": "ddosify"})
+		h.Done()
+		if res.Err.Type != "" {
+			t.Fatalf("%s Send: %v", test.name, res.Err)
+		}
+
+		rec := <-reqs
+		if rec.encoding != test.encoding || rec.body != static {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.encoding, rec)
+		}
+		if res.ReqBodyLength != int64(len(static)) || res.ReqCompressedBodyLength != rec.length ||
+			rec.length >= res.ReqBodyLength {
+			t.Errorf("%s Expected %v, Found: %v", test.name, []int64{int64(len(static)), rec.length},
+				[]int64{res.ReqBodyLength, res.ReqCompressedBodyLength})
+		}
+	}
+}
+
+func TestSendCompressedRequestFallback(t *testing.T) {
+	t.Parallel()
+
+	static := strings.Repeat(`{"name": "ddosify"}`, 50)
+	var mu sync.Mutex
+	var encodings []string
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		if r.Header.Get("Content-Encoding") != "" && r.URL.Path == "/plain" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		fallback  bool
+		status    int
+		encodings []string
+	}{
+		{"Accepted", "/gzip", true, http.StatusOK, []string{types.RequestCompressionGzip}},
+		{"Fallback", "/plain", true, http.StatusOK, []string{types.RequestCompressionGzip, ""}},
+		{"NoFallback", "/plain", false, http.StatusUnsupportedMediaType, []string{types.RequestCompressionGzip}},
+	}
+
+	for _, test := range tests {
+		mu.Lock()
+		encodings, bodies = nil, nil
+		mu.Unlock()
+
+		s := types.ScenarioStep{
+			ID:                         1,
+			Method:                     http.MethodPost,
+			URL:                        server.URL + test.path,
+			Timeout:                    types.DefaultTimeout,
+			Payload:                    static,
+			RequestCompression:         types.RequestCompressionGzip,
+			RequestCompressionFallback: test.fallback,
+		}
+		ei := &injection.EnvironmentInjector{}
+		ei.Init()
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+			t.Fatalf("%s Init: %v", test.name, err)
+		}
+		res := h.Send(nil, map[string]interface{}{})
+		h.Done()
+
+		if res.StatusCode != test.status {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.status, res.StatusCode)
+		}
+		fallback := len(test.encodings) > 1
+		if res.CompressionFallback != fallback {
+			t.Errorf("%s Expected %v, Found: %v", test.name, fallback, res.CompressionFallback)
+		}
+		mu.Lock()
+		if !reflect.DeepEqual(encodings, test.encodings) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.encodings, encodings)
+		}
+		if fallback && bodies[1] != static {
+			t.Errorf("%s Expected %v, Found: %v", test.name, static, bodies[1])
+		}
+		mu.Unlock()
+	}
+}
@@ -0,0 +1,112 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/extraction"
+)
+
+// Max number of the bytes of the form page read for the token
+const maxCSRFFormBytes = 1 << 20
+
+// csrfXPath returns the xpath of the token in the hidden input field, or in the meta tag of the same name like
+// the csrf-token of the Rails apps.
+func csrfXPath(field string) string {
+	return fmt.Sprintf("//input[@name='%s']/@value | //meta[@name='%s']/@content", field, field)
+}
+
+// addCSRFToken fetches the form page of the step by the client, and adds the token of it to the request. The session
+// cookie set by the page is kept by the jar of the client, or added to the request if the client has no jar.
+func (h *HttpRequester) addCSRFToken(client *http.Client, httpReq *http.Request, envs map[string]interface{}) error {
+	c := h.packet.CSRF
+	formURL := c.FormURL
+	if h.dynamicRgx.MatchString(formURL) {
+		formURL, _ = h.ei.Of(envs).InjectDynamic(formURL)
+	}
+	if h.envRgx.MatchString(formURL) {
+		var err error
+		if formURL, err = h.ei.InjectEnv(formURL, envs); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodGet, formURL, nil)
+	if err != nil {
+		return err
+	}
+	if ua := httpReq.Header.Get("User-Agent"); ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxCSRFFormBytes))
+	drainBody(res)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("form page responded with status %d", res.StatusCode)
+	}
+	token, err := extraction.ExtractFromHtml(body, csrfXPath(c.Field))
+	if err != nil {
+		return fmt.Errorf("token %s is not found in the form page", c.Field)
+	}
+
+	if client.Jar == nil {
+		for _, cookie := range res.Cookies() {
+			httpReq.AddCookie(cookie)
+		}
+	}
+
+	if c.Header != "" {
+		httpReq.Header.Set(c.Header, fmt.Sprint(token))
+		return nil
+	}
+
+	var payload []byte
+	if httpReq.Body != nil {
+		if payload, err = io.ReadAll(httpReq.Body); err != nil {
+			return err
+		}
+		httpReq.Body.Close()
+	}
+	if len(payload) > 0 {
+		payload = append(payload, '&')
+	}
+	payload = append(payload, url.QueryEscape(c.Field)+"="+url.QueryEscape(fmt.Sprint(token))...)
+	httpReq.Body = io.NopCloser(bytes.NewReader(payload))
+	httpReq.ContentLength = int64(len(payload))
+	httpReq.GetBody = func() (io.ReadCloser, error) { // for redirects
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+	if httpReq.Header.Get("Content-Type") == "" {
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// newCSRFServer serves a login form with a token per session, the POST requests get 200 only with the token and
+// the session cookie of the form page.
+func newCSRFServer(formCount *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			n := atomic.AddInt32(formCount, 1)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: fmt.Sprintf("s%d", n)})
+			fmt.Fprintf(w, `<html><body><form method="post"><input type="hidden" name="csrf_token" value="t%d">`+
+				`<input name="username"></form></body></html>`, n)
+			return
+		}
+		session, err := r.Cookie("session")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token := r.Header.Get("X-CSRF-Token")
+		if token == "" {
+			token = r.PostFormValue("csrf_token")
+		}
+		if token != "t"+strings.TrimPrefix(session.Value, "s") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-CSRF-Token") == "" && r.PostFormValue("username") != "user" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestSendCSRF(t *testing.T) {
+	t.Parallel()
+
+	var formCount int32
+	server := newCSRFServer(&formCount)
+	defer server.Close()
+
+	jar, _ := cookiejar.New(nil)
+	tests := []struct {
+		name    string
+		payload string
+		header  string
+		client  *http.Client
+	}{
+		{"FormField", "username=user", "", nil},
+		{"FormFieldWithJar", "username=user", "", &http.Client{Jar: jar}},
+		{"Header", `{"username":"user"}`, "X-CSRF-Token", nil},
+	}
+
+	for _, test := range tests {
+		s := types.ScenarioStep{
+			ID:      1,
+			Method:  http.MethodPost,
+			URL:     server.URL,
+			Payload: test.payload,
+			Timeout: types.DefaultTimeout,
+			CSRF:    &types.CSRF{FormURL: server.URL + "/login", Field: "csrf_token", Header: test.header},
+		}
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			res := h.Send(test.client, map[string]interface{}{})
+			if res.Err.Type != "" || res.StatusCode != http.StatusOK {
+				t.Errorf("%s Expected %v, Found: %v %v", test.name, http.StatusOK, res.StatusCode, res.Err)
+			}
+		}
+		h.Done()
+	}
+
+	// form page is fetched for each request
+	if n := atomic.LoadInt32(&formCount); n != 6 {
+		t.Errorf("Expected %v, Found: %v", 6, n)
+	}
+}
+
+func TestSendCSRFTokenNotFound(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><form method="post"></form></body></html>`)
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		CSRF:    &types.CSRF{FormURL: server.URL, Field: "csrf_token"},
+	}
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	res := h.Send(nil, map[string]interface{}{})
+	if res.Err.Type != types.ErrorInvalidRequest || !strings.Contains(res.Err.Reason, "csrf_token") {
+		t.Errorf("Expected %v, Found: %v", types.ErrorInvalidRequest, res.Err)
+	}
+}
@@ -0,0 +1,417 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DialContextFunc is the signature of the functions that dial the connections of the requesters.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dialer dials the connections of the requesters. Addresses pinned by the resolve entries are dialed
+// directly, other hosts are resolved once and cached for the TTL, so the system resolver is not hit per connection.
+// Connections are bound to the source addresses in round-robin order, if any.
+// Dialer is safe for concurrent use.
+type Dialer struct {
+	dialer      *net.Dialer
+	resolver    *net.Resolver
+	ttl         time.Duration
+	resolve     map[string]string // host:port -> ip
+	sourceAddrs []net.IP
+	next        uint32 // index of the next source address
+
+	mu    sync.Mutex
+	cache map[string]dnsCacheEntry
+}
+
+type dnsCacheEntry struct {
+	ips     []net.IPAddr
+	expires time.Time
+}
+
+// NewDialer returns a Dialer that caches the resolved addresses for the given ttl and dials the addresses in
+// resolve, in host:port -> ip format, to the pinned ip. Caching is disabled if ttl is not positive.
+// Connections are dialed from the sourceAddrs in turn, the system picks the source address if it is empty.
+func NewDialer(ttl time.Duration, resolve map[string]string, sourceAddrs []net.IP) *Dialer {
+	return &Dialer{
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		resolver:    net.DefaultResolver,
+		ttl:         ttl,
+		resolve:     resolve,
+		sourceAddrs: sourceAddrs,
+		cache:       make(map[string]dnsCacheEntry),
+	}
+}
+
+// SetKeepAlive sets the interval of the TCP keep-alive probes of the dialed connections, 30 seconds by default.
+// It should be called before the dialer is used.
+func (d *Dialer) SetKeepAlive(interval time.Duration) {
+	d.dialer.KeepAlive = interval
+}
+
+// DialContext connects to the address on the named network, it has the signature of http.Transport.DialContext.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if ip, ok := d.resolve[strings.ToLower(addr)]; ok {
+		return d.dial(ctx, network, ip, port)
+	}
+	if d.ttl <= 0 || net.ParseIP(host) != nil {
+		return d.dial(ctx, network, host, port)
+	}
+
+	ips, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// try the resolved addresses in order, like the net package does
+	for _, ip := range ips {
+		var conn net.Conn
+		conn, err = d.dial(ctx, network, ip.String(), port)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+// dial connects to the host from the next source address. The sources of the other ip family are skipped for an ip
+// host, the net package filters the resolved addresses of a hostname by the family of the source.
+func (d *Dialer) dial(ctx context.Context, network, host, port string) (net.Conn, error) {
+	addr := net.JoinHostPort(host, port)
+	if len(d.sourceAddrs) == 0 {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	remote := net.ParseIP(host)
+	n := uint32(len(d.sourceAddrs))
+	start := atomic.AddUint32(&d.next, 1) - 1
+	for i := uint32(0); i < n; i++ {
+		ip := d.sourceAddrs[(start+i)%n]
+		if remote != nil && (ip.To4() == nil) != (remote.To4() == nil) {
+			continue
+		}
+		dialer := *d.dialer
+		if strings.HasPrefix(network, "udp") {
+			dialer.LocalAddr = &net.UDPAddr{IP: ip}
+		} else {
+			dialer.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return d.dialer.DialContext(ctx, network, addr)
+}
+
+// lookup returns the cached addresses of the host, resolves it if there is no entry or the entry is expired.
+// Lookup is done with the given ctx, so the dns phase is still visible to the httptrace of the request.
+func (d *Dialer) lookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	d.mu.Lock()
+	e, ok := d.cache[host]
+	d.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.ips, nil
+	}
+
+	ips, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	d.mu.Lock()
+	d.cache[host] = dnsCacheEntry{ips: ips, expires: time.Now().Add(d.ttl)}
+	d.mu.Unlock()
+	return ips, nil
+}
+
+// ConnTracker counts the open connections dialed through it per address, the connections idle in the pools of the
+// transports are included until they are closed. ConnTracker is safe for concurrent use.
+type ConnTracker struct {
+	mu    sync.Mutex
+	open  map[string]int64
+	peak  map[string]int64
+	total int64 // open connections of all the addresses
+	max   int64 // peak of the total
+}
+
+// NewConnTracker returns an empty ConnTracker.
+func NewConnTracker() *ConnTracker {
+	return &ConnTracker{open: make(map[string]int64), peak: make(map[string]int64)}
+}
+
+// Wrap returns a dial function counting the connections dialed by dial, or by a default net.Dialer if it is nil.
+func (t *ConnTracker) Wrap(dial DialContextFunc) DialContextFunc {
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		t.add(addr, 1)
+		return &trackedConn{Conn: conn, tracker: t, addr: addr}, nil
+	}
+}
+
+func (t *ConnTracker) add(addr string, delta int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.open[addr] += delta
+	t.total += delta
+	if t.open[addr] > t.peak[addr] {
+		t.peak[addr] = t.open[addr]
+	}
+	if t.total > t.max {
+		t.max = t.total
+	}
+	if t.open[addr] == 0 {
+		delete(t.open, addr)
+	}
+}
+
+// Open returns the number of the open connections per address, the addresses without any are omitted.
+func (t *ConnTracker) Open() map[string]int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	open := make(map[string]int64, len(t.open))
+	for addr, n := range t.open {
+		open[addr] = n
+	}
+	return open
+}
+
+// Peak returns the peak of the open connections of all the addresses and the peak per address.
+func (t *ConnTracker) Peak() (int64, map[string]int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	peak := make(map[string]int64, len(t.peak))
+	for addr, n := range t.peak {
+		peak[addr] = n
+	}
+	return t.max, peak
+}
+
+// trackedConn decrements the open connections of its address once it is closed.
+type trackedConn struct {
+	net.Conn
+	tracker *ConnTracker
+	addr    string
+	once    sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { c.tracker.add(c.addr, -1) })
+	return c.Conn.Close()
+}
+
+// ConnLimiter limits the open connections dialed through it per address, like the documented connection limit of a
+// partner API. A dial at the limit waits until a connection of its address is closed, or fails with ctx.Err() once
+// ctx is done. The connections idle in the pools of the transports count in the limit, so a waiting dial closes an
+// idle connection of its address if there is one, the connections of the other steps and clients are not held
+// until their idle timeout. ConnLimiter is safe for concurrent use.
+type ConnLimiter struct {
+	max int
+
+	mu    sync.Mutex
+	hosts map[string]*limitedHost
+
+	// counters of the dials waited at the limit, updated atomically
+	waits    int64
+	waitTime int64 // in nanoseconds
+}
+
+type limitedHost struct {
+	open int
+	idle map[*limitedConn]struct{}
+	// closed and replaced once a connection of the host is closed, wakes up the waiting dials
+	freed chan struct{}
+}
+
+// NewConnLimiter returns a ConnLimiter letting max connections per address be open at the same time. max should be
+// greater than 0.
+func NewConnLimiter(max int) *ConnLimiter {
+	return &ConnLimiter{max: max, hosts: make(map[string]*limitedHost)}
+}
+
+// Wrap returns a dial function dialing by dial, or by a default net.Dialer if it is nil, within the limit.
+func (l *ConnLimiter) Wrap(dial DialContextFunc) DialContextFunc {
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := l.acquire(ctx, addr); err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			l.release(addr, nil)
+			return nil, err
+		}
+		return &limitedConn{Conn: conn, limiter: l, addr: addr}, nil
+	}
+}
+
+// Waits returns the number of the dials waited for a connection of their addresses at the limit, and the total
+// time they waited.
+func (l *ConnLimiter) Waits() (int64, time.Duration) {
+	return atomic.LoadInt64(&l.waits), time.Duration(atomic.LoadInt64(&l.waitTime))
+}
+
+// acquire takes a connection slot of the address, waits until one is freed at the limit.
+func (l *ConnLimiter) acquire(ctx context.Context, addr string) error {
+	var waitStart time.Time
+	defer func() {
+		if !waitStart.IsZero() {
+			atomic.AddInt64(&l.waits, 1)
+			atomic.AddInt64(&l.waitTime, int64(time.Since(waitStart)))
+		}
+	}()
+
+	for {
+		l.mu.Lock()
+		h := l.host(addr)
+		if h.open < l.max {
+			h.open++
+			l.mu.Unlock()
+			return nil
+		}
+		var idle *limitedConn
+		for c := range h.idle {
+			idle = c
+			break
+		}
+		freed := h.freed
+		l.mu.Unlock()
+
+		if waitStart.IsZero() {
+			waitStart = time.Now()
+		}
+		if idle != nil {
+			// the transport drops the closed connection from its pool, its slot is freed by Close
+			idle.Close()
+			continue
+		}
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release frees the connection slot of the address taken by the conn, nil if the dial failed.
+func (l *ConnLimiter) release(addr string, conn *limitedConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.host(addr)
+	h.open--
+	delete(h.idle, conn)
+	close(h.freed)
+	h.freed = make(chan struct{})
+}
+
+// use marks the conn in use by a request and returns the number of its uses, see setIdle.
+func (l *ConnLimiter) use(conn *limitedConn) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	conn.uses++
+	delete(l.host(conn.addr).idle, conn)
+	return conn.uses
+}
+
+// setIdle marks the conn idle in the pool of its transport after the given use of it. The transport puts the conn
+// back before the request is told, so the next request can take it first, the idle mark of an older use is ignored.
+func (l *ConnLimiter) setIdle(conn *limitedConn, use uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if conn.closed || conn.uses != use {
+		return
+	}
+	l.host(conn.addr).idle[conn] = struct{}{}
+}
+
+// host returns the connections of the address, should be called with mu held.
+func (l *ConnLimiter) host(addr string) *limitedHost {
+	h, ok := l.hosts[addr]
+	if !ok {
+		h = &limitedHost{idle: make(map[*limitedConn]struct{}), freed: make(chan struct{})}
+		l.hosts[addr] = h
+	}
+	return h
+}
+
+// limitedConn frees its connection slot once it is closed. The requests mark it idle while it is in the pool of
+// the transport, see useConn.
+type limitedConn struct {
+	net.Conn
+	limiter *ConnLimiter
+	addr    string
+	once    sync.Once
+	closed  bool   // guarded by the mu of the limiter
+	uses    uint64 // requests that got the conn, guarded by the mu of the limiter
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.limiter.mu.Lock()
+		c.closed = true
+		c.limiter.mu.Unlock()
+		// the slot is freed once the connection is closed
+		c.limiter.release(c.addr, c)
+	})
+	return err
+}
+
+// useConn marks the connection of a request in use for its ConnLimiter, if it is dialed by one. It returns the use
+// of the connection, passed to idleConn once the request puts the connection back to the pool of the transport.
+func useConn(conn net.Conn) uint64 {
+	if c, ok := conn.(*limitedConn); ok {
+		return c.limiter.use(c)
+	}
+	return 0
+}
+
+// idleConn marks the connection idle for its ConnLimiter, unless another request got it after the given use.
+func idleConn(conn net.Conn, use uint64) {
+	if c, ok := conn.(*limitedConn); ok {
+		c.limiter.setIdle(c, use)
+	}
+}
@@ -0,0 +1,398 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestDialerResolve(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	// ddosify.test is not resolvable, resolve entry pins it to the test server
+	addr := net.JoinHostPort("ddosify.test", u.Port())
+	d := NewDialer(0, map[string]string{addr: u.Hostname()}, nil)
+
+	conn, err := d.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("TestDialerResolve error occurred: %v", err)
+	}
+	conn.Close()
+}
+
+func TestDialerCache(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	d := NewDialer(time.Minute, nil, nil)
+	conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("localhost", u.Port()))
+	if err != nil {
+		t.Fatalf("TestDialerCache error occurred: %v", err)
+	}
+	conn.Close()
+
+	if e, ok := d.cache["localhost"]; !ok || len(e.ips) == 0 {
+		t.Errorf("Expected localhost to be cached, Found: %v", d.cache)
+	}
+
+	// cached entries are dialed without a lookup
+	d.cache["ddosify.test"] = dnsCacheEntry{ips: []net.IPAddr{{IP: net.ParseIP(u.Hostname())}}, expires: time.Now().Add(time.Minute)}
+	conn, err = d.DialContext(context.Background(), "tcp", net.JoinHostPort("ddosify.test", u.Port()))
+	if err != nil {
+		t.Fatalf("TestDialerCache error occurred: %v", err)
+	}
+	conn.Close()
+}
+
+func TestSendWithDialer(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	addr := net.JoinHostPort("ddosify.test", u.Port())
+	d := NewDialer(time.Minute, map[string]string{addr: u.Hostname()}, nil)
+
+	s := types.ScenarioStep{
+		ID:          1,
+		Method:      http.MethodGet,
+		URL:         "http://" + addr,
+		Timeout:     types.DefaultTimeout,
+		DialContext: d.DialContext,
+	}
+	h := &HttpRequester{}
+	if err := h.Init(context.Background(), s, nil, false, nil); err != nil {
+		t.Fatalf("TestSendWithDialer init error: %v", err)
+	}
+
+	res := h.Send(nil, map[string]interface{}{})
+	if res.Err.Type != "" || res.StatusCode != http.StatusOK {
+		t.Errorf("Expected %v, Found: %v, %v", http.StatusOK, res.StatusCode, res.Err)
+	}
+}
+
+func TestH2CTransportDialer(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+
+	addr := net.JoinHostPort("ddosify.test", u.Port())
+	d := NewDialer(0, map[string]string{addr: u.Hostname()}, nil)
+
+	tr := NewH2CTransport(d.DialContext)
+	conn, err := tr.DialTLSContext(context.Background(), "tcp", addr, nil)
+	if err != nil {
+		t.Fatalf("TestH2CTransportDialer error occurred: %v", err)
+	}
+	conn.Close()
+}
+
+func TestDialerSourceAddrs(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	sources := make(chan string, 4)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+			sources <- host
+			conn.Close()
+		}
+	}()
+
+	// ipv6 source is skipped for the ipv4 target
+	d := NewDialer(0, nil, []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("::1"), net.ParseIP("127.0.0.3")})
+	expected := []string{"127.0.0.2", "127.0.0.3", "127.0.0.3", "127.0.0.2"}
+	for _, e := range expected {
+		conn, err := d.DialContext(context.Background(), "tcp", lis.Addr().String())
+		if err != nil {
+			t.Fatalf("TestDialerSourceAddrs error occurred: %v", err)
+		}
+		conn.Close()
+		if s := <-sources; s != e {
+			t.Errorf("Expected %v, Found: %v", e, s)
+		}
+	}
+}
+
+func TestConnTracker(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	tracker := NewConnTracker()
+	dial := tracker.Wrap(nil)
+	addr := lis.Addr().String()
+	var conns []net.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := dial(context.Background(), "tcp", addr)
+		if err != nil {
+			t.Fatalf("TestConnTracker error occurred: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	if open := tracker.Open(); open[addr] != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, open[addr])
+	}
+
+	// closing twice is counted once
+	conns[0].Close()
+	conns[0].Close()
+	if open := tracker.Open(); open[addr] != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, open[addr])
+	}
+	conns[1].Close()
+	conns[2].Close()
+	if open := tracker.Open(); len(open) != 0 {
+		t.Errorf("Expected no open connections, Found: %v", open)
+	}
+
+	total, perHost := tracker.Peak()
+	if total != 3 || perHost[addr] != 3 {
+		t.Errorf("Expected %v, Found: %v %v", 3, total, perHost)
+	}
+
+	// failed dials are not counted
+	if _, err := dial(context.Background(), "tcp", "127.0.0.1:0"); err == nil {
+		t.Fatalf("Expected dial error")
+	}
+	if total, _ := tracker.Peak(); total != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, total)
+	}
+}
+
+func TestConnLimiter(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	limiter := NewConnLimiter(1)
+	dial := limiter.Wrap(nil)
+	addr := lis.Addr().String()
+	first, err := dial(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("TestConnLimiter error occurred: %v", err)
+	}
+
+	// the dial at the limit fails once its ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := dial(ctx, "tcp", addr); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, Found: %v", context.DeadlineExceeded, err)
+	}
+
+	// the dial at the limit waits until the connection is closed
+	time.AfterFunc(30*time.Millisecond, func() {
+		first.Close()
+		first.Close() // closing twice frees the slot once
+	})
+	second, err := dial(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("TestConnLimiter error occurred: %v", err)
+	}
+
+	// the idle connection is closed for the waiting dial
+	idleConn(second, useConn(second))
+	third, err := dial(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("TestConnLimiter error occurred: %v", err)
+	}
+	defer third.Close()
+	if _, err := second.Write([]byte("x")); err == nil {
+		t.Errorf("Expected the idle connection to be closed")
+	}
+
+	waits, waited := limiter.Waits()
+	if waits != 3 || waited < 40*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v %v", "3 waits of at least 40ms", waits, waited)
+	}
+}
+
+func TestConnLimiterStaleIdle(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	dial := NewConnLimiter(1).Wrap(nil)
+	addr := lis.Addr().String()
+	conn, err := dial(context.Background(), "tcp", addr)
+	if err != nil {
+		t.Fatalf("TestConnLimiterStaleIdle error occurred: %v", err)
+	}
+
+	// the next request got the connection before the first one marked it idle
+	first := useConn(conn)
+	second := useConn(conn)
+	idleConn(conn, first)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := dial(ctx, "tcp", addr); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, Found: %v", context.DeadlineExceeded, err)
+	}
+	if _, err := conn.Write([]byte("x")); err != nil {
+		t.Errorf("Expected the connection in use not to be closed, Found: %v", err)
+	}
+
+	idleConn(conn, second)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	next, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		t.Fatalf("TestConnLimiterStaleIdle error occurred: %v", err)
+	}
+	defer next.Close()
+}
+
+func TestSendWithConnLimiter(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// steps have their own transports, the idle connection of a step is closed for the other one
+	tracker := NewConnTracker()
+	dial := NewConnLimiter(1).Wrap(tracker.Wrap(nil))
+	var steps []*HttpRequester
+	for i := 0; i < 2; i++ {
+		h := &HttpRequester{}
+		s := types.ScenarioStep{ID: uint16(i + 1), Method: http.MethodGet, URL: server.URL,
+			Timeout: types.DefaultTimeout, DialContext: dial}
+		if err := h.Init(context.Background(), s, nil, false, nil); err != nil {
+			t.Fatalf("TestSendWithConnLimiter init error: %v", err)
+		}
+		defer h.Done()
+		steps = append(steps, h)
+	}
+	for i := 0; i < 3; i++ {
+		for _, h := range steps {
+			if res := h.Send(nil, map[string]interface{}{}); res.Err.Type != "" {
+				t.Fatalf("TestSendWithConnLimiter error occurred: %v", res.Err)
+			}
+		}
+	}
+
+	if peak, _ := tracker.Peak(); peak != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, peak)
+	}
+}
+
+func TestSendWithConnLimiterConcurrent(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the concurrent requests take the connection put back by the others, it is never closed while in use. The
+	// requests are not idempotent, so the ones on a closed connection are not retried by the transport.
+	tracker := NewConnTracker()
+	h := &HttpRequester{}
+	s := types.ScenarioStep{ID: 1, Method: http.MethodPost, URL: server.URL, Timeout: types.DefaultTimeout,
+		DialContext: NewConnLimiter(1).Wrap(tracker.Wrap(nil))}
+	if err := h.Init(context.Background(), s, nil, false, nil); err != nil {
+		t.Fatalf("TestSendWithConnLimiterConcurrent init error: %v", err)
+	}
+	defer h.Done()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if res := h.Send(nil, map[string]interface{}{}); res.Err.Type != "" {
+					t.Errorf("TestSendWithConnLimiterConcurrent error occurred: %v", res.Err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak, _ := tracker.Peak(); peak != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, peak)
+	}
+}
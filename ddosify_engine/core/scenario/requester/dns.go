@@ -0,0 +1,452 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/evaluator"
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/types/regex"
+	"go.ddosify.com/ddosify/core/util"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	dnsPoolMaxCap   = 1000
+	dnsDefaultPort  = "53"
+	dnsTransportTCP = "tcp"
+
+	// Maximum size of a dns message, udp responses are limited by the size of the datagrams
+	dnsMaxMessageSize = 65535
+)
+
+var dnsQueryTypes = map[string]dnsmessage.Type{
+	"A":     dnsmessage.TypeA,
+	"AAAA":  dnsmessage.TypeAAAA,
+	"CNAME": dnsmessage.TypeCNAME,
+	"MX":    dnsmessage.TypeMX,
+	"NS":    dnsmessage.TypeNS,
+	"PTR":   dnsmessage.TypePTR,
+	"SOA":   dnsmessage.TypeSOA,
+	"SRV":   dnsmessage.TypeSRV,
+	"TXT":   dnsmessage.TypeTXT,
+	"ANY":   dnsmessage.TypeALL,
+}
+
+var dnsRCodes = map[dnsmessage.RCode]string{
+	dnsmessage.RCodeSuccess:        "NOERROR",
+	dnsmessage.RCodeFormatError:    "FORMERR",
+	dnsmessage.RCodeServerFailure:  "SERVFAIL",
+	dnsmessage.RCodeNameError:      "NXDOMAIN",
+	dnsmessage.RCodeNotImplemented: "NOTIMP",
+	dnsmessage.RCodeRefused:        "REFUSED",
+}
+
+type DNSConnFactory func() net.Conn
+type DNSConnCloseMethod func(net.Conn)
+
+// NewDNSConnPool creates a pool of the udp sockets or the tcp connections to a resolver, mirrors the
+// NewClientPool of the HTTP clients.
+func NewDNSConnPool(initialCap, maxCap int, factory DNSConnFactory, close DNSConnCloseMethod) (*util.Pool[net.Conn], error) {
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	pool := &util.Pool[net.Conn]{
+		Items:   make(chan net.Conn, maxCap),
+		Factory: factory,
+		Close:   close,
+	}
+	pool.Fill(initialCap)
+
+	return pool, nil
+}
+
+// dnsAnswer is the JSON representation of a resource record in the response body of a dns step.
+type dnsAnswer struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  uint32 `json:"ttl"`
+	Data string `json:"data"`
+}
+
+// dnsResponse is the response body of a dns step, captures and assertions use it like a JSON body,
+// e.g. json_path("answers.0.data").
+type dnsResponse struct {
+	RCode     string      `json:"rcode"`
+	Truncated bool        `json:"truncated"`
+	Answers   []dnsAnswer `json:"answers"`
+}
+
+// DNSRequester sends the queries of the dns steps. Sockets to the resolver are pooled and reused across
+// iterations for both of the udp and tcp transports.
+type DNSRequester struct {
+	ctx        context.Context
+	packet     types.ScenarioStep
+	ei         *injection.EnvironmentInjector
+	debug      bool
+	qType      dnsmessage.Type
+	pool       *util.Pool[net.Conn]
+	dial       DialContextFunc
+	dynamicRgx *regexp.Regexp
+	envRgx     *regexp.Regexp
+}
+
+// Init creates the connection pool. Connections are dialed lazily on the first Send.
+// Proxies are not supported by the dns steps, proxyAddr is ignored.
+func (d *DNSRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAddr *url.URL, debug bool,
+	ei *injection.EnvironmentInjector) (err error) {
+	d.ctx = ctx
+	d.packet = s
+	d.ei = ei
+	d.debug = debug
+	d.dynamicRgx = regexp.MustCompile(regex.DynamicVariableRegex)
+	d.envRgx = regexp.MustCompile(regex.EnvironmentVariableRegex)
+
+	var ok bool
+	if d.qType, ok = dnsQueryTypes[s.DNS.QType]; !ok {
+		return fmt.Errorf("unsupported dns query type: %s", s.DNS.QType)
+	}
+
+	d.dial = s.DialContext
+	if d.dial == nil {
+		d.dial = (&net.Dialer{}).DialContext
+	}
+
+	// Factory can't dial since the resolver may contain variables, connections are created in Send
+	d.pool, err = NewDNSConnPool(0, dnsPoolMaxCap, func() net.Conn { return nil }, closeTCPConn)
+	return
+}
+
+func (d *DNSRequester) Send(envs map[string]interface{}) (res *types.ScenarioStepResult) {
+	var requestErr types.RequestError
+	var respBody []byte
+	var rcode dnsmessage.RCode
+	var answerCount int
+	var truncated bool
+	var extractedVars = make(map[string]interface{})
+	var failedCaptures = make(map[string]string, 0)
+	var failedAssertions = make([]types.FailedAssertion, 0)
+
+	var usableVars = make(map[string]interface{}, len(envs))
+	for k, v := range envs {
+		usableVars[k] = v
+	}
+
+	res = &types.ScenarioStepResult{
+		StepID:    d.packet.ID,
+		StepName:  d.packet.Name,
+		RequestID: uuid.New(),
+	}
+
+	target, addr, query, name, err := d.prepareReq(usableVars)
+	if err != nil {
+		res.Err = types.RequestError{
+			Type:   types.ErrorInvalidRequest,
+			Reason: fmt.Sprintf("Could not prepare req, %s", err.Error()),
+		}
+		return res
+	}
+	res.Url = target
+	res.Method = d.packet.DNS.QType
+	res.ReqBody = []byte(name)
+
+	reqStartTime := time.Now()
+	msg, err := d.send(addr, query)
+	dur := time.Since(reqStartTime)
+
+	if err != nil {
+		requestErr = fetchSocketErrType(d.ctx, err)
+	} else {
+		rcode = msg.RCode
+		truncated = msg.Truncated
+		answerCount = len(msg.Answers)
+		respBody, _ = json.Marshal(toDNSResponse(msg))
+	}
+
+	if requestErr.Type == "" {
+		if len(d.packet.EnvsToCapture) > 0 {
+			failedCaptures = captureEnvironmentVariables(d.packet.EnvsToCapture, nil, respBody, nil, nil, extractedVars)
+			requestErr = fatalCaptureError(d.packet.EnvsToCapture, failedCaptures)
+		}
+
+		if len(d.packet.Assertions) > 0 {
+			_, failedAssertions = applyAssertions(d.packet.Assertions, &evaluator.AssertEnv{
+				StatusCode:   int64(rcode),
+				ResponseSize: int64(len(respBody)),
+				ResponseTime: dur.Milliseconds(), // in ms
+				Body:         string(respBody),
+				Variables:    concatEnvs(envs, extractedVars),
+			})
+		}
+	} else {
+		failedCaptures = captureEnvironmentVariables(d.packet.EnvsToCapture, nil, nil, nil, nil, extractedVars)
+	}
+
+	res.StatusCode = int(rcode)
+	res.RequestTime = reqStartTime
+	res.Duration = dur
+	res.ContentLength = int64(len(respBody))
+	res.Err = requestErr
+	res.RespBody = respBody
+	res.Custom = map[string]interface{}{
+		"dnsRcode":       dnsRCodeName(rcode),
+		"dnsAnswerCount": answerCount,
+		"dnsTruncated":   truncated,
+	}
+	res.ExtractedEnvs = extractedVars
+	res.UsableEnvs = usableVars
+	res.FailedCaptures = failedCaptures
+	res.FailedAssertions = failedAssertions
+
+	return res
+}
+
+// send writes the query to a pooled connection and reads the response with the same ID. Pooled tcp connections
+// may be closed by the resolver while they are idle, the query is sent once more on a new connection in that case.
+func (d *DNSRequester) send(addr string, query []byte) (*dnsmessage.Message, error) {
+	conn := d.pool.Get()
+	reused := conn != nil
+	for {
+		var err error
+		if conn == nil {
+			if conn, err = d.dialConn(addr); err != nil {
+				return nil, err
+			}
+		}
+
+		msg, err := d.exchange(conn, query)
+		if err == nil {
+			d.pool.Put(conn)
+			return msg, nil
+		}
+
+		// late responses of a timed out query would be read by the next one otherwise
+		d.pool.Close(conn)
+		if !reused || d.packet.DNS.Transport != dnsTransportTCP || isTimeout(err) || d.ctx.Err() != nil {
+			return nil, err
+		}
+		conn, reused = nil, false
+	}
+}
+
+func (d *DNSRequester) dialConn(addr string) (net.Conn, error) {
+	timeout := d.packet.DialTimeout
+	if timeout == 0 {
+		timeout = d.packet.TimeoutDuration()
+	}
+	ctx, cancel := context.WithTimeout(d.ctx, timeout)
+	defer cancel()
+
+	conn, err := d.dial(ctx, d.packet.DNS.Transport, addr)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && d.ctx.Err() == nil {
+		return nil, &dialTimeoutError{err: err}
+	}
+	return conn, err
+}
+
+// exchange writes the query and reads the response, tcp messages are prefixed with their two byte length.
+func (d *DNSRequester) exchange(conn net.Conn, query []byte) (*dnsmessage.Message, error) {
+	conn.SetDeadline(time.Now().Add(d.packet.TimeoutDuration()))
+
+	// unblock the reads if the engine is stopped
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-d.ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	id := binary.BigEndian.Uint16(query)
+	tcp := d.packet.DNS.Transport == dnsTransportTCP
+	if tcp {
+		framed := make([]byte, 2, len(query)+2)
+		binary.BigEndian.PutUint16(framed, uint16(len(query)))
+		query = append(framed, query...)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, dnsMaxMessageSize)
+	for {
+		var n int
+		var err error
+		if tcp {
+			var l [2]byte
+			if _, err = io.ReadFull(conn, l[:]); err != nil {
+				return nil, err
+			}
+			n, err = io.ReadFull(conn, buf[:binary.BigEndian.Uint16(l[:])])
+		} else {
+			n, err = conn.Read(buf)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		msg := &dnsmessage.Message{}
+		if err = msg.Unpack(buf[:n]); err != nil {
+			return nil, fmt.Errorf("invalid dns response: %v", err)
+		}
+		if msg.ID == id && msg.Response {
+			return msg, nil
+		}
+		// response of another query, e.g. a duplicated datagram
+	}
+}
+
+// prepareReq injects the dynamic and environment variables into the url and the name, and packs the query.
+func (d *DNSRequester) prepareReq(envs map[string]interface{}) (target, addr string, query []byte, name string, err error) {
+	if target, err = d.inject(d.packet.URL, envs); err != nil {
+		return
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return "", "", nil, "", fmt.Errorf("invalid dns target: %s", target)
+	}
+	port := u.Port()
+	if port == "" {
+		port = dnsDefaultPort
+	}
+	addr = net.JoinHostPort(u.Hostname(), port)
+
+	if name, err = d.inject(d.packet.DNS.Name, envs); err != nil {
+		return
+	}
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qName, err := dnsmessage.NewName(name)
+	if err != nil {
+		return
+	}
+
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{
+		ID:               uint16(rand.Intn(1 << 16)),
+		RecursionDesired: !d.packet.DNS.NoRecursion,
+	})
+	b.EnableCompression()
+	if err = b.StartQuestions(); err != nil {
+		return
+	}
+	if err = b.Question(dnsmessage.Question{Name: qName, Type: d.qType, Class: dnsmessage.ClassINET}); err != nil {
+		return
+	}
+	query, err = b.Finish()
+	return
+}
+
+func (d *DNSRequester) inject(s string, envs map[string]interface{}) (string, error) {
+	var err error
+	if d.dynamicRgx.MatchString(s) {
+		s, err = d.ei.Of(envs).InjectDynamic(s)
+		if err != nil {
+			return "", err
+		}
+	}
+	if d.envRgx.MatchString(s) {
+		s, err = d.ei.InjectEnv(s, envs)
+		if err != nil {
+			return "", err
+		}
+	}
+	return s, nil
+}
+
+func toDNSResponse(msg *dnsmessage.Message) dnsResponse {
+	r := dnsResponse{
+		RCode:     dnsRCodeName(msg.RCode),
+		Truncated: msg.Truncated,
+		Answers:   make([]dnsAnswer, 0, len(msg.Answers)),
+	}
+	for _, a := range msg.Answers {
+		r.Answers = append(r.Answers, dnsAnswer{
+			Name: a.Header.Name.String(),
+			Type: strings.TrimPrefix(a.Header.Type.String(), "Type"),
+			TTL:  a.Header.TTL,
+			Data: dnsResourceData(a.Body),
+		})
+	}
+	return r
+}
+
+// dnsResourceData formats the data of a resource record like the dig tool.
+func dnsResourceData(body dnsmessage.ResourceBody) string {
+	switch b := body.(type) {
+	case *dnsmessage.AResource:
+		return net.IP(b.A[:]).String()
+	case *dnsmessage.AAAAResource:
+		return net.IP(b.AAAA[:]).String()
+	case *dnsmessage.CNAMEResource:
+		return b.CNAME.String()
+	case *dnsmessage.NSResource:
+		return b.NS.String()
+	case *dnsmessage.PTRResource:
+		return b.PTR.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", b.Pref, b.MX.String())
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", b.Priority, b.Weight, b.Port, b.Target.String())
+	case *dnsmessage.TXTResource:
+		return strings.Join(b.TXT, "")
+	case *dnsmessage.SOAResource:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", b.NS.String(), b.MBox.String(), b.Serial, b.Refresh,
+			b.Retry, b.Expire, b.MinTTL)
+	}
+	return ""
+}
+
+func dnsRCodeName(rcode dnsmessage.RCode) string {
+	if name, ok := dnsRCodes[rcode]; ok {
+		return name
+	}
+	return strconv.Itoa(int(rcode))
+}
+
+// Done closes the pooled connections.
+func (d *DNSRequester) Done() {
+	if d.pool != nil {
+		d.pool.Done()
+	}
+}
+
+func (d *DNSRequester) Type() string {
+	return "DNS"
+}
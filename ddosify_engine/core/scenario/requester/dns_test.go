@@ -0,0 +1,227 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsAnswerFor resolves the names under ddosify.com to 127.0.0.1, others are NXDOMAIN.
+func dnsAnswerFor(t *testing.T, query []byte) []byte {
+	var q dnsmessage.Message
+	if err := q.Unpack(query); err != nil {
+		t.Errorf("Unpack: %v", err)
+		return nil
+	}
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: q.ID, Response: true, RecursionDesired: q.RecursionDesired},
+		Questions: q.Questions,
+	}
+	name := q.Questions[0].Name
+	if strings.HasSuffix(name.String(), "ddosify.com.") {
+		resp.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}},
+		}}
+	} else {
+		resp.RCode = dnsmessage.RCodeNameError
+	}
+	b, _ := resp.Pack()
+	return b
+}
+
+func newUDPDNSServer(t *testing.T) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pc.WriteTo(dnsAnswerFor(t, buf[:n]), addr)
+		}
+	}()
+	return pc
+}
+
+func newTCPDNSServer(t *testing.T, accepted chan<- struct{}) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer c.Close()
+				for {
+					var l [2]byte
+					if _, err := io.ReadFull(c, l[:]); err != nil {
+						return
+					}
+					query := make([]byte, binary.BigEndian.Uint16(l[:]))
+					if _, err := io.ReadFull(c, query); err != nil {
+						return
+					}
+					resp := dnsAnswerFor(t, query)
+					binary.BigEndian.PutUint16(l[:], uint16(len(resp)))
+					c.Write(append(l[:], resp...))
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestDNSRequesterUDP(t *testing.T) {
+	t.Parallel()
+
+	pc := newUDPDNSServer(t)
+	defer pc.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	ipPath := "answers.0.data"
+	s := types.ScenarioStep{
+		ID:            1,
+		Type:          types.StepTypeDNS,
+		URL:           "dns://" + pc.LocalAddr().String(),
+		Timeout:       types.DefaultTimeout,
+		DNS:           types.DNSConf{Name: "{{SUB}}.ddosify.com", QType: "A", Transport: "udp"},
+		Assertions:    []string{"equals(status_code, 0)"},
+		EnvsToCapture: []types.EnvCaptureConf{{Name: "IP", From: types.Body, JsonPath: &ipPath}},
+	}
+
+	d := &DNSRequester{}
+	if err := d.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer d.Done()
+
+	for i := 0; i < 2; i++ {
+		res := d.Send(map[string]interface{}{"SUB": "api"})
+		if res.Err.Type != "" {
+			t.Fatalf("Expected no error, Found: %v", res.Err)
+		}
+		if string(res.ReqBody) != "api.ddosify.com." {
+			t.Errorf("Expected %v, Found: %v", "api.ddosify.com.", string(res.ReqBody))
+		}
+		if res.Custom["dnsRcode"] != "NOERROR" || res.Custom["dnsAnswerCount"] != 1 {
+			t.Errorf("Expected %v, Found: %v", "NOERROR with 1 answer", res.Custom)
+		}
+		if len(res.FailedAssertions) != 0 {
+			t.Errorf("Expected no failed assertion, Found: %v", res.FailedAssertions)
+		}
+		if res.ExtractedEnvs["IP"] != "127.0.0.1" {
+			t.Errorf("Expected %v, Found: %v", "127.0.0.1", res.ExtractedEnvs["IP"])
+		}
+	}
+
+	// socket is reused in the second query
+	if d.pool.Len() != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, d.pool.Len())
+	}
+}
+
+func TestDNSRequesterTCP(t *testing.T) {
+	t.Parallel()
+
+	accepted := make(chan struct{}, 10)
+	l := newTCPDNSServer(t, accepted)
+	defer l.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeDNS,
+		URL:     "dns://" + l.Addr().String(),
+		Timeout: types.DefaultTimeout,
+		DNS:     types.DNSConf{Name: "missing.example.com", QType: "AAAA", Transport: "tcp"},
+	}
+
+	d := &DNSRequester{}
+	if err := d.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer d.Done()
+
+	for i := 0; i < 2; i++ {
+		res := d.Send(map[string]interface{}{})
+		if res.Err.Type != "" {
+			t.Fatalf("Expected no error, Found: %v", res.Err)
+		}
+		if res.StatusCode != int(dnsmessage.RCodeNameError) {
+			t.Errorf("Expected %v, Found: %v", dnsmessage.RCodeNameError, res.StatusCode)
+		}
+		if res.Custom["dnsRcode"] != "NXDOMAIN" {
+			t.Errorf("Expected %v, Found: %v", "NXDOMAIN", res.Custom["dnsRcode"])
+		}
+		if res.Method != "AAAA" {
+			t.Errorf("Expected %v, Found: %v", "AAAA", res.Method)
+		}
+	}
+
+	if len(accepted) != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, len(accepted))
+	}
+}
+
+func TestDNSResourceData(t *testing.T) {
+	t.Parallel()
+
+	name := dnsmessage.MustNewName("mail.ddosify.com.")
+	tests := []struct {
+		body     dnsmessage.ResourceBody
+		expected string
+	}{
+		{&dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}, "10.0.0.1"},
+		{&dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}}, "::1"},
+		{&dnsmessage.MXResource{Pref: 10, MX: name}, "10 mail.ddosify.com."},
+		{&dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}}, "v=spf1 -all"},
+		{&dnsmessage.SRVResource{Priority: 1, Weight: 2, Port: 443, Target: name}, "1 2 443 mail.ddosify.com."},
+	}
+
+	for _, test := range tests {
+		if data := dnsResourceData(test.body); data != test.expected {
+			t.Errorf("Expected %v, Found: %v", test.expected, data)
+		}
+	}
+}
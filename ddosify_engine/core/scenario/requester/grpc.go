@@ -0,0 +1,405 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/evaluator"
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/types/regex"
+	"go.ddosify.com/ddosify/core/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// Connections are multiplexed by gRPC, so a small pool is enough even for high concurrency
+	grpcPoolInitialCap = 1
+	grpcPoolMaxCap     = 100
+)
+
+type GrpcFactory func() *grpc.ClientConn
+type GrpcCloseMethod func(*grpc.ClientConn)
+
+// NewGrpcConnPool creates a pool of gRPC client connections, mirrors the NewClientPool of the HTTP clients.
+func NewGrpcConnPool(initialCap, maxCap int, factory GrpcFactory, close GrpcCloseMethod) (*util.Pool[*grpc.ClientConn], error) {
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	pool := &util.Pool[*grpc.ClientConn]{
+		Items:   make(chan *grpc.ClientConn, maxCap),
+		Factory: factory,
+		Close:   close,
+	}
+	pool.Fill(initialCap)
+
+	return pool, nil
+}
+
+type GrpcRequester struct {
+	ctx       context.Context
+	proxyAddr *url.URL
+	packet    types.ScenarioStep
+	ei        *injection.EnvironmentInjector
+	debug     bool
+	pool      *util.Pool[*grpc.ClientConn]
+
+	target     string
+	method     string
+	inputDesc  protoreflect.MessageDescriptor
+	outputDesc protoreflect.MessageDescriptor
+	streamDesc *grpc.StreamDesc // nil for the unary methods
+	dynamicRgx *regexp.Regexp
+	envRgx     *regexp.Regexp
+}
+
+// Init loads the method descriptor from the proto set and creates the connection pool.
+func (g *GrpcRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAddr *url.URL, debug bool,
+	ei *injection.EnvironmentInjector) (err error) {
+	g.ctx = ctx
+	g.packet = s
+	g.proxyAddr = proxyAddr
+	g.ei = ei
+	g.debug = debug
+	g.dynamicRgx = regexp.MustCompile(regex.DynamicVariableRegex)
+	g.envRgx = regexp.MustCompile(regex.EnvironmentVariableRegex)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return
+	}
+	g.target = u.Host
+
+	service, method, err := parseGrpcMethod(s.Grpc.Method)
+	if err != nil {
+		return
+	}
+	g.method = "/" + service + "/" + method
+
+	md, err := findMethodDescriptor(s.Grpc.ProtoSet, service, method)
+	if err != nil {
+		return
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		g.streamDesc = &grpc.StreamDesc{
+			StreamName:    method,
+			ClientStreams: md.IsStreamingClient(),
+			ServerStreams: md.IsStreamingServer(),
+		}
+	}
+	g.inputDesc = md.Input()
+	g.outputDesc = md.Output()
+
+	var creds credentials.TransportCredentials
+	if u.Scheme == "grpcs" {
+		creds = credentials.NewTLS(g.initTLSConfig())
+	} else {
+		creds = insecure.NewCredentials()
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if dial := g.packet.DialContext; dial != nil {
+		dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dial(ctx, "tcp", addr)
+		}))
+	}
+
+	g.pool, err = NewGrpcConnPool(grpcPoolInitialCap, grpcPoolMaxCap, func() *grpc.ClientConn {
+		// grpc.Dial is non-blocking, connection errors are returned from the calls
+		conn, _ := grpc.Dial(g.target, dialOpts...)
+		return conn
+	}, func(c *grpc.ClientConn) {
+		if c != nil {
+			c.Close()
+		}
+	})
+	return
+}
+
+func (g *GrpcRequester) initTLSConfig() *tls.Config {
+	return newTLSConfig(g.packet)
+}
+
+func (g *GrpcRequester) Send(envs map[string]interface{}) (res *types.ScenarioStepResult) {
+	var requestErr types.RequestError
+	var respBody []byte
+	var extractedVars = make(map[string]interface{})
+	var failedCaptures = make(map[string]string, 0)
+	var failedAssertions = make([]types.FailedAssertion, 0)
+
+	var usableVars = make(map[string]interface{}, len(envs))
+	for k, v := range envs {
+		usableVars[k] = v
+	}
+
+	res = &types.ScenarioStepResult{
+		StepID:    g.packet.ID,
+		StepName:  g.packet.Name,
+		RequestID: uuid.New(),
+		Url:       g.packet.URL,
+		Method:    g.method,
+	}
+
+	payload, md, err := g.prepareReq(usableVars)
+	if err != nil {
+		res.Err = types.RequestError{
+			Type:   types.ErrorInvalidRequest,
+			Reason: fmt.Sprintf("Could not prepare req, %s", err.Error()),
+		}
+		return res
+	}
+
+	reqMsg := dynamicpb.NewMessage(g.inputDesc)
+	if err = protojson.Unmarshal([]byte(payload), reqMsg); err != nil {
+		res.Err = types.RequestError{
+			Type:   types.ErrorInvalidRequest,
+			Reason: fmt.Sprintf("Could not prepare req, invalid message: %s", err.Error()),
+		}
+		return res
+	}
+	ctx := metadata.NewOutgoingContext(g.ctx, md)
+	if g.packet.TimeoutDuration() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.packet.TimeoutDuration())
+		defer cancel()
+	}
+
+	conn := g.pool.Get()
+	if conn == nil {
+		res.Err = types.RequestError{Type: types.ErrorConn, Reason: fmt.Sprintf("could not dial %s", g.target)}
+		return res
+	}
+	defer g.pool.Put(conn)
+
+	var header, trailer metadata.MD
+	reqStartTime := time.Now()
+	if g.streamDesc != nil {
+		var sr grpcStreamResult
+		sr, err = g.callStream(ctx, conn, reqMsg)
+		header, trailer = sr.header, sr.trailer
+		respBody = sr.body(g.streamDesc.ServerStreams)
+		res.EventIntervals = sr.intervals
+		if g.streamDesc.ClientStreams {
+			res.MessagesSent = sr.sent
+		}
+	} else {
+		respMsg := dynamicpb.NewMessage(g.outputDesc)
+		err = conn.Invoke(ctx, g.method, reqMsg, respMsg, grpc.Header(&header), grpc.Trailer(&trailer))
+		if err == nil {
+			respBody, _ = protojson.Marshal(respMsg)
+		}
+	}
+	dur := time.Since(reqStartTime)
+
+	st := status.Convert(err)
+	if err != nil {
+		requestErr = fetchGrpcErrType(g.ctx, st)
+	}
+
+	respHeaders := mdToHeader(header)
+	respTrailers := mdToHeader(trailer)
+
+	if requestErr.Type == "" {
+		// capture
+		if len(g.packet.EnvsToCapture) > 0 {
+			failedCaptures = captureEnvironmentVariables(g.packet.EnvsToCapture, respHeaders, respBody, nil, respTrailers,
+				extractedVars)
+			requestErr = fatalCaptureError(g.packet.EnvsToCapture, failedCaptures)
+		}
+
+		// assert
+		if len(g.packet.Assertions) > 0 {
+			_, failedAssertions = applyAssertions(g.packet.Assertions, &evaluator.AssertEnv{
+				StatusCode:   int64(st.Code()),
+				ResponseSize: int64(len(respBody)),
+				ResponseTime: dur.Milliseconds(), // in ms
+				Body:         string(respBody),
+				Headers:      respHeaders,
+				Trailers:     respTrailers,
+				Variables:    concatEnvs(envs, extractedVars),
+			})
+		}
+	} else {
+		failedCaptures = captureEnvironmentVariables(g.packet.EnvsToCapture, nil, nil, nil, nil, extractedVars)
+	}
+
+	res.StatusCode = int(st.Code())
+	res.RequestTime = reqStartTime
+	res.Duration = dur
+	res.ContentLength = int64(len(respBody))
+	res.Err = requestErr
+	res.ReqHeaders = mdToHeader(md)
+	res.ReqBody = []byte(payload)
+	res.RespHeaders = respHeaders
+	res.RespTrailers = respTrailers
+	res.RespBody = respBody
+	res.Custom = map[string]interface{}{
+		"grpcStatus": st.Code().String(),
+	}
+	res.ExtractedEnvs = extractedVars
+	res.UsableEnvs = usableVars
+	res.FailedCaptures = failedCaptures
+	res.FailedAssertions = failedAssertions
+
+	return res
+}
+
+// prepareReq injects the dynamic and environment variables into the payload and the headers.
+func (g *GrpcRequester) prepareReq(envs map[string]interface{}) (string, metadata.MD, error) {
+	payload, err := g.inject(g.packet.Payload, envs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	md := metadata.MD{}
+	for k, v := range g.packet.Headers {
+		kk, err := g.inject(k, envs)
+		if err != nil {
+			return "", nil, err
+		}
+		vv, err := g.inject(v, envs)
+		if err != nil {
+			return "", nil, err
+		}
+		md.Append(kk, vv)
+	}
+	return payload, md, nil
+}
+
+func (g *GrpcRequester) inject(s string, envs map[string]interface{}) (string, error) {
+	var err error
+	if g.dynamicRgx.MatchString(s) {
+		s, err = g.ei.Of(envs).InjectDynamic(s)
+		if err != nil {
+			return "", err
+		}
+	}
+	if g.envRgx.MatchString(s) {
+		s, err = g.ei.InjectEnv(s, envs)
+		if err != nil {
+			return "", err
+		}
+	}
+	return s, nil
+}
+
+// mdToHeader converts the lowercase gRPC metadata keys to the canonical header keys, so that assertions and
+// captures can use the headers of the gRPC responses like the HTTP ones.
+func mdToHeader(md metadata.MD) http.Header {
+	h := make(http.Header, len(md))
+	for k, values := range md {
+		for _, v := range values {
+			h.Add(k, v)
+		}
+	}
+	return h
+}
+
+// fetchGrpcErrType maps the transport level gRPC errors to the types.RequestError.
+// Application level status codes are not errors, they are reported in the StatusCode.
+func fetchGrpcErrType(ctx context.Context, st *status.Status) types.RequestError {
+	if ctx.Err() != nil {
+		return types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled}
+	}
+	switch st.Code() {
+	case codes.Unavailable:
+		return types.RequestError{Type: types.ErrorConn, Reason: st.Message()}
+	case codes.DeadlineExceeded:
+		return types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonReadTimeout}
+	}
+	return types.RequestError{}
+}
+
+func (g *GrpcRequester) Done() {
+	if g.pool != nil {
+		g.pool.Done()
+	}
+}
+
+func (g *GrpcRequester) Type() string {
+	return "GRPC"
+}
+
+// parseGrpcMethod splits the "package.Service/Method" into its service and method parts.
+func parseGrpcMethod(fullMethod string) (service string, method string, err error) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	i := strings.LastIndex(fullMethod, "/")
+	if i <= 0 || i == len(fullMethod)-1 {
+		return "", "", fmt.Errorf("grpc method should be like package.Service/Method, got: %s", fullMethod)
+	}
+	return fullMethod[:i], fullMethod[i+1:], nil
+}
+
+func findMethodDescriptor(protoSetPath, service, method string) (protoreflect.MethodDescriptor, error) {
+	b, err := os.ReadFile(protoSetPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read proto set: %v", err)
+	}
+
+	fds := &descriptorpb.FileDescriptorSet{}
+	if err = proto.Unmarshal(b, fds); err != nil {
+		return nil, fmt.Errorf("could not parse proto set: %v", err)
+	}
+
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse proto set: %v", err)
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found in proto set", service)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", method, service)
+	}
+	return md, nil
+}
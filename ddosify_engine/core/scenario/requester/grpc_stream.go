@@ -0,0 +1,134 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type grpcStreamResult struct {
+	header    metadata.MD
+	trailer   metadata.MD
+	responses [][]byte // json encoded messages
+	intervals []time.Duration
+	sent      int
+}
+
+// body is the json array of the received messages for the server streams, and the single response message
+// of the client streams.
+func (r grpcStreamResult) body(serverStreams bool) []byte {
+	if !serverStreams {
+		if len(r.responses) == 0 {
+			return nil
+		}
+		return r.responses[0]
+	}
+	b := []byte{'['}
+	b = append(b, bytes.Join(r.responses, []byte{','})...)
+	return append(b, ']')
+}
+
+// callStream opens a stream on the conn, sends the message StreamCount times on the client streams and reads the
+// responses until the end of the stream or MessageCount messages. Sending and receiving are concurrent, so a bidi
+// stream does not block on the flow control when the server replies before reading all the messages.
+// The stream is canceled when reading stops early or the ctx is done, the returned error is the status of the
+// stream.
+func (g *GrpcRequester) callStream(ctx context.Context, conn *grpc.ClientConn,
+	msg proto.Message) (r grpcStreamResult, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	stream, err := conn.NewStream(ctx, g.streamDesc, g.method)
+	if err != nil {
+		return r, err
+	}
+
+	sent := make(chan int, 1)
+	go func() {
+		sent <- g.sendStream(ctx, stream, msg)
+	}()
+
+	last := start
+	for {
+		if g.packet.Grpc.MessageCount > 0 && len(r.intervals) >= g.packet.Grpc.MessageCount {
+			break
+		}
+		resp := dynamicpb.NewMessage(g.outputDesc)
+		if err = stream.RecvMsg(resp); err != nil {
+			r.trailer = stream.Trailer()
+			if err == io.EOF {
+				err = nil
+			}
+			break
+		}
+		now := time.Now()
+		r.intervals = append(r.intervals, now.Sub(last))
+		last = now
+		b, _ := protojson.Marshal(resp)
+		r.responses = append(r.responses, b)
+		if !g.streamDesc.ServerStreams {
+			// client streams have a single response, the status is received with it
+			r.trailer = stream.Trailer()
+			break
+		}
+	}
+
+	// stops the sender if the server ended the stream or enough messages are read
+	cancel()
+	r.sent = <-sent
+	r.header, _ = stream.Header()
+	return r, err
+}
+
+// sendStream sends the msg on the stream and closes the send direction. It returns the number of the sent messages.
+// Send errors are not returned, the status of the stream is received by the reader.
+func (g *GrpcRequester) sendStream(ctx context.Context, stream grpc.ClientStream, msg proto.Message) int {
+	count := 1
+	if g.streamDesc.ClientStreams && g.packet.Grpc.StreamCount > 0 {
+		count = g.packet.Grpc.StreamCount
+	}
+	interval := time.Duration(g.packet.Grpc.SendInterval) * time.Millisecond
+
+	for i := 0; i < count; i++ {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return i
+			}
+		}
+		if err := stream.SendMsg(msg); err != nil {
+			return i
+		}
+	}
+	stream.CloseSend()
+	return count
+}
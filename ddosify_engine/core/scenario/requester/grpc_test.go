@@ -0,0 +1,373 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestParseGrpcMethod(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		method      string
+		service     string
+		name        string
+		shouldError bool
+	}{
+		{"grpc.health.v1.Health/Check", "grpc.health.v1.Health", "Check", false},
+		{"/grpc.health.v1.Health/Check", "grpc.health.v1.Health", "Check", false},
+		{"grpc.health.v1.Health", "", "", true},
+		{"grpc.health.v1.Health/", "", "", true},
+		{"/Check", "", "", true},
+	}
+
+	for _, tc := range tests {
+		service, name, err := parseGrpcMethod(tc.method)
+		if tc.shouldError != (err != nil) {
+			t.Errorf("%s: expected error %v, Found: %v", tc.method, tc.shouldError, err)
+		}
+		if service != tc.service || name != tc.name {
+			t.Errorf("%s: Expected %s %s, Found: %s %s", tc.method, tc.service, tc.name, service, name)
+		}
+	}
+}
+
+func writeHealthProtoSet(t *testing.T) string {
+	fds := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto)},
+	}
+	b, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "health.protoset")
+	if err = os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGrpcRequesterSend(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotMetadata metadata.MD
+	hs := health.NewServer()
+	hs.SetServingStatus("ddosify", healthpb.HealthCheckResponse_SERVING)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		gotMetadata, _ = metadata.FromIncomingContext(ctx)
+		grpc.SetTrailer(ctx, metadata.Pairs("x-trailer", "done"))
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeGRPC,
+		URL:     "grpc://" + lis.Addr().String(),
+		Headers: map[string]string{"x-user": "{{USER}}"},
+		Payload: `{"service": "{{SERVICE}}"}`,
+		Timeout: types.DefaultTimeout,
+		Grpc: types.GrpcConf{
+			Method:   "grpc.health.v1.Health/Check",
+			ProtoSet: writeHealthProtoSet(t),
+		},
+		Assertions: []string{"equals(status_code, 0)"},
+	}
+
+	g := &GrpcRequester{}
+	if err = g.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer g.Done()
+
+	res := g.Send(map[string]interface{}{"USER": "ddosify", "SERVICE": "ddosify"})
+	if res.Err.Type != "" {
+		t.Fatalf("Expected no error, Found: %v", res.Err)
+	}
+	if res.StatusCode != int(codes.OK) {
+		t.Errorf("Expected %v, Found: %v", codes.OK, res.StatusCode)
+	}
+	if !strings.Contains(string(res.RespBody), "SERVING") {
+		t.Errorf("Expected body to contain SERVING, Found: %s", res.RespBody)
+	}
+	if res.RespTrailers.Get("x-trailer") != "done" {
+		t.Errorf("Expected trailer %v, Found: %v", "done", res.RespTrailers)
+	}
+	if got := gotMetadata.Get("x-user"); len(got) != 1 || got[0] != "ddosify" {
+		t.Errorf("Expected metadata %v, Found: %v", "ddosify", got)
+	}
+	if len(res.FailedAssertions) != 0 {
+		t.Errorf("Expected no failed assertion, Found: %v", res.FailedAssertions)
+	}
+
+	// unknown service returns NotFound code, that is not a connection error
+	res = g.Send(map[string]interface{}{"USER": "ddosify", "SERVICE": "unknown"})
+	if res.StatusCode != int(codes.NotFound) {
+		t.Errorf("Expected %v, Found: %v", codes.NotFound, res.StatusCode)
+	}
+	if res.Err.Type != "" {
+		t.Errorf("Expected no error, Found: %v", res.Err)
+	}
+	if len(res.FailedAssertions) != 1 {
+		t.Errorf("Expected 1 failed assertion, Found: %v", res.FailedAssertions)
+	}
+}
+
+type streamTestServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (streamTestServer) StreamingOutputCall(req *testpb.StreamingOutputCallRequest,
+	stream testpb.TestService_StreamingOutputCallServer) error {
+	for _, p := range req.ResponseParameters {
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{
+			Payload: &testpb.Payload{Body: make([]byte, p.Size)},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (streamTestServer) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	var size int32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: size})
+		}
+		if err != nil {
+			return err
+		}
+		size += int32(len(req.Payload.GetBody()))
+	}
+}
+
+func (streamTestServer) FullDuplexCall(stream testpb.TestService_FullDuplexCallServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(&testpb.StreamingOutputCallResponse{Payload: req.Payload}); err != nil {
+			return err
+		}
+	}
+}
+
+func writeTestServiceProtoSet(t *testing.T) string {
+	fds := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range []protoreflect.FileDescriptor{testpb.File_grpc_testing_empty_proto,
+		testpb.File_grpc_testing_messages_proto, testpb.File_grpc_testing_test_proto} {
+		fds.File = append(fds.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	b, err := proto.Marshal(fds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "test.protoset")
+	if err = os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGrpcRequesterStream(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	testpb.RegisterTestServiceServer(srv, streamTestServer{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	protoSet := writeTestServiceProtoSet(t)
+	fiveResponses := `{"responseParameters": [{"size": 1}, {"size": 1}, {"size": 1}, {"size": 1}, {"size": 1}]}`
+	tests := []struct {
+		name         string
+		method       string
+		payload      string
+		conf         types.GrpcConf
+		messageCount int
+		sent         int
+		body         string
+	}{
+		{
+			name:         "ServerStream",
+			method:       "grpc.testing.TestService/StreamingOutputCall",
+			payload:      fiveResponses,
+			messageCount: 5,
+			body:         `[{"payload":{"body":"AA=="}},`,
+		},
+		{
+			name:         "ServerStreamMessageCount",
+			method:       "grpc.testing.TestService/StreamingOutputCall",
+			payload:      fiveResponses,
+			conf:         types.GrpcConf{MessageCount: 2},
+			messageCount: 2,
+			body:         `[{"payload":{"body":"AA=="}},{"payload":{"body":"AA=="}}]`,
+		},
+		{
+			name:         "ClientStream",
+			method:       "grpc.testing.TestService/StreamingInputCall",
+			payload:      `{"payload": {"body": "YWJj"}}`,
+			conf:         types.GrpcConf{StreamCount: 3, SendInterval: 1},
+			messageCount: 1,
+			sent:         3,
+			body:         `{"aggregatedPayloadSize":9}`,
+		},
+		{
+			name:         "BidiStream",
+			method:       "grpc.testing.TestService/FullDuplexCall",
+			payload:      `{"payload": {"body": "YWJj"}}`,
+			conf:         types.GrpcConf{StreamCount: 4},
+			messageCount: 4,
+			sent:         4,
+			body:         `[{"payload":{"body":"YWJj"}},`,
+		},
+	}
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			conf := tf.conf
+			conf.Method = tf.method
+			conf.ProtoSet = protoSet
+			s := types.ScenarioStep{
+				ID:      1,
+				Type:    types.StepTypeGRPC,
+				URL:     "grpc://" + lis.Addr().String(),
+				Payload: tf.payload,
+				Timeout: types.DefaultTimeout,
+				Grpc:    conf,
+			}
+
+			g := &GrpcRequester{}
+			if err := g.Init(context.Background(), s, nil, false, ei); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			defer g.Done()
+
+			res := g.Send(map[string]interface{}{})
+			if res.Err.Type != "" {
+				t.Fatalf("Expected no error, Found: %v", res.Err)
+			}
+			if res.StatusCode != int(codes.OK) {
+				t.Errorf("Expected %v, Found: %v", codes.OK, res.StatusCode)
+			}
+			if len(res.EventIntervals) != tf.messageCount {
+				t.Errorf("Expected %v, Found: %v", tf.messageCount, len(res.EventIntervals))
+			}
+			if res.MessagesSent != tf.sent {
+				t.Errorf("Expected %v, Found: %v", tf.sent, res.MessagesSent)
+			}
+			if !strings.HasPrefix(string(res.RespBody), tf.body) {
+				t.Errorf("Expected %v, Found: %s", tf.body, res.RespBody)
+			}
+		})
+	}
+}
+
+func TestGrpcRequesterStreamCanceled(t *testing.T) {
+	t.Parallel()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	testpb.RegisterTestServiceServer(srv, streamTestServer{})
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	s := types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeGRPC,
+		URL:     "grpc://" + lis.Addr().String(),
+		Payload: `{}`,
+		Timeout: types.DefaultTimeout,
+		Grpc: types.GrpcConf{
+			Method:       "grpc.testing.TestService/FullDuplexCall",
+			ProtoSet:     writeTestServiceProtoSet(t),
+			StreamCount:  1000,
+			SendInterval: 10,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &GrpcRequester{}
+	if err = g.Init(ctx, s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer g.Done()
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	res := g.Send(map[string]interface{}{})
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the stream to be closed when the run is stopped")
+	}
+	if res.Err.Type != types.ErrorIntented {
+		t.Errorf("Expected %v, Found: %v", types.ErrorIntented, res.Err.Type)
+	}
+}
@@ -0,0 +1,332 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Duration that a host is sent over the fallback transport after its QUIC handshake failed, before HTTP/3 is
+// tried again.
+const h3BrokenDuration = time.Minute
+
+// H3Transport sends the requests over HTTP/3. A QUIC connection is kept per host and the concurrent requests
+// are multiplexed over it, like the HTTP/2 connections of an http.Transport. If the QUIC handshake to a host
+// fails, like a server without HTTP/3 or a firewall dropping UDP, the request is sent over the fallback
+// transport, and so are the requests to the host for the next minute. The QUIC handshake is reported as the
+// TLS duration of the request. H3Transport is safe for concurrent use.
+type H3Transport struct {
+	tlsConfig  *tls.Config
+	quicConfig *quic.Config
+	h3         *http3.Transport // creates the HTTP/3 client connections over the QUIC connections
+	fallback   http.RoundTripper
+
+	mu     sync.Mutex
+	conns  map[string]*h3Conn   // open and dialing connections, by host:port
+	broken map[string]time.Time // hosts whose handshake failed, by the time it failed
+}
+
+// h3Conn is a pooled QUIC connection, conn and cc are set once dialed is closed without an err.
+type h3Conn struct {
+	dialed   chan struct{}
+	err      error
+	conn     quic.Connection
+	cc       *http3.ClientConn
+	inFlight int // requests whose responses are not closed yet, guarded by the mu of the transport
+}
+
+// NewH3Transport returns an H3Transport over the given fallback transport. The handshakes time out after the
+// handshakeTimeout and the idle connections are pinged at the keepAlive interval, if they are positive.
+func NewH3Transport(tlsConfig *tls.Config, handshakeTimeout, keepAlive time.Duration,
+	fallback http.RoundTripper) *H3Transport {
+	return &H3Transport{
+		tlsConfig: tlsConfig,
+		quicConfig: &quic.Config{
+			HandshakeIdleTimeout: handshakeTimeout,
+			KeepAlivePeriod:      keepAlive,
+		},
+		// bodies are decompressed by the requester, like the responses of the other transports
+		h3:       &http3.Transport{DisableCompression: true},
+		fallback: fallback,
+		conns:    make(map[string]*h3Conn),
+		broken:   make(map[string]time.Time),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *H3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	addr := h3Addr(req.URL.Host)
+	if req.URL.Scheme != "https" || t.isBroken(addr) {
+		// like a redirect to an http url
+		return t.fallback.RoundTrip(req)
+	}
+
+	c, reused, err := t.getConn(req.Context(), addr)
+	if err != nil {
+		var he *h3HandshakeError
+		if errors.As(err, &he) && req.Context().Err() == nil {
+			// the request is not sent before the connection is established, so it is sent again as it is
+			t.markBroken(addr)
+			return t.fallback.RoundTrip(req)
+		}
+		return nil, err
+	}
+
+	trace := httptrace.ContextClientTrace(req.Context())
+	if trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Reused: reused})
+	}
+	wrote := func() {
+		if trace != nil && trace.WroteRequest != nil {
+			trace.WroteRequest(httptrace.WroteRequestInfo{})
+		}
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		// the headers are written at once
+		wrote()
+	} else {
+		r := *req
+		r.Body = &h3ReqBody{ReadCloser: req.Body, wrote: wrote}
+		req = &r
+	}
+
+	resp, err := c.cc.RoundTrip(req)
+	if err != nil {
+		t.release(addr, c)
+		return nil, err
+	}
+	if trace != nil && trace.GotFirstResponseByte != nil {
+		trace.GotFirstResponseByte()
+	}
+	resp.Body = &h3RespBody{ReadCloser: resp.Body, release: func() { t.release(addr, c) }}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the QUIC connections without requests in flight, and the idle connections of the
+// fallback transport.
+func (t *H3Transport) CloseIdleConnections() {
+	t.mu.Lock()
+	for addr, c := range t.conns {
+		if c.inFlight == 0 && c.ready() {
+			c.conn.CloseWithError(0, "")
+			delete(t.conns, addr)
+		}
+	}
+	t.mu.Unlock()
+	if c, ok := t.fallback.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// Close closes all the QUIC connections and the idle connections of the fallback transport.
+func (t *H3Transport) Close() error {
+	t.mu.Lock()
+	for addr, c := range t.conns {
+		if c.ready() {
+			c.conn.CloseWithError(0, "")
+		}
+		delete(t.conns, addr)
+	}
+	t.mu.Unlock()
+	if c, ok := t.fallback.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
+
+// getConn returns the connection of the host, dialed by the request if there is none. The requests arriving
+// during the dial wait for it. Reused is true if the connection was established before the request.
+func (t *H3Transport) getConn(ctx context.Context, addr string) (c *h3Conn, reused bool, err error) {
+	t.mu.Lock()
+	c, ok := t.conns[addr]
+	if ok && c.closed() {
+		delete(t.conns, addr)
+		ok = false
+	}
+	if !ok {
+		c = &h3Conn{dialed: make(chan struct{})}
+		t.conns[addr] = c
+	}
+	reused = ok && c.ready()
+	c.inFlight++
+	t.mu.Unlock()
+
+	if !ok {
+		// not canceled by the request, the requests waiting for the dial are bound by their own contexts
+		go t.dial(context.WithoutCancel(ctx), addr, c)
+	}
+	select {
+	case <-c.dialed:
+	case <-ctx.Done():
+		t.release(addr, c)
+		return nil, false, ctx.Err()
+	}
+	if c.err != nil {
+		t.release(addr, c)
+		return nil, false, c.err
+	}
+	return c, reused, nil
+}
+
+// dial establishes the QUIC connection of c, on its own UDP socket that is closed with the connection. The
+// errors are wrapped by h3HandshakeError, so the failed handshakes are told apart from the failed requests.
+func (t *H3Transport) dial(ctx context.Context, addr string, c *h3Conn) {
+	defer close(c.dialed)
+
+	tlsConfig := &tls.Config{}
+	if t.tlsConfig != nil {
+		tlsConfig = t.tlsConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tlsConfig.NextProtos = []string{http3.NextProtoH3}
+
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+	conn, err := quic.DialAddr(ctx, addr, tlsConfig, t.quicConfig)
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		var cs tls.ConnectionState
+		if err == nil {
+			cs = conn.ConnectionState().TLS
+		}
+		trace.TLSHandshakeDone(cs, err)
+	}
+	if err != nil {
+		c.err = &h3HandshakeError{err: err}
+		return
+	}
+	c.conn = conn
+	c.cc = t.h3.NewClientConn(conn)
+}
+
+// release ends a request of the connection, a closed connection is removed from the pool after its last request.
+func (t *H3Transport) release(addr string, c *h3Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c.inFlight--
+	if c.closed() && t.conns[addr] == c {
+		delete(t.conns, addr)
+	}
+}
+
+func (t *H3Transport) isBroken(addr string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at, ok := t.broken[addr]
+	if ok && time.Since(at) >= h3BrokenDuration {
+		delete(t.broken, addr)
+		return false
+	}
+	return ok
+}
+
+func (t *H3Transport) markBroken(addr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.broken[addr] = time.Now()
+}
+
+// ready returns true if the connection is established and not closed yet.
+func (c *h3Conn) ready() bool {
+	select {
+	case <-c.dialed:
+		return c.err == nil && c.conn.Context().Err() == nil
+	default:
+		return false
+	}
+}
+
+// closed returns true if the dial failed or the established connection is closed, like by its idle timeout.
+func (c *h3Conn) closed() bool {
+	select {
+	case <-c.dialed:
+		return c.err != nil || c.conn.Context().Err() != nil
+	default:
+		return false
+	}
+}
+
+// h3Addr returns the host:port of the url host, the port is 443 if the host has none.
+func h3Addr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, "443")
+}
+
+// h3ReqBody reports the request as written once its body is read to the end or closed.
+type h3ReqBody struct {
+	io.ReadCloser
+	wrote func()
+	once  sync.Once
+}
+
+func (b *h3ReqBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.wrote)
+	}
+	return n, err
+}
+
+func (b *h3ReqBody) Close() error {
+	b.once.Do(b.wrote)
+	return b.ReadCloser.Close()
+}
+
+// h3RespBody ends the request of the connection when the response body is closed.
+type h3RespBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *h3RespBody) Close() error {
+	b.once.Do(b.release)
+	return b.ReadCloser.Close()
+}
+
+// h3HandshakeError is returned by the dial of the H3Transport if the QUIC connection could not be established.
+type h3HandshakeError struct {
+	err error
+}
+
+func (e *h3HandshakeError) Error() string {
+	return "h3 handshake: " + e.err.Error()
+}
+
+func (e *h3HandshakeError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,190 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// newH3TestServer serves the handler over HTTP/3 on a local UDP port, by the certificate of the tls server.
+func newH3TestServer(t *testing.T, tlsServer *httptest.Server, handler http.Handler) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	server := &http3.Server{
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: tlsServer.TLS.Certificates}),
+	}
+	go server.Serve(conn)
+	t.Cleanup(func() {
+		server.Close()
+		conn.Close()
+	})
+	return "https://" + conn.LocalAddr().String()
+}
+
+func TestSendH3(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var remoteAddrs []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		remoteAddrs = append(remoteAddrs, r.RemoteAddr)
+		mu.Unlock()
+		w.Write([]byte("h3"))
+	})
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+
+	s := types.ScenarioStep{
+		ID:       1,
+		Method:   http.MethodGet,
+		URL:      newH3TestServer(t, tlsServer, handler),
+		Timeout:  types.DefaultTimeout,
+		Protocol: types.ProtocolH3,
+	}
+
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	// requester's own client and a pooled client shared with HTTP/1 steps
+	for _, c := range []*http.Client{nil, {Transport: &http.Transport{}}} {
+		res := h.Send(c, map[string]interface{}{})
+		if res.Err.Type != "" {
+			t.Fatalf("Expected no error, Found: %v", res.Err)
+		}
+		if res.Proto != "HTTP/3.0" {
+			t.Errorf("Expected %v, Found: %v", "HTTP/3.0", res.Proto)
+		}
+	}
+
+	// the QUIC connection is reused
+	mu.Lock()
+	defer mu.Unlock()
+	if len(remoteAddrs) != 2 || remoteAddrs[0] != remoteAddrs[1] {
+		t.Errorf("Expected the requests over the same connection, Found: %v", remoteAddrs)
+	}
+}
+
+func TestSendH3Fallback(t *testing.T) {
+	t.Parallel()
+
+	// no HTTP/3 server on the udp port of the target
+	tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	s := types.ScenarioStep{
+		ID:                  1,
+		Method:              http.MethodGet,
+		URL:                 tlsServer.URL,
+		Timeout:             types.DefaultTimeout,
+		Protocol:            types.ProtocolH3,
+		TLSHandshakeTimeout: 200 * time.Millisecond,
+	}
+
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		res := h.Send(nil, map[string]interface{}{})
+		if res.Err.Type != "" {
+			t.Fatalf("Expected no error, Found: %v", res.Err)
+		}
+		if res.Proto != "HTTP/2.0" {
+			t.Errorf("Expected %v, Found: %v", "HTTP/2.0", res.Proto)
+		}
+		// the host is marked after the failed handshake, the next request doesn't try HTTP/3
+		if i == 1 && time.Since(start) > 100*time.Millisecond {
+			t.Errorf("Expected the fallback without a handshake, Found: %v", time.Since(start))
+		}
+	}
+}
+
+func TestH3TransportConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	remoteAddrs := make(map[string]int)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		remoteAddrs[r.RemoteAddr]++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	})
+	tlsServer := httptest.NewTLSServer(handler)
+	defer tlsServer.Close()
+	url := newH3TestServer(t, tlsServer, handler)
+
+	tr := NewH3Transport(&tls.Config{InsecureSkipVerify: true}, 0, 0, &http.Transport{})
+	defer tr.Close()
+	client := &http.Client{Transport: tr}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(url)
+			if err != nil {
+				t.Errorf("Expected no error, Found: %v", err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	// the concurrent requests are multiplexed over the connection dialed by the first one
+	mu.Lock()
+	if len(remoteAddrs) != 1 {
+		t.Errorf("Expected %v connection, Found: %v", 1, remoteAddrs)
+	}
+	mu.Unlock()
+	tr.CloseIdleConnections()
+	tr.mu.Lock()
+	if len(tr.conns) != 0 {
+		t.Errorf("Expected the idle connection closed, Found: %v", tr.conns)
+	}
+	tr.mu.Unlock()
+}
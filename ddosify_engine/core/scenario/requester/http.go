@@ -24,9 +24,11 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"net/url"
@@ -34,6 +36,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -41,9 +44,11 @@ import (
 	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/evaluator"
 	"go.ddosify.com/ddosify/core/scenario/scripting/extraction"
 	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/scenario/scripting/schema"
 	"go.ddosify.com/ddosify/core/types"
 	"go.ddosify.com/ddosify/core/types/regex"
 	"golang.org/x/net/http2"
+	"golang.org/x/oauth2"
 )
 
 type HttpRequester struct {
@@ -58,8 +63,18 @@ type HttpRequester struct {
 	debug                bool
 	dynamicRgx           *regexp.Regexp
 	envRgx               *regexp.Regexp
+	tokenSource          oauth2.TokenSource      // for oauth2_cc auth, nil otherwise
+	multipart            *multipartBody          // for the streamed multipart payloads, nil otherwise
+	bodyTemplate         *injection.BodyTemplate // parsed payload if it has variables, nil otherwise
+	chunked              *chunkedBody            // for the chunked bodies, nil otherwise
+	schema               *schema.Schema          // validates the response bodies, nil if the step has no response schema
+	compressedPayload    []byte                  // compressed static payload of the request compression, nil otherwise
+	certs                *certCache              // verified peer certificates of the cert audit, nil if not captured
 }
 
+// Max number of the schema violations reported for a response
+const maxSchemaErrors = 5
+
 // Init creates a client with the given scenarioItem. HttpRequester uses the same http.Client for all requests
 func (h *HttpRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAddr *url.URL, debug bool,
 	ei *injection.EnvironmentInjector) (err error) {
@@ -72,29 +87,84 @@ func (h *HttpRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAdd
 	h.debug = debug
 	h.dynamicRgx = regexp.MustCompile(regex.DynamicVariableRegex)
 	h.envRgx = regexp.MustCompile(regex.EnvironmentVariableRegex)
+	if s.CaptureCert {
+		h.certs = newCertCache(s)
+	}
 
 	// Transport segment
-	tr := h.initTransport()
-	tr.MaxIdleConnsPerHost = 60000
-	tr.MaxIdleConns = 0
+	var tr http.RoundTripper
+	if h.packet.Protocol == types.ProtocolH2C {
+		h2c := NewH2CTransport(h.dialContext())
+		applyKeepAlivePing(h2c, h.packet.Transport.KeepAlivePing)
+		tr = h2c
+	} else if h.packet.Protocol == types.ProtocolH3 {
+		// falls back to HTTP/2, or HTTP/1.1 if the server doesn't negotiate it, when the QUIC handshake fails
+		fallback := h.initTransport()
+		fallback.MaxIdleConnsPerHost = 60000
+		fallback.MaxIdleConns = 0
+		applyTransportConf(fallback, h.packet.Transport)
+		h.configureH2(fallback)
+		tr = fallback
+		if h.proxyAddr == nil {
+			// QUIC can't be tunneled through the http proxies, the steps with a proxy are sent by the fallback
+			tr = NewH3Transport(h.initTLSConfig(), h.packet.TLSHandshakeTimeout, h.packet.Transport.KeepAlivePing, fallback)
+		}
+	} else {
+		htr := h.initTransport()
+		htr.MaxIdleConnsPerHost = 60000
+		htr.MaxIdleConns = 0
+		applyTransportConf(htr, h.packet.Transport)
+		tr = htr
+	}
 
 	// http client
-	h.client = &http.Client{Transport: tr, Timeout: time.Duration(h.packet.Timeout) * time.Second}
+	h.client = &http.Client{Transport: tr, Timeout: h.packet.TimeoutDuration()}
 	if val, ok := h.packet.Custom["disable-redirect"]; ok {
 		val := val.(bool)
 		if val {
+			h.packet.Redirect.Disabled = true
 			h.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse
 			}
 		}
 	}
 
+	if h.packet.Type == types.StepTypeGraphQL {
+		h.packet.Payload = h.packet.GraphQL.Payload()
+	}
+
+	if h.packet.ResponseSchema != "" {
+		h.schema, err = schema.Load(h.packet.ResponseSchema)
+		if err != nil {
+			return fmt.Errorf("response schema could not be loaded: %w", err)
+		}
+	}
+
+	if len(h.packet.MultipartStream) > 0 {
+		h.multipart, err = newMultipartBody(h.packet.MultipartStream, h.ei)
+		if err != nil {
+			return
+		}
+	}
+
+	if h.packet.ChunkedBody != nil {
+		h.chunked = newChunkedBody(*h.packet.ChunkedBody)
+	}
+
 	// Request instance
 	err = h.initRequestInstance()
 	if err != nil {
 		return
 	}
 
+	// oauth2, fetch the first token before the test starts to fail fast on wrong credentials
+	if h.packet.Auth.Type == types.AuthOAuth2ClientCredentials {
+		h.tokenSource = oauth2TokenSource(h.packet.Auth)
+		if _, err = h.tokenSource.Token(); err != nil {
+			return fmt.Errorf("oauth2 token could not be fetched: %w", err)
+		}
+	}
+
 	// body
 	if h.dynamicRgx.MatchString(h.packet.Payload) {
 		_, err = h.ei.InjectDynamic(h.packet.Payload)
@@ -108,6 +178,19 @@ func (h *HttpRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAdd
 		h.containsEnvVar["body"] = true
 	}
 
+	// templated payloads are parsed once, the variables are injected for each request
+	if h.containsDynamicField["body"] || h.containsEnvVar["body"] {
+		h.bodyTemplate = h.ei.ParseBodyTemplate(h.packet.Payload)
+	}
+
+	// static payloads are compressed once
+	if h.packet.RequestCompression != "" && !h.containsDynamicField["body"] && !h.containsEnvVar["body"] {
+		h.compressedPayload, err = compressBody(h.packet.RequestCompression, injection.StringToBytes(h.packet.Payload))
+		if err != nil {
+			return
+		}
+	}
+
 	// url
 	if h.dynamicRgx.MatchString(h.packet.URL) {
 		_, err = h.ei.InjectDynamic(h.packet.URL)
@@ -160,6 +243,19 @@ func (h *HttpRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAdd
 		h.containsEnvVar["basicauth"] = true
 	}
 
+	// bearer token
+	if h.dynamicRgx.MatchString(h.packet.Auth.Token) {
+		_, err = h.ei.InjectDynamic(h.packet.Auth.Token)
+		if err != nil {
+			return
+		}
+		h.containsDynamicField["bearer"] = true
+	}
+
+	if h.envRgx.MatchString(h.packet.Auth.Token) {
+		h.containsEnvVar["bearer"] = true
+	}
+
 	return
 }
 
@@ -170,46 +266,111 @@ func (h *HttpRequester) Done() {
 	// Otherwise, the next job can't use these sockets because they are reserved for the current target host.
 
 	h.client.CloseIdleConnections()
+	if h3, ok := h.client.Transport.(*H3Transport); ok {
+		h3.Close()
+	}
+}
+
+// stepClient returns the client that the step is sent by, the transport of the passed client is updated for the step.
+func (h *HttpRequester) stepClient(client *http.Client) *http.Client {
+	if client == nil {
+		// engine mode is 'ddosify'
+		// if passed client is nil , use requesters client that is dedicated to one step, thereby one transport
+		return h.client
+	}
+
+	// engine mode is 'distinct-user' or 'repeated-user'
+	// passed client is used for multiple steps throughout an iteration, update transport.
+	// Custom transports of the client factory are used as they are.
+	if h.packet.Protocol == types.ProtocolH2C || h.packet.Protocol == types.ProtocolH3 {
+		if _, ok := client.Transport.(*http.Transport); ok || client.Transport == nil {
+			// client is shared with the HTTP/1 steps, keep its jar but send this step over the h2c or h3 transport
+			stepClient := *client
+			stepClient.Transport = h.client.Transport
+			client = &stepClient
+		}
+	} else if client.Transport == nil {
+		htr := h.initTransport()
+		htr.MaxConnsPerHost = 1 // use same connection per host throughout an iteration
+		applyTransportConf(htr, h.packet.Transport)
+		client.Transport = htr
+	} else if tr, ok := client.Transport.(*http.Transport); ok {
+		h.updateTransport(tr)
+	}
+
+	// update client timeout
+	client.Timeout = h.packet.TimeoutDuration()
+	return client
+}
+
+// Warm sends a HEAD request to the url of the step, so the connection of the client is opened and kept alive for
+// the first request of the test. The cookies of the response are not kept, and the redirects are not followed.
+// Steps with the variables in their urls are skipped, their hosts are not known before the test.
+func (h *HttpRequester) Warm(client *http.Client) error {
+	if h.containsDynamicField["url"] || h.containsEnvVar["url"] || h.keepAliveDisabled() {
+		return nil
+	}
+
+	c := *h.stepClient(client)
+	c.Jar = nil
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodHead, h.packet.URL, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, res.Body)
+	return res.Body.Close()
 }
 
-func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (res *types.ScenarioStepResult) {
+func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) *types.ScenarioStepResult {
+	return h.send(client, envs, h.packet.RequestCompression != "")
+}
+
+// send sends the request, its body is compressed by the request compression of the step if compress is set.
+func (h *HttpRequester) send(client *http.Client, envs map[string]interface{},
+	compress bool) (res *types.ScenarioStepResult) {
 	var statusCode int
 	var contentLength int64
 	var requestErr types.RequestError
 	var reqStartTime = time.Now()
+	var requestID = uuid.New()
 
 	// for debug mode
 	var copiedReqBody []byte
 	var respBody []byte
 	var respHeaders http.Header
+	var respTrailers http.Header
 	var bodyReadErr error
+	var bodyTruncated bool
+	var dec *decompressor // nil if the response is not compressed
+	var proto string
+	var cert *types.CertInfo
 	var extractedVars = make(map[string]interface{})
 	var failedCaptures = make(map[string]string, 0)
 	var failedAssertions = make([]types.FailedAssertion, 0)
+	var schemaErrors []string
 
 	var usableVars = make(map[string]interface{}, len(envs))
 	for k, v := range envs {
 		usableVars[k] = v
 	}
 
-	if client == nil {
-		// engine mode is 'ddosify'
-		// if passed client is nil , use requesters client that is dedicated to one step, thereby one transport
-		client = h.client
-	} else {
-		// engine mode is 'distinct-user' or 'repeated-user'
-		// passed client is used for multiple steps throughout an iteration, update transport
-		if client.Transport == nil {
-			client.Transport = h.initTransport()
-			client.Transport.(*http.Transport).MaxConnsPerHost = 1 // use same connection per host throughout an iteration
-		} else {
-			h.updateTransport(client.Transport.(*http.Transport))
-		}
-
-		// update client timeout
-		client.Timeout = time.Duration(h.packet.Timeout) * time.Second
+	var validators *types.Validators
+	if h.packet.Validators != nil {
+		// the passed client is the virtual user, nil in the ddosify mode
+		validators = h.packet.Validators.Of(client)
 	}
 
+	passedClient := client
+	client = h.stepClient(client)
+
 	durations := &duration{
 		serverProcessDurCh:   make(chan time.Duration, 1),
 		serverProcessStartCh: make(chan time.Time, 1),
@@ -227,15 +388,54 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 		res = &types.ScenarioStepResult{
 			StepID:    h.packet.ID,
 			StepName:  h.packet.Name,
-			RequestID: uuid.New(),
+			RequestID: requestID,
 			Err:       requestErr,
 		}
 
 		return res
 	}
 
+	if h.packet.CSRF != nil {
+		if err = h.addCSRFToken(client, httpReq, usableVars); err != nil {
+			requestErr.Type = types.ErrorInvalidRequest
+			requestErr.Reason = fmt.Sprintf("Could not get csrf token, %s", err.Error())
+			if _, ok := err.(*url.Error); ok {
+				// form page could not be fetched, reported like the errors of the request
+				requestErr = fetchErrType(err)
+			}
+			return &types.ScenarioStepResult{
+				StepID:    h.packet.ID,
+				StepName:  h.packet.Name,
+				RequestID: requestID,
+				Err:       requestErr,
+			}
+		}
+	}
+
+	if h.packet.RequestIDHeader != "" {
+		httpReq.Header.Set(h.packet.RequestIDHeader, requestID.String())
+	}
+	if h.packet.Traceparent && httpReq.Header.Get(types.TraceparentHeader) == "" {
+		httpReq.Header.Set(types.TraceparentHeader, types.Traceparent(requestID))
+	}
+	h.setAcceptEncoding(httpReq)
+	var revalidated bool
+	if validators != nil {
+		revalidated = setConditionalHeaders(httpReq, validators)
+	}
+
+	var upload *uploadProgress
+	if h.chunked != nil {
+		upload = h.chunked.setBody(httpReq)
+	}
+
 	if httpReq.Body != nil {
-		if int64(len(h.packet.Payload)) > 300000 {
+		if h.multipart != nil {
+			// Don't read the streamed bodies into the memory
+			copiedReqBody = []byte("streamed multipart body")
+		} else if h.chunked != nil {
+			copiedReqBody = []byte("streamed chunked body")
+		} else if int64(len(h.packet.Payload)) > 300000 {
 			// Don't store req bodies bigger than 300KB
 			copiedReqBody = []byte("too long body")
 		} else {
@@ -248,14 +448,60 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 		}
 	}
 
+	// compressed after the copy, debug mode shows the uncompressed body
+	var reqBodyLength int64
+	if compress && httpReq.Body != nil {
+		if reqBodyLength, err = h.compressReqBody(httpReq); err != nil {
+			requestErr.Type = types.ErrorInvalidRequest
+			requestErr.Reason = fmt.Sprintf("Could not compress req body, %s", err.Error())
+			return &types.ScenarioStepResult{
+				StepID:    h.packet.ID,
+				StepName:  h.packet.Name,
+				RequestID: requestID,
+				Err:       requestErr,
+			}
+		}
+	}
+
+	for _, intercept := range h.packet.RequestInterceptors {
+		if err = intercept(httpReq); err != nil {
+			return &types.ScenarioStepResult{
+				StepID:    h.packet.ID,
+				StepName:  h.packet.Name,
+				RequestID: requestID,
+				Err:       types.RequestError{Type: types.ErrorInterceptor, Reason: err.Error()},
+			}
+		}
+	}
+
 	// Action
-	httpRes, err := client.Do(httpReq)
+	var redirects []types.RedirectHop
+	reqClient := h.redirectClient(client, &redirects)
+	if h.packet.Auth.Type == types.AuthNTLM {
+		reqClient.Transport = &ntlmTransport{base: reqClient.Transport}
+	}
+	httpRes, err := reqClient.Do(httpReq)
+	if err == nil && reqBodyLength > 0 && h.packet.RequestCompressionFallback &&
+		httpRes.StatusCode == http.StatusUnsupportedMediaType {
+		// compressed body is rejected, the request is sent again uncompressed
+		io.Copy(io.Discard, httpRes.Body)
+		httpRes.Body.Close()
+		go time.AfterFunc(10*time.Millisecond, durationCloseFunc(durations))
+
+		res = h.send(passedClient, envs, false)
+		res.CompressionFallback = true
+		return res
+	}
 	if err != nil {
 		requestErr = fetchErrType(err)
-		failedCaptures = h.captureEnvironmentVariables(nil, nil, nil, extractedVars)
+		failedCaptures = h.captureEnvironmentVariables(nil, nil, nil, nil, extractedVars)
 	} else {
-		// got response, no timeout or any other error, resStart should be set
-		durations.setResDur()
+		for _, intercept := range h.packet.ResponseInterceptors {
+			if ierr := intercept(httpRes); ierr != nil {
+				requestErr = types.RequestError{Type: types.ErrorInterceptor, Reason: ierr.Error()}
+				break
+			}
+		}
 	}
 
 	// From the DOC: If the Body is not both read to EOF and closed,
@@ -263,23 +509,77 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 	// may not be able to re-use a persistent TCP connection to the server for a subsequent "keep-alive" request.
 	if httpRes != nil {
 		// read resp body conditionally
-		if h.debug || len(h.packet.EnvsToCapture) > 0 || len(h.packet.Assertions) > 0 {
-			respBody, bodyReadErr = io.ReadAll(httpRes.Body)
+		var body io.Reader = httpRes.Body
+		if !h.compressionDisabled() && !httpRes.Uncompressed {
+			if dec = newDecompressor(httpRes.Body, httpRes.Header.Get("Content-Encoding")); dec != nil {
+				body = dec
+			}
+		}
+		maxBody := h.packet.MaxResponseBodyBytes
+		if maxBody > 0 {
+			// one more byte to detect the truncation
+			body = io.LimitReader(body, maxBody+1)
+		}
+		graphql := h.packet.Type == types.StepTypeGraphQL
+		if h.debug || graphql || h.schema != nil || len(h.packet.EnvsToCapture) > 0 || len(h.packet.Assertions) > 0 ||
+			h.packet.SuccessWhen != "" {
+			respBody, bodyReadErr = io.ReadAll(body)
 			if bodyReadErr != nil {
 				requestErr = fetchErrType(bodyReadErr)
 			}
+			if maxBody > 0 && int64(len(respBody)) > maxBody {
+				respBody = respBody[:maxBody]
+				bodyTruncated = true
+			}
+			if h.schema != nil && requestErr.Type == "" {
+				schemaErrors = h.schema.Validate(respBody, maxSchemaErrors)
+			}
+			if graphql && requestErr.Type == "" {
+				// GraphQL servers report the failed operations in the body, mostly with 200 status code
+				if reason, failed := graphQLErrors(respBody); failed {
+					requestErr = types.RequestError{Type: types.ErrorGraphQL, Reason: reason}
+				}
+			}
 		} else {
-			// do not write into memory, just read
-			_, bodyReadErr = io.Copy(io.Discard, httpRes.Body)
+			// do not write into memory, just read. Only the head of the body is kept for the failure samples,
+			// one more byte to detect the truncation
+			var n int64
+			if keep := h.packet.SampleBodyBytes; keep > 0 {
+				head := bytes.NewBuffer(make([]byte, 0, 512))
+				n, bodyReadErr = io.CopyN(head, body, keep+1)
+				if bodyReadErr == io.EOF {
+					bodyReadErr = nil
+				}
+				respBody = head.Bytes()
+			}
+			if bodyReadErr == nil {
+				var rest int64
+				rest, bodyReadErr = io.Copy(io.Discard, body)
+				n += rest
+			}
 			if bodyReadErr != nil {
 				requestErr = fetchErrType(bodyReadErr)
 			}
+			bodyTruncated = maxBody > 0 && n > maxBody
 		}
+		// got response, resStart should be set. Response read duration covers the content transfer of the body
+		durations.setResDur()
 
 		httpRes.Body.Close()
 		respHeaders = httpRes.Header
+		respTrailers = receivedTrailers(httpRes.Trailer)
 		contentLength = httpRes.ContentLength
+		if dec != nil && contentLength < 0 { // compressed bytes on the wire of the chunked responses
+			contentLength = dec.wire.n
+		}
 		statusCode = httpRes.StatusCode
+		proto = httpRes.Proto
+		if validators != nil {
+			storeValidators(httpReq, httpRes, validators)
+		}
+		if h.certs != nil && httpRes.TLS != nil {
+			cert = h.certs.get(httpRes.TLS, httpReq.URL.Hostname())
+		}
 		cookies := make(map[string]*http.Cookie, len(httpRes.Cookies()))
 		for _, cookie := range httpRes.Cookies() {
 			cookies[cookie.Name] = &http.Cookie{
@@ -298,21 +598,42 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 
 		// capture
 		if len(h.packet.EnvsToCapture) > 0 {
-			failedCaptures = h.captureEnvironmentVariables(httpRes.Header, respBody, cookies, extractedVars)
+			failedCaptures = h.captureEnvironmentVariables(httpRes.Header, respBody, cookies, respTrailers, extractedVars)
+			if requestErr.Type == "" {
+				requestErr = fatalCaptureError(h.packet.EnvsToCapture, failedCaptures)
+			}
 		}
 
 		// assert
-		if len(h.packet.Assertions) > 0 {
-			_, failedAssertions = h.applyAssertions(&evaluator.AssertEnv{
+		successWhen := h.packet.SuccessWhen != "" && len(h.packet.ExpectedStatus) == 0 && requestErr.Type == ""
+		var assertEnv *evaluator.AssertEnv
+		if len(h.packet.Assertions) > 0 || successWhen {
+			assertEnv = &evaluator.AssertEnv{
 				StatusCode:   int64(httpRes.StatusCode),
 				ResponseSize: int64(len(respBody)),
 				ResponseTime: durations.totalDuration().Milliseconds(), // in ms
 				Body:         string(respBody),
 				Headers:      httpRes.Header,
+				Trailers:     respTrailers,
 				Variables:    concatEnvs(envs, extractedVars),
 				Cookies:      cookies,
+			}
+		}
+		if len(h.packet.Assertions) > 0 {
+			_, failedAssertions = h.applyAssertions(assertEnv)
+		}
+		if len(h.packet.ExpectedStatus) > 0 && requestErr.Type == "" && !h.packet.ExpectedStatus.Match(statusCode) {
+			failedAssertions = append(failedAssertions, types.FailedAssertion{
+				Rule:     "expected_status: " + h.packet.ExpectedStatus.String(),
+				Received: map[string]interface{}{"status_code": statusCode},
 			})
 		}
+		if successWhen {
+			if _, failed := applyAssertions([]string{h.packet.SuccessWhen}, assertEnv); len(failed) > 0 {
+				failed[0].Rule = "success_when: " + failed[0].Rule
+				failedAssertions = append(failedAssertions, failed[0])
+			}
+		}
 	}
 
 	var ddResTime time.Duration
@@ -328,7 +649,7 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 	res = &types.ScenarioStepResult{
 		StepID:        h.packet.ID,
 		StepName:      h.packet.Name,
-		RequestID:     uuid.New(),
+		RequestID:     requestID,
 		StatusCode:    statusCode,
 		RequestTime:   reqStartTime,
 		Duration:      durations.totalDuration(),
@@ -342,6 +663,14 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 		RespHeaders: respHeaders,
 		RespBody:    respBody,
 
+		RespBodyTruncated: bodyTruncated,
+		RespTrailers:      respTrailers,
+		Redirects:         redirects,
+		Conn:              durations.getConnState(),
+		Proto:             proto,
+		Cert:              cert,
+		Revalidated:       revalidated,
+
 		Custom: map[string]interface{}{
 			"dnsDuration":           durations.getDNSDur(),
 			"connDuration":          durations.getConnDur(),
@@ -353,9 +682,11 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 		UsableEnvs:       usableVars,
 		FailedCaptures:   failedCaptures,
 		FailedAssertions: failedAssertions,
+		SchemaErrors:     schemaErrors,
 	}
 
-	if strings.EqualFold(h.request.URL.Scheme, types.ProtocolHTTPS) { // TODOcorr : check here, used URL.scheme instead TODOcorr
+	// scheme of the prepared request, the configured url may be dynamic
+	if strings.EqualFold(httpReq.URL.Scheme, types.ProtocolHTTPS) {
 		res.Custom["tlsDuration"] = durations.getTLSDur()
 	}
 
@@ -363,9 +694,65 @@ func (h *HttpRequester) Send(client *http.Client, envs map[string]interface{}) (
 		res.Custom["ddResponseTime"] = ddResTime
 	}
 
+	if dec != nil {
+		res.DecompressedLength = dec.n
+		res.Custom["decompressDuration"] = dec.dur
+	}
+
+	if reqBodyLength > 0 {
+		res.ReqBodyLength = reqBodyLength
+		res.ReqCompressedBodyLength = httpReq.ContentLength
+	}
+	if upload != nil {
+		res.UploadedBytes, res.UploadDuration = upload.result()
+	}
+	if httpRes != nil {
+		res.Throttled, res.RetryAfter = throttled(httpRes.StatusCode, httpRes.Header, time.Now())
+	}
+
 	return
 }
 
+// throttled reports whether the response is a 429 Too Many Requests, or a 503 Service Unavailable with a
+// Retry-After header, and returns the delay of its Retry-After header. The header is given in seconds or as an
+// HTTP date, the delay is zero if it is missing, invalid or in the past.
+func throttled(statusCode int, header http.Header, now time.Time) (bool, time.Duration) {
+	v := strings.TrimSpace(header.Get("Retry-After"))
+	if statusCode != http.StatusTooManyRequests && (statusCode != http.StatusServiceUnavailable || v == "") {
+		return false, 0
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return true, 0
+		}
+		return true, time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return true, t.Sub(now)
+	}
+	return true, 0
+}
+
+// graphQLErrors returns the message of the first error if the errors array of the GraphQL response is not empty.
+func graphQLErrors(body []byte) (string, bool) {
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Errors) == 0 {
+		return "", false
+	}
+	reason := resp.Errors[0].Message
+	if reason == "" {
+		reason = "graphql error"
+	}
+	if len(resp.Errors) > 1 {
+		reason = fmt.Sprintf("%s (and %d more)", reason, len(resp.Errors)-1)
+	}
+	return reason, true
+}
+
 var durationCloseFunc = func(d *duration) func() {
 	return func() {
 		d.close()
@@ -386,6 +773,22 @@ func concatEnvs(envs1, envs2 map[string]interface{}) map[string]interface{} {
 	return total
 }
 
+// receivedTrailers returns the trailers sent by the server after the body, nil if there are none. The trailers
+// announced by the Trailer header but not sent have no values, they are left out.
+func receivedTrailers(trailer http.Header) http.Header {
+	var received http.Header
+	for k, v := range trailer {
+		if len(v) == 0 {
+			continue
+		}
+		if received == nil {
+			received = make(http.Header, len(trailer))
+		}
+		received[k] = v
+	}
+	return received
+}
+
 func concatHeaders(envs1, envs2 map[string][]string) map[string][]string {
 	total := make(map[string][]string)
 
@@ -405,14 +808,24 @@ func (h *HttpRequester) prepareReq(envs map[string]interface{}, trace *httptrace
 	httpReq := h.request.Clone(h.ctx)
 
 	body := h.packet.Payload
-	if h.containsDynamicField["body"] || h.containsEnvVar["body"] {
-		pieces := h.ei.GenerateBodyPieces(body, envs)
+	if h.multipart != nil {
+		values, length := h.multipart.inject(envs)
+		httpReq.Body = h.multipart.reader(values)
+		httpReq.ContentLength = length
+		httpReq.GetBody = func() (io.ReadCloser, error) { // for redirects
+			return h.multipart.reader(values), nil
+		}
+	} else if h.containsDynamicField["body"] || h.containsEnvVar["body"] {
+		pieces := h.ei.GenerateTemplatePieces(h.bodyTemplate, envs)
 		customReader := injection.DdosifyBodyReader{
 			Body:   body,
 			Pieces: pieces,
 		}
 		httpReq.Body = &customReader
 		httpReq.ContentLength = int64(injection.GetContentLength(pieces))
+		httpReq.GetBody = func() (io.ReadCloser, error) { // for redirects, the cloned one returns the raw payload
+			return &injection.DdosifyBodyReader{Body: body, Pieces: pieces}, nil
+		}
 	} else {
 		// if body is constant, we can just set it
 		httpReq.Body = io.NopCloser(bytes.NewReader(injection.StringToBytes(body)))
@@ -424,7 +837,7 @@ func (h *HttpRequester) prepareReq(envs map[string]interface{}, trace *httptrace
 	var errURL error
 
 	if h.containsDynamicField["url"] {
-		hostURL, _ = h.ei.InjectDynamic(hostURL)
+		hostURL, _ = h.ei.Of(envs).InjectDynamic(hostURL)
 	}
 	if h.containsEnvVar["url"] {
 		hostURL, errURL = h.ei.InjectEnv(hostURL, envs)
@@ -451,10 +864,10 @@ func (h *HttpRequester) prepareReq(envs map[string]interface{}, trace *httptrace
 				kk := k
 				vv := v
 				if re.MatchString(v) {
-					vv, _ = h.ei.InjectDynamic(v)
+					vv, _ = h.ei.Of(envs).InjectDynamic(v)
 				}
 				if re.MatchString(k) {
-					kk, _ = h.ei.InjectDynamic(k)
+					kk, _ = h.ei.Of(envs).InjectDynamic(k)
 					httpReq.Header.Del(k)
 				}
 				httpReq.Header.Set(kk, vv)
@@ -490,8 +903,8 @@ func (h *HttpRequester) prepareReq(envs map[string]interface{}, trace *httptrace
 
 	username, password := h.packet.Auth.Username, h.packet.Auth.Password
 	if h.containsDynamicField["basicauth"] {
-		username, _ = h.ei.InjectDynamic(username)
-		password, _ = h.ei.InjectDynamic(password)
+		username, _ = h.ei.Of(envs).InjectDynamic(username)
+		password, _ = h.ei.Of(envs).InjectDynamic(password)
 	}
 	if h.containsEnvVar["basicauth"] {
 		var err error
@@ -505,10 +918,37 @@ func (h *HttpRequester) prepareReq(envs map[string]interface{}, trace *httptrace
 			return nil, err
 		}
 	}
-	if username != "" && password != "" {
+	if h.packet.Auth.Type == types.AuthNTLM {
+		// the handshake is done by the ntlmTransport when the server challenges the request
+		httpReq = httpReq.WithContext(withNTLMCreds(httpReq.Context(), username, password))
+	} else if username != "" && password != "" {
 		httpReq.SetBasicAuth(username, password)
 	}
 
+	if h.packet.Auth.Type == types.AuthBearer {
+		token := h.packet.Auth.Token
+		if h.containsDynamicField["bearer"] {
+			token, _ = h.ei.Of(envs).InjectDynamic(token)
+		}
+		if h.containsEnvVar["bearer"] {
+			var err error
+			token, err = h.ei.InjectEnv(token, envs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if h.tokenSource != nil {
+		// cached token is returned until it is about to expire
+		token, err := h.tokenSource.Token()
+		if err != nil {
+			return nil, fmt.Errorf("oauth2 token could not be fetched: %w", err)
+		}
+		token.SetAuthHeader(httpReq)
+	}
+
 	httpReq = httpReq.WithContext(httptrace.WithClientTrace(httpReq.Context(), trace))
 	return httpReq, nil
 }
@@ -523,7 +963,14 @@ func fetchErrType(err error) types.RequestError {
 	ue, ok := err.(*url.Error)
 	if ok {
 		errString := ue.Error()
-		if strings.Contains(errString, "proxyconnect") {
+		var dialErr *dialTimeoutError
+		if portExhausted(err) {
+			requestErr = types.RequestError{Type: types.ErrorLocal, Reason: types.ReasonPortExhausted}
+		} else if errors.As(err, &dialErr) {
+			requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonDialTimeout}
+		} else if strings.Contains(errString, "TLS handshake timeout") {
+			requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonTLSTimeout}
+		} else if strings.Contains(errString, "proxyconnect") {
 			if strings.Contains(errString, "connection refused") {
 				requestErr = types.RequestError{Type: types.ErrorProxy, Reason: types.ReasonProxyFailed}
 			} else if strings.Contains(errString, "Client.Timeout") {
@@ -532,11 +979,11 @@ func fetchErrType(err error) types.RequestError {
 				requestErr = types.RequestError{Type: types.ErrorProxy, Reason: errString}
 			}
 		} else if strings.Contains(errString, context.DeadlineExceeded.Error()) {
-			requestErr = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}
+			requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonConnTimeout}
 		} else if strings.Contains(errString, "Client.Timeout exceeded while awaiting headers") {
-			requestErr = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}
+			requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonConnTimeout}
 		} else if strings.Contains(errString, "i/o timeout") {
-			requestErr = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonReadTimeout}
+			requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonReadTimeout}
 		} else if strings.Contains(errString, "connection refused") {
 			requestErr = types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnRefused}
 		} else if strings.Contains(errString, context.Canceled.Error()) {
@@ -551,58 +998,184 @@ func fetchErrType(err error) types.RequestError {
 	return requestErr
 }
 
+// portExhausted reports whether the error is caused by the exhausted ephemeral ports of the load generator, it is
+// EADDRNOTAVAIL on Linux and WSAEADDRNOTAVAIL on Windows.
+func portExhausted(err error) bool {
+	if errors.Is(err, syscall.EADDRNOTAVAIL) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "cannot assign requested address") ||
+		strings.Contains(msg, "requested address is not valid in its context")
+}
+
 func (h *HttpRequester) initTransport() *http.Transport {
 	tr := &http.Transport{
-		TLSClientConfig: h.initTLSConfig(),
-		Proxy:           http.ProxyURL(h.proxyAddr),
+		TLSClientConfig:     h.initTLSConfig(),
+		Proxy:               http.ProxyURL(h.proxyAddr),
+		DialContext:         h.dialContext(),
+		TLSHandshakeTimeout: h.packet.TLSHandshakeTimeout,
 	}
 
-	tr.DisableKeepAlives = false
-	if h.packet.Headers["Connection"] == "close" {
-		tr.DisableKeepAlives = true
-	}
+	tr.DisableKeepAlives = h.keepAliveDisabled()
 	if val, ok := h.packet.Custom["disable-compression"]; ok {
 		tr.DisableCompression = val.(bool)
 	}
 	if val, ok := h.packet.Custom["h2"]; ok {
 		val := val.(bool)
 		if val {
-			http2.ConfigureTransport(tr)
+			h.configureH2(tr)
 		}
 	}
 	return tr
 }
 
+// redirectClient returns a copy of the client following the redirects by the redirect policy of the step, the
+// followed redirects are appended to hops. The copy shares the transport and the jar of the client.
+func (h *HttpRequester) redirectClient(client *http.Client, hops *[]types.RedirectHop) *http.Client {
+	maxRedirects := h.packet.Redirect.MaxRedirects()
+	hopStart := time.Now()
+
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			// the last redirect response is the result
+			return http.ErrUseLastResponse
+		}
+		now := time.Now()
+		*hops = append(*hops, types.RedirectHop{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: req.Response.StatusCode,
+			Duration:   now.Sub(hopStart),
+		})
+		hopStart = now
+		return nil
+	}
+	return &c
+}
+
+// applyTransportConf overrides the connection limits of the transport by the given non-zero limits.
+func applyTransportConf(tr *http.Transport, conf types.TransportConf) {
+	if conf.MaxIdleConns > 0 {
+		tr.MaxIdleConns = conf.MaxIdleConns
+	}
+	if conf.MaxIdleConnsPerHost > 0 {
+		tr.MaxIdleConnsPerHost = conf.MaxIdleConnsPerHost
+	}
+	if conf.MaxConnsPerHost > 0 {
+		tr.MaxConnsPerHost = conf.MaxConnsPerHost
+	}
+	if conf.IdleConnTimeout > 0 {
+		tr.IdleConnTimeout = conf.IdleConnTimeout
+	}
+}
+
+// configureH2 enables HTTP/2 on the transport, its idle connections are pinged by the keep-alive ping of the step.
+// A transport configured before, like the pooled client transport updated by each step, is kept as is.
+func (h *HttpRequester) configureH2(tr *http.Transport) {
+	if h2, err := http2.ConfigureTransports(tr); err == nil {
+		applyKeepAlivePing(h2, h.packet.Transport.KeepAlivePing)
+	}
+}
+
+// applyKeepAlivePing pings the idle HTTP/2 connections of the transport at the given interval if it is positive.
+// Connections not answering a ping in the PingTimeout of the transport, 15 seconds by default, are closed.
+func applyKeepAlivePing(tr *http2.Transport, interval time.Duration) {
+	if interval > 0 {
+		tr.ReadIdleTimeout = interval
+	}
+}
+
+// dialContext returns the dial function of the transports of the step, limited by the dial timeout of the step.
+// Nil means the default dialer of the transport.
+func (h *HttpRequester) dialContext() DialContextFunc {
+	dial := h.packet.DialContext
+	timeout := h.packet.DialTimeout
+	if timeout <= 0 {
+		return dial
+	}
+	if dial == nil {
+		dial = (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		conn, err := dial(dialCtx, network, addr)
+		if err != nil && ctx.Err() == nil && dialCtx.Err() == context.DeadlineExceeded {
+			return nil, &dialTimeoutError{err: err}
+		}
+		return conn, err
+	}
+}
+
+// dialTimeoutError is returned by the dial function of a step if the dial timeout of the step is exceeded.
+type dialTimeoutError struct {
+	err error
+}
+
+func (e *dialTimeoutError) Error() string {
+	return "dial timeout: " + e.err.Error()
+}
+
+func (e *dialTimeoutError) Unwrap() error {
+	return e.err
+}
+
+// NewH2CTransport returns a transport that speaks HTTP/2 over plain TCP with prior knowledge, without the
+// HTTP/1 upgrade. Connections are dialed by dial, or by a default net.Dialer if it is nil.
+// Proxies are not supported by the h2c transport.
+func NewH2CTransport(dial DialContextFunc) *http2.Transport {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return dial(ctx, network, addr)
+		},
+	}
+}
+
 func (h *HttpRequester) updateTransport(tr *http.Transport) {
 	tr.TLSClientConfig = h.initTLSConfig()
 	tr.Proxy = http.ProxyURL(h.proxyAddr)
+	tr.DialContext = h.dialContext()
+	tr.TLSHandshakeTimeout = h.packet.TLSHandshakeTimeout
 
-	tr.DisableKeepAlives = false
-	if h.packet.Headers["Connection"] == "close" {
-		tr.DisableKeepAlives = true
-	}
+	tr.DisableKeepAlives = h.keepAliveDisabled()
 	if val, ok := h.packet.Custom["disable-compression"]; ok {
 		tr.DisableCompression = val.(bool)
 	}
 	if val, ok := h.packet.Custom["h2"]; ok {
 		val := val.(bool)
 		if val {
-			http2.ConfigureTransport(tr)
+			h.configureH2(tr)
 		}
 	}
 }
 
 func (h *HttpRequester) initTLSConfig() *tls.Config {
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: true,
-	}
+	return newTLSConfig(h.packet)
+}
+
+// newTLSConfig returns the tls.Config of the step. If the step has a TLSConfig, its clone is returned,
+// so certificates loaded once are shared without sharing the config itself that may be modified by the transport.
+func newTLSConfig(s types.ScenarioStep) *tls.Config {
+	var tlsConfig *tls.Config
+	if s.TLSConfig != nil {
+		tlsConfig = s.TLSConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
 
-	if h.packet.CertPool != nil && h.packet.Cert.Certificate != nil {
-		tlsConfig.RootCAs = h.packet.CertPool
-		tlsConfig.Certificates = []tls.Certificate{h.packet.Cert}
+		if s.CertPool != nil && s.Cert.Certificate != nil {
+			tlsConfig.RootCAs = s.CertPool
+			tlsConfig.Certificates = []tls.Certificate{s.Cert}
+		}
 	}
 
-	if val, ok := h.packet.Custom["hostname"]; ok {
+	if val, ok := s.Custom["hostname"]; ok {
 		tlsConfig.ServerName = val.(string)
 	}
 	return tlsConfig
@@ -634,21 +1207,30 @@ func (h *HttpRequester) initRequestInstance() (err error) {
 		}
 	}
 
+	if h.multipart != nil {
+		header.Set("Content-Type", h.multipart.contentType())
+	} else if h.packet.Type == types.StepTypeGraphQL && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+
 	h.request.Header = header
 
 	// Auth should be set after header assignment.
-	if h.packet.Auth != (types.Auth{}) {
+	if h.packet.Auth != (types.Auth{}) && h.packet.Auth.Type != types.AuthOAuth2ClientCredentials &&
+		h.packet.Auth.Type != types.AuthBearer && h.packet.Auth.Type != types.AuthNTLM {
 		h.request.SetBasicAuth(h.packet.Auth.Username, h.packet.Auth.Password)
 	}
 
 	// If keep-alive is false, prevent the reuse of the previous TCP connection at the request layer also.
-	h.request.Close = false
-	if h.packet.Headers["Connection"] == "close" {
-		h.request.Close = true
-	}
+	h.request.Close = h.keepAliveDisabled()
 	return
 }
 
+// keepAliveDisabled returns true if a new connection should be opened for each request of the step.
+func (h *HttpRequester) keepAliveDisabled() bool {
+	return h.packet.DisableKeepAlive || h.packet.Headers["Connection"] == "close"
+}
+
 func (h *HttpRequester) Type() string {
 	return "HTTP"
 }
@@ -663,6 +1245,8 @@ func newTrace(duration *duration, proxyAddr *url.URL, headersByClient map[string
 	// For start times, except resStart, this mutex is been using.
 	// For duration calculations, "duration" struct internally uses another mutex.
 	var m sync.Mutex
+	var conn net.Conn // connection of the request and its use, see PutIdleConn
+	var use uint64
 
 	return &httptrace.ClientTrace{
 		DNSStart: func(info httptrace.DNSStartInfo) {
@@ -723,7 +1307,18 @@ func newTrace(duration *duration, proxyAddr *url.URL, headersByClient map[string
 			if reqStart.IsZero() {
 				reqStart = time.Now()
 			}
+			conn, use = connInfo.Conn, useConn(connInfo.Conn)
 			m.Unlock()
+			duration.setConnState(connInfo.Reused)
+		},
+		PutIdleConn: func(err error) {
+			// the connection is back in the pool of the transport, see ConnLimiter
+			m.Lock()
+			c, u := conn, use
+			m.Unlock()
+			if err == nil && c != nil {
+				idleConn(c, u)
+			}
 		},
 		WroteRequest: func(w httptrace.WroteRequestInfo) {
 			// no need to handle error in here. We can detect it at http.Client.Do return.
@@ -743,8 +1338,12 @@ func newTrace(duration *duration, proxyAddr *url.URL, headersByClient map[string
 }
 
 func (h *HttpRequester) applyAssertions(assertEnv *evaluator.AssertEnv) (bool, []types.FailedAssertion) {
+	return applyAssertions(h.packet.Assertions, assertEnv)
+}
+
+// applyAssertions is shared by the requester implementations, returns the failed rules with received values.
+func applyAssertions(assertions []string, assertEnv *evaluator.AssertEnv) (bool, []types.FailedAssertion) {
 	// result, failedAssertionIndex, assertionError
-	assertions := h.packet.Assertions
 	assertionsSuccess := true
 	failedAssertions := []types.FailedAssertion{}
 	for _, rule := range assertions {
@@ -773,43 +1372,76 @@ func (h *HttpRequester) applyAssertions(assertEnv *evaluator.AssertEnv) (bool, [
 }
 
 func (h *HttpRequester) captureEnvironmentVariables(header http.Header, respBody []byte,
-	cookies map[string]*http.Cookie, extractedVars map[string]interface{}) map[string]string {
+	cookies map[string]*http.Cookie, trailers http.Header, extractedVars map[string]interface{}) map[string]string {
+	return captureEnvironmentVariables(h.packet.EnvsToCapture, header, respBody, cookies, trailers, extractedVars)
+}
+
+// captureEnvironmentVariables is shared by the requester implementations, fills extractedVars and
+// returns the failed captures with their reasons.
+func captureEnvironmentVariables(envsToCapture []types.EnvCaptureConf, header http.Header, respBody []byte,
+	cookies map[string]*http.Cookie, trailers http.Header, extractedVars map[string]interface{}) map[string]string {
 	var err error
 	failedCaptures := make(map[string]string, 0)
 	var captureError extraction.ExtractionError
 
 	// request failed, only set default value for later steps
 	if header == nil && respBody == nil {
-		for _, ce := range h.packet.EnvsToCapture {
-			extractedVars[ce.Name] = "" // default value for not extracted envs
+		for _, ce := range envsToCapture {
+			for _, v := range ce.Vars() {
+				extractedVars[v] = "" // default value for not extracted envs
+			}
 			failedCaptures[ce.Name] = "request failed"
 		}
 		return failedCaptures
 	}
 
 	// extract from response
-	for _, ce := range h.packet.EnvsToCapture {
-		var val interface{}
+	for _, ce := range envsToCapture {
+		var val, source interface{}
 		switch ce.From {
 		case types.Header:
-			val, err = extraction.Extract(header, ce)
+			source = header
 		case types.Body:
-			val, err = extraction.Extract(respBody, ce)
+			source = respBody
 		case types.Cookie:
-			val, err = extraction.Extract(cookies, ce)
+			source = cookies
+		case types.Trailer:
+			source = trailers
 		}
+		vars := ce.Vars()
+		val, err = extraction.Extract(source, ce)
 		if err != nil && errors.As(err, &captureError) {
 			// do not terminate in case of a capture error, continue capturing
-			extractedVars[ce.Name] = "" // default value for not extracted envs
+			for _, v := range vars {
+				extractedVars[v] = "" // default value for not extracted envs
+			}
 			failedCaptures[ce.Name] = captureError.Error()
 			continue
 		}
 		extractedVars[ce.Name] = val
+		if len(vars) > 1 {
+			// named groups of the matched expression
+			groups, _ := extraction.ExtractRegexGroups(source, ce)
+			for _, v := range vars[1:] {
+				extractedVars[v] = groups[v]
+			}
+		}
 	}
 
 	return failedCaptures
 }
 
+// fatalCaptureError returns the ErrorCapture of the first failed fatal capture by the order of the captures, the
+// zero RequestError if none of them failed.
+func fatalCaptureError(envsToCapture []types.EnvCaptureConf, failedCaptures map[string]string) types.RequestError {
+	for _, ce := range envsToCapture {
+		if reason, failed := failedCaptures[ce.Name]; failed && ce.Fatal() {
+			return types.RequestError{Type: types.ErrorCapture, Reason: fmt.Sprintf("capture %s failed, %s", ce.Name, reason)}
+		}
+	}
+	return types.RequestError{}
+}
+
 type duration struct {
 
 	// DNS lookup duration. If IP:Port porvided instead of domain, this will be 0
@@ -830,6 +1462,9 @@ type duration struct {
 	// Duration between full request write to first response. AKA Time To First Byte (TTFB)
 	serverProcessDur time.Duration
 
+	// Whether the connection of the request is reused
+	connState types.ConnState
+
 	// Time at response reading start
 	resStart         time.Time
 	resStartCh       chan time.Time
@@ -880,6 +1515,24 @@ func (d *duration) setDNSDur(t time.Duration) {
 	}
 }
 
+// setConnState keeps the connection of the first request, redirects may obtain other connections.
+func (d *duration) setConnState(reused bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.connState == types.ConnUnknown {
+		d.connState = types.ConnNew
+		if reused {
+			d.connState = types.ConnReused
+		}
+	}
+}
+
+func (d *duration) getConnState() types.ConnState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.connState
+}
+
 func (d *duration) getDNSDur() time.Duration {
 	d.mu.Lock()
 	defer d.mu.Unlock()
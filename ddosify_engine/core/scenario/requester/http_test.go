@@ -24,17 +24,29 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httptrace"
 	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
 	"go.ddosify.com/ddosify/core/types"
 	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func TestInit(t *testing.T) {
@@ -689,6 +701,28 @@ func TestTraceServerProcessDur_ErrCase(t *testing.T) {
 	}
 }
 
+func TestFetchErrTypePortExhausted(t *testing.T) {
+	t.Parallel()
+
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EADDRNOTAVAIL)}
+	tests := []struct {
+		name string
+		err  types.RequestError
+	}{
+		{"HTTP", fetchErrType(&url.Error{Op: "Get", URL: "http://test.com", Err: dialErr})},
+		{"Socket", fetchSocketErrType(context.Background(), dialErr)},
+		{"Windows", fetchErrType(&url.Error{Op: "Get", URL: "http://test.com",
+			Err: errors.New("connectex: The requested address is not valid in its context.")})},
+	}
+
+	expected := types.RequestError{Type: types.ErrorLocal, Reason: types.ReasonPortExhausted}
+	for _, test := range tests {
+		if test.err != expected {
+			t.Errorf("%s Expected %v, Found: %v", test.name, expected, test.err)
+		}
+	}
+}
+
 func TestResponseCookiesSentToAssertions(t *testing.T) {
 	t.Parallel()
 	// Test server
@@ -734,3 +768,1232 @@ func TestResponseCookiesSentToAssertions(t *testing.T) {
 		t.Errorf("received expected %s, got %v", "Ronaldo", res.FailedAssertions[0].Received)
 	}
 }
+
+func TestSendH2C(t *testing.T) {
+	t.Parallel()
+
+	var protoMajor int
+	handler := h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protoMajor = r.ProtoMajor
+	}), &http2.Server{})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:       1,
+		Method:   http.MethodGet,
+		URL:      server.URL,
+		Timeout:  types.DefaultTimeout,
+		Protocol: types.ProtocolH2C,
+	}
+
+	h := &HttpRequester{}
+	err := h.Init(context.TODO(), s, nil, false, nil)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	// requester's own client and a pooled client shared with HTTP/1 steps
+	for _, c := range []*http.Client{nil, {Transport: &http.Transport{}}} {
+		protoMajor = 0
+		res := h.Send(c, map[string]interface{}{})
+		if res.Err.Type != "" {
+			t.Fatalf("Expected no error, Found: %v", res.Err)
+		}
+		if protoMajor != 2 {
+			t.Errorf("Expected %v, Found: %v", 2, protoMajor)
+		}
+		if res.Proto != "HTTP/2.0" {
+			t.Errorf("Expected %v, Found: %v", "HTTP/2.0", res.Proto)
+		}
+	}
+}
+
+func TestSendWithTLSConfig(t *testing.T) {
+	t.Parallel()
+
+	var gotClientCert bool
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotClientCert = len(r.TLS.PeerCertificates) > 0
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	ca := x509.NewCertPool()
+	ca.AddCert(server.Certificate())
+	serverCert := server.TLS.Certificates[0] // reused as the client cert
+
+	tests := []struct {
+		name      string
+		tlsConfig *tls.Config
+		shouldErr bool
+	}{
+		{"MutualTLS", &tls.Config{RootCAs: ca, Certificates: []tls.Certificate{serverCert}}, false},
+		{"NoClientCert", &tls.Config{RootCAs: ca}, true},
+		{"UnknownCA", &tls.Config{Certificates: []tls.Certificate{serverCert}}, true},
+		{"MinVersionNotSupported", &tls.Config{RootCAs: ca, Certificates: []tls.Certificate{serverCert},
+			MaxVersion: tls.VersionTLS11}, true},
+	}
+
+	for _, test := range tests {
+		gotClientCert = false
+		s := types.ScenarioStep{
+			ID:        1,
+			Method:    http.MethodGet,
+			URL:       server.URL,
+			Timeout:   types.DefaultTimeout,
+			TLSConfig: test.tlsConfig,
+		}
+
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+
+		// requester's own client and a pooled client
+		for _, c := range []*http.Client{nil, {}} {
+			res := h.Send(c, map[string]interface{}{})
+			if test.shouldErr && res.Err.Type == "" {
+				t.Errorf("%s should be errored", test.name)
+			}
+			if !test.shouldErr && (res.Err.Type != "" || !gotClientCert) {
+				t.Errorf("%s Expected no error and client cert, Found: %v, %v", test.name, res.Err, gotClientCert)
+			}
+		}
+		h.Done()
+
+		if test.tlsConfig.NextProtos != nil || test.tlsConfig.ServerName != "" {
+			t.Errorf("%s shared tls config should not be modified", test.name)
+		}
+	}
+}
+
+func TestSendWithTemplateFunctions(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		path, header, body string
+	}
+	var got []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got = append(got, received{r.URL.Path, r.Header.Get("X-Request-Id"), string(body)})
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodPost,
+		URL:     server.URL + "/{{randomString(8)}}",
+		Headers: map[string]string{"X-Request-Id": "{{uuid()}}"},
+		Payload: "n={{randomInt(1,1000000)}}",
+		Timeout: types.DefaultTimeout,
+	}
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	for i := 0; i < 2; i++ {
+		if res := h.Send(nil, map[string]interface{}{}); res.Err.Type != "" {
+			t.Fatalf("Send: %v", res.Err)
+		}
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected %d, Found: %d", 2, len(got))
+	}
+	for _, r := range got {
+		if strings.Contains(r.path+r.header+r.body, "{{") || len(r.path) != 9 || !strings.HasPrefix(r.body, "n=") {
+			t.Errorf("Expected template functions to be evaluated, Found: %v", r)
+		}
+	}
+	if got[0].header == got[1].header || got[0].path == got[1].path {
+		t.Errorf("Expected new values on each request, Found: %v", got)
+	}
+}
+
+func TestSendWithSigningFunctions(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		signature, auth, body string
+	}
+	got := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got <- received{r.Header.Get("X-Signature"), r.Header.Get("Authorization"), string(body)}
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:     1,
+		Method: http.MethodPost,
+		URL:    server.URL,
+		Headers: map[string]string{
+			"X-Signature":   `{{hmacSHA256("{{secret}}", "{{payload}}")}}`,
+			"Authorization": `Basic {{base64("{{user}}:{{pass}}")}}`,
+		},
+		Payload: "{{payload}}",
+		Timeout: types.DefaultTimeout,
+	}
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	envs := map[string]interface{}{"secret": "key", "payload": "The quick brown fox jumps over the lazy dog",
+		"user": "user", "pass": "pass"}
+	if res := h.Send(nil, envs); res.Err.Type != "" {
+		t.Fatalf("Send: %v", res.Err)
+	}
+
+	expected := received{
+		signature: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		auth:      "Basic dXNlcjpwYXNz",
+		body:      "The quick brown fox jumps over the lazy dog",
+	}
+	if r := <-got; r != expected {
+		t.Errorf("Expected %v, Found: %v", expected, r)
+	}
+}
+
+func TestSendWithRequestIDHeader(t *testing.T) {
+	t.Parallel()
+
+	ids := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids <- r.Header.Get("X-Correlation-Id")
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:              1,
+		Method:          http.MethodGet,
+		URL:             server.URL,
+		Timeout:         types.DefaultTimeout,
+		RequestIDHeader: "X-Correlation-Id",
+	}
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	var sent []string
+	for i := 0; i < 2; i++ {
+		res := h.Send(nil, map[string]interface{}{})
+		if res.Err.Type != "" {
+			t.Fatalf("Send: %v", res.Err)
+		}
+		if got := <-ids; got != res.RequestID.String() {
+			t.Errorf("Expected %v, Found: %v", res.RequestID.String(), got)
+		}
+		if got := res.ReqHeaders.Get("X-Correlation-Id"); got != res.RequestID.String() {
+			t.Errorf("Expected %v, Found: %v", res.RequestID.String(), got)
+		}
+		sent = append(sent, res.RequestID.String())
+	}
+	if sent[0] == sent[1] {
+		t.Errorf("Expected a unique id for each request, Found: %v", sent)
+	}
+}
+
+func TestSendWithTraceparent(t *testing.T) {
+	t.Parallel()
+
+	traceparents := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents <- r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:          1,
+		Method:      http.MethodGet,
+		URL:         server.URL,
+		Timeout:     types.DefaultTimeout,
+		Traceparent: true,
+	}
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	res := h.Send(nil, map[string]interface{}{})
+	if res.Err.Type != "" {
+		t.Fatalf("Send: %v", res.Err)
+	}
+	traceID, spanID := types.TraceIDs(res.RequestID)
+	expected := "00-" + traceID + "-" + spanID + "-01"
+	if got := <-traceparents; got != expected {
+		t.Errorf("Expected %v, Found: %v", expected, got)
+	}
+}
+
+func TestSendWithMaxResponseBodyBytes(t *testing.T) {
+	t.Parallel()
+
+	bodySize := 1 << 20
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), bodySize))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		maxBody       int64
+		assertions    []string // body is buffered if there is an assertion
+		expectedLen   int
+		expectedTrunc bool
+	}{
+		{"BufferedTruncated", 1000, []string{"status_code == 200"}, 1000, true},
+		{"BufferedNotTruncated", int64(bodySize), []string{"status_code == 200"}, bodySize, false},
+		{"BufferedUnlimited", 0, []string{"status_code == 200"}, bodySize, false},
+		{"DiscardedTruncated", 1000, nil, 0, true},
+		{"DiscardedNotTruncated", int64(bodySize) + 1, nil, 0, false},
+	}
+
+	for _, test := range tests {
+		s := types.ScenarioStep{
+			ID:                   1,
+			Method:               http.MethodGet,
+			URL:                  server.URL,
+			Timeout:              types.DefaultTimeout,
+			Assertions:           test.assertions,
+			MaxResponseBodyBytes: test.maxBody,
+		}
+
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		res := h.Send(nil, map[string]interface{}{})
+		h.Done()
+
+		if res.Err.Type != "" || len(res.FailedAssertions) > 0 {
+			t.Errorf("%s unexpected error: %v %v", test.name, res.Err, res.FailedAssertions)
+		}
+		if len(res.RespBody) != test.expectedLen || res.RespBodyTruncated != test.expectedTrunc {
+			t.Errorf("%s Expected %d bytes truncated %v, Found: %d bytes truncated %v",
+				test.name, test.expectedLen, test.expectedTrunc, len(res.RespBody), res.RespBodyTruncated)
+		}
+	}
+}
+
+func TestSendPhaseDurations(t *testing.T) {
+	t.Parallel()
+
+	bodyDelay := 100 * time.Millisecond
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first part"))
+		w.(http.Flusher).Flush()
+		time.Sleep(bodyDelay)
+		w.Write([]byte("second part"))
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+	}
+
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	res := h.Send(nil, map[string]interface{}{})
+	if res.Err.Type != "" {
+		t.Fatalf("Send: %v", res.Err)
+	}
+
+	for _, k := range []string{"dnsDuration", "connDuration", "tlsDuration", "reqDuration", "serverProcessDuration", "resDuration"} {
+		if _, ok := res.Custom[k].(time.Duration); !ok {
+			t.Errorf("Expected %s in the result, Found: %v", k, res.Custom)
+		}
+	}
+	if d := res.Custom["tlsDuration"].(time.Duration); d <= 0 {
+		t.Errorf("Expected tls handshake duration to be measured, Found: %v", d)
+	}
+	// content transfer is included in the response read
+	if d := res.Custom["resDuration"].(time.Duration); d < bodyDelay {
+		t.Errorf("Expected response read duration to be at least %v, Found: %v", bodyDelay, d)
+	}
+	if res.Duration < bodyDelay {
+		t.Errorf("Expected total duration to be at least %v, Found: %v", bodyDelay, res.Duration)
+	}
+}
+
+func TestSendGraphQL(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		contentType string
+		body        map[string]interface{}
+	}
+	var got received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&got.body)
+		if got.body["variables"].(map[string]interface{})["id"] == "404" {
+			w.Write([]byte(`{"data": null, "errors": [{"message": "user not found"}, {"message": "other"}]}`))
+			return
+		}
+		w.Write([]byte(`{"data": {"user": {"id": "1"}}}`))
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeGraphQL,
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		GraphQL: types.GraphQLConf{
+			Query:         "query GetUser($id: ID!) { user(id: $id) { id } }",
+			Variables:     `{"id": "{{userId}}"}`,
+			OperationName: "GetUser",
+		},
+	}
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	res := h.Send(nil, map[string]interface{}{"userId": "1"})
+	if res.Err.Type != "" || res.StatusCode != http.StatusOK {
+		t.Errorf("Expected %v, Found: %v", "no error", res.Err)
+	}
+	if got.contentType != "application/json" {
+		t.Errorf("Expected %v, Found: %v", "application/json", got.contentType)
+	}
+	if got.body["query"] != s.GraphQL.Query || got.body["operationName"] != "GetUser" {
+		t.Errorf("Expected %v, Found: %v", s.GraphQL, got.body)
+	}
+
+	res = h.Send(nil, map[string]interface{}{"userId": "404"})
+	expected := types.RequestError{Type: types.ErrorGraphQL, Reason: "user not found (and 1 more)"}
+	if res.Err != expected || res.StatusCode != http.StatusOK {
+		t.Errorf("Expected %v, Found: %v", expected, res.Err)
+	}
+}
+
+func TestSendRegexCaptureGroups(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/expired" {
+			w.Write([]byte(`<p>session expired</p>`))
+			return
+		}
+		w.Write([]byte(`<a href="/account?token=ab12&id=7">account</a>`))
+	}))
+	defer server.Close()
+
+	exp := "token=(?P<tok>[a-f0-9]+)&id=(?P<id>[0-9]+)"
+	send := func(path string, fatal bool) *types.ScenarioStepResult {
+		s := types.ScenarioStep{
+			ID:      1,
+			Method:  http.MethodGet,
+			URL:     server.URL + path,
+			Timeout: types.DefaultTimeout,
+			EnvsToCapture: []types.EnvCaptureConf{{
+				Name:   "LOGIN",
+				From:   types.Body,
+				RegExp: &types.RegexCaptureConf{Exp: &exp, Fatal: fatal},
+			}},
+		}
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		defer h.Done()
+		return h.Send(http.DefaultClient, map[string]interface{}{})
+	}
+
+	res := send("/", true)
+	if res.Err.Type != "" || res.ExtractedEnvs["tok"] != "ab12" || res.ExtractedEnvs["id"] != "7" {
+		t.Errorf("Expected %v, Found: %v, %v", "tok ab12 and id 7", res.Err, res.ExtractedEnvs)
+	}
+	if string(res.ExtractedEnvs["LOGIN"].([]byte)) != "token=ab12&id=7" {
+		t.Errorf("Expected %v, Found: %v", "token=ab12&id=7", res.ExtractedEnvs["LOGIN"])
+	}
+
+	// soft capture only reports the failure
+	res = send("/expired", false)
+	if res.Err.Type != "" || res.ExtractedEnvs["tok"] != "" || res.FailedCaptures["LOGIN"] == "" {
+		t.Errorf("Expected %v, Found: %v, %v", "failed capture", res.Err, res.FailedCaptures)
+	}
+
+	res = send("/expired", true)
+	if res.Err.Type != types.ErrorCapture || !strings.HasPrefix(res.Err.Reason, "capture LOGIN failed, no match") {
+		t.Errorf("Expected %v, Found: %v", types.ErrorCapture, res.Err)
+	}
+}
+
+func TestSendBearerAuth(t *testing.T) {
+	t.Parallel()
+
+	authHeaders := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders <- r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		Auth:    types.Auth{Type: types.AuthBearer, Token: "{{token}}"},
+	}
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	// token captured by a previous step
+	if res := h.Send(nil, map[string]interface{}{"token": "abc.def"}); res.Err.Type != "" {
+		t.Fatalf("Send: %v", res.Err)
+	}
+
+	expected := "Bearer abc.def"
+	if found := <-authHeaders; found != expected {
+		t.Errorf("Expected %v, Found: %v", expected, found)
+	}
+}
+
+func TestSendExpectedStatus(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		expected types.ExpectedStatus
+		fail     bool
+	}{
+		{"Any", nil, false},
+		{"Matched", types.ExpectedStatus{{Min: 401, Max: 401}}, false},
+		{"MatchedClass", types.ExpectedStatus{{Min: 200, Max: 299}, {Min: 400, Max: 499}}, false},
+		{"NotMatched", types.ExpectedStatus{{Min: 200, Max: 299}}, true},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			s := types.ScenarioStep{
+				ID:             1,
+				Method:         http.MethodGet,
+				URL:            server.URL,
+				Timeout:        types.DefaultTimeout,
+				ExpectedStatus: tf.expected,
+			}
+			ei := &injection.EnvironmentInjector{}
+			ei.Init()
+			h := &HttpRequester{}
+			if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			res := h.Send(nil, map[string]interface{}{})
+			if res.Err.Type != "" {
+				t.Fatalf("Send: %v", res.Err)
+			}
+			if (len(res.FailedAssertions) > 0) != tf.fail {
+				t.Errorf("Expected %v, Found: %v", tf.fail, res.FailedAssertions)
+			}
+			if tf.fail && res.FailedAssertions[0].Rule != "expected_status: 2xx" {
+				t.Errorf("Expected %v, Found: %v", "expected_status: 2xx", res.FailedAssertions[0].Rule)
+			}
+		})
+	}
+}
+
+func TestSendSuccessWhen(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		successWhen string
+		expected    types.ExpectedStatus
+		failedRule  string
+	}{
+		{"Met", "status_code < 500 && response_time < 60000", nil, ""},
+		{"NotMet", "status_code < 400", nil, "success_when: status_code < 400"},
+		{"OverriddenByExpectedStatus", "status_code < 400", types.ExpectedStatus{{Min: 404, Max: 404}}, ""},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			s := types.ScenarioStep{
+				ID:             1,
+				Method:         http.MethodGet,
+				URL:            server.URL,
+				Timeout:        types.DefaultTimeout,
+				ExpectedStatus: tf.expected,
+				SuccessWhen:    tf.successWhen,
+			}
+			ei := &injection.EnvironmentInjector{}
+			ei.Init()
+			h := &HttpRequester{}
+			if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			res := h.Send(nil, map[string]interface{}{})
+			if res.Err.Type != "" {
+				t.Fatalf("Send: %v", res.Err)
+			}
+			if tf.failedRule == "" {
+				if len(res.FailedAssertions) > 0 {
+					t.Errorf("Expected no failed assertions, Found: %v", res.FailedAssertions)
+				}
+				return
+			}
+			if len(res.FailedAssertions) != 1 || res.FailedAssertions[0].Rule != tf.failedRule {
+				t.Errorf("Expected %v, Found: %v", tf.failedRule, res.FailedAssertions)
+			}
+		})
+	}
+}
+
+func TestSendSampleBodyBytes(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("service unavailable"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		keep     int64
+		expected string
+	}{
+		{"Disabled", 0, ""},
+		{"Head", 7, "service "},
+		{"Whole", 64, "service unavailable"},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			s := types.ScenarioStep{
+				ID:              1,
+				Method:          http.MethodGet,
+				URL:             server.URL,
+				Timeout:         types.DefaultTimeout,
+				SampleBodyBytes: tf.keep,
+			}
+			ei := &injection.EnvironmentInjector{}
+			ei.Init()
+			h := &HttpRequester{}
+			if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			res := h.Send(nil, map[string]interface{}{})
+			if res.Err.Type != "" {
+				t.Fatalf("Send: %v", res.Err)
+			}
+			if string(res.RespBody) != tf.expected {
+				t.Errorf("Expected %q, Found: %q", tf.expected, res.RespBody)
+			}
+		})
+	}
+}
+
+func TestSendTrailers(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.Write([]byte("streamed"))
+		w.(http.Flusher).Flush()
+		w.Header().Set("Grpc-Status", "14")
+	}))
+	defer server.Close()
+
+	statusKey := "grpc-status"
+	tests := []struct {
+		name       string
+		captures   []types.EnvCaptureConf
+		assertions []string
+	}{
+		{"Discarded", nil, nil},
+		{"CapturedAndAsserted", []types.EnvCaptureConf{{Name: "status", From: types.Trailer, Key: &statusKey}},
+			[]string{`trailers.grpc-status == "0"`}},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			s := types.ScenarioStep{
+				ID:            1,
+				Method:        http.MethodGet,
+				URL:           server.URL,
+				Timeout:       types.DefaultTimeout,
+				EnvsToCapture: tf.captures,
+				Assertions:    tf.assertions,
+			}
+			ei := &injection.EnvironmentInjector{}
+			ei.Init()
+			h := &HttpRequester{}
+			if err := h.Init(context.TODO(), s, nil, false, ei); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			res := h.Send(nil, map[string]interface{}{})
+			if res.Err.Type != "" {
+				t.Fatalf("Send: %v", res.Err)
+			}
+
+			// announced but not sent trailers are left out
+			expected := http.Header{"Grpc-Status": {"14"}}
+			if !reflect.DeepEqual(res.RespTrailers, expected) {
+				t.Errorf("Expected %v, Found: %v", expected, res.RespTrailers)
+			}
+			if res.RespHeaders.Get("Grpc-Status") != "" {
+				t.Errorf("Expected the trailers not in the headers, Found: %v", res.RespHeaders)
+			}
+			if tf.captures == nil {
+				return
+			}
+			if res.ExtractedEnvs["status"] != "14" {
+				t.Errorf("Expected %v, Found: %v", "14", res.ExtractedEnvs["status"])
+			}
+			if len(res.FailedAssertions) != 1 || res.FailedAssertions[0].Received[`trailers.grpc-status`] != "14" {
+				t.Errorf("Expected the failed trailer assertion, Found: %v", res.FailedAssertions)
+			}
+		})
+	}
+}
+func TestSendDisableKeepAlive(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		disableKeepAlive bool
+		expectedConns    int
+	}{
+		{"KeepAlive", false, 1},
+		{"DisableKeepAlive", true, 3},
+	}
+
+	for _, test := range tests {
+		var mu sync.Mutex
+		conns := make(map[string]struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			conns[r.RemoteAddr] = struct{}{}
+			mu.Unlock()
+		}))
+
+		s := types.ScenarioStep{
+			ID:               1,
+			Method:           http.MethodGet,
+			URL:              server.URL,
+			Timeout:          types.DefaultTimeout,
+			DisableKeepAlive: test.disableKeepAlive,
+		}
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		for i := 0; i < 3; i++ {
+			if res := h.Send(nil, map[string]interface{}{}); res.Err.Type != "" {
+				t.Fatalf("%s Send: %v", test.name, res.Err)
+			}
+		}
+		h.Done()
+		server.Close()
+
+		if len(conns) != test.expectedConns {
+			t.Errorf("%s Expected %d, Found: %d", test.name, test.expectedConns, len(conns))
+		}
+	}
+}
+
+func TestSendConnState(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		disableKeepAlive bool
+		expected         []types.ConnState
+	}{
+		{"KeepAlive", false, []types.ConnState{types.ConnNew, types.ConnReused, types.ConnReused}},
+		{"DisableKeepAlive", true, []types.ConnState{types.ConnNew, types.ConnNew, types.ConnNew}},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		s := types.ScenarioStep{
+			ID:               1,
+			Method:           http.MethodGet,
+			URL:              server.URL,
+			Timeout:          types.DefaultTimeout,
+			DisableKeepAlive: test.disableKeepAlive,
+		}
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		found := []types.ConnState{}
+		for i := 0; i < len(test.expected); i++ {
+			found = append(found, h.Send(nil, map[string]interface{}{}).Conn)
+		}
+		h.Done()
+		server.Close()
+
+		if !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expected, found)
+		}
+	}
+}
+
+func TestSendRedirects(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/b", http.StatusMovedPermanently) })
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/c", http.StatusFound) })
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name              string
+		redirect          types.RedirectConf
+		custom            map[string]interface{}
+		expectedStatus    int
+		expectedRedirects []int
+	}{
+		{"Default", types.RedirectConf{}, nil, http.StatusOK, []int{http.StatusMovedPermanently, http.StatusFound}},
+		{"Max", types.RedirectConf{Max: 1}, nil, http.StatusFound, []int{http.StatusMovedPermanently}},
+		{"Disabled", types.RedirectConf{Disabled: true}, nil, http.StatusMovedPermanently, nil},
+		{"DisableRedirectCustom", types.RedirectConf{}, map[string]interface{}{"disable-redirect": true},
+			http.StatusMovedPermanently, nil},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			s := types.ScenarioStep{ID: 1, Method: http.MethodGet, URL: server.URL + "/a", Timeout: types.DefaultTimeout,
+				Redirect: tf.redirect, Custom: tf.custom}
+			h := &HttpRequester{}
+			if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			res := h.Send(nil, map[string]interface{}{})
+
+			var redirects []int
+			for _, hop := range res.Redirects {
+				redirects = append(redirects, hop.StatusCode)
+			}
+			if res.StatusCode != tf.expectedStatus || !reflect.DeepEqual(redirects, tf.expectedRedirects) {
+				t.Errorf("Expected %v, Found: %v", []interface{}{tf.expectedStatus, tf.expectedRedirects},
+					[]interface{}{res.StatusCode, redirects})
+			}
+			if len(res.Redirects) > 0 && res.Redirects[0].URL != server.URL+"/a" {
+				t.Errorf("Expected %v, Found: %v", server.URL+"/a", res.Redirects[0].URL)
+			}
+		})
+	}
+}
+
+func TestInitTransportConf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		conf     types.TransportConf
+		expected types.TransportConf
+	}{
+		{"Default", types.TransportConf{}, types.TransportConf{MaxIdleConnsPerHost: 60000}},
+		{"Custom", types.TransportConf{MaxIdleConns: 100, MaxIdleConnsPerHost: 10, MaxConnsPerHost: 20,
+			IdleConnTimeout: 5 * time.Second},
+			types.TransportConf{MaxIdleConns: 100, MaxIdleConnsPerHost: 10, MaxConnsPerHost: 20,
+				IdleConnTimeout: 5 * time.Second}},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+			h := &HttpRequester{}
+			s := types.ScenarioStep{ID: 1, Method: http.MethodGet, URL: "http://localhost", Transport: tf.conf}
+			if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+			tr := h.client.Transport.(*http.Transport)
+			found := types.TransportConf{MaxIdleConns: tr.MaxIdleConns, MaxIdleConnsPerHost: tr.MaxIdleConnsPerHost,
+				MaxConnsPerHost: tr.MaxConnsPerHost, IdleConnTimeout: tr.IdleConnTimeout}
+			if found != tf.expected {
+				t.Errorf("Expected %v, Found: %v", tf.expected, found)
+			}
+		})
+	}
+}
+
+func TestInitKeepAlivePing(t *testing.T) {
+	t.Parallel()
+
+	conf := types.TransportConf{KeepAlivePing: 15 * time.Second}
+	h := &HttpRequester{}
+	s := types.ScenarioStep{ID: 1, Method: http.MethodGet, URL: "http://localhost", Protocol: types.ProtocolH2C,
+		Transport: conf}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if tr := h.client.Transport.(*http2.Transport); tr.ReadIdleTimeout != conf.KeepAlivePing {
+		t.Errorf("Expected %v, Found: %v", conf.KeepAlivePing, tr.ReadIdleTimeout)
+	}
+
+	// HTTP/2 over TLS is negotiated by the configured transport
+	h = &HttpRequester{}
+	s = types.ScenarioStep{ID: 1, Method: http.MethodGet, URL: "https://localhost", Transport: conf,
+		Custom: map[string]interface{}{"h2": true}}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if tr := h.client.Transport.(*http.Transport); tr.TLSNextProto["h2"] == nil {
+		t.Errorf("Expected the h2 protocol to be configured")
+	}
+}
+
+func TestSendWithResponseSchema(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("partial") != "" {
+			w.Write([]byte(`{"id": "1"}`))
+			return
+		}
+		w.Write([]byte(`{"id": 1, "name": "test"}`))
+	}))
+	defer server.Close()
+
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	ioutil.WriteFile(schemaPath, []byte(`{"type": "object", "required": ["id", "name"],
+		"properties": {"id": {"type": "integer"}, "name": {"type": "string"}}}`), 0644)
+
+	tests := []struct {
+		url      string
+		expected []string
+	}{
+		{server.URL, nil},
+		{server.URL + "?partial=1", []string{`$: missing required property "name"`, "$.id: expected type integer, found string"}},
+	}
+
+	for _, test := range tests {
+		s := types.ScenarioStep{
+			ID:             1,
+			Method:         http.MethodGet,
+			URL:            test.url,
+			Timeout:        types.DefaultTimeout,
+			ResponseSchema: schemaPath,
+		}
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		res := h.Send(nil, map[string]interface{}{})
+		h.Done()
+
+		if res.Err.Type != "" || !reflect.DeepEqual(res.SchemaErrors, test.expected) {
+			t.Errorf("Expected %v, Found: %v %v", test.expected, res.Err, res.SchemaErrors)
+		}
+	}
+
+	h := &HttpRequester{}
+	s := types.ScenarioStep{ID: 1, Method: http.MethodGet, URL: server.URL, ResponseSchema: schemaPath + ".missing"}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err == nil {
+		t.Errorf("Expected %v, Found: %v", "error", err)
+	}
+}
+
+func TestSendTimeouts(t *testing.T) {
+	t.Parallel()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	// accepts the connections but never completes the tls handshake
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer silent.Close()
+	go func() {
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	blockingDial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	tests := []struct {
+		name     string
+		step     types.ScenarioStep
+		expected types.RequestError
+	}{
+		{"Request", types.ScenarioStep{URL: slow.URL, RequestTimeout: 100 * time.Millisecond},
+			types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonConnTimeout}},
+		{"Dial", types.ScenarioStep{URL: slow.URL, DialTimeout: 50 * time.Millisecond, DialContext: blockingDial},
+			types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonDialTimeout}},
+		{"TLSHandshake", types.ScenarioStep{URL: "https://" + silent.Addr().String(), TLSHandshakeTimeout: 50 * time.Millisecond},
+			types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonTLSTimeout}},
+	}
+
+	for _, test := range tests {
+		s := test.step
+		s.ID = 1
+		s.Method = http.MethodGet
+		s.Timeout = types.DefaultTimeout
+
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		start := time.Now()
+		res := h.Send(nil, map[string]interface{}{})
+		h.Done()
+
+		if res.Err != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expected, res.Err)
+		}
+		if time.Since(start) > 400*time.Millisecond {
+			t.Errorf("%s Expected %v, Found: %v", test.name, "timeout before 400ms", time.Since(start))
+		}
+	}
+}
+
+func TestSendCaptureCert(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	tests := []struct {
+		name       string
+		tlsConfig  *tls.Config
+		chainError bool
+	}{
+		{"SelfSigned", nil, true},
+		{"TrustedRoot", &tls.Config{RootCAs: roots}, false},
+	}
+
+	for _, test := range tests {
+		s := types.ScenarioStep{
+			ID:          1,
+			Method:      http.MethodGet,
+			URL:         server.URL,
+			Timeout:     types.DefaultTimeout,
+			TLSConfig:   test.tlsConfig,
+			CaptureCert: true,
+		}
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+
+		res := h.Send(nil, map[string]interface{}{})
+		if res.Err.Type != "" {
+			t.Fatalf("%s Expected no error, Found: %v", test.name, res.Err)
+		}
+		c := res.Cert
+		if c == nil {
+			t.Fatalf("%s Expected the cert to be captured", test.name)
+		}
+		if c.Host != "127.0.0.1" || !c.NotAfter.Equal(server.Certificate().NotAfter) || c.Version != "TLS 1.3" {
+			t.Errorf("%s Expected %v, Found: %v", test.name, "127.0.0.1 TLS 1.3", *c)
+		}
+		if c.HostnameError != "" || c.WeakVersion {
+			t.Errorf("%s Expected %v, Found: %v", test.name, "no hostname and protocol issue", *c)
+		}
+		if (c.ChainError != "") != test.chainError {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.chainError, c.ChainError)
+		}
+
+		// verified once for the connections having the same certificate
+		if res = h.Send(nil, map[string]interface{}{}); res.Cert != c {
+			t.Errorf("%s Expected %v, Found: %v", test.name, c, res.Cert)
+		}
+		h.Done()
+	}
+}
+
+func TestSendInterceptors(t *testing.T) {
+	t.Parallel()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("X-Signature", r.Header.Get("X-Signature"))
+	}))
+	defer server.Close()
+
+	sign := func(r *http.Request) error {
+		r.Header.Set("X-Signature", "signed:"+r.Header.Get("X-Canary"))
+		return nil
+	}
+	canary := func(r *http.Request) error {
+		r.Header.Set("X-Canary", "1")
+		return nil
+	}
+	verify := func(r *http.Response) error {
+		if r.Header.Get("X-Signature") != "signed:1" {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+	fail := func(r *http.Request) error { return errors.New("no signing key") }
+
+	tests := []struct {
+		name        string
+		reqs        []types.RequestInterceptor
+		expectedErr string
+		sent        bool
+	}{
+		{"Chain", []types.RequestInterceptor{canary, sign}, "", true},
+		{"Order", []types.RequestInterceptor{sign, canary}, "signature mismatch", true},
+		{"RequestError", []types.RequestInterceptor{canary, fail, sign}, "no signing key", false},
+	}
+
+	for _, test := range tests {
+		s := types.ScenarioStep{
+			ID:                   1,
+			Method:               http.MethodGet,
+			URL:                  server.URL,
+			Timeout:              types.DefaultTimeout,
+			RequestInterceptors:  test.reqs,
+			ResponseInterceptors: []types.ResponseInterceptor{verify},
+		}
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+
+		before := atomic.LoadInt32(&calls)
+		res := h.Send(nil, map[string]interface{}{})
+		h.Done()
+
+		if test.expectedErr == "" && res.Err.Type != "" {
+			t.Errorf("%s Expected no error, Found: %v", test.name, res.Err)
+		}
+		if test.expectedErr != "" && (res.Err.Type != types.ErrorInterceptor || res.Err.Reason != test.expectedErr) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expectedErr, res.Err)
+		}
+		if sent := atomic.LoadInt32(&calls) > before; sent != test.sent {
+			t.Errorf("%s Expected sent: %v, Found: %v", test.name, test.sent, sent)
+		}
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		statusCode int
+		retryAfter string
+		throttled  bool
+		delay      time.Duration
+	}{
+		{"OK", http.StatusOK, "5", false, 0},
+		{"TooManyRequests", http.StatusTooManyRequests, "", true, 0},
+		{"Seconds", http.StatusTooManyRequests, "5", true, 5 * time.Second},
+		{"Date", http.StatusTooManyRequests, now.Add(time.Minute).Format(http.TimeFormat), true, time.Minute},
+		{"PastDate", http.StatusTooManyRequests, now.Add(-time.Minute).Format(http.TimeFormat), true, 0},
+		{"Negative", http.StatusTooManyRequests, "-5", true, 0},
+		{"Invalid", http.StatusTooManyRequests, "soon", true, 0},
+		{"Unavailable", http.StatusServiceUnavailable, "", false, 0},
+		{"UnavailableRetryAfter", http.StatusServiceUnavailable, "10", true, 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			header := http.Header{}
+			if test.retryAfter != "" {
+				header.Set("Retry-After", test.retryAfter)
+			}
+			throttled, delay := throttled(test.statusCode, header, now)
+			if throttled != test.throttled || delay != test.delay {
+				t.Errorf("Expected %v %v, Found: %v %v", test.throttled, test.delay, throttled, delay)
+			}
+		}
+		t.Run(test.name, tf)
+	}
+}
+
+func TestSendThrottled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+	}
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init errored: %v", err)
+	}
+
+	res := h.Send(http.DefaultClient, map[string]interface{}{})
+	if !res.Throttled || res.RetryAfter != 2*time.Second {
+		t.Errorf("Expected %v %v, Found: %v %v", true, 2*time.Second, res.Throttled, res.RetryAfter)
+	}
+}
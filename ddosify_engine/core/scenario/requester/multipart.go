@@ -0,0 +1,178 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// multipartBody builds the multipart/form-data body of a step for each request,
+// streaming the file parts from the disk instead of buffering them.
+type multipartBody struct {
+	parts    []types.MultipartPart
+	boundary string
+
+	// total length of the body with the templated values before the injection,
+	// files are stat'ed once in newMultipartBody
+	length int64
+
+	// parsed values of the templated parts by their indexes
+	ei        *injection.EnvironmentInjector
+	templates map[int]*injection.BodyTemplate
+}
+
+func newMultipartBody(parts []types.MultipartPart, ei *injection.EnvironmentInjector) (*multipartBody, error) {
+	m := &multipartBody{
+		parts:    make([]types.MultipartPart, len(parts)),
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+		ei:       ei,
+	}
+	copy(m.parts, parts)
+
+	for i, p := range m.parts {
+		if !p.Template {
+			continue
+		}
+		if p.FilePath != "" {
+			// templated files are read once, the content is injected for each request
+			content, err := os.ReadFile(p.FilePath)
+			if err != nil {
+				return nil, err
+			}
+			p.Value = string(content)
+			if p.FileName == "" {
+				p.FileName = filepath.Base(p.FilePath)
+			}
+			p.FilePath = ""
+			m.parts[i] = p
+		}
+		if m.templates == nil {
+			m.templates = make(map[int]*injection.BodyTemplate)
+		}
+		m.templates[i] = ei.ParseBodyTemplate(p.Value)
+	}
+
+	// write the body without the file contents to calculate the length
+	var fileSizes int64
+	for _, p := range m.parts {
+		if p.FilePath == "" {
+			continue
+		}
+		info, err := os.Stat(p.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("multipart file %s is a directory", p.FilePath)
+		}
+		fileSizes += info.Size()
+	}
+	buf := &bytes.Buffer{}
+	if err := m.write(buf, false, nil); err != nil {
+		return nil, err
+	}
+	m.length = int64(buf.Len()) + fileSizes
+	return m, nil
+}
+
+func (m *multipartBody) contentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// inject returns the values of the templated parts injected from the envs, and the length of the body with them.
+func (m *multipartBody) inject(envs map[string]interface{}) (map[int]string, int64) {
+	if len(m.templates) == 0 {
+		return nil, m.length
+	}
+	values := make(map[int]string, len(m.templates))
+	length := m.length
+	for i, t := range m.templates {
+		values[i] = m.ei.InjectTemplate(t, envs)
+		length += int64(len(values[i]) - len(t.Body))
+	}
+	return values, length
+}
+
+// reader returns a new body with the injected values of the templated parts, written by a separate goroutine
+// while it is read. Closing the reader before EOF stops the writer.
+func (m *multipartBody) reader(values map[int]string) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(m.write(pw, true, values))
+	}()
+	return pr
+}
+
+func (m *multipartBody) write(w io.Writer, withFiles bool, values map[int]string) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for i, p := range m.parts {
+		if v, ok := values[i]; ok {
+			p.Value = v
+		}
+		if !p.IsFile() {
+			if err := mw.WriteField(p.Name, p.Value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fileName := p.FileName
+		if fileName == "" {
+			fileName = filepath.Base(p.FilePath)
+		}
+		part, err := mw.CreateFormFile(p.Name, fileName)
+		if err != nil {
+			return err
+		}
+		if p.FilePath == "" {
+			if _, err = io.WriteString(part, p.Value); err != nil {
+				return err
+			}
+		} else if withFiles {
+			if err = copyFile(part, p.FilePath); err != nil {
+				return err
+			}
+		}
+	}
+	return mw.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
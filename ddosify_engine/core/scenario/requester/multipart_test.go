@@ -0,0 +1,184 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestSendMultipartStream(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Repeat("ddosify", 100000)
+	filePath := filepath.Join(t.TempDir(), "upload.bin")
+	if err := os.WriteFile(filePath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var contentLength int64
+	var reqErr error
+	got := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		mr, err := r.MultipartReader()
+		if err != nil {
+			reqErr = err
+			return
+		}
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				reqErr = err
+				return
+			}
+			b, _ := io.ReadAll(p)
+			got[p.FormName()+":"+p.FileName()] = string(b)
+		}
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		MultipartStream: []types.MultipartPart{
+			{Name: "user", Value: "ddosify"},
+			{Name: "file", FilePath: filePath},
+			{Name: "notes", Value: "inline content", FileName: "notes.txt"},
+		},
+	}
+	h := &HttpRequester{}
+	if err := h.Init(context.Background(), s, nil, true, nil); err != nil {
+		t.Fatalf("TestSendMultipartStream init error: %v", err)
+	}
+
+	// body is built for each request
+	for i := 0; i < 2; i++ {
+		res := h.Send(nil, map[string]interface{}{})
+		if res.Err.Type != "" || res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected %v, Found: %v, %v", http.StatusOK, res.StatusCode, res.Err)
+		}
+		if reqErr != nil {
+			t.Fatalf("TestSendMultipartStream request error: %v", reqErr)
+		}
+		if contentLength != h.multipart.length || contentLength <= int64(len(content)) {
+			t.Errorf("Expected %v, Found: %v", h.multipart.length, contentLength)
+		}
+		if string(res.ReqBody) != "streamed multipart body" {
+			t.Errorf("Expected %v, Found: %v", "streamed multipart body", string(res.ReqBody))
+		}
+	}
+
+	expected := map[string]string{
+		"user:":           "ddosify",
+		"file:upload.bin": content,
+		"notes:notes.txt": "inline content",
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("Expected %v, Found: %v", len(v), len(got[k]))
+		}
+	}
+}
+
+func TestSendMultipartStreamTemplate(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "order.json")
+	if err := os.WriteFile(filePath, []byte(`{"user": "{{username}}", "items": 3}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var contentLength int64
+	got := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		b, _ := io.ReadAll(r.Body)
+		got["length"] = strconv.Itoa(len(b))
+		mr := multipart.NewReader(bytes.NewReader(b), r.Header.Get("Content-Type")[len("multipart/form-data; boundary="):])
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			v, _ := io.ReadAll(p)
+			got[p.FormName()] = string(v)
+		}
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		MultipartStream: []types.MultipartPart{
+			{Name: "user", Value: "{{username}}", Template: true},
+			{Name: "order", FilePath: filePath, Template: true},
+		},
+	}
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("TestSendMultipartStreamTemplate init error: %v", err)
+	}
+
+	res := h.Send(nil, map[string]interface{}{"username": "ddosify-user"})
+	if res.Err.Type != "" || res.StatusCode != http.StatusOK {
+		t.Fatalf("Expected %v, Found: %v, %v", http.StatusOK, res.StatusCode, res.Err)
+	}
+	if got["user"] != "ddosify-user" {
+		t.Errorf("Expected %v, Found: %v", "ddosify-user", got["user"])
+	}
+	if expected := `{"user": "ddosify-user", "items": 3}`; got["order"] != expected {
+		t.Errorf("Expected %v, Found: %v", expected, got["order"])
+	}
+	if strconv.FormatInt(contentLength, 10) != got["length"] {
+		t.Errorf("Expected %v, Found: %v", got["length"], contentLength)
+	}
+}
+
+func TestNewMultipartBodyMissingFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := newMultipartBody([]types.MultipartPart{{Name: "file", FilePath: filepath.Join(t.TempDir(), "missing")}}, nil)
+	if err == nil {
+		t.Errorf("Expected error, Found: %v", err)
+	}
+}
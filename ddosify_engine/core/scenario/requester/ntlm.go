@@ -0,0 +1,351 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Flags of the NTLM messages, NTLMv2 with the extended session security and the unicode strings
+const (
+	ntlmNegotiateUnicode         = 0x00000001
+	ntlmNegotiateOEM             = 0x00000002
+	ntlmRequestTarget            = 0x00000004
+	ntlmNegotiateNTLM            = 0x00000200
+	ntlmNegotiateAlwaysSign      = 0x00008000
+	ntlmNegotiateExtendedSession = 0x00080000
+	ntlmNegotiateTargetInfo      = 0x00800000
+	ntlmNegotiate128             = 0x20000000
+	ntlmNegotiate56              = 0x80000000
+
+	ntlmNegotiateFlags = ntlmNegotiateUnicode | ntlmNegotiateOEM | ntlmRequestTarget | ntlmNegotiateNTLM |
+		ntlmNegotiateAlwaysSign | ntlmNegotiateExtendedSession | ntlmNegotiateTargetInfo | ntlmNegotiate128 |
+		ntlmNegotiate56
+
+	// id of the timestamp in the target info of the challenge
+	ntlmAvTimestamp = 7
+)
+
+var ntlmSignature = []byte("NTLMSSP\x00")
+
+type ntlmCredsKey struct{}
+
+// ntlmCreds are the credentials of a request, injected in prepareReq and read by the ntlmTransport.
+type ntlmCreds struct {
+	domain   string
+	username string
+	password string
+}
+
+// withNTLMCreds returns the ctx carrying the credentials, the domain is parsed from the DOMAIN\user or
+// user@domain forms of the username.
+func withNTLMCreds(ctx context.Context, username, password string) context.Context {
+	c := ntlmCreds{username: username, password: password}
+	if i := strings.Index(username, `\`); i >= 0 {
+		c.domain, c.username = username[:i], username[i+1:]
+	} else if i := strings.LastIndex(username, "@"); i >= 0 {
+		c.username, c.domain = username[:i], username[i+1:]
+	}
+	return context.WithValue(ctx, ntlmCredsKey{}, c)
+}
+
+// ntlmTransport performs the NTLM handshake when the server challenges a request with the NTLM or Negotiate
+// schemes, and sends the request again with the authenticate message. NTLM authenticates the connection, so the
+// next requests over the same connection are sent as they are. All the legs of the handshake should go over the
+// same connection, so the transport should keep a single connection per host like the ones of the virtual users.
+type ntlmTransport struct {
+	base http.RoundTripper
+}
+
+func (t *ntlmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	creds, ok := req.Context().Value(ntlmCredsKey{}).(ntlmCreds)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	// body is sent again by the last leg of the handshake
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	scheme := ntlmScheme(res.Header)
+	if scheme == "" {
+		return res, nil
+	}
+	drainBody(res)
+
+	// negotiate without the body, like the other clients
+	negotiate := req.Clone(req.Context())
+	negotiate.Body, negotiate.GetBody, negotiate.ContentLength = http.NoBody, nil, 0
+	negotiate.Header.Set("Authorization", scheme+" "+base64.StdEncoding.EncodeToString(ntlmNegotiateMessage()))
+	res, err = t.base.RoundTrip(negotiate)
+	if err != nil {
+		return nil, err
+	}
+	challenge, ok := ntlmChallengeOf(res.Header, scheme)
+	if res.StatusCode != http.StatusUnauthorized || !ok {
+		return res, nil // not challenged, the response of the negotiate is the result
+	}
+	drainBody(res)
+
+	msg, err := ntlmAuthenticateMessage(challenge, creds, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	authenticate := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if authenticate.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	authenticate.Header.Set("Authorization", scheme+" "+base64.StdEncoding.EncodeToString(msg))
+	return t.base.RoundTrip(authenticate)
+}
+
+// ntlmScheme returns the scheme of the challenge that NTLM can answer, NTLM is preferred to Negotiate.
+func ntlmScheme(h http.Header) string {
+	scheme := ""
+	for _, v := range h.Values("WWW-Authenticate") {
+		s := strings.TrimSpace(v)
+		if strings.EqualFold(s, "NTLM") {
+			return "NTLM"
+		}
+		if strings.EqualFold(s, "Negotiate") {
+			scheme = "Negotiate"
+		}
+	}
+	return scheme
+}
+
+// ntlmChallengeOf returns the decoded challenge message of the scheme.
+func ntlmChallengeOf(h http.Header, scheme string) ([]byte, bool) {
+	for _, v := range h.Values("WWW-Authenticate") {
+		fields := strings.Fields(v)
+		if len(fields) != 2 || !strings.EqualFold(fields[0], scheme) {
+			continue
+		}
+		if b, err := base64.StdEncoding.DecodeString(fields[1]); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// drainBody reads the rest of the body before closing it, so the connection is reused by the next leg.
+func drainBody(res *http.Response) {
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+}
+
+func ntlmNegotiateMessage() []byte {
+	b := make([]byte, 32)
+	copy(b, ntlmSignature)
+	binary.LittleEndian.PutUint32(b[8:], 1)
+	binary.LittleEndian.PutUint32(b[12:], ntlmNegotiateFlags)
+	return b
+}
+
+// ntlmAuthenticateMessage returns the NTLMv2 response to the challenge message.
+func ntlmAuthenticateMessage(challenge []byte, creds ntlmCreds, now time.Time) ([]byte, error) {
+	if len(challenge) < 48 || !bytes.Equal(challenge[:8], ntlmSignature) || binary.LittleEndian.Uint32(challenge[8:]) != 2 {
+		return nil, errors.New("invalid ntlm challenge")
+	}
+	flags := binary.LittleEndian.Uint32(challenge[20:])
+	serverChallenge := challenge[24:32]
+	targetInfo, err := ntlmField(challenge, 40)
+	if err != nil {
+		return nil, err
+	}
+
+	// the timestamp of the server is used if given, the lm response is empty then
+	timestamp := ntlmAvPair(targetInfo, ntlmAvTimestamp)
+	serverTime := timestamp != nil
+	if !serverTime {
+		timestamp = make([]byte, 8)
+		// windows file time, 100ns intervals since 1601
+		binary.LittleEndian.PutUint64(timestamp, uint64(now.UnixNano()/100+116444736000000000))
+	}
+	clientChallenge := make([]byte, 8)
+	if _, err := rand.Read(clientChallenge); err != nil {
+		return nil, err
+	}
+
+	ntProof, temp := ntlmV2Response(creds, serverChallenge, clientChallenge, timestamp, targetInfo)
+	nt := append(ntProof, temp...)
+	lm := make([]byte, 24)
+	if !serverTime {
+		lm = append(hmacMD5(ntowfV2(creds), serverChallenge, clientChallenge), clientChallenge...)
+	}
+
+	domain, user := ntlmString(creds.domain), ntlmString(creds.username)
+	payload := [][]byte{lm, nt, domain, user, nil, nil} // workstation and session key are empty
+	b := make([]byte, 64)
+	copy(b, ntlmSignature)
+	binary.LittleEndian.PutUint32(b[8:], 3)
+	offset := len(b)
+	for i, p := range payload {
+		field := b[12+8*i:]
+		binary.LittleEndian.PutUint16(field, uint16(len(p)))
+		binary.LittleEndian.PutUint16(field[2:], uint16(len(p)))
+		binary.LittleEndian.PutUint32(field[4:], uint32(offset))
+		offset += len(p)
+	}
+	binary.LittleEndian.PutUint32(b[60:], flags&ntlmNegotiateFlags)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	return b, nil
+}
+
+// ntlmV2Response returns the NTProofStr and the blob of the NTLMv2 response.
+func ntlmV2Response(creds ntlmCreds, serverChallenge, clientChallenge, timestamp, targetInfo []byte) ([]byte, []byte) {
+	temp := []byte{1, 1, 0, 0, 0, 0, 0, 0}
+	temp = append(temp, timestamp...)
+	temp = append(temp, clientChallenge...)
+	temp = append(temp, 0, 0, 0, 0)
+	temp = append(temp, targetInfo...)
+	temp = append(temp, 0, 0, 0, 0)
+	return hmacMD5(ntowfV2(creds), serverChallenge, temp), temp
+}
+
+func ntowfV2(creds ntlmCreds) []byte {
+	return hmacMD5(md4(ntlmString(creds.password)), ntlmString(strings.ToUpper(creds.username)+creds.domain))
+}
+
+// ntlmField returns the payload of the length, max length and offset fields at i.
+func ntlmField(msg []byte, i int) ([]byte, error) {
+	l := int(binary.LittleEndian.Uint16(msg[i:]))
+	offset := int(binary.LittleEndian.Uint32(msg[i+4:]))
+	if offset+l > len(msg) {
+		return nil, errors.New("invalid ntlm challenge")
+	}
+	return msg[offset : offset+l], nil
+}
+
+// ntlmAvPair returns the value of the id in the target info, nil if it is not found.
+func ntlmAvPair(info []byte, id uint16) []byte {
+	for len(info) >= 4 {
+		avID, l := binary.LittleEndian.Uint16(info), int(binary.LittleEndian.Uint16(info[2:]))
+		if avID == 0 || len(info) < 4+l { // end of the list
+			return nil
+		}
+		if avID == id {
+			return info[4 : 4+l]
+		}
+		info = info[4+l:]
+	}
+	return nil
+}
+
+// ntlmString encodes s in UTF-16LE.
+func ntlmString(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	m := hmac.New(md5.New, key)
+	for _, d := range data {
+		m.Write(d)
+	}
+	return m.Sum(nil)
+}
+
+// md4 returns the MD4 digest of the data, for the NT hash of the password (RFC 1320).
+func md4(data []byte) []byte {
+	msg := append([]byte{}, data...)
+	msg = append(msg, 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	msgLen := make([]byte, 8)
+	binary.LittleEndian.PutUint64(msgLen, uint64(len(data))*8)
+	msg = append(msg, msgLen...)
+
+	a, b, c, d := uint32(0x67452301), uint32(0xefcdab89), uint32(0x98badcfe), uint32(0x10325476)
+	var x [16]uint32
+	for len(msg) > 0 {
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(msg[4*i:])
+		}
+		aa, bb, cc, dd := a, b, c, d
+
+		f := func(x, y, z uint32) uint32 { return x&y | ^x&z }
+		g := func(x, y, z uint32) uint32 { return x&y | x&z | y&z }
+		h := func(x, y, z uint32) uint32 { return x ^ y ^ z }
+		for _, i := range []int{0, 4, 8, 12} {
+			a = bits.RotateLeft32(a+f(b, c, d)+x[i], 3)
+			d = bits.RotateLeft32(d+f(a, b, c)+x[i+1], 7)
+			c = bits.RotateLeft32(c+f(d, a, b)+x[i+2], 11)
+			b = bits.RotateLeft32(b+f(c, d, a)+x[i+3], 19)
+		}
+		for _, i := range []int{0, 1, 2, 3} {
+			a = bits.RotateLeft32(a+g(b, c, d)+x[i]+0x5a827999, 3)
+			d = bits.RotateLeft32(d+g(a, b, c)+x[i+4]+0x5a827999, 5)
+			c = bits.RotateLeft32(c+g(d, a, b)+x[i+8]+0x5a827999, 9)
+			b = bits.RotateLeft32(b+g(c, d, a)+x[i+12]+0x5a827999, 13)
+		}
+		for _, i := range []int{0, 2, 1, 3} {
+			a = bits.RotateLeft32(a+h(b, c, d)+x[i]+0x6ed9eba1, 3)
+			d = bits.RotateLeft32(d+h(a, b, c)+x[i+8]+0x6ed9eba1, 9)
+			c = bits.RotateLeft32(c+h(d, a, b)+x[i+4]+0x6ed9eba1, 11)
+			b = bits.RotateLeft32(b+h(c, d, a)+x[i+12]+0x6ed9eba1, 15)
+		}
+		a, b, c, d = a+aa, b+bb, c+cc, d+dd
+		msg = msg[64:]
+	}
+
+	sum := make([]byte, 16)
+	binary.LittleEndian.PutUint32(sum, a)
+	binary.LittleEndian.PutUint32(sum[4:], b)
+	binary.LittleEndian.PutUint32(sum[8:], c)
+	binary.LittleEndian.PutUint32(sum[12:], d)
+	return sum
+}
@@ -0,0 +1,227 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestMD4(t *testing.T) {
+	t.Parallel()
+
+	// test suite of RFC 1320
+	tests := map[string]string{
+		"":    "31d6cfe0d16ae931b73c59d7e0c089c0",
+		"abc": "a448017aaf21d8525fc10ae87aa6729d",
+		"12345678901234567890123456789012345678901234567890123456789012345678901234567890": "e33b4ddc9c38f2199c3e7b164fcc0536",
+	}
+	for in, expected := range tests {
+		if got := hex.EncodeToString(md4([]byte(in))); got != expected {
+			t.Errorf("Expected %v, Found: %v", expected, got)
+		}
+	}
+}
+
+func TestNTLMv2Response(t *testing.T) {
+	t.Parallel()
+
+	// NTLMv2 example of MS-NLMP 4.2.4
+	creds := ntlmCreds{domain: "Domain", username: "User", password: "Password"}
+	if got := hex.EncodeToString(ntowfV2(creds)); got != "0c868a403bfd7a93a3001ef22ef02e3f" {
+		t.Errorf("Expected %v, Found: %v", "0c868a403bfd7a93a3001ef22ef02e3f", got)
+	}
+
+	targetInfo := ntlmTestAvPair(2, "Domain")
+	targetInfo = append(targetInfo, ntlmTestAvPair(1, "Server")...)
+	targetInfo = append(targetInfo, 0, 0, 0, 0)
+	serverChallenge, _ := hex.DecodeString("0123456789abcdef")
+	clientChallenge := bytes.Repeat([]byte{0xaa}, 8)
+	ntProof, _ := ntlmV2Response(creds, serverChallenge, clientChallenge, make([]byte, 8), targetInfo)
+	if got := hex.EncodeToString(ntProof); got != "68cd0ab851e51c96aabc927bebef6a1c" {
+		t.Errorf("Expected %v, Found: %v", "68cd0ab851e51c96aabc927bebef6a1c", got)
+	}
+}
+
+func TestWithNTLMCreds(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		username string
+		expected ntlmCreds
+	}{
+		{`CORP\alice`, ntlmCreds{domain: "CORP", username: "alice", password: "pass"}},
+		{"alice@corp.local", ntlmCreds{domain: "corp.local", username: "alice", password: "pass"}},
+		{"alice", ntlmCreds{username: "alice", password: "pass"}},
+	}
+	for _, test := range tests {
+		got := withNTLMCreds(context.Background(), test.username, "pass").Value(ntlmCredsKey{})
+		if got != test.expected {
+			t.Errorf("Expected %v, Found: %v", test.expected, got)
+		}
+	}
+}
+
+func TestSendNTLM(t *testing.T) {
+	t.Parallel()
+
+	serverChallenge := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	targetInfo := append(ntlmTestAvPair(2, "CORP"), 0, 0, 0, 0)
+	creds := ntlmCreds{domain: "CORP", username: "alice", password: "secret"}
+
+	var mu sync.Mutex
+	authenticated := make(map[string]bool) // by connection
+	challenges := 0
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		if authenticated[r.RemoteAddr] {
+			bodies = append(bodies, string(body))
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "NTLM ")
+		msg, _ := base64.StdEncoding.DecodeString(token)
+		switch {
+		case len(msg) > 12 && binary.LittleEndian.Uint32(msg[8:]) == 1: // negotiate
+			challenges++
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(ntlmTestChallenge(serverChallenge, targetInfo)))
+			w.WriteHeader(http.StatusUnauthorized)
+		case len(msg) > 64 && binary.LittleEndian.Uint32(msg[8:]) == 3: // authenticate
+			nt, _ := ntlmField(msg, 20)
+			user, _ := ntlmField(msg, 36)
+			expected := hmacMD5(ntowfV2(creds), serverChallenge, nt[16:])
+			if !bytes.Equal(nt[:16], expected) || !bytes.Equal(user, ntlmString("alice")) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			authenticated[r.RemoteAddr] = true
+			bodies = append(bodies, string(body))
+		default:
+			w.Header().Add("WWW-Authenticate", "Negotiate")
+			w.Header().Add("WWW-Authenticate", "NTLM")
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodPost,
+		URL:     server.URL,
+		Payload: `{"user": "{{user}}"}`,
+		Timeout: types.DefaultTimeout,
+		Auth:    types.Auth{Type: types.AuthNTLM, Username: `CORP\alice`, Password: "{{password}}"},
+	}
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	h := &HttpRequester{}
+	if err := h.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("TestSendNTLM init error: %v", err)
+	}
+
+	// the virtual user authenticates its connection once
+	client := &http.Client{}
+	envs := map[string]interface{}{"user": "alice", "password": "secret"}
+	for i := 0; i < 3; i++ {
+		res := h.Send(client, envs)
+		if res.Err.Type != "" || res.StatusCode != http.StatusOK {
+			t.Fatalf("Expected %v, Found: %v, %v", http.StatusOK, res.StatusCode, res.Err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if challenges != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, challenges)
+	}
+	if len(bodies) != 3 || bodies[0] != `{"user": "alice"}` {
+		t.Errorf("Expected the body to be sent by each request, Found: %v", bodies)
+	}
+}
+
+func TestSendNTLMWrongPassword(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "NTLM ")
+		msg, _ := base64.StdEncoding.DecodeString(token)
+		if len(msg) > 12 && binary.LittleEndian.Uint32(msg[8:]) == 1 {
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(
+				ntlmTestChallenge(make([]byte, 8), []byte{0, 0, 0, 0})))
+		} else {
+			w.Header().Set("WWW-Authenticate", "NTLM")
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		Auth:    types.Auth{Type: types.AuthNTLM, Username: "alice", Password: "wrong"},
+	}
+	h := &HttpRequester{}
+	if err := h.Init(context.Background(), s, nil, false, nil); err != nil {
+		t.Fatalf("TestSendNTLMWrongPassword init error: %v", err)
+	}
+
+	res := h.Send(&http.Client{}, nil)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected %v, Found: %v", http.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+func ntlmTestAvPair(id uint16, value string) []byte {
+	v := ntlmString(value)
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint16(b, id)
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(v)))
+	return append(b, v...)
+}
+
+func ntlmTestChallenge(serverChallenge, targetInfo []byte) []byte {
+	b := make([]byte, 48)
+	copy(b, ntlmSignature)
+	binary.LittleEndian.PutUint32(b[8:], 2)
+	binary.LittleEndian.PutUint32(b[20:], ntlmNegotiateFlags)
+	copy(b[24:], serverChallenge)
+	binary.LittleEndian.PutUint16(b[40:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint16(b[42:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint32(b[44:], 48)
+	return append(b, targetInfo...)
+}
@@ -0,0 +1,67 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// timeout of a single token request to the token endpoint
+const oauth2TokenTimeout = 10 * time.Second
+
+var (
+	// token sources are shared by all the requesters with the same client credentials,
+	// so a token is fetched once for all virtual users instead of one per request
+	oauth2TokenSources   = make(map[types.Auth]oauth2.TokenSource)
+	oauth2TokenSourcesMu sync.Mutex
+)
+
+// oauth2TokenSource returns the cached token source of the given client credentials. Returned token source is
+// safe for concurrent use, it fetches a new token from TokenURL when the current one is about to expire.
+func oauth2TokenSource(auth types.Auth) oauth2.TokenSource {
+	oauth2TokenSourcesMu.Lock()
+	defer oauth2TokenSourcesMu.Unlock()
+
+	if ts, ok := oauth2TokenSources[auth]; ok {
+		return ts
+	}
+
+	conf := &clientcredentials.Config{
+		ClientID:     auth.ClientID,
+		ClientSecret: auth.ClientSecret,
+		TokenURL:     auth.TokenURL,
+		Scopes:       strings.Fields(auth.Scopes),
+	}
+	// token source outlives the test contexts, token requests are limited by the client timeout instead
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Timeout: oauth2TokenTimeout})
+	ts := conf.TokenSource(ctx)
+
+	oauth2TokenSources[auth] = ts
+	return ts
+}
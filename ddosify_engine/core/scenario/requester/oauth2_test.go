@@ -0,0 +1,154 @@
+package requester
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// newTokenServer returns an oauth2 token endpoint that issues a new token on each call, tokens expire in expiresIn seconds
+func newTokenServer(expiresIn int, fetchCount *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(fetchCount, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":%d,"scope":"%s"}`,
+			n, expiresIn, r.Form.Get("scope"))
+	}))
+}
+
+func TestOAuth2TokenSharedByRequesters(t *testing.T) {
+	t.Parallel()
+
+	var fetchCount int32
+	tokenServer := newTokenServer(3600, &fetchCount)
+	defer tokenServer.Close()
+
+	var gotAuthHeader atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthHeader.Store(r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		Auth: types.Auth{
+			Type:         types.AuthOAuth2ClientCredentials,
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			Scopes:       "read write",
+		},
+	}
+
+	// e.g. two steps or two proxies with the same credentials
+	for i := 0; i < 2; i++ {
+		h := &HttpRequester{}
+		if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+		defer h.Done()
+
+		for j := 0; j < 5; j++ {
+			res := h.Send(nil, map[string]interface{}{})
+			if res.Err.Type != "" {
+				t.Fatalf("Expected no error, Found: %v", res.Err)
+			}
+			if gotAuthHeader.Load() != "Bearer token-1" {
+				t.Errorf("Expected %v, Found: %v", "Bearer token-1", gotAuthHeader.Load())
+			}
+		}
+	}
+
+	if atomic.LoadInt32(&fetchCount) != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, fetchCount)
+	}
+}
+
+func TestOAuth2TokenRefreshedNearExpiry(t *testing.T) {
+	t.Parallel()
+
+	var fetchCount int32
+	// oauth2 refreshes tokens 10 seconds before the expiry, so this token is usable for 1 second
+	tokenServer := newTokenServer(11, &fetchCount)
+	defer tokenServer.Close()
+
+	var gotAuthHeader atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthHeader.Store(r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodGet,
+		URL:     server.URL,
+		Timeout: types.DefaultTimeout,
+		Auth: types.Auth{
+			Type:         types.AuthOAuth2ClientCredentials,
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+		},
+	}
+
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer h.Done()
+
+	h.Send(nil, map[string]interface{}{})
+	if gotAuthHeader.Load() != "Bearer token-1" {
+		t.Errorf("Expected %v, Found: %v", "Bearer token-1", gotAuthHeader.Load())
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	h.Send(nil, map[string]interface{}{})
+	if gotAuthHeader.Load() != "Bearer token-2" {
+		t.Errorf("Expected %v, Found: %v", "Bearer token-2", gotAuthHeader.Load())
+	}
+}
+
+func TestOAuth2InvalidCredentials(t *testing.T) {
+	t.Parallel()
+
+	var fetchCount int32
+	tokenServer := newTokenServer(3600, &fetchCount)
+	defer tokenServer.Close()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Method:  http.MethodGet,
+		URL:     "http://test.com",
+		Timeout: types.DefaultTimeout,
+		Auth: types.Auth{
+			Type:         types.AuthOAuth2ClientCredentials,
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "wrong",
+		},
+	}
+
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err == nil {
+		t.Errorf("Expected error for invalid client credentials, Found: nil")
+	}
+}
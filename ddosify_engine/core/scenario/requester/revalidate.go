@@ -0,0 +1,75 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"net/http"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// setConditionalHeaders adds the If-None-Match and If-Modified-Since headers from the stored validator of the url
+// to the GET and HEAD requests. Headers given by the step are not overridden. Returns true if the request is
+// conditional.
+func setConditionalHeaders(req *http.Request, validators *types.Validators) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	v, ok := validators.Get(req.URL.String())
+	if !ok {
+		return false
+	}
+	conditional := false
+	if v.ETag != "" && req.Header.Get("If-None-Match") == "" {
+		req.Header.Set("If-None-Match", v.ETag)
+		conditional = true
+	}
+	if v.LastModified != "" && req.Header.Get("If-Modified-Since") == "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+		conditional = true
+	}
+	return conditional
+}
+
+// storeValidators keeps the validators of a successful response for the next requests to the url. A 304
+// response keeps the stored ones unless it has new validators.
+func storeValidators(req *http.Request, res *http.Response, validators *types.Validators) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return
+	}
+	v := types.Validator{ETag: res.Header.Get("ETag"), LastModified: res.Header.Get("Last-Modified")}
+	if v.ETag == "" && v.LastModified == "" {
+		return
+	}
+	if res.StatusCode == http.StatusNotModified {
+		if old, ok := validators.Get(req.URL.String()); ok {
+			if v.ETag == "" {
+				v.ETag = old.ETag
+			}
+			if v.LastModified == "" {
+				v.LastModified = old.LastModified
+			}
+		}
+	} else if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return
+	}
+	validators.Set(req.URL.String(), v)
+}
@@ -0,0 +1,113 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestSendRevalidate(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	s := types.ScenarioStep{
+		ID:         1,
+		Method:     http.MethodGet,
+		URL:        server.URL,
+		Validators: types.NewValidatorStore(),
+	}
+	h := &HttpRequester{}
+	if err := h.Init(context.TODO(), s, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	user, other := &http.Client{}, &http.Client{}
+	tests := []struct {
+		client      *http.Client
+		statusCode  int
+		revalidated bool
+	}{
+		{user, http.StatusOK, false},
+		{user, http.StatusNotModified, true},
+		{user, http.StatusNotModified, true},
+		// validators are kept per user
+		{other, http.StatusOK, false},
+	}
+	for i, test := range tests {
+		res := h.Send(test.client, map[string]interface{}{})
+		if res.StatusCode != test.statusCode || res.Revalidated != test.revalidated {
+			t.Errorf("%d Expected %v, Found: %v", i, []interface{}{test.statusCode, test.revalidated},
+				[]interface{}{res.StatusCode, res.Revalidated})
+		}
+	}
+	// a new user of the client starts with no validators
+	s.Validators.Forget(user)
+	if res := h.Send(user, map[string]interface{}{}); res.StatusCode != http.StatusOK || res.Revalidated {
+		t.Errorf("Expected %v, Found: %v", http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestStoreValidators(t *testing.T) {
+	t.Parallel()
+
+	validators := types.NewValidatorStore().Of(nil)
+	req := httptest.NewRequest(http.MethodGet, "http://ddosify.test/a", nil)
+	res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": {`"v1"`}, "Last-Modified": {"lm"}}}
+	storeValidators(req, res, validators)
+
+	// 304 updates the given validators, keeps the others
+	res = &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{"Etag": {`"v2"`}}}
+	storeValidators(req, res, validators)
+	expected := types.Validator{ETag: `"v2"`, LastModified: "lm"}
+	if v, _ := validators.Get(req.URL.String()); v != expected {
+		t.Errorf("Expected %v, Found: %v", expected, v)
+	}
+
+	// validators of the errors and the other methods are not stored
+	res = &http.Response{StatusCode: http.StatusInternalServerError, Header: http.Header{"Etag": {`"v3"`}}}
+	storeValidators(req, res, validators)
+	post := httptest.NewRequest(http.MethodPost, "http://ddosify.test/b", nil)
+	storeValidators(post, &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": {`"v4"`}}}, validators)
+	if v, _ := validators.Get(req.URL.String()); v != expected {
+		t.Errorf("Expected %v, Found: %v", expected, v)
+	}
+	if _, ok := validators.Get(post.URL.String()); ok {
+		t.Errorf("Expected no validator for %v", post.URL)
+	}
+	if setConditionalHeaders(post, validators) {
+		t.Errorf("Expected %v, Found: %v", false, true)
+	}
+}
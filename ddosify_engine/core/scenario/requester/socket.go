@@ -0,0 +1,378 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/evaluator"
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/types/regex"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+const (
+	tcpPoolMaxCap = 1000
+
+	// Buffer size of a single read when the types.SocketConf.ReadBytes is not given, fits the largest udp datagram
+	socketReadBufSize = 64 * 1024
+)
+
+type TCPFactory func() net.Conn
+type TCPCloseMethod func(net.Conn)
+
+// NewTCPConnPool creates a pool of tcp connections, mirrors the NewClientPool of the HTTP clients.
+func NewTCPConnPool(initialCap, maxCap int, factory TCPFactory, close TCPCloseMethod) (*util.Pool[net.Conn], error) {
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	pool := &util.Pool[net.Conn]{
+		Items:   make(chan net.Conn, maxCap),
+		Factory: factory,
+		Close:   close,
+	}
+	pool.Fill(initialCap)
+
+	return pool, nil
+}
+
+func closeTCPConn(c net.Conn) {
+	if c != nil {
+		c.Close()
+	}
+}
+
+// SocketRequester sends the raw payload of the tcp and udp steps. Tcp connections are pooled and reused across
+// iterations, a new udp socket is used for each request.
+type SocketRequester struct {
+	ctx        context.Context
+	packet     types.ScenarioStep
+	ei         *injection.EnvironmentInjector
+	debug      bool
+	network    string
+	pool       *util.Pool[net.Conn] // tcp only
+	dial       DialContextFunc
+	match      *regexp.Regexp
+	dynamicRgx *regexp.Regexp
+	envRgx     *regexp.Regexp
+}
+
+// Init creates the connection pool of the tcp steps. Connections are dialed lazily on the first Send.
+// Proxies are not supported by the raw sockets, proxyAddr is ignored.
+func (sr *SocketRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAddr *url.URL, debug bool,
+	ei *injection.EnvironmentInjector) (err error) {
+	sr.ctx = ctx
+	sr.packet = s
+	sr.ei = ei
+	sr.debug = debug
+	sr.network = s.Type
+	sr.dynamicRgx = regexp.MustCompile(regex.DynamicVariableRegex)
+	sr.envRgx = regexp.MustCompile(regex.EnvironmentVariableRegex)
+
+	sr.dial = s.DialContext
+	if sr.dial == nil {
+		sr.dial = (&net.Dialer{}).DialContext
+	}
+
+	if s.Socket.Match != "" {
+		if sr.match, err = regexp.Compile(s.Socket.Match); err != nil {
+			return
+		}
+	}
+
+	if sr.network == types.StepTypeTCP {
+		// Factory can't dial since the address may contain variables, connections are created in Send
+		sr.pool, err = NewTCPConnPool(0, tcpPoolMaxCap, func() net.Conn { return nil }, closeTCPConn)
+	}
+	return
+}
+
+func (sr *SocketRequester) Send(envs map[string]interface{}) (res *types.ScenarioStepResult) {
+	var requestErr types.RequestError
+	var respBody []byte
+	var extractedVars = make(map[string]interface{})
+	var failedCaptures = make(map[string]string, 0)
+	var failedAssertions = make([]types.FailedAssertion, 0)
+
+	var usableVars = make(map[string]interface{}, len(envs))
+	for k, v := range envs {
+		usableVars[k] = v
+	}
+
+	res = &types.ScenarioStepResult{
+		StepID:    sr.packet.ID,
+		StepName:  sr.packet.Name,
+		RequestID: uuid.New(),
+	}
+
+	target, addr, payload, err := sr.prepareReq(usableVars)
+	if err != nil {
+		res.Err = types.RequestError{
+			Type:   types.ErrorInvalidRequest,
+			Reason: fmt.Sprintf("Could not prepare req, %s", err.Error()),
+		}
+		return res
+	}
+	res.Url = target
+	res.Method = strings.ToUpper(sr.network)
+	res.ReqBody = payload
+
+	reqStartTime := time.Now()
+	respBody, err = sr.send(addr, payload)
+	dur := time.Since(reqStartTime)
+	if err != nil {
+		requestErr = fetchSocketErrType(sr.ctx, err)
+	}
+
+	if requestErr.Type == "" {
+		if len(sr.packet.EnvsToCapture) > 0 {
+			failedCaptures = captureEnvironmentVariables(sr.packet.EnvsToCapture, nil, respBody, nil, nil, extractedVars)
+			requestErr = fatalCaptureError(sr.packet.EnvsToCapture, failedCaptures)
+		}
+
+		if sr.match != nil && !sr.match.Match(respBody) {
+			failedAssertions = append(failedAssertions, types.FailedAssertion{
+				Rule:     fmt.Sprintf("match(%q)", sr.packet.Socket.Match),
+				Received: map[string]interface{}{"body": string(respBody)},
+				Reason:   "response does not match the pattern",
+			})
+		}
+
+		if len(sr.packet.Assertions) > 0 {
+			_, assertionFails := applyAssertions(sr.packet.Assertions, &evaluator.AssertEnv{
+				ResponseSize: int64(len(respBody)),
+				ResponseTime: dur.Milliseconds(), // in ms
+				Body:         string(respBody),
+				Variables:    concatEnvs(envs, extractedVars),
+			})
+			failedAssertions = append(failedAssertions, assertionFails...)
+		}
+	} else {
+		failedCaptures = captureEnvironmentVariables(sr.packet.EnvsToCapture, nil, nil, nil, nil, extractedVars)
+	}
+
+	res.RequestTime = reqStartTime
+	res.Duration = dur
+	res.ContentLength = int64(len(respBody))
+	res.Err = requestErr
+	res.RespBody = respBody
+	res.Custom = map[string]interface{}{
+		"socketBytesSent":     len(payload),
+		"socketBytesReceived": len(respBody),
+	}
+	res.ExtractedEnvs = extractedVars
+	res.UsableEnvs = usableVars
+	res.FailedCaptures = failedCaptures
+	res.FailedAssertions = failedAssertions
+
+	return res
+}
+
+// send writes the payload to a pooled tcp connection or to a new udp socket and reads the response.
+// A pooled connection may be closed by the server while it is idle, the request is sent once more on
+// a new connection in that case, like the retries of the http.Transport on the reused connections.
+func (sr *SocketRequester) send(addr string, payload []byte) ([]byte, error) {
+	if sr.pool == nil {
+		conn, err := sr.dialConn(addr)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		return sr.exchange(conn, payload)
+	}
+
+	conn := sr.pool.Get()
+	reused := conn != nil
+	for {
+		var err error
+		if conn == nil {
+			if conn, err = sr.dialConn(addr); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := sr.exchange(conn, payload)
+		if err == nil {
+			if sr.packet.DisableKeepAlive {
+				sr.pool.Close(conn)
+			} else {
+				sr.pool.Put(conn)
+			}
+			return resp, nil
+		}
+
+		// the stream can't be reused after a partial read or a deadline
+		sr.pool.Close(conn)
+		if !reused || len(resp) > 0 || isTimeout(err) || sr.ctx.Err() != nil {
+			return resp, err
+		}
+		conn, reused = nil, false
+	}
+}
+
+func (sr *SocketRequester) dialConn(addr string) (net.Conn, error) {
+	timeout := sr.packet.DialTimeout
+	if timeout == 0 {
+		timeout = sr.packet.TimeoutDuration()
+	}
+	ctx, cancel := context.WithTimeout(sr.ctx, timeout)
+	defer cancel()
+
+	conn, err := sr.dial(ctx, sr.network, addr)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && sr.ctx.Err() == nil {
+		return nil, &dialTimeoutError{err: err}
+	}
+	return conn, err
+}
+
+// exchange writes the payload and reads the response according to the types.SocketConf.
+func (sr *SocketRequester) exchange(conn net.Conn, payload []byte) (resp []byte, err error) {
+	conf := sr.packet.Socket
+	conn.SetDeadline(time.Now().Add(sr.packet.TimeoutDuration()))
+
+	// unblock the reads if the engine is stopped
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-sr.ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	if len(payload) > 0 {
+		if _, err = conn.Write(payload); err != nil {
+			return
+		}
+	}
+	if conf.WriteOnly {
+		return
+	}
+
+	if conf.ReadBytes == 0 {
+		buf := make([]byte, socketReadBufSize)
+		n, err := conn.Read(buf)
+		return buf[:n], err
+	}
+
+	if sr.network == types.StepTypeTCP {
+		resp = make([]byte, conf.ReadBytes)
+		n, err := io.ReadFull(conn, resp)
+		return resp[:n], err
+	}
+
+	// udp, read the datagrams until the expected bytes are received
+	buf := make([]byte, socketReadBufSize)
+	for len(resp) < conf.ReadBytes {
+		var n int
+		if n, err = conn.Read(buf); err != nil {
+			return
+		}
+		resp = append(resp, buf[:n]...)
+	}
+	return
+}
+
+// prepareReq injects the dynamic and environment variables into the url and the payload, the payload is
+// decoded after the injection if it is hex encoded.
+func (sr *SocketRequester) prepareReq(envs map[string]interface{}) (string, string, []byte, error) {
+	target, err := sr.inject(sr.packet.URL, envs)
+	if err != nil {
+		return "", "", nil, err
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" || u.Port() == "" {
+		return "", "", nil, fmt.Errorf("invalid %s target: %s", sr.network, target)
+	}
+
+	payload, err := sr.inject(sr.packet.Payload, envs)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if !sr.packet.Socket.Hex {
+		return target, u.Host, []byte(payload), nil
+	}
+
+	b, err := hex.DecodeString(strings.Join(strings.Fields(payload), ""))
+	if err != nil {
+		return "", "", nil, fmt.Errorf("payload is not hex encoded, %v", err)
+	}
+	return target, u.Host, b, nil
+}
+
+func (sr *SocketRequester) inject(s string, envs map[string]interface{}) (string, error) {
+	var err error
+	if sr.dynamicRgx.MatchString(s) {
+		s, err = sr.ei.Of(envs).InjectDynamic(s)
+		if err != nil {
+			return "", err
+		}
+	}
+	if sr.envRgx.MatchString(s) {
+		s, err = sr.ei.InjectEnv(s, envs)
+		if err != nil {
+			return "", err
+		}
+	}
+	return s, nil
+}
+
+func fetchSocketErrType(ctx context.Context, err error) types.RequestError {
+	var dialErr *dialTimeoutError
+	switch {
+	case ctx.Err() != nil:
+		return types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled}
+	case portExhausted(err):
+		return types.RequestError{Type: types.ErrorLocal, Reason: types.ReasonPortExhausted}
+	case errors.As(err, &dialErr):
+		return types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonDialTimeout}
+	case isTimeout(err):
+		return types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonReadTimeout}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnRefused}
+	}
+	return types.RequestError{Type: types.ErrorConn, Reason: err.Error()}
+}
+
+// Done closes the pooled tcp connections.
+func (sr *SocketRequester) Done() {
+	if sr.pool != nil {
+		sr.pool.Done()
+	}
+}
+
+func (sr *SocketRequester) Type() string {
+	return "SOCKET"
+}
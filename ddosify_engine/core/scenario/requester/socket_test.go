@@ -0,0 +1,240 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// newEchoListener echoes the received lines back with a "+" prefix, counts the accepted connections.
+func newEchoListener(t *testing.T, accepted chan<- struct{}) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer c.Close()
+				buf := make([]byte, 1024)
+				for {
+					n, err := c.Read(buf)
+					if err != nil {
+						return
+					}
+					c.Write(append([]byte("+"), buf[:n]...))
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestSocketRequesterTCP(t *testing.T) {
+	t.Parallel()
+
+	accepted := make(chan struct{}, 10)
+	l := newEchoListener(t, accepted)
+	defer l.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	s := types.ScenarioStep{
+		ID:         1,
+		Type:       types.StepTypeTCP,
+		URL:        "tcp://" + l.Addr().String(),
+		Payload:    "PING {{NAME}}\r\n",
+		Timeout:    types.DefaultTimeout,
+		Socket:     types.SocketConf{ReadBytes: 13, Match: `^\+PING`},
+		Assertions: []string{"equals(response_size, 13)"},
+	}
+
+	sr := &SocketRequester{}
+	if err := sr.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		res := sr.Send(map[string]interface{}{"NAME": "DDSFY"})
+		if res.Err.Type != "" {
+			t.Fatalf("Expected no error, Found: %v", res.Err)
+		}
+		if string(res.RespBody) != "+PING DDSFY\r\n" {
+			t.Errorf("Expected %q, Found: %q", "+PING DDSFY\r\n", res.RespBody)
+		}
+		if res.Method != "TCP" {
+			t.Errorf("Expected %v, Found: %v", "TCP", res.Method)
+		}
+		if len(res.FailedAssertions) != 0 {
+			t.Errorf("Expected no failed assertion, Found: %v", res.FailedAssertions)
+		}
+	}
+
+	// connection is reused in the second send
+	if len(accepted) != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, len(accepted))
+	}
+	if sr.pool.Len() != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, sr.pool.Len())
+	}
+	sr.Done()
+}
+
+func TestSocketRequesterTCPMatchFail(t *testing.T) {
+	t.Parallel()
+
+	l := newEchoListener(t, make(chan struct{}, 10))
+	defer l.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeTCP,
+		URL:     "tcp://" + l.Addr().String(),
+		Payload: "50494e47", // PING
+		Timeout: types.DefaultTimeout,
+		Socket:  types.SocketConf{Hex: true, Match: "PONG"},
+	}
+
+	sr := &SocketRequester{}
+	if err := sr.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer sr.Done()
+
+	res := sr.Send(map[string]interface{}{})
+	if res.Err.Type != "" {
+		t.Fatalf("Expected no error, Found: %v", res.Err)
+	}
+	if !bytes.Equal(res.ReqBody, []byte("PING")) {
+		t.Errorf("Expected %q, Found: %q", "PING", res.ReqBody)
+	}
+	if len(res.FailedAssertions) != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, res.FailedAssertions)
+	}
+}
+
+func TestSocketRequesterTCPErrors(t *testing.T) {
+	t.Parallel()
+
+	// closed listener, connection is refused
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := l.Addr().String()
+	l.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeTCP,
+		URL:     "tcp://" + addr,
+		Timeout: types.DefaultTimeout,
+	}
+	sr := &SocketRequester{}
+	sr.Init(context.Background(), s, nil, false, ei)
+	defer sr.Done()
+
+	res := sr.Send(map[string]interface{}{})
+	if res.Err.Type != types.ErrorConn || res.Err.Reason != types.ReasonConnRefused {
+		t.Errorf("Expected %v, Found: %v", types.ReasonConnRefused, res.Err)
+	}
+
+	// server never responds
+	silent, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer silent.Close()
+	s.URL = "tcp://" + silent.Addr().String()
+	s.RequestTimeout = 100 * time.Millisecond
+	sr = &SocketRequester{}
+	sr.Init(context.Background(), s, nil, false, ei)
+	defer sr.Done()
+
+	res = sr.Send(map[string]interface{}{})
+	if res.Err.Type != types.ErrorTimeout || res.Err.Reason != types.ReasonReadTimeout {
+		t.Errorf("Expected %v, Found: %v", types.ReasonReadTimeout, res.Err)
+	}
+}
+
+func TestSocketRequesterUDP(t *testing.T) {
+	t.Parallel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			// two datagrams per request
+			pc.WriteTo(buf[:n], addr)
+			pc.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	s := types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeUDP,
+		URL:     "udp://" + pc.LocalAddr().String(),
+		Payload: "0102",
+		Timeout: types.DefaultTimeout,
+		Socket:  types.SocketConf{Hex: true, ReadBytes: 4},
+	}
+
+	sr := &SocketRequester{}
+	if err := sr.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer sr.Done()
+
+	res := sr.Send(map[string]interface{}{})
+	if res.Err.Type != "" {
+		t.Fatalf("Expected no error, Found: %v", res.Err)
+	}
+	if !bytes.Equal(res.RespBody, []byte{1, 2, 1, 2}) {
+		t.Errorf("Expected %v, Found: %v", []byte{1, 2, 1, 2}, res.RespBody)
+	}
+	if res.Custom["socketBytesReceived"] != 4 {
+		t.Errorf("Expected %v, Found: %v", 4, res.Custom["socketBytesReceived"])
+	}
+}
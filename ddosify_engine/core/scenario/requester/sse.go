@@ -0,0 +1,336 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/evaluator"
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/types/regex"
+)
+
+const (
+	// Max length of a line of the event stream
+	sseMaxLineSize = 1 << 20
+
+	// Max bytes of a non event stream response body read for the captures and assertions
+	sseMaxBodySize = 1 << 20
+)
+
+// SSERequester opens the server-sent events stream of a sse step and reads its events.
+// Each Send opens a new stream, so the concurrent iterations are the concurrent subscribers of the server.
+type SSERequester struct {
+	ctx        context.Context
+	packet     types.ScenarioStep
+	ei         *injection.EnvironmentInjector
+	debug      bool
+	client     *http.Client
+	dynamicRgx *regexp.Regexp
+	envRgx     *regexp.Regexp
+}
+
+// Init creates the client of the streams. Reads are bounded by the context of each Send instead of a client timeout.
+func (s *SSERequester) Init(ctx context.Context, ss types.ScenarioStep, proxyAddr *url.URL, debug bool,
+	ei *injection.EnvironmentInjector) (err error) {
+	s.ctx = ctx
+	s.packet = ss
+	s.ei = ei
+	s.debug = debug
+	s.dynamicRgx = regexp.MustCompile(regex.DynamicVariableRegex)
+	s.envRgx = regexp.MustCompile(regex.EnvironmentVariableRegex)
+
+	tr := &http.Transport{
+		Proxy:               http.ProxyURL(proxyAddr),
+		TLSClientConfig:     newTLSConfig(ss),
+		TLSHandshakeTimeout: ss.TimeoutDuration(),
+		DisableCompression:  true, // events should be delivered as they are flushed
+	}
+	if ss.DialContext != nil {
+		tr.DialContext = ss.DialContext
+	}
+	s.client = &http.Client{Transport: tr}
+	return
+}
+
+func (s *SSERequester) Send(envs map[string]interface{}) (res *types.ScenarioStepResult) {
+	var requestErr types.RequestError
+	var statusCode int
+	var respHeaders http.Header
+	var respBody []byte
+	var events []string
+	var intervals []time.Duration
+	var bytesReceived int64
+	var extractedVars = make(map[string]interface{})
+	var failedCaptures = make(map[string]string, 0)
+	var failedAssertions = make([]types.FailedAssertion, 0)
+
+	var usableVars = make(map[string]interface{}, len(envs))
+	for k, v := range envs {
+		usableVars[k] = v
+	}
+
+	res = &types.ScenarioStepResult{
+		StepID:    s.packet.ID,
+		StepName:  s.packet.Name,
+		RequestID: uuid.New(),
+	}
+
+	target, header, payload, err := s.prepareReq(usableVars)
+	if err != nil {
+		res.Err = types.RequestError{
+			Type:   types.ErrorInvalidRequest,
+			Reason: fmt.Sprintf("Could not prepare req, %s", err.Error()),
+		}
+		return res
+	}
+	res.Url = target
+	res.Method = s.packet.Method
+	res.ReqHeaders = header
+	res.ReqBody = []byte(payload)
+
+	conf := s.packet.SSE
+	readTimeout := s.packet.TimeoutDuration()
+	if conf.ReadDuration > 0 {
+		readTimeout = time.Duration(conf.ReadDuration) * time.Millisecond
+	}
+	// canceling the context closes the stream, also when the engine is stopped
+	readCtx, cancel := context.WithTimeout(s.ctx, readTimeout)
+	defer cancel()
+
+	reqStartTime := time.Now()
+	req, err := http.NewRequestWithContext(readCtx, s.packet.Method, target, strings.NewReader(payload))
+	if err == nil {
+		req.Header = header
+		var httpRes *http.Response
+		if httpRes, err = s.client.Do(req); err == nil {
+			statusCode = httpRes.StatusCode
+			respHeaders = httpRes.Header
+			if statusCode == http.StatusOK {
+				events, intervals, bytesReceived, err = s.readEvents(httpRes.Body)
+			} else {
+				// not a stream, like an auth error. Body is kept for the assertions.
+				respBody, err = io.ReadAll(io.LimitReader(httpRes.Body, sseMaxBodySize))
+				bytesReceived = int64(len(respBody))
+			}
+			httpRes.Body.Close()
+		}
+	}
+	dur := time.Since(reqStartTime)
+
+	if err != nil {
+		if s.ctx.Err() != nil {
+			requestErr = types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled}
+		} else if errors.Is(readCtx.Err(), context.DeadlineExceeded) {
+			if statusCode == 0 {
+				requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonConnTimeout}
+			} else if conf.ReadDuration == 0 {
+				// timeout is expected only when reading for a duration
+				requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonReadTimeout}
+			}
+		} else {
+			requestErr = fetchErrType(err)
+		}
+	}
+
+	if len(events) > 0 {
+		// captures and assertions are applied to the data of the last event
+		respBody = []byte(events[len(events)-1])
+	}
+
+	if requestErr.Type == "" {
+		if len(s.packet.EnvsToCapture) > 0 {
+			failedCaptures = captureEnvironmentVariables(s.packet.EnvsToCapture, respHeaders, respBody, nil, nil, extractedVars)
+			requestErr = fatalCaptureError(s.packet.EnvsToCapture, failedCaptures)
+		}
+
+		if len(s.packet.Assertions) > 0 {
+			_, failedAssertions = applyAssertions(s.packet.Assertions, &evaluator.AssertEnv{
+				StatusCode:   int64(statusCode),
+				ResponseSize: bytesReceived,
+				ResponseTime: dur.Milliseconds(), // in ms
+				Body:         string(respBody),
+				Headers:      respHeaders,
+				Variables:    concatEnvs(envs, extractedVars),
+			})
+		}
+	} else {
+		failedCaptures = captureEnvironmentVariables(s.packet.EnvsToCapture, nil, nil, nil, nil, extractedVars)
+	}
+
+	var avgInterval time.Duration
+	for _, i := range intervals {
+		avgInterval += i
+	}
+	if len(intervals) > 0 {
+		avgInterval /= time.Duration(len(intervals))
+	}
+
+	res.StatusCode = statusCode
+	res.RequestTime = reqStartTime
+	res.Duration = dur
+	res.ContentLength = bytesReceived
+	res.Err = requestErr
+	res.RespHeaders = respHeaders
+	res.RespBody = respBody
+	res.EventIntervals = intervals
+	res.Custom = map[string]interface{}{
+		"sseEventCount":     len(intervals),
+		"sseBytesReceived":  bytesReceived,
+		"sseEventIntervals": intervals,
+		"sseAvgInterval":    avgInterval,
+	}
+	res.ExtractedEnvs = extractedVars
+	res.UsableEnvs = usableVars
+	res.FailedCaptures = failedCaptures
+	res.FailedAssertions = failedAssertions
+
+	return res
+}
+
+// readEvents reads the events of the stream according to the types.SSEConf, until the stream is closed by the server.
+// Interval of an event is the duration since the previous event, or since the response headers for the first one.
+// Data of the events is kept only if it is needed by the debug mode, the captures or the assertions.
+func (s *SSERequester) readEvents(body io.Reader) (events []string, intervals []time.Duration,
+	bytesReceived int64, err error) {
+	count := s.packet.SSE.EventCount
+	if count == 0 && s.packet.SSE.ReadDuration == 0 {
+		count = 1
+	}
+	keepData := s.debug || len(s.packet.EnvsToCapture) > 0 || len(s.packet.Assertions) > 0
+
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 0, 4096), sseMaxLineSize)
+
+	var data []string
+	var hasData bool
+	last := time.Now()
+	for (count == 0 || len(intervals) < count) && sc.Scan() {
+		line := sc.Text()
+		bytesReceived += int64(len(line)) + 1
+
+		if line == "" {
+			// blank line dispatches the event, events without data are ignored like the browsers do
+			if hasData {
+				now := time.Now()
+				intervals = append(intervals, now.Sub(last))
+				last = now
+				if keepData {
+					events = append(events, strings.Join(data, "\n"))
+				}
+			}
+			data, hasData = data[:0], false
+			continue
+		}
+		if line[0] == ':' {
+			continue // comment, like a heartbeat
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		// event, id and retry fields don't change the measurements
+		if field == "data" {
+			data = append(data, value)
+			hasData = true
+		}
+	}
+	return events, intervals, bytesReceived, sc.Err()
+}
+
+// prepareReq injects the dynamic and environment variables into the url, the headers and the payload.
+func (s *SSERequester) prepareReq(envs map[string]interface{}) (string, http.Header, string, error) {
+	target, err := s.inject(s.packet.URL, envs)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	payload, err := s.inject(s.packet.Payload, envs)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	header := make(http.Header)
+	header.Set("Accept", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	for k, v := range s.packet.Headers {
+		kk, err := s.inject(k, envs)
+		if err != nil {
+			return "", nil, "", err
+		}
+		vv, err := s.inject(v, envs)
+		if err != nil {
+			return "", nil, "", err
+		}
+		header.Set(kk, vv)
+	}
+	if s.packet.Auth.Type == types.AuthBearer {
+		token, err := s.inject(s.packet.Auth.Token, envs)
+		if err != nil {
+			return "", nil, "", err
+		}
+		header.Set("Authorization", "Bearer "+token)
+	} else if s.packet.Auth != (types.Auth{}) {
+		r := &http.Request{Header: header}
+		r.SetBasicAuth(s.packet.Auth.Username, s.packet.Auth.Password)
+	}
+	return target, header, payload, nil
+}
+
+func (s *SSERequester) inject(str string, envs map[string]interface{}) (string, error) {
+	var err error
+	if s.dynamicRgx.MatchString(str) {
+		str, err = s.ei.Of(envs).InjectDynamic(str)
+		if err != nil {
+			return "", err
+		}
+	}
+	if s.envRgx.MatchString(str) {
+		str, err = s.ei.InjectEnv(str, envs)
+		if err != nil {
+			return "", err
+		}
+	}
+	return str, nil
+}
+
+// Done closes the idle connections, the streams are closed at the end of each Send.
+func (s *SSERequester) Done() {
+	if s.client != nil {
+		s.client.CloseIdleConnections()
+	}
+}
+
+func (s *SSERequester) Type() string {
+	return "SSE"
+}
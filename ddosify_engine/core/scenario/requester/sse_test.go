@@ -0,0 +1,202 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// newEventServer streams 3 price events and holds the stream until the client disconnects.
+func newEventServer(disconnected chan<- struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			rw.Write([]byte(`{"error":"unauthorized"}`))
+			return
+		}
+		rw.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(rw, ": connected\n\n")
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(rw, "event: tick\nid: %d\ndata: {\"symbol\":\"%s\",\ndata: \"price\":%d}\n\n", i, r.URL.Query().Get("s"), i)
+			rw.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		<-r.Context().Done()
+		disconnected <- struct{}{}
+	}))
+}
+
+func newSSEStep(url string, conf types.SSEConf) types.ScenarioStep {
+	return types.ScenarioStep{
+		ID:      1,
+		Type:    types.StepTypeSSE,
+		Method:  http.MethodGet,
+		URL:     url,
+		Timeout: types.DefaultTimeout,
+		Auth:    types.Auth{Type: types.AuthBearer, Token: "{{TOKEN}}"},
+		SSE:     conf,
+	}
+}
+
+func TestSSERequesterSend(t *testing.T) {
+	t.Parallel()
+
+	disconnected := make(chan struct{}, 1)
+	srv := newEventServer(disconnected)
+	defer srv.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	symbolPath := "symbol"
+	s := newSSEStep(srv.URL+"?s={{SYMBOL}}", types.SSEConf{EventCount: 3})
+	s.Assertions = []string{"equals(json_path(\"price\"), 3)"}
+	s.EnvsToCapture = []types.EnvCaptureConf{{Name: "SYM", From: types.Body, JsonPath: &symbolPath}}
+
+	r := &SSERequester{}
+	if err := r.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer r.Done()
+
+	res := r.Send(map[string]interface{}{"SYMBOL": "DDSFY", "TOKEN": "secret"})
+	if res.Err.Type != "" {
+		t.Fatalf("Expected no error, Found: %v", res.Err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected %v, Found: %v", http.StatusOK, res.StatusCode)
+	}
+	if len(res.EventIntervals) != 3 || res.Custom["sseEventCount"] != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, res.EventIntervals)
+	}
+	if res.ContentLength == 0 {
+		t.Errorf("Expected received bytes to be reported")
+	}
+	if len(res.FailedAssertions) != 0 {
+		t.Errorf("Expected no failed assertion, Found: %v", res.FailedAssertions)
+	}
+	if res.ExtractedEnvs["SYM"] != "DDSFY" {
+		t.Errorf("Expected %v, Found: %v", "DDSFY", res.ExtractedEnvs["SYM"])
+	}
+
+	// stream is closed after the events are read
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected the stream to be closed after the send")
+	}
+}
+
+func TestSSERequesterReadDuration(t *testing.T) {
+	t.Parallel()
+
+	srv := newEventServer(make(chan struct{}, 1))
+	defer srv.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	r := &SSERequester{}
+	if err := r.Init(context.Background(), newSSEStep(srv.URL, types.SSEConf{ReadDuration: 200}), nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer r.Done()
+
+	res := r.Send(map[string]interface{}{"TOKEN": "secret"})
+	if res.Err.Type != "" {
+		t.Fatalf("Expected no error, Found: %v", res.Err)
+	}
+	if len(res.EventIntervals) != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, len(res.EventIntervals))
+	}
+	if res.Duration < 200*time.Millisecond {
+		t.Errorf("Expected to read at least 200ms, Found: %v", res.Duration)
+	}
+}
+
+func TestSSERequesterErrors(t *testing.T) {
+	t.Parallel()
+
+	srv := newEventServer(make(chan struct{}, 1))
+	defer srv.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	// not a stream, the response is the result
+	r := &SSERequester{}
+	r.Init(context.Background(), newSSEStep(srv.URL, types.SSEConf{}), nil, false, ei)
+	res := r.Send(map[string]interface{}{"TOKEN": "wrong"})
+	if res.Err.Type != "" || res.StatusCode != http.StatusUnauthorized || string(res.RespBody) != `{"error":"unauthorized"}` {
+		t.Errorf("Expected %v, Found: %v %v %s", http.StatusUnauthorized, res.Err, res.StatusCode, res.RespBody)
+	}
+	r.Done()
+
+	// engine is stopped while waiting for the events
+	ctx, cancel := context.WithCancel(context.Background())
+	r = &SSERequester{}
+	r.Init(ctx, newSSEStep(srv.URL, types.SSEConf{EventCount: 5}), nil, false, ei)
+	defer r.Done()
+	time.AfterFunc(100*time.Millisecond, cancel)
+	res = r.Send(map[string]interface{}{"TOKEN": "secret"})
+	if res.Err.Type != types.ErrorIntented {
+		t.Errorf("Expected %v, Found: %v", types.ErrorIntented, res.Err)
+	}
+}
+
+func TestSSEReadEvents(t *testing.T) {
+	t.Parallel()
+
+	stream := ": heartbeat\r\n\r\n" +
+		"data: first\r\n\r\n" +
+		"event: ping\nid: 2\n\n" + // no data, not dispatched
+		"data:multi\ndata: line\n\n" +
+		"data: unterminated"
+
+	r := &SSERequester{packet: types.ScenarioStep{SSE: types.SSEConf{ReadDuration: 1000}}, debug: true}
+	events, intervals, bytesReceived, err := r.readEvents(strings.NewReader(stream))
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	expected := []string{"first", "multi\nline"}
+	if !reflect.DeepEqual(events, expected) || len(intervals) != 2 {
+		t.Errorf("Expected %q, Found: %q", expected, events)
+	}
+	if bytesReceived != int64(len(strings.ReplaceAll(stream, "\r", "")))+1 {
+		t.Errorf("Expected %v, Found: %v", len(strings.ReplaceAll(stream, "\r", ""))+1, bytesReceived)
+	}
+
+	// event count is reached
+	r.packet.SSE = types.SSEConf{EventCount: 1}
+	if events, _, _, _ = r.readEvents(strings.NewReader(stream)); !reflect.DeepEqual(events, []string{"first"}) {
+		t.Errorf("Expected %q, Found: %q", []string{"first"}, events)
+	}
+}
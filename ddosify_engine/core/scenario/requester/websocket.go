@@ -0,0 +1,388 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"go.ddosify.com/ddosify/core/scenario/scripting/assertion/evaluator"
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/types/regex"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+const (
+	wsPoolMaxCap = 1000
+
+	// Maximum duration to wait for the close frame of the server in teardown
+	wsCloseAckTimeout = time.Second
+)
+
+type WSFactory func() *websocket.Conn
+type WSCloseMethod func(*websocket.Conn)
+
+// NewWSConnPool creates a pool of websocket connections, mirrors the NewClientPool of the HTTP clients.
+func NewWSConnPool(initialCap, maxCap int, factory WSFactory, close WSCloseMethod) (*util.Pool[*websocket.Conn], error) {
+	if initialCap < 0 || maxCap <= 0 || initialCap > maxCap {
+		return nil, errors.New("invalid capacity settings")
+	}
+
+	pool := &util.Pool[*websocket.Conn]{
+		Items:   make(chan *websocket.Conn, maxCap),
+		Factory: factory,
+		Close:   close,
+	}
+	pool.Fill(initialCap)
+
+	return pool, nil
+}
+
+// closeWSConn sends a close frame and waits briefly for the close frame of the server before closing the
+// underlying connection.
+func closeWSConn(c *websocket.Conn) {
+	if c == nil {
+		return
+	}
+	defer c.Close()
+
+	deadline := time.Now().Add(wsCloseAckTimeout)
+	err := c.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	if err != nil {
+		return
+	}
+
+	// read until the close frame of the server arrives, ReadMessage returns *websocket.CloseError for it
+	c.SetReadDeadline(deadline)
+	for {
+		if _, _, err = c.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+type WebSocketRequester struct {
+	ctx        context.Context
+	proxyAddr  *url.URL
+	packet     types.ScenarioStep
+	ei         *injection.EnvironmentInjector
+	debug      bool
+	pool       *util.Pool[*websocket.Conn]
+	dialer     *websocket.Dialer
+	dynamicRgx *regexp.Regexp
+	envRgx     *regexp.Regexp
+}
+
+// Init creates the dialer and the connection pool. Connections are dialed lazily on the first Send.
+func (w *WebSocketRequester) Init(ctx context.Context, s types.ScenarioStep, proxyAddr *url.URL, debug bool,
+	ei *injection.EnvironmentInjector) (err error) {
+	w.ctx = ctx
+	w.packet = s
+	w.proxyAddr = proxyAddr
+	w.ei = ei
+	w.debug = debug
+	w.dynamicRgx = regexp.MustCompile(regex.DynamicVariableRegex)
+	w.envRgx = regexp.MustCompile(regex.EnvironmentVariableRegex)
+
+	w.dialer = &websocket.Dialer{
+		Proxy:            http.ProxyURL(w.proxyAddr),
+		TLSClientConfig:  w.initTLSConfig(),
+		HandshakeTimeout: w.packet.TimeoutDuration(),
+		NetDialContext:   w.packet.DialContext,
+	}
+
+	// Factory can't dial since the url may contain variables, connections are created in Send
+	w.pool, err = NewWSConnPool(0, wsPoolMaxCap, func() *websocket.Conn { return nil }, closeWSConn)
+	return
+}
+
+func (w *WebSocketRequester) initTLSConfig() *tls.Config {
+	return newTLSConfig(w.packet)
+}
+
+func (w *WebSocketRequester) Send(envs map[string]interface{}) (res *types.ScenarioStepResult) {
+	var requestErr types.RequestError
+	var statusCode int
+	var respHeaders http.Header
+	var messages [][]byte
+	var latencies []time.Duration
+	var bytesReceived int64
+	var extractedVars = make(map[string]interface{})
+	var failedCaptures = make(map[string]string, 0)
+	var failedAssertions = make([]types.FailedAssertion, 0)
+
+	var usableVars = make(map[string]interface{}, len(envs))
+	for k, v := range envs {
+		usableVars[k] = v
+	}
+
+	res = &types.ScenarioStepResult{
+		StepID:    w.packet.ID,
+		StepName:  w.packet.Name,
+		RequestID: uuid.New(),
+	}
+
+	target, header, payload, err := w.prepareReq(usableVars)
+	if err != nil {
+		res.Err = types.RequestError{
+			Type:   types.ErrorInvalidRequest,
+			Reason: fmt.Sprintf("Could not prepare req, %s", err.Error()),
+		}
+		return res
+	}
+	res.Url = target
+	res.Method = http.MethodGet
+	res.ReqHeaders = header
+	res.ReqBody = []byte(payload)
+
+	reqStartTime := time.Now()
+
+	conn := w.pool.Get()
+	if conn == nil {
+		var httpRes *http.Response
+		conn, httpRes, err = w.dialer.DialContext(w.ctx, target, header)
+		if httpRes != nil {
+			statusCode = httpRes.StatusCode
+			respHeaders = httpRes.Header
+		}
+	} else {
+		// reused connection, handshake is already done
+		statusCode = http.StatusSwitchingProtocols
+	}
+
+	if err != nil {
+		requestErr = fetchWSErrType(w.ctx, err)
+	} else {
+		messages, latencies, bytesReceived, err = w.exchange(conn, payload)
+		if err == nil {
+			w.pool.Put(conn)
+		} else {
+			// gorilla connections are not reusable after a read error, including the deadline
+			w.pool.Close(conn)
+			if !isTimeout(err) || w.ctx.Err() != nil {
+				requestErr = fetchWSErrType(w.ctx, err)
+			} else if w.packet.WebSocket.ReadDuration == 0 {
+				// timeout is expected only when reading for a duration
+				requestErr = types.RequestError{Type: types.ErrorTimeout, Reason: types.ReasonReadTimeout}
+			}
+		}
+	}
+	dur := time.Since(reqStartTime)
+
+	var respBody []byte
+	if len(messages) > 0 {
+		// captures and assertions are applied to the last message
+		respBody = messages[len(messages)-1]
+	}
+
+	if requestErr.Type == "" {
+		if len(w.packet.EnvsToCapture) > 0 {
+			failedCaptures = captureEnvironmentVariables(w.packet.EnvsToCapture, respHeaders, respBody, nil, nil, extractedVars)
+			requestErr = fatalCaptureError(w.packet.EnvsToCapture, failedCaptures)
+		}
+
+		if len(w.packet.Assertions) > 0 {
+			_, failedAssertions = applyAssertions(w.packet.Assertions, &evaluator.AssertEnv{
+				StatusCode:   int64(statusCode),
+				ResponseSize: bytesReceived,
+				ResponseTime: dur.Milliseconds(), // in ms
+				Body:         string(respBody),
+				Headers:      respHeaders,
+				Variables:    concatEnvs(envs, extractedVars),
+			})
+		}
+	} else {
+		failedCaptures = captureEnvironmentVariables(w.packet.EnvsToCapture, nil, nil, nil, nil, extractedVars)
+	}
+
+	var avgLatency time.Duration
+	for _, l := range latencies {
+		avgLatency += l
+	}
+	if len(latencies) > 0 {
+		avgLatency /= time.Duration(len(latencies))
+	}
+
+	res.StatusCode = statusCode
+	res.RequestTime = reqStartTime
+	res.Duration = dur
+	res.ContentLength = bytesReceived
+	res.Err = requestErr
+	res.RespHeaders = respHeaders
+	res.RespBody = respBody
+	res.Custom = map[string]interface{}{
+		"wsMessageCount":     len(messages),
+		"wsBytesReceived":    bytesReceived,
+		"wsMessageLatencies": latencies,
+		"wsAvgLatency":       avgLatency,
+	}
+	res.ExtractedEnvs = extractedVars
+	res.UsableEnvs = usableVars
+	res.FailedCaptures = failedCaptures
+	res.FailedAssertions = failedAssertions
+
+	return res
+}
+
+// exchange sends the payload as the initial frame and reads the messages according to the types.WebSocketConf.
+// Latency of a message is the duration since the previous message, or since the initial frame for the first one.
+func (w *WebSocketRequester) exchange(conn *websocket.Conn, payload string) (messages [][]byte,
+	latencies []time.Duration, bytesReceived int64, err error) {
+	conf := w.packet.WebSocket
+	deadline := time.Now().Add(w.packet.TimeoutDuration())
+	if conf.ReadDuration > 0 {
+		deadline = time.Now().Add(time.Duration(conf.ReadDuration) * time.Millisecond)
+	}
+
+	// unblock the reads if the engine is stopped
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-w.ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	if payload != "" {
+		conn.SetWriteDeadline(deadline)
+		if err = conn.WriteMessage(websocket.TextMessage, []byte(payload)); err != nil {
+			return
+		}
+	}
+
+	count := conf.MessageCount
+	if count == 0 && conf.ReadDuration == 0 {
+		count = 1
+	}
+
+	conn.SetReadDeadline(deadline)
+	last := time.Now()
+	for count == 0 || len(messages) < count {
+		var msg []byte
+		_, msg, err = conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		now := time.Now()
+		latencies = append(latencies, now.Sub(last))
+		last = now
+		bytesReceived += int64(len(msg))
+		if w.debug || len(w.packet.EnvsToCapture) > 0 || len(w.packet.Assertions) > 0 {
+			messages = append(messages, msg)
+		} else {
+			messages = append(messages, nil)
+		}
+	}
+	return
+}
+
+// prepareReq injects the dynamic and environment variables into the url, the headers and the payload.
+func (w *WebSocketRequester) prepareReq(envs map[string]interface{}) (string, http.Header, string, error) {
+	target, err := w.inject(w.packet.URL, envs)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	payload, err := w.inject(w.packet.Payload, envs)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	header := make(http.Header)
+	for k, v := range w.packet.Headers {
+		kk, err := w.inject(k, envs)
+		if err != nil {
+			return "", nil, "", err
+		}
+		vv, err := w.inject(v, envs)
+		if err != nil {
+			return "", nil, "", err
+		}
+		header.Set(kk, vv)
+	}
+	if w.packet.Auth.Type == types.AuthBearer {
+		token, err := w.inject(w.packet.Auth.Token, envs)
+		if err != nil {
+			return "", nil, "", err
+		}
+		header.Set("Authorization", "Bearer "+token)
+	} else if w.packet.Auth != (types.Auth{}) {
+		r := &http.Request{Header: header}
+		r.SetBasicAuth(w.packet.Auth.Username, w.packet.Auth.Password)
+	}
+	return target, header, payload, nil
+}
+
+func (w *WebSocketRequester) inject(s string, envs map[string]interface{}) (string, error) {
+	var err error
+	if w.dynamicRgx.MatchString(s) {
+		s, err = w.ei.Of(envs).InjectDynamic(s)
+		if err != nil {
+			return "", err
+		}
+	}
+	if w.envRgx.MatchString(s) {
+		s, err = w.ei.InjectEnv(s, envs)
+		if err != nil {
+			return "", err
+		}
+	}
+	return s, nil
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func fetchWSErrType(ctx context.Context, err error) types.RequestError {
+	if ctx.Err() != nil {
+		return types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled}
+	}
+	if errors.Is(err, websocket.ErrBadHandshake) {
+		return types.RequestError{Type: types.ErrorConn, Reason: err.Error()}
+	}
+	return fetchErrType(err)
+}
+
+// Done closes the pooled connections gracefully, see closeWSConn.
+func (w *WebSocketRequester) Done() {
+	if w.pool != nil {
+		w.pool.Done()
+	}
+}
+
+func (w *WebSocketRequester) Type() string {
+	return "WEBSOCKET"
+}
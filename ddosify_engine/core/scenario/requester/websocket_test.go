@@ -0,0 +1,163 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package requester
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func newTickServer(t *testing.T, gotClose chan<- int) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(rw, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.SetCloseHandler(func(code int, text string) error {
+			gotClose <- code
+			return c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+		})
+
+		for {
+			_, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			for i := 0; i < 3; i++ {
+				c.WriteMessage(websocket.TextMessage, []byte(`{"symbol":"`+string(msg)+`","price":`+string(rune('1'+i))+`}`))
+			}
+		}
+	}))
+}
+
+func TestWebSocketRequesterSend(t *testing.T) {
+	t.Parallel()
+
+	gotClose := make(chan int, 1)
+	srv := newTickServer(t, gotClose)
+	defer srv.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	symbolPath := "symbol"
+	s := types.ScenarioStep{
+		ID:            1,
+		Type:          types.StepTypeWebSocket,
+		URL:           "ws" + strings.TrimPrefix(srv.URL, "http"),
+		Payload:       "{{SYMBOL}}",
+		Timeout:       types.DefaultTimeout,
+		WebSocket:     types.WebSocketConf{MessageCount: 3},
+		Assertions:    []string{"equals(json_path(\"price\"), 3)"},
+		EnvsToCapture: []types.EnvCaptureConf{{Name: "SYM", From: types.Body, JsonPath: &symbolPath}},
+	}
+
+	w := &WebSocketRequester{}
+	if err := w.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		res := w.Send(map[string]interface{}{"SYMBOL": "DDSFY"})
+		if res.Err.Type != "" {
+			t.Fatalf("Expected no error, Found: %v", res.Err)
+		}
+		if res.StatusCode != http.StatusSwitchingProtocols {
+			t.Errorf("Expected %v, Found: %v", http.StatusSwitchingProtocols, res.StatusCode)
+		}
+		if res.Custom["wsMessageCount"] != 3 {
+			t.Errorf("Expected %v, Found: %v", 3, res.Custom["wsMessageCount"])
+		}
+		if len(res.Custom["wsMessageLatencies"].([]time.Duration)) != 3 {
+			t.Errorf("Expected 3 latencies, Found: %v", res.Custom["wsMessageLatencies"])
+		}
+		if res.ContentLength == 0 {
+			t.Errorf("Expected received bytes to be reported")
+		}
+		if len(res.FailedAssertions) != 0 {
+			t.Errorf("Expected no failed assertion, Found: %v", res.FailedAssertions)
+		}
+		if res.ExtractedEnvs["SYM"] != "DDSFY" {
+			t.Errorf("Expected %v, Found: %v", "DDSFY", res.ExtractedEnvs["SYM"])
+		}
+	}
+
+	// connection is reused in the second send, only one connection is closed
+	if w.pool.Len() != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, w.pool.Len())
+	}
+
+	w.Done()
+	select {
+	case code := <-gotClose:
+		if code != websocket.CloseNormalClosure {
+			t.Errorf("Expected %v, Found: %v", websocket.CloseNormalClosure, code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Expected close frame to be sent in Done")
+	}
+}
+
+func TestWebSocketRequesterReadDuration(t *testing.T) {
+	t.Parallel()
+
+	srv := newTickServer(t, make(chan int, 1))
+	defer srv.Close()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+
+	s := types.ScenarioStep{
+		ID:        1,
+		Type:      types.StepTypeWebSocket,
+		URL:       "ws" + strings.TrimPrefix(srv.URL, "http"),
+		Payload:   "DDSFY",
+		Timeout:   types.DefaultTimeout,
+		WebSocket: types.WebSocketConf{ReadDuration: 200},
+	}
+
+	w := &WebSocketRequester{}
+	if err := w.Init(context.Background(), s, nil, false, ei); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	defer w.Done()
+
+	res := w.Send(map[string]interface{}{})
+	if res.Err.Type != "" {
+		t.Fatalf("Expected no error, Found: %v", res.Err)
+	}
+	if res.Custom["wsMessageCount"] != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, res.Custom["wsMessageCount"])
+	}
+	if res.Duration < 200*time.Millisecond {
+		t.Errorf("Expected to read at least 200ms, Found: %v", res.Duration)
+	}
+}
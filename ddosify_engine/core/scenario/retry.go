@@ -0,0 +1,115 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+// Status codes that trigger a retry if the retry policy has no status codes and error types configured.
+var defaultRetryStatusCodes = []int{502, 503, 504}
+
+// retryPolicy sends a step again while its result is retryable, waiting for the backoff duration in between.
+type retryPolicy struct {
+	conf types.RetryConf
+}
+
+// newRetryPolicy returns nil if the retry is not enabled for the step.
+func newRetryPolicy(conf types.RetryConf) *retryPolicy {
+	if !conf.Enabled() {
+		return nil
+	}
+	return &retryPolicy{conf: conf}
+}
+
+// do calls send until its result is not retryable or the max attempts are reached, jitter is drawn from rnd.
+// Returns the result of the last attempt. Stops retrying if the ctx is done.
+func (rp *retryPolicy) do(ctx context.Context, rnd *rand.Rand,
+	send func() *types.ScenarioStepResult) *types.ScenarioStepResult {
+	res := send()
+	for retry := 1; retry < rp.conf.MaxAttempts && rp.shouldRetry(res); retry++ {
+		if !sleepContext(ctx, rp.backoff(rnd, retry)) {
+			break
+		}
+		next := send()
+		if next.Err.Reason == types.ReasonMaxRequests {
+			// the retry is not sent, the result of the last attempt is kept
+			break
+		}
+		res = next
+		res.Retries = retry
+	}
+	return res
+}
+
+func (rp *retryPolicy) shouldRetry(res *types.ScenarioStepResult) bool {
+	if res.Err.Type != "" {
+		if res.Err.Type == types.ErrorIntented || res.Err.Type == types.ErrorInvalidRequest ||
+			strings.Contains(res.Err.Reason, types.ReasonCtxCanceled) {
+			return false
+		}
+		if len(rp.conf.Errors) > 0 {
+			return util.StringInSlice(res.Err.Type, rp.conf.Errors)
+		}
+		return len(rp.conf.StatusCodes) == 0
+	}
+
+	codes := rp.conf.StatusCodes
+	if len(codes) == 0 && len(rp.conf.Errors) == 0 {
+		codes = defaultRetryStatusCodes
+	}
+	for _, c := range codes {
+		if c == res.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait duration before the given retry, starting from 1.
+func (rp *retryPolicy) backoff(rnd *rand.Rand, retry int) time.Duration {
+	d := time.Duration(rp.conf.Delay) * time.Millisecond
+	maxDelay := time.Duration(rp.conf.MaxDelay) * time.Millisecond
+
+	if rp.conf.Backoff == types.RetryBackoffExponential {
+		for i := 1; i < retry && d > 0; i++ {
+			d *= 2
+			if maxDelay > 0 && d >= maxDelay {
+				break
+			}
+		}
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+
+	if rp.conf.Jitter && d > 1 {
+		half := d / 2
+		d = half + time.Duration(rnd.Int63n(int64(d-half)+1))
+	}
+	return d
+}
@@ -0,0 +1,153 @@
+package scenario
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		conf     types.RetryConf
+		res      types.ScenarioStepResult
+		expected bool
+	}{
+		{"Default5xx", types.RetryConf{MaxAttempts: 2}, types.ScenarioStepResult{StatusCode: 503}, true},
+		{"DefaultSuccess", types.RetryConf{MaxAttempts: 2}, types.ScenarioStepResult{StatusCode: 200}, false},
+		{"DefaultNotListed5xx", types.RetryConf{MaxAttempts: 2}, types.ScenarioStepResult{StatusCode: 500}, false},
+		{"DefaultConnError", types.RetryConf{MaxAttempts: 2},
+			types.ScenarioStepResult{Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonConnTimeout}}, true},
+		{"InvalidRequest", types.RetryConf{MaxAttempts: 2},
+			types.ScenarioStepResult{Err: types.RequestError{Type: types.ErrorInvalidRequest}}, false},
+		{"Canceled", types.RetryConf{MaxAttempts: 2},
+			types.ScenarioStepResult{Err: types.RequestError{Type: types.ErrorConn, Reason: types.ReasonCtxCanceled}}, false},
+		{"StatusCodes", types.RetryConf{MaxAttempts: 2, StatusCodes: []int{429}},
+			types.ScenarioStepResult{StatusCode: 429}, true},
+		{"StatusCodesNotListed", types.RetryConf{MaxAttempts: 2, StatusCodes: []int{429}},
+			types.ScenarioStepResult{StatusCode: 503}, false},
+		{"StatusCodesOnlyNoErr", types.RetryConf{MaxAttempts: 2, StatusCodes: []int{429}},
+			types.ScenarioStepResult{Err: types.RequestError{Type: types.ErrorConn}}, false},
+		{"Errors", types.RetryConf{MaxAttempts: 2, Errors: []string{types.ErrorDns}},
+			types.ScenarioStepResult{Err: types.RequestError{Type: types.ErrorDns}}, true},
+		{"ErrorsNotListed", types.RetryConf{MaxAttempts: 2, Errors: []string{types.ErrorDns}},
+			types.ScenarioStepResult{Err: types.RequestError{Type: types.ErrorConn}}, false},
+		{"ErrorsOnlyNo5xx", types.RetryConf{MaxAttempts: 2, Errors: []string{types.ErrorDns}},
+			types.ScenarioStepResult{StatusCode: 503}, false},
+	}
+
+	for _, tc := range tests {
+		rp := newRetryPolicy(tc.conf)
+		if got := rp.shouldRetry(&tc.res); got != tc.expected {
+			t.Errorf("%s: Expected %v, Found: %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	fixed := newRetryPolicy(types.RetryConf{MaxAttempts: 5, Delay: 100})
+	exp := newRetryPolicy(types.RetryConf{MaxAttempts: 5, Delay: 100, Backoff: types.RetryBackoffExponential, MaxDelay: 300})
+
+	expectedFixed := []time.Duration{100, 100, 100}
+	expectedExp := []time.Duration{100, 200, 300, 300}
+	for i, e := range expectedFixed {
+		if got := fixed.backoff(nil, i+1); got != e*time.Millisecond {
+			t.Errorf("Fixed retry %d, Expected %v, Found: %v", i+1, e*time.Millisecond, got)
+		}
+	}
+	for i, e := range expectedExp {
+		if got := exp.backoff(nil, i+1); got != e*time.Millisecond {
+			t.Errorf("Exponential retry %d, Expected %v, Found: %v", i+1, e*time.Millisecond, got)
+		}
+	}
+
+	jitter := newRetryPolicy(types.RetryConf{MaxAttempts: 5, Delay: 100, Jitter: true})
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		if got := jitter.backoff(rnd, 1); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Errorf("Jitter, Expected between 50ms and 100ms, Found: %v", got)
+		}
+	}
+
+	if newRetryPolicy(types.RetryConf{MaxAttempts: 1}) != nil {
+		t.Errorf("Expected nil policy for a single attempt")
+	}
+}
+
+func TestRetryPolicyStopsOnContextDone(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rp := newRetryPolicy(types.RetryConf{MaxAttempts: 3, Delay: 10000})
+
+	calls := 0
+	start := time.Now()
+	res := rp.do(ctx, nil, func() *types.ScenarioStepResult {
+		calls++
+		cancel()
+		return &types.ScenarioStepResult{StatusCode: 503}
+	})
+
+	if calls != 1 || res.Retries != 0 {
+		t.Errorf("Expected 1 call without retries, Found: %d calls, %d retries", calls, res.Retries)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected backoff to be interrupted, Found: %v", elapsed)
+	}
+}
+
+func TestDoRetriesFailedStep(t *testing.T) {
+	t.Parallel()
+
+	var callCount int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&callCount, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+	host := httptest.NewServer(http.HandlerFunc(handler))
+	defer host.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{
+				ID:      1,
+				Method:  http.MethodGet,
+				URL:     host.URL,
+				Timeout: types.DefaultTimeout,
+				Retry:   types.RetryConf{MaxAttempts: 3, Delay: 10},
+			},
+		},
+	}
+
+	service := NewScenarioService()
+	err := service.Init(context.TODO(), scenario, []*url.URL{nil}, ScenarioOpts{})
+	if err != nil {
+		t.Fatalf("TestDoRetriesFailedStep init error: %v", err)
+	}
+	defer service.Done()
+
+	response, rErr := service.Do(nil, time.Now())
+	if rErr != nil {
+		t.Fatalf("TestDoRetriesFailedStep errored: %v", rErr)
+	}
+
+	res := response.StepResults[0]
+	if res.StatusCode != http.StatusOK || res.Retries != 2 {
+		t.Errorf("Expected status %d after 2 retries, Found: %d after %d retries", http.StatusOK, res.StatusCode, res.Retries)
+	}
+	if c := atomic.LoadInt32(&callCount); c != 3 {
+		t.Errorf("Expected %d, Found: %d", 3, c)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"regexp"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types/regex"
+)
+
+// globalScope holds the envs of the scenario. It is set once when the service is initialized and shared read only
+// by all the iterations. Values with dynamic variables, like {{_randomCity}}, get a new value in each iteration.
+type globalScope struct {
+	vars map[string]interface{}
+
+	// keys of the values with dynamic variables, injected in the scope of each iteration
+	dynamic []string
+}
+
+func newGlobalScope(envs map[string]interface{}) *globalScope {
+	dynamicRgx := regexp.MustCompile(regex.DynamicVariableRegex)
+	g := &globalScope{vars: make(map[string]interface{}, len(envs))}
+	for k, v := range envs {
+		g.vars[k] = v
+		if s, ok := v.(string); ok && dynamicRgx.MatchString(s) {
+			g.dynamic = append(g.dynamic, k)
+		}
+	}
+	return g
+}
+
+// newIterationScope creates the empty scope of an iteration, with the dynamic globals injected for it.
+func (g *globalScope) newIterationScope(ei *injection.EnvironmentInjector) *iterationScope {
+	vars := make(map[string]interface{}, len(g.dynamic))
+	for _, k := range g.dynamic {
+		vars[k] = g.vars[k]
+	}
+	injectDynamicVars(ei, vars)
+	return &iterationScope{globals: g, vars: vars, ei: ei}
+}
+
+// iterationScope holds the variables of a single iteration: the injected dynamic globals, the test data row and the
+// captures of its steps. It starts fresh for each iteration and is used by a single goroutine, so concurrent
+// iterations never see each other's captures. Lookups fall back to the globals.
+type iterationScope struct {
+	globals *globalScope
+	vars    map[string]interface{}
+
+	// injector of the random values of the iteration, passed to the requesters in the envs
+	ei *injection.EnvironmentInjector
+
+	// envs view of the scope, rebuilt after the scope is changed
+	view map[string]interface{}
+}
+
+// get returns the variable of the iteration if it is set, the global one otherwise.
+func (s *iterationScope) get(key string) (interface{}, bool) {
+	if v, ok := s.vars[key]; ok {
+		return v, true
+	}
+	v, ok := s.globals.vars[key]
+	return v, ok
+}
+
+// set sets the variable in the iteration scope, it shadows the global variable with the same key.
+func (s *iterationScope) set(key string, v interface{}) {
+	s.vars[key] = v
+	s.view = nil
+}
+
+// setAll sets all the given variables, like the captures of a step.
+func (s *iterationScope) setAll(vars map[string]interface{}) {
+	for k, v := range vars {
+		s.set(k, v)
+	}
+}
+
+// envs returns the variables visible to the iteration, passed to the requesters and the conditions of the steps.
+// It shouldn't be modified, the scope is changed only by set.
+func (s *iterationScope) envs() map[string]interface{} {
+	if s.view != nil {
+		return s.view
+	}
+	s.view = make(map[string]interface{}, len(s.globals.vars)+len(s.vars)+1)
+	for k, v := range s.globals.vars {
+		s.view[k] = v
+	}
+	for k, v := range s.vars {
+		s.view[k] = v
+	}
+	s.view[injection.InjectorEnv] = s.ei
+	return s.view
+}
@@ -0,0 +1,137 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestIterationScope(t *testing.T) {
+	t.Parallel()
+
+	ei := &injection.EnvironmentInjector{}
+	ei.Init()
+	envs := map[string]interface{}{"HOST": "test.com", "CITY": "{{_randomCity}}", "NUMBERS": []interface{}{1, 2}}
+	g := newGlobalScope(envs)
+	envs["HOST"] = "changed" // globals are copied at the start
+
+	s1, s2 := g.newIterationScope(ei), g.newIterationScope(ei)
+	if v, _ := s1.get("HOST"); v != "test.com" {
+		t.Errorf("Expected %v, Found: %v", "test.com", v)
+	}
+	if v, _ := s1.get("CITY"); v == "{{_randomCity}}" {
+		t.Errorf("Expected the dynamic global to be injected, Found: %v", v)
+	}
+
+	// iteration variables shadow the globals, only in their own scope
+	s1.setAll(map[string]interface{}{"HOST": "captured.com", "TOKEN": "abc"})
+	if v, _ := s1.get("HOST"); v != "captured.com" {
+		t.Errorf("Expected %v, Found: %v", "captured.com", v)
+	}
+	if v, _ := s2.get("HOST"); v != "test.com" {
+		t.Errorf("Expected %v, Found: %v", "test.com", v)
+	}
+	if _, ok := s2.get("TOKEN"); ok {
+		t.Errorf("Expected the capture of the other iteration not to be found")
+	}
+	if v := g.vars["HOST"]; v != "test.com" {
+		t.Errorf("Expected the globals not to change, Found: %v", v)
+	}
+
+	// view is rebuilt after the scope is changed, the injector of the iteration is passed in it
+	view := s1.envs()
+	if view["HOST"] != "captured.com" || view["TOKEN"] != "abc" || view[injection.InjectorEnv] != ei || len(view) != 5 {
+		t.Errorf("Expected %v, Found: %v", "view with the captures", view)
+	}
+	s1.set("TOKEN", "def")
+	if s1.envs()["TOKEN"] != "def" {
+		t.Errorf("Expected %v, Found: %v", "def", s1.envs()["TOKEN"])
+	}
+}
+
+func TestDoIsolatesIterationCaptures(t *testing.T) {
+	t.Parallel()
+
+	// login issues a token of the user, profile accepts only the token of the same user
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.URL.Query().Get("user")
+		switch r.URL.Path {
+		case "/login":
+			time.Sleep(time.Duration(len(user)%5) * time.Millisecond) // interleave the iterations
+			json.NewEncoder(w).Encode(map[string]string{"token": "token-" + user})
+		case "/profile":
+			if r.Header.Get("X-Token") != "token-"+user {
+				w.WriteHeader(http.StatusForbidden)
+			}
+		}
+	}))
+	defer server.Close()
+
+	tokenPath := "token"
+	scenario := types.Scenario{
+		Envs: map[string]interface{}{"USER": "{{_randomUUID}}"},
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "/login?user={{USER}}", Timeout: types.DefaultTimeout,
+				EnvsToCapture: []types.EnvCaptureConf{{Name: "TOKEN", From: types.Body, JsonPath: &tokenPath}}},
+			{ID: 2, Method: http.MethodGet, URL: server.URL + "/profile?user={{USER}}", Timeout: types.DefaultTimeout,
+				Headers: map[string]string{"X-Token": "{{TOKEN}}"}},
+		},
+	}
+	iterations := 50
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode:             types.EngineModeDdosify,
+		IterationCount:         iterations,
+		MaxConcurrentIterCount: iterations,
+	}); err != nil {
+		t.Fatalf("TestDoIsolatesIterationCaptures init error: %v", err)
+	}
+	defer service.Done()
+
+	var wg sync.WaitGroup
+	results := make(chan *types.ScenarioResult, iterations)
+	for i := 0; i < iterations; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, _ := service.Do(nil, time.Now())
+			results <- res
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	for res := range results {
+		if len(res.StepResults) != 2 || res.StepResults[1].StatusCode != http.StatusOK {
+			t.Fatalf("Expected the token of the same iteration to be sent, Found: %v", res.StepResults)
+		}
+	}
+}
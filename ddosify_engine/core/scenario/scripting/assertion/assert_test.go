@@ -13,6 +13,8 @@ func TestAssert(t *testing.T) {
 	testHeader := http.Header{}
 	testHeader.Add("Content-Type", "application/json")
 	testHeader.Add("content-length", "222")
+	testTrailer := http.Header{}
+	testTrailer.Add("Grpc-Status", "0")
 
 	tests := []struct {
 		input         string
@@ -138,6 +140,23 @@ func TestAssert(t *testing.T) {
 			expected:      false,
 			expectedError: "NotFoundError",
 		},
+		{
+			input: `trailers.grpc-status == "0"`,
+			envs: &evaluator.AssertEnv{
+				Headers:  testHeader,
+				Trailers: testTrailer,
+			},
+			expected: true,
+		},
+		{
+			input: `exists(trailers.Content-Type)`,
+			envs: &evaluator.AssertEnv{
+				Headers:  testHeader,
+				Trailers: testTrailer,
+			},
+			expected:      false,
+			expectedError: "NotFoundError",
+		},
 		{
 			input: `contains(body,"xyz")`,
 			envs: &evaluator.AssertEnv{
@@ -395,6 +414,48 @@ func TestAssert(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			input: "status_code >= 200 && status_code <= 299",
+			envs: &evaluator.AssertEnv{
+				StatusCode: 299,
+			},
+			expected: true,
+		},
+		{
+			input: "response_time <= 500",
+			envs: &evaluator.AssertEnv{
+				ResponseTime: 501,
+			},
+			expected: false,
+		},
+		{
+			input: "json_path(\"$.data.id\") != \"\"",
+			envs: &evaluator.AssertEnv{
+				Body: "{\"data\":{\"id\":\"abc\"}}",
+			},
+			expected: true,
+		},
+		{
+			input: "json_path(\"$.data.items[1].name\") == \"second\"",
+			envs: &evaluator.AssertEnv{
+				Body: "{\"data\":{\"items\":[{\"name\":\"first\"},{\"name\":\"second\"}]}}",
+			},
+			expected: true,
+		},
+		{
+			input: "json_path(\"version\") >= \"v1.10\"", // lexicographical
+			envs: &evaluator.AssertEnv{
+				Body: "{\"version\":\"v1.9\"}",
+			},
+			expected: true,
+		},
+		{
+			input: "json_path(\"count\") != \"2\"", // json strings
+			envs: &evaluator.AssertEnv{
+				Body: "{\"count\":\"1\"}",
+			},
+			expected: true,
+		},
 		{
 			input: "status_code > variables.envFloatVal", // int float comparison
 			envs: &evaluator.AssertEnv{
@@ -749,6 +810,27 @@ func TestAssert(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			input: `header_rate("x-cache", "HIT") > 0.8`,
+			envs: &evaluator.AssertEnv{
+				HeaderValues: map[string]map[string]int64{"X-Cache": {"hit": 9, "miss": 1}},
+			},
+			expected: true,
+		},
+		{
+			input: `header_rate("X-Cache", "hit") > 0.95`,
+			envs: &evaluator.AssertEnv{
+				HeaderValues: map[string]map[string]int64{"X-Cache": {"hit": 9, "miss": 1}},
+			},
+			expected: false,
+		},
+		{
+			input: `header_rate("X-Cache", "hit") > 0.8`, // no response with the header
+			envs: &evaluator.AssertEnv{
+				HeaderValues: map[string]map[string]int64{"X-Cache": {}},
+			},
+			expected: false,
+		},
 		{
 			input:    "percentile([]) == 200.6875",
 			expected: false,
@@ -8,6 +8,7 @@ type AssertEnv struct {
 	ResponseTime int64 // in ms
 	Body         string
 	Headers      http.Header
+	Trailers     http.Header // read after the body
 	Variables    map[string]interface{}
 	Cookies      map[string]*http.Cookie // cookies sent by the server, name -> cookie
 
@@ -15,4 +16,8 @@ type AssertEnv struct {
 	TotalTime     []int64 // in ms
 	FailCount     int
 	FailCountPerc float64 // should be in range [0,1]
+
+	// Response counts of the values of the headers referenced by header_rate, canonical header name -> value -> count.
+	// Values are lowercased.
+	HeaderValues map[string]map[string]int64
 }
@@ -273,6 +273,22 @@ func Eval(node ast.Node, env *AssertEnv, receivedMap map[string]interface{}) (in
 						}
 					}
 					return percentile(arr, 80)
+				case HEADERRATE:
+					header, ok := args[0].(string)
+					if !ok {
+						return false, ArgumentError{
+							msg:        "header of header_rate must be a string",
+							wrappedErr: nil,
+						}
+					}
+					value, ok := args[1].(string)
+					if !ok {
+						return false, ArgumentError{
+							msg:        "value of header_rate must be a string",
+							wrappedErr: nil,
+						}
+					}
+					return headerRate(env.HeaderValues, header, value)
 				case RANGE:
 					var x, low, high float64
 
@@ -365,7 +381,7 @@ func evalInfixExpression(
 		return evalIntegerInfixExpression(operator, int64(left.(int)), right.(int64))
 	}
 	if leftType == reflect.Int && rightType == reflect.Int {
-		return evalIntegerInfixExpression(operator, int64(left.(int)), int64(left.(int)))
+		return evalIntegerInfixExpression(operator, int64(left.(int)), int64(right.(int)))
 	}
 
 	// int - float, convert int64 to float64, data loss for big int64 numbers
@@ -409,8 +425,12 @@ func evalInfixExpression(
 		isLJson := json.Unmarshal([]byte(left.(string)), &lJson)
 		isRJson := json.Unmarshal([]byte(right.(string)), &rJson)
 
-		if isLJson == nil && isRJson == nil {
-			return reflect.DeepEqual(lJson, rJson), nil
+		if isLJson == nil && isRJson == nil && (operator == "==" || operator == "!=") {
+			eq := reflect.DeepEqual(lJson, rJson)
+			if operator == "!=" {
+				return !eq, nil
+			}
+			return eq, nil
 		}
 	}
 
@@ -435,6 +455,20 @@ func evalInfixExpression(
 		}
 	}
 
+	if leftType == reflect.String && rightType == reflect.String {
+		// lexicographical comparison for non-json strings
+		switch operator {
+		case "<":
+			return left.(string) < right.(string), nil
+		case ">":
+			return left.(string) > right.(string), nil
+		case "<=":
+			return left.(string) <= right.(string), nil
+		case ">=":
+			return left.(string) >= right.(string), nil
+		}
+	}
+
 	if operator == "==" {
 		return reflect.DeepEqual(left, right), nil
 	}
@@ -520,6 +554,10 @@ func evalFloatInfixExpression(operator string,
 		return left < right, nil
 	case ">":
 		return left > right, nil
+	case "<=":
+		return left <= right, nil
+	case ">=":
+		return left >= right, nil
 	case "==":
 		return left == right, nil
 	case "!=":
@@ -542,6 +580,10 @@ func evalTimeInfixExpression(operator string, lTime, rTime time.Time) (interface
 		return lTime.Before(rTime), nil
 	case ">":
 		return lTime.After(rTime), nil
+	case "<=":
+		return !lTime.After(rTime), nil
+	case ">=":
+		return !lTime.Before(rTime), nil
 	default:
 		return 0, OperatorError{
 			msg:        fmt.Sprintf("unknown operator %s for time.Time", operator),
@@ -568,6 +610,10 @@ func evalIntegerInfixExpression(
 		return left < right, nil
 	case ">":
 		return left > right, nil
+	case "<=":
+		return left <= right, nil
+	case ">=":
+		return left >= right, nil
 	case "==":
 		return left == right, nil
 	case "!=":
@@ -640,6 +686,18 @@ func evalIdentifier(
 			wrappedErr: nil,
 		}
 	}
+	if strings.HasPrefix(ident, "trailers.") {
+		vr := strings.TrimPrefix(ident, "trailers.")
+		tv := env.Trailers.Get(vr)
+		if tv != "" {
+			receivedMap[ident] = tv
+			return tv, nil
+		}
+		return "", NotFoundError{
+			source:     fmt.Sprintf("trailer not found %s", vr),
+			wrappedErr: nil,
+		}
+	}
 	if strings.HasPrefix(ident, "cookies.") {
 		// cookies.cookie_name.field_name
 		// cookies.csrftoken.expires
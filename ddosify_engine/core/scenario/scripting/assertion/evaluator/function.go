@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"reflect"
 	"strings"
@@ -190,6 +191,20 @@ var equalsOnFile = func(source interface{}, filepath string) (bool, error) {
 	return false, nil
 }
 
+// headerRate returns the rate of the responses having the header with the given value, in range [0,1].
+// Responses without the header are not counted.
+var headerRate = func(values map[string]map[string]int64, header string, value string) (float64, error) {
+	counts := values[http.CanonicalHeaderKey(header)]
+	var total int64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("no response with the %s header on header_rate func", header)
+	}
+	return float64(counts[strings.ToLower(value)]) / float64(total), nil
+}
+
 var assertionFuncMap = map[string]struct{}{
 	NOT:          {},
 	LESSTHAN:     {},
@@ -213,6 +228,7 @@ var assertionFuncMap = map[string]struct{}{
 	P90:          {},
 	P80:          {},
 	TIME:         {},
+	HEADERRATE:   {},
 }
 
 const (
@@ -239,4 +255,6 @@ const (
 	P95 = "p95"
 	P90 = "p90"
 	P80 = "p80"
+
+	HEADERRATE = "header_rate"
 )
@@ -144,6 +144,20 @@ func TestNextToken(t *testing.T) {
 				{token.EOF, ""},
 			},
 		},
+		{
+			input: "status_code >= 200 <= 299",
+			expected: []struct {
+				expectedType    token.TokenType
+				expectedLiteral string
+			}{
+				{token.IDENT, "status_code"},
+				{token.GT_EQ, ">="},
+				{token.INT, "200"},
+				{token.LT_EQ, "<="},
+				{token.INT, "299"},
+				{token.EOF, ""},
+			},
+		},
 		{
 			input: "response_size == 234",
 			expected: []struct {
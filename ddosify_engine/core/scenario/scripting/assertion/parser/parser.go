@@ -15,7 +15,7 @@ const (
 	LOWEST
 	ANDOR        // && ||
 	EQUALS       // ==
-	LESSGREATER  // > or <
+	LESSGREATER  // > or < or >= or <=
 	SUM          // +
 	PRODUCT      // *
 	PREFIX       // -X or !X
@@ -29,6 +29,8 @@ var precedences = map[token.TokenType]int{
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
@@ -85,6 +87,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.infixParseFns[token.NOT_EQ] = p.parseInfixExpression
 	p.infixParseFns[token.LT] = p.parseInfixExpression
 	p.infixParseFns[token.GT] = p.parseInfixExpression
+	p.infixParseFns[token.LT_EQ] = p.parseInfixExpression
+	p.infixParseFns[token.GT_EQ] = p.parseInfixExpression
 	p.infixParseFns[token.AND] = p.parseInfixExpression
 	p.infixParseFns[token.OR] = p.parseInfixExpression
 	p.infixParseFns[token.LPAREN] = p.parseCallExpression
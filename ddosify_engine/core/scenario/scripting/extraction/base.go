@@ -42,6 +42,18 @@ func Extract(source interface{}, ce types.EnvCaptureConf) (val interface{}, err
 		} else {
 			err = fmt.Errorf("http header key not specified")
 		}
+	case types.Trailer:
+		trailer := source.(http.Header)
+		if ce.Key != nil {
+			val = trailer.Get(*ce.Key)
+			if val == "" {
+				err = fmt.Errorf("http trailer %s not found", *ce.Key)
+			} else if ce.RegExp != nil {
+				val, err = ExtractWithRegex(val, *ce.RegExp)
+			}
+		} else {
+			err = fmt.Errorf("http trailer key not specified")
+		}
 	case types.Body:
 		if ce.JsonPath != nil {
 			val, err = ExtractFromJson(source, *ce.JsonPath)
@@ -89,6 +101,29 @@ func ExtractWithRegex(source interface{}, regexConf types.RegexCaptureConf) (val
 	}
 }
 
+// ExtractRegexGroups returns the values of the named groups of the RegExp of the capture by their names, from the
+// header or the trailer of its key if the capture is from them.
+func ExtractRegexGroups(source interface{}, ce types.EnvCaptureConf) (map[string]string, error) {
+	if ce.RegExp == nil || ce.RegExp.Exp == nil {
+		return nil, fmt.Errorf("regexp not specified")
+	}
+	if (ce.From == types.Header || ce.From == types.Trailer) && ce.Key != nil {
+		if h, ok := source.(http.Header); ok {
+			source = h.Get(*ce.Key)
+		}
+	}
+	re := regexExtractor{}
+	re.Init(*ce.RegExp.Exp)
+	switch s := source.(type) {
+	case []byte: // from response body
+		return re.extractGroups(s, ce.RegExp.No)
+	case string: // from response header
+		return re.extractGroups([]byte(s), ce.RegExp.No)
+	default:
+		return nil, fmt.Errorf("Unsupported type for extraction source")
+	}
+}
+
 func ExtractFromJson(source interface{}, jsonPath string) (interface{}, error) {
 	je := jsonExtractor{}
 	switch s := source.(type) {
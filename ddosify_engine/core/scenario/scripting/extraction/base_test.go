@@ -26,6 +26,50 @@ func TestHttpHeaderKey_NotSpecified(t *testing.T) {
 	}
 }
 
+func TestHttpTrailer(t *testing.T) {
+	key := "grpc-status"
+	ce := types.EnvCaptureConf{
+		Name: "status",
+		From: types.Trailer,
+		Key:  &key,
+	}
+	trailer := http.Header{}
+	trailer.Set("Grpc-Status", "14")
+
+	val, err := Extract(trailer, ce)
+	if err != nil || val != "14" {
+		t.Errorf("Expected %v, Found: %v %v", "14", val, err)
+	}
+
+	if _, err = Extract(http.Header{}, ce); err == nil {
+		t.Errorf("Expected error when trailer not found")
+	}
+}
+
+func TestExtractRegexGroups(t *testing.T) {
+	key := "Location"
+	exp := "token=(?P<tok>[a-f0-9]+)"
+	ce := types.EnvCaptureConf{
+		Name:   "LOGIN",
+		From:   types.Header,
+		Key:    &key,
+		RegExp: &types.RegexCaptureConf{Exp: &exp},
+	}
+	header := http.Header{}
+	header.Set("Location", "/home?token=ab12")
+
+	groups, err := ExtractRegexGroups(header, ce)
+	if err != nil || groups["tok"] != "ab12" {
+		t.Errorf("Expected %v, Found: %v %v", "ab12", groups["tok"], err)
+	}
+
+	ce.From, ce.Key = types.Body, nil
+	groups, err = ExtractRegexGroups([]byte("<a href=\"/?token=cd34\">"), ce)
+	if err != nil || groups["tok"] != "cd34" {
+		t.Errorf("Expected %v, Found: %v %v", "cd34", groups["tok"], err)
+	}
+}
+
 func TestExtract_TypeAssertErrorRecover(t *testing.T) {
 	headerKey := "x"
 	ce := types.EnvCaptureConf{
@@ -3,6 +3,7 @@ package extraction
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/tidwall/gjson"
@@ -40,8 +41,25 @@ var unmarshalJsonCapture = func(result gjson.Result) (interface{}, error) {
 	return nil, fmt.Errorf("json could not be unmarshaled")
 }
 
+// normalizeJsonPath converts the common JSONPath notation to the gjson path syntax,
+// e.g. "$.data.items[0].id" -> "data.items.0.id". Paths already in gjson syntax are returned as is.
+func normalizeJsonPath(jsonPath string) string {
+	if !strings.HasPrefix(jsonPath, "$") {
+		return jsonPath
+	}
+	p := strings.TrimPrefix(jsonPath, "$")
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return "@this"
+	}
+	p = jsonPathIndexRgx.ReplaceAllString(p, ".$1")
+	return strings.TrimPrefix(p, ".")
+}
+
+var jsonPathIndexRgx = regexp.MustCompile(`\[(\d+)\]`)
+
 func (je jsonExtractor) extractFromString(source string, jsonPath string) (interface{}, error) {
-	result := gjson.Get(source, jsonPath)
+	result := gjson.Get(source, normalizeJsonPath(jsonPath))
 
 	// path not found
 	if result.Raw == "" && result.Type == gjson.Null {
@@ -71,7 +89,7 @@ func (je jsonExtractor) extractFromString(source string, jsonPath string) (inter
 }
 
 func (je jsonExtractor) extractFromByteSlice(source []byte, jsonPath string) (interface{}, error) {
-	result := gjson.GetBytes(source, jsonPath)
+	result := gjson.GetBytes(source, normalizeJsonPath(jsonPath))
 
 	// path not found
 	if result.Raw == "" && result.Type == gjson.Null {
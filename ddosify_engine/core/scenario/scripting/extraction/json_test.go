@@ -294,3 +294,20 @@ func TestJsonExtract_JsonPathNotFound(t *testing.T) {
 		t.Errorf("TestJsonExtract_JsonPathNotFound failed, expected %#v, found %#v", expected, val)
 	}
 }
+
+func TestNormalizeJsonPath(t *testing.T) {
+	tests := map[string]string{
+		"name.last":            "name.last",
+		"$.name.last":          "name.last",
+		"$.items[0].id":        "items.0.id",
+		"$[2].tags[10]":        "2.tags.10",
+		"$":                    "@this",
+		"items.#(id==1).title": "items.#(id==1).title",
+	}
+
+	for in, expected := range tests {
+		if got := normalizeJsonPath(in); got != expected {
+			t.Errorf("normalizeJsonPath(%s): expected %s, found %s", in, expected, got)
+		}
+	}
+}
@@ -38,3 +38,25 @@ func (ri *regexExtractor) extractFromByteSlice(text []byte, matchNo int) ([]byte
 	}
 	return matches[0], nil
 }
+
+// extractGroups returns the values of the named groups of the match like extractFromByteSlice, the groups not
+// taking part in the match are empty.
+func (ri *regexExtractor) extractGroups(text []byte, matchNo int) (map[string]string, error) {
+	matches := ri.r.FindAllSubmatch(text, -1)
+
+	if matches == nil {
+		return nil, fmt.Errorf("no match for the Regex: %s  Match no: %d", ri.r.String(), matchNo)
+	}
+
+	match := matches[0]
+	if len(matches) > matchNo {
+		match = matches[matchNo]
+	}
+	groups := make(map[string]string)
+	for i, name := range ri.r.SubexpNames() {
+		if name != "" {
+			groups[name] = string(match[i])
+		}
+	}
+	return groups, nil
+}
@@ -39,3 +39,26 @@ func TestRegexExtractFromStringNoMatch(t *testing.T) {
 	}
 
 }
+
+func TestRegexExtractGroups(t *testing.T) {
+	regex := "token=(?P<tok>[a-f0-9]+)(?:&id=(?P<id>[0-9]+))?"
+
+	re := regexExtractor{}
+	re.Init(regex)
+
+	source := []byte("token=ab12&id=7 token=cd34")
+
+	groups, err := re.extractGroups(source, 1)
+	if err != nil || groups["tok"] != "cd34" || groups["id"] != "" {
+		t.Errorf("RegexMatch should return the groups of the second match, Found: %v, %v", groups, err)
+	}
+
+	groups, err = re.extractGroups(source, 0)
+	if err != nil || groups["tok"] != "ab12" || groups["id"] != "7" {
+		t.Errorf("RegexMatch should return the groups of the first match, Found: %v, %v", groups, err)
+	}
+
+	if _, err := re.extractGroups([]byte("messialvarez"), 0); err == nil {
+		t.Errorf("Should be error %v", err)
+	}
+}
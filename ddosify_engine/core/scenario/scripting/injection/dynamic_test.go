@@ -7,9 +7,10 @@ import (
 func TestDynamicVariableRace(t *testing.T) {
 	num := 10
 	ei := EnvironmentInjector{}
+	ei.Init()
 	for key := range dynamicFakeDataMap {
 		for i := 0; i < num; i++ {
-			go ei.getFakeData(key)
+			go ei.getFakeData(key, nil)
 		}
 	}
 }
@@ -145,14 +145,54 @@ type EnvironmentInjector struct {
 	dr  *regexp.Regexp
 	jdr *regexp.Regexp
 	mu  sync.Mutex
+
+	// random values of the dynamic variables, template functions and rand() picks, guarded by mu. The dynamic
+	// variables of a derived injector are created on its first draw.
+	rnd      *rand.Rand
+	fakeData map[string]interface{}
 }
 
+// InjectorEnv is the key of the injector of an iteration in the envs passed to the requesters. It can't be
+// referenced by the variables of a scenario.
+const InjectorEnv = "\x00injector"
+
 func (ei *EnvironmentInjector) Init() {
 	ei.r = regexp.MustCompile(regex.EnvironmentVariableRegex)
 	ei.jr = regexp.MustCompile(regex.JsonEnvironmentVarRegex)
 	ei.dr = regexp.MustCompile(regex.DynamicVariableRegex)
 	ei.jdr = regexp.MustCompile(regex.JsonDynamicVariableRegex)
-	rand.Seed(time.Now().UnixNano())
+	ei.Seed(time.Now().UnixNano())
+}
+
+// Seed resets the random source of the injector, so the same seed produces the same sequence of values.
+func (ei *EnvironmentInjector) Seed(seed int64) {
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	ei.rnd = rand.New(rand.NewSource(seed))
+	ei.fakeData = newFakeDataMap(ei.rnd)
+}
+
+// Derive returns an injector sharing the expressions of ei, drawing its random values from rnd. The service derives
+// one for each iteration from its own stream, so the values of an iteration don't depend on the order the concurrent
+// iterations draw them.
+func (ei *EnvironmentInjector) Derive(rnd *rand.Rand) *EnvironmentInjector {
+	return &EnvironmentInjector{r: ei.r, jr: ei.jr, dr: ei.dr, jdr: ei.jdr, rnd: rnd}
+}
+
+// Of returns the injector of the iteration set in the envs by InjectorEnv, ei if there is none.
+func (ei *EnvironmentInjector) Of(envs map[string]interface{}) *EnvironmentInjector {
+	if d, ok := envs[InjectorEnv].(*EnvironmentInjector); ok && d != nil {
+		return d
+	}
+	return ei
+}
+
+// source returns the random source and the dynamic variables of the injector, the caller holds mu.
+func (ei *EnvironmentInjector) source() (*rand.Rand, map[string]interface{}) {
+	if ei.fakeData == nil {
+		ei.fakeData = newFakeDataMap(ei.rnd)
+	}
+	return ei.rnd, ei.fakeData
 }
 
 func truncateTag(tag string, rx string) string {
@@ -161,9 +201,9 @@ func truncateTag(tag string, rx string) string {
 	} else if strings.EqualFold(rx, regex.JsonEnvironmentVarRegex) {
 		return tag[3 : len(tag)-3] // "{{...}}"
 	} else if strings.EqualFold(rx, regex.DynamicVariableRegex) {
-		return tag[3 : len(tag)-2] // {{_...}}
+		return strings.TrimPrefix(tag[2:len(tag)-2], "_") // {{_...}} or {{func(...)}}
 	} else if strings.EqualFold(rx, regex.JsonDynamicVariableRegex) {
-		return tag[4 : len(tag)-3] //"{{_...}}"
+		return strings.TrimPrefix(tag[3:len(tag)-3], "_") //"{{_...}}" or "{{func(...)}}"
 	}
 	return ""
 }
@@ -174,6 +214,19 @@ func (ei *EnvironmentInjector) InjectEnv(text string, envs map[string]interface{
 	injectStrFunc := getInjectStrFunc(regex.EnvironmentVariableRegex, ei, envs, &errors)
 	injectToJsonByteFunc := getInjectJsonFunc(regex.JsonEnvironmentVarRegex, ei, envs, &errors)
 
+	// template functions taking variables like {{sha256Hex("{{body}}")}} are left by InjectDynamic,
+	// they are called before their variables are injected
+	injectFuncStrFunc := getInjectStrFunc(regex.DynamicVariableRegex, ei, envs, &errors)
+	text = ei.dr.ReplaceAllStringFunc(text, func(s string) string {
+		if !hasVariableArgs(s) {
+			return s
+		}
+		return injectFuncStrFunc(s)
+	})
+	if len(errors) > 0 {
+		return "", unifyErrors(errors)
+	}
+
 	// json injection
 	bText := StringToBytes(text)
 	if json.Valid(bText) {
@@ -219,17 +272,21 @@ func (ei *EnvironmentInjector) getEnv(envs map[string]interface{}, key string) (
 	}
 
 	if pickRand {
+		ei = ei.Of(envs)
+		ei.mu.Lock()
+		defer ei.mu.Unlock()
+		rnd, _ := ei.source()
 		switch v := val.(type) {
 		case []interface{}:
-			val = v[rand.Intn(len(v))]
+			val = v[rnd.Intn(len(v))]
 		case []string:
-			val = v[rand.Intn(len(v))]
+			val = v[rnd.Intn(len(v))]
 		case []bool:
-			val = v[rand.Intn(len(v))]
+			val = v[rnd.Intn(len(v))]
 		case []int:
-			val = v[rand.Intn(len(v))]
+			val = v[rnd.Intn(len(v))]
 		case []float64:
-			val = v[rand.Intn(len(v))]
+			val = v[rnd.Intn(len(v))]
 		default:
 			err = fmt.Errorf("can not perform rand() operation on non-array value")
 		}
@@ -272,7 +329,10 @@ func getInjectStrFunc(rx string,
 		if rx == regex.EnvironmentVariableRegex {
 			env, err = ei.getEnv(envs, truncated)
 		} else if rx == regex.DynamicVariableRegex {
-			env, err = ei.getFakeData(truncated)
+			if envs == nil && hasVariableArgs(truncated) {
+				return s // injected with the envs by InjectEnv
+			}
+			env, err = ei.getFakeData(truncated, envs)
 		} else {
 			// this should never happen
 			panic("invalid regex")
@@ -314,7 +374,10 @@ func getInjectJsonFunc(rx string,
 
 		truncated = truncateTag(string(s), rx)
 		if rx == regex.JsonDynamicVariableRegex {
-			env, err = ei.getFakeData(truncated)
+			if envs == nil && hasVariableArgs(truncated) {
+				return s // injected with the envs by InjectEnv
+			}
+			env, err = ei.getFakeData(truncated, envs)
 		} else if rx == regex.JsonEnvironmentVarRegex {
 			env, err = ei.getEnv(envs, truncated)
 		} else {
@@ -344,9 +407,20 @@ func (a EnvMatchSlice) Len() int           { return len(a) }
 func (a EnvMatchSlice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a EnvMatchSlice) Less(i, j int) bool { return a[i].found[0] < a[j].found[0] }
 
+// BodyTemplate is a body parsed for its variables once, the pieces of it are generated for each request
+// by injecting the values without searching the body again.
+type BodyTemplate struct {
+	Body    string
+	matches EnvMatchSlice
+}
+
+// GenerateBodyPieces parses the body and generates its pieces with the values injected from the envs.
 func (ei *EnvironmentInjector) GenerateBodyPieces(body string, envs map[string]interface{}) []BodyPiece {
-	// generate body pieces
-	pieces := make([]BodyPiece, 0)
+	return ei.GenerateTemplatePieces(ei.ParseBodyTemplate(body), envs)
+}
+
+// ParseBodyTemplate finds the variables in the body, the json bodies are searched for the json variables too.
+func (ei *EnvironmentInjector) ParseBodyTemplate(body string) *BodyTemplate {
 	matches := EnvMatchSlice{}
 
 	bText := StringToBytes(body)
@@ -427,12 +501,59 @@ func (ei *EnvironmentInjector) GenerateBodyPieces(body string, envs map[string]i
 		}
 	}
 
+	matches = excludeFunctionCallsFromEnvs(matches)
 	sort.Sort(matches) // by start index
+	return &BodyTemplate{Body: body, matches: matches}
+}
+
+// HasVariables returns true if the body has any variable to inject.
+func (t *BodyTemplate) HasVariables() bool {
+	return len(t.matches) > 0
+}
 
+// InjectTemplate returns the parsed body with the values injected from the envs.
+func (ei *EnvironmentInjector) InjectTemplate(t *BodyTemplate, envs map[string]interface{}) string {
+	if !t.HasVariables() {
+		return t.Body
+	}
+	var b strings.Builder
+	for _, p := range ei.GenerateTemplatePieces(t, envs) {
+		if p.injectable {
+			b.WriteString(p.value)
+		} else {
+			b.WriteString(t.Body[p.start:p.end])
+		}
+	}
+	return b.String()
+}
+
+// GenerateTemplatePieces generates the pieces of the parsed body with the values injected from the envs.
+func (ei *EnvironmentInjector) GenerateTemplatePieces(t *BodyTemplate, envs map[string]interface{}) []BodyPiece {
+	body := t.Body
+	pieces := make([]BodyPiece, 0, 2*len(t.matches)+1)
 	errors := make([]error, 0)
 	off := 0
 
-	for _, match := range matches {
+	f := getInjectStrFunc(regex.EnvironmentVariableRegex, ei, envs, &errors)
+	fd := getInjectStrFunc(regex.DynamicVariableRegex, ei, envs, &errors)
+
+	jf := getInjectJsonFunc(regex.JsonEnvironmentVarRegex, ei, envs, &errors)
+	jfd := getInjectJsonFunc(regex.JsonDynamicVariableRegex, ei, envs, &errors)
+
+	getValue := func(s string, r string) string {
+		if r == regex.JsonEnvironmentVarRegex {
+			return string(jf(StringToBytes(s)))
+		} else if r == regex.JsonDynamicVariableRegex {
+			return string(jfd(StringToBytes(s)))
+		} else if r == regex.EnvironmentVariableRegex {
+			return f(s)
+		} else if r == regex.DynamicVariableRegex {
+			return fd(s)
+		}
+		return s // this should never happen
+	}
+
+	for _, match := range t.matches {
 		r := match.regex
 		start := match.found[0]
 		end := match.found[1]
@@ -446,25 +567,6 @@ func (ei *EnvironmentInjector) GenerateBodyPieces(body string, envs map[string]i
 			})
 		}
 
-		f := getInjectStrFunc(regex.EnvironmentVariableRegex, ei, envs, &errors)
-		fd := getInjectStrFunc(regex.DynamicVariableRegex, ei, nil, &errors)
-
-		jf := getInjectJsonFunc(regex.JsonEnvironmentVarRegex, ei, envs, &errors)
-		jfd := getInjectJsonFunc(regex.JsonDynamicVariableRegex, ei, nil, &errors)
-
-		getValue := func(s string, r string) string {
-			if r == regex.JsonEnvironmentVarRegex {
-				return string(jf(StringToBytes(s)))
-			} else if r == regex.JsonDynamicVariableRegex {
-				return string(jfd(StringToBytes(s)))
-			} else if r == regex.EnvironmentVariableRegex {
-				return f(s)
-			} else if r == regex.DynamicVariableRegex {
-				return fd(s)
-			}
-			return s // this should never happen
-		}
-
 		val := getValue(body[start:end], r)
 
 		pieces = append(pieces, BodyPiece{
@@ -489,6 +591,27 @@ func (ei *EnvironmentInjector) GenerateBodyPieces(body string, envs map[string]i
 	return pieces
 }
 
+// excludeFunctionCallsFromEnvs drops the env matches inside the dynamic matches. Template functions without
+// arguments like {{uuid()}} are matched by the env regex too, but they are injected as dynamic variables.
+func excludeFunctionCallsFromEnvs(matches EnvMatchSlice) EnvMatchSlice {
+	filtered := make(EnvMatchSlice, 0, len(matches))
+	for _, m := range matches {
+		isEnv := m.regex == regex.EnvironmentVariableRegex || m.regex == regex.JsonEnvironmentVarRegex
+		inDynamic := false
+		for _, d := range matches {
+			if isEnv && (d.regex == regex.DynamicVariableRegex || d.regex == regex.JsonDynamicVariableRegex) &&
+				m.found[0] >= d.found[0] && m.found[1] <= d.found[1] {
+				inDynamic = true
+				break
+			}
+		}
+		if !inDynamic {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
 func GetContentLength(pieces []BodyPiece) int {
 	var contentLength int
 	for _, piece := range pieces {
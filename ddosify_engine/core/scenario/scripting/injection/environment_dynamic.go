@@ -33,18 +33,24 @@ func (ei *EnvironmentInjector) InjectDynamic(text string) (string, error) {
 
 }
 
-func (ei *EnvironmentInjector) getFakeData(key string) (interface{}, error) {
-	var fakeFunc interface{}
-	var keyExists bool
-	if fakeFunc, keyExists = dynamicFakeDataMap[key]; !keyExists {
+// getFakeData returns the value of the dynamic variable or the template function, the variables in the arguments of
+// the function are injected from the envs.
+func (ei *EnvironmentInjector) getFakeData(key string, envs map[string]interface{}) (interface{}, error) {
+	ei = ei.Of(envs)
+	if templateFunctionRgx.MatchString(key) {
+		return ei.callTemplateFunction(key, envs)
+	}
+
+	if _, keyExists := dynamicFakeDataMap[key]; !keyExists {
 		return nil, fmt.Errorf("%s is not a valid dynamic variable", key)
 	}
 
-	preventRaceOnRandomFunc := func(fakeFunc interface{}) interface{} {
+	preventRaceOnRandomFunc := func(key string) interface{} {
 		ei.mu.Lock()
 		defer ei.mu.Unlock()
-		return reflect.ValueOf(fakeFunc).Call(nil)[0].Interface()
+		_, fakeData := ei.source()
+		return reflect.ValueOf(fakeData[key]).Call(nil)[0].Interface()
 	}
 
-	return preventRaceOnRandomFunc(fakeFunc), nil
+	return preventRaceOnRandomFunc(key), nil
 }
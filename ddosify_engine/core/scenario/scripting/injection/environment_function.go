@@ -0,0 +1,258 @@
+package injection
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ddosify/go-faker/faker"
+	"go.ddosify.com/ddosify/core/types/regex"
+)
+
+var templateFunctionRgx = regexp.MustCompile(regex.TemplateFunctionRegex)
+
+const alphaNumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Named layouts of now(), other values are used as a Go time layout
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    "2006-01-02 15:04:05",
+	"DateOnly":    "2006-01-02",
+	"TimeOnly":    "15:04:05",
+}
+
+// templateFunctions are called with the arguments given in the template, like {{randomInt(1,100)}}.
+// Functions are called on each injection, so every request gets a new value.
+var templateFunctions = map[string]func(rnd *rand.Rand, args []string) (interface{}, error){
+	"uuid": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 0, 0); err != nil {
+			return nil, err
+		}
+		return randomUUID(rnd).String(), nil
+	},
+	"randomInt": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 2, 2); err != nil {
+			return nil, err
+		}
+		min, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("min should be an integer: %s", args[0])
+		}
+		max, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("max should be an integer: %s", args[1])
+		}
+		if min > max {
+			return nil, fmt.Errorf("min can not be greater than max")
+		}
+		return min + rnd.Intn(max-min+1), nil
+	},
+	"randomFloat": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 2, 2); err != nil {
+			return nil, err
+		}
+		min, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("min should be a number: %s", args[0])
+		}
+		max, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("max should be a number: %s", args[1])
+		}
+		if min > max {
+			return nil, fmt.Errorf("min can not be greater than max")
+		}
+		return min + rnd.Float64()*(max-min), nil
+	},
+	"randomString": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 0, 1); err != nil {
+			return nil, err
+		}
+		length := 10
+		if len(args) == 1 {
+			var err error
+			if length, err = strconv.Atoi(args[0]); err != nil || length < 0 {
+				return nil, fmt.Errorf("length should be a positive integer: %s", args[0])
+			}
+		}
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = alphaNumeric[rnd.Intn(len(alphaNumeric))]
+		}
+		return string(b), nil
+	},
+	"randomEmail": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 0, 0); err != nil {
+			return nil, err
+		}
+		return faker.Faker{Generator: rnd}.RandomEmail(), nil
+	},
+	"now": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 0, 1); err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		if len(args) == 0 {
+			return now.Format(time.RFC3339), nil
+		}
+		switch args[0] {
+		case "unix":
+			return now.Unix(), nil
+		case "unixMilli":
+			return now.UnixNano() / int64(time.Millisecond), nil
+		}
+		if layout, ok := timeLayouts[args[0]]; ok {
+			return now.Format(layout), nil
+		}
+		return now.Format(args[0]), nil
+	},
+	"timestamp": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 0, 0); err != nil {
+			return nil, err
+		}
+		return time.Now().Unix(), nil
+	},
+	"base64": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString([]byte(args[0])), nil
+	},
+	"hmacSHA256": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 2, 3); err != nil {
+			return nil, err
+		}
+		mac := hmac.New(sha256.New, []byte(args[0]))
+		mac.Write([]byte(args[1]))
+		if len(args) == 3 {
+			return encodeDigest(mac.Sum(nil), args[2])
+		}
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	},
+	"sha256Hex": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 1, 1); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256([]byte(args[0]))
+		return hex.EncodeToString(sum[:]), nil
+	},
+	"md5Hex": func(rnd *rand.Rand, args []string) (interface{}, error) {
+		if err := checkArgCount(args, 1, 1); err != nil {
+			return nil, err
+		}
+		sum := md5.Sum([]byte(args[0]))
+		return hex.EncodeToString(sum[:]), nil
+	},
+}
+
+// encodeDigest encodes the digest in the given encoding, hex or base64.
+func encodeDigest(digest []byte, encoding string) (string, error) {
+	switch encoding {
+	case "hex":
+		return hex.EncodeToString(digest), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(digest), nil
+	}
+	return "", fmt.Errorf("encoding should be hex or base64: %s", encoding)
+}
+
+func checkArgCount(args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("expected %d arguments, found %d", min, len(args))
+		}
+		return fmt.Errorf("expected %d to %d arguments, found %d", min, max, len(args))
+	}
+	return nil
+}
+
+// callTemplateFunction evaluates a function call expression like randomInt(1,100), the variables in the arguments
+// like {{body}} are injected from the envs first. Random values are drawn from the random source of the injector.
+func (ei *EnvironmentInjector) callTemplateFunction(expr string, envs map[string]interface{}) (interface{}, error) {
+	open := strings.Index(expr, "(")
+	if open < 0 || !strings.HasSuffix(expr, ")") {
+		return nil, fmt.Errorf("%s is not a valid function call", expr)
+	}
+
+	name := expr[:open]
+	f, ok := templateFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a valid function", name)
+	}
+
+	args := parseFunctionArgs(expr[open+1 : len(expr)-1])
+	for i, arg := range args {
+		if !ei.r.MatchString(arg) {
+			continue
+		}
+		// injected after splitting, so the commas and quotes of the values don't split the arguments
+		injected, err := ei.InjectEnv(arg, envs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", expr, err)
+		}
+		args[i] = injected
+	}
+
+	ei.mu.Lock()
+	defer ei.mu.Unlock()
+	rnd, _ := ei.source()
+	val, err := f(rnd, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", expr, err)
+	}
+	return val, nil
+}
+
+// hasVariableArgs reports whether the arguments of the function call contain variables, like sha256Hex("{{body}}").
+func hasVariableArgs(expr string) bool {
+	open := strings.Index(expr, "(")
+	return open >= 0 && strings.Contains(expr[open:], "{{")
+}
+
+// parseFunctionArgs splits the comma separated arguments. Arguments can be quoted with " or ',
+// quotes can be escaped in json bodies like \"RFC3339\".
+func parseFunctionArgs(s string) []string {
+	s = strings.ReplaceAll(s, `\"`, `"`)
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	args := []string{}
+	var sb strings.Builder
+	var quote rune
+	for _, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				sb.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			args = append(args, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return append(args, strings.TrimSpace(sb.String()))
+}
@@ -0,0 +1,257 @@
+package injection
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTemplateFunctions(t *testing.T) {
+	t.Parallel()
+
+	ei := EnvironmentInjector{}
+	ei.Init()
+
+	tests := []struct {
+		name  string
+		text  string
+		check func(string) bool
+	}{
+		{"uuid", "{{uuid()}}", func(s string) bool { _, err := uuid.Parse(s); return err == nil }},
+		{"randomInt", "{{randomInt(1,100)}}", func(s string) bool { i, err := strconv.Atoi(s); return err == nil && i >= 1 && i <= 100 }},
+		{"randomIntSpaces", "{{randomInt( -5 , -5 )}}", func(s string) bool { return s == "-5" }},
+		{"randomFloat", "{{randomFloat(1.5,2.5)}}", func(s string) bool {
+			f, err := strconv.ParseFloat(s, 64)
+			return err == nil && f >= 1.5 && f <= 2.5
+		}},
+		{"randomString", "{{randomString(16)}}", regexp.MustCompile(`^[a-zA-Z0-9]{16}$`).MatchString},
+		{"randomStringDefault", "{{randomString()}}", regexp.MustCompile(`^[a-zA-Z0-9]{10}$`).MatchString},
+		{"randomEmail", "{{randomEmail()}}", func(s string) bool { return strings.Contains(s, "@") }},
+		{"now", "{{now()}}", func(s string) bool { _, err := time.Parse(time.RFC3339, s); return err == nil }},
+		{"nowLayoutName", `{{now("RFC1123")}}`, func(s string) bool { _, err := time.Parse(time.RFC1123, s); return err == nil }},
+		{"nowGoLayout", `{{now('2006-01-02')}}`, func(s string) bool { return s == time.Now().Format("2006-01-02") }},
+		{"nowUnix", `{{now("unix")}}`, func(s string) bool { _, err := strconv.ParseInt(s, 10, 64); return err == nil }},
+		{"timestamp", "{{timestamp()}}", func(s string) bool { _, err := strconv.ParseInt(s, 10, 64); return err == nil }},
+		{"base64", `{{base64("user:pass")}}`, func(s string) bool { return s == "dXNlcjpwYXNz" }},
+		{"sha256Hex", "{{sha256Hex(abc)}}", func(s string) bool {
+			return s == "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+		}},
+		{"md5Hex", "{{md5Hex('abc')}}", func(s string) bool { return s == "900150983cd24fb0d6963f7d28e17f72" }},
+		{"hmacSHA256", `{{hmacSHA256("key", "The quick brown fox jumps over the lazy dog")}}`, func(s string) bool {
+			return s == "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+		}},
+		{"hmacSHA256Base64", `{{hmacSHA256("key", "The quick brown fox jumps over the lazy dog", "base64")}}`,
+			func(s string) bool { return s == "97yD9DBThCSxMpjmqm+xQ+9NWaFJRhdZl0edvC0aPNg=" }},
+	}
+
+	for _, test := range tests {
+		got, err := ei.InjectDynamic(test.text)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !test.check(got) {
+			t.Errorf("%s: unexpected value %s", test.name, got)
+		}
+	}
+}
+
+func TestTemplateFunctionsInvalidCalls(t *testing.T) {
+	t.Parallel()
+
+	ei := EnvironmentInjector{}
+	ei.Init()
+
+	invalids := []string{
+		"{{randomInt(1)}}",
+		"{{randomInt(a,b)}}",
+		"{{randomInt(10,1)}}",
+		"{{randomFloat(1)}}",
+		"{{randomString(-1)}}",
+		"{{uuid(1)}}",
+		"{{base64()}}",
+		`{{hmacSHA256("key")}}`,
+		`{{hmacSHA256("key", "data", "base32")}}`,
+		"{{sha256Hex(a,b)}}",
+	}
+	for _, text := range invalids {
+		if _, err := ei.InjectDynamic(text); err == nil {
+			t.Errorf("%s should be errored", text)
+		}
+	}
+}
+
+func TestTemplateFunctionsEvaluatedPerInjection(t *testing.T) {
+	t.Parallel()
+
+	ei := EnvironmentInjector{}
+	ei.Init()
+
+	body := "id={{uuid()}}"
+	seen := map[string]struct{}{}
+	for i := 0; i < 10; i++ {
+		pieces := ei.GenerateBodyPieces(body, nil)
+		if len(pieces) != 2 {
+			t.Fatalf("Expected %d, Found: %d", 2, len(pieces))
+		}
+		seen[pieces[1].value] = struct{}{}
+	}
+	if len(seen) != 10 {
+		t.Errorf("Expected a new value on each injection, Found: %d distinct values", len(seen))
+	}
+}
+
+func TestTemplateFunctionsInJsonBody(t *testing.T) {
+	t.Parallel()
+
+	ei := EnvironmentInjector{}
+	ei.Init()
+
+	body := `{"id": "{{uuid()}}", "age": "{{randomInt(18,18)}}", "name": "{{name}}", "at": "{{now(\"DateOnly\")}}"}`
+	envs := map[string]interface{}{"name": "test"}
+	pieces := ei.GenerateBodyPieces(body, envs)
+
+	reader := DdosifyBodyReader{Body: body, Pieces: pieces}
+	b, _ := io.ReadAll(&reader)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Expected valid json, Found: %s, %v", b, err)
+	}
+	if _, err := uuid.Parse(got["id"].(string)); err != nil {
+		t.Errorf("Expected uuid, Found: %v", got["id"])
+	}
+	expected := map[string]interface{}{"age": float64(18), "name": "test", "at": time.Now().Format("2006-01-02")}
+	delete(got, "id")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, got)
+	}
+}
+
+func TestTemplateFunctionsWithVariables(t *testing.T) {
+	t.Parallel()
+
+	ei := EnvironmentInjector{}
+	ei.Init()
+	envs := map[string]interface{}{"secret": "secret", "body": `{"id":"a,b"}`, "user": "user", "pass": `p,a"ss`}
+	signature := "4867963ad98b3a97c27271c24d1c826a9e4ecdd752d667af77b8bc759fbb5275"
+
+	// left by the dynamic injection, the values with commas and quotes don't split the arguments
+	header := `{{hmacSHA256("{{secret}}", "{{body}}")}}`
+	got, err := ei.InjectDynamic(header)
+	if err != nil || got != header {
+		t.Errorf("Expected %v, Found: %v %v", header, got, err)
+	}
+	if got, err = ei.InjectEnv(got, envs); err != nil || got != signature {
+		t.Errorf("Expected %v, Found: %v %v", signature, got, err)
+	}
+
+	credentials := "Basic {{base64('{{user}}:{{pass}}')}}"
+	if got, err = ei.InjectEnv(credentials, envs); err != nil || got != "Basic dXNlcjpwLGEic3M=" {
+		t.Errorf("Expected %v, Found: %v %v", "Basic dXNlcjpwLGEic3M=", got, err)
+	}
+
+	if _, err = ei.InjectEnv("{{sha256Hex({{missing}})}}", envs); err == nil {
+		t.Errorf("Expected error of the missing variable")
+	}
+
+	body := `{"hash": "{{sha256Hex(\"{{body}}\")}}", "user": "{{user}}"}`
+	reader := DdosifyBodyReader{Body: body, Pieces: ei.GenerateBodyPieces(body, envs)}
+	b, _ := io.ReadAll(&reader)
+	expected := `{"hash": "afb50492c27c0e57181a64387dd2e1d3766437685e295e9e41cf2c73687744bb", "user": "user"}`
+	if string(b) != expected {
+		t.Errorf("Expected %v, Found: %s", expected, b)
+	}
+}
+
+func TestParseFunctionArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args     string
+		expected []string
+	}{
+		{"", nil},
+		{"1, 2", []string{"1", "2"}},
+		{`"Mon, 02 Jan 2006"`, []string{"Mon, 02 Jan 2006"}},
+		{`\"RFC3339\"`, []string{"RFC3339"}},
+		{`'a', "b"`, []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		if got := parseFunctionArgs(test.args); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Expected %v, Found: %v", test.expected, got)
+		}
+	}
+}
+
+func TestSeededInjection(t *testing.T) {
+	t.Parallel()
+
+	text := "{{_randomUUID}} {{_randomFullName}} {{_randomIP}} {{_randomInt}} {{_randomBoolean}} {{_randomCity}} " +
+		"{{_randomIPV6}} {{_randomWord}} {{uuid()}} {{randomInt(1,1000000)}} {{randomString(8)}}"
+	envs := map[string]interface{}{"ids": []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}}
+
+	inject := func(seed int64) []string {
+		ei := EnvironmentInjector{}
+		ei.Init()
+		ei.Seed(seed)
+		values := []string{}
+		for i := 0; i < 5; i++ {
+			d, err := ei.InjectDynamic(text)
+			if err != nil {
+				t.Fatalf("TestSeededInjection error occurred: %v", err)
+			}
+			e, _ := ei.InjectEnv("{{rand(ids)}}", envs)
+			values = append(values, d, e)
+		}
+		return values
+	}
+
+	first := inject(42)
+	if second := inject(42); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected %v, Found: %v", first, second)
+	}
+	if other := inject(43); reflect.DeepEqual(first, other) {
+		t.Errorf("Expected different values for a different seed, Found: %v", other)
+	}
+}
+
+func TestDerivedInjection(t *testing.T) {
+	t.Parallel()
+
+	ei := EnvironmentInjector{}
+	ei.Init()
+	text := "{{_randomUUID}} {{randomInt(1,1000000)}} {{rand(ids)}}"
+	inject := func(seed int64) string {
+		// the injector of the iteration is taken from the envs by the base injector
+		envs := map[string]interface{}{
+			"ids":       []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			InjectorEnv: ei.Derive(rand.New(rand.NewSource(seed))),
+		}
+		d, err := ei.Of(envs).InjectDynamic(text)
+		if err != nil {
+			t.Fatalf("TestDerivedInjection error occurred: %v", err)
+		}
+		e, err := ei.InjectEnv(d, envs)
+		if err != nil {
+			t.Fatalf("TestDerivedInjection error occurred: %v", err)
+		}
+		return e
+	}
+
+	first := inject(42)
+	if second := inject(42); first != second {
+		t.Errorf("Expected %v, Found: %v", first, second)
+	}
+	if other := inject(43); first == other {
+		t.Errorf("Expected different values for a different seed, Found: %v", other)
+	}
+}
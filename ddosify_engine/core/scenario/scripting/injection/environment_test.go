@@ -871,6 +871,29 @@ func TestGenerateBodyPieces(t *testing.T) {
 	}
 }
 
+func TestInjectTemplate(t *testing.T) {
+	ei := EnvironmentInjector{}
+	ei.Init()
+
+	// parsed once, injected for each request
+	tmpl := ei.ParseBodyTemplate(`{"id": "{{id}}", "count": "{{count}}"}`)
+	if !tmpl.HasVariables() {
+		t.Errorf("expected the template to have variables")
+	}
+	for _, id := range []string{"a", "b"} {
+		got := ei.InjectTemplate(tmpl, map[string]interface{}{"id": id, "count": 3})
+		expected := `{"id": "` + id + `", "count": 3}`
+		if got != expected {
+			t.Errorf("Expected %v, Found: %v", expected, got)
+		}
+	}
+
+	static := ei.ParseBodyTemplate(`{"id": 1}`)
+	if static.HasVariables() || ei.InjectTemplate(static, nil) != `{"id": 1}` {
+		t.Errorf("expected the static body to be sent as it is")
+	}
+}
+
 func TestGenerateBodyPiecesWithDynamicVars(t *testing.T) {
 	body := "test{{env1}}xyz{{_randomInt}}"
 
@@ -1,13 +1,26 @@
 package injection
 
-import "github.com/ddosify/go-faker/faker"
+import (
+	"math/rand"
+	"strings"
+	"time"
 
-var dynamicFakeDataMap map[string]interface{}
-var dataFaker faker.Faker
+	"github.com/ddosify/go-faker/faker"
+	"github.com/google/uuid"
+	jfaker "github.com/jaswdr/faker"
+)
 
-func init() {
-	dataFaker = faker.NewFaker()
-	dynamicFakeDataMap = map[string]interface{}{
+// dynamicFakeDataMap holds the names of the dynamic variables, injectors use their own seeded map.
+var dynamicFakeDataMap = newFakeDataMap(rand.New(rand.NewSource(time.Now().UnixNano())))
+
+// newFakeDataMap returns the dynamic variable functions drawing from rnd. Some of the go-faker
+// functions create a time seeded generator on each call, those are replaced with their rnd equivalents,
+// so the values of a seeded injector are reproducible. rnd is not safe for concurrent use.
+func newFakeDataMap(rnd *rand.Rand) map[string]interface{} {
+	dataFaker := faker.Faker{Generator: rnd}
+	jf := jfaker.NewWithSeed(rnd)
+
+	m := map[string]interface{}{
 		/*
 		* Postman equivalents: https://learning.postman.com/docs/writing-scripts/script-references/variables-list
 		 */
@@ -170,4 +183,74 @@ func init() {
 		"randomFloat":  dataFaker.RandomFloat,
 		"randomString": dataFaker.RandomString,
 	}
+
+	// go-faker functions that do not use the generator
+	m["guid"] = func() uuid.UUID { return randomUUID(rnd) }
+	m["randomUUID"] = func() uuid.UUID { return randomUUID(rnd) }
+	m["randomAlphaNumeric"] = func() string { return string("abcdefghijklmnopqrstuvwxyz0123456789"[rnd.Intn(36)]) }
+	m["randomBoolean"] = jf.Bool
+	m["randomInt"] = func() int { return jf.IntBetween(0, 1000) }
+	m["randomColor"] = jf.Color().SafeColorName
+	m["randomHexColor"] = jf.Color().Hex
+	m["randomIP"] = jf.Internet().Ipv4
+	m["randomIPV6"] = func() string { return randomIpv6(rnd) }
+	m["randomMACAddress"] = jf.Internet().MacAddress
+	m["randomPassword"] = jf.Internet().Password
+	m["randomLocale"] = jf.Language().LanguageAbbr
+	m["randomUserAgent"] = jf.UserAgent().UserAgent
+	m["randomFirstName"] = jf.Person().FirstName
+	m["randomLastName"] = jf.Person().LastName
+	m["randomFullName"] = jf.Person().Name
+	m["randomNamePrefix"] = jf.Person().Title
+	m["randomNameSuffix"] = jf.Person().Suffix
+	m["randomCity"] = jf.Address().City
+	m["randomStreetName"] = jf.Address().StreetName
+	m["randomStreetAddress"] = jf.Address().StreetAddress
+	m["randomCountry"] = jf.Address().Country
+	m["randomLatitude"] = jf.Address().Latitude
+	m["randomLongitude"] = jf.Address().Longitude
+	m["randomBitcoin"] = func() string { return randomBitcoin(rnd) }
+	m["randomDateFuture"] = func() string { return randomDate(rnd, 0, 1.0/9) }
+	m["randomDatePast"] = func() string { return randomDate(rnd, -0.2, 0) }
+	m["randomDateRecent"] = func() string { return randomDate(rnd, -1.0/200, 0) }
+
+	return m
+}
+
+func randomUUID(rnd *rand.Rand) uuid.UUID {
+	id, err := uuid.NewRandomFromReader(rnd)
+	if err != nil {
+		return uuid.New()
+	}
+	return id
+}
+
+func randomIpv6(rnd *rand.Rand) string {
+	const hex = "abcdef0123456789"
+	blocks := make([]string, 8)
+	for i := range blocks {
+		b := make([]byte, 4)
+		for j := range b {
+			b[j] = hex[rnd.Intn(len(hex))]
+		}
+		blocks[i] = string(b)
+	}
+	return strings.Join(blocks, ":")
+}
+
+func randomBitcoin(rnd *rand.Rand) string {
+	const letters = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	b := make([]byte, rnd.Intn(35-26)+26)
+	for i := range b {
+		b[i] = letters[rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// randomDate returns a date between now+from*now and now+to*now in unix seconds, like go-faker does.
+func randomDate(rnd *rand.Rand, from, to float64) string {
+	now := time.Now().Unix()
+	min := now + int64(float64(now)*from)
+	max := now + int64(float64(now)*to)
+	return time.Unix(rnd.Int63n(max-min)+min, 0).Format(time.UnixDate)
 }
@@ -0,0 +1,418 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Schema is a compiled JSON Schema. A practical subset of the draft 7 keywords is supported:
+// type, enum, const, properties, required, additionalProperties, items, minItems, maxItems, uniqueItems,
+// minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf,
+// allOf, anyOf, oneOf, not and the local $ref like "#/definitions/user". Other keywords, like format, are ignored.
+// Validate is safe for concurrent use.
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+}
+
+// Load reads and compiles the JSON Schema file at the path.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// New compiles the given JSON Schema document.
+func New(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("schema is not a valid json: %w", err)
+	}
+	s := &Schema{root: root, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.compile(root, "#"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// compile checks the sub schemas and compiles their patterns, so they are not compiled per validation.
+func (s *Schema) compile(node interface{}, at string) error {
+	switch n := node.(type) {
+	case bool:
+		return nil
+	case map[string]interface{}:
+		if p, ok := n["pattern"].(string); ok {
+			if _, ok := s.patterns[p]; !ok {
+				rgx, err := regexp.Compile(p)
+				if err != nil {
+					return fmt.Errorf("invalid pattern at %s: %w", at, err)
+				}
+				s.patterns[p] = rgx
+			}
+		}
+		if ref, ok := n["$ref"].(string); ok {
+			if _, err := s.resolve(ref); err != nil {
+				return err
+			}
+		}
+		for k, v := range n {
+			switch k {
+			case "properties", "definitions", "$defs":
+				props, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s at %s should be an object", k, at)
+				}
+				for name, sub := range props {
+					if err := s.compile(sub, at+"/"+k+"/"+name); err != nil {
+						return err
+					}
+				}
+			case "items":
+				if list, ok := v.([]interface{}); ok {
+					for i, sub := range list {
+						if err := s.compile(sub, fmt.Sprintf("%s/items/%d", at, i)); err != nil {
+							return err
+						}
+					}
+				} else if err := s.compile(v, at+"/items"); err != nil {
+					return err
+				}
+			case "allOf", "anyOf", "oneOf":
+				list, ok := v.([]interface{})
+				if !ok {
+					return fmt.Errorf("%s at %s should be an array", k, at)
+				}
+				for i, sub := range list {
+					if err := s.compile(sub, fmt.Sprintf("%s/%s/%d", at, k, i)); err != nil {
+						return err
+					}
+				}
+			case "not", "additionalProperties":
+				if err := s.compile(v, at+"/"+k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("schema at %s should be an object or a boolean", at)
+	}
+}
+
+// resolve returns the sub schema of the local reference, like "#/definitions/user".
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if ref == "#" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("only the local references are supported, found: %s", ref)
+	}
+	node := s.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("reference can not be resolved: %s", ref)
+		}
+		if node, ok = obj[token]; !ok {
+			return nil, fmt.Errorf("reference can not be resolved: %s", ref)
+		}
+	}
+	return node, nil
+}
+
+// Validate validates the JSON document against the schema, returns at most max violation messages.
+// All the violations are returned if max is not positive. Returns nil if the document is valid.
+func (s *Schema) Validate(doc []byte, max int) []string {
+	var v interface{}
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return []string{fmt.Sprintf("response is not a valid json: %v", err)}
+	}
+	c := &validation{schema: s, max: max}
+	c.validate(s.root, v, "$")
+	return c.errs
+}
+
+type validation struct {
+	schema *Schema
+	max    int
+	errs   []string
+}
+
+func (c *validation) full() bool {
+	return c.max > 0 && len(c.errs) >= c.max
+}
+
+func (c *validation) addf(path string, format string, args ...interface{}) {
+	if !c.full() {
+		c.errs = append(c.errs, path+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+// valid returns true if the value conforms to the sub schema, without recording the violations.
+func (c *validation) valid(node interface{}, v interface{}, path string) bool {
+	sub := &validation{schema: c.schema, max: 1}
+	sub.validate(node, v, path)
+	return len(sub.errs) == 0
+}
+
+func (c *validation) validate(node interface{}, v interface{}, path string) {
+	if c.full() {
+		return
+	}
+	n, ok := node.(map[string]interface{})
+	if !ok {
+		if b, _ := node.(bool); !b {
+			c.addf(path, "no value is allowed")
+		}
+		return
+	}
+
+	if ref, ok := n["$ref"].(string); ok {
+		// siblings of $ref are ignored in draft 7
+		sub, _ := c.schema.resolve(ref) // resolved in compile
+		c.validate(sub, v, path)
+		return
+	}
+
+	if t, ok := n["type"]; ok && !typeMatches(t, v) {
+		c.addf(path, "expected type %s, found %s", typeString(t), typeOf(v))
+		return
+	}
+	if enum, ok := n["enum"].([]interface{}); ok && !contains(enum, v) {
+		c.addf(path, "value %s is not one of the enum values", short(v))
+	}
+	if cv, ok := n["const"]; ok && !reflect.DeepEqual(cv, v) {
+		c.addf(path, "value %s is not equal to the const %s", short(v), short(cv))
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		c.validateObject(n, val, path)
+	case []interface{}:
+		c.validateArray(n, val, path)
+	case string:
+		c.validateString(n, val, path)
+	case float64:
+		c.validateNumber(n, val, path)
+	}
+
+	if list, ok := n["allOf"].([]interface{}); ok {
+		for _, sub := range list {
+			c.validate(sub, v, path)
+		}
+	}
+	if list, ok := n["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range list {
+			if c.valid(sub, v, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			c.addf(path, "value does not match any of the anyOf schemas")
+		}
+	}
+	if list, ok := n["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, sub := range list {
+			if c.valid(sub, v, path) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			c.addf(path, "value matches %d of the oneOf schemas, expected exactly one", matched)
+		}
+	}
+	if sub, ok := n["not"]; ok && c.valid(sub, v, path) {
+		c.addf(path, "value should not match the not schema")
+	}
+}
+
+func (c *validation) validateObject(n map[string]interface{}, obj map[string]interface{}, path string) {
+	if required, ok := n["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, exists := obj[name]; !exists {
+					c.addf(path, "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	props, _ := n["properties"].(map[string]interface{})
+	additional, hasAdditional := n["additionalProperties"]
+
+	// sorted for the deterministic messages
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		propPath := path + "." + k
+		if sub, ok := props[k]; ok {
+			c.validate(sub, obj[k], propPath)
+		} else if hasAdditional {
+			if b, ok := additional.(bool); ok && !b {
+				c.addf(path, "additional property %q is not allowed", k)
+			} else {
+				c.validate(additional, obj[k], propPath)
+			}
+		}
+	}
+}
+
+func (c *validation) validateArray(n map[string]interface{}, arr []interface{}, path string) {
+	if min, ok := n["minItems"].(float64); ok && float64(len(arr)) < min {
+		c.addf(path, "expected at least %v items, found %d", min, len(arr))
+	}
+	if max, ok := n["maxItems"].(float64); ok && float64(len(arr)) > max {
+		c.addf(path, "expected at most %v items, found %d", max, len(arr))
+	}
+	if unique, _ := n["uniqueItems"].(bool); unique {
+		for i := 1; i < len(arr); i++ {
+			if contains(arr[:i], arr[i]) {
+				c.addf(path, "items are not unique, duplicate at index %d", i)
+				break
+			}
+		}
+	}
+
+	switch items := n["items"].(type) {
+	case []interface{}: // tuple
+		for i := 0; i < len(items) && i < len(arr); i++ {
+			c.validate(items[i], arr[i], path+"["+strconv.Itoa(i)+"]")
+		}
+	case nil:
+	default:
+		for i, item := range arr {
+			c.validate(items, item, path+"["+strconv.Itoa(i)+"]")
+			if c.full() {
+				return
+			}
+		}
+	}
+}
+
+func (c *validation) validateString(n map[string]interface{}, s string, path string) {
+	length := float64(utf8.RuneCountInString(s))
+	if min, ok := n["minLength"].(float64); ok && length < min {
+		c.addf(path, "expected at least %v characters, found %v", min, length)
+	}
+	if max, ok := n["maxLength"].(float64); ok && length > max {
+		c.addf(path, "expected at most %v characters, found %v", max, length)
+	}
+	if p, ok := n["pattern"].(string); ok && !c.schema.patterns[p].MatchString(s) {
+		c.addf(path, "value %s does not match the pattern %q", short(s), p)
+	}
+}
+
+func (c *validation) validateNumber(n map[string]interface{}, f float64, path string) {
+	if min, ok := n["minimum"].(float64); ok && f < min {
+		c.addf(path, "value %v is less than the minimum %v", f, min)
+	}
+	if max, ok := n["maximum"].(float64); ok && f > max {
+		c.addf(path, "value %v is greater than the maximum %v", f, max)
+	}
+	if min, ok := n["exclusiveMinimum"].(float64); ok && f <= min {
+		c.addf(path, "value %v should be greater than %v", f, min)
+	}
+	if max, ok := n["exclusiveMaximum"].(float64); ok && f >= max {
+		c.addf(path, "value %v should be less than %v", f, max)
+	}
+	if m, ok := n["multipleOf"].(float64); ok && m > 0 {
+		if q := f / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			c.addf(path, "value %v is not a multiple of %v", f, m)
+		}
+	}
+}
+
+func typeMatches(t interface{}, v interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return isType(tt, v)
+	case []interface{}:
+		for _, one := range tt {
+			if s, ok := one.(string); ok && isType(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isType(t string, v interface{}) bool {
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && math.Trunc(f) == f
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	default:
+		return typeOf(v) == t
+	}
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func typeString(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, one := range list {
+			names = append(names, fmt.Sprint(one))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, e := range list {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// short returns the json of the value, shortened to keep the messages readable.
+func short(v interface{}) string {
+	b, _ := json.Marshal(v)
+	if len(b) > 50 {
+		return string(b[:47]) + "..."
+	}
+	return string(b)
+}
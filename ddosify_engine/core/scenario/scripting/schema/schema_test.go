@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const userSchema = `{
+	"type": "object",
+	"required": ["id", "name", "roles"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"name": {"type": "string", "minLength": 2, "pattern": "^[A-Z]"},
+		"email": {"type": ["string", "null"]},
+		"status": {"enum": ["active", "passive"]},
+		"roles": {"type": "array", "minItems": 1, "uniqueItems": true, "items": {"$ref": "#/definitions/role"}}
+	},
+	"definitions": {
+		"role": {"type": "string", "maxLength": 10}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	s, err := New([]byte(userSchema))
+	if err != nil {
+		t.Fatalf("TestValidate error occurred: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		doc      string
+		max      int
+		expected []string
+	}{
+		{"Valid", `{"id": 1, "name": "Alice", "email": null, "status": "active", "roles": ["admin"]}`, 0, nil},
+		{"MissingAndType", `{"id": "1", "name": "Bob"}`, 0, []string{
+			`$: missing required property "roles"`,
+			"$.id: expected type integer, found string",
+		}},
+		{"Nested", `{"id": 0, "name": "bob", "roles": ["admin", "admin", "superadministrator"], "x": 1}`, 0, []string{
+			"$.id: value 0 is less than the minimum 1",
+			`$.name: value "bob" does not match the pattern "^[A-Z]"`,
+			"$.roles: items are not unique, duplicate at index 1",
+			"$.roles[2]: expected at most 10 characters, found 18",
+			`$: additional property "x" is not allowed`,
+		}},
+		{"MaxErrors", `{"id": 0, "name": "b", "roles": []}`, 2, []string{
+			"$.id: value 0 is less than the minimum 1",
+			"$.name: expected at least 2 characters, found 1",
+		}},
+		{"Enum", `{"id": 1, "name": "Al", "status": "deleted", "roles": ["a"]}`, 0, []string{
+			`$.status: value "deleted" is not one of the enum values`,
+		}},
+		{"InvalidJson", `{"id": 1, "name": `, 0, []string{
+			"response is not a valid json: unexpected end of JSON input",
+		}},
+	}
+
+	for _, test := range tests {
+		errs := s.Validate([]byte(test.doc), test.max)
+		if !reflect.DeepEqual(errs, test.expected) {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expected, errs)
+		}
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	t.Parallel()
+
+	s, err := New([]byte(`{"oneOf": [{"type": "integer"}, {"type": "number", "multipleOf": 0.5}], "not": {"const": 3}}`))
+	if err != nil {
+		t.Fatalf("TestValidateCombinators error occurred: %v", err)
+	}
+
+	tests := []struct {
+		doc   string
+		valid bool
+	}{
+		{"1.5", true},
+		{"3", false}, // not
+		{"2", false}, // matches both of the oneOf schemas
+		{"1.2", false},
+	}
+
+	for _, test := range tests {
+		if valid := s.Validate([]byte(test.doc), 0) == nil; valid != test.valid {
+			t.Errorf("%s Expected %v, Found: %v", test.doc, test.valid, valid)
+		}
+	}
+}
+
+func TestNewInvalidSchema(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		`{"type": "string"`,
+		`{"pattern": "["}`,
+		`{"$ref": "#/definitions/missing"}`,
+		`{"$ref": "https://example.com/schema.json"}`,
+		`{"properties": {"a": 1}}`,
+	}
+
+	for _, test := range tests {
+		if _, err := New([]byte(test)); err == nil {
+			t.Errorf("Expected %v, Found: %v", "error", err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "schema.json")
+	os.WriteFile(path, []byte(userSchema), 0644)
+
+	if _, err := Load(path); err != nil {
+		t.Errorf("Expected %v, Found: %v", nil, err)
+	}
+	if _, err := Load(path + ".missing"); err == nil {
+		t.Errorf("Expected %v, Found: %v", "error", err)
+	}
+}
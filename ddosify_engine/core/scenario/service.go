@@ -24,15 +24,19 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/proxy"
+	"go.ddosify.com/ddosify/core/scenario/data"
 	"go.ddosify.com/ddosify/core/scenario/requester"
 	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
 	"go.ddosify.com/ddosify/core/types"
@@ -48,16 +52,86 @@ type ScenarioService struct {
 	clients map[*url.URL][]scenarioItemRequester
 
 	cPool *util.Pool[*http.Client]
+	// pools the clients of the HTTP steps per host instead of cPool, nil unless ScenarioOpts.ClientPerHost is set
+	hostPool *HostClientPool
+
+	// creates the cookie jar of an iteration in distinct-user mode
+	newCookieJar func() (http.CookieJar, error)
+	// creates the client of the setup and the teardown steps in the user modes, nil otherwise
+	newOnceClient ClientFactoryMethod
 
 	scenario types.Scenario
 	ctx      context.Context
 
+	// envs of the scenario, each iteration has its own scope on top of them. Created on the first iteration.
+	globals     *globalScope
+	globalsOnce sync.Once
+
+	// ctx of the requests. Outlives ctx by the grace period, so in-flight requests are drained on stop.
+	// Same as ctx if there is no grace period.
+	reqCtx    context.Context
+	reqCancel context.CancelFunc
+
 	clientMutex sync.Mutex
 	debug       bool
 	engineMode  string
+	noProxy     []string
+	// opens a new connection for each request, pooled clients are used once
+	disableKeepAlive bool
+	requestIDHeader  string
+	successWhen      string
+	sampleBodyBytes  int64
+	traceparent      bool
+	transport        types.TransportConf
+	captureCert      bool
+	reqInterceptors  []types.RequestInterceptor
+	resInterceptors  []types.ResponseInterceptor
+	// rotates the User-Agent headers of the HTTP steps, nil if there are no user agents
+	userAgents *userAgents
+	// iteration i is run by the virtual user i % stickyUsers, which always uses the same client of the pool
+	stickyUsers int
+	// serializes the iterations of each sticky user, its client is not shared by the concurrent iterations. So at
+	// most stickyUsers iterations send their steps at a time, the others wait for their users.
+	stickyMu []sync.Mutex
+	// iterations wait for a client at the capacity of cPool, see ScenarioOpts.CapClientPool
+	capClientPool bool
+	// capacity of cPool, set once its live clients exceed it
+	exceededPoolCap int32
+	iterations      uint64
+	// numbers the pooled clients of the repeated users that are not sticky
+	clientUsers clientUsers
+	// derives the random stream of each iteration from the seed
+	rng *util.RandFactory
+	// dials the connections of the steps, nil if neither dns caching nor resolve entries are set
+	dialer *requester.Dialer
+	// counts the connections dialed by the steps, nil if they are not counted
+	connTracker *requester.ConnTracker
+	// limits the open connections of the steps per address, nil if they are not limited
+	connLimiter *requester.ConnLimiter
+	// cache validators of the virtual users, nil if revalidation is disabled
+	validators *types.ValidatorStore
+	// paces the requests of all the iterations, nil if there is no rps limit
+	limiter *util.RateLimiter
+	// latencies are measured from the times the requests are ready to be sent, including the wait for the limiter
+	correctOmission bool
+	// requests of the whole run are limited to maxRequests if it is not zero, maxRequestsReached is closed once
+	// they are sent
+	maxRequests        int64
+	sentRequests       int64
+	maxRequestsReached chan struct{}
+	// max pause of a virtual user for the Retry-After of its throttled response, not paused if zero
+	honorRetryAfter time.Duration
 
-	ei        *injection.EnvironmentInjector
-	iterIndex int64
+	ei         *injection.EnvironmentInjector
+	feeders    map[string]*data.DataFeeder
+	feederKeys []string // sorted, so the random rows are drawn in the same order in each iteration
+
+	// picks the weighted scenario of an iteration, nil if the scenario has no weighted scenarios
+	picker *weightedPicker
+	// indexes of the requesters of each weighted scenario in running order
+	weightedSteps [][]int
+	// runs the steps of the iterations by their dependencies, nil if the steps run in order
+	graph *stepGraph
 }
 
 // NewScenarioService is the constructor of the ScenarioService.
@@ -71,6 +145,44 @@ type ScenarioOpts struct {
 	MaxConcurrentIterCount int
 	EngineMode             string
 	InitialCookies         []*http.Cookie
+	NoProxy                []string            // targets that bypass the proxies
+	Seed                   int64               // seed of the random streams of the iterations, random if zero
+	DNSCacheTTL            time.Duration       // resolved addresses of the hosts are cached for the ttl, disabled if zero
+	Resolve                map[string]string   // host:port -> ip, pinned addresses that bypass dns
+	SourceAddrs            []net.IP            // local addresses of the connections, used in round-robin order
+	GracePeriod            time.Duration       // max wait for the in-flight requests after ctx is done
+	RequestCtx             context.Context     // parent of the requests outliving ctx by the grace period, Background if nil
+	RPS                    int                 // max requests per second of all the iterations, unlimited if zero
+	CorrectOmission        bool                // measures the latencies including the wait for the RPS limit
+	MaxRequests            int64               // max requests of the whole run, unlimited if zero
+	HonorRetryAfter        time.Duration       // max pause for the Retry-After of the throttled responses, see Hammer
+	DisableKeepAlive       bool                // opens a new connection for each request
+	Revalidate             bool                // users revalidate the responses by the conditional requests
+	Transport              types.TransportConf // connection limits of the transports of the HTTP steps
+	RequestIDHeader        string              // header carrying the unique id of each request, not sent if empty
+	SuccessWhen            string              // assertion expression of the successful HTTP responses, see Hammer
+	SampleBodyBytes        int64               // bytes of the response bodies kept for the failure samples
+	Traceparent            bool                // sends the traceparent header of the unique id of each request
+	StickyUsers            int                 // number of the virtual users pinned to their clients, disabled if zero
+	CapClientPool          bool                // iterations wait for a pooled client instead of exceeding the capacity
+	AdaptiveClientPool     bool                // sizes the idle clients of the pool by the demand, see Hammer
+	ClientPerHost          bool                // pools the clients of the HTTP steps per host, see Hammer
+	ClientTTL              time.Duration       // pooled clients older than it are closed and replaced, see Hammer
+	ClientHealthCheck      time.Duration       // idle clients idle longer than it are probed before reuse, see Hammer
+	CaptureCert            bool                // captures the peer certificates of the TLS connections
+	PreWarm                bool                // opens the connections of the initial clients to the targets in Init
+	ClientFactory          ClientFactoryMethod // creates the clients of the user modes instead of the default ones
+	UserAgents             types.UserAgentConf // User-Agent headers rotated over the requests or the users
+
+	// called with the requests and the responses of the HTTP steps, see Hammer.RequestInterceptors
+	RequestInterceptors  []types.RequestInterceptor
+	ResponseInterceptors []types.ResponseInterceptor
+
+	// counts the open connections of the steps per address, not counted if nil
+	ConnTracker *requester.ConnTracker
+
+	// limits the open connections of the steps per address, not limited if nil
+	ConnLimiter *requester.ConnLimiter
 }
 
 // Init initializes the ScenarioService.clients with the given types.Scenario and proxies.
@@ -79,11 +191,65 @@ func (s *ScenarioService) Init(ctx context.Context, scenario types.Scenario,
 	proxies []*url.URL, opts ScenarioOpts) (err error) {
 	s.scenario = scenario
 	s.ctx = ctx
+	s.reqCtx, s.reqCancel = ctx, func() {}
+	if opts.GracePeriod > 0 {
+		parent := opts.RequestCtx
+		if parent == nil {
+			parent = context.Background()
+		}
+		s.reqCtx, s.reqCancel = context.WithCancel(parent)
+		go s.cancelRequestsAfter(opts.GracePeriod)
+	}
 	s.debug = opts.Debug
+	s.noProxy = opts.NoProxy
+	s.disableKeepAlive = opts.DisableKeepAlive
+	s.requestIDHeader = opts.RequestIDHeader
+	s.successWhen = opts.SuccessWhen
+	s.sampleBodyBytes = opts.SampleBodyBytes
+	if s.successWhen != "" {
+		if err = parseExpression(s.successWhen); err != nil {
+			return fmt.Errorf("invalid success_when: %v", err)
+		}
+	}
+	s.traceparent = opts.Traceparent
+	s.transport = opts.Transport
+	s.stickyUsers = opts.StickyUsers
+	s.stickyMu = make([]sync.Mutex, opts.StickyUsers)
+	s.capClientPool = opts.CapClientPool
+	s.captureCert = opts.CaptureCert
+	s.reqInterceptors = opts.RequestInterceptors
+	s.resInterceptors = opts.ResponseInterceptors
+	s.userAgents = newUserAgents(opts.UserAgents)
+	s.rng = util.NewRandFactory(opts.Seed)
+	if opts.RPS > 0 {
+		s.limiter = util.NewRateLimiter(opts.RPS)
+		s.correctOmission = opts.CorrectOmission
+	}
+	if opts.MaxRequests > 0 {
+		s.maxRequests = opts.MaxRequests
+		s.maxRequestsReached = make(chan struct{})
+	}
+	s.honorRetryAfter = opts.HonorRetryAfter
+	if opts.DNSCacheTTL > 0 || len(opts.Resolve) > 0 || len(opts.SourceAddrs) > 0 || opts.Transport.KeepAlivePing > 0 {
+		s.dialer = requester.NewDialer(opts.DNSCacheTTL, opts.Resolve, opts.SourceAddrs)
+		if opts.Transport.KeepAlivePing > 0 {
+			s.dialer.SetKeepAlive(opts.Transport.KeepAlivePing)
+		}
+	}
+	s.connTracker = opts.ConnTracker
+	s.connLimiter = opts.ConnLimiter
+	if opts.Revalidate {
+		s.validators = types.NewValidatorStore()
+	}
 	s.clients = make(map[*url.URL][]scenarioItemRequester, len(proxies))
 
+	// the iterations draw from an injector derived from their own stream, the seeded one is left to the setup and
+	// teardown steps
 	ei := &injection.EnvironmentInjector{}
 	ei.Init()
+	if opts.Seed != 0 {
+		ei.Seed(s.rng.Stream("once-injection", 0).Int63())
+	}
 	s.ei = ei
 
 	for _, p := range proxies {
@@ -92,11 +258,20 @@ func (s *ScenarioService) Init(ctx context.Context, scenario types.Scenario,
 			return
 		}
 	}
-	vi := &injection.EnvironmentInjector{}
-	vi.Init()
-	s.ei = vi
 	s.engineMode = opts.EngineMode
 
+	s.initWeightedScenarios()
+	if scenario.HasDependencies() {
+		s.graph = newStepGraph(scenario.Steps)
+	}
+
+	s.feeders = make(map[string]*data.DataFeeder, len(scenario.Data))
+	for key, csvData := range scenario.Data {
+		s.feeders[key] = data.NewDataFeeder(csvData)
+		s.feederKeys = append(s.feederKeys, key)
+	}
+	sort.Strings(s.feederKeys)
+
 	if s.engineInUserMode() {
 		// create client pool
 		var initialCount int
@@ -112,15 +287,259 @@ func (s *ScenarioService) Init(ctx context.Context, scenario types.Scenario,
 			initialCount = opts.MaxConcurrentIterCount
 			maxCount = opts.IterationCount
 		}
-		s.cPool, err = NewClientPool(initialCount, maxCount, s.engineMode, putInitialCookiesInJarFactory(s.engineMode, opts.InitialCookies), func(c *http.Client) { c.CloseIdleConnections() })
+		factory := putInitialCookiesInJarFactory(s.engineMode, opts.InitialCookies)
+		s.newCookieJar = func() (http.CookieJar, error) {
+			return createCookieJar(s.engineMode, setInitialCookies(opts.InitialCookies))
+		}
+		if opts.ClientFactory != nil {
+			// transports of the given clients are kept as is, even for the h2c steps
+			factory = withCookieJar(opts.ClientFactory, s.newCookieJar)
+		} else if onlyH2CSteps(scenario) {
+			factory = withH2C(factory, s.dialContext(), s.transport.KeepAlivePing)
+		}
+		s.newOnceClient = factory
+		if s.stickyUsers > 0 {
+			// clients are created per sticky slot, idle clients are not used
+			initialCount = 0
+		}
+		closeClient := func(c *http.Client) {
+			c.CloseIdleConnections()
+			if s.validators != nil {
+				s.validators.Forget(c)
+			}
+		}
+		singleUse := opts.DisableKeepAlive && s.engineMode != types.EngineModeRepeatedUser
+		if opts.ClientPerHost {
+			s.hostPool, err = NewHostClientPool(initialCount, maxCount, s.engineMode, factory, closeClient)
+			if err == nil {
+				warmers := s.warmers()
+				s.hostPool.TTL = opts.ClientTTL
+				s.hostPool.Configure = func(host string, p *util.Pool[*http.Client]) {
+					p.SingleUse = singleUse
+					if opts.ClientHealthCheck > 0 {
+						// the clients of a host are probed against the steps of the host only
+						setIdleHealthCheck(p, opts.ClientHealthCheck, probe(warmers[host]))
+					}
+				}
+			}
+		} else if opts.ClientTTL > 0 {
+			s.cPool, err = NewClientPoolWithTTL(initialCount, maxCount, opts.ClientTTL, s.engineMode, factory, closeClient)
+			if err == nil && opts.AdaptiveClientPool {
+				// sized like the pool of NewAdaptiveClientPool
+				s.cPool.Adaptive = &util.AdaptiveCap{Min: initialCount}
+			}
+		} else if opts.AdaptiveClientPool {
+			s.cPool, err = NewAdaptiveClientPool(initialCount, maxCount, s.engineMode, factory, closeClient)
+		} else {
+			s.cPool, err = NewClientPool(initialCount, maxCount, s.engineMode, factory, closeClient)
+		}
+		if err == nil && s.cPool != nil {
+			s.cPool.StickySlots = s.stickyUsers
+			s.cPool.OnCapExceeded = func(live, capacity int) { atomic.StoreInt32(&s.exceededPoolCap, int32(capacity)) }
+		}
+		if err == nil && s.cPool != nil {
+			// clients of the repeated users are kept for their cookies, their connections are not reused either
+			s.cPool.SingleUse = singleUse
+			if opts.ClientHealthCheck > 0 {
+				var warmers []requester.Warmer
+				for _, ws := range s.warmers() {
+					warmers = append(warmers, ws...)
+				}
+				setIdleHealthCheck(s.cPool, opts.ClientHealthCheck, probe(warmers))
+			}
+		}
 	}
 	// s.cPool will be nil otherwise
 
+	if err == nil && opts.PreWarm && !opts.Debug {
+		s.preWarm(opts.MaxConcurrentIterCount)
+	}
+
 	return
 }
 
+// max number of the clients warmed concurrently by preWarm
+const preWarmParallel = 100
+
+// preWarm opens the connections of the idle clients of the pool to the hosts of the HTTP steps, so the first
+// requests of the test don't pay for the handshakes. If the clients are pooled per host, the idle clients of the
+// pool of each host are warmed to their host only. In the ddosify mode the steps have their own clients, up to
+// conns connections of each one are opened concurrently, at most preWarmParallel of them. Failures are ignored,
+// the requests of the test report them.
+func (s *ScenarioService) preWarm(conns int) {
+	hostWarmers := s.warmers()
+	var warmers []requester.Warmer
+	for _, ws := range hostWarmers {
+		warmers = append(warmers, ws...)
+	}
+	if len(warmers) == 0 {
+		return
+	}
+
+	if s.hostPool != nil {
+		for host, ws := range hostWarmers {
+			s.hostPool.poolOf(host).Warm(preWarmParallel, func(c *http.Client) {
+				for _, w := range ws {
+					w.Warm(c)
+				}
+			})
+		}
+		return
+	}
+
+	if s.cPool != nil {
+		s.cPool.Warm(preWarmParallel, func(c *http.Client) {
+			for _, w := range warmers {
+				w.Warm(c)
+			}
+		})
+		return
+	}
+
+	if conns > preWarmParallel {
+		conns = preWarmParallel
+	}
+	var wg sync.WaitGroup
+	for _, w := range warmers {
+		for i := 0; i < conns; i++ {
+			wg.Add(1)
+			go func(w requester.Warmer) {
+				defer wg.Done()
+				w.Warm(nil)
+			}(w)
+		}
+		wg.Wait() // concurrent requests of a step open separate connections
+	}
+}
+
+// warmers returns the requesters of the steps that can open their connections, by the hosts of the steps.
+func (s *ScenarioService) warmers() map[string][]requester.Warmer {
+	warmers := make(map[string][]requester.Warmer)
+	for _, requesters := range s.clients {
+		for _, sr := range requesters {
+			rs := []requester.Requester{sr.requester}
+			if sr.targets != nil {
+				rs = sr.targets.requesters
+			}
+			for _, r := range rs {
+				if w, ok := r.(requester.Warmer); ok {
+					warmers[sr.host] = append(warmers[sr.host], w)
+				}
+			}
+		}
+	}
+	return warmers
+}
+
+// probe returns the health check of the pooled clients, a client is healthy if it reaches the targets of all the
+// given warmers by a HEAD request. The response statuses are not checked, only the connection errors fail it.
+func probe(warmers []requester.Warmer) func(*http.Client) bool {
+	return func(c *http.Client) bool {
+		for _, w := range warmers {
+			if w.Warm(c) != nil {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// cancelRequestsAfter cancels the in-flight requests when the grace period is passed after ctx is done.
+func (s *ScenarioService) cancelRequestsAfter(grace time.Duration) {
+	select {
+	case <-s.ctx.Done():
+	case <-s.reqCtx.Done(): // service is done
+		return
+	}
+
+	t := time.NewTimer(grace)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		s.reqCancel()
+	case <-s.reqCtx.Done():
+	}
+}
+
+// dialContext returns the dial function of the steps, nil if the default dialer of the transports should be used.
+func (s *ScenarioService) dialContext() requester.DialContextFunc {
+	var dial requester.DialContextFunc
+	if s.dialer != nil {
+		dial = s.dialer.DialContext
+	}
+	if s.connTracker != nil {
+		dial = s.connTracker.Wrap(dial)
+	}
+	if s.connLimiter != nil {
+		// outermost, so the requests see the connections of the limiter, see requester.ConnLimiter
+		dial = s.connLimiter.Wrap(dial)
+	}
+	return dial
+}
+
+// initWeightedScenarios creates the picker of the weighted scenarios. Requesters are created in the order of
+// the scenario steps, so the steps of each weighted scenario are mapped to the requester indexes.
+func (s *ScenarioService) initWeightedScenarios() {
+	if len(s.scenario.WeightedScenarios) == 0 {
+		return
+	}
+
+	stepIndexes := make(map[uint16]int, len(s.scenario.Steps))
+	for i, si := range s.scenario.Steps {
+		stepIndexes[si.ID] = i
+	}
+
+	weights := make([]int, len(s.scenario.WeightedScenarios))
+	s.weightedSteps = make([][]int, len(s.scenario.WeightedScenarios))
+	for i, ws := range s.scenario.WeightedScenarios {
+		weights[i] = ws.Weight
+		for _, id := range ws.StepIDs {
+			s.weightedSteps[i] = append(s.weightedSteps[i], stepIndexes[id])
+		}
+	}
+	s.picker = newWeightedPicker(weights)
+}
+
+// pickRequesters returns the requesters of the weighted scenario picked for the iteration.
+// Returns all the requesters if the scenario has no weighted scenarios.
+func (s *ScenarioService) pickRequesters(requesters []scenarioItemRequester,
+	rnd *rand.Rand) []scenarioItemRequester {
+	if s.picker == nil {
+		return requesters
+	}
+
+	indexes := s.weightedSteps[s.picker.pick(rnd)]
+	picked := make([]scenarioItemRequester, 0, len(indexes))
+	for _, i := range indexes {
+		picked = append(picked, requesters[i])
+	}
+	return picked
+}
+
+// onlyH2CSteps returns true if all the HTTP steps of the scenario use the h2c protocol, so the pooled clients
+// can be created with the h2c transport directly. Setup and teardown steps use the clients of the same factory.
+func onlyH2CSteps(scenario types.Scenario) bool {
+	found := false
+	for _, steps := range [][]types.ScenarioStep{scenario.AllSteps(), scenario.Setup, scenario.Teardown} {
+		for _, si := range steps {
+			if !si.IsHTTP() {
+				continue
+			}
+			if si.Protocol != types.ProtocolH2C {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
 func putInitialCookiesInJarFactory(engineMode string, initCookies []*http.Cookie) ClientFactoryMethod {
-	return createClientFactoryMethod(engineMode, func(cj http.CookieJar) {
+	return createClientFactoryMethod(engineMode, setInitialCookies(initCookies))
+}
+
+func setInitialCookies(initCookies []*http.Cookie) func(http.CookieJar) {
+	return func(cj http.CookieJar) {
 		for _, c := range initCookies {
 			var scheme string = "http"
 			if c.Secure {
@@ -129,76 +548,614 @@ func putInitialCookiesInJarFactory(engineMode string, initCookies []*http.Cookie
 			url := &url.URL{Host: c.Domain, Scheme: scheme}
 			cj.SetCookies(url, []*http.Cookie{c})
 		}
-	})
+	}
+}
+
+// UserClient is the pooled client kept by a virtual user for its lifetime, see types.Hammer.ClientAffinity.
+type UserClient struct {
+	client *http.Client
+	// set once a request of the client fails at the connection level, the client is not pooled again
+	connFailed bool
+}
+
+// AcquireUserClient takes a client of the pool for a virtual user, it's kept by the user until ReleaseUserClient.
+// Returns nil if the engine mode has no client pool.
+func (s *ScenarioService) AcquireUserClient() (*UserClient, error) {
+	if !s.engineInUserMode() || s.hostPool != nil {
+		return nil, nil
+	}
+	if s.capClientPool {
+		client, err := s.cPool.GetContext(s.ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &UserClient{client: client}, nil
+	}
+	return &UserClient{client: s.cPool.Get()}, nil
+}
+
+// ReleaseUserClient puts the client of the virtual user back to the pool on the teardown of the user.
+func (s *ScenarioService) ReleaseUserClient(u *UserClient) {
+	if u == nil {
+		return
+	}
+	if u.connFailed {
+		s.cPool.PutBad(u.client)
+	} else {
+		s.cPool.Put(u.client)
+	}
+}
+
+// stepClients are the clients of the HTTP steps of an iteration. The steps share the client of the virtual user,
+// unless the clients are pooled per host. Then a step takes a client from the pool of its host the first time the
+// host is seen in the iteration, and the following steps of the host reuse it.
+type stepClients struct {
+	client *http.Client          // client of all the steps, nil if they are pooled per host
+	pool   *HostClientPool       // nil unless the clients are pooled per host
+	take   func(cl *http.Client) // prepares the clients taken from the pool for the iteration
+
+	mu     sync.Mutex
+	hosts  map[string]*http.Client
+	broken map[string]bool // hosts whose clients failed at the connection level
+}
+
+// newHostClients returns the stepClients of an iteration over the clients pooled per host.
+func (s *ScenarioService) newHostClients() *stepClients {
+	return &stepClients{
+		pool: s.hostPool,
+		take: func(cl *http.Client) {
+			if s.engineMode != types.EngineModeDistinctUser {
+				return
+			}
+			// every iteration is a new user, pooled client should not send the cookies of the previous iteration
+			if jar, err := s.newCookieJar(); err == nil {
+				cl.Jar = jar
+			}
+			if s.validators != nil {
+				s.validators.Forget(cl)
+			}
+		},
+		hosts:  make(map[string]*http.Client),
+		broken: make(map[string]bool),
+	}
+}
+
+// of returns the client of the step, nil if the step isn't an HTTP step of the user modes.
+func (c *stepClients) of(sr scenarioItemRequester) *http.Client {
+	if c.pool == nil || sr.host == "" {
+		return c.client
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cl, ok := c.hosts[sr.host]
+	if !ok {
+		cl = c.pool.GetForHost(sr.host)
+		c.take(cl)
+		c.hosts[sr.host] = cl
+	}
+	return cl
+}
+
+// failed marks the client of the step as failed at the connection level, it is not put back to the pool.
+func (c *stepClients) failed(sr scenarioItemRequester) {
+	if c.pool == nil {
+		return
+	}
+	c.mu.Lock()
+	c.broken[sr.host] = true
+	c.mu.Unlock()
+}
+
+// release puts the clients taken from the pools of the hosts back at the end of the iteration.
+func (c *stepClients) release() {
+	for host, cl := range c.hosts {
+		if c.broken[host] {
+			c.pool.PutBadForHost(host, cl)
+		} else {
+			c.pool.PutForHost(host, cl)
+		}
+	}
 }
 
 // Do executes the scenario for the given proxy.
 // Returns "types.Response" filled by the requester of the given Proxy, injects the given startTime to the response
 // Returns error only if types.Response.Err.Type is types.ErrorProxy or types.ErrorIntented
 func (s *ScenarioService) Do(proxy *url.URL, startTime time.Time) (
+	response *types.ScenarioResult, err *types.RequestError) {
+	return s.DoAsUser(proxy, startTime, nil)
+}
+
+// DoAsUser is Do with the client kept by the virtual user, the iteration takes a client of the pool if u is nil.
+func (s *ScenarioService) DoAsUser(proxy *url.URL, startTime time.Time, u *UserClient) (
 	response *types.ScenarioResult, err *types.RequestError) {
 	response = &types.ScenarioResult{StepResults: []*types.ScenarioStepResult{}}
 	response.StartTime = startTime
 	response.ProxyAddr = proxy
-	rand.Seed(time.Now().UnixNano())
+
+	// random stream of the iteration, the same seed gives the same picks, rows, sleeps and jitters
+	iter := atomic.AddUint64(&s.iterations, 1) - 1
+	rnd := s.rng.Stream("iteration", iter)
 
 	requesters, e := s.getOrCreateRequesters(proxy)
 	if e != nil {
 		return nil, &types.RequestError{Type: types.ErrorUnkown, Reason: e.Error()}
 	}
+	// requesters of the cleanup steps follow the ones of the steps, see createRequesters
+	cleanups := requesters[len(s.scenario.Steps):]
+	requesters = s.pickRequesters(requesters[:len(s.scenario.Steps)], rnd)
 
-	// start envs separately for each iteration
-	envs := make(map[string]interface{}, len(s.scenario.Envs))
-	for k, v := range s.scenario.Envs {
-		envs[k] = v
-	}
-	// inject dynamic variables beforehand for each iteration
-	injectDynamicVars(s.ei, envs)
+	// each iteration starts with a fresh scope, captures of the other iterations are never seen
+	s.globalsOnce.Do(func() { s.globals = newGlobalScope(s.scenario.Envs) })
+	scope := s.globals.newIterationScope(s.iterationInjector(iter))
 	// pass a row from data for each iteration
-	s.enrichEnvFromData(envs)
-	atomic.AddInt64(&s.iterIndex, 1)
+	if e := s.enrichEnvFromData(scope, rnd); e != nil {
+		// the steps would be sent without the variables of the data, they fail without being sent
+		for _, sr := range requesters {
+			response.StepResults = append(response.StepResults, notSent(sr, e))
+		}
+		return
+	}
 
+	// every iteration is a new user, unless the user keeps its client for the whole run
+	vu := iter
 	var client *http.Client
-	if s.engineInUserMode() {
-		// get client from pool
-		client = s.cPool.Get()
-		defer s.cPool.Put(client)
+	var connFailed bool // client is not put back to the pool if any of its requests failed at the connection level
+	var clients *stepClients
+	if s.engineInUserMode() && s.stickyUsers > 0 {
+		vu = iter % uint64(s.stickyUsers)
+		// the steps update the timeout and the transport of the client, the iterations of the user run in turn
+		s.stickyMu[vu].Lock()
+		defer s.stickyMu[vu].Unlock()
+		client = s.cPool.GetSticky(int(vu))
+	} else if s.hostPool != nil {
+		// the steps take the clients of their hosts, put back at the end of the iteration
+		clients = s.newHostClients()
+		defer clients.release()
+	} else if s.engineInUserMode() {
+		if u != nil {
+			// the user keeps its client, it's put back to the pool by ReleaseUserClient
+			client = u.client
+			defer func() { u.connFailed = u.connFailed || connFailed }()
+		} else {
+			// get client from pool
+			if s.capClientPool {
+				if client, e = s.cPool.GetContext(s.ctx); e != nil {
+					return nil, &types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled}
+				}
+			} else {
+				client = s.cPool.Get()
+			}
+			defer func() {
+				if connFailed {
+					s.cPool.PutBad(client)
+				} else {
+					s.cPool.Put(client)
+				}
+			}()
+		}
+
+		if s.engineMode == types.EngineModeDistinctUser {
+			// every iteration is a new user, pooled client should not send the cookies of the previous iteration
+			if jar, err := s.newCookieJar(); err == nil {
+				client.Jar = jar
+			}
+			if s.validators != nil {
+				s.validators.Forget(client)
+			}
+		} else {
+			vu = s.clientUsers.indexOf(client)
+		}
+	}
+	if clients == nil {
+		clients = &stepClients{client: client}
+	}
+	newVirtualUser(s.rng, vu).setEnvs(scope)
+	if s.userAgents != nil && s.userAgents.perUser {
+		scope.set(userAgentEnv, s.userAgents.ofUser(s.rng, vu))
 	}
 
+	// cleanup steps run unless the iteration is stopped before its steps
+	cleanup := len(cleanups) > 0 && s.ctx.Err() == nil
+	if s.graph != nil {
+		err, connFailed = s.doGraph(requesters, iter, scope, clients, response)
+	} else {
+		err, connFailed = s.doSteps(requesters, rnd, scope, clients, response)
+	}
+	if cleanup && s.doCleanup(cleanups, rnd, scope, clients, response) {
+		connFailed = true
+	}
+	return
+}
+
+// doSteps runs the steps of the iteration in order, each step sees the captures of the previous ones.
+func (s *ScenarioService) doSteps(requesters []scenarioItemRequester, rnd *rand.Rand, scope *iterationScope,
+	clients *stepClients, response *types.ScenarioResult) (err *types.RequestError, connFailed bool) {
+	var prev *types.ScenarioStepResult // result of the last sent step
 	for _, sr := range requesters {
-		var res *types.ScenarioStepResult
-		switch sr.requester.Type() {
-		case "HTTP":
-			httpRequester := sr.requester.(requester.HttpRequesterI)
-			res = httpRequester.Send(client, envs)
-		default:
-			res = &types.ScenarioStepResult{Err: types.RequestError{Type: fmt.Sprintf("type not defined: %s", sr.requester.Type())}}
+		if s.ctx.Err() != nil {
+			// stopped, don't send the remaining steps. Steps completed until now are reported.
+			if len(response.StepResults) == 0 {
+				err = &types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled}
+			}
+			return
+		}
+
+		if sr.condition != nil && !sr.condition.met(prev, scope.envs()) {
+			skipped := sr.condition.skipped()
+			skipped.Tags = sr.tags
+			response.StepResults = append(response.StepResults, skipped)
+			continue
+		}
+
+		res, copies := s.sendCopies(sr, rnd, clients.of(sr), func(userAgent string) map[string]interface{} {
+			if userAgent != "" {
+				scope.set(userAgentEnv, userAgent)
+			}
+			return scope.envs()
+		})
+		if res.Err.Reason == types.ReasonMaxRequests {
+			// the requests of the run are sent, the steps completed until now are reported
+			response.StepResults = append(response.StepResults, copies...)
+			if len(response.StepResults) == 0 {
+				err = &res.Err
+			}
+			return
+		}
+		res.ErrCategory = res.Categorize()
+		res.Tags = sr.tags
+		if res.Err.ConnFailed() && sr.requester.Type() == "HTTP" || copiesConnFailed(sr, copies) {
+			connFailed = true
+			clients.failed(sr)
 		}
 
 		if res.Err.Type == types.ErrorProxy || res.Err.Type == types.ErrorIntented {
 			err = &res.Err
 			if res.Err.Type == types.ErrorIntented {
 				// Stop the loop. ErrorProxy can be fixed in time. But ErrorIntented is a signal to stop all.
+				response.StepResults = append(response.StepResults, copies...)
 				return
 			}
 		}
 		response.StepResults = append(response.StepResults, res)
+		response.StepResults = append(response.StepResults, copies...)
+		prev = res
 
 		// Sleep before running the next step
-		if sr.sleeper != nil && len(s.scenario.Steps) > 1 {
-			sr.sleeper.sleep()
+		if sr.sleeper != nil && len(requesters) > 1 {
+			sr.sleeper.sleep(s.ctx, rnd)
 		}
 
-		enrichEnvFromPrevStep(envs, res.ExtractedEnvs)
+		scope.setAll(res.ExtractedEnvs)
+	}
+
+	return
+}
+
+// doCleanup sends the cleanup steps of the iteration in order after its steps, even if they failed or the
+// iteration is stopped. Requests of a stopped test are sent in the grace period, they are canceled without it.
+// Cleanup requests are not limited by the max requests of the run. Returns true if any of its HTTP requests failed
+// at the connection level.
+func (s *ScenarioService) doCleanup(cleanups []scenarioItemRequester, rnd *rand.Rand, scope *iterationScope,
+	clients *stepClients, response *types.ScenarioResult) (connFailed bool) {
+	var prev *types.ScenarioStepResult // result of the last sent step, the conditions are evaluated against it
+	for _, res := range response.StepResults {
+		if !res.Skipped {
+			prev = res
+		}
 	}
 
+	for _, sr := range cleanups {
+		if sr.condition != nil && !sr.condition.met(prev, scope.envs()) {
+			skipped := sr.condition.skipped()
+			skipped.Tags = sr.tags
+			response.StepResults = append(response.StepResults, skipped)
+			continue
+		}
+
+		res, copies := s.sendCopies(sr, rnd, clients.of(sr), func(userAgent string) map[string]interface{} {
+			if userAgent != "" {
+				scope.set(userAgentEnv, userAgent)
+			}
+			return scope.envs()
+		})
+		if res.Err.Type == types.ErrorIntented {
+			// requests are canceled, the remaining ones would be canceled too
+			response.StepResults = append(response.StepResults, copies...)
+			return
+		}
+		res.ErrCategory = res.Categorize()
+		res.Tags = sr.tags
+		if res.Err.ConnFailed() && sr.requester.Type() == "HTTP" || copiesConnFailed(sr, copies) {
+			connFailed = true
+			clients.failed(sr)
+		}
+		response.StepResults = append(response.StepResults, res)
+		response.StepResults = append(response.StepResults, copies...)
+		prev = res
+
+		if sr.sleeper != nil {
+			sr.sleeper.sleep(s.ctx, rnd)
+		}
+		scope.setAll(res.ExtractedEnvs)
+	}
 	return
 }
 
-func enrichEnvFromPrevStep(m1 map[string]interface{}, m2 map[string]interface{}) {
-	for k, v := range m2 {
-		m1[k] = v
+// sendRequests sends the step by its retry policy, each request waits for the rps limit and counts in the max
+// requests of the run. envs returns the variables of a request, userAgent is its User-Agent if it is rotated per
+// request, empty otherwise.
+func (s *ScenarioService) sendRequests(sr scenarioItemRequester, rnd *rand.Rand, client *http.Client,
+	envs func(userAgent string) map[string]interface{}) *types.ScenarioStepResult {
+	send := func() *types.ScenarioStepResult {
+		var intended time.Time // time the request is ready to be sent, zero if the omission isn't corrected
+		if s.limiter != nil {
+			var e error
+			if s.correctOmission {
+				intended, e = s.limiter.WaitHeld(s.ctx)
+			} else {
+				e = s.limiter.Wait(s.ctx)
+			}
+			// cleanup steps of a stopped test are still sent
+			if e != nil && !sr.cleanup {
+				return &types.ScenarioStepResult{
+					StepID: sr.scenarioItemID,
+					Err:    types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonCtxCanceled},
+				}
+			}
+		}
+		if !sr.cleanup && !s.takeRequest() {
+			return &types.ScenarioStepResult{
+				StepID: sr.scenarioItemID,
+				Err:    types.RequestError{Type: types.ErrorIntented, Reason: types.ReasonMaxRequests},
+			}
+		}
+		var userAgent string
+		if sr.userAgent && !s.userAgents.perUser {
+			userAgent = s.userAgents.ofRequest(rnd)
+		}
+		lag := time.Since(intended)
+		var res *types.ScenarioStepResult
+		if sr.targets != nil {
+			res = sr.targets.send(rnd, client, envs(userAgent))
+		} else {
+			res = sendStep(sr.requester, client, envs(userAgent))
+		}
+		if !intended.IsZero() && lag > 0 {
+			// the request is held back by the rps limit, its latency counts the delay
+			res.ScheduleLag = lag
+			res.Duration += lag
+		}
+		s.pauseThrottled(res)
+		return res
+	}
+	if sr.retry != nil {
+		return sr.retry.do(s.ctx, rnd, send)
+	}
+	return send()
+}
+
+// pauseThrottled pauses the virtual user of the throttled result for its Retry-After delay, up to the
+// honorRetryAfter. Its next request, a retry or the next step, is sent after the pause.
+func (s *ScenarioService) pauseThrottled(res *types.ScenarioStepResult) {
+	if s.honorRetryAfter == 0 || !res.Throttled || res.RetryAfter <= 0 {
+		return
 	}
+	d := res.RetryAfter
+	if d > s.honorRetryAfter {
+		d = s.honorRetryAfter
+	}
+	sleepContext(s.ctx, d)
+}
+
+// sendCopies sends the parallel copies of the step concurrently by sendRequests, each copy draws from its own
+// random stream derived from rnd and gets the variables of the scope when the step starts. Returns the result of
+// the first copy, which the captures and the conditions of the following steps use, and the results of the other
+// copies that are sent. Results of the copies are categorized and tagged, the first one is left to the caller.
+func (s *ScenarioService) sendCopies(sr scenarioItemRequester, rnd *rand.Rand, client *http.Client,
+	envs func(userAgent string) map[string]interface{}) (res *types.ScenarioStepResult,
+	copies []*types.ScenarioStepResult) {
+	if sr.parallel <= 1 {
+		return s.sendRequests(sr, rnd, client, envs), nil
+	}
+
+	// envs may change the scope by the rotated User-Agent, the copies don't share it
+	copyEnvs := withUserAgent(envs(""))
+	results := make([]*types.ScenarioStepResult, sr.parallel)
+	clients := make([]*http.Client, sr.parallel)
+	owned := make([]bool, sr.parallel) // the copy has its own transport, closed after it is sent
+	clients[0] = client
+	for i := 1; i < sr.parallel; i++ {
+		// copied before any copy is sent, the requester updates the client
+		clients[i], owned[i] = parallelClient(client)
+	}
+	var wg sync.WaitGroup
+	for i := range results {
+		copyRnd := rand.New(rand.NewSource(rnd.Int63()))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = s.sendRequests(sr, copyRnd, clients[i], copyEnvs)
+			if owned[i] {
+				clients[i].CloseIdleConnections()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, c := range results[1:] {
+		if c.Err.Type == types.ErrorIntented {
+			// canceled or over the max requests, not sent
+			continue
+		}
+		c.ErrCategory = c.Categorize()
+		c.Tags = sr.tags
+		copies = append(copies, c)
+	}
+	return results[0], copies
+}
+
+// parallelClient returns the client of a parallel copy of the step. The client of the iteration is updated for
+// each step by the requester and uses one connection per host, so the copy gets a shallow copy of it that keeps
+// its jar over a clone of its transport. Custom transports of the client factory are shared as they are.
+// Returns true if the copy has a transport of its own.
+func parallelClient(client *http.Client) (*http.Client, bool) {
+	if client == nil {
+		// engine mode is 'ddosify', the requester's own client is used
+		return nil, false
+	}
+	c := *client
+	switch tr := client.Transport.(type) {
+	case nil:
+		// first step of the iteration, the requester sets its transport
+		return &c, true
+	case *http.Transport:
+		tr = tr.Clone()
+		tr.MaxConnsPerHost = 0
+		c.Transport = tr
+		return &c, true
+	}
+	return &c, false
+}
+
+// copiesConnFailed returns true if any of the copies of the HTTP step failed at the connection level.
+func copiesConnFailed(sr scenarioItemRequester, copies []*types.ScenarioStepResult) bool {
+	if sr.requester.Type() != "HTTP" {
+		return false
+	}
+	for _, c := range copies {
+		if c.Err.ConnFailed() {
+			return true
+		}
+	}
+	return false
+}
+
+// iterationInjector returns the injector of the random values of the iteration, drawing from its own stream.
+func (s *ScenarioService) iterationInjector(iter uint64) *injection.EnvironmentInjector {
+	if s.ei == nil {
+		// the requesters are created without injectors, like the mocks of the tests
+		return nil
+	}
+	return s.ei.Derive(s.rng.Stream("injection", iter))
+}
+
+// withUserAgent returns the envs func of a request that doesn't change vars, the rotated User-Agent is added to
+// a copy of them.
+func withUserAgent(vars map[string]interface{}) func(userAgent string) map[string]interface{} {
+	return func(userAgent string) map[string]interface{} {
+		if userAgent == "" {
+			return vars
+		}
+		envs := make(map[string]interface{}, len(vars)+1)
+		for k, v := range vars {
+			envs[k] = v
+		}
+		envs[userAgentEnv] = userAgent
+		return envs
+	}
+}
+
+// sendStep sends the step by the given requester, client is used only by the HTTP requester.
+// The injector of the iteration is removed from the envs of the result.
+func sendStep(r requester.Requester, client *http.Client, envs map[string]interface{}) (
+	res *types.ScenarioStepResult) {
+	switch r.Type() {
+	case "HTTP":
+		httpRequester := r.(requester.HttpRequesterI)
+		res = httpRequester.Send(client, envs)
+	case "GRPC":
+		grpcRequester := r.(requester.GrpcRequesterI)
+		res = grpcRequester.Send(envs)
+	case "WEBSOCKET":
+		wsRequester := r.(requester.WebSocketRequesterI)
+		res = wsRequester.Send(envs)
+	case "SOCKET":
+		socketRequester := r.(requester.SocketRequesterI)
+		res = socketRequester.Send(envs)
+	case "DNS":
+		dnsRequester := r.(requester.DNSRequesterI)
+		res = dnsRequester.Send(envs)
+	case "SSE":
+		sseRequester := r.(requester.SSERequesterI)
+		res = sseRequester.Send(envs)
+	default:
+		return &types.ScenarioStepResult{Err: types.RequestError{Type: fmt.Sprintf("type not defined: %s", r.Type())}}
+	}
+	delete(res.UsableEnvs, injection.InjectorEnv)
+	return res
+}
+
+// ClientPoolWarning returns the warning about the live clients exceeding the capacity of the client pool,
+// empty if the capacity is not exceeded. Exceeding is reported only if the capacity is not enforced by CapClientPool.
+func (s *ScenarioService) ClientPoolWarning() string {
+	capacity := atomic.LoadInt32(&s.exceededPoolCap)
+	if s.cPool == nil || capacity == 0 {
+		return ""
+	}
+	return fmt.Sprintf("live clients exceeded the client pool capacity of %d clients, up to %d clients were live. "+
+		"Set cap_client_pool to limit the concurrent clients to the capacity", capacity, s.cPool.PeakLive())
+}
+
+// PoolStats returns the statistics of the client pool of the virtual users, false if the engine mode has no pool.
+// The statistics of the pools of the hosts are summed if the clients are pooled per host. It should be called
+// after Init.
+func (s *ScenarioService) PoolStats() (util.PoolStats, bool) {
+	if s.hostPool != nil {
+		var sum util.PoolStats
+		for _, st := range s.hostPool.Stats() {
+			sum.Idle += st.Idle
+			sum.InUse += st.InUse
+			sum.Created += st.Created
+			sum.Reused += st.Reused
+			sum.ClosedFull += st.ClosedFull
+			sum.ClosedBad += st.ClosedBad
+			sum.MaxCap += st.MaxCap
+			sum.IdleCap += st.IdleCap
+			sum.Waits += st.Waits
+			sum.WaitTime += st.WaitTime
+		}
+		return sum, true
+	}
+	if s.cPool == nil {
+		return util.PoolStats{}, false
+	}
+	return s.cPool.Stats(), true
+}
+
+// HostPoolStats returns the statistics of the client pool of each host, nil unless the clients are pooled per host.
+// It should be called after Init.
+func (s *ScenarioService) HostPoolStats() map[string]util.PoolStats {
+	if s.hostPool == nil {
+		return nil
+	}
+	return s.hostPool.Stats()
+}
+
+// takeRequest returns false if the max requests of the run are already sent, otherwise the request is counted.
+func (s *ScenarioService) takeRequest() bool {
+	if s.maxRequests == 0 {
+		return true
+	}
+	n := atomic.AddInt64(&s.sentRequests, 1)
+	if n == s.maxRequests {
+		close(s.maxRequestsReached)
+	}
+	return n <= s.maxRequests
+}
+
+// MaxRequestsReached returns the channel closed once the max requests of the run are sent, nil if the requests
+// are unlimited.
+func (s *ScenarioService) MaxRequestsReached() <-chan struct{} {
+	return s.maxRequestsReached
+}
+
+// MaxRequestsResult returns the number of the requests sent if the run is stopped by the max requests, empty
+// otherwise.
+func (s *ScenarioService) MaxRequestsResult() string {
+	if s.maxRequests == 0 || atomic.LoadInt64(&s.sentRequests) < s.maxRequests {
+		return ""
+	}
+	return fmt.Sprintf("%d requests are sent", s.maxRequests)
 }
 
 func (s *ScenarioService) engineInUserMode() bool {
@@ -208,15 +1165,14 @@ func (s *ScenarioService) engineInUserMode() bool {
 	return false
 }
 
-func (s *ScenarioService) enrichEnvFromData(envs map[string]interface{}) {
-	var row map[string]interface{}
+// enrichEnvFromData sets the variables of the rows of the data for the iteration. Returns an error if the rows of
+// a data are used up, that is the case in sequential order unless it is circular.
+func (s *ScenarioService) enrichEnvFromData(scope *iterationScope, rnd *rand.Rand) error {
 	sb := strings.Builder{}
-	for key, csvData := range s.scenario.Data {
-		lenRows := len(csvData.Rows)
-		if csvData.Random {
-			row = csvData.Rows[rand.Intn(lenRows)]
-		} else {
-			row = csvData.Rows[s.iterIndex%int64(lenRows)]
+	for _, key := range s.feederKeys {
+		row, ok := s.feeders[key].Next(rnd)
+		if !ok {
+			return fmt.Errorf("rows of the data %s are used up, set circular to reuse them", key)
 		}
 
 		for tag, v := range row {
@@ -225,22 +1181,44 @@ func (s *ScenarioService) enrichEnvFromData(envs map[string]interface{}) {
 			sb.WriteString(".")
 			sb.WriteString(tag)
 			// data.info.name
-			envs[sb.String()] = v
+			scope.set(sb.String(), v)
 			sb.Reset()
 		}
 	}
+	return nil
+}
+
+// notSent returns the failed result of the step that is not sent because of err.
+func notSent(sr scenarioItemRequester, err error) *types.ScenarioStepResult {
+	res := &types.ScenarioStepResult{
+		StepID:      sr.scenarioItemID,
+		StepName:    sr.name,
+		RequestID:   uuid.New(),
+		RequestTime: time.Now(),
+		Err:         types.RequestError{Type: types.ErrorInvalidRequest, Reason: err.Error()},
+		Tags:        sr.tags,
+	}
+	res.ErrCategory = res.Categorize()
+	return res
 }
 
 func (s *ScenarioService) Done() {
+	if s.reqCancel != nil {
+		s.reqCancel()
+	}
+
 	for _, v := range s.clients {
 		for _, r := range v {
-			r.requester.Done()
+			r.done()
 		}
 	}
 
 	if s.cPool != nil {
 		s.cPool.Done()
 	}
+	if s.hostPool != nil {
+		s.hostPool.DoneAll()
+	}
 }
 
 func (s *ScenarioService) getOrCreateRequesters(proxy *url.URL) (requesters []scenarioItemRequester, err error) {
@@ -257,36 +1235,114 @@ func (s *ScenarioService) getOrCreateRequesters(proxy *url.URL) (requesters []sc
 	return s.clients[proxy], err
 }
 
-func (s *ScenarioService) createRequesters(proxy *url.URL) (err error) {
-	s.clients[proxy] = []scenarioItemRequester{}
-	for _, si := range s.scenario.Steps {
-		var r requester.Requester
-		r, err = requester.NewRequester(si)
-		if err != nil {
+func (s *ScenarioService) createRequesters(proxyAddr *url.URL) (err error) {
+	s.clients[proxyAddr] = []scenarioItemRequester{}
+	for i, si := range s.scenario.AllSteps() {
+		var sr scenarioItemRequester
+		if sr, err = s.newItemRequester(s.reqCtx, si, proxyAddr); err != nil {
 			return
 		}
-		s.clients[proxy] = append(
-			s.clients[proxy],
-			scenarioItemRequester{
-				scenarioItemID: si.ID,
-				sleeper:        newSleeper(si.Sleep),
-				requester:      r,
-			},
-		)
+		sr.cleanup = i >= len(s.scenario.Steps)
+		s.clients[proxyAddr] = append(s.clients[proxyAddr], sr)
+	}
+	return err
+}
 
-		switch r.Type() {
-		case "HTTP":
-			httpRequester := r.(requester.HttpRequesterI)
-			err = httpRequester.Init(s.ctx, si, proxy, s.debug, s.ei)
-		default:
-			err = fmt.Errorf("type not defined: %s", r.Type())
-		}
+// newItemRequester creates the requester of the step with the settings of the service, its requests live as long
+// as ctx.
+func (s *ScenarioService) newItemRequester(ctx context.Context, si types.ScenarioStep,
+	proxyAddr *url.URL) (sr scenarioItemRequester, err error) {
+	si.DialContext = s.dialContext()
+	si.Validators = s.validators
+	si.DisableKeepAlive = si.DisableKeepAlive || s.disableKeepAlive
+	si.RequestIDHeader = s.requestIDHeader
+	si.SuccessWhen = s.successWhen
+	si.SampleBodyBytes = s.sampleBodyBytes
+	si.Traceparent = s.traceparent
+	si.CaptureCert = s.captureCert
+	si.Transport = s.transport
+	si.RequestInterceptors = s.reqInterceptors
+	si.ResponseInterceptors = s.resInterceptors
+	userAgent := s.userAgents != nil && takesUserAgent(si)
+	if userAgent {
+		si.Headers = withUserAgentHeader(si.Headers)
+	}
 
-		if err != nil {
+	condition, err := newStepCondition(si)
+	if err != nil {
+		return
+	}
+
+	var r requester.Requester
+	var targets *stepTargets
+	if len(si.Targets) > 0 {
+		if targets, err = s.newStepTargets(ctx, si, proxyAddr); err != nil {
 			return
 		}
+		r = targets.requesters[0]
+	} else if r, err = s.initRequester(ctx, si, proxyAddr); err != nil {
+		return
 	}
-	return err
+	return scenarioItemRequester{
+		scenarioItemID: si.ID,
+		name:           si.Name,
+		sleeper:        newSleeper(si.Sleep, si.SleepDistribution),
+		retry:          newRetryPolicy(si.Retry),
+		condition:      condition,
+		tags:           si.Tags,
+		requester:      r,
+		targets:        targets,
+		userAgent:      userAgent,
+		parallel:       si.Parallel,
+		host:           stepHost(r, si),
+	}, nil
+}
+
+// stepHost returns the key of the client pool of the HTTP step by its url, empty for the other steps. The steps
+// with weighted targets are keyed by their own url.
+func stepHost(r requester.Requester, si types.ScenarioStep) string {
+	if r.Type() != "HTTP" {
+		return ""
+	}
+	return hostKey(si.URL)
+}
+
+// initRequester creates the requester of the step and initializes it, its requests live as long as ctx.
+func (s *ScenarioService) initRequester(ctx context.Context, si types.ScenarioStep,
+	proxyAddr *url.URL) (r requester.Requester, err error) {
+	r, err = requester.NewRequester(si)
+	if err != nil {
+		return
+	}
+
+	stepProxy := proxyAddr
+	if proxyAddr != nil && proxy.BypassProxy(si.URL, s.noProxy) {
+		stepProxy = nil
+	}
+
+	switch r.Type() {
+	case "HTTP":
+		httpRequester := r.(requester.HttpRequesterI)
+		err = httpRequester.Init(ctx, si, stepProxy, s.debug, s.ei)
+	case "GRPC":
+		grpcRequester := r.(requester.GrpcRequesterI)
+		err = grpcRequester.Init(ctx, si, stepProxy, s.debug, s.ei)
+	case "WEBSOCKET":
+		wsRequester := r.(requester.WebSocketRequesterI)
+		err = wsRequester.Init(ctx, si, stepProxy, s.debug, s.ei)
+	case "SOCKET":
+		socketRequester := r.(requester.SocketRequesterI)
+		err = socketRequester.Init(ctx, si, stepProxy, s.debug, s.ei)
+	case "DNS":
+		dnsRequester := r.(requester.DNSRequesterI)
+		err = dnsRequester.Init(ctx, si, stepProxy, s.debug, s.ei)
+	case "SSE":
+		sseRequester := r.(requester.SSERequesterI)
+		err = sseRequester.Init(ctx, si, stepProxy, s.debug, s.ei)
+	default:
+		err = fmt.Errorf("type not defined: %s", r.Type())
+	}
+	return
 }
 
 func injectDynamicVars(vi *injection.EnvironmentInjector, envs map[string]interface{}) {
@@ -308,13 +1364,33 @@ func injectDynamicVars(vi *injection.EnvironmentInjector, envs map[string]interf
 
 type scenarioItemRequester struct {
 	scenarioItemID uint16
+	name           string
 	sleeper        Sleeper
+	retry          *retryPolicy
+	condition      *stepCondition // nil if the step is always sent
+	tags           []string
 	requester      requester.Requester
+	targets        *stepTargets // sends to the weighted targets of the step instead of requester, nil if none
+	userAgent      bool         // sends the rotated User-Agent, see userAgents
+	cleanup        bool         // sent at the end of the iteration, see types.Scenario.Cleanup
+	parallel       int          // copies sent concurrently, see types.ScenarioStep.Parallel
+	host           string       // key of the client pool of an HTTP step by its url, see hostKey
+}
+
+// done releases the requester of the step or the requesters of its targets.
+func (sr scenarioItemRequester) done() {
+	if sr.targets != nil {
+		sr.targets.done()
+		return
+	}
+	sr.requester.Done()
 }
 
 // Sleeper is the interface for implementing different sleep strategies.
+// Implementations return early if the given ctx is done, so the shutdown of the engine is not delayed.
+// Random durations are drawn from the given rnd of the iteration.
 type Sleeper interface {
-	sleep()
+	sleep(ctx context.Context, rnd *rand.Rand)
 }
 
 // RangeSleep is the implementation of the range sleep feature
@@ -323,10 +1399,9 @@ type RangeSleep struct {
 	max int
 }
 
-func (rs *RangeSleep) sleep() {
-	rand.Seed(time.Now().UnixNano())
-	dur := rand.Intn(rs.max-rs.min+1) + rs.min
-	time.Sleep(time.Duration(dur) * time.Millisecond)
+func (rs *RangeSleep) sleep(ctx context.Context, rnd *rand.Rand) {
+	dur := rnd.Intn(rs.max-rs.min+1) + rs.min
+	sleepContext(ctx, time.Duration(dur)*time.Millisecond)
 }
 
 // DurationSleep is the implementation of the exact duration sleep feature
@@ -334,38 +1409,92 @@ type DurationSleep struct {
 	duration int
 }
 
-func (ds *DurationSleep) sleep() {
-	time.Sleep(time.Duration(ds.duration) * time.Millisecond)
+func (ds *DurationSleep) sleep(ctx context.Context, rnd *rand.Rand) {
+	sleepContext(ctx, time.Duration(ds.duration)*time.Millisecond)
+}
+
+// Sampled sleep durations are clamped to the max sleep of a step
+const maxSampledSleep = 90 * time.Second
+
+// ExponentialSleep is the implementation of the exponentially distributed sleep durations, like the think times
+// of the real users
+type ExponentialSleep struct {
+	mean int // in ms
+}
+
+func (es *ExponentialSleep) sleep(ctx context.Context, rnd *rand.Rand) {
+	sleepContext(ctx, es.sample(rnd))
+}
+
+func (es *ExponentialSleep) sample(rnd *rand.Rand) time.Duration {
+	return clampSleep(rnd.ExpFloat64() * float64(es.mean))
+}
+
+// NormalSleep is the implementation of the normally distributed sleep durations
+type NormalSleep struct {
+	mean   int // in ms
+	stdDev int
+}
+
+func (ns *NormalSleep) sleep(ctx context.Context, rnd *rand.Rand) {
+	sleepContext(ctx, ns.sample(rnd))
+}
+
+func (ns *NormalSleep) sample(rnd *rand.Rand) time.Duration {
+	return clampSleep(rnd.NormFloat64()*float64(ns.stdDev) + float64(ns.mean))
+}
+
+// clampSleep converts the sampled ms to a duration in [0, maxSampledSleep].
+func clampSleep(ms float64) time.Duration {
+	d := time.Duration(ms * float64(time.Millisecond))
+	if d < 0 {
+		return 0
+	}
+	if d > maxSampledSleep {
+		return maxSampledSleep
+	}
+	return d
+}
+
+// sleepContext waits for the given duration or until the ctx is done. Returns false if the ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if ctx == nil {
+		time.Sleep(d)
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // newSleeper is the factor method for the Sleeper implementations.
-func newSleeper(sleepStr string) Sleeper {
+func newSleeper(sleepStr string, dist types.SleepDistribution) Sleeper {
+	switch dist.Type {
+	case types.SleepDistExponential:
+		return &ExponentialSleep{mean: dist.Mean}
+	case types.SleepDistNormal:
+		return &NormalSleep{mean: dist.Mean, stdDev: dist.StdDev}
+	}
 	if sleepStr == "" {
 		return nil
 	}
 
-	var sl Sleeper
-
 	// Sleep field already validated in types.scenario.validate(). No need to check parsing errors here.
-	s := strings.Split(sleepStr, "-")
-	if len(s) == 2 {
-		min, _ := strconv.Atoi(s[0])
-		max, _ := strconv.Atoi(s[1])
-		if min > max {
-			min, max = max, min
-		}
-
-		sl = &RangeSleep{
+	min, max, _ := types.ParseSleep(sleepStr)
+	if min != max {
+		return &RangeSleep{
 			min: min,
 			max: max,
 		}
-	} else {
-		dur, _ := strconv.Atoi(s[0])
-
-		sl = &DurationSleep{
-			duration: dur,
-		}
 	}
 
-	return sl
+	return &DurationSleep{
+		duration: min,
+	}
 }
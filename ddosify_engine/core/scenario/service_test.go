@@ -23,15 +23,24 @@ package scenario
 import (
 	"context"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"go.ddosify.com/ddosify/core/scenario/requester"
 	"go.ddosify.com/ddosify/core/scenario/scripting/injection"
 	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
 )
 
 type MockHttpRequester struct {
@@ -73,7 +82,7 @@ type MockSleep struct {
 	SleepCallCount int
 }
 
-func (msl *MockSleep) sleep() {
+func (msl *MockSleep) sleep(ctx context.Context, rnd *rand.Rand) {
 	msl.SleepCalled = true
 	msl.SleepCallCount++
 }
@@ -602,7 +611,7 @@ func TestCreateRequestersErrorOnRequesterInit(t *testing.T) {
 	}
 }
 
-func TestnewSleeper(t *testing.T) {
+func TestNewSleeper(t *testing.T) {
 	t.Parallel()
 
 	sleepRange := "300-500"
@@ -618,17 +627,17 @@ func TestnewSleeper(t *testing.T) {
 	}
 
 	// "range" sleep strategy test
-	sleep := newSleeper(sleepRange)
+	sleep := newSleeper(sleepRange, types.SleepDistribution{})
 	if !reflect.DeepEqual(sleep, expectedSleepRange) {
 		t.Errorf("Expected %v, Found: %v", expectedSleepRange, sleep)
 	}
-	sleep = newSleeper(sleepRangeReverse)
+	sleep = newSleeper(sleepRangeReverse, types.SleepDistribution{})
 	if !reflect.DeepEqual(sleep, expectedSleepRange) {
 		t.Errorf("Expected %v, Found: %v", expectedSleepRange, sleep)
 	}
 
 	// "duration" sleep strategy test
-	sleep = newSleeper(sleepDuration)
+	sleep = newSleeper(sleepDuration, types.SleepDistribution{})
 	if !reflect.DeepEqual(sleep, exptectedSleepDuration) {
 		t.Errorf("Expected %v, Found: %v", exptectedSleepDuration, sleep)
 	}
@@ -660,7 +669,7 @@ func TestSleep(t *testing.T) {
 
 	// Test range
 	start := time.Now()
-	sleepRange.sleep()
+	sleepRange.sleep(context.TODO(), rand.New(rand.NewSource(1)))
 	elapsed := time.Duration(time.Since(start) / time.Millisecond)
 	if elapsed > time.Duration(max)+delta || elapsed < time.Duration(min)-delta {
 		t.Errorf("Expected: [%d-%d], Found: %d", min, max, elapsed)
@@ -668,7 +677,7 @@ func TestSleep(t *testing.T) {
 
 	// Test exact duration
 	start = time.Now()
-	sleepDuration.sleep()
+	sleepDuration.sleep(context.TODO(), nil)
 	elapsed = time.Duration(time.Since(start) / time.Millisecond)
 	if elapsed > time.Duration(dur)+delta {
 		t.Errorf("Expected: %d, Found: %d", dur, elapsed)
@@ -676,6 +685,105 @@ func TestSleep(t *testing.T) {
 
 }
 
+func TestSleepInterruptedByContext(t *testing.T) {
+	t.Parallel()
+
+	sleepers := []Sleeper{
+		&DurationSleep{duration: 10000},
+		&RangeSleep{min: 5000, max: 10000},
+	}
+
+	for _, sl := range sleepers {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		start := time.Now()
+		sl.sleep(ctx, rand.New(rand.NewSource(1)))
+		elapsed := time.Since(start)
+		cancel()
+
+		if elapsed > time.Second {
+			t.Errorf("Expected sleep %T to be interrupted, Found: %v", sl, elapsed)
+		}
+	}
+}
+
+func TestNewSleeperWithUnits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		sleep    string
+		expected Sleeper
+	}{
+		{"1s-3s", &RangeSleep{min: 1000, max: 3000}},
+		{"500ms-1s", &RangeSleep{min: 500, max: 1000}},
+		{"3s-1s", &RangeSleep{min: 1000, max: 3000}},
+		{"2s", &DurationSleep{duration: 2000}},
+		{"250", &DurationSleep{duration: 250}},
+	}
+
+	for _, test := range tests {
+		sl := newSleeper(test.sleep, types.SleepDistribution{})
+		if !reflect.DeepEqual(sl, test.expected) {
+			t.Errorf("Expected %#v, Found: %#v", test.expected, sl)
+		}
+	}
+}
+
+func TestSleepDistributions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		dist   types.SleepDistribution
+		mean   float64 // in ms
+		stdDev float64
+	}{
+		{"Exponential", types.SleepDistribution{Type: types.SleepDistExponential, Mean: 500}, 500, 500},
+		{"Normal", types.SleepDistribution{Type: types.SleepDistNormal, Mean: 1000, StdDev: 200}, 1000, 200},
+	}
+
+	const n = 20000
+	for _, test := range tests {
+		sl := newSleeper("", test.dist).(interface {
+			sample(rnd *rand.Rand) time.Duration
+		})
+
+		rnd := rand.New(rand.NewSource(1))
+		samples := make([]float64, n)
+		var sum float64
+		for i := range samples {
+			d := sl.sample(rnd)
+			if d < 0 || d > maxSampledSleep {
+				t.Fatalf("%s Expected a sleep in [0, %v], Found: %v", test.name, maxSampledSleep, d)
+			}
+			samples[i] = float64(d) / float64(time.Millisecond)
+			sum += samples[i]
+		}
+		mean := sum / n
+		var variance float64
+		for _, s := range samples {
+			variance += (s - mean) * (s - mean)
+		}
+		stdDev := math.Sqrt(variance / n)
+		if math.Abs(mean-test.mean) > test.mean*0.05 || math.Abs(stdDev-test.stdDev) > test.stdDev*0.05 {
+			t.Errorf("%s Expected %v, Found: %v", test.name, []float64{test.mean, test.stdDev}, []float64{mean, stdDev})
+		}
+
+		// same seed, same durations
+		if a, b := sl.sample(rand.New(rand.NewSource(7))), sl.sample(rand.New(rand.NewSource(7))); a != b {
+			t.Errorf("%s Expected %v, Found: %v", test.name, a, b)
+		}
+	}
+
+	// clamped to non-negative
+	sl := &NormalSleep{mean: 1, stdDev: 1000}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		if d := sl.sample(rnd); d < 0 {
+			t.Fatalf("Expected a non-negative sleep, Found: %v", d)
+		}
+	}
+}
+
 func TestInjectDynamicVars(t *testing.T) {
 	invalidDynamicKey := "{{_randomDdppdd}}"
 	envs := map[string]interface{}{
@@ -735,3 +843,1530 @@ func TestOnlyOneClientInDebugModeInUserMode(t *testing.T) {
 		t.Fatal("TestOnlyOneClientInDebugModeInUserMode should have only one client")
 	}
 }
+
+func TestDoDrainsInFlightRequestsInGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	var nextCalls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	})
+	mux.HandleFunc("/next", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&nextCalls, 1)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "/slow", Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: server.URL + "/next", Timeout: types.DefaultTimeout},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		expectedErr bool
+	}{
+		{"Drained", 2 * time.Second, false},
+		{"GracePeriodPassed", 50 * time.Millisecond, true},
+		{"NoGracePeriod", 0, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		tf := func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			service := NewScenarioService()
+			if err := service.Init(ctx, scenario, []*url.URL{nil}, ScenarioOpts{GracePeriod: test.grace}); err != nil {
+				t.Fatalf("TestDoDrainsInFlightRequestsInGracePeriod init error: %v", err)
+			}
+			defer service.Done()
+
+			time.AfterFunc(100*time.Millisecond, cancel) // stop while the first step is in-flight
+			res, err := service.Do(nil, time.Now())
+
+			if test.expectedErr {
+				if err == nil || err.Type != types.ErrorIntented {
+					t.Errorf("Expected %v, Found: %v", types.ErrorIntented, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected nil error, Found: %v", err)
+			}
+			// in-flight step is completed, the next step is not sent after stop
+			if len(res.StepResults) != 1 || res.StepResults[0].StatusCode != http.StatusOK {
+				t.Errorf("Expected %v, Found: %v", 1, len(res.StepResults))
+			}
+		}
+		t.Run(test.name, tf)
+	}
+
+	if c := atomic.LoadInt32(&nextCalls); c != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, c)
+	}
+}
+
+func TestDoWithRPSLimit(t *testing.T) {
+	t.Parallel()
+
+	p1, _ := url.Parse("http://proxy_server.com:80")
+	service := ScenarioService{
+		clients: map[*url.URL][]scenarioItemRequester{
+			p1: {
+				{scenarioItemID: 1, requester: &MockHttpRequester{ReturnSend: &types.ScenarioStepResult{StepID: 1}}},
+				{scenarioItemID: 2, requester: &MockHttpRequester{ReturnSend: &types.ScenarioStepResult{StepID: 2}}},
+			},
+		},
+		scenario: types.Scenario{Steps: []types.ScenarioStep{{ID: 1}, {ID: 2}}},
+		ctx:      context.TODO(),
+		limiter:  util.NewRateLimiter(50),
+	}
+
+	// 10 requests at 50 rps, the first one is not delayed
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := service.Do(p1, time.Now()); err != nil {
+			t.Fatalf("TestDoWithRPSLimit error occurred: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 170*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v", 180*time.Millisecond, elapsed)
+	}
+
+	// waiting requests are not sent after stop
+	ctx, cancel := context.WithCancel(context.Background())
+	service.ctx = ctx
+	service.limiter = util.NewRateLimiter(1)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	res, err := service.Do(p1, time.Now())
+	if err == nil || err.Type != types.ErrorIntented {
+		t.Errorf("Expected %v, Found: %v", types.ErrorIntented, err)
+	}
+	if len(res.StepResults) != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, len(res.StepResults))
+	}
+}
+
+func TestDoCorrectsOmission(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+		},
+	}
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		IterationCount:         4,
+		MaxConcurrentIterCount: 3,
+		RPS:                    10,
+		CorrectOmission:        true,
+	}); err != nil {
+		t.Fatalf("TestDoCorrectsOmission init error: %v", err)
+	}
+	defer service.Done()
+
+	// concurrent requests above the rate are held back by 0, 100ms and 200ms
+	var mu sync.Mutex
+	lags := make([]time.Duration, 0, 3)
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := service.Do(nil, time.Now())
+			if err != nil {
+				t.Errorf("TestDoCorrectsOmission error occurred: %v", err)
+				return
+			}
+			sr := res.StepResults[0]
+			if sr.Duration < sr.ScheduleLag {
+				t.Errorf("Expected %v, Found: %v", "the lag in the duration", sr.Duration)
+			}
+			mu.Lock()
+			lags = append(lags, sr.ScheduleLag)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(lags, func(i, j int) bool { return lags[i] < lags[j] })
+	if len(lags) != 3 || lags[0] > 50*time.Millisecond || lags[2] < 150*time.Millisecond || lags[2] > time.Second {
+		t.Fatalf("Expected %v, Found: %v", []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond}, lags)
+	}
+
+	// a request after an idle period is not held back by the missed slots
+	time.Sleep(300 * time.Millisecond)
+	res, err := service.Do(nil, time.Now())
+	if err != nil {
+		t.Fatalf("TestDoCorrectsOmission error occurred: %v", err)
+	}
+	if lag := res.StepResults[0].ScheduleLag; lag > 50*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v", 0, lag)
+	}
+}
+
+func TestDoParallelStep(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	all := make(chan struct{}) // closed when the copies of the parallel step are in flight together
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+			if maxInFlight == 3 {
+				close(all)
+			}
+		}
+		mu.Unlock()
+		if r.URL.Path == "/items" {
+			select {
+			case <-all:
+			case <-time.After(time.Second):
+			}
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "/items", Timeout: types.DefaultTimeout, Parallel: 3},
+			{ID: 2, Method: http.MethodGet, URL: server.URL + "/cart", Timeout: types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode: types.EngineModeDistinctUser, IterationCount: 1, MaxConcurrentIterCount: 1,
+	}); err != nil {
+		t.Fatalf("TestDoParallelStep init error: %v", err)
+	}
+	res, err := service.Do(nil, time.Now())
+	if err != nil {
+		t.Fatalf("TestDoParallelStep error occurred: %v", err)
+	}
+	service.Done()
+
+	var ids []uint16
+	for _, sr := range res.StepResults {
+		if sr.Err.Type != "" {
+			t.Errorf("Expected no error, Found: %v", sr.Err)
+		}
+		ids = append(ids, sr.StepID)
+	}
+	expected := []uint16{1, 1, 1, 2}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, ids)
+	}
+	if maxInFlight != 3 {
+		t.Errorf("Expected max in flight %d, Found: %d", 3, maxInFlight)
+	}
+}
+
+func TestDoSkipsStepsByCondition(t *testing.T) {
+	t.Parallel()
+
+	p1, _ := url.Parse("http://proxy_server.com:80")
+	step := func(id uint16, cond string, statusCode int) scenarioItemRequester {
+		c, err := newStepCondition(types.ScenarioStep{ID: id, Name: fmt.Sprintf("step%d", id), If: cond})
+		if err != nil {
+			t.Fatalf("TestDoSkipsStepsByCondition error occurred: %v", err)
+		}
+		return scenarioItemRequester{
+			scenarioItemID: id,
+			condition:      c,
+			requester:      &MockHttpRequester{ReturnSend: &types.ScenarioStepResult{StepID: id, StatusCode: statusCode}},
+		}
+	}
+	service := ScenarioService{
+		clients: map[*url.URL][]scenarioItemRequester{
+			p1: {
+				step(1, "", 429),
+				step(2, "status_code == 200", 200), // login is rate limited
+				step(3, "status_code == 429", 200), // previous sent step is the login
+				step(4, `variables.plan == "pro"`, 200),
+				step(5, "variables.undefined == 1", 200), // evaluation error
+			},
+		},
+		scenario: types.Scenario{
+			Steps: []types.ScenarioStep{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}},
+			Envs:  map[string]interface{}{"plan": "pro"},
+		},
+		ctx: context.TODO(),
+	}
+
+	res, err := service.Do(p1, time.Now())
+	if err != nil {
+		t.Fatalf("TestDoSkipsStepsByCondition error occurred: %v", err)
+	}
+
+	expected := []bool{false, true, false, false, true}
+	if len(res.StepResults) != len(expected) {
+		t.Fatalf("Expected %v, Found: %v", len(expected), len(res.StepResults))
+	}
+	for i, skipped := range expected {
+		sr := res.StepResults[i]
+		if sr.StepID != uint16(i+1) || sr.Skipped != skipped {
+			t.Errorf("Expected %v, Found: %v", skipped, sr.Skipped)
+		}
+	}
+	if res.StepResults[1].StepName != "step2" {
+		t.Errorf("Expected %v, Found: %v", "step2", res.StepResults[1].StepName)
+	}
+}
+
+func TestNewStepConditionInvalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := newStepCondition(types.ScenarioStep{ID: 1, If: "status_code =="}); err == nil {
+		t.Errorf("Expected error, Found: %v", err)
+	}
+	if c, err := newStepCondition(types.ScenarioStep{ID: 1}); c != nil || err != nil {
+		t.Errorf("Expected %v, Found: %v, %v", nil, c, err)
+	}
+}
+
+func TestInitInvalidSuccessWhen(t *testing.T) {
+	t.Parallel()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: "http://127.0.0.1", Timeout: types.DefaultTimeout},
+		},
+	}
+	service := NewScenarioService()
+	err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{SuccessWhen: "status_code <"})
+	if err == nil {
+		t.Errorf("Expected error, Found: %v", err)
+	}
+}
+
+func TestDoStickyUsers(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var addrs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		addrs = append(addrs, r.RemoteAddr)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode:             types.EngineModeRepeatedUser,
+		IterationCount:         9,
+		MaxConcurrentIterCount: 3,
+		StickyUsers:            3,
+	}); err != nil {
+		t.Fatalf("TestDoStickyUsers init error: %v", err)
+	}
+	defer service.Done()
+
+	for i := 0; i < 9; i++ {
+		if _, err := service.Do(nil, time.Now()); err != nil {
+			t.Fatalf("TestDoStickyUsers error occurred: %v", err)
+		}
+	}
+
+	// iteration i is run by the user i % 3, over the same connection of its client
+	for i := 3; i < 9; i++ {
+		if addrs[i] != addrs[i%3] {
+			t.Errorf("Expected %v, Found: %v", addrs[i%3], addrs[i])
+		}
+	}
+	if service.cPool.Len() != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, service.cPool.Len())
+	}
+}
+
+func TestDoStickyUsersConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	addrs := make(map[string]bool)
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		mu.Lock()
+		addrs[r.RemoteAddr] = true
+		if n > maxInFlight {
+			maxInFlight = n
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: server.URL, Timeout: 2 * types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode:             types.EngineModeRepeatedUser,
+		IterationCount:         32,
+		MaxConcurrentIterCount: 16,
+		StickyUsers:            2,
+	}); err != nil {
+		t.Fatalf("TestDoStickyUsersConcurrent init error: %v", err)
+	}
+	defer service.Done()
+
+	// more concurrent iterations than the sticky users, the iterations of a user run in turn over its client
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.Do(nil, time.Now()); err != nil {
+				t.Errorf("TestDoStickyUsersConcurrent error occurred: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(addrs) != 2 || maxInFlight > 2 {
+		t.Errorf("Expected %v, Found: %v %v", 2, len(addrs), maxInFlight)
+	}
+}
+
+func TestDoAsUser(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var addrs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		addrs = append(addrs, r.RemoteAddr)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode:             types.EngineModeRepeatedUser,
+		IterationCount:         6,
+		MaxConcurrentIterCount: 2,
+	}); err != nil {
+		t.Fatalf("TestDoAsUser init error: %v", err)
+	}
+	defer service.Done()
+
+	users := make([]*UserClient, 2)
+	for i := range users {
+		u, err := service.AcquireUserClient()
+		if err != nil || u == nil {
+			t.Fatalf("TestDoAsUser acquire error: %v", err)
+		}
+		users[i] = u
+	}
+	if service.cPool.Len() != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, service.cPool.Len())
+	}
+
+	// the users alternate, each one keeps the connection of its own client
+	for i := 0; i < 6; i++ {
+		if _, err := service.DoAsUser(nil, time.Now(), users[i%2]); err != nil {
+			t.Fatalf("TestDoAsUser error occurred: %v", err)
+		}
+	}
+	for i := 2; i < 6; i++ {
+		if addrs[i] != addrs[i%2] {
+			t.Errorf("Expected %v, Found: %v", addrs[i%2], addrs[i])
+		}
+	}
+	if addrs[0] == addrs[1] {
+		t.Errorf("Expected distinct connections, Found: %v", addrs[:2])
+	}
+
+	for _, u := range users {
+		service.ReleaseUserClient(u)
+	}
+	if service.cPool.Len() != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, service.cPool.Len())
+	}
+}
+
+func TestDoVirtualUsers(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var users []string // index:id of the iterations
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		users = append(users, r.URL.Query().Get("vu"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "?vu={{vu.index}}:{{vu.id}}", Timeout: types.DefaultTimeout},
+		},
+	}
+
+	run := func(opts ScenarioOpts) []string {
+		users = nil
+		service := NewScenarioService()
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, opts); err != nil {
+			t.Fatalf("TestDoVirtualUsers init error: %v", err)
+		}
+		defer service.Done()
+		for i := 0; i < 6; i++ {
+			if _, err := service.Do(nil, time.Now()); err != nil {
+				t.Fatalf("TestDoVirtualUsers error occurred: %v", err)
+			}
+		}
+		return users
+	}
+	indexes := func(users []string) []string {
+		idx := make([]string, len(users))
+		for i, u := range users {
+			idx[i] = strings.Split(u, ":")[0]
+		}
+		return idx
+	}
+
+	// every iteration is a new user
+	distinct := ScenarioOpts{EngineMode: types.EngineModeDistinctUser, IterationCount: 6, MaxConcurrentIterCount: 2,
+		Seed: 42}
+	first := run(distinct)
+	if !reflect.DeepEqual(indexes(first), []string{"0", "1", "2", "3", "4", "5"}) {
+		t.Errorf("Expected indexes 0-5, Found: %v", first)
+	}
+	ids := map[string]struct{}{}
+	for _, u := range first {
+		ids[strings.Split(u, ":")[1]] = struct{}{}
+	}
+	if len(ids) != 6 {
+		t.Errorf("Expected %d unique ids, Found: %v", 6, first)
+	}
+	// the same seed gives the same ids
+	if second := run(distinct); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected %v, Found: %v", first, second)
+	}
+
+	// each client of the pool is a user in all of its iterations
+	repeated := run(ScenarioOpts{EngineMode: types.EngineModeRepeatedUser, IterationCount: 6,
+		MaxConcurrentIterCount: 2, Seed: 42})
+	for _, u := range repeated {
+		if u != first[0] && u != first[1] {
+			t.Errorf("Expected %v or %v, Found: %v", first[0], first[1], u)
+		}
+	}
+
+	sticky := run(ScenarioOpts{EngineMode: types.EngineModeRepeatedUser, IterationCount: 6,
+		MaxConcurrentIterCount: 2, StickyUsers: 3, Seed: 42})
+	expected := []string{first[0], first[1], first[2], first[0], first[1], first[2]}
+	if !reflect.DeepEqual(sticky, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, sticky)
+	}
+}
+
+func TestDoSeededInjectionConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Envs: map[string]interface{}{"ids": []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodPost, URL: server.URL, Timeout: types.DefaultTimeout,
+				Payload: `{{vu.index}} {{_randomUUID}} {{randomInt(1,1000000)}} {{rand(ids)}} {{_randomFullName}}`},
+			{ID: 2, Method: http.MethodPost, URL: server.URL, Timeout: types.DefaultTimeout,
+				Payload: `{{vu.index}} {{_randomIP}} {{randomString(8)}} {{rand(ids)}}`},
+		},
+	}
+
+	run := func() []string {
+		bodies = nil
+		service := NewScenarioService()
+		opts := ScenarioOpts{EngineMode: types.EngineModeDistinctUser, IterationCount: 40, MaxConcurrentIterCount: 8,
+			Seed: 42}
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, opts); err != nil {
+			t.Fatalf("TestDoSeededInjectionConcurrent init error: %v", err)
+		}
+		defer service.Done()
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 5; j++ {
+					if _, err := service.Do(nil, time.Now()); err != nil {
+						t.Errorf("TestDoSeededInjectionConcurrent error occurred: %v", err)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		sort.Strings(bodies)
+		return bodies
+	}
+
+	// the values of each iteration are drawn from its own stream, whatever order the iterations run in
+	first := run()
+	if len(first) != 80 {
+		t.Fatalf("Expected %d bodies, Found: %d", 80, len(first))
+	}
+	if second := run(); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected %v, Found: %v", first, second)
+	}
+}
+
+func TestDoMaxRequests(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&count, 1)
+		if r.URL.Path == "/retry" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: server.URL + "/retry", Timeout: types.DefaultTimeout,
+				Retry: types.RetryConf{MaxAttempts: 5}},
+		},
+	}
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		IterationCount: 3, MaxConcurrentIterCount: 1, MaxRequests: 4,
+	}); err != nil {
+		t.Fatalf("TestDoMaxRequests init error: %v", err)
+	}
+	defer service.Done()
+
+	// the retries of the step stop at the max requests, the result of the last attempt is reported
+	res, err := service.Do(nil, time.Now())
+	if err != nil {
+		t.Fatalf("TestDoMaxRequests error occurred: %v", err)
+	}
+	if len(res.StepResults) != 2 || res.StepResults[1].StatusCode != http.StatusServiceUnavailable ||
+		res.StepResults[1].Retries != 2 {
+		t.Errorf("Expected 2 step results with 2 retries, Found: %+v", res.StepResults)
+	}
+	select {
+	case <-service.MaxRequestsReached():
+	default:
+		t.Errorf("Expected the max requests to be reached")
+	}
+
+	if _, err = service.Do(nil, time.Now()); err == nil || err.Reason != types.ReasonMaxRequests {
+		t.Errorf("Expected %v, Found: %v", types.ReasonMaxRequests, err)
+	}
+	if c := atomic.LoadInt64(&count); c != 4 {
+		t.Errorf("Expected %v, Found: %v", 4, c)
+	}
+	if r := service.MaxRequestsResult(); r != "4 requests are sent" {
+		t.Errorf("Expected %v, Found: %v", "4 requests are sent", r)
+	}
+}
+
+func TestDoUserAgents(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var agents []string // User-Agent of the requests of each step
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents = append(agents, r.URL.Path+" "+r.UserAgent())
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "/a", Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: server.URL + "/b", Timeout: types.DefaultTimeout,
+				Headers: map[string]string{"user-agent": "custom"}},
+		},
+	}
+
+	run := func(conf types.UserAgentConf) []string {
+		agents = nil
+		service := NewScenarioService()
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+			EngineMode: types.EngineModeDistinctUser, IterationCount: 3, MaxConcurrentIterCount: 1, Seed: 42,
+			UserAgents: conf,
+		}); err != nil {
+			t.Fatalf("TestDoUserAgents init error: %v", err)
+		}
+		defer service.Done()
+		for i := 0; i < 3; i++ {
+			if _, err := service.Do(nil, time.Now()); err != nil {
+				t.Fatalf("TestDoUserAgents error occurred: %v", err)
+			}
+		}
+		return agents
+	}
+
+	// the step with its own User-Agent doesn't take a value of the rotation
+	perRequest := run(types.UserAgentConf{Values: []string{"ua1", "ua2"}})
+	expected := []string{"/a ua1", "/b custom", "/a ua2", "/b custom", "/a ua1", "/b custom"}
+	if !reflect.DeepEqual(perRequest, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, perRequest)
+	}
+
+	// the same seed gives the same random picks, a user keeps its value in all of its requests
+	conf := types.UserAgentConf{Builtin: true, Order: types.UserAgentOrderRandom, Per: types.UserAgentPerUser}
+	perUser := run(conf)
+	for i := 0; i < len(perUser); i += 2 {
+		if ua := strings.TrimPrefix(perUser[i], "/a "); !strings.HasPrefix(ua, "Mozilla/5.0") {
+			t.Errorf("Expected a built-in user agent, Found: %v", ua)
+		}
+	}
+	if second := run(conf); !reflect.DeepEqual(perUser, second) {
+		t.Errorf("Expected %v, Found: %v", perUser, second)
+	}
+}
+
+// signingTransport marks the requests sent over it, like a request signing middleware.
+type signingTransport struct {
+	base http.RoundTripper
+	sent int32
+}
+
+func (st *signingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&st.sent, 1)
+	r = r.Clone(r.Context())
+	r.Header.Set("X-Signature", "signed")
+	return st.base.RoundTrip(r)
+}
+
+func TestDoClientFactory(t *testing.T) {
+	t.Parallel()
+
+	var unsigned, withCookie int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Signature") != "signed" {
+			atomic.AddInt32(&unsigned, 1)
+		}
+		if _, err := r.Cookie("session"); err == nil {
+			atomic.AddInt32(&withCookie, 1)
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+		},
+	}
+
+	for _, mode := range []string{types.EngineModeDistinctUser, types.EngineModeRepeatedUser} {
+		atomic.StoreInt32(&unsigned, 0)
+		atomic.StoreInt32(&withCookie, 0)
+		st := &signingTransport{base: http.DefaultTransport}
+		service := NewScenarioService()
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+			EngineMode:             mode,
+			IterationCount:         2,
+			MaxConcurrentIterCount: 1,
+			ClientFactory:          func() *http.Client { return &http.Client{Transport: st} },
+		}); err != nil {
+			t.Fatalf("TestDoClientFactory init error: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			if _, err := service.Do(nil, time.Now()); err != nil {
+				t.Fatalf("TestDoClientFactory error occurred: %v", err)
+			}
+		}
+		service.Done()
+
+		if atomic.LoadInt32(&st.sent) != 4 || atomic.LoadInt32(&unsigned) != 0 {
+			t.Errorf("%s: Expected all the %d requests signed, Found: %d sent, %d unsigned",
+				mode, 4, st.sent, unsigned)
+		}
+		// clients got the cookie jar of the engine mode, the distinct users start with an empty one
+		expected := int32(2)
+		if mode == types.EngineModeRepeatedUser {
+			expected = 3
+		}
+		if c := atomic.LoadInt32(&withCookie); c != expected {
+			t.Errorf("%s: Expected %d requests with the cookie, Found: %d", mode, expected, c)
+		}
+	}
+}
+
+func TestDoPutsBadClient(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close() // requests to it fail with connection refused
+	defer server.Close()
+
+	tests := []struct {
+		name              string
+		url               string
+		expectedIdle      int
+		expectedClosedBad int64
+	}{
+		{"Success", server.URL, 1, 0},
+		{"ConnectionError", closed.URL, 0, 1},
+	}
+
+	for _, test := range tests {
+		scenario := types.Scenario{
+			Steps: []types.ScenarioStep{
+				{ID: 1, Method: http.MethodGet, URL: test.url, Timeout: types.DefaultTimeout},
+			},
+		}
+		service := NewScenarioService()
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+			EngineMode:             types.EngineModeRepeatedUser,
+			IterationCount:         1,
+			MaxConcurrentIterCount: 1,
+		}); err != nil {
+			t.Fatalf("%s init error: %v", test.name, err)
+		}
+		service.Do(nil, time.Now())
+
+		stats := service.cPool.Stats()
+		if stats.Idle != test.expectedIdle || stats.ClosedBad != test.expectedClosedBad {
+			t.Errorf("%s Expected %v, Found: %v", test.name, []int64{int64(test.expectedIdle), test.expectedClosedBad},
+				[]int64{int64(stats.Idle), stats.ClosedBad})
+		}
+		service.Done()
+	}
+}
+
+func TestDoCapClientPool(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name            string
+		capClientPool   bool
+		expectedPeak    int
+		expectedWarning bool
+	}{
+		{"NotCapped", false, 3, true},
+		{"Capped", true, 1, false},
+	}
+
+	for _, test := range tests {
+		scenario := types.Scenario{
+			Steps: []types.ScenarioStep{
+				{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+			},
+		}
+		service := NewScenarioService()
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+			EngineMode:             types.EngineModeRepeatedUser,
+			IterationCount:         3,
+			MaxConcurrentIterCount: 1,
+			CapClientPool:          test.capClientPool,
+		}); err != nil {
+			t.Fatalf("%s init error: %v", test.name, err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := service.Do(nil, time.Now()); err != nil {
+					t.Errorf("%s error occurred: %v", test.name, err)
+				}
+			}()
+		}
+		wg.Wait()
+		service.Done()
+
+		if peak := service.cPool.PeakLive(); peak != test.expectedPeak {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expectedPeak, peak)
+		}
+		if warning := service.ClientPoolWarning(); (warning != "") != test.expectedWarning {
+			t.Errorf("%s Expected warning: %v, Found: %v", test.name, test.expectedWarning, warning)
+		}
+	}
+}
+
+func TestDoClientPerHost(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	addrs := make(map[string]map[string]bool) // remote addresses by server
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			if addrs[name] == nil {
+				addrs[name] = make(map[string]bool)
+			}
+			addrs[name][r.RemoteAddr] = true
+			mu.Unlock()
+		}
+	}
+	a := httptest.NewServer(handler("a"))
+	defer a.Close()
+	b := httptest.NewServer(handler("b"))
+	defer b.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: a.URL + "/1", Timeout: types.DefaultTimeout},
+			{ID: 2, Method: http.MethodGet, URL: b.URL, Timeout: types.DefaultTimeout},
+			{ID: 3, Method: http.MethodGet, URL: a.URL + "/3", Timeout: types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode:             types.EngineModeRepeatedUser,
+		IterationCount:         3,
+		MaxConcurrentIterCount: 1,
+		ClientPerHost:          true,
+	}); err != nil {
+		t.Fatalf("TestDoClientPerHost init error: %v", err)
+	}
+	defer service.Done()
+
+	for i := 0; i < 3; i++ {
+		res, err := service.Do(nil, time.Now())
+		if err != nil {
+			t.Fatalf("TestDoClientPerHost error occurred: %v", err)
+		}
+		for _, sr := range res.StepResults {
+			if sr.Err.Type != "" {
+				t.Fatalf("TestDoClientPerHost step %d error occurred: %v", sr.StepID, sr.Err)
+			}
+		}
+	}
+
+	// each host has its own pooled client, reused by the steps and the iterations over one connection
+	mu.Lock()
+	defer mu.Unlock()
+	if len(addrs["a"]) != 1 || len(addrs["b"]) != 1 {
+		t.Errorf("Expected %v, Found: %v %v", 1, len(addrs["a"]), len(addrs["b"]))
+	}
+	if service.cPool != nil {
+		t.Errorf("Expected no shared client pool, Found: %v", service.cPool)
+	}
+	stats := service.HostPoolStats()
+	if len(stats) != 2 {
+		t.Errorf("Expected %v host pools, Found: %v", 2, stats)
+	}
+	for _, server := range []*httptest.Server{a, b} {
+		st, ok := stats[hostKey(server.URL)]
+		// filled with a client on the first step of the host
+		if !ok || st.Created != 0 || st.Reused != 3 || st.Idle != 1 {
+			t.Errorf("%s Expected %v reused, Found: %+v", server.URL, 3, st)
+		}
+	}
+	if st, ok := service.PoolStats(); !ok || st.Reused != 6 || st.Idle != 2 {
+		t.Errorf("Expected %v reused, %v idle, Found: %+v", 6, 2, st)
+	}
+}
+
+func TestDoClientTTL(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var addrs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		addrs = append(addrs, r.RemoteAddr)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		clientPerHost bool
+	}{
+		{"Pool", false},
+		{"ClientPerHost", true},
+	}
+
+	ttl := 100 * time.Millisecond
+	for _, test := range tests {
+		mu.Lock()
+		addrs = nil
+		mu.Unlock()
+
+		scenario := types.Scenario{
+			Steps: []types.ScenarioStep{
+				{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+			},
+		}
+		service := NewScenarioService()
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+			EngineMode:             types.EngineModeRepeatedUser,
+			IterationCount:         3,
+			MaxConcurrentIterCount: 1,
+			ClientPerHost:          test.clientPerHost,
+			ClientTTL:              ttl,
+		}); err != nil {
+			t.Fatalf("%s init error: %v", test.name, err)
+		}
+
+		for i := 0; i < 3; i++ {
+			if i == 2 {
+				// the client of the first iterations expires
+				time.Sleep(ttl)
+			}
+			if _, err := service.Do(nil, time.Now()); err != nil {
+				t.Fatalf("%s error occurred: %v", test.name, err)
+			}
+		}
+
+		stats, _ := service.PoolStats()
+		service.Done()
+
+		mu.Lock()
+		if len(addrs) != 3 || addrs[1] != addrs[0] || addrs[2] == addrs[1] {
+			t.Errorf("%s Expected the connection to be rotated after the ttl, Found: %v", test.name, addrs)
+		}
+		mu.Unlock()
+		if stats.Created != 1 || stats.Reused != 2 {
+			t.Errorf("%s Expected %v created, %v reused, Found: %+v", test.name, 1, 2, stats)
+		}
+	}
+}
+
+func TestDoClientHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	var heads, gets int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			atomic.AddInt32(&heads, 1)
+		} else {
+			atomic.AddInt32(&gets, 1)
+		}
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+		},
+	}
+	interval := 100 * time.Millisecond
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode:             types.EngineModeRepeatedUser,
+		IterationCount:         3,
+		MaxConcurrentIterCount: 1,
+		ClientHealthCheck:      interval,
+	}); err != nil {
+		t.Fatalf("TestDoClientHealthCheck init error: %v", err)
+	}
+	defer service.Done()
+
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			// the client is idle for longer than the interval, it is probed before the iteration
+			time.Sleep(interval)
+		}
+		if _, err := service.Do(nil, time.Now()); err != nil {
+			t.Fatalf("TestDoClientHealthCheck error occurred: %v", err)
+		}
+	}
+
+	if h, g := atomic.LoadInt32(&heads), atomic.LoadInt32(&gets); h != 1 || g != 3 {
+		t.Errorf("Expected %v probes, %v requests, Found: %v %v", 1, 3, h, g)
+	}
+	if st, _ := service.PoolStats(); st.Reused != 3 || st.Created != 0 {
+		t.Errorf("Expected the healthy client to be reused, Found: %+v", st)
+	}
+}
+
+func TestDoDataRowsUsedUp(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Name: "login", Method: http.MethodGet, URL: server.URL + "/{{data.users.name}}",
+				Timeout: types.DefaultTimeout},
+		},
+		Data: map[string]types.CsvData{
+			"users": {Rows: []map[string]interface{}{{"name": "a"}, {"name": "b"}}},
+		},
+	}
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{}); err != nil {
+		t.Fatalf("TestDoDataRowsUsedUp init error: %v", err)
+	}
+	defer service.Done()
+
+	for i := 0; i < 3; i++ {
+		res, err := service.Do(nil, time.Now())
+		if err != nil {
+			t.Fatalf("TestDoDataRowsUsedUp error occurred: %v", err)
+		}
+		sr := res.StepResults[0]
+		if i < 2 && sr.Err.Type != "" {
+			t.Errorf("Iteration %d Expected no error, Found: %v", i, sr.Err)
+		}
+		// the rows are not circular, the last iteration fails without sending its step
+		if i == 2 && (sr.Err.Type != types.ErrorInvalidRequest || !strings.Contains(sr.Err.Reason, "users are used up") ||
+			sr.StepName != "login") {
+			t.Errorf("Iteration %d Expected %v, Found: %+v", i, types.ErrorInvalidRequest, sr)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, n)
+	}
+}
+
+func TestInitPreWarm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		engineMode string
+		users      int
+	}{
+		{"RepeatedUser", types.EngineModeRepeatedUser, 3},
+		{"DistinctUser", types.EngineModeDistinctUser, 2},
+		{"Ddosify", types.EngineModeDdosify, 2},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			warmed := make(map[string]bool)
+			var heads int
+			var getAddr string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodHead {
+					heads++
+					warmed[r.RemoteAddr] = true
+					http.SetCookie(w, &http.Cookie{Name: "warm", Value: "1"})
+					return
+				}
+				if r.URL.Path == "/" { // the warmed step
+					getAddr = r.RemoteAddr
+				}
+				if _, err := r.Cookie("warm"); err == nil {
+					t.Errorf("Expected the cookies of the warm-up to be ignored")
+				}
+			}))
+			defer server.Close()
+
+			scenario := types.Scenario{
+				Steps: []types.ScenarioStep{
+					{ID: 1, Method: http.MethodGet, URL: server.URL, Timeout: types.DefaultTimeout},
+					{ID: 2, Method: http.MethodGet, URL: server.URL + "/{{_randomInt}}", Timeout: types.DefaultTimeout},
+				},
+			}
+			service := NewScenarioService()
+			if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+				EngineMode:             tf.engineMode,
+				IterationCount:         tf.users,
+				MaxConcurrentIterCount: tf.users,
+				PreWarm:                true,
+			}); err != nil {
+				t.Fatalf("TestInitPreWarm init error: %v", err)
+			}
+			defer service.Done()
+
+			// the step with a variable in its url is not warmed
+			mu.Lock()
+			if heads != tf.users {
+				t.Errorf("Expected %v, Found: %v", tf.users, heads)
+			}
+			mu.Unlock()
+
+			if _, err := service.Do(nil, time.Now()); err != nil {
+				t.Fatalf("TestInitPreWarm error occurred: %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !warmed[getAddr] {
+				t.Errorf("Expected the request to be sent over a warmed connection %v, Found: %v", warmed, getAddr)
+			}
+		})
+	}
+}
+
+func TestDoGraph(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var paths []string // paths of the requests in arrival order
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("Token", "abc")
+		case "/products", "/cart":
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	key := "Token"
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 4, Method: http.MethodGet, URL: server.URL + "/checkout/{{TOKEN}}", Timeout: types.DefaultTimeout,
+				DependsOn: []uint16{2, 3}},
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "/login", Timeout: types.DefaultTimeout,
+				EnvsToCapture: []types.EnvCaptureConf{{Name: "TOKEN", From: types.Header, Key: &key}}},
+			{ID: 2, Method: http.MethodGet, URL: server.URL + "/products", Timeout: types.DefaultTimeout,
+				DependsOn: []uint16{1}},
+			{ID: 3, Method: http.MethodGet, URL: server.URL + "/cart", Timeout: types.DefaultTimeout,
+				DependsOn: []uint16{1}, If: "equals(status_code, 200)"},
+			{ID: 5, Method: http.MethodGet, URL: server.URL + "/skipped", Timeout: types.DefaultTimeout,
+				DependsOn: []uint16{1}, If: "equals(status_code, 500)"},
+		},
+	}
+
+	// http steps of the user modes share the client of the user, they are sent one at a time
+	for _, mode := range []string{types.EngineModeDdosify, types.EngineModeDistinctUser} {
+		paths = nil
+		service := NewScenarioService()
+		if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+			EngineMode: mode, IterationCount: 1, MaxConcurrentIterCount: 1,
+		}); err != nil {
+			t.Fatalf("TestDoGraph init error: %v", err)
+		}
+
+		start := time.Now()
+		res, err := service.Do(nil, time.Now())
+		service.Done()
+		if err != nil {
+			t.Fatalf("TestDoGraph error occurred: %v", err)
+		}
+		// products and cart are sent concurrently
+		if d := time.Since(start); mode == types.EngineModeDdosify && d >= 400*time.Millisecond {
+			t.Errorf("Expected the independent steps to run concurrently, Found: %v", d)
+		}
+		checkGraphResult(t, res, paths)
+	}
+}
+
+func checkGraphResult(t *testing.T, res *types.ScenarioResult, paths []string) {
+	t.Helper()
+
+	// results are in the order of the steps
+	var ids []uint16
+	for _, sr := range res.StepResults {
+		ids = append(ids, sr.StepID)
+		if !sr.Skipped && (sr.Err.Type != "" || sr.StatusCode != http.StatusOK) {
+			t.Errorf("Unexpected result of step %d: %v %d", sr.StepID, sr.Err, sr.StatusCode)
+		}
+	}
+	if !reflect.DeepEqual(ids, []uint16{4, 1, 2, 3, 5}) {
+		t.Errorf("Expected %v, Found: %v", []uint16{4, 1, 2, 3, 5}, ids)
+	}
+	if !res.StepResults[4].Skipped {
+		t.Errorf("Expected the step 5 to be skipped")
+	}
+
+	if len(paths) != 4 || paths[0] != "/login" || paths[3] != "/checkout/abc" {
+		t.Errorf("Unexpected order of the requests: %v", paths)
+	}
+}
+
+func TestDoCleanup(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var requests []string // methods and paths of the requests in arrival order
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.Method {
+		case http.MethodPost:
+			w.Write([]byte(`{"id": "7"}`))
+		case http.MethodPut:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	path := "id"
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodPost, URL: server.URL + "/items", Timeout: types.DefaultTimeout,
+				EnvsToCapture: []types.EnvCaptureConf{{Name: "ID", From: types.Body, JsonPath: &path}}},
+			{ID: 2, Method: http.MethodPut, URL: server.URL + "/items/{{ID}}", Timeout: types.DefaultTimeout},
+			{ID: 3, Method: http.MethodGet, URL: server.URL + "/items/{{ID}}", Timeout: types.DefaultTimeout,
+				If: "status_code == 200"}, // update failed
+		},
+		Cleanup: []types.ScenarioStep{
+			{ID: 4, Method: http.MethodDelete, URL: server.URL + "/items/{{ID}}", Timeout: types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode: types.EngineModeDdosify, IterationCount: 1, MaxConcurrentIterCount: 1, MaxRequests: 2,
+	}); err != nil {
+		t.Fatalf("TestDoCleanup init error: %v", err)
+	}
+	res, err := service.Do(nil, time.Now())
+	service.Done()
+	if err != nil {
+		t.Fatalf("TestDoCleanup error occurred: %v", err)
+	}
+
+	// cleanup is sent after the failed step, it is not limited by the max requests
+	expected := []string{"POST /items", "PUT /items/7", "DELETE /items/7"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, requests)
+	}
+	var ids []uint16
+	for _, sr := range res.StepResults {
+		ids = append(ids, sr.StepID)
+	}
+	if !reflect.DeepEqual(ids, []uint16{1, 2, 3, 4}) || !res.StepResults[2].Skipped {
+		t.Errorf("Expected %v, Found: %v", []uint16{1, 2, 3, 4}, ids)
+	}
+	if sr := res.StepResults[3]; sr.Err.Type != "" || sr.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected result of the cleanup step: %v %d", sr.Err, sr.StatusCode)
+	}
+}
+
+func TestDoCleanupStopped(t *testing.T) {
+	t.Parallel()
+
+	p1, _ := url.Parse("http://proxy_server.com:80")
+	step := func(id uint16, errType string) *MockHttpRequester {
+		return &MockHttpRequester{ReturnSend: &types.ScenarioStepResult{StepID: id, Err: types.RequestError{Type: errType}}}
+	}
+	create, update, cleanup := step(1, ""), step(2, types.ErrorIntented), step(3, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	service := ScenarioService{
+		clients: map[*url.URL][]scenarioItemRequester{
+			p1: {
+				{scenarioItemID: 1, requester: create},
+				{scenarioItemID: 2, requester: update},
+				{scenarioItemID: 3, requester: cleanup, cleanup: true},
+			},
+		},
+		scenario: types.Scenario{
+			Steps:   []types.ScenarioStep{{ID: 1}, {ID: 2}},
+			Cleanup: []types.ScenarioStep{{ID: 3}},
+		},
+		ctx: ctx,
+	}
+
+	// the iteration is stopped by the update, the cleanup is still sent
+	if _, err := service.Do(p1, time.Now()); err == nil || err.Type != types.ErrorIntented {
+		t.Errorf("Expected %v, Found: %v", types.ErrorIntented, err)
+	}
+	if !cleanup.SendCalled {
+		t.Errorf("Expected the cleanup step to be sent")
+	}
+
+	// nothing to clean up if the iteration is stopped before its steps
+	cancel()
+	create.SendCalled, cleanup.SendCalled = false, false
+	if _, err := service.Do(p1, time.Now()); err == nil || err.Type != types.ErrorIntented {
+		t.Errorf("Expected %v, Found: %v", types.ErrorIntented, err)
+	}
+	if create.SendCalled || cleanup.SendCalled {
+		t.Errorf("Expected no step to be sent")
+	}
+}
+
+func TestSetupTeardown(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var requests []string // methods, paths and auth headers of the requests in arrival order
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+		mu.Unlock()
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"token": "abc"}`))
+		}
+	}))
+	defer server.Close()
+
+	path := "token"
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "/items", Timeout: types.DefaultTimeout,
+				Headers: map[string]string{"Authorization": "{{TOKEN}}"}},
+		},
+		Setup: []types.ScenarioStep{
+			{ID: 2, Method: http.MethodPost, URL: server.URL + "/login", Timeout: types.DefaultTimeout,
+				EnvsToCapture: []types.EnvCaptureConf{{Name: "TOKEN", From: types.Body, JsonPath: &path}}},
+		},
+		Teardown: []types.ScenarioStep{
+			{ID: 3, Method: http.MethodDelete, URL: server.URL + "/sessions/{{TOKEN}}", Timeout: types.DefaultTimeout},
+		},
+		Envs: map[string]interface{}{},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode: types.EngineModeDistinctUser, IterationCount: 2, MaxConcurrentIterCount: 1, MaxRequests: 2,
+	}); err != nil {
+		t.Fatalf("TestSetupTeardown init error: %v", err)
+	}
+	if err := service.Setup(context.Background(), nil); err != nil {
+		t.Fatalf("TestSetupTeardown setup error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := service.Do(nil, time.Now()); err != nil {
+			t.Fatalf("TestSetupTeardown error occurred: %v", err)
+		}
+	}
+	// teardown is not limited by the max requests
+	if err := service.Teardown(context.Background(), nil); err != nil {
+		t.Fatalf("TestSetupTeardown teardown error: %v", err)
+	}
+	service.Done()
+
+	expected := []string{"POST /login ", "GET /items abc", "GET /items abc", "DELETE /sessions/abc "}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, requests)
+	}
+}
+
+func TestSetupTeardownFailed(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var requests []string // methods and paths of the requests in arrival order
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	path := "token"
+	capture := []types.EnvCaptureConf{{Name: "TOKEN", From: types.Body, JsonPath: &path}}
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: server.URL + "/items", Timeout: types.DefaultTimeout},
+		},
+		Setup: []types.ScenarioStep{
+			{ID: 2, Method: http.MethodPost, URL: server.URL + "/login", Timeout: types.DefaultTimeout,
+				EnvsToCapture: capture},
+			{ID: 3, Method: http.MethodPost, URL: server.URL + "/items", Timeout: types.DefaultTimeout},
+		},
+		Teardown: []types.ScenarioStep{
+			{ID: 4, Method: http.MethodPost, URL: server.URL + "/logout", Timeout: types.DefaultTimeout,
+				EnvsToCapture: capture},
+			{ID: 5, Method: http.MethodDelete, URL: server.URL + "/items", Timeout: types.DefaultTimeout},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.Background(), scenario, []*url.URL{nil}, ScenarioOpts{
+		EngineMode: types.EngineModeDdosify, IterationCount: 1, MaxConcurrentIterCount: 1,
+	}); err != nil {
+		t.Fatalf("TestSetupTeardownFailed init error: %v", err)
+	}
+	defer service.Done()
+
+	// setup stops at the failed capture
+	err := service.Setup(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "setup step 2 failed") {
+		t.Errorf("Expected %v, Found: %v", "setup step 2 failed", err)
+	}
+	// all the teardown steps are sent, the first failure is returned
+	err = service.Teardown(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "teardown step 4 failed") {
+		t.Errorf("Expected %v, Found: %v", "teardown step 4 failed", err)
+	}
+
+	expected := []string{"POST /login", "POST /logout", "DELETE /items"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, requests)
+	}
+}
+
+func TestPauseThrottled(t *testing.T) {
+	t.Parallel()
+
+	s := &ScenarioService{ctx: context.Background(), honorRetryAfter: 50 * time.Millisecond}
+
+	// the pause is cut to honorRetryAfter
+	start := time.Now()
+	s.pauseThrottled(&types.ScenarioStepResult{Throttled: true, RetryAfter: time.Minute})
+	if d := time.Since(start); d < 50*time.Millisecond || d > 5*time.Second {
+		t.Errorf("Expected %v, Found: %v", 50*time.Millisecond, d)
+	}
+
+	// not throttled, no pause
+	start = time.Now()
+	s.pauseThrottled(&types.ScenarioStepResult{RetryAfter: time.Minute})
+	if d := time.Since(start); d >= 50*time.Millisecond {
+		t.Errorf("Expected no pause, Found: %v", d)
+	}
+
+	// stopped test cuts the pause
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = &ScenarioService{ctx: ctx, honorRetryAfter: time.Minute}
+	start = time.Now()
+	s.pauseThrottled(&types.ScenarioStepResult{Throttled: true, RetryAfter: time.Minute})
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Expected no pause, Found: %v", d)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package scenario
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/url"
+
+	"go.ddosify.com/ddosify/core/scenario/requester"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// stepTargets sends the requests of a step to its weighted targets. Each target has its own requester, created
+// from the step with the URL of the target.
+type stepTargets struct {
+	names      []string
+	requesters []requester.Requester
+	picker     *weightedPicker
+}
+
+func (s *ScenarioService) newStepTargets(ctx context.Context, si types.ScenarioStep,
+	proxyAddr *url.URL) (*stepTargets, error) {
+	st := &stepTargets{
+		names:      make([]string, 0, len(si.Targets)),
+		requesters: make([]requester.Requester, 0, len(si.Targets)),
+	}
+	weights := make([]int, 0, len(si.Targets))
+	for _, t := range si.Targets {
+		ts := si
+		ts.URL = t.URL
+		r, err := s.initRequester(ctx, ts, proxyAddr)
+		if err != nil {
+			st.done()
+			return nil, err
+		}
+		st.names = append(st.names, t.TargetName())
+		st.requesters = append(st.requesters, r)
+		weights = append(weights, t.Weight)
+	}
+	st.picker = newWeightedPicker(weights)
+	return st, nil
+}
+
+// send sends the request to the target picked by rnd, the result is tagged with the name of the target.
+func (st *stepTargets) send(rnd *rand.Rand, client *http.Client, envs map[string]interface{}) *types.ScenarioStepResult {
+	i := st.picker.pick(rnd)
+	res := sendStep(st.requesters[i], client, envs)
+	res.Target = st.names[i]
+	return res
+}
+
+func (st *stepTargets) done() {
+	for _, r := range st.requesters {
+		r.Done()
+	}
+}
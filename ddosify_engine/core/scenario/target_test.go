@@ -0,0 +1,80 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package scenario
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func TestDoSendsToWeightedTargets(t *testing.T) {
+	t.Parallel()
+
+	var euHits, usHits int64
+	eu := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&euHits, 1)
+	}))
+	defer eu.Close()
+	us := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&usHits, 1)
+	}))
+	defer us.Close()
+
+	scenario := types.Scenario{
+		Steps: []types.ScenarioStep{
+			{ID: 1, Method: http.MethodGet, Timeout: types.DefaultTimeout, Targets: []types.WeightedTarget{
+				{Name: "eu-west", URL: eu.URL, Weight: 3},
+				{URL: us.URL, Weight: 1},
+			}},
+		},
+	}
+
+	service := NewScenarioService()
+	if err := service.Init(context.TODO(), scenario, []*url.URL{nil}, ScenarioOpts{Seed: 5}); err != nil {
+		t.Fatalf("TestDoSendsToWeightedTargets init error: %v", err)
+	}
+	defer service.Done()
+
+	usName := scenario.Steps[0].Targets[1].TargetName() // host of the url
+	targets := make(map[string]int64)
+	iterations := 200
+	for i := 0; i < iterations; i++ {
+		response, _ := service.Do(nil, time.Now())
+		res := response.StepResults[0]
+		if res.Err.Type != "" {
+			t.Fatalf("TestDoSendsToWeightedTargets request error: %v", res.Err)
+		}
+		targets[res.Target]++
+	}
+
+	if targets["eu-west"] != euHits || targets[usName] != usHits || euHits+usHits != int64(iterations) {
+		t.Errorf("Expected results tagged by the hit targets %d, %d, Found: %v", euHits, usHits, targets)
+	}
+	if euHits <= usHits || usHits == 0 {
+		t.Errorf("Expected both targets to be picked by their weights, Found: %d, %d", euHits, usHits)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package scenario
+
+import (
+	"math/rand"
+	"strings"
+	"sync/atomic"
+
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+// userAgentEnv is set to the User-Agent of the request in the iteration scope, the HTTP steps without their own
+// User-Agent header send it.
+const userAgentEnv = "vu.user_agent"
+
+// userAgents rotates the User-Agent headers of the HTTP steps over the requests or the virtual users.
+type userAgents struct {
+	values  []string
+	random  bool
+	perUser bool
+	// round-robin counter of the requests
+	next uint64
+}
+
+// newUserAgents returns nil if the conf has no values.
+func newUserAgents(conf types.UserAgentConf) *userAgents {
+	values := conf.All()
+	if len(values) == 0 {
+		return nil
+	}
+	return &userAgents{
+		values:  values,
+		random:  strings.EqualFold(conf.Order, types.UserAgentOrderRandom),
+		perUser: strings.EqualFold(conf.Per, types.UserAgentPerUser),
+	}
+}
+
+// ofUser returns the User-Agent of the virtual user, the same user always gets the same value.
+func (u *userAgents) ofUser(rng *util.RandFactory, vu uint64) string {
+	if u.random {
+		return u.values[rng.Stream("user-agent", vu).Intn(len(u.values))]
+	}
+	return u.values[vu%uint64(len(u.values))]
+}
+
+// ofRequest returns the User-Agent of the next request, random ones are drawn from rnd of the iteration.
+func (u *userAgents) ofRequest(rnd *rand.Rand) string {
+	if u.random {
+		return u.values[rnd.Intn(len(u.values))]
+	}
+	return u.values[(atomic.AddUint64(&u.next, 1)-1)%uint64(len(u.values))]
+}
+
+// takesUserAgent returns true if the step sends the rotated User-Agent, the HTTP steps that set their own
+// User-Agent header keep it.
+func takesUserAgent(si types.ScenarioStep) bool {
+	if !si.IsHTTP() {
+		return false
+	}
+	for k := range si.Headers {
+		if strings.EqualFold(k, "User-Agent") {
+			return false
+		}
+	}
+	return true
+}
+
+// withUserAgentHeader returns a copy of the headers of a step that sends the rotated User-Agent.
+func withUserAgentHeader(headers map[string]string) map[string]string {
+	h := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		h[k] = v
+	}
+	h["User-Agent"] = "{{" + userAgentEnv + "}}"
+	return h
+}
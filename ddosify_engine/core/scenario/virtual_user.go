@@ -0,0 +1,75 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package scenario
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.ddosify.com/ddosify/core/types"
+	"go.ddosify.com/ddosify/core/util"
+)
+
+// VirtualUser is the identity of the simulated user running an iteration, the steps refer to it by {{vu.id}} and
+// {{vu.index}}. A user keeps its identity as long as it keeps its client, so its requests can be keyed by it.
+type VirtualUser struct {
+	// Index is the 0-based number of the user in the run
+	Index uint64
+	// ID is the uuid of the user, derived from the seed and the index. The same seed gives the same ids.
+	ID string
+}
+
+// newVirtualUser returns the user of the given index, its id is drawn from the "vu" stream of the index.
+func newVirtualUser(rng *util.RandFactory, index uint64) VirtualUser {
+	id, err := uuid.NewRandomFromReader(rng.Stream("vu", index))
+	if err != nil {
+		id = uuid.New()
+	}
+	return VirtualUser{Index: index, ID: id.String()}
+}
+
+// setEnvs sets the vars of the user in the scope of its iteration.
+func (vu VirtualUser) setEnvs(scope *iterationScope) {
+	scope.set(types.VirtualUserIDEnv, vu.ID)
+	scope.set(types.VirtualUserIndexEnv, int64(vu.Index))
+}
+
+// clientUsers numbers the pooled clients of the repeated users in the order of their first iterations, a client is
+// the same user in all of its iterations. A client replacing a bad one is a new user.
+type clientUsers struct {
+	mu    sync.Mutex
+	users map[*http.Client]uint64
+}
+
+func (u *clientUsers) indexOf(c *http.Client) uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.users == nil {
+		u.users = make(map[*http.Client]uint64)
+	}
+	i, ok := u.users[c]
+	if !ok {
+		i = uint64(len(u.users))
+		u.users[c] = i
+	}
+	return i
+}
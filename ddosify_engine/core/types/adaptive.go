@@ -0,0 +1,82 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Actions of the adaptive load when a threshold is breached
+const (
+	AdaptiveOnBreachHold    = "hold"
+	AdaptiveOnBreachBackOff = "back_off"
+
+	DefaultAdaptiveInterval = 5 * time.Second
+	DefaultAdaptiveOnBreach = AdaptiveOnBreachHold
+)
+
+// AdaptiveLoad is the closed-loop load that finds the capacity of the target. Virtual users run the iterations
+// back to back, the users are increased by Step every Interval until the p95 latency or the error rate of the
+// requests completed in the interval breaches its threshold. Then the users are held, or backed off by Step.
+// The test runs for the duration of the Hammer.
+type AdaptiveLoad struct {
+	// Bounds of the concurrent virtual users, the test starts with MinUsers
+	MinUsers int
+	MaxUsers int
+
+	// Number of the users added or removed at each adjustment
+	Step int
+
+	// Period of the evaluation of the thresholds
+	Interval time.Duration
+
+	// Thresholds, disabled if zero. At least one of them should be given.
+	MaxP95       time.Duration
+	MaxErrorRate float64 // between 0 and 1
+
+	// hold or back_off
+	OnBreach string
+}
+
+// Validate checks the bounds and the thresholds of the adaptive load.
+func (a *AdaptiveLoad) Validate() error {
+	if a.MinUsers < 1 || a.MaxUsers < a.MinUsers {
+		return fmt.Errorf("adaptive load needs min_users of at least 1 and max_users greater than or equal to min_users")
+	}
+	if a.Step < 1 {
+		return fmt.Errorf("adaptive load step should be greater than 0")
+	}
+	if a.Interval < time.Second {
+		return fmt.Errorf("adaptive load interval should be at least 1s")
+	}
+	if a.MaxP95 < 0 || a.MaxErrorRate < 0 || a.MaxErrorRate > 1 {
+		return fmt.Errorf("adaptive load max_p95 should be positive and max_error_rate should be between 0 and 100")
+	}
+	if a.MaxP95 == 0 && a.MaxErrorRate == 0 {
+		return fmt.Errorf("adaptive load needs a max_p95 or a max_error_rate threshold")
+	}
+	if a.OnBreach != AdaptiveOnBreachHold && a.OnBreach != AdaptiveOnBreachBackOff {
+		return fmt.Errorf("unsupported on_breach of the adaptive load: %s, supported values are %v",
+			a.OnBreach, []string{AdaptiveOnBreachHold, AdaptiveOnBreachBackOff})
+	}
+	return nil
+}
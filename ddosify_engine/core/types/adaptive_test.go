@@ -0,0 +1,57 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLoadValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := AdaptiveLoad{MinUsers: 1, MaxUsers: 10, Step: 1, Interval: time.Second, MaxP95: time.Second,
+		OnBreach: AdaptiveOnBreachHold}
+	tests := []struct {
+		name      string
+		modify    func(a *AdaptiveLoad)
+		shouldErr bool
+	}{
+		{"Valid", func(a *AdaptiveLoad) {}, false},
+		{"ErrorRateOnly", func(a *AdaptiveLoad) { a.MaxP95, a.MaxErrorRate = 0, 0.05 }, false},
+		{"BackOff", func(a *AdaptiveLoad) { a.OnBreach = AdaptiveOnBreachBackOff }, false},
+		{"NoMinUsers", func(a *AdaptiveLoad) { a.MinUsers = 0 }, true},
+		{"MaxBelowMin", func(a *AdaptiveLoad) { a.MinUsers = 20 }, true},
+		{"NoStep", func(a *AdaptiveLoad) { a.Step = 0 }, true},
+		{"ShortInterval", func(a *AdaptiveLoad) { a.Interval = 100 * time.Millisecond }, true},
+		{"NoThreshold", func(a *AdaptiveLoad) { a.MaxP95 = 0 }, true},
+		{"ErrorRateAbove100", func(a *AdaptiveLoad) { a.MaxErrorRate = 1.5 }, true},
+		{"InvalidOnBreach", func(a *AdaptiveLoad) { a.OnBreach = "stop" }, true},
+	}
+
+	for _, test := range tests {
+		a := valid
+		test.modify(&a)
+		if err := a.Validate(); (err != nil) != test.shouldErr {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.shouldErr, err)
+		}
+	}
+}
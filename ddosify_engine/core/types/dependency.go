@@ -0,0 +1,193 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HasDependencies reports whether any step has DependsOn, so the steps run as a dependency graph in each iteration.
+// The steps without DependsOn start with the iteration, the others start once all of their dependencies are done
+// and the independent steps run concurrently. Captured envs of a step are defined only for the steps depending
+// on it, directly or through the other steps.
+func (s *Scenario) HasDependencies() bool {
+	for _, si := range s.Steps {
+		if len(si.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// DependencyOrder returns the indexes of the steps in an order that each step comes after its dependencies.
+// Returns an error if a dependency is not found or the dependencies have a cycle.
+func (s *Scenario) DependencyOrder() ([]int, error) {
+	indexes := s.stepIndexes()
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(s.Steps))
+	order := make([]int, 0, len(s.Steps))
+	var path []uint16 // ids of the steps being visited, the cycle is reported by them
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch states[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("steps have a cyclic dependency: %s", cyclePath(path, s.Steps[i].ID))
+		}
+		states[i] = visiting
+		path = append(path, s.Steps[i].ID)
+		for _, id := range s.Steps[i].DependsOn {
+			d, ok := indexes[id]
+			if !ok {
+				return fmt.Errorf("dependency %d of the step %d is not found", id, s.Steps[i].ID)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		states[i] = visited
+		order = append(order, i)
+		return nil
+	}
+	for i := range s.Steps {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// stepIndexes returns the indexes of the steps by their ids, the first one of the duplicate ids.
+func (s *Scenario) stepIndexes() map[uint16]int {
+	indexes := make(map[uint16]int, len(s.Steps))
+	for i, si := range s.Steps {
+		if _, ok := indexes[si.ID]; !ok {
+			indexes[si.ID] = i
+		}
+	}
+	return indexes
+}
+
+// cyclePath formats the cycle of the visited path back to the step id, like "1 -> 2 -> 1".
+func cyclePath(path []uint16, id uint16) string {
+	start := 0
+	for i, p := range path {
+		if p == id {
+			start = i
+			break
+		}
+	}
+	ids := make([]string, 0, len(path)-start+1)
+	for _, p := range path[start:] {
+		ids = append(ids, fmt.Sprint(p))
+	}
+	ids = append(ids, fmt.Sprint(id))
+	return strings.Join(ids, " -> ")
+}
+
+// validationOrder returns the order of validating the steps and the envs defined for each step. Steps are validated
+// in their order and see the captures of the previous steps, unless the scenario has dependencies. Then each step
+// sees the captures of the steps it depends on.
+func (s *Scenario) validationOrder(definedEnvs map[string]struct{}) ([]int, func(i int) map[string]struct{}, error) {
+	if !s.HasDependencies() {
+		order := make([]int, len(s.Steps))
+		for i := range order {
+			order[i] = i
+		}
+		// validateStep adds the captures of each step to the shared envs
+		return order, func(int) map[string]struct{} { return definedEnvs }, nil
+	}
+
+	if len(s.WeightedScenarios) > 0 {
+		return nil, nil, fmt.Errorf("depends_on can not be used with the weighted scenarios")
+	}
+	order, err := s.DependencyOrder()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	indexes := s.stepIndexes()
+	visible := make([]map[string]struct{}, len(s.Steps))  // captures of the dependencies of the step
+	captured := make([]map[string]struct{}, len(s.Steps)) // captures of the step and its dependencies
+	for _, i := range order {
+		visible[i] = make(map[string]struct{})
+		for _, id := range s.Steps[i].DependsOn {
+			for k := range captured[indexes[id]] {
+				visible[i][k] = struct{}{}
+			}
+		}
+		captured[i] = make(map[string]struct{}, len(visible[i])+len(s.Steps[i].EnvsToCapture))
+		for k := range visible[i] {
+			captured[i][k] = struct{}{}
+		}
+		for _, ce := range s.Steps[i].EnvsToCapture {
+			for _, v := range ce.Vars() {
+				captured[i][v] = struct{}{}
+			}
+		}
+	}
+	stepEnvs := func(i int) map[string]struct{} {
+		envs := make(map[string]struct{}, len(definedEnvs)+len(visible[i]))
+		for k := range definedEnvs {
+			envs[k] = struct{}{}
+		}
+		for k := range visible[i] {
+			envs[k] = struct{}{}
+		}
+		return envs
+	}
+	return order, stepEnvs, nil
+}
+
+// keptDependencies returns the dependencies in kept, the dependencies not kept are replaced by their own ones.
+func (s *Scenario) keptDependencies(ids []uint16, kept map[uint16]bool) []uint16 {
+	deps := make(map[uint16][]uint16, len(s.Steps))
+	for _, si := range s.Steps {
+		deps[si.ID] = si.DependsOn
+	}
+
+	var resolved []uint16
+	seen := make(map[uint16]bool)
+	var resolve func(ids []uint16)
+	resolve = func(ids []uint16) {
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if kept[id] {
+				resolved = append(resolved, id)
+			} else {
+				resolve(deps[id])
+			}
+		}
+	}
+	resolve(ids)
+	return resolved
+}
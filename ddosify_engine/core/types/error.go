@@ -20,7 +20,10 @@
 
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Constants for custom error types and reasons
 const (
@@ -33,17 +36,50 @@ const (
 	ErrorParse          = "parseError"
 	ErrorAddr           = "addressError"
 	ErrorInvalidRequest = "invalidRequestError"
+	ErrorGraphQL        = "graphqlError"     // errors array in the response of a GraphQL step
+	ErrorTimeout        = "timeoutError"     // request, dial or tls handshake timeouts of the step
+	ErrorLocal          = "localError"       // resource limits of the load generator, not failures of the target
+	ErrorInterceptor    = "interceptorError" // errors returned by the request and response interceptors
+	ErrorCapture        = "captureError"     // failed fatal captures of the response, see RegexCaptureConf.Fatal
 
 	// Reasons
 	ReasonProxyFailed  = "proxy connection refused"
 	ReasonProxyTimeout = "proxy timeout"
 	ReasonConnTimeout  = "connection timeout"
 	ReasonReadTimeout  = "read timeout"
+	ReasonDialTimeout  = "dial timeout"
+	ReasonTLSTimeout   = "tls handshake timeout"
 	ReasonConnRefused  = "connection refused"
 
+	// The ephemeral ports of the load generator are exhausted, mostly by the connections in TIME_WAIT.
+	ReasonPortExhausted = "local ports exhausted (cannot assign requested address)"
+
 	// In gracefully stop, engine cancels the ongoing requests.
 	// We can detect the canceled requests with the help of this.
 	ReasonCtxCanceled = "context canceled"
+
+	// Requests not sent after the Hammer.MaxRequests are sent.
+	ReasonMaxRequests = "max requests reached"
+)
+
+// ErrorCategory is the bucket of a failed step result in the error breakdown of the report.
+type ErrorCategory string
+
+// Constants of the error categories
+const (
+	ErrorCategoryDNS       ErrorCategory = "dns"
+	ErrorCategoryConnect   ErrorCategory = "connect"
+	ErrorCategoryTLS       ErrorCategory = "tls"
+	ErrorCategoryTimeout   ErrorCategory = "timeout"
+	ErrorCategoryRead      ErrorCategory = "read"
+	ErrorCategoryWrite     ErrorCategory = "write"
+	ErrorCategoryHTTP4xx   ErrorCategory = "http_4xx"    // failed assertions of the responses with 4xx status codes
+	ErrorCategoryHTTP5xx   ErrorCategory = "http_5xx"    // failed assertions of the responses with 5xx status codes
+	ErrorCategoryAssertion ErrorCategory = "assertion"   // failed assertions or schema violations of the other responses
+	ErrorCategoryResponse  ErrorCategory = "response"    // errors reported in the response, like the GraphQL errors
+	ErrorCategoryLocal     ErrorCategory = "local"       // resource limits of the load generator, like the exhausted ports
+	ErrorCategoryIntercept ErrorCategory = "interceptor" // errors of the request and response interceptors
+	ErrorCategoryOther     ErrorCategory = "other"
 )
 
 // RequestError is our custom error struct created in the requester.Requester implementations.
@@ -57,6 +93,60 @@ func (e *RequestError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Type, e.Reason)
 }
 
+// Category returns the ErrorCategory of the error, connection errors are bucketed by their reasons.
+func (e *RequestError) Category() ErrorCategory {
+	switch e.Type {
+	case "":
+		return ""
+	case ErrorDns, ErrorAddr:
+		return ErrorCategoryDNS
+	case ErrorTimeout:
+		return ErrorCategoryTimeout
+	case ErrorProxy:
+		return ErrorCategoryConnect
+	case ErrorGraphQL, ErrorCapture:
+		return ErrorCategoryResponse
+	case ErrorLocal:
+		return ErrorCategoryLocal
+	case ErrorInterceptor:
+		return ErrorCategoryIntercept
+	case ErrorConn, ErrorUnkown:
+		reason := strings.ToLower(e.Reason)
+		if i := strings.LastIndex(reason, "\": "); i >= 0 {
+			// url errors are like `Get "https://target/path": reason`, path should not be matched
+			reason = reason[i+3:]
+		}
+		switch {
+		case strings.Contains(reason, "no such host") || strings.Contains(reason, "lookup "):
+			return ErrorCategoryDNS
+		case strings.Contains(reason, "timeout"):
+			return ErrorCategoryTimeout
+		case strings.Contains(reason, "tls") || strings.Contains(reason, "x509") ||
+			strings.Contains(reason, "certificate"):
+			return ErrorCategoryTLS
+		case strings.Contains(reason, "broken pipe") || strings.Contains(reason, "write"):
+			return ErrorCategoryWrite
+		case strings.Contains(reason, "connection reset") || strings.Contains(reason, "eof") ||
+			strings.Contains(reason, "read"):
+			return ErrorCategoryRead
+		case e.Type == ErrorConn:
+			return ErrorCategoryConnect
+		}
+	}
+	return ErrorCategoryOther
+}
+
+// ConnFailed reports whether the error is a connection level failure, after which the connections of the client
+// should not be reused.
+func (e *RequestError) ConnFailed() bool {
+	return e.Type == ErrorConn || e.Type == ErrorTimeout
+}
+
+// Local reports whether the error is caused by the resource limits of the load generator instead of the target.
+func (e *RequestError) Local() bool {
+	return e.Type == ErrorLocal
+}
+
 type ScenarioValidationError struct { // UnWrappable
 	msg        string
 	wrappedErr error
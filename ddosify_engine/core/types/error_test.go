@@ -0,0 +1,100 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import "testing"
+
+func TestRequestErrorCategory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err      RequestError
+		expected ErrorCategory
+	}{
+		{RequestError{}, ""},
+		{RequestError{Type: ErrorDns, Reason: "no such host"}, ErrorCategoryDNS},
+		{RequestError{Type: ErrorTimeout, Reason: ReasonTLSTimeout}, ErrorCategoryTimeout},
+		{RequestError{Type: ErrorProxy, Reason: ReasonProxyFailed}, ErrorCategoryConnect},
+		{RequestError{Type: ErrorConn, Reason: ReasonConnRefused}, ErrorCategoryConnect},
+		{RequestError{Type: ErrorConn, Reason: `Get "https://test.com": dial tcp: lookup test.com: no such host`}, ErrorCategoryDNS},
+		{RequestError{Type: ErrorConn, Reason: `Get "https://test.com": remote error: tls: bad certificate`}, ErrorCategoryTLS},
+		{RequestError{Type: ErrorConn, Reason: `Get "https://test.com": x509: certificate signed by unknown authority`}, ErrorCategoryTLS},
+		{RequestError{Type: ErrorConn, Reason: "connection reset by peer"}, ErrorCategoryRead},
+		{RequestError{Type: ErrorConn, Reason: `Post "https://test.com/threads": EOF`}, ErrorCategoryRead},
+		{RequestError{Type: ErrorConn, Reason: "write tcp 127.0.0.1:80: broken pipe"}, ErrorCategoryWrite},
+		{RequestError{Type: ErrorConn, Reason: `Get "https://test.com/read": dial tcp: connect: network is unreachable`}, ErrorCategoryConnect},
+		{RequestError{Type: ErrorGraphQL, Reason: "not found"}, ErrorCategoryResponse},
+		{RequestError{Type: ErrorCapture, Reason: "capture tok failed"}, ErrorCategoryResponse},
+		{RequestError{Type: ErrorLocal, Reason: ReasonPortExhausted}, ErrorCategoryLocal},
+		{RequestError{Type: ErrorInterceptor, Reason: "unsigned"}, ErrorCategoryIntercept},
+		{RequestError{Type: ErrorInvalidRequest, Reason: "invalid"}, ErrorCategoryOther},
+	}
+
+	for _, test := range tests {
+		if c := test.err.Category(); c != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.err.Reason, test.expected, c)
+		}
+	}
+}
+
+func TestRequestErrorConnFailed(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err      RequestError
+		expected bool
+	}{
+		{RequestError{}, false},
+		{RequestError{Type: ErrorConn, Reason: ReasonConnRefused}, true},
+		{RequestError{Type: ErrorTimeout, Reason: ReasonReadTimeout}, true},
+		{RequestError{Type: ErrorDns, Reason: "no such host"}, false},
+		{RequestError{Type: ErrorInvalidRequest, Reason: "invalid url"}, false},
+	}
+
+	for _, test := range tests {
+		if f := test.err.ConnFailed(); f != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.err.Type, test.expected, f)
+		}
+	}
+}
+
+func TestScenarioStepResultCategorize(t *testing.T) {
+	t.Parallel()
+
+	failed := []FailedAssertion{{Rule: "equals(status_code,200)"}}
+	tests := []struct {
+		result   ScenarioStepResult
+		expected ErrorCategory
+	}{
+		{ScenarioStepResult{StatusCode: 503}, ""}, // no assertion, not a failure
+		{ScenarioStepResult{StatusCode: 503, FailedAssertions: failed}, ErrorCategoryHTTP5xx},
+		{ScenarioStepResult{StatusCode: 404, FailedAssertions: failed}, ErrorCategoryHTTP4xx},
+		{ScenarioStepResult{StatusCode: 200, FailedAssertions: failed}, ErrorCategoryAssertion},
+		{ScenarioStepResult{StatusCode: 200, SchemaErrors: []string{"$.id: missing"}}, ErrorCategoryAssertion},
+		{ScenarioStepResult{Err: RequestError{Type: ErrorTimeout, Reason: ReasonReadTimeout}}, ErrorCategoryTimeout},
+	}
+
+	for _, test := range tests {
+		if c := test.result.Categorize(); c != test.expected {
+			t.Errorf("Expected %v, Found: %v", test.expected, c)
+		}
+	}
+}
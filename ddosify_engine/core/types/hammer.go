@@ -22,11 +22,16 @@ package types
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.ddosify.com/ddosify/core/proxy"
 	"go.ddosify.com/ddosify/core/util"
+	"golang.org/x/net/http/httpguts"
 )
 
 // Constants for Hammer field values
@@ -42,14 +47,35 @@ const (
 	EngineModeDdosify      = "ddosify"
 
 	// Default Values
-	DefaultIterCount     = 100
-	DefaultLoadType      = LoadTypeLinear
-	DefaultDuration      = 10
-	DefaultTimeout       = 5
-	DefaultMethod        = http.MethodGet
-	DefaultOutputType    = "stdout" // TODO: get this value from report.OutputTypeStdout when import cycle resolved.
-	DefaultSamplingCount = 3
-	DefaultSingleMode    = true
+	DefaultIterCount      = 100
+	DefaultLoadType       = LoadTypeLinear
+	DefaultDuration       = 10
+	DefaultTimeout        = 5
+	DefaultMethod         = http.MethodGet
+	DefaultOutputType     = "stdout" // TODO: get this value from report.OutputTypeStdout when import cycle resolved.
+	DefaultSamplingCount  = 3
+	DefaultSingleMode     = true
+	DefaultDNSQueryType   = "A"
+	DefaultDNSTransport   = "udp"
+	DefaultMaxRedirects   = 10 // like the net/http client
+	DefaultCertExpiryDays = 30
+	DefaultChunkSize      = 16 << 10 // of the chunked bodies
+
+	DefaultFailureSamplesPerCategory = 10
+	DefaultFailureSampleBodyBytes    = 4 << 10
+
+	// Percentile Modes
+	PercentileModeApproximate = "approximate"
+	PercentileModeExact       = "exact"
+
+	// Limits of the exported latency histograms, see LatencyBuckets
+	maxLatencySubBuckets = 100
+	maxLatencyBuckets    = 1000
+
+	// Backpressure Policies
+	BackpressureDrop  = "drop"
+	BackpressureQueue = "queue"
+	BackpressureBlock = "block"
 )
 
 var loadTypes = [...]string{LoadTypeLinear, LoadTypeIncremental, LoadTypeWaved}
@@ -78,7 +104,16 @@ type CsvConf struct {
 	Vars          map[string]Tag `json:"vars"` // "0":"name", "1":"city","2":"team"
 	SkipEmptyLine bool           `json:"skip_empty_line"`
 	AllowQuota    bool           `json:"allow_quota"`
-	Order         string         `json:"order"`
+	// "random", "sequential" or "round-robin". Round-robin is the sequential order that always wraps around.
+	Order string `json:"order"`
+	// Wraps around when the rows run out in sequential order, true if nil. Otherwise the iterations after the last
+	// row fail without sending their steps.
+	Circular *bool `json:"circular"`
+}
+
+// IsCircular reports whether the rows wrap around in sequential order, see Circular.
+func (c CsvConf) IsCircular() bool {
+	return c.Circular == nil || *c.Circular
 }
 
 // TimeRunCount is the data structure to store manual load type data.
@@ -94,6 +129,74 @@ type CustomCookie struct {
 	Raw      string `json:"raw"`
 }
 
+// InfluxConf is the InfluxDB v2 write endpoint that the influxdb output format posts the results to.
+type InfluxConf struct {
+	// Base url of the InfluxDB, like http://localhost:8086. Results are written to the OutputFile if empty.
+	URL    string
+	Org    string
+	Bucket string
+	Token  string
+
+	// Max number of the results in a request, and the max wait before posting the buffered ones.
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// OTelConf is the OpenTelemetry collector that the spans of the requests and the request metrics are exported to,
+// by the OTLP/HTTP protocol with the JSON encoding.
+type OTelConf struct {
+	// Base url of the OTLP/HTTP receiver of the collector, like http://localhost:4318. Disabled if empty.
+	Endpoint string
+
+	// Headers of the export requests, like an auth token
+	Headers map[string]string
+
+	// service.name resource attribute of the spans and metrics, "ddosify" if empty
+	ServiceName string
+
+	// Max number of the spans in a request, and the max wait before exporting the buffered spans and the metrics.
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// TimeSeriesConf is the file that the time-bucketed stats of the test are written to, a row per bucket
+// with the throughput, error rate and latency percentiles of the requests completed in it.
+type TimeSeriesConf struct {
+	// Path of the file. Disabled if empty.
+	File string
+
+	// Format of the rows [json, csv], json if empty.
+	Format string
+
+	// Length of the buckets, a second if zero.
+	Interval time.Duration
+}
+
+// FailureSamplesConf is the file that the complete requests and responses of the first failing requests of each
+// error category are written to, as JSON lines.
+type FailureSamplesConf struct {
+	// Path of the file. Disabled if empty.
+	File string
+
+	// Number of the failing requests written per error category, DefaultFailureSamplesPerCategory if zero.
+	PerCategory int
+
+	// Max bytes of the response bodies written to the samples, DefaultFailureSampleBodyBytes if zero.
+	MaxBodyBytes int64
+}
+
+// BodyBytes returns the max bytes of the response bodies written to the samples, the default if not set.
+// Zero if the samples are disabled.
+func (c FailureSamplesConf) BodyBytes() int64 {
+	if c.File == "" {
+		return 0
+	}
+	if c.MaxBodyBytes <= 0 {
+		return DefaultFailureSampleBodyBytes
+	}
+	return c.MaxBodyBytes
+}
+
 // Hammer is like a lighter for the engine.
 // It includes attack metadata and all necessary data to initialize the internal services in the engine.
 type Hammer struct {
@@ -106,24 +209,250 @@ type Hammer struct {
 	// Total Duration of the test in seconds.
 	TestDuration int
 
+	// Max requests per second sent by all the iterations, requests wait for their turn. Unlimited if zero.
+	RPS int
+
+	// Measures the latencies of the requests from the times they are ready to be sent, instead of their actual
+	// send times, so the requests held back by the RPS limit while a stalled target backs them up are not
+	// under-reported (coordinated omission). Needs RPS.
+	CorrectOmission bool
+
+	// Max number of the requests sent in the whole run, the test is stopped once they are sent. The iterations in
+	// flight complete their requests sent until then, the remaining steps of them are not sent. Unlimited if zero.
+	MaxRequests int64
+
+	// Max wait for the in-flight requests to complete when the test is stopped, they are canceled after it.
+	// Requests completed in the grace period are reported. In-flight requests are canceled immediately if zero.
+	GracePeriod time.Duration
+
+	// Hard ceiling of the wall-clock time of the test from its setup to its teardown, a backstop for the runs hanging
+	// past their duration. Once exceeded, the test is stopped, its in-flight requests and the teardown are canceled
+	// without the grace period, the result collected until then is reported and the test fails. Unlimited if zero.
+	MaxDuration time.Duration
+
+	// Max pause of a virtual user for the Retry-After delay of its throttled response, before its next request.
+	// A pause longer than it is cut to it. Throttled responses are counted either way, Retry-After is not honored
+	// if zero. See ScenarioStepResult.Throttled.
+	HonorRetryAfter time.Duration
+
+	// Max random delay of the start of each iteration, smooths the bursts of the iterations started together.
+	Jitter time.Duration
+
+	// Iterations scheduled at the beginning of the test are delayed by a random duration up to the end of the
+	// startup spread, so they don't start at once. Disabled if zero.
+	StartupSpread time.Duration
+
+	// Iterations started in the warm-up period at the beginning of the test are sent but excluded from the
+	// aggregated results and the test-wide assertions. Disabled if zero.
+	Warmup time.Duration
+
+	// Iterations started in the ramp-down period at the end of the test are sent but excluded from the
+	// aggregated results and the test-wide assertions, like the warm-up. Disabled if zero.
+	RampDown time.Duration
+
+	// Conditions that abort the test early, evaluated on the iterations completed in a sliding window.
+	// Ex: ["error_rate > 50% over 10s", "p99 > 5s"]
+	StopOn []string
+
+	// Absolute targets evaluated on the result at the end of the test, the test fails if one of them is not met.
+	// Ex: ["p99 < 800ms", "error_rate <= 1%", "throughput >= 1000rps"]
+	SLA []string
+
 	// Duration (in second) - Request count map. Example: {10: 1500, 50: 400, ...}
 	TimeRunCountMap TimeRunCount
 
+	// Load pattern, overrides LoadType and TimeRunCountMap if set.
+	LoadPattern LoadPattern
+
+	// Closed-loop load adjusting the concurrent users by the latency and the error rate, overrides LoadPattern,
+	// LoadType and TimeRunCountMap if set.
+	Adaptive *AdaptiveLoad
+
+	// Closed load of a fixed number of iterations for each user, overrides LoadPattern, LoadType, TimeRunCountMap,
+	// IterationCount and TestDuration if set.
+	UserQuota *UserQuota
+
+	// Limits the running iterations of an open load when the target can't keep up with it, unlimited if nil.
+	Backpressure *Backpressure
+
 	// Test Scenario
 	Scenario Scenario
 
+	// Seed of all the random values of the test, like the scenario mix, random data rows, sleep ranges, jitters
+	// and dynamic variables, makes them reproducible. Random if zero.
+	Seed int64
+
 	// Proxy/Proxies to use
 	Proxy proxy.Proxy
 
+	// Duration that the resolved addresses of the hosts are cached. Disabled if zero.
+	DNSCacheTTL time.Duration
+
+	// Addresses pinned to an ip, bypassing dns, in host:port:ip format. Ex: ["example.com:443:10.0.0.1"]
+	Resolve []string
+
+	// Local ips that the connections are bound to in round-robin order, to spread them across the interfaces.
+	// The system picks the source address if empty.
+	SourceAddrs []string
+
+	// Max open connections per host:port shared by all the steps and virtual users, like the connection limit
+	// of a partner API. Unlike the MaxConnsPerHost of the TransportConfig, which limits each transport, the
+	// requests wait for a connection of the test at the limit, up to their timeouts. The connections idle in
+	// the keep-alive pools count in the limit. Unlimited if zero.
+	MaxHostConns int
+
+	// Opens a new connection for each request, to measure the connection setup overhead.
+	DisableKeepAlive bool
+
+	// Virtual users keep the ETag and Last-Modified validators of the responses and revalidate them by
+	// the conditional requests, like the clients with a cache.
+	Revalidate bool
+
+	// Opens the connections of the initial clients to the hosts of the HTTP steps by a HEAD request before the test,
+	// so the handshakes are not in the latencies of the first requests.
+	PreWarm bool
+
+	// Assertion expression deciding the successful responses of the HTTP steps, like
+	// "status_code < 500 && response_time < 2000". The ExpectedStatus of a step overrides it. Disabled if empty.
+	SuccessWhen string
+
+	// Name of the header carrying the unique ID of each HTTP request, like "X-Request-Id", to find the requests
+	// in the server logs. The same ID is the RequestID of the result. Disabled if empty.
+	RequestIDHeader string
+
+	// User-Agent headers of the HTTP steps rotated over the requests or the virtual users. Disabled if there are
+	// no values.
+	UserAgents UserAgentConf
+
+	// Number of the virtual users of the repeated-user mode that are pinned to their own clients, for sticky
+	// sessions. Iteration i is run by the user i % StickyUsers, the users are mapped to the clients by modulo, each
+	// user has a client of its own. The iterations of a user run one at a time over its client, so it caps the
+	// concurrent iterations of the load: at most StickyUsers iterations are in flight, the others wait for their
+	// users. Disabled if zero.
+	StickyUsers int
+
+	// Limits the live clients of the client pool of the distinct-user and repeated-user modes to its capacity,
+	// iterations wait for a client to be put back instead of creating a new one. Otherwise the pool only keeps
+	// up to its capacity of idle clients and the exceeding of the capacity is reported.
+	CapClientPool bool
+
+	// Sizes the idle clients kept by the client pool of the distinct-user mode by the demand, between the max
+	// concurrent iterations and the iteration count, instead of keeping up to the iteration count of them.
+	AdaptiveClientPool bool
+
+	// Pools the clients of the HTTP steps of the distinct-user and repeated-user modes per host, by the scheme,
+	// host and port of the step urls, so the connections of a pooled client are only reused against its own host.
+	// Each step of an iteration takes a client of its host, the following steps of the same host reuse it. The
+	// clients of an iteration share the cookies of their own hosts only.
+	ClientPerHost bool
+
+	// Max age of the pooled clients of the distinct-user and repeated-user modes. A client older than it is not
+	// handed out anymore, its connections are closed and a new client is created instead, so the long-lived
+	// connections dropped by the load balancers are rotated. Clients don't expire if zero.
+	ClientTTL time.Duration
+
+	// Idle time after which the pooled clients of the distinct-user and repeated-user modes are probed before they
+	// are reused. A client idle in the pool for longer than it sends a HEAD request to the targets of the HTTP
+	// steps, a client failing by a connection error is closed and replaced, so the broken connections don't fail
+	// the first requests of the iterations. Not probed if zero.
+	ClientHealthCheck time.Duration
+
+	// Keeps the pooled client of each virtual user of the UserQuota and Adaptive loads for the lifetime of the user,
+	// so its iterations reuse the same connections like a browser tab. The client is put back to the pool when the
+	// user is stopped. The clients are taken from the pool for each iteration otherwise.
+	ClientAffinity bool
+
+	// Connection limits of the transports of the HTTP steps, the defaults of the engine mode are kept if zero.
+	Transport TransportConf
+
+	// Captures the peer certificates of the TLS connections of the HTTP steps and summarizes them in the report.
+	// Disabled if nil.
+	CertAudit *CertAudit
+
+	// Runs only the steps having any of these tags, the other steps are not sent. All the steps run if empty.
+	OnlyTags []string
+
+	// Latency percentiles reported instead of the p50, p90, p95 and p99, like 99.9 and 99.99.
+	Percentiles []float64
+
+	// PercentileModeExact computes the percentiles from all the response times kept in the memory,
+	// PercentileModeApproximate estimates them from the latency histograms. Approximate if empty.
+	PercentileMode string
+
+	// Boundaries of the exported latency histograms, used by the Prometheus latency metrics and the histograms of
+	// the steps in the JSON result. The metrics use their default buckets and the JSON result has no histograms if nil.
+	LatencyBuckets *LatencyBuckets
+
+	// Fraction of the iterations recorded into the latency histograms of the result and sent to the per request
+	// outputs, like 0.1 for one in ten iterations. The counters of the result, the live metrics and the time series
+	// stay exact, their percentiles are estimated from the sampled iterations. All the iterations are recorded if zero.
+	SampleRate float64
+
 	// Destination of the results data.
 	ReportDestination string
 
+	// Shows a live dashboard of the throughput, latency percentiles, error rate and status codes instead of
+	// the live result lines of the stdout report. Updated in place on a terminal, printed as plain lines otherwise.
+	Dashboard bool
+
+	// Listen address of the Prometheus metrics server, like ":9090". Disabled if empty.
+	MetricsAddr string
+
+	// Listen address of the control API, like ":9091". The test is paused by POST /pause and resumed by
+	// POST /resume, the paused periods are excluded from the achieved rates. Disabled if empty.
+	ControlAddr string
+
+	// Format of the per request results written to OutputFile [json, csv, influxdb]. Disabled if empty.
+	OutputFormat string
+	OutputFile   string
+
+	// Streams the per request results to stdout as json lines during the test, the report is printed to stderr.
+	// Results are dropped and counted while stdout can't keep up with the load.
+	StreamJSON bool
+
+	// Destination of the influxdb output format, if it is not written to the OutputFile.
+	Influx InfluxConf
+
+	// Collector that the spans of the requests and the metrics are exported to. Disabled if the endpoint is empty.
+	OTel OTelConf
+
+	// Time-bucketed stats of the test. Disabled if the file is empty.
+	TimeSeries TimeSeriesConf
+
+	// Requests and responses of the first failing requests of each error category. Disabled if the file is empty.
+	FailureSamples FailureSamplesConf
+
+	// Called with the result of each request, like for a debug log or a custom sink. Optional.
+	// Called from a single goroutine other than the load generating ones. Results are dropped if the
+	// callback can't keep up with them, it should not block for long.
+	OnResult func(*ScenarioStepResult)
+
+	// Custom destinations of the result of each request, like a message queue. The results are fanned out to all
+	// of them concurrently, each one has its own buffer. Optional.
+	ResultSinks []ResultSinkConf
+
+	// Creates the clients of the virtual users of the distinct-user and repeated-user modes, like the clients of a
+	// custom http.RoundTripper for request signing. A custom Transport is used as is, the proxy, TLS and connection
+	// settings of the steps are not applied to it. Clients without a Jar get the cookie jar of the engine mode.
+	// The default clients are created if nil. Optional.
+	ClientFactory func() *http.Client
+
+	// Called in order with each request of the HTTP steps just before it is sent, and with each response of them
+	// before its body is read, like for the request signing. The chain stops at the first error, which fails the
+	// request with the ErrorInterceptor. Called from the load generating goroutines concurrently. Optional.
+	RequestInterceptors  []RequestInterceptor
+	ResponseInterceptors []ResponseInterceptor
+
 	// Dynamic field for extra parameters.
 	Others map[string]interface{}
 
 	// Debug mode on/off
 	Debug bool
 
+	// Fails the test if any step of the debug iteration fails by an error, a failed capture, an assertion or a
+	// schema violation, to check the scenario before a real run. Needs Debug.
+	DryRun bool
+
 	// Sampling rate
 	SamplingRate int
 
@@ -147,6 +476,99 @@ type Hammer struct {
 }
 
 // Validate validates attack metadata and executes the validation methods of the services.
+// CertAudit reports the certificate issues of the targets, like the expired or soon expiring certificates,
+// hostname mismatches, unverified chains and weak TLS versions, without failing the requests.
+type CertAudit struct {
+	// Certificates expiring in this many days are reported, the expired ones are always reported.
+	ExpiryDays int
+}
+
+// UserQuota is the closed load that runs the scenario Iterations times for each of the Users, back to back.
+// The test ends when all the users complete their iterations, so the planned iterations are run exactly,
+// unless the test is stopped.
+type UserQuota struct {
+	Users      int
+	Iterations int
+}
+
+// Total returns the planned iterations of the test.
+func (q *UserQuota) Total() int {
+	return q.Users * q.Iterations
+}
+
+// Backpressure limits the running iterations to MaxInFlight. Policy decides the iterations scheduled while
+// MaxInFlight iterations are running:
+//   - drop: they are not started and counted as dropped, so the latencies are not skewed by the slow target
+//     delaying the schedule (coordinated omission) and the shortfall is visible in the achieved load.
+//   - queue: they wait for a running iteration to complete, up to QueueSize of them. The ones over it are dropped.
+//   - block: the scheduler waits for a running iteration to complete, the rest of the schedule is shifted.
+type Backpressure struct {
+	Policy      string
+	MaxInFlight int
+	QueueSize   int // only for the queue policy
+}
+
+func (b *Backpressure) validate() error {
+	if !util.StringInSlice(b.Policy, []string{BackpressureDrop, BackpressureQueue, BackpressureBlock}) {
+		return fmt.Errorf("unsupported backpressure policy: %s", b.Policy)
+	}
+	if b.MaxInFlight < 1 {
+		return fmt.Errorf("backpressure max in flight should be greater than 0")
+	}
+	if b.Policy == BackpressureQueue && b.QueueSize < 1 {
+		return fmt.Errorf("backpressure queue size should be greater than 0 for the %s policy", BackpressureQueue)
+	}
+	if b.Policy != BackpressureQueue && b.QueueSize != 0 {
+		return fmt.Errorf("backpressure queue size can only be used with the %s policy", BackpressureQueue)
+	}
+	return nil
+}
+
+// LatencyBuckets are the log-linear boundaries of a latency histogram, like the ones of an HDR histogram. The first
+// bucket ends at Min, each power of two from Min to Max is split into SubBuckets linear buckets and the last
+// bucket ends at Max.
+type LatencyBuckets struct {
+	Min        time.Duration
+	Max        time.Duration
+	SubBuckets int // 1 if zero, the boundaries are the powers of two
+}
+
+// Bounds returns the upper bounds of the buckets in ascending order.
+func (b *LatencyBuckets) Bounds() []time.Duration {
+	subs := b.SubBuckets
+	if subs < 1 {
+		subs = 1
+	}
+	bounds := []time.Duration{b.Min}
+	for base := b.Min; base < b.Max; base *= 2 {
+		step := base / time.Duration(subs)
+		for i := 1; i <= subs; i++ {
+			v := base + step*time.Duration(i)
+			if v >= b.Max {
+				return append(bounds, b.Max)
+			}
+			bounds = append(bounds, v)
+		}
+	}
+	return bounds
+}
+
+func (b *LatencyBuckets) validate() error {
+	if b.Min < time.Microsecond {
+		return fmt.Errorf("latency buckets min should be at least 1µs")
+	}
+	if b.Max <= b.Min {
+		return fmt.Errorf("latency buckets max should be greater than min")
+	}
+	if b.SubBuckets < 0 || b.SubBuckets > maxLatencySubBuckets {
+		return fmt.Errorf("latency sub buckets should be between 0 and %d", maxLatencySubBuckets)
+	}
+	if n := len(b.Bounds()); n > maxLatencyBuckets {
+		return fmt.Errorf("latency buckets should be at most %d, found %d", maxLatencyBuckets, n)
+	}
+	return nil
+}
+
 func (h *Hammer) Validate() error {
 	if len(h.Scenario.Steps) == 0 {
 		return fmt.Errorf("scenario or target is empty")
@@ -165,6 +587,226 @@ func (h *Hammer) Validate() error {
 		return fmt.Errorf("unsupported EngineMode: %s", h.EngineMode)
 	}
 
+	if h.Proxy.Addr != nil && !util.StringInSlice(strings.ToLower(h.Proxy.Addr.Scheme), proxy.SupportedSchemes[:]) {
+		return fmt.Errorf("unsupported proxy scheme: %s, supported schemes are %v", h.Proxy.Addr.Scheme, proxy.SupportedSchemes)
+	}
+
+	if h.RPS < 0 {
+		return fmt.Errorf("rps should be greater than or equal to 0")
+	}
+	if h.DryRun && !h.Debug {
+		return fmt.Errorf("dry run needs the debug mode")
+	}
+	if h.CorrectOmission && h.RPS == 0 {
+		return fmt.Errorf("coordinated omission correction needs an rps")
+	}
+	if h.MaxRequests < 0 {
+		return fmt.Errorf("max requests should be greater than or equal to 0")
+	}
+	if h.GracePeriod < 0 {
+		return fmt.Errorf("grace period should be greater than or equal to 0")
+	}
+	if h.MaxDuration < 0 {
+		return fmt.Errorf("max duration should be greater than or equal to 0")
+	}
+	if h.HonorRetryAfter < 0 {
+		return fmt.Errorf("honor retry after should be greater than or equal to 0")
+	}
+	if h.Warmup < 0 {
+		return fmt.Errorf("warmup should be greater than or equal to 0")
+	}
+	if h.RampDown < 0 {
+		return fmt.Errorf("ramp down should be greater than or equal to 0")
+	}
+	if h.Jitter < 0 || h.StartupSpread < 0 {
+		return fmt.Errorf("jitter and startup spread should be greater than or equal to 0")
+	}
+	if err := h.UserAgents.validate(); err != nil {
+		return err
+	}
+	if h.StickyUsers < 0 {
+		return fmt.Errorf("sticky users should be greater than or equal to 0")
+	}
+	for _, s := range h.ResultSinks {
+		if err := s.validate(); err != nil {
+			return err
+		}
+	}
+	if h.ClientFactory != nil && h.EngineMode != EngineModeDistinctUser && h.EngineMode != EngineModeRepeatedUser {
+		return fmt.Errorf("client factory is only supported in %s and %s engine modes",
+			EngineModeDistinctUser, EngineModeRepeatedUser)
+	}
+	if h.AdaptiveClientPool && h.EngineMode != EngineModeDistinctUser {
+		return fmt.Errorf("adaptive client pool is only supported in %s engine mode", EngineModeDistinctUser)
+	}
+	if h.StickyUsers > 0 && h.EngineMode != EngineModeRepeatedUser {
+		return fmt.Errorf("sticky users are only supported in %s engine mode", EngineModeRepeatedUser)
+	}
+	if h.ClientPerHost {
+		if h.EngineMode != EngineModeDistinctUser && h.EngineMode != EngineModeRepeatedUser {
+			return fmt.Errorf("client per host is only supported in %s and %s engine modes",
+				EngineModeDistinctUser, EngineModeRepeatedUser)
+		}
+		if h.StickyUsers > 0 || h.ClientAffinity || h.CapClientPool || h.AdaptiveClientPool {
+			return fmt.Errorf("client per host can not be used with sticky users, client affinity, " +
+				"cap client pool and adaptive client pool")
+		}
+	}
+	if h.ClientTTL < 0 {
+		return fmt.Errorf("client ttl should be greater than or equal to 0")
+	}
+	if h.ClientTTL > 0 {
+		if h.EngineMode != EngineModeDistinctUser && h.EngineMode != EngineModeRepeatedUser {
+			return fmt.Errorf("client ttl is only supported in %s and %s engine modes",
+				EngineModeDistinctUser, EngineModeRepeatedUser)
+		}
+		if h.StickyUsers > 0 {
+			return fmt.Errorf("client ttl can not be used with sticky users")
+		}
+	}
+	if h.ClientHealthCheck < 0 {
+		return fmt.Errorf("client health check should be greater than or equal to 0")
+	}
+	if h.ClientHealthCheck > 0 {
+		if h.EngineMode != EngineModeDistinctUser && h.EngineMode != EngineModeRepeatedUser {
+			return fmt.Errorf("client health check is only supported in %s and %s engine modes",
+				EngineModeDistinctUser, EngineModeRepeatedUser)
+		}
+		if h.StickyUsers > 0 {
+			return fmt.Errorf("client health check can not be used with sticky users")
+		}
+	}
+	if h.ClientAffinity {
+		if h.EngineMode != EngineModeDistinctUser && h.EngineMode != EngineModeRepeatedUser {
+			return fmt.Errorf("client affinity is only supported in %s and %s engine modes",
+				EngineModeDistinctUser, EngineModeRepeatedUser)
+		}
+		if h.UserQuota == nil && h.Adaptive == nil {
+			return fmt.Errorf("client affinity needs the virtual users of a user quota or an adaptive load")
+		}
+		if h.StickyUsers > 0 {
+			return fmt.Errorf("client affinity can not be used with sticky users")
+		}
+	}
+	for _, steps := range [][]ScenarioStep{h.Scenario.AllSteps(), h.Scenario.Setup, h.Scenario.Teardown} {
+		for _, st := range steps {
+			if st.Auth.Type != AuthNTLM {
+				continue
+			}
+			// the handshake authenticates the connection, the virtual users keep a connection per host
+			if h.EngineMode != EngineModeDistinctUser && h.EngineMode != EngineModeRepeatedUser {
+				return fmt.Errorf("%s auth is only supported in %s and %s engine modes", AuthNTLM,
+					EngineModeDistinctUser, EngineModeRepeatedUser)
+			}
+			if h.DisableKeepAlive || st.DisableKeepAlive {
+				return fmt.Errorf("%s auth can not be used with the keep-alive disabled", AuthNTLM)
+			}
+		}
+	}
+	if _, err := ParseStopConditions(h.StopOn); err != nil {
+		return err
+	}
+	if _, err := ParseSLAChecks(h.SLA); err != nil {
+		return err
+	}
+	if h.DNSCacheTTL < 0 {
+		return fmt.Errorf("dns cache ttl should be greater than or equal to 0")
+	}
+	if _, err := ParseResolve(h.Resolve); err != nil {
+		return err
+	}
+	if _, err := ParseSourceAddrs(h.SourceAddrs); err != nil {
+		return err
+	}
+	if h.MaxHostConns < 0 {
+		return fmt.Errorf("max host conns should be greater than or equal to 0")
+	}
+	if h.CertAudit != nil && h.CertAudit.ExpiryDays < 0 {
+		return fmt.Errorf("cert audit expiry days should be greater than or equal to 0")
+	}
+	if err := validatePercentiles(h.Percentiles); err != nil {
+		return err
+	}
+	if h.PercentileMode != "" && h.PercentileMode != PercentileModeApproximate &&
+		h.PercentileMode != PercentileModeExact {
+		return fmt.Errorf("unsupported percentile mode: %s", h.PercentileMode)
+	}
+	if h.LatencyBuckets != nil {
+		if err := h.LatencyBuckets.validate(); err != nil {
+			return err
+		}
+	}
+	if h.SampleRate < 0 || h.SampleRate > 1 {
+		return fmt.Errorf("sample rate should be between 0 and 1")
+	}
+	if h.UserQuota != nil {
+		if h.UserQuota.Users < 1 || h.UserQuota.Iterations < 1 {
+			return fmt.Errorf("user quota needs users and iterations of at least 1")
+		}
+		if h.Adaptive != nil || h.LoadPattern != nil || len(h.TimeRunCountMap) > 0 || h.RPS > 0 {
+			return fmt.Errorf("user quota can't be used with the adaptive load, load patterns, manual_load and rps")
+		}
+	}
+	if h.Backpressure != nil {
+		if err := h.Backpressure.validate(); err != nil {
+			return err
+		}
+		if h.Adaptive != nil || h.UserQuota != nil {
+			return fmt.Errorf("backpressure can't be used with the adaptive load and the user quota")
+		}
+	}
+	if h.Adaptive != nil {
+		if err := h.Adaptive.Validate(); err != nil {
+			return err
+		}
+		if h.ControlAddr != "" {
+			return fmt.Errorf("control api is not supported with the adaptive load")
+		}
+	}
+	if h.RequestIDHeader != "" && !httpguts.ValidHeaderFieldName(h.RequestIDHeader) {
+		return fmt.Errorf("request id header is not a valid header name: %s", h.RequestIDHeader)
+	}
+	if err := h.Transport.validate(); err != nil {
+		return err
+	}
+	if len(h.OnlyTags) > 0 && len(h.Scenario.FilterTags(h.OnlyTags).Steps) == 0 {
+		return fmt.Errorf("no step has the tags: %s", strings.Join(h.OnlyTags, ", "))
+	}
+
+	if h.OutputFormat != "" && h.OutputFile == "" && h.Influx.URL == "" {
+		return fmt.Errorf("output file should be given for output format: %s", h.OutputFormat)
+	}
+	if h.Influx.URL != "" {
+		if u, err := url.Parse(h.Influx.URL); err != nil || u.Host == "" || !(u.Scheme == "http" || u.Scheme == "https") {
+			return fmt.Errorf("influx url is not valid: %s", h.Influx.URL)
+		}
+		if h.Influx.Bucket == "" {
+			return fmt.Errorf("influx bucket should be given for the influx url")
+		}
+		if h.Influx.BatchSize < 0 || h.Influx.FlushInterval < 0 {
+			return fmt.Errorf("influx batch size and flush interval should be greater than or equal to 0")
+		}
+	}
+	if h.OTel.Endpoint != "" {
+		if u, err := url.Parse(h.OTel.Endpoint); err != nil || u.Host == "" || !(u.Scheme == "http" || u.Scheme == "https") {
+			return fmt.Errorf("otel endpoint is not valid: %s", h.OTel.Endpoint)
+		}
+		if h.OTel.BatchSize < 0 || h.OTel.FlushInterval < 0 {
+			return fmt.Errorf("otel batch size and flush interval should be greater than or equal to 0")
+		}
+	}
+	if h.TimeSeries.File != "" {
+		if f := strings.ToLower(h.TimeSeries.Format); f != "" && f != "json" && f != "csv" {
+			return fmt.Errorf("unsupported timeseries format: %s", h.TimeSeries.Format)
+		}
+		if h.TimeSeries.Interval < 0 {
+			return fmt.Errorf("timeseries interval should be greater than or equal to 0")
+		}
+	}
+	if h.FailureSamples.File != "" && (h.FailureSamples.PerCategory < 0 || h.FailureSamples.MaxBodyBytes < 0) {
+		return fmt.Errorf("failure samples per category and max body bytes should be greater than or equal to 0")
+	}
+
 	if len(h.TimeRunCountMap) > 0 {
 		for _, t := range h.TimeRunCountMap {
 			if t.Duration < 1 {
@@ -176,6 +818,47 @@ func (h *Hammer) Validate() error {
 	return nil
 }
 
+// ValidateSteps validates the steps of the scenario, errors are returned by the index of the invalid steps.
+// See Scenario.ValidateSteps.
+func (h *Hammer) ValidateSteps() (map[int]error, error) {
+	h.Scenario.CsvVars = getCsvEnvs(h.TestDataConf)
+	return h.Scenario.ValidateSteps()
+}
+
+// ParseResolve parses the resolve entries in host:port:ip format into a host:port -> ip map.
+// IPv6 addresses can be given in brackets, like "example.com:443:[::1]".
+func ParseResolve(entries []string) (map[string]string, error) {
+	resolve := make(map[string]string, len(entries))
+	for _, e := range entries {
+		parts := strings.SplitN(e, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid resolve entry: %s, format should be host:port:ip", e)
+		}
+		if port, err := strconv.Atoi(parts[1]); err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port in resolve entry: %s", e)
+		}
+		ip := strings.TrimSuffix(strings.TrimPrefix(parts[2], "["), "]")
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid ip in resolve entry: %s", e)
+		}
+		resolve[net.JoinHostPort(strings.ToLower(parts[0]), parts[1])] = ip
+	}
+	return resolve, nil
+}
+
+// ParseSourceAddrs parses the source addresses of the connections, they should be ips.
+func ParseSourceAddrs(addrs []string) ([]net.IP, error) {
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(a, "["), "]"))
+		if ip == nil {
+			return nil, fmt.Errorf("invalid source address: %s, it should be an ip", a)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
 func getCsvEnvs(testDataConf map[string]CsvConf) []string {
 	csvVars := make([]string, 0)
 
@@ -194,3 +877,17 @@ func getCsvEnvs(testDataConf map[string]CsvConf) []string {
 
 	return csvVars
 }
+
+func validatePercentiles(percentiles []float64) error {
+	seen := make(map[float64]bool, len(percentiles))
+	for _, p := range percentiles {
+		if p <= 0 || p > 100 {
+			return fmt.Errorf("percentile should be greater than 0 and less than or equal to 100: %v", p)
+		}
+		if seen[p] {
+			return fmt.Errorf("duplicate percentile: %v", p)
+		}
+		seen[p] = true
+	}
+	return nil
+}
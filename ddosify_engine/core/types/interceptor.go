@@ -0,0 +1,33 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import "net/http"
+
+// RequestInterceptor is called with each request of the HTTP steps just before it is sent, after all the settings
+// of the step are applied to it, like to sign the request or to add a header. The request can be modified in place.
+// Returning an error fails the request with the ErrorInterceptor, it is not sent.
+type RequestInterceptor func(*http.Request) error
+
+// ResponseInterceptor is called with each response of the HTTP steps before its body is read by the captures and the
+// assertions, like to verify a signature header. The body can be replaced but should be closed if it is. Returning
+// an error fails the request with the ErrorInterceptor.
+type ResponseInterceptor func(*http.Response) error
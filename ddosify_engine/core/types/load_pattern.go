@@ -0,0 +1,197 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Constants of the Load Pattern types
+const (
+	LoadPatternConstant = "constant"
+	LoadPatternRamp     = "ramp"
+	LoadPatternStep     = "step"
+	LoadPatternSpike    = "spike"
+)
+
+// LoadPattern determines how many iterations to start per second at the elapsed time t of the test.
+// The engine queries the pattern for every second of the test duration.
+type LoadPattern interface {
+	RequestsAt(t time.Duration) int
+}
+
+// LoadPatternConf is the configuration of the built-in load patterns. Rates are iterations per second.
+type LoadPatternConf struct {
+	Type string
+
+	// Total duration of the pattern, also the duration of the test
+	Duration time.Duration
+
+	// constant
+	Rate int
+
+	// ramp, step and spike
+	Start int
+	End   int
+
+	// step
+	Step         int
+	StepDuration time.Duration
+
+	// spike
+	Peak          int
+	SpikeAt       time.Duration
+	SpikeDuration time.Duration
+
+	// Optional ramp-down phase after the Duration, the rate decreases linearly to zero in it.
+	// The test lasts Duration + RampDown.
+	RampDown time.Duration
+}
+
+// NewLoadPattern validates the given configuration and creates the corresponding LoadPattern.
+func NewLoadPattern(c LoadPatternConf) (LoadPattern, error) {
+	if c.Duration < time.Second {
+		return nil, fmt.Errorf("load pattern duration should be at least 1s")
+	}
+	if c.Rate < 0 || c.Start < 0 || c.End < 0 || c.Peak < 0 {
+		return nil, fmt.Errorf("load pattern rates can not be negative")
+	}
+	if c.RampDown < 0 {
+		return nil, fmt.Errorf("load pattern ramp_down can not be negative")
+	}
+
+	p, err := newLoadPattern(c)
+	if err != nil || c.RampDown == 0 {
+		return p, err
+	}
+	return &RampDownPattern{Pattern: p, From: c.Duration, Duration: c.RampDown}, nil
+}
+
+func newLoadPattern(c LoadPatternConf) (LoadPattern, error) {
+	switch c.Type {
+	case LoadPatternConstant:
+		return &ConstantPattern{Rate: c.Rate}, nil
+	case LoadPatternRamp:
+		return &RampPattern{Start: c.Start, End: c.End, Duration: c.Duration}, nil
+	case LoadPatternStep:
+		if c.Step <= 0 || c.StepDuration < time.Second {
+			return nil, fmt.Errorf("step load pattern needs a positive step and a step_duration of at least 1s")
+		}
+		return &StepPattern{Start: c.Start, End: c.End, Step: c.Step, StepDuration: c.StepDuration}, nil
+	case LoadPatternSpike:
+		if c.SpikeDuration <= 0 {
+			return nil, fmt.Errorf("spike load pattern needs a positive spike_duration")
+		}
+		return &SpikePattern{Base: c.Start, Peak: c.Peak, Sustain: c.End,
+			SpikeAt: c.SpikeAt, SpikeDuration: c.SpikeDuration}, nil
+	default:
+		return nil, fmt.Errorf("unsupported load pattern: %s", c.Type)
+	}
+}
+
+// ConstantPattern starts the same number of iterations every second.
+type ConstantPattern struct {
+	Rate int
+}
+
+func (p *ConstantPattern) RequestsAt(t time.Duration) int {
+	return p.Rate
+}
+
+// RampPattern increases (or decreases) the rate linearly from Start to End in Duration.
+type RampPattern struct {
+	Start    int
+	End      int
+	Duration time.Duration
+}
+
+func (p *RampPattern) RequestsAt(t time.Duration) int {
+	if t >= p.Duration {
+		return p.End
+	}
+	progress := float64(t) / float64(p.Duration)
+	return p.Start + int(float64(p.End-p.Start)*progress)
+}
+
+// StepPattern increases the rate by Step in every StepDuration, starting from Start and capped at End.
+type StepPattern struct {
+	Start        int
+	End          int
+	Step         int
+	StepDuration time.Duration
+}
+
+func (p *StepPattern) RequestsAt(t time.Duration) int {
+	rate := p.Start + int(t/p.StepDuration)*p.Step
+	if rate > p.End {
+		return p.End
+	}
+	return rate
+}
+
+// SpikePattern runs at Base rate until SpikeAt, jumps to Peak for SpikeDuration, then sustains at Sustain rate.
+type SpikePattern struct {
+	Base          int
+	Peak          int
+	Sustain       int
+	SpikeAt       time.Duration
+	SpikeDuration time.Duration
+}
+
+func (p *SpikePattern) RequestsAt(t time.Duration) int {
+	switch {
+	case t < p.SpikeAt:
+		return p.Base
+	case t < p.SpikeAt+p.SpikeDuration:
+		return p.Peak
+	default:
+		return p.Sustain
+	}
+}
+
+// RampDownPattern follows Pattern until From, then decreases the rate linearly from the rate of Pattern
+// at From to zero in Duration.
+type RampDownPattern struct {
+	Pattern  LoadPattern
+	From     time.Duration
+	Duration time.Duration
+}
+
+func (p *RampDownPattern) RequestsAt(t time.Duration) int {
+	if t < p.From {
+		return p.Pattern.RequestsAt(t)
+	}
+	if t >= p.From+p.Duration {
+		return 0
+	}
+	progress := float64(t-p.From) / float64(p.Duration)
+	return int(float64(p.Pattern.RequestsAt(p.From)) * (1 - progress))
+}
+
+// TotalIterations returns the number of iterations the pattern starts in the given duration.
+func TotalIterations(p LoadPattern, duration int) int {
+	total := 0
+	for i := 0; i < duration; i++ {
+		total += p.RequestsAt(time.Duration(i) * time.Second)
+	}
+	return total
+}
@@ -0,0 +1,96 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadPatterns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		conf     LoadPatternConf
+		expected []int // rates for each second
+	}{
+		{
+			name:     "constant",
+			conf:     LoadPatternConf{Type: LoadPatternConstant, Rate: 7, Duration: 3 * time.Second},
+			expected: []int{7, 7, 7},
+		},
+		{
+			name:     "ramp",
+			conf:     LoadPatternConf{Type: LoadPatternRamp, Start: 10, End: 50, Duration: 4 * time.Second},
+			expected: []int{10, 20, 30, 40},
+		},
+		{
+			name: "step",
+			conf: LoadPatternConf{Type: LoadPatternStep, Start: 5, End: 12, Step: 5,
+				StepDuration: 2 * time.Second, Duration: 7 * time.Second},
+			expected: []int{5, 5, 10, 10, 12, 12, 12},
+		},
+		{
+			name: "spike",
+			conf: LoadPatternConf{Type: LoadPatternSpike, Start: 10, Peak: 100, End: 20,
+				SpikeAt: 2 * time.Second, SpikeDuration: time.Second, Duration: 5 * time.Second},
+			expected: []int{10, 10, 100, 20, 20},
+		},
+		{
+			name: "ramp down",
+			conf: LoadPatternConf{Type: LoadPatternConstant, Rate: 20, Duration: 2 * time.Second,
+				RampDown: 4 * time.Second},
+			expected: []int{20, 20, 20, 15, 10, 5, 0},
+		},
+	}
+
+	for _, tc := range tests {
+		p, err := NewLoadPattern(tc.conf)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for i, e := range tc.expected {
+			if got := p.RequestsAt(time.Duration(i) * time.Second); got != e {
+				t.Errorf("%s at %ds: Expected %d, Found: %d", tc.name, i, e, got)
+			}
+		}
+	}
+}
+
+func TestNewLoadPatternInvalid(t *testing.T) {
+	t.Parallel()
+
+	confs := []LoadPatternConf{
+		{Type: LoadPatternRamp, Start: 1, End: 10},                                          // no duration
+		{Type: "sine", Duration: time.Second},                                               // unknown type
+		{Type: LoadPatternStep, Start: 1, End: 10, Duration: time.Second},                   // no step
+		{Type: LoadPatternConstant, Rate: -1, Duration: time.Second},                        // negative rate
+		{Type: LoadPatternSpike, Start: 1, Peak: 10, End: 5, Duration: 10 * time.Second},    // no spike duration
+		{Type: LoadPatternConstant, Rate: 1, Duration: time.Second, RampDown: -time.Second}, // negative ramp down
+	}
+
+	for _, c := range confs {
+		if _, err := NewLoadPattern(c); err == nil {
+			t.Errorf("Expected error for %+v", c)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package regex
 
-const DynamicVariableRegex = `\{{(_)[^}]+\}}`
-const JsonDynamicVariableRegex = `\"{{(_)[^}]+\}}"`
+// Template functions with arguments like {{randomInt(1,100)}}, evaluated like dynamic variables.
+// Arguments can contain variables like {{sha256Hex("{{body}}")}}.
+const templateFunctionRegex = `(?:uuid|randomInt|randomFloat|randomString|randomEmail|now|timestamp|` +
+	`base64|hmacSHA256|sha256Hex|md5Hex)\((?:[^}]|{{[^{}]*}})*\)`
+const TemplateFunctionRegex = `^` + templateFunctionRegex + `$`
+
+const DynamicVariableRegex = `\{{(_[^}]+|` + templateFunctionRegex + `)\}}`
+const JsonDynamicVariableRegex = `\"{{(_[^}]+|` + templateFunctionRegex + `)\}}"`
 
 const EnvironmentVariableRegex = `{{[a-zA-Z$][a-zA-Z0-9_().-]*}}`
 const JsonEnvironmentVarRegex = `\"{{[a-zA-Z$][a-zA-Z0-9_().-]*}}"`
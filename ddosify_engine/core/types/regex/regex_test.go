@@ -20,6 +20,11 @@ func TestDynamicVariableRegex(t *testing.T) {
 		{"Match5", "https://example.com/{{_timestamp}}/{_abc}", true},
 		{"Match6", "https://example.com/{{_abc/{{_timestamp}}", true},
 		{"Match7", "https://example.com/_aaa/{{_timestamp}}", true},
+		{"MatchFunc1", "https://example.com/{{uuid()}}", true},
+		{"MatchFunc2", "https://example.com/{{randomInt(1,100)}}", true},
+		{"MatchFunc3", `https://example.com/?t={{now("RFC3339")}}`, true},
+		{"MatchFunc4", `https://example.com/?sig={{hmacSHA256("{{secret}}", "{{body}}")}}`, true},
+		{"MatchFunc5", "https://example.com/{{md5Hex(abc)}}", true},
 
 		{"Not Match1", "https://example.com/{{_abc", false},
 		{"Not Match2", "https://example.com/{{_abc}", false},
@@ -28,6 +33,9 @@ func TestDynamicVariableRegex(t *testing.T) {
 		{"Not Match5", "https://example.com/abc", false},
 		{"Not Match6", "https://example.com/abc/{{cc}}", false},
 		{"Not Match7", "https://example.com/abc/{{cc}}/fcf", false},
+		{"Not MatchFunc1", "https://example.com/{{rand(list)}}", false},
+		{"Not MatchFunc2", "https://example.com/{{unknown(1)}}", false},
+		{"Not MatchFunc3", "https://example.com/{{uuid}}", false},
 	}
 
 	for _, test := range tests {
@@ -38,6 +38,16 @@ type ScenarioResult struct {
 
 	// Dynamic field for extra data needs in response object consumers.
 	Others map[string]interface{}
+
+	// True if the Scenario is started in the warm-up period of the test, it is excluded from the aggregated results.
+	Warmup bool
+
+	// True if the Scenario is started in the ramp-down period of the test, it is excluded from the aggregated results.
+	RampDown bool
+
+	// True if the Scenario is not sampled by the sample rate of the test, it is only counted in the aggregated
+	// results. See Hammer.SampleRate.
+	Unsampled bool
 }
 
 // ScenarioStepResult is corresponding to ScenarioStep.
@@ -48,6 +58,9 @@ type ScenarioStepResult struct {
 	// Name of the ScenarioStep
 	StepName string
 
+	// Tags of the ScenarioStep
+	Tags []string
+
 	// Each request has a unique ID.
 	RequestID uuid.UUID
 
@@ -60,15 +73,74 @@ type ScenarioStepResult struct {
 	// Total duration. From request sending to full response receiving.
 	Duration time.Duration
 
-	// Response content length
+	// Delay of the request held back by the RPS limit, included in the Duration. Only set if the coordinated
+	// omission is corrected, see Hammer.CorrectOmission.
+	ScheduleLag time.Duration
+
+	// Response content length, the compressed length if the response is compressed
 	ContentLength int64
 
+	// Decompressed length of the compressed responses, zero otherwise
+	DecompressedLength int64
+
+	// Body length of the compressed requests before and after the compression, zeros otherwise
+	ReqBodyLength           int64
+	ReqCompressedBodyLength int64
+
+	// True if the compressed request is rejected by 415 Unsupported Media Type and this result is of the request
+	// sent again uncompressed, see ScenarioStep.RequestCompressionFallback
+	CompressionFallback bool
+
+	// True if the response is a 429 Too Many Requests, or a 503 Service Unavailable with a Retry-After header.
+	// RetryAfter is the delay of its Retry-After header, zero if it has no valid one.
+	Throttled  bool
+	RetryAfter time.Duration
+
+	// Body bytes uploaded by a step with a chunked body and the duration of writing them, zeros otherwise
+	UploadedBytes  int64
+	UploadDuration time.Duration
+
 	// Error occurred at request time.
 	Err RequestError
 
+	// Bucket of the failure in the error breakdown of the report, empty if the step succeeded. See Categorize.
+	ErrCategory ErrorCategory
+
+	// Connection that the request is sent over, see ConnState
+	Conn ConnState
+
+	// Protocol version of the received response like HTTP/1.1 and HTTP/2.0, empty if there is no response
+	Proto string
+
+	// Peer certificate of the TLS connection, captured only if the step has CaptureCert. Shared by the results
+	// of the connections having the same certificate and parameters, it should not be modified.
+	Cert *CertInfo
+
+	// Redirects followed before the final response, in order. Empty if the step is not redirected.
+	Redirects []RedirectHop
+
+	// Inter-arrival times of the events received by a sse step, the first one is since the response headers.
+	// For the grpc streaming methods, inter-arrival times of the received messages since the stream is opened.
+	EventIntervals []time.Duration
+
+	// Number of the messages sent on a grpc client or bidi stream
+	MessagesSent int
+
+	// Number of retries made by the retry policy of the step before this result
+	Retries int
+
+	// True if the request is a conditional one revalidating the stored validators of the url
+	Revalidated bool
+
+	// True if the step is not sent because its condition is not met. Not counted as a success or a failure.
+	Skipped bool
+
 	// Url
 	Url string
 
+	// Name of the weighted target that the request is sent to, empty if the step has no targets
+	Target string
+
 	// Method
 	Method string
 
@@ -84,6 +156,12 @@ type ScenarioStepResult struct {
 	// Response Body
 	RespBody []byte
 
+	// True if the response body exceeded the MaxResponseBodyBytes of the step and the rest of it is not read
+	RespBodyTruncated bool
+
+	// Response Trailers
+	RespTrailers http.Header
+
 	// Protocol specific metrics. For ex: DNSLookupDuration: 1s for HTTP
 	Custom map[string]interface{}
 
@@ -98,4 +176,69 @@ type ScenarioStepResult struct {
 
 	// Failed assertion rules and received values
 	FailedAssertions []FailedAssertion
+
+	// First violations of the response schema of the step, empty if the response conforms to it
+	SchemaErrors []string
+}
+
+// CertInfo is the peer certificate of a TLS connection and the negotiated parameters of it.
+type CertInfo struct {
+	// Server name that the certificate is verified for
+	Host string
+
+	Subject  string
+	Issuer   string
+	NotAfter time.Time
+
+	// Negotiated TLS version like "TLS 1.3" and the cipher suite of the connection
+	Version     string
+	CipherSuite string
+
+	// True if the negotiated version is older than TLS 1.2
+	WeakVersion bool
+
+	// Errors of the hostname verification and of the chain verification against the root CAs of the step,
+	// or the system roots. Empty if verified, the request is not failed by them.
+	HostnameError string
+	ChainError    string
+}
+
+// ConnState tells whether a request is sent over a new or a reused keep-alive connection.
+type ConnState uint8
+
+// Constants of the connection states
+const (
+	ConnUnknown ConnState = iota // no connection is obtained, or not reported by the protocol
+	ConnNew                      // a new connection is dialed for the request
+	ConnReused                   // an idle keep-alive connection is reused
+)
+
+// RedirectHop is a redirect response followed by an HTTP step.
+type RedirectHop struct {
+	// Requested url that responded with the redirect
+	URL string
+
+	// Status code of the redirect response like 301, 302
+	StatusCode int
+
+	// From sending the request to receiving the redirect response
+	Duration time.Duration
+}
+
+// Categorize returns the ErrorCategory of the result. Request errors are categorized by their types and reasons,
+// failed assertions and schema violations by the status class of the response.
+func (sr *ScenarioStepResult) Categorize() ErrorCategory {
+	if sr.Err.Type != "" {
+		return sr.Err.Category()
+	}
+	if len(sr.FailedAssertions) == 0 && len(sr.SchemaErrors) == 0 {
+		return ""
+	}
+	switch {
+	case sr.StatusCode >= 500 && sr.StatusCode < 600:
+		return ErrorCategoryHTTP5xx
+	case sr.StatusCode >= 400 && sr.StatusCode < 500:
+		return ErrorCategoryHTTP4xx
+	}
+	return ErrorCategoryAssertion
 }
@@ -21,17 +21,26 @@
 package types
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	validator "github.com/asaskevich/govalidator"
+	"go.ddosify.com/ddosify/core/types/regex"
 	"go.ddosify.com/ddosify/core/util"
 )
 
@@ -40,32 +49,88 @@ const (
 	// Constants of the Protocol types
 	ProtocolHTTP  = "HTTP"
 	ProtocolHTTPS = "HTTPS"
+	ProtocolH2C   = "H2C" // HTTP/2 cleartext with prior knowledge
+	ProtocolH3    = "H3"  // HTTP/3 over QUIC, falls back to HTTP/2 or HTTP/1.1 if the QUIC handshake fails
+
+	// Constants of the Step types. Empty step type means HTTP.
+	StepTypeHTTP      = "http"
+	StepTypeGRPC      = "grpc"
+	StepTypeWebSocket = "websocket"
+	StepTypeGraphQL   = "graphql" // sent by the HTTP requester with a JSON body built from the GraphQLConf
+	StepTypeTCP       = "tcp"
+	StepTypeUDP       = "udp"
+	StepTypeDNS       = "dns"
+	StepTypeSSE       = "sse" // server-sent events, the stream is read according to the SSEConf
 
 	// Constants of the Auth types
-	AuthHttpBasic = "basic"
+	AuthHttpBasic               = "basic"
+	AuthOAuth2ClientCredentials = "oauth2_cc"
+	AuthBearer                  = "bearer"
+	AuthNTLM                    = "ntlm" // NTLMv2 handshake on the challenged connections
+
+	// Constants of the request body compressions
+	RequestCompressionGzip    = "gzip"
+	RequestCompressionDeflate = "deflate"
+
+	// Constants of the sleep distributions
+	SleepDistUniform     = "uniform"
+	SleepDistExponential = "exponential"
+	SleepDistNormal      = "normal"
+
+	// Constants of the retry backoff strategies
+	RetryBackoffFixed       = "fixed"
+	RetryBackoffExponential = "exponential"
 
 	// Max sleep in ms (90s)
 	maxSleep = 90000
 
+	// Max number of attempts of a step, including the first one
+	maxRetryAttempts = 10
+
 	// Should match environment variables, reference
 	EnvironmentVariableRegexStr = `{{[a-zA-Z$][a-zA-Z0-9_().-]*}}`
 
 	// Should match environment variables, definition, exact match
 	EnvironmentVariableNameStr = `^[a-zA-Z][a-zA-Z0-9_-]*$`
+
+	// Variables of the virtual user running the iteration, defined for all the steps
+	VirtualUserIDEnv    = "vu.id"
+	VirtualUserIndexEnv = "vu.index"
 )
 
 // SupportedProtocols should be updated whenever a new requester.Requester interface implemented
-var SupportedProtocols = [...]string{ProtocolHTTP, ProtocolHTTPS}
+var SupportedProtocols = [...]string{ProtocolHTTP, ProtocolHTTPS, ProtocolH2C, ProtocolH3}
 var supportedProtocolMethods = []string{
 	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete,
 	http.MethodPatch, http.MethodHead, http.MethodOptions,
 }
+var supportedStepTypes = []string{
+	StepTypeHTTP, StepTypeGRPC, StepTypeWebSocket, StepTypeGraphQL, StepTypeTCP, StepTypeUDP, StepTypeDNS,
+	StepTypeSSE,
+}
+var SupportedDNSQueryTypes = []string{
+	"A", "AAAA", "CNAME", "MX", "NS", "PTR", "SOA", "SRV", "TXT", "ANY",
+}
+var supportedDNSTransports = []string{
+	"udp", "tcp",
+}
+var supportedSleepDistributions = []string{
+	SleepDistUniform, SleepDistExponential, SleepDistNormal,
+}
+var supportedRequestCompressions = []string{
+	RequestCompressionGzip, RequestCompressionDeflate,
+}
 var supportedAuthentications = []string{
-	AuthHttpBasic,
+	AuthHttpBasic, AuthOAuth2ClientCredentials, AuthBearer, AuthNTLM,
+}
+var supportedRetryBackoffs = []string{
+	RetryBackoffFixed, RetryBackoffExponential,
 }
 
 var envVarRegexp *regexp.Regexp
 var envVarNameRegexp *regexp.Regexp
+var templateFunctionRegexp = regexp.MustCompile(regex.TemplateFunctionRegex)
+var stepTagRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.:-]+$`)
 
 func init() {
 	envVarRegexp = regexp.MustCompile(EnvironmentVariableRegexStr)
@@ -78,16 +143,239 @@ type Scenario struct {
 	Envs    map[string]interface{}
 	CsvVars []string           // only for validation
 	Data    map[string]CsvData // populated data
+
+	// Each iteration runs one of the WeightedScenarios picked by their weights. All the Steps run if empty.
+	WeightedScenarios []WeightedScenario
+
+	// Cleanup steps run in order at the end of each iteration, even if the steps failed or the iteration is
+	// stopped. They see the captures of all the steps and they are not filtered by the tags.
+	Cleanup []ScenarioStep
+
+	// Setup steps run once in order before the load starts, a failed setup step aborts the test. Their captures are
+	// global envs of all the iterations. Teardown steps run once in order after the load, they see the captures of
+	// the setup steps. Both are not filtered by the tags.
+	Setup    []ScenarioStep
+	Teardown []ScenarioStep
 }
 
+// AllSteps returns the steps followed by the cleanup steps.
+func (s *Scenario) AllSteps() []ScenarioStep {
+	if len(s.Cleanup) == 0 {
+		return s.Steps
+	}
+	return append(append(make([]ScenarioStep, 0, len(s.Steps)+len(s.Cleanup)), s.Steps...), s.Cleanup...)
+}
+
+// FilterTags returns the scenario of the steps having any of the given tags. Steps of the weighted scenarios
+// are filtered too, the weighted scenarios without any step left are removed. Dependencies on the filtered steps
+// are replaced by their own dependencies, so the kept steps run in the same order.
+func (s Scenario) FilterTags(tags []string) Scenario {
+	kept := make(map[uint16]bool, len(s.Steps))
+	for _, si := range s.Steps {
+		if si.HasAnyTag(tags) {
+			kept[si.ID] = true
+		}
+	}
+	steps := make([]ScenarioStep, 0, len(kept))
+	for _, si := range s.Steps {
+		if kept[si.ID] {
+			if len(si.DependsOn) > 0 {
+				si.DependsOn = s.keptDependencies(si.DependsOn, kept)
+			}
+			steps = append(steps, si)
+		}
+	}
+	s.Steps = steps
+
+	if len(s.WeightedScenarios) > 0 {
+		weighted := make([]WeightedScenario, 0, len(s.WeightedScenarios))
+		for _, ws := range s.WeightedScenarios {
+			ids := make([]uint16, 0, len(ws.StepIDs))
+			for _, id := range ws.StepIDs {
+				if kept[id] {
+					ids = append(ids, id)
+				}
+			}
+			if len(ids) > 0 {
+				ws.StepIDs = ids
+				weighted = append(weighted, ws)
+			}
+		}
+		s.WeightedScenarios = weighted
+	}
+	return s
+}
+
+// WeightedScenario is a named flow of the Scenario steps. It is picked for an iteration with the probability of
+// Weight / sum of all the weights.
+type WeightedScenario struct {
+	Name   string
+	Weight int
+
+	// IDs of the steps in running order
+	StepIDs []uint16
+}
+
+// validate validates the scenario, the undefined variables of all the steps are reported together once the other
+// checks of the steps pass.
 func (s *Scenario) validate() error {
 	stepIds := make(map[uint16]struct{}, len(s.Steps))
+	definedEnvs, err := s.definedEnvs()
+	if err != nil {
+		return err
+	}
+
+	var undefined undefinedEnvs
+	setupEnvs := s.setupEnvs()
+	for _, st := range s.Setup {
+		if err := validateOrderedStep(st, "setup", setupEnvs, stepIds); err != nil && !undefined.add(st.ID, err) {
+			return err
+		}
+	}
+	addCaptures(definedEnvs, s.Setup)
+
+	order, stepEnvs, err := s.validationOrder(definedEnvs)
+	if err != nil {
+		return err
+	}
+	for _, i := range order {
+		st := s.Steps[i]
+		if err := validateStep(st, stepEnvs(i), stepIds); err != nil && !undefined.add(st.ID, err) {
+			return err
+		}
+	}
+	cleanupEnvs := s.cleanupEnvs(definedEnvs)
+	for _, st := range s.Cleanup {
+		if err := validateOrderedStep(st, "cleanup", cleanupEnvs, stepIds); err != nil && !undefined.add(st.ID, err) {
+			return err
+		}
+	}
+	for _, st := range s.Teardown {
+		if err := validateOrderedStep(st, "teardown", setupEnvs, stepIds); err != nil && !undefined.add(st.ID, err) {
+			return err
+		}
+	}
+	if err := undefined.err(); err != nil {
+		return err
+	}
+
+	for _, ws := range s.WeightedScenarios {
+		if ws.Weight <= 0 {
+			return fmt.Errorf("weight of the scenario %s should be greater than zero", ws.Name)
+		}
+		if len(ws.StepIDs) == 0 {
+			return fmt.Errorf("scenario %s has no steps", ws.Name)
+		}
+		for _, id := range ws.StepIDs {
+			if _, ok := stepIds[id]; !ok {
+				return fmt.Errorf("step id %d of the scenario %s is not found", id, ws.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateSteps validates the steps like validate, but it doesn't stop at the first invalid step. Errors are
+// returned by the index of the step in AllSteps followed by the setup and the teardown steps, the error is about
+// the envs or the test data that all the steps use.
+func (s *Scenario) ValidateSteps() (map[int]error, error) {
+	stepIds := make(map[uint16]struct{}, len(s.Steps))
+	definedEnvs, err := s.definedEnvs()
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make(map[int]error)
+	setupIndex := len(s.Steps) + len(s.Cleanup)
+	setupEnvs := s.setupEnvs()
+	for i, st := range s.Setup {
+		if err := validateOrderedStep(st, "setup", setupEnvs, stepIds); err != nil {
+			errs[setupIndex+i] = err
+		}
+	}
+	addCaptures(definedEnvs, s.Setup)
+
+	order, stepEnvs, err := s.validationOrder(definedEnvs)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range order {
+		if err := validateStep(s.Steps[i], stepEnvs(i), stepIds); err != nil {
+			errs[i] = err
+		}
+	}
+	cleanupEnvs := s.cleanupEnvs(definedEnvs)
+	for i, st := range s.Cleanup {
+		if err := validateOrderedStep(st, "cleanup", cleanupEnvs, stepIds); err != nil {
+			errs[len(s.Steps)+i] = err
+		}
+	}
+	for i, st := range s.Teardown {
+		if err := validateOrderedStep(st, "teardown", setupEnvs, stepIds); err != nil {
+			errs[setupIndex+len(s.Setup)+i] = err
+		}
+	}
+	return errs, nil
+}
+
+// cleanupEnvs returns the envs defined for the cleanup steps, the given ones and the captures of all the steps.
+// The captures of the failed steps are missing at the start of the cleanup, like the later steps of a stopped
+// iteration.
+func (s *Scenario) cleanupEnvs(definedEnvs map[string]struct{}) map[string]struct{} {
+	envs := make(map[string]struct{}, len(definedEnvs))
+	for k := range definedEnvs {
+		envs[k] = struct{}{}
+	}
+	addCaptures(envs, s.Steps)
+	return envs
+}
+
+// setupEnvs returns the envs defined for the setup steps, only the global envs. Setup steps run before the
+// iterations, so the csv vars and the virtual user vars are missing.
+func (s *Scenario) setupEnvs() map[string]struct{} {
+	envs := make(map[string]struct{}, len(s.Envs))
+	for k := range s.Envs {
+		envs[k] = struct{}{}
+	}
+	return envs
+}
+
+// addCaptures adds the captured envs of the steps to envs.
+func addCaptures(envs map[string]struct{}, steps []ScenarioStep) {
+	for _, st := range steps {
+		for _, ce := range st.EnvsToCapture {
+			for _, v := range ce.Vars() {
+				envs[v] = struct{}{}
+			}
+		}
+	}
+}
+
+// validateOrderedStep validates the cleanup, setup or teardown step like validateStep, these steps run in order
+// so they can't have dependencies. Setup and teardown steps can't have parallel copies either.
+func validateOrderedStep(st ScenarioStep, kind string, definedEnvs map[string]struct{},
+	stepIds map[uint16]struct{}) error {
+	if err := validateStep(st, definedEnvs, stepIds); err != nil {
+		return err
+	}
+	if len(st.DependsOn) > 0 {
+		return fmt.Errorf("depends_on can not be used in the %s step %d", kind, st.ID)
+	}
+	if st.Parallel > 1 && kind != "cleanup" {
+		// run once steps are sent one at a time, see ScenarioService.Setup
+		return fmt.Errorf("parallel can not be used in the %s step %d", kind, st.ID)
+	}
+	return nil
+}
+
+// definedEnvs returns the global envs, the csv vars and the virtual user vars that the steps can use.
+func (s *Scenario) definedEnvs() (map[string]struct{}, error) {
 	definedEnvs := map[string]struct{}{}
 
 	// add global envs
 	for key := range s.Envs {
 		if !envVarNameRegexp.MatchString(key) { // not a valid env definition
-			return fmt.Errorf("env key is not valid: %s", key)
+			return nil, fmt.Errorf("env key is not valid: %s", key)
 		}
 		definedEnvs[key] = struct{}{} // exist
 	}
@@ -95,98 +383,166 @@ func (s *Scenario) validate() error {
 	for _, key := range s.CsvVars { // data.info.name
 		splitted := strings.Split(key, ".")
 		if len(splitted) > 3 {
-			return fmt.Errorf("csv key can not have dot in it: %s", key)
+			return nil, fmt.Errorf("csv key can not have dot in it: %s", key)
 		}
 		for _, s := range splitted {
 			if !envVarNameRegexp.MatchString(s) { // not a valid env definition
-				return fmt.Errorf("csv key is not valid: %s", key)
+				return nil, fmt.Errorf("csv key is not valid: %s", key)
 			}
 		}
 		definedEnvs[key] = struct{}{} // exist
 	}
+	// add virtual user vars
+	definedEnvs[VirtualUserIDEnv] = struct{}{}
+	definedEnvs[VirtualUserIndexEnv] = struct{}{}
+	return definedEnvs, nil
+}
 
-	for _, st := range s.Steps {
-		if err := st.validate(definedEnvs); err != nil {
-			return err
-		}
-
-		// enrich Envs map with captured envs from each step
+// validateStep validates the step, then adds its captured envs to definedEnvs for the following steps and
+// its id to stepIds.
+func validateStep(st ScenarioStep, definedEnvs map[string]struct{}, stepIds map[uint16]struct{}) error {
+	// captured envs are defined for the following steps even if the step is invalid
+	defer func() {
 		for _, ce := range st.EnvsToCapture {
-			if !envVarNameRegexp.MatchString(ce.Name) { // not a valid env definition
-				return fmt.Errorf("captured env key is not valid: %s", ce.Name)
+			for _, v := range ce.Vars() {
+				definedEnvs[v] = struct{}{}
 			}
-			definedEnvs[ce.Name] = struct{}{}
 		}
+		stepIds[st.ID] = struct{}{}
+	}()
+
+	if err := st.validate(definedEnvs); err != nil {
+		return err
+	}
 
-		if _, ok := stepIds[st.ID]; ok {
-			return fmt.Errorf("duplicate step id: %d", st.ID)
+	// enrich Envs map with captured envs from each step
+	for _, ce := range st.EnvsToCapture {
+		for _, v := range ce.Vars() {
+			if !envVarNameRegexp.MatchString(v) { // not a valid env definition
+				return fmt.Errorf("captured env key is not valid: %s", v)
+			}
 		}
-		stepIds[st.ID] = struct{}{}
+	}
+
+	if _, ok := stepIds[st.ID]; ok {
+		return fmt.Errorf("duplicate step id: %d", st.ID)
 	}
 	return nil
 }
 
+// checkEnvsValidInStep checks that the variables referred in the step are defined, all the undefined ones of the
+// step are reported in the error.
 func checkEnvsValidInStep(st *ScenarioStep, definedEnvs map[string]struct{}) error {
-	var err error
-	matchInEnvs := func(matches []string) error {
+	var undefined, notInOS []string
+	seen := make(map[string]bool)
+	matchInEnvs := func(matches []string) {
 		for _, v := range matches {
-			if _, ok := definedEnvs[v[2:len(v)-2]]; !ok { // {{....}}
-				// utility functions are matched too, check if starts with rand
-				// TODO: find a better solution about utility functions and validation checks
-
-				if strings.HasPrefix(v[2:len(v)-2], "rand(") {
-					if _, ok := definedEnvs[v[7:len(v)-3]]; ok {
-						continue
-					}
-				}
-
-				if strings.HasPrefix(v[2:len(v)-2], "$") {
-					varName := v[3 : len(v)-2]
-					if _, ok := os.LookupEnv(varName); ok {
-						continue
-					}
+			if _, ok := definedEnvs[v[2:len(v)-2]]; ok || seen[v] { // {{....}}
+				continue
+			}
+			// utility functions are matched too, check if starts with rand
+			// TODO: find a better solution about utility functions and validation checks
 
-					return EnvironmentNotDefinedError{
-						msg: fmt.Sprintf("%s is not found in the operating system environment variables", v),
-					}
+			if strings.HasPrefix(v[2:len(v)-2], "rand(") {
+				if _, ok := definedEnvs[v[7:len(v)-3]]; ok {
+					continue
 				}
+			}
+
+			// template functions without arguments like {{uuid()}} are injected as dynamic variables
+			if templateFunctionRegexp.MatchString(v[2 : len(v)-2]) {
+				continue
+			}
 
-				return EnvironmentNotDefinedError{
-					msg: fmt.Sprintf("%s is not defined to use by global and captured environments", v),
+			seen[v] = true
+			if strings.HasPrefix(v[2:len(v)-2], "$") {
+				if _, ok := os.LookupEnv(v[3 : len(v)-2]); !ok {
+					notInOS = append(notInOS, v)
 				}
+				continue
 			}
+			undefined = append(undefined, v)
 		}
-		return nil
 	}
 
-	f := func(source string) error {
-		matches := envVarRegexp.FindAllString(source, -1)
-		return matchInEnvs(matches)
+	f := func(source string) {
+		matchInEnvs(envVarRegexp.FindAllString(source, -1))
 	}
 
 	// check env usage in url
-	err = f(st.URL)
-	if err != nil {
-		return err
+	f(st.URL)
+	for _, t := range st.Targets {
+		f(t.URL)
 	}
 
-	// check env usage in header
-	for k, v := range st.Headers {
-		err = f(k)
-		if err != nil {
-			return err
-		}
-
-		err = f(v)
-		if err != nil {
-			return err
-		}
+	// check env usage in header, in the order of the keys so the error is the same for each run
+	keys := make([]string, 0, len(st.Headers))
+	for k := range st.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f(k)
+		f(st.Headers[k])
 	}
 
 	// check env usage in payload
-	err = f(st.Payload)
-	return err
+	f(st.Payload)
+
+	// check env usage in the form page of the csrf token
+	if st.CSRF != nil {
+		f(st.CSRF.FormURL)
+	}
+
+	// check env usage in the credentials, like a token captured by a login step
+	f(st.Auth.Username)
+	f(st.Auth.Password)
+	f(st.Auth.Token)
 
+	// check env usage in the queried name of the dns steps
+	f(st.DNS.Name)
+
+	var msgs []string
+	if len(undefined) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%s %s not defined to use by global and captured environments",
+			strings.Join(undefined, ", "), isOrAre(len(undefined))))
+	}
+	if len(notInOS) > 0 {
+		msgs = append(msgs, fmt.Sprintf("%s %s not found in the operating system environment variables",
+			strings.Join(notInOS, ", "), isOrAre(len(notInOS))))
+	}
+	if len(msgs) > 0 {
+		return EnvironmentNotDefinedError{msg: strings.Join(msgs, ", ")}
+	}
+	return nil
+}
+
+func isOrAre(n int) string {
+	if n == 1 {
+		return "is"
+	}
+	return "are"
+}
+
+// undefinedEnvs collects the undefined variables of the steps by the step ids, so all of them are reported at once
+// instead of failing the test on them one at a time.
+type undefinedEnvs []string
+
+// add records the error if it is about the undefined variables of the step, returns false otherwise.
+func (u *undefinedEnvs) add(id uint16, err error) bool {
+	var envErr EnvironmentNotDefinedError
+	if !errors.As(err, &envErr) {
+		return false
+	}
+	*u = append(*u, fmt.Sprintf("step %d: %s", id, envErr.msg))
+	return true
+}
+
+func (u undefinedEnvs) err() error {
+	if len(u) == 0 {
+		return nil
+	}
+	return wrapAsScenarioValidationError(EnvironmentNotDefinedError{msg: strings.Join(u, "; ")})
 }
 
 // ScenarioStep represents one step of a Scenario.
@@ -210,6 +566,10 @@ type ScenarioStep struct {
 	// A TLS cert pool
 	CertPool *x509.CertPool
 
+	// TLS settings built by TLSConf.Load, overrides Cert and CertPool if set.
+	// Shared by all the connections of the step, requesters should clone it before modifying.
+	TLSConfig *tls.Config
+
 	// Request Headers
 	Headers map[string]string
 
@@ -222,9 +582,21 @@ type ScenarioStep struct {
 	// Connection timeout duration of the request in seconds
 	Timeout int
 
-	// Sleep duration after running the step. Can be a time range like "300-500" or an exact duration like "350" in ms
+	// Timeout of the request with a sub-second precision, overrides Timeout if set
+	RequestTimeout time.Duration
+
+	// Max wait for a connection to be dialed, and for the TLS handshake of it. No limit other than the request
+	// timeout if zero.
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+
+	// Sleep duration after running the step. Can be a time range like "300-500" or an exact duration like "350" in ms.
+	// Durations with a unit like "1s-3s" are accepted too, see ParseSleep.
 	Sleep string
 
+	// Distribution of the sleep durations, uniform over the range of the Sleep if the type is empty.
+	SleepDistribution SleepDistribution
+
 	// Protocol specific request parameters. For ex: DisableRedirects:true for Http requests
 	Custom map[string]interface{}
 
@@ -233,19 +605,497 @@ type ScenarioStep struct {
 
 	// assertion expressions
 	Assertions []string
+
+	// Type of the step, determines the requester implementation. Empty means HTTP.
+	Type string
+
+	// gRPC specific parameters, used if Type is StepTypeGRPC
+	Grpc GrpcConf
+
+	// WebSocket specific parameters, used if Type is StepTypeWebSocket
+	WebSocket WebSocketConf
+
+	// Server-sent events specific parameters, used if Type is StepTypeSSE
+	SSE SSEConf
+
+	// Raw socket parameters, used if Type is StepTypeTCP or StepTypeUDP
+	Socket SocketConf
+
+	// DNS specific parameters, used if Type is StepTypeDNS
+	DNS DNSConf
+
+	// GraphQL specific parameters, used if Type is StepTypeGraphQL. Overrides Payload.
+	GraphQL GraphQLConf
+
+	// Transport protocol of the HTTP steps. Empty means negotiated by the scheme of the URL.
+	Protocol string
+
+	// Retry policy of the step. Disabled if MaxAttempts is less than 2.
+	Retry RetryConf
+
+	// Condition of sending the step, an expression like the assertions evaluated against the result of the
+	// previous step and the variables. The step is skipped if it is not true. Always sent if empty.
+	If string
+
+	// IDs of the steps done before the step starts. If any step of the scenario has them, the steps run as a
+	// dependency graph, see Scenario.HasDependencies.
+	DependsOn []uint16
+
+	// Number of the copies of the step sent concurrently, like the assets of a page fetched at once by a browser.
+	// Each copy is a request of the step in the results. Sent once if zero.
+	Parallel int
+
+	// Maximum number of the response body bytes read, the rest of the body is not read. Unlimited if zero.
+	MaxResponseBodyBytes int64
+
+	// Path of the JSON Schema file that the response bodies of the step are validated against. Disabled if empty.
+	ResponseSchema string
+
+	// Status codes of the successful responses of the step, responses with the other status codes fail like a
+	// failed assertion. Every status code is accepted if empty.
+	ExpectedStatus ExpectedStatus
+
+	// Assertion expression of the successful responses of the run, like "status_code < 500". Responses failing it
+	// fail like a failed assertion. Not evaluated for the steps with an ExpectedStatus or if empty.
+	SuccessWhen string
+
+	// Keeps up to the given bytes of the response bodies that are not read otherwise, for the failure samples.
+	// The bodies are only discarded if zero.
+	SampleBodyBytes int64
+
+	// Opens a new connection for each request of the step, like the "Connection: close" header.
+	DisableKeepAlive bool
+
+	// Header carrying the RequestID of each request of the step. Not sent if empty.
+	RequestIDHeader string
+
+	// Sends the W3C traceparent header of the RequestID with each request of the step, see Traceparent.
+	Traceparent bool
+
+	// Captures the peer certificate of the TLS connections into the Cert of the results, set by the cert audit.
+	CaptureCert bool
+
+	// Connection limits of the transports of the step
+	Transport TransportConf
+
+	// Redirect policy of the HTTP steps
+	Redirect RedirectConf
+
+	// Tags of the step like "payments", the results are grouped by them and the steps can be filtered by them.
+	Tags []string
+
+	// Dials the connections of the step, like a dns caching dialer. Default dialer of the transport is used if nil.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Cache validators of the virtual users, the GET and HEAD requests of an HTTP step are revalidated by the
+	// conditional requests if set.
+	Validators *ValidatorStore
+
+	// Parts of the multipart/form-data body built for each request of an HTTP step, overrides Payload if set.
+	// File contents are streamed from the disk, they are not read into the memory.
+	MultipartStream []MultipartPart
+
+	// Compresses the Payload of an HTTP step by RequestCompressionGzip or RequestCompressionDeflate and sends it with
+	// the Content-Encoding header. Static payloads are compressed once, templated ones for each request.
+	// Disabled if empty.
+	RequestCompression string
+
+	// Sends the request of an HTTP step again uncompressed once, if its compressed body is rejected by 415
+	// Unsupported Media Type. Ignored if the body is not compressed by RequestCompression.
+	RequestCompressionFallback bool
+
+	// Generated body of an HTTP step streamed with Transfer-Encoding: chunked, overrides Payload if set.
+	ChunkedBody *ChunkedBody
+
+	// Weighted targets of an HTTP step, each request is sent to one of them instead of the URL.
+	// Results are reported per target too. Disabled if empty.
+	Targets []WeightedTarget
+
+	// Fetches the form page before each request of an HTTP step and sends the CSRF token of it. Disabled if nil.
+	CSRF *CSRF
+
+	// Interceptors of the requests and the responses of an HTTP step, set by the Hammer.RequestInterceptors and
+	// Hammer.ResponseInterceptors.
+	RequestInterceptors  []RequestInterceptor
+	ResponseInterceptors []ResponseInterceptor
+}
+
+// CSRF is the token of a server-rendered form, like a login form. The form page is fetched by the client of the
+// virtual user before each request of the step, so the session cookie set by the page is sent with the request.
+// The value of the hidden input field of the token is added to the urlencoded payload, or sent in the Header.
+type CSRF struct {
+	// URL of the form page, variables are injected.
+	FormURL string
+
+	// Name of the input field of the token, the token is sent in the form field of the same name.
+	Field string
+
+	// Header of the token like X-CSRF-Token, the payload is sent as it is if set.
+	Header string
+}
+
+func (c *CSRF) validate(si *ScenarioStep) error {
+	if !si.IsHTTP() || si.Type == StepTypeGraphQL {
+		return fmt.Errorf("csrf is only supported by the http steps")
+	}
+	if !envVarRegexp.MatchString(c.FormURL) && !validator.IsURL(strings.ReplaceAll(c.FormURL, " ", "_")) {
+		return fmt.Errorf("csrf form url of the step %d is not valid: %s", si.ID, c.FormURL)
+	}
+	if c.Field == "" {
+		return fmt.Errorf("csrf field of the step %d should be given", si.ID)
+	}
+	if c.Header == "" && (len(si.MultipartStream) > 0 || si.ChunkedBody != nil) {
+		return fmt.Errorf("csrf token of the step %d can not be added to a multipart or chunked payload, "+
+			"send it in a header instead", si.ID)
+	}
+	return nil
+}
+
+// WeightedTarget is picked for a request of its step with the probability of Weight / sum of all the weights.
+type WeightedTarget struct {
+	// Name of the target in the results like a region, host of the URL if empty
+	Name   string
+	URL    string
+	Weight int
+}
+
+func validateTargets(si *ScenarioStep) error {
+	if !si.IsHTTP() {
+		return fmt.Errorf("targets are only supported by the http steps")
+	}
+	names := make(map[string]bool, len(si.Targets))
+	for i, t := range si.Targets {
+		if err := IsTargetValid(t.URL); err != nil {
+			return fmt.Errorf("target %d of the step %d: %v", i+1, si.ID, err)
+		}
+		if t.Weight <= 0 {
+			return fmt.Errorf("weight of the target %s of the step %d should be greater than zero", t.URL, si.ID)
+		}
+		name := t.TargetName()
+		if names[name] {
+			return fmt.Errorf("target name %s of the step %d is duplicated", name, si.ID)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// TargetName returns the Name, or the host of the URL if the Name is empty.
+func (t WeightedTarget) TargetName() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if u, err := url.Parse(t.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return t.URL
+}
+
+// ChunkedBody is a body of Size bytes written in chunks of ChunkSize bytes, to stress the request body handling
+// of the servers with streamed uploads. Delaying the chunks models the slow uploaders.
+type ChunkedBody struct {
+	Size      int64
+	ChunkSize int
+
+	// Wait before each chunk but the first one. Disabled if zero.
+	Delay time.Duration
+}
+
+func (c *ChunkedBody) validate(si *ScenarioStep) error {
+	if c.Size < 1 || c.ChunkSize < 1 {
+		return fmt.Errorf("chunked body of the step %d needs a size and a chunk size of at least 1", si.ID)
+	}
+	if c.Delay < 0 {
+		return fmt.Errorf("chunked body delay of the step %d can not be negative", si.ID)
+	}
+	if !si.IsHTTP() || si.Type == StepTypeGraphQL || len(si.MultipartStream) > 0 || si.RequestCompression != "" {
+		return fmt.Errorf("chunked body is only supported by the http steps without a multipart payload " +
+			"and a request compression")
+	}
+	return nil
+}
+
+// RetryConf determines when and how a failed step is sent again.
+type RetryConf struct {
+	// Total number of attempts including the first one
+	MaxAttempts int
+
+	// Status codes that trigger a retry
+	StatusCodes []int
+
+	// Error types that trigger a retry, like "connectionError". If both StatusCodes and Errors are empty,
+	// all error types except the invalid requests, and 502, 503, 504 status codes trigger a retry.
+	Errors []string
+
+	// Backoff strategy between the attempts, RetryBackoffFixed or RetryBackoffExponential. Default is fixed.
+	Backoff string
+
+	// Wait duration before the first retry in milliseconds. Doubled on each retry for exponential backoff.
+	Delay int
+
+	// Upper limit of the wait duration in milliseconds, zero means no limit.
+	MaxDelay int
+
+	// Randomizes the wait duration between the half and the full of it
+	Jitter bool
+}
+
+// Enabled returns true if the step should be retried on failures.
+func (rc RetryConf) Enabled() bool {
+	return rc.MaxAttempts > 1
+}
+
+func (rc RetryConf) validate() error {
+	if rc.MaxAttempts < 0 || rc.MaxAttempts > maxRetryAttempts {
+		return fmt.Errorf("retry max_attempts should be between 0 and %d, provided: %d", maxRetryAttempts, rc.MaxAttempts)
+	}
+	if rc.Backoff != "" && !util.StringInSlice(rc.Backoff, supportedRetryBackoffs) {
+		return fmt.Errorf("unsupported retry backoff: %s", rc.Backoff)
+	}
+	if rc.Delay < 0 || rc.MaxDelay < 0 {
+		return fmt.Errorf("retry delays can not be negative")
+	}
+	if rc.Delay > maxSleep || rc.MaxDelay > maxSleep {
+		return fmt.Errorf("maximum retry delay limit exceeded, maximum: %d ms", maxSleep)
+	}
+	for _, code := range rc.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("retry status code is not valid: %d", code)
+		}
+	}
+	return nil
+}
+
+// RedirectConf determines how the redirect responses of the HTTP steps are followed.
+type RedirectConf struct {
+	// Redirects are not followed, the 3xx response is the result of the step
+	Disabled bool
+
+	// Max number of the redirects followed, DefaultMaxRedirects if zero. The 3xx response after the last followed
+	// redirect is the result of the step.
+	Max int
+}
+
+// MaxRedirects returns the max number of the redirects followed, zero if the redirects are disabled.
+func (rc RedirectConf) MaxRedirects() int {
+	if rc.Disabled {
+		return 0
+	}
+	if rc.Max == 0 {
+		return DefaultMaxRedirects
+	}
+	return rc.Max
+}
+
+func (rc RedirectConf) validate() error {
+	if rc.Max < 0 {
+		return fmt.Errorf("max redirects should be greater than or equal to 0")
+	}
+	return nil
+}
+
+// TransportConf overrides the connection limits of the transports of the HTTP steps. Zero values keep the
+// defaults of the engine mode, see the transports of the requester.
+type TransportConf struct {
+	// Max idle connections of all the hosts, zero means no limit
+	MaxIdleConns int
+
+	// Max idle connections kept for reuse per host
+	MaxIdleConnsPerHost int
+
+	// Max connections per host including the active ones, zero means no limit
+	MaxConnsPerHost int
+
+	// Idle connections are closed after the timeout, zero means no timeout
+	IdleConnTimeout time.Duration
+
+	// Interval of the keep-alive probes of the idle connections, the HTTP/2 PING frames and the TCP keep-alives.
+	// Keeps the pooled connections open through the intermediaries dropping the idle ones, the defaults are kept
+	// if zero.
+	KeepAlivePing time.Duration
+}
+
+func (tc TransportConf) validate() error {
+	if tc.MaxIdleConns < 0 || tc.MaxIdleConnsPerHost < 0 || tc.MaxConnsPerHost < 0 || tc.IdleConnTimeout < 0 ||
+		tc.KeepAlivePing < 0 {
+		return fmt.Errorf("transport limits should be greater than or equal to 0")
+	}
+	return nil
+}
+
+// GrpcConf includes the necessary data to make a gRPC call without generated stubs.
+type GrpcConf struct {
+	// Full method name like "package.Service/Method"
+	Method string
+
+	// Path of the FileDescriptorSet file generated by "protoc --include_imports --descriptor_set_out"
+	ProtoSet string
+
+	// Number of the Payload messages sent on a client or bidi streaming method, 1 if zero
+	StreamCount int
+
+	// Number of the messages to read from a server or bidi streaming method, the stream is closed after them.
+	// Messages are read until the end of the stream if zero.
+	MessageCount int
+
+	// Wait between the sent messages of a client or bidi streaming method, in milliseconds
+	SendInterval int
+}
+
+// WebSocketConf determines how long a websocket step reads from the connection after sending the Payload.
+// If both of them are zero, the step reads a single message.
+type WebSocketConf struct {
+	// Number of messages to read
+	MessageCount int
+
+	// Duration to read the messages, in milliseconds
+	ReadDuration int
+}
+
+// SSEConf determines how long a sse step reads the event stream.
+// If both of them are zero, the step reads a single event.
+type SSEConf struct {
+	// Number of events to read
+	EventCount int
+
+	// Duration to read the events, in milliseconds
+	ReadDuration int
+}
+
+// SocketConf determines how the Payload of a tcp or udp step is sent and how the response is read.
+type SocketConf struct {
+	// Payload is hex encoded like "0a1b2c", it is decoded after the variables are injected
+	Hex bool
+
+	// Number of the response bytes to read. If zero, the first chunk (or datagram for udp) is read.
+	ReadBytes int
+
+	// Only sends the payload, no response is read
+	WriteOnly bool
+
+	// Regular expression that the response should match, a mismatch is reported as a failed assertion
+	Match string
+}
+
+// DNSConf includes the question of a dns step, sent to the resolver given in the URL like dns://8.8.8.8:53.
+type DNSConf struct {
+	// Domain name to query, can include the variables like "{{_randomWord}}.example.com"
+	Name string
+
+	// Query type, one of the SupportedDNSQueryTypes
+	QType string
+
+	// Transport protocol of the queries, udp or tcp
+	Transport string
+
+	// Clears the recursion desired flag of the queries, for the authoritative servers
+	NoRecursion bool
+}
+
+// GraphQLConf includes the operation of a GraphQL step, sent as the JSON body of a POST request.
+type GraphQLConf struct {
+	// Query document of the operation
+	Query string
+
+	// JSON object of the variables. Can include the environment variables like {"id": "{{userId}}"}.
+	Variables string
+
+	// Name of the operation to run if the Query includes more than one. Optional.
+	OperationName string
+}
+
+// Payload returns the JSON request body of the operation.
+func (c GraphQLConf) Payload() string {
+	var b strings.Builder
+	query, _ := json.Marshal(c.Query)
+	b.WriteString(`{"query":`)
+	b.Write(query)
+	if c.OperationName != "" {
+		name, _ := json.Marshal(c.OperationName)
+		b.WriteString(`,"operationName":`)
+		b.Write(name)
+	}
+	if strings.TrimSpace(c.Variables) != "" {
+		// may include the environment variables, injected into the body per request
+		b.WriteString(`,"variables":`)
+		b.WriteString(c.Variables)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// MultipartPart is a form field or a file of a multipart/form-data body.
+type MultipartPart struct {
+	// Form field name of the part
+	Name string
+
+	// Value of the form field. Content of the file if FileName is given and FilePath is empty.
+	Value string
+
+	// Path of the local file sent as the content of the part.
+	FilePath string
+
+	// File name in the Content-Disposition of the part. Base of the FilePath is used if empty.
+	FileName string
+
+	// Injects the variables into the Value, or into the content of the file read into the memory once,
+	// for each request.
+	Template bool
+}
+
+// IsFile returns true if the part is sent as a file.
+func (p MultipartPart) IsFile() bool {
+	return p.FilePath != "" || p.FileName != ""
+}
+
+// TimeoutDuration returns the timeout of a request of the step.
+func (si *ScenarioStep) TimeoutDuration() time.Duration {
+	if si.RequestTimeout > 0 {
+		return si.RequestTimeout
+	}
+	return time.Duration(si.Timeout) * time.Second
+}
+
+// IsHTTP returns true if the step is sent by the HTTP requester.
+func (si *ScenarioStep) IsHTTP() bool {
+	return si.Type == "" || si.Type == StepTypeHTTP || si.Type == StepTypeGraphQL
 }
 
 type SourceType string
 
 const (
-	Header SourceType = "header"
-	Body   SourceType = "body"
-	Cookie SourceType = "cookies"
+	Header  SourceType = "header"
+	Body    SourceType = "body"
+	Cookie  SourceType = "cookies"
+	Trailer SourceType = "trailer" // read after the body, like the grpc-status of gRPC-web
 )
 
 type RegexCaptureConf struct {
 	Exp *string `json:"exp"`
 	No  int     `json:"matchNo"`
+
+	// Fails the step with ErrorCapture if the capture fails, like when the expression doesn't match. The failure is
+	// only reported in the FailedCaptures of the step otherwise.
+	Fatal bool `json:"fatal"`
+}
+
+// GroupNames returns the names of the named groups of the expression, like tok of (?P<tok>[a-f0-9]+), in their
+// order. Each one is captured as a variable next to the match. Nil if the expression is invalid.
+func (c *RegexCaptureConf) GroupNames() []string {
+	if c.Exp == nil {
+		return nil
+	}
+	re, err := regexp.Compile(*c.Exp)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, n := range re.SubexpNames() {
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
 }
 
 type EnvCaptureConf struct {
@@ -259,9 +1109,25 @@ type EnvCaptureConf struct {
 	CookieName *string           `json:"cookie_name"`
 }
 
+// Vars returns the names of the variables captured by the conf, its Name followed by the named groups of its RegExp.
+func (ce EnvCaptureConf) Vars() []string {
+	if ce.RegExp == nil {
+		return []string{ce.Name}
+	}
+	return append([]string{ce.Name}, ce.RegExp.GroupNames()...)
+}
+
+// Fatal reports whether the step fails if the capture fails, see RegexCaptureConf.Fatal.
+func (ce EnvCaptureConf) Fatal() bool {
+	return ce.RegExp != nil && ce.RegExp.Fatal
+}
+
 type CsvData struct {
 	Rows   []map[string]interface{}
 	Random bool
+
+	// Wrap around when the rows run out in sequential order, otherwise the remaining iterations fail without a row
+	Circular bool
 }
 
 // Auth struct should be able to include all necessary authentication realated data for supportedAuthentications.
@@ -269,39 +1135,157 @@ type Auth struct {
 	Type     string
 	Username string
 	Password string
+
+	// OAuth2 client credentials grant, for AuthOAuth2ClientCredentials
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       string // space separated
+
+	// Static token sent in the "Authorization: Bearer" header, for AuthBearer. Variables are injected.
+	Token string
 }
 
 func (si *ScenarioStep) validate(definedEnvs map[string]struct{}) error {
-	if !util.StringInSlice(si.Method, supportedProtocolMethods) {
-		return fmt.Errorf("unsupported Request Method: %s", si.Method)
+	if si.Type != "" && !util.StringInSlice(si.Type, supportedStepTypes) {
+		return fmt.Errorf("unsupported step type: %s", si.Type)
+	}
+	switch si.Type {
+	case StepTypeGRPC:
+		if err := si.validateGrpc(); err != nil {
+			return err
+		}
+	case StepTypeWebSocket:
+		if err := si.validateWebSocket(); err != nil {
+			return err
+		}
+	case StepTypeSSE:
+		if err := si.validateSSE(); err != nil {
+			return err
+		}
+	case StepTypeTCP, StepTypeUDP:
+		if err := si.validateSocket(); err != nil {
+			return err
+		}
+	case StepTypeDNS:
+		if err := si.validateDNS(); err != nil {
+			return err
+		}
+	default:
+		if !util.StringInSlice(si.Method, supportedProtocolMethods) {
+			return fmt.Errorf("unsupported Request Method: %s", si.Method)
+		}
+		if si.Protocol != "" && !util.StringInSlice(si.Protocol, SupportedProtocols[:]) {
+			return fmt.Errorf("unsupported protocol: %s", si.Protocol)
+		}
+		if si.Protocol == ProtocolH2C && strings.HasPrefix(strings.ToLower(si.URL), "https://") {
+			return fmt.Errorf("h2c protocol can not be used with https target: %s", si.URL)
+		}
+		if si.Protocol == ProtocolH3 && strings.HasPrefix(strings.ToLower(si.URL), "http://") {
+			return fmt.Errorf("h3 protocol can not be used with http target: %s", si.URL)
+		}
+	}
+	if si.Type == StepTypeGraphQL {
+		if strings.TrimSpace(si.GraphQL.Query) == "" {
+			return fmt.Errorf("graphql query is required for the step %d", si.ID)
+		}
+		if si.Method != http.MethodPost {
+			return fmt.Errorf("graphql step %d should use the POST method, provided: %s", si.ID, si.Method)
+		}
+		if len(si.MultipartStream) > 0 {
+			return fmt.Errorf("multipart payload can not be used with the graphql step %d", si.ID)
+		}
 	}
 	if si.Auth != (Auth{}) && !util.StringInSlice(si.Auth.Type, supportedAuthentications) {
 		return fmt.Errorf("unsupported Authentication Method (%s) ", si.Auth.Type)
 	}
+	if si.Auth.Type == AuthOAuth2ClientCredentials {
+		if !validator.IsURL(si.Auth.TokenURL) {
+			return fmt.Errorf("token_url is not valid for %s auth: %s", AuthOAuth2ClientCredentials, si.Auth.TokenURL)
+		}
+		if si.Auth.ClientID == "" {
+			return fmt.Errorf("client_id should be given for %s auth", AuthOAuth2ClientCredentials)
+		}
+	}
+	if si.Auth.Type == AuthBearer && si.Auth.Token == "" {
+		return fmt.Errorf("token should be given for %s auth", AuthBearer)
+	}
+	if si.Auth.Type == AuthNTLM && (si.Auth.Username == "" || si.Auth.Password == "") {
+		return fmt.Errorf("username and password should be given for %s auth", AuthNTLM)
+	}
 	if si.ID == 0 {
 		return fmt.Errorf("step ID should be greater than zero")
 	}
-	if !envVarRegexp.MatchString(si.URL) && !validator.IsURL(strings.ReplaceAll(si.URL, " ", "_")) {
+	if si.Parallel < 0 {
+		return fmt.Errorf("parallel of the step %d should be greater than or equal to 0", si.ID)
+	}
+	if si.IsHTTP() && len(si.Targets) == 0 && !envVarRegexp.MatchString(si.URL) &&
+		!validator.IsURL(strings.ReplaceAll(si.URL, " ", "_")) {
 		return fmt.Errorf("target is not valid: %s", si.URL)
 	}
 	if si.Sleep != "" {
-		sleep := strings.Split(si.Sleep, "-")
-
-		// Avoid invalid syntax like "-300-500"
-		if len(sleep) > 2 {
-			return fmt.Errorf("sleep expression is not valid: %s", si.Sleep)
+		if _, _, err := ParseSleep(si.Sleep); err != nil {
+			return err
 		}
-
-		// Validate string to int conversion
-		for _, s := range sleep {
-			dur, err := strconv.Atoi(s)
-			if err != nil {
-				return fmt.Errorf("sleep is not valid: %s", si.Sleep)
+	}
+	if err := si.SleepDistribution.validate(si.Sleep); err != nil {
+		return err
+	}
+	if err := si.Retry.validate(); err != nil {
+		return err
+	}
+	if err := si.Redirect.validate(); err != nil {
+		return err
+	}
+	if si.RequestTimeout < 0 || si.DialTimeout < 0 || si.TLSHandshakeTimeout < 0 {
+		return fmt.Errorf("timeouts of the step %d can not be negative", si.ID)
+	}
+	if si.MaxResponseBodyBytes < 0 {
+		return fmt.Errorf("max response body bytes can not be negative: %d", si.MaxResponseBodyBytes)
+	}
+	for _, tag := range si.Tags {
+		if !stepTagRegexp.MatchString(tag) {
+			return fmt.Errorf("tag of the step %d is not valid: %q", si.ID, tag)
+		}
+	}
+	if si.ResponseSchema != "" && !si.IsHTTP() {
+		return fmt.Errorf("response schema is only supported by the http steps")
+	}
+	if len(si.ExpectedStatus) > 0 && !si.IsHTTP() {
+		return fmt.Errorf("expected status is only supported by the http steps")
+	}
+	if len(si.MultipartStream) > 0 {
+		if !si.IsHTTP() {
+			return fmt.Errorf("multipart payload is only supported by the http steps")
+		}
+		for _, p := range si.MultipartStream {
+			if p.Name == "" {
+				return fmt.Errorf("multipart part name can not be empty in step %d", si.ID)
 			}
+		}
+	}
+	if si.RequestCompression != "" {
+		if !util.StringInSlice(si.RequestCompression, supportedRequestCompressions) {
+			return fmt.Errorf("unsupported request compression: %s", si.RequestCompression)
+		}
+		if !si.IsHTTP() || len(si.MultipartStream) > 0 {
+			return fmt.Errorf("request compression is only supported by the http steps with a payload")
+		}
+	}
 
-			if dur > maxSleep {
-				return fmt.Errorf("maximum sleep limit exceeded. provided: %d ms, maximum: %d ms", dur, maxSleep)
-			}
+	if si.ChunkedBody != nil {
+		if err := si.ChunkedBody.validate(si); err != nil {
+			return err
+		}
+	}
+	if si.CSRF != nil {
+		if err := si.CSRF.validate(si); err != nil {
+			return err
+		}
+	}
+	if len(si.Targets) > 0 {
+		if err := validateTargets(si); err != nil {
+			return err
 		}
 	}
 
@@ -320,6 +1304,107 @@ func (si *ScenarioStep) validate(definedEnvs map[string]struct{}) error {
 	return nil
 }
 
+// HasAnyTag reports whether the step has any of the given tags.
+func (si *ScenarioStep) HasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		if util.StringInSlice(tag, si.Tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (si *ScenarioStep) validateGrpc() error {
+	u, err := url.Parse(si.URL)
+	if err != nil || u.Host == "" || !(u.Scheme == "grpc" || u.Scheme == "grpcs") {
+		return fmt.Errorf("grpc target should be like grpc://host:port or grpcs://host:port, got: %s", si.URL)
+	}
+	if !strings.Contains(strings.TrimPrefix(si.Grpc.Method, "/"), "/") {
+		return fmt.Errorf("grpc method should be like package.Service/Method, got: %s", si.Grpc.Method)
+	}
+	if si.Grpc.ProtoSet == "" {
+		return fmt.Errorf("grpc proto_set is required for the step %d", si.ID)
+	}
+	if si.Grpc.StreamCount < 0 || si.Grpc.MessageCount < 0 || si.Grpc.SendInterval < 0 {
+		return fmt.Errorf("grpc stream_count, message_count and send_interval can not be negative")
+	}
+	return nil
+}
+
+func (si *ScenarioStep) validateWebSocket() error {
+	if !envVarRegexp.MatchString(si.URL) {
+		u, err := url.Parse(si.URL)
+		if err != nil || u.Host == "" || !(u.Scheme == "ws" || u.Scheme == "wss") {
+			return fmt.Errorf("websocket target should be like ws://host:port/path or wss://host:port/path, got: %s", si.URL)
+		}
+	}
+	if si.WebSocket.MessageCount < 0 || si.WebSocket.ReadDuration < 0 {
+		return fmt.Errorf("websocket message_count and read_duration can not be negative")
+	}
+	return nil
+}
+
+func (si *ScenarioStep) validateSSE() error {
+	if !envVarRegexp.MatchString(si.URL) {
+		u, err := url.Parse(si.URL)
+		if err != nil || u.Host == "" || !(u.Scheme == "http" || u.Scheme == "https") {
+			return fmt.Errorf("sse target should be like http://host:port/path or https://host:port/path, got: %s", si.URL)
+		}
+	}
+	if !util.StringInSlice(si.Method, supportedProtocolMethods) {
+		return fmt.Errorf("unsupported Request Method: %s", si.Method)
+	}
+	if si.SSE.EventCount < 0 || si.SSE.ReadDuration < 0 {
+		return fmt.Errorf("sse event_count and read_duration can not be negative")
+	}
+	return nil
+}
+
+func (si *ScenarioStep) validateSocket() error {
+	if !envVarRegexp.MatchString(si.URL) {
+		u, err := url.Parse(si.URL)
+		if err != nil || u.Scheme != si.Type || u.Hostname() == "" || u.Port() == "" {
+			return fmt.Errorf("%s target should be like %s://host:port, got: %s", si.Type, si.Type, si.URL)
+		}
+	}
+	if si.Socket.ReadBytes < 0 {
+		return fmt.Errorf("%s read_bytes can not be negative", si.Type)
+	}
+	if si.Socket.WriteOnly && (si.Socket.ReadBytes > 0 || si.Socket.Match != "") {
+		return fmt.Errorf("%s write_only can not be used with read_bytes or match", si.Type)
+	}
+	if si.Socket.Match != "" {
+		if _, err := regexp.Compile(si.Socket.Match); err != nil {
+			return fmt.Errorf("invalid %s match pattern: %v", si.Type, err)
+		}
+	}
+	if si.Socket.Hex && !strings.Contains(si.Payload, "{{") { // templated payloads are validated on injection
+		if _, err := hex.DecodeString(strings.Join(strings.Fields(si.Payload), "")); err != nil {
+			return fmt.Errorf("%s payload is not hex encoded: %v", si.Type, err)
+		}
+	}
+	return nil
+}
+
+func (si *ScenarioStep) validateDNS() error {
+	if !envVarRegexp.MatchString(si.URL) {
+		u, err := url.Parse(si.URL)
+		if err != nil || u.Scheme != StepTypeDNS || u.Hostname() == "" {
+			return fmt.Errorf("dns target should be like dns://host:port, got: %s", si.URL)
+		}
+	}
+	if strings.TrimSpace(si.DNS.Name) == "" {
+		return fmt.Errorf("dns name is required for the step %d", si.ID)
+	}
+	if !util.StringInSlice(si.DNS.QType, SupportedDNSQueryTypes) {
+		return fmt.Errorf("unsupported dns query type: %s", si.DNS.QType)
+	}
+	if !util.StringInSlice(si.DNS.Transport, supportedDNSTransports) {
+		return fmt.Errorf("unsupported dns transport: %s", si.DNS.Transport)
+	}
+	return nil
+}
+
 func wrapAsScenarioValidationError(err error) ScenarioValidationError {
 	return ScenarioValidationError{
 		msg:        fmt.Sprintf("ScenarioValidationError %v", err),
@@ -328,7 +1413,7 @@ func wrapAsScenarioValidationError(err error) ScenarioValidationError {
 }
 
 func validateCaptureConf(conf EnvCaptureConf) error {
-	if !(conf.From == Header || conf.From == Body || conf.From == Cookie) {
+	if !(conf.From == Header || conf.From == Body || conf.From == Cookie || conf.From == Trailer) {
 		return CaptureConfigError{
 			msg: fmt.Sprintf("invalid \"from\" type in capture env : %s", conf.From),
 		}
@@ -340,12 +1425,31 @@ func validateCaptureConf(conf EnvCaptureConf) error {
 		}
 	}
 
+	if conf.From == Trailer && conf.Key == nil {
+		return CaptureConfigError{
+			msg: fmt.Sprintf("%s, trailer key must be specified", conf.Name),
+		}
+	}
+
 	if conf.From == Body && conf.JsonPath == nil && conf.RegExp == nil && conf.Xpath == nil && conf.XpathHtml == nil {
 		return CaptureConfigError{
 			msg: fmt.Sprintf("%s, one of json_path, regexp, xpath or xpath_html key must be specified when extracting from body", conf.Name),
 		}
 	}
 
+	if conf.RegExp != nil {
+		if conf.RegExp.Exp == nil {
+			return CaptureConfigError{
+				msg: fmt.Sprintf("%s, regexp exp must be specified", conf.Name),
+			}
+		}
+		if _, err := regexp.Compile(*conf.RegExp.Exp); err != nil {
+			return CaptureConfigError{
+				msg: fmt.Sprintf("%s, invalid regexp: %v", conf.Name, err),
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -371,6 +1475,140 @@ func ParseTLS(certFile, keyFile string) (tls.Certificate, *x509.CertPool, error)
 	return cert, pool, nil
 }
 
+// ParseSleep parses a sleep expression like "1000", "300-500" or "1s-3s" and returns the min and max durations
+// in ms. Bare numbers are in ms, values with a unit are parsed by time.ParseDuration.
+func ParseSleep(sleep string) (min int, max int, err error) {
+	parts := strings.Split(sleep, "-")
+
+	// Avoid invalid syntax like "-300-500"
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("sleep expression is not valid: %s", sleep)
+	}
+
+	durs := make([]int, 0, len(parts))
+	for _, p := range parts {
+		dur, err := strconv.Atoi(p)
+		if err != nil {
+			d, perr := time.ParseDuration(p)
+			if perr != nil || d < 0 {
+				return 0, 0, fmt.Errorf("sleep is not valid: %s", sleep)
+			}
+			dur = int(d.Milliseconds())
+		}
+
+		if dur > maxSleep {
+			return 0, 0, fmt.Errorf("maximum sleep limit exceeded. provided: %d ms, maximum: %d ms", dur, maxSleep)
+		}
+		durs = append(durs, dur)
+	}
+
+	min, max = durs[0], durs[len(durs)-1]
+	if min > max {
+		min, max = max, min
+	}
+	return min, max, nil
+}
+
+// SleepDistribution is the random distribution of the sleep durations of a step. Durations are sampled from the
+// random stream of the iteration, so the same seed gives the same durations.
+type SleepDistribution struct {
+	// One of SleepDistUniform, SleepDistExponential and SleepDistNormal. Uniform samples from the range of the Sleep,
+	// the others don't use the Sleep.
+	Type string
+
+	// Mean of the exponential and normal distributions and the standard deviation of the normal one in ms.
+	// Sampled durations are clamped to [0, 90s].
+	Mean   int
+	StdDev int
+}
+
+func (sd SleepDistribution) validate(sleep string) error {
+	switch sd.Type {
+	case "":
+		return nil
+	case SleepDistUniform:
+		if sleep == "" {
+			return fmt.Errorf("uniform sleep distribution requires a min and max")
+		}
+		return nil
+	}
+	if !util.StringInSlice(sd.Type, supportedSleepDistributions) {
+		return fmt.Errorf("unsupported sleep distribution: %s", sd.Type)
+	}
+	if sleep != "" {
+		return fmt.Errorf("min and max can not be used with the %s sleep distribution", sd.Type)
+	}
+	if sd.Mean <= 0 || sd.StdDev < 0 {
+		return fmt.Errorf("mean of the sleep distribution should be greater than 0 and stddev should not be negative")
+	}
+	if sd.Mean > maxSleep || sd.StdDev > maxSleep {
+		return fmt.Errorf("maximum sleep limit exceeded, maximum: %d ms", maxSleep)
+	}
+	return nil
+}
+
+// TLSConf is the user given TLS settings of a step.
+type TLSConf struct {
+	InsecureSkipVerify bool
+
+	// Client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+
+	// CA bundle to verify the server certificate, system roots are used if empty
+	CAFile string
+
+	// Minimum accepted TLS version, one of 1.0, 1.1, 1.2, 1.3
+	MinVersion string
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Load reads the certificate files and returns the tls.Config of the settings.
+func (c TLSConf) Load() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.MinVersion != "" {
+		v, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls min_version: %s", c.MinVersion)
+		}
+		tlsConfig.MinVersion = v
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("both cert_path and key_path should be given for the client certificate")
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CAFile != "" {
+		caCert, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificate found in ca_path: %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
 func IsTargetValid(url string) error {
 	if !envVarRegexp.MatchString(url) && !validator.IsURL(strings.ReplaceAll(url, " ", "_")) {
 		return fmt.Errorf("target is not valid: %s", url)
@@ -1,11 +1,22 @@
 package types
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
 	"net/http"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestScenarioStepValid_EnvVariableInHeader(t *testing.T) {
@@ -100,6 +111,88 @@ func TestScenarioStepValid_EnvVariableInURL(t *testing.T) {
 	t.Logf("%v", environmentNotDefined)
 }
 
+func TestScenarioStepValid_TemplateFunctions(t *testing.T) {
+	st := ScenarioStep{
+		ID:      23,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"X-Request-Id": "{{uuid()}}"},
+		Payload: `{"email": "{{randomEmail()}}", "age": "{{randomInt(18,65)}}", "at": "{{now(\"RFC3339\")}}"}`,
+		URL:     "https://test.com/{{timestamp()}}",
+	}
+
+	if err := st.validate(map[string]struct{}{}); err != nil {
+		t.Errorf("Expected template functions to be valid, Found: %v", err)
+	}
+}
+
+func TestScenarioStepValid_TemplateFunctionsWithVariables(t *testing.T) {
+	st := ScenarioStep{
+		ID:      24,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"X-Signature": `{{hmacSHA256("{{secret}}", "{{payload}}")}}`},
+		Payload: "{{payload}}",
+		URL:     "https://test.com/{{md5Hex(abc)}}",
+	}
+
+	if err := st.validate(map[string]struct{}{"secret": {}, "payload": {}}); err != nil {
+		t.Errorf("Expected template functions to be valid, Found: %v", err)
+	}
+
+	var environmentNotDefined EnvironmentNotDefinedError
+	if err := st.validate(map[string]struct{}{"payload": {}}); !errors.As(err, &environmentNotDefined) {
+		t.Errorf("Should be EnvironmentNotDefinedError, Found: %v", err)
+	}
+}
+
+func TestScenarioValid_UndefinedEnvsListed(t *testing.T) {
+	t.Parallel()
+
+	path := "$.token"
+	s := Scenario{
+		Steps: []ScenarioStep{
+			{ID: 1, Method: http.MethodPost, URL: "https://test.com/login", Payload: `{"user": "{{USER}}"}`,
+				EnvsToCapture: []EnvCaptureConf{{Name: "TOKEN", From: Body, JsonPath: &path}}},
+			{ID: 2, Method: http.MethodGet, URL: "https://test.com/{{usr}}/{{toekn}}",
+				Headers: map[string]string{"Authorization": "{{TOKEN}}", "X-Token": "{{toekn}}"}},
+			{ID: 3, Method: http.MethodGet, URL: "https://test.com",
+				Auth: Auth{Type: AuthHttpBasic, Username: "{{USER}}", Password: "{{pasword}}"}},
+		},
+		Envs: map[string]interface{}{"USER": "admin"},
+	}
+
+	err := s.validate()
+	var environmentNotDefined EnvironmentNotDefinedError
+	if !errors.As(err, &environmentNotDefined) {
+		t.Fatalf("Should be EnvironmentNotDefinedError, Found: %v", err)
+	}
+	expected := "step 2: {{usr}}, {{toekn}} are not defined to use by global and captured environments; " +
+		"step 3: {{pasword}} is not defined to use by global and captured environments"
+	if environmentNotDefined.Error() != expected {
+		t.Errorf("Expected %v, Found: %v", expected, environmentNotDefined.Error())
+	}
+
+	// other errors are returned as they are
+	s.Steps[2].Method = "FETCH"
+	if err := s.validate(); errors.As(err, &environmentNotDefined) {
+		t.Errorf("Expected the method error, Found: %v", err)
+	}
+}
+
+func TestScenarioValid_VirtualUserEnvs(t *testing.T) {
+	s := Scenario{
+		Steps: []ScenarioStep{{
+			ID:      1,
+			Method:  http.MethodGet,
+			Headers: map[string]string{"X-User": "{{vu.id}}"},
+			URL:     "https://test.com/users/{{vu.index}}",
+		}},
+	}
+
+	if err := s.validate(); err != nil {
+		t.Errorf("Expected virtual user envs to be valid, Found: %v", err)
+	}
+}
+
 func TestScenarioStep_InvalidCaptureConfig(t *testing.T) {
 	url := "https://test.com"
 
@@ -125,6 +218,17 @@ func TestScenarioStep_InvalidCaptureConfig(t *testing.T) {
 		}},
 	}
 
+	stNoTrailerKey := ScenarioStep{
+		ID:     22,
+		Name:   "",
+		Method: http.MethodGet,
+		URL:    url,
+		EnvsToCapture: []EnvCaptureConf{{
+			Name: "FromTrailer",
+			From: SourceType(Trailer),
+		}},
+	}
+
 	stNoBodySpecifierKey := ScenarioStep{
 		ID:     22,
 		Name:   "",
@@ -136,6 +240,31 @@ func TestScenarioStep_InvalidCaptureConfig(t *testing.T) {
 		}},
 	}
 
+	invalidExp := "token=(?P<tok>[a-f"
+	stInvalidRegexp := ScenarioStep{
+		ID:     22,
+		Name:   "",
+		Method: http.MethodGet,
+		URL:    url,
+		EnvsToCapture: []EnvCaptureConf{{
+			Name:   "FromBody",
+			From:   SourceType(Body),
+			RegExp: &RegexCaptureConf{Exp: &invalidExp},
+		}},
+	}
+
+	stNoRegexpExp := ScenarioStep{
+		ID:     22,
+		Name:   "",
+		Method: http.MethodGet,
+		URL:    url,
+		EnvsToCapture: []EnvCaptureConf{{
+			Name:   "FromBody",
+			From:   SourceType(Body),
+			RegExp: &RegexCaptureConf{},
+		}},
+	}
+
 	definedEnvs := map[string]struct{}{}
 
 	tests := []struct {
@@ -143,8 +272,11 @@ func TestScenarioStep_InvalidCaptureConfig(t *testing.T) {
 		st   ScenarioStep
 	}{
 		{"NoHeaderKey", stNoHeaderKey},
+		{"NoTrailerKey", stNoTrailerKey},
 		{"NoBodySpecifierKey", stNoBodySpecifierKey},
 		{"EmptyFromField", stEmptyFromField},
+		{"InvalidRegexp", stInvalidRegexp},
+		{"NoRegexpExp", stNoRegexpExp},
 	}
 
 	for _, test := range tests {
@@ -163,6 +295,51 @@ func TestScenarioStep_InvalidCaptureConfig(t *testing.T) {
 	}
 }
 
+func TestScenarioRegexCaptureGroups(t *testing.T) {
+	t.Parallel()
+
+	exp := "token=(?P<tok>[a-f0-9]+)&id=(?P<id>[0-9]+)"
+	newScenario := func(groupExp string, url string) Scenario {
+		return Scenario{Steps: []ScenarioStep{
+			{ID: 1, Method: http.MethodGet, URL: "https://test.com", EnvsToCapture: []EnvCaptureConf{{
+				Name: "LOGIN", From: Body, RegExp: &RegexCaptureConf{Exp: &groupExp},
+			}}},
+			{ID: 2, Method: http.MethodGet, URL: url},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		exp       string
+		url       string
+		shouldErr bool
+	}{
+		{"Groups", exp, "https://test.com/{{tok}}/{{id}}/{{LOGIN}}", false},
+		{"UndefinedGroup", exp, "https://test.com/{{user}}", true},
+		{"InvalidGroupName", "token=(?P<_tok>[a-f0-9]+)", "https://test.com", true},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+			s := newScenario(tf.exp, tf.url)
+			err := s.validate()
+			if tf.shouldErr && err == nil {
+				t.Errorf("Should be errored")
+			}
+			if !tf.shouldErr && err != nil {
+				t.Errorf("Error occurred %v", err)
+			}
+		})
+	}
+
+	ce := EnvCaptureConf{Name: "LOGIN", RegExp: &RegexCaptureConf{Exp: &exp}}
+	if vars := ce.Vars(); !reflect.DeepEqual(vars, []string{"LOGIN", "tok", "id"}) {
+		t.Errorf("Expected %v, Found: %v", []string{"LOGIN", "tok", "id"}, vars)
+	}
+}
+
 func TestScenarioStepValid_OSEnvVariableInPayload(t *testing.T) {
 	url := "https://test.com"
 	st := ScenarioStep{
@@ -192,3 +369,701 @@ func TestScenarioStepValid_OSEnvVariableInPayload(t *testing.T) {
 
 	t.Logf("%v", environmentNotDefined)
 }
+
+// writeTestCertFiles creates a self-signed certificate for 127.0.0.1 and returns its cert and key file paths
+func writeTestCertFiles(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("key generation failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"Ddosify Test"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cert generation failed: %v", err)
+	}
+	keyDer, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func TestTLSConfLoad(t *testing.T) {
+	t.Parallel()
+
+	certFile, keyFile := writeTestCertFiles(t)
+
+	conf := TLSConf{
+		InsecureSkipVerify: false,
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		CAFile:             certFile,
+		MinVersion:         "1.2",
+	}
+	tlsConfig, err := conf.Load()
+	if err != nil {
+		t.Fatalf("TestTLSConfLoad errored: %v", err)
+	}
+
+	if tlsConfig.InsecureSkipVerify {
+		t.Errorf("Expected %v, Found: %v", false, tlsConfig.InsecureSkipVerify)
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("Expected %v, Found: %v", tls.VersionTLS12, tlsConfig.MinVersion)
+	}
+	if len(tlsConfig.Certificates) != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, len(tlsConfig.Certificates))
+	}
+	if tlsConfig.RootCAs == nil {
+		t.Errorf("Expected RootCAs to be loaded from the ca bundle")
+	}
+}
+
+func TestTLSConfLoadErrors(t *testing.T) {
+	t.Parallel()
+
+	certFile, keyFile := writeTestCertFiles(t)
+
+	tests := []struct {
+		name string
+		conf TLSConf
+	}{
+		{"InvalidMinVersion", TLSConf{MinVersion: "1.4"}},
+		{"CertWithoutKey", TLSConf{CertFile: certFile}},
+		{"KeyWithoutCert", TLSConf{KeyFile: keyFile}},
+		{"MismatchedKeyPair", TLSConf{CertFile: keyFile, KeyFile: certFile}},
+		{"CANotFound", TLSConf{CAFile: filepath.Join(t.TempDir(), "not_found.pem")}},
+		{"CAWithoutCert", TLSConf{CAFile: keyFile}},
+	}
+
+	for _, test := range tests {
+		if _, err := test.conf.Load(); err == nil {
+			t.Errorf("TestTLSConfLoadErrors %s should be errored", test.name)
+		}
+	}
+}
+
+func TestGraphQLConfPayload(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		conf     GraphQLConf
+		expected string
+	}{
+		{GraphQLConf{Query: "{ users { id } }"}, `{"query":"{ users { id } }"}`},
+		{GraphQLConf{Query: "query A { a }", OperationName: "A", Variables: `{"id": {{id}}}`},
+			`{"query":"query A { a }","operationName":"A","variables":{"id": {{id}}}}`},
+	}
+
+	for _, test := range tests {
+		if p := test.conf.Payload(); p != test.expected {
+			t.Errorf("Expected %v, Found: %v", test.expected, p)
+		}
+	}
+}
+
+func TestScenarioStepValidGraphQL(t *testing.T) {
+	t.Parallel()
+
+	base := ScenarioStep{
+		ID:      1,
+		Type:    StepTypeGraphQL,
+		Method:  http.MethodPost,
+		URL:     "https://test.com/graphql",
+		GraphQL: GraphQLConf{Query: "{ users { id } }"},
+	}
+	if err := base.validate(map[string]struct{}{}); err != nil {
+		t.Errorf("Expected %v, Found: %v", nil, err)
+	}
+
+	noQuery := base
+	noQuery.GraphQL.Query = " "
+	get := base
+	get.Method = http.MethodGet
+	for _, s := range []ScenarioStep{noQuery, get} {
+		if err := s.validate(map[string]struct{}{}); err == nil {
+			t.Errorf("Expected %v, Found: %v", "error", err)
+		}
+	}
+}
+
+func TestScenarioStepValidSocket(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		step  ScenarioStep
+		valid bool
+	}{
+		{"Tcp", ScenarioStep{ID: 1, Type: StepTypeTCP, URL: "tcp://localhost:6379", Socket: SocketConf{Match: "^\\+PONG"}}, true},
+		{"UdpHex", ScenarioStep{ID: 1, Type: StepTypeUDP, URL: "udp://localhost:8125", Payload: "0a 1b", Socket: SocketConf{Hex: true}}, true},
+		{"TemplatedHex", ScenarioStep{ID: 1, Type: StepTypeUDP, URL: "udp://{{HOST}}", Payload: "0a{{BYTE}}", Socket: SocketConf{Hex: true}}, true},
+		{"SchemeMismatch", ScenarioStep{ID: 1, Type: StepTypeTCP, URL: "udp://localhost:8125"}, false},
+		{"NoPort", ScenarioStep{ID: 1, Type: StepTypeTCP, URL: "tcp://localhost"}, false},
+		{"InvalidHex", ScenarioStep{ID: 1, Type: StepTypeTCP, URL: "tcp://localhost:80", Payload: "xyz", Socket: SocketConf{Hex: true}}, false},
+		{"InvalidMatch", ScenarioStep{ID: 1, Type: StepTypeTCP, URL: "tcp://localhost:80", Socket: SocketConf{Match: "["}}, false},
+		{"NegativeRead", ScenarioStep{ID: 1, Type: StepTypeTCP, URL: "tcp://localhost:80", Socket: SocketConf{ReadBytes: -1}}, false},
+		{"WriteOnlyRead", ScenarioStep{ID: 1, Type: StepTypeUDP, URL: "udp://localhost:80", Socket: SocketConf{WriteOnly: true, ReadBytes: 2}}, false},
+	}
+
+	for _, test := range tests {
+		err := test.step.validate(map[string]struct{}{"HOST": {}, "BYTE": {}})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidDNS(t *testing.T) {
+	t.Parallel()
+
+	valid := DNSConf{Name: "ddosify.com", QType: "A", Transport: "udp"}
+	tests := []struct {
+		name  string
+		url   string
+		conf  DNSConf
+		valid bool
+	}{
+		{"Valid", "dns://8.8.8.8", valid, true},
+		{"Templated", "dns://{{RESOLVER}}", DNSConf{Name: "{{SUB}}.ddosify.com", QType: "MX", Transport: "tcp"}, true},
+		{"Scheme", "udp://8.8.8.8:53", valid, false},
+		{"NoName", "dns://8.8.8.8", DNSConf{QType: "A", Transport: "udp"}, false},
+		{"QType", "dns://8.8.8.8", DNSConf{Name: "ddosify.com", QType: "AXFR", Transport: "udp"}, false},
+		{"Transport", "dns://8.8.8.8", DNSConf{Name: "ddosify.com", QType: "A", Transport: "quic"}, false},
+		{"UndefinedEnv", "dns://8.8.8.8", DNSConf{Name: "{{MISSING}}.ddosify.com", QType: "A", Transport: "udp"}, false},
+	}
+
+	for _, test := range tests {
+		s := ScenarioStep{ID: 1, Type: StepTypeDNS, URL: test.url, DNS: test.conf}
+		err := s.validate(map[string]struct{}{"RESOLVER": {}, "SUB": {}})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidSleepDistribution(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		sleep string
+		dist  SleepDistribution
+		valid bool
+	}{
+		{"Exponential", "", SleepDistribution{Type: SleepDistExponential, Mean: 500}, true},
+		{"Normal", "", SleepDistribution{Type: SleepDistNormal, Mean: 1000, StdDev: 200}, true},
+		{"Uniform", "300-500", SleepDistribution{Type: SleepDistUniform}, true},
+		{"UniformNoRange", "", SleepDistribution{Type: SleepDistUniform}, false},
+		{"Unsupported", "", SleepDistribution{Type: "pareto", Mean: 500}, false},
+		{"WithRange", "300-500", SleepDistribution{Type: SleepDistExponential, Mean: 500}, false},
+		{"NoMean", "", SleepDistribution{Type: SleepDistNormal, StdDev: 200}, false},
+		{"NegativeStdDev", "", SleepDistribution{Type: SleepDistNormal, Mean: 500, StdDev: -1}, false},
+		{"MaxExceeded", "", SleepDistribution{Type: SleepDistExponential, Mean: 100000}, false},
+	}
+
+	for _, test := range tests {
+		s := ScenarioStep{ID: 1, Method: "GET", URL: "https://test.com", Sleep: test.sleep, SleepDistribution: test.dist}
+		err := s.validate(map[string]struct{}{})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidRequestCompression(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		step  ScenarioStep
+		valid bool
+	}{
+		{"Gzip", ScenarioStep{RequestCompression: RequestCompressionGzip}, true},
+		{"Deflate", ScenarioStep{Type: StepTypeGraphQL, RequestCompression: RequestCompressionDeflate,
+			GraphQL: GraphQLConf{Query: "{ users { id } }"}}, true},
+		{"Unsupported", ScenarioStep{RequestCompression: "br"}, false},
+		{"WebSocket", ScenarioStep{Type: StepTypeWebSocket, RequestCompression: RequestCompressionGzip}, false},
+		{"Multipart", ScenarioStep{RequestCompression: RequestCompressionGzip,
+			MultipartStream: []MultipartPart{{Name: "file"}}}, false},
+	}
+
+	for _, test := range tests {
+		s := test.step
+		s.ID = 1
+		s.Method = "POST"
+		s.URL = "https://test.com"
+		err := s.validate(map[string]struct{}{})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidProtocol(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		url   string
+		proto string
+		valid bool
+	}{
+		{"H2C", "http://test.com", ProtocolH2C, true},
+		{"H2CHttps", "https://test.com", ProtocolH2C, false},
+		{"H3", "https://test.com", ProtocolH3, true},
+		{"H3Http", "http://test.com", ProtocolH3, false},
+		{"Unsupported", "https://test.com", "SPDY", false},
+	}
+
+	for _, test := range tests {
+		s := ScenarioStep{ID: 1, Method: "GET", URL: test.url, Protocol: test.proto}
+		err := s.validate(map[string]struct{}{})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidChunkedBody(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		step  ScenarioStep
+		valid bool
+	}{
+		{"Valid", ScenarioStep{ChunkedBody: &ChunkedBody{Size: 1 << 20, ChunkSize: 1024, Delay: time.Second}}, true},
+		{"NoSize", ScenarioStep{ChunkedBody: &ChunkedBody{ChunkSize: 1024}}, false},
+		{"NegativeDelay", ScenarioStep{ChunkedBody: &ChunkedBody{Size: 10, ChunkSize: 1, Delay: -1}}, false},
+		{"Compressed", ScenarioStep{RequestCompression: RequestCompressionGzip,
+			ChunkedBody: &ChunkedBody{Size: 10, ChunkSize: 1}}, false},
+		{"GraphQL", ScenarioStep{Type: StepTypeGraphQL, GraphQL: GraphQLConf{Query: "{ users { id } }"},
+			ChunkedBody: &ChunkedBody{Size: 10, ChunkSize: 1}}, false},
+	}
+
+	for _, test := range tests {
+		s := test.step
+		s.ID = 1
+		s.Method = "POST"
+		s.URL = "https://test.com"
+		err := s.validate(map[string]struct{}{})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidCSRF(t *testing.T) {
+	t.Parallel()
+
+	form := "https://test.com/login"
+	tests := []struct {
+		name  string
+		step  ScenarioStep
+		valid bool
+	}{
+		{"Valid", ScenarioStep{CSRF: &CSRF{FormURL: form, Field: "csrf_token"}}, true},
+		{"FormURLWithEnv", ScenarioStep{CSRF: &CSRF{FormURL: "{{host}}/login", Field: "csrf_token"}}, true},
+		{"InvalidFormURL", ScenarioStep{CSRF: &CSRF{FormURL: "login", Field: "csrf_token"}}, false},
+		{"NoField", ScenarioStep{CSRF: &CSRF{FormURL: form}}, false},
+		{"Chunked", ScenarioStep{ChunkedBody: &ChunkedBody{Size: 10, ChunkSize: 1},
+			CSRF: &CSRF{FormURL: form, Field: "csrf_token"}}, false},
+		{"ChunkedWithHeader", ScenarioStep{ChunkedBody: &ChunkedBody{Size: 10, ChunkSize: 1},
+			CSRF: &CSRF{FormURL: form, Field: "csrf_token", Header: "X-CSRF-Token"}}, true},
+		{"WebSocket", ScenarioStep{Type: StepTypeWebSocket, CSRF: &CSRF{FormURL: form, Field: "csrf_token"}}, false},
+	}
+
+	for _, test := range tests {
+		s := test.step
+		s.ID = 1
+		s.Method = "POST"
+		s.URL = "https://test.com"
+		if s.Type == StepTypeWebSocket {
+			s.URL = "wss://test.com"
+		}
+		err := s.validate(map[string]struct{}{"host": {}})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		stepURL string
+		targets []WeightedTarget
+		valid   bool
+	}{
+		{"Valid", "", []WeightedTarget{{Name: "eu", URL: "https://eu.test.com", Weight: 3},
+			{URL: "https://us.test.com", Weight: 1}}, true},
+		{"InvalidURL", "", []WeightedTarget{{URL: "eu test", Weight: 1}}, false},
+		{"ZeroWeight", "", []WeightedTarget{{URL: "https://eu.test.com"}}, false},
+		{"DuplicatedName", "", []WeightedTarget{{URL: "https://eu.test.com", Weight: 1},
+			{URL: "https://eu.test.com/v2", Weight: 1}}, false},
+		{"NoTargets", "", nil, false},
+	}
+
+	for _, test := range tests {
+		s := ScenarioStep{ID: 1, Method: "GET", URL: test.stepURL, Targets: test.targets}
+		err := s.validate(map[string]struct{}{})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidSSE(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		url    string
+		method string
+		conf   SSEConf
+		valid  bool
+	}{
+		{"Valid", "https://test.com/events", "GET", SSEConf{EventCount: 10}, true},
+		{"Templated", "{{HOST}}/events", "POST", SSEConf{ReadDuration: 5000}, true},
+		{"Scheme", "ws://test.com/events", "GET", SSEConf{}, false},
+		{"Method", "https://test.com/events", "CONNECT", SSEConf{}, false},
+		{"NegativeCount", "https://test.com/events", "GET", SSEConf{EventCount: -1}, false},
+		{"NegativeDuration", "https://test.com/events", "GET", SSEConf{ReadDuration: -1}, false},
+	}
+
+	for _, test := range tests {
+		s := ScenarioStep{ID: 1, Type: StepTypeSSE, URL: test.url, Method: test.method, SSE: test.conf}
+		err := s.validate(map[string]struct{}{"HOST": {}})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestScenarioStepValidTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		tags  []string
+		valid bool
+	}{
+		{"Valid", []string{"payments", "critical", "team:core", "v1.2_beta-3"}, true},
+		{"Empty", []string{""}, false},
+		{"Space", []string{"pay ments"}, false},
+		{"Comma", []string{"payments,critical"}, false},
+	}
+
+	for _, test := range tests {
+		s := ScenarioStep{ID: 1, Method: "GET", URL: "https://test.com", Tags: test.tags}
+		err := s.validate(map[string]struct{}{})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+	}
+}
+
+func TestRedirectConf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		conf     RedirectConf
+		expected int
+		valid    bool
+	}{
+		{"Default", RedirectConf{}, DefaultMaxRedirects, true},
+		{"Max", RedirectConf{Max: 3}, 3, true},
+		{"Disabled", RedirectConf{Disabled: true, Max: 3}, 0, true},
+		{"NegativeMax", RedirectConf{Max: -1}, -1, false},
+	}
+
+	for _, test := range tests {
+		s := ScenarioStep{ID: 1, Method: "GET", URL: "https://test.com", Redirect: test.conf}
+		err := s.validate(map[string]struct{}{})
+		if (err == nil) != test.valid {
+			t.Errorf("%s Expected valid: %v, Found: %v", test.name, test.valid, err)
+		}
+		if m := test.conf.MaxRedirects(); test.valid && m != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.name, test.expected, m)
+		}
+	}
+}
+
+func TestScenarioFilterTags(t *testing.T) {
+	t.Parallel()
+
+	s := Scenario{
+		Steps: []ScenarioStep{
+			{ID: 1, Tags: []string{"auth"}},
+			{ID: 2, Tags: []string{"payments", "critical"}},
+			{ID: 3},
+			{ID: 4, Tags: []string{"critical"}},
+		},
+		WeightedScenarios: []WeightedScenario{
+			{Name: "browse", Weight: 1, StepIDs: []uint16{1, 3}},
+			{Name: "checkout", Weight: 2, StepIDs: []uint16{1, 2, 4}},
+		},
+	}
+
+	filtered := s.FilterTags([]string{"payments", "critical"})
+	var ids []uint16
+	for _, si := range filtered.Steps {
+		ids = append(ids, si.ID)
+	}
+	if !reflect.DeepEqual(ids, []uint16{2, 4}) {
+		t.Errorf("Expected %v, Found: %v", []uint16{2, 4}, ids)
+	}
+	expected := []WeightedScenario{{Name: "checkout", Weight: 2, StepIDs: []uint16{2, 4}}}
+	if !reflect.DeepEqual(filtered.WeightedScenarios, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, filtered.WeightedScenarios)
+	}
+	if len(s.Steps) != 4 || len(s.WeightedScenarios[1].StepIDs) != 3 {
+		t.Errorf("Expected the original scenario unchanged, Found: %v", s)
+	}
+}
+
+func TestScenarioDependencies(t *testing.T) {
+	t.Parallel()
+
+	path := "$.token"
+	step := func(id uint16, url string, deps ...uint16) ScenarioStep {
+		return ScenarioStep{ID: id, Method: http.MethodGet, URL: url, DependsOn: deps}
+	}
+	login := step(1, "https://test.com/login")
+	login.EnvsToCapture = []EnvCaptureConf{{Name: "TOKEN", From: Body, JsonPath: &path}}
+
+	tests := []struct {
+		name      string
+		steps     []ScenarioStep
+		weighted  []WeightedScenario
+		shouldErr bool
+	}{
+		{"Valid", []ScenarioStep{login, step(2, "https://test.com/{{TOKEN}}", 1),
+			step(3, "https://test.com/c", 1), step(4, "https://test.com/{{TOKEN}}", 2, 3)}, nil, false},
+		{"DependencyAfterStep", []ScenarioStep{step(2, "https://test.com/{{TOKEN}}", 1), login}, nil, false},
+		{"CaptureOfIndependentStep", []ScenarioStep{login, step(2, "https://test.com/a", 1),
+			step(3, "https://test.com/{{TOKEN}}")}, nil, true},
+		{"NotFound", []ScenarioStep{login, step(2, "https://test.com/a", 5)}, nil, true},
+		{"Self", []ScenarioStep{login, step(2, "https://test.com/a", 2)}, nil, true},
+		{"Cycle", []ScenarioStep{step(1, "https://test.com/a", 3), step(2, "https://test.com/b", 1),
+			step(3, "https://test.com/c", 2)}, nil, true},
+		{"Weighted", []ScenarioStep{login, step(2, "https://test.com/a", 1)},
+			[]WeightedScenario{{Name: "w", Weight: 1, StepIDs: []uint16{1, 2}}}, true},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+			s := Scenario{Steps: tf.steps, WeightedScenarios: tf.weighted}
+
+			err := s.validate()
+			if tf.shouldErr && err == nil {
+				t.Errorf("Should be errored")
+			}
+			if !tf.shouldErr && err != nil {
+				t.Errorf("Error occurred %v", err)
+			}
+		})
+	}
+}
+
+func TestScenarioDependencyCycle(t *testing.T) {
+	t.Parallel()
+
+	s := Scenario{Steps: []ScenarioStep{
+		{ID: 1},
+		{ID: 2, DependsOn: []uint16{1, 4}},
+		{ID: 3, DependsOn: []uint16{2}},
+		{ID: 4, DependsOn: []uint16{3}},
+	}}
+	_, err := s.DependencyOrder()
+	expected := "steps have a cyclic dependency: 2 -> 4 -> 3 -> 2"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %v, Found: %v", expected, err)
+	}
+
+	s.Steps[1].DependsOn = []uint16{1}
+	order, err := s.DependencyOrder()
+	if err != nil {
+		t.Fatalf("TestScenarioDependencyCycle error occurred %v", err)
+	}
+	if !reflect.DeepEqual(order, []int{0, 1, 2, 3}) {
+		t.Errorf("Expected %v, Found: %v", []int{0, 1, 2, 3}, order)
+	}
+}
+
+func TestScenarioCleanup(t *testing.T) {
+	t.Parallel()
+
+	path := "$.id"
+	create := ScenarioStep{ID: 1, Method: http.MethodPost, URL: "https://test.com/items",
+		EnvsToCapture: []EnvCaptureConf{{Name: "ID", From: Body, JsonPath: &path}}}
+	update := ScenarioStep{ID: 2, Method: http.MethodPut, URL: "https://test.com/items/{{ID}}", DependsOn: []uint16{1}}
+	cleanup := func(id uint16, url string, deps ...uint16) ScenarioStep {
+		return ScenarioStep{ID: id, Method: http.MethodDelete, URL: url, DependsOn: deps}
+	}
+
+	tests := []struct {
+		name      string
+		steps     []ScenarioStep
+		cleanup   []ScenarioStep
+		shouldErr bool
+	}{
+		{"Valid", []ScenarioStep{create}, []ScenarioStep{cleanup(2, "https://test.com/items/{{ID}}")}, false},
+		{"CaptureOfDependencyGraph", []ScenarioStep{create, update},
+			[]ScenarioStep{cleanup(3, "https://test.com/items/{{ID}}")}, false},
+		{"UndefinedEnv", []ScenarioStep{create}, []ScenarioStep{cleanup(2, "https://test.com/items/{{NAME}}")}, true},
+		{"DuplicateID", []ScenarioStep{create}, []ScenarioStep{cleanup(1, "https://test.com/items/{{ID}}")}, true},
+		{"DependsOn", []ScenarioStep{create}, []ScenarioStep{cleanup(2, "https://test.com/items/{{ID}}", 1)}, true},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+			s := Scenario{Steps: tf.steps, Cleanup: tf.cleanup}
+
+			err := s.validate()
+			if tf.shouldErr && err == nil {
+				t.Errorf("Should be errored")
+			}
+			if !tf.shouldErr && err != nil {
+				t.Errorf("Error occurred %v", err)
+			}
+		})
+	}
+
+	// cleanup steps are kept by the tags
+	s := Scenario{Steps: []ScenarioStep{create}, Cleanup: []ScenarioStep{cleanup(2, "https://test.com")}}
+	if filtered := s.FilterTags([]string{"smoke"}); len(filtered.Cleanup) != 1 || len(filtered.AllSteps()) != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, len(filtered.Cleanup))
+	}
+}
+
+func TestScenarioSetupTeardown(t *testing.T) {
+	t.Parallel()
+
+	path := "$.token"
+	login := ScenarioStep{ID: 1, Method: http.MethodPost, URL: "https://test.com/login",
+		EnvsToCapture: []EnvCaptureConf{{Name: "TOKEN", From: Body, JsonPath: &path}}}
+	list := ScenarioStep{ID: 2, Method: http.MethodGet, URL: "https://test.com/items",
+		Headers: map[string]string{"Authorization": "{{TOKEN}}"}}
+	step := func(id uint16, url string, deps ...uint16) ScenarioStep {
+		return ScenarioStep{ID: id, Method: http.MethodDelete, URL: url, DependsOn: deps}
+	}
+
+	tests := []struct {
+		name      string
+		setup     []ScenarioStep
+		teardown  []ScenarioStep
+		shouldErr bool
+	}{
+		{"Valid", []ScenarioStep{login}, []ScenarioStep{step(3, "https://test.com/sessions/{{TOKEN}}")}, false},
+		{"CaptureOfPreviousSetup", []ScenarioStep{login, step(3, "https://test.com/sessions/{{TOKEN}}")}, nil, false},
+		{"UndefinedInSteps", nil, nil, true},
+		{"VirtualUserInSetup", []ScenarioStep{login, step(3, "https://test.com/{{"+VirtualUserIDEnv+"}}")},
+			nil, true},
+		{"GlobalEnv", []ScenarioStep{login, step(3, "https://test.com/{{HOST}}")}, nil, false},
+		{"DuplicateID", []ScenarioStep{login}, []ScenarioStep{step(2, "https://test.com")}, true},
+		{"DependsOn", []ScenarioStep{login, step(3, "https://test.com", 1)}, nil, true},
+		{"DependsOnInTeardown", []ScenarioStep{login}, []ScenarioStep{step(3, "https://test.com", 1)}, true},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+			s := Scenario{Steps: []ScenarioStep{list}, Setup: tf.setup, Teardown: tf.teardown,
+				Envs: map[string]interface{}{"HOST": "test.com"}}
+
+			err := s.validate()
+			if tf.shouldErr && err == nil {
+				t.Errorf("Should be errored")
+			}
+			if !tf.shouldErr && err != nil {
+				t.Errorf("Error occurred %v", err)
+			}
+		})
+	}
+
+	// errors of the setup and the teardown steps follow the ones of the cleanup steps
+	s := Scenario{Steps: []ScenarioStep{list}, Cleanup: []ScenarioStep{step(3, "https://test.com")},
+		Setup: []ScenarioStep{login, step(4, "https://test.com/{{NAME}}")}, Teardown: []ScenarioStep{step(1, "")}}
+	errs, err := s.ValidateSteps()
+	if err != nil || len(errs) != 2 || errs[3] == nil || errs[4] == nil {
+		t.Errorf("Expected errors of the steps %v, Found: %v %v", []int{3, 4}, errs, err)
+	}
+}
+
+func TestScenarioParallel(t *testing.T) {
+	t.Parallel()
+
+	step := func(id uint16, parallel int) ScenarioStep {
+		return ScenarioStep{ID: id, Method: http.MethodGet, URL: "https://test.com", Parallel: parallel}
+	}
+
+	tests := []struct {
+		name      string
+		scenario  Scenario
+		shouldErr bool
+	}{
+		{"Valid", Scenario{Steps: []ScenarioStep{step(1, 3), step(2, 0)}}, false},
+		{"Negative", Scenario{Steps: []ScenarioStep{step(1, -1)}}, true},
+		{"Cleanup", Scenario{Steps: []ScenarioStep{step(1, 0)}, Cleanup: []ScenarioStep{step(2, 2)}}, false},
+		{"Setup", Scenario{Steps: []ScenarioStep{step(1, 0)}, Setup: []ScenarioStep{step(2, 2)}}, true},
+		{"Teardown", Scenario{Steps: []ScenarioStep{step(1, 0)}, Teardown: []ScenarioStep{step(2, 2)}}, true},
+		{"OneInSetup", Scenario{Steps: []ScenarioStep{step(1, 0)}, Setup: []ScenarioStep{step(2, 1)}}, false},
+	}
+
+	for _, test := range tests {
+		tf := test
+		t.Run(tf.name, func(t *testing.T) {
+			t.Parallel()
+			err := tf.scenario.validate()
+			if tf.shouldErr && err == nil {
+				t.Errorf("Should be errored")
+			}
+			if !tf.shouldErr && err != nil {
+				t.Errorf("Error occurred %v", err)
+			}
+		})
+	}
+}
+
+func TestScenarioFilterTagsDependencies(t *testing.T) {
+	t.Parallel()
+
+	s := Scenario{
+		Steps: []ScenarioStep{
+			{ID: 1, Tags: []string{"checkout"}},
+			{ID: 2},
+			{ID: 3, DependsOn: []uint16{2}},
+			{ID: 4, Tags: []string{"checkout"}, DependsOn: []uint16{3, 1}},
+		},
+	}
+
+	filtered := s.FilterTags([]string{"checkout"})
+	if deps := filtered.Steps[1].DependsOn; !reflect.DeepEqual(deps, []uint16{1}) {
+		t.Errorf("Expected %v, Found: %v", []uint16{1}, deps)
+	}
+	if !reflect.DeepEqual(s.Steps[3].DependsOn, []uint16{3, 1}) {
+		t.Errorf("Expected the original scenario unchanged, Found: %v", s.Steps[3].DependsOn)
+	}
+}
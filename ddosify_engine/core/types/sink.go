@@ -0,0 +1,66 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Policies of a ResultSinkConf when its buffer is full
+	SinkPolicyDrop  = "drop"
+	SinkPolicyBlock = "block"
+
+	DefaultSinkBufferSize = 4096
+)
+
+// ResultSink receives the result of each request, like a file or a message queue. Write is called from a single
+// goroutine of the sink, so implementations don't need to be safe for concurrent use. Close is called once after
+// the last Write, when the test is done.
+type ResultSink interface {
+	Write(r *ScenarioStepResult) error
+	Close() error
+}
+
+// ResultSinkConf is a ResultSink of the Hammer with its buffer of the results not written yet.
+type ResultSinkConf struct {
+	Sink ResultSink
+
+	// Number of the results buffered for the sink, DefaultSinkBufferSize if zero.
+	BufferSize int
+
+	// SinkPolicyDrop drops and counts the results while the buffer is full, so a slow sink doesn't slow down the
+	// load. SinkPolicyBlock waits for the sink instead, so the sink gets all the results. Drop if empty.
+	Policy string
+}
+
+func (c ResultSinkConf) validate() error {
+	if c.Sink == nil {
+		return fmt.Errorf("result sink should be given")
+	}
+	if c.BufferSize < 0 {
+		return fmt.Errorf("result sink buffer size should be greater than or equal to 0")
+	}
+	if p := strings.ToLower(c.Policy); p != "" && p != SinkPolicyDrop && p != SinkPolicyBlock {
+		return fmt.Errorf("unsupported result sink policy %s, should be drop|block", c.Policy)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported metrics of the SLA checks other than the stop metrics
+const (
+	SLAMetricThroughput = "throughput"
+)
+
+var slaCheckRegexp = regexp.MustCompile(`^\s*(\w+)\s*(>=|<=|>|<)\s*(\S+?)\s*(rps)?\s*$`)
+
+// SLACheck is an absolute target that the result of the test must meet, evaluated once at the end of the test.
+// Ex: "p99 < 800ms", "error_rate <= 1%", "throughput >= 1000rps"
+type SLACheck struct {
+	Expr   string
+	Metric string
+	Op     string
+
+	// Ratio between 0 and 1 for error_rate, seconds for the duration metrics, requests per second for throughput.
+	Threshold float64
+}
+
+// ParseSLACheck parses the SLA check expression in "<metric> <op> <threshold>" format.
+func ParseSLACheck(expr string) (SLACheck, error) {
+	m := slaCheckRegexp.FindStringSubmatch(expr)
+	if m == nil {
+		return SLACheck{}, fmt.Errorf("sla check is not valid: %q, expected format is \"<metric> <op> <threshold>\"", expr)
+	}
+
+	c := SLACheck{
+		Expr:   strings.TrimSpace(expr),
+		Metric: strings.ToLower(m[1]),
+		Op:     m[2],
+	}
+	if m[4] != "" && c.Metric != SLAMetricThroughput {
+		return SLACheck{}, fmt.Errorf("rps threshold of the sla check %q is only supported by %s", expr, SLAMetricThroughput)
+	}
+
+	switch c.Metric {
+	case StopMetricErrorRate:
+		perc := strings.HasSuffix(m[3], "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(m[3], "%"), 64)
+		if perc {
+			v /= 100
+		}
+		if err != nil || v < 0 || v > 1 {
+			return SLACheck{}, fmt.Errorf("error rate of the sla check %q should be a percentage "+
+				"like 1%% or a ratio between 0 and 1", expr)
+		}
+		c.Threshold = v
+	case StopMetricAvg, StopMetricP50, StopMetricP90, StopMetricP95, StopMetricP99:
+		d, err := time.ParseDuration(m[3])
+		if err != nil || d < 0 {
+			return SLACheck{}, fmt.Errorf("threshold of the sla check %q should be a duration like 800ms", expr)
+		}
+		c.Threshold = d.Seconds()
+	case SLAMetricThroughput:
+		v, err := strconv.ParseFloat(m[3], 64)
+		if err != nil || v < 0 {
+			return SLACheck{}, fmt.Errorf("throughput of the sla check %q should be requests per second like 1000rps", expr)
+		}
+		c.Threshold = v
+	default:
+		return SLACheck{}, fmt.Errorf("unsupported metric of the sla check %q, supported metrics are %v", expr,
+			[]string{StopMetricErrorRate, StopMetricAvg, StopMetricP50, StopMetricP90, StopMetricP95, StopMetricP99,
+				SLAMetricThroughput})
+	}
+
+	return c, nil
+}
+
+// Passed returns true if the value of the metric meets the threshold.
+func (c SLACheck) Passed(value float64) bool {
+	return compare(c.Op, value, c.Threshold)
+}
+
+// Format returns the human readable form of the metric value.
+func (c SLACheck) Format(value float64) string {
+	switch c.Metric {
+	case StopMetricErrorRate:
+		return strconv.FormatFloat(value*100, 'f', 2, 64) + "%"
+	case SLAMetricThroughput:
+		return strconv.FormatFloat(value, 'f', 2, 64) + " rps"
+	}
+	return time.Duration(value * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+// ParseSLAChecks parses the given SLA check expressions.
+func ParseSLAChecks(exprs []string) ([]SLACheck, error) {
+	checks := make([]SLACheck, 0, len(exprs))
+	for _, expr := range exprs {
+		c, err := ParseSLACheck(expr)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	return checks, nil
+}
@@ -0,0 +1,93 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package types
+
+import (
+	"testing"
+)
+
+func TestParseSLACheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr     string
+		expected SLACheck
+	}{
+		{"p99 < 800ms", SLACheck{Metric: StopMetricP99, Op: "<", Threshold: 0.8}},
+		{"error_rate <= 1%", SLACheck{Metric: StopMetricErrorRate, Op: "<=", Threshold: 0.01}},
+		{"throughput >= 1000rps", SLACheck{Metric: SLAMetricThroughput, Op: ">=", Threshold: 1000}},
+		{" Throughput > 12.5 rps ", SLACheck{Metric: SLAMetricThroughput, Op: ">", Threshold: 12.5}},
+		{"avg<2s", SLACheck{Metric: StopMetricAvg, Op: "<", Threshold: 2}},
+	}
+
+	for _, test := range tests {
+		c, err := ParseSLACheck(test.expr)
+		if err != nil {
+			t.Errorf("%s error occurred: %v", test.expr, err)
+			continue
+		}
+		test.expected.Expr = c.Expr
+		if c != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.expr, test.expected, c)
+		}
+	}
+}
+
+func TestParseSLACheckInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"",
+		"p99 800ms",
+		"p99 < 800",
+		"p99 < 800msrps",
+		"error_rate < 150%",
+		"throughput > -1",
+		"throughput > fast",
+		"p99 < 1s over 10s",
+		"rps > 1000",
+	}
+
+	for _, test := range tests {
+		if _, err := ParseSLACheck(test); err == nil {
+			t.Errorf("%q Expected %v, Found: %v", test, "error", err)
+		}
+	}
+}
+
+func TestSLACheckPassed(t *testing.T) {
+	t.Parallel()
+
+	c, _ := ParseSLACheck("throughput >= 1000rps")
+	if !c.Passed(1000) || c.Passed(999.9) {
+		t.Errorf("Expected %v, Found: %v", "passed only at or above 1000 rps", c)
+	}
+	if f := c.Format(999.9); f != "999.90 rps" {
+		t.Errorf("Expected %v, Found: %v", "999.90 rps", f)
+	}
+
+	c, _ = ParseSLACheck("error_rate < 1%")
+	if !c.Passed(0.005) || c.Passed(0.01) {
+		t.Errorf("Expected %v, Found: %v", "passed only below 1%", c)
+	}
+	if f := c.Format(0.0125); f != "1.25%" {
+		t.Errorf("Expected %v, Found: %v", "1.25%", f)
+	}
+}
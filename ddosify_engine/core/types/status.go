@@ -0,0 +1,104 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusRange is an inclusive range of the status codes, a single status code if Min is equal to Max.
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+func (r StatusRange) String() string {
+	switch {
+	case r.Min == r.Max:
+		return strconv.Itoa(r.Min)
+	case r.Min%100 == 0 && r.Max == r.Min+99:
+		return fmt.Sprintf("%dxx", r.Min/100)
+	default:
+		return fmt.Sprintf("%d-%d", r.Min, r.Max)
+	}
+}
+
+// ExpectedStatus is the list of the status codes a step expects, responses with the other status codes fail.
+type ExpectedStatus []StatusRange
+
+// ParseExpectedStatus parses the status code expressions like "401", "2xx" or "200-204".
+func ParseExpectedStatus(exprs []string) (ExpectedStatus, error) {
+	var es ExpectedStatus
+	for _, expr := range exprs {
+		r, err := parseStatusRange(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, r)
+	}
+	return es, nil
+}
+
+func parseStatusRange(expr string) (StatusRange, error) {
+	var r StatusRange
+	var err error
+	lower := strings.ToLower(expr)
+	switch {
+	case len(lower) == 3 && strings.HasSuffix(lower, "xx"):
+		var class int
+		if class, err = strconv.Atoi(lower[:1]); err == nil {
+			r = StatusRange{Min: class * 100, Max: class*100 + 99}
+		}
+	case strings.Contains(expr, "-"):
+		bounds := strings.SplitN(expr, "-", 2)
+		if r.Min, err = strconv.Atoi(strings.TrimSpace(bounds[0])); err == nil {
+			r.Max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+		}
+	default:
+		if r.Min, err = strconv.Atoi(expr); err == nil {
+			r.Max = r.Min
+		}
+	}
+	if err != nil || r.Min < 100 || r.Max > 599 || r.Min > r.Max {
+		return StatusRange{}, fmt.Errorf("invalid expected status: %q", expr)
+	}
+	return r, nil
+}
+
+// Match returns true if the status code is in one of the expected ranges.
+func (es ExpectedStatus) Match(code int) bool {
+	for _, r := range es {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+func (es ExpectedStatus) String() string {
+	s := make([]string, len(es))
+	for i, r := range es {
+		s[i] = r.String()
+	}
+	return strings.Join(s, ", ")
+}
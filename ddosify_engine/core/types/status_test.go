@@ -0,0 +1,59 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpectedStatus(t *testing.T) {
+	t.Parallel()
+
+	es, err := ParseExpectedStatus([]string{"401", "2xx", "200-204", " 5XX "})
+	if err != nil {
+		t.Fatalf("TestParseExpectedStatus error occurred %v", err)
+	}
+	expected := ExpectedStatus{{Min: 401, Max: 401}, {Min: 200, Max: 299}, {Min: 200, Max: 204}, {Min: 500, Max: 599}}
+	if !reflect.DeepEqual(es, expected) {
+		t.Errorf("Expected %v, Found: %v", expected, es)
+	}
+	if es.String() != "401, 2xx, 200-204, 5xx" {
+		t.Errorf("Expected %v, Found: %v", "401, 2xx, 200-204, 5xx", es.String())
+	}
+
+	for _, code := range []int{401, 250, 503} {
+		if !es.Match(code) {
+			t.Errorf("Expected %v to match %v", code, es)
+		}
+	}
+	for _, code := range []int{302, 404} {
+		if es.Match(code) {
+			t.Errorf("Expected %v not to match %v", code, es)
+		}
+	}
+
+	for _, expr := range []string{"", "abc", "6xx", "99", "300-200", "200-", "1000"} {
+		if _, err := ParseExpectedStatus([]string{expr}); err == nil {
+			t.Errorf("Expected error for %q", expr)
+		}
+	}
+}
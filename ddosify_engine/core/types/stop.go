@@ -0,0 +1,145 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StopMetricErrorRate = "error_rate"
+	StopMetricAvg       = "avg"
+	StopMetricP50       = "p50"
+	StopMetricP90       = "p90"
+	StopMetricP95       = "p95"
+	StopMetricP99       = "p99"
+
+	// Window of the stop conditions given without "over <duration>"
+	DefaultStopWindow = 10 * time.Second
+)
+
+var stopConditionRegexp = regexp.MustCompile(`^\s*(\w+)\s*(>=|<=|>|<)\s*(\S+)(?:\s+over\s+(\S+))?\s*$`)
+
+// StopCondition aborts the test when its metric, calculated on the iterations completed in the last window,
+// crosses the threshold. Ex: "error_rate > 50% over 10s", "p99 > 5s"
+type StopCondition struct {
+	Expr   string
+	Metric string
+	Op     string
+
+	// Ratio between 0 and 1 for error_rate, seconds for the duration metrics.
+	Threshold float64
+
+	Window time.Duration
+}
+
+// ParseStopCondition parses the stop condition expression in "<metric> <op> <threshold> [over <window>]" format.
+func ParseStopCondition(expr string) (StopCondition, error) {
+	m := stopConditionRegexp.FindStringSubmatch(expr)
+	if m == nil {
+		return StopCondition{}, fmt.Errorf("stop condition is not valid: %q, expected format is "+
+			"\"<metric> <op> <threshold> [over <window>]\"", expr)
+	}
+
+	c := StopCondition{
+		Expr:   strings.TrimSpace(expr),
+		Metric: strings.ToLower(m[1]),
+		Op:     m[2],
+		Window: DefaultStopWindow,
+	}
+
+	switch c.Metric {
+	case StopMetricErrorRate:
+		perc := strings.HasSuffix(m[3], "%")
+		v, err := strconv.ParseFloat(strings.TrimSuffix(m[3], "%"), 64)
+		if perc {
+			v /= 100
+		}
+		if err != nil || v < 0 || v > 1 {
+			return StopCondition{}, fmt.Errorf("error rate of the stop condition %q should be a percentage "+
+				"like 50%% or a ratio between 0 and 1", expr)
+		}
+		c.Threshold = v
+	case StopMetricAvg, StopMetricP50, StopMetricP90, StopMetricP95, StopMetricP99:
+		d, err := time.ParseDuration(m[3])
+		if err != nil || d < 0 {
+			return StopCondition{}, fmt.Errorf("threshold of the stop condition %q should be a duration like 5s", expr)
+		}
+		c.Threshold = d.Seconds()
+	default:
+		return StopCondition{}, fmt.Errorf("unsupported metric of the stop condition %q, supported metrics are %v",
+			expr, []string{StopMetricErrorRate, StopMetricAvg, StopMetricP50, StopMetricP90, StopMetricP95, StopMetricP99})
+	}
+
+	if m[4] != "" {
+		w, err := time.ParseDuration(m[4])
+		if err != nil || w <= 0 {
+			return StopCondition{}, fmt.Errorf("window of the stop condition %q should be a positive duration like 10s", expr)
+		}
+		c.Window = w
+	}
+
+	return c, nil
+}
+
+// Met returns true if the value of the metric crosses the threshold.
+func (c StopCondition) Met(value float64) bool {
+	return compare(c.Op, value, c.Threshold)
+}
+
+func compare(op string, value, threshold float64) bool {
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
+// Format returns the human readable form of the metric value.
+func (c StopCondition) Format(value float64) string {
+	if c.Metric == StopMetricErrorRate {
+		return strconv.FormatFloat(value*100, 'f', 1, 64) + "%"
+	}
+	return time.Duration(value * float64(time.Second)).Round(time.Millisecond).String()
+}
+
+// ParseStopConditions parses the given stop condition expressions.
+func ParseStopConditions(exprs []string) ([]StopCondition, error) {
+	conditions := make([]StopCondition, 0, len(exprs))
+	for _, expr := range exprs {
+		c, err := ParseStopCondition(expr)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
@@ -0,0 +1,93 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStopCondition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr     string
+		expected StopCondition
+	}{
+		{"error_rate > 50% over 10s", StopCondition{Metric: StopMetricErrorRate, Op: ">", Threshold: 0.5, Window: 10 * time.Second}},
+		{"error_rate >= 0.25", StopCondition{Metric: StopMetricErrorRate, Op: ">=", Threshold: 0.25, Window: DefaultStopWindow}},
+		{"p99 > 5s", StopCondition{Metric: StopMetricP99, Op: ">", Threshold: 5, Window: DefaultStopWindow}},
+		{" AVG<=250ms over 1m ", StopCondition{Metric: StopMetricAvg, Op: "<=", Threshold: 0.25, Window: time.Minute}},
+	}
+
+	for _, test := range tests {
+		c, err := ParseStopCondition(test.expr)
+		if err != nil {
+			t.Errorf("%s error occurred: %v", test.expr, err)
+			continue
+		}
+		test.expected.Expr = c.Expr
+		if c != test.expected {
+			t.Errorf("%s Expected %v, Found: %v", test.expr, test.expected, c)
+		}
+	}
+}
+
+func TestParseStopConditionInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"",
+		"error_rate 50%",
+		"error_rate > 150%",
+		"error_rate > 2",
+		"p99 > 5",
+		"p98 > 5s",
+		"p99 > 5s over 0s",
+		"p99 > 5s during 10s",
+	}
+
+	for _, test := range tests {
+		if _, err := ParseStopCondition(test); err == nil {
+			t.Errorf("%q Expected %v, Found: %v", test, "error", err)
+		}
+	}
+}
+
+func TestStopConditionMet(t *testing.T) {
+	t.Parallel()
+
+	c, _ := ParseStopCondition("error_rate > 50%")
+	if c.Met(0.5) || !c.Met(0.51) {
+		t.Errorf("Expected %v, Found: %v", "met only above 50%", c)
+	}
+	if f := c.Format(0.734); f != "73.4%" {
+		t.Errorf("Expected %v, Found: %v", "73.4%", f)
+	}
+
+	c, _ = ParseStopCondition("p99 < 100ms")
+	if !c.Met(0.05) || c.Met(0.1) {
+		t.Errorf("Expected %v, Found: %v", "met only below 100ms", c)
+	}
+	if f := c.Format(5.2); f != "5.2s" {
+		t.Errorf("Expected %v, Found: %v", "5.2s", f)
+	}
+}
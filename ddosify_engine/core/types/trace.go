@@ -0,0 +1,42 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"encoding/hex"
+
+	"github.com/google/uuid"
+)
+
+// TraceparentHeader is the W3C trace context header, see https://www.w3.org/TR/trace-context.
+const TraceparentHeader = "traceparent"
+
+// TraceIDs returns the hex encoded trace and span ids of the request with the given RequestID. The trace id is
+// the RequestID and the span id is its last 8 bytes, so the server side spans of a request can be found by its id.
+func TraceIDs(requestID uuid.UUID) (traceID, spanID string) {
+	return hex.EncodeToString(requestID[:]), hex.EncodeToString(requestID[8:])
+}
+
+// Traceparent returns the traceparent header of the sampled request with the given RequestID.
+func Traceparent(requestID uuid.UUID) string {
+	traceID, spanID := TraceIDs(requestID)
+	return "00-" + traceID + "-" + spanID + "-01"
+}
@@ -0,0 +1,92 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+const (
+	// Picks of the UserAgentConf values
+	UserAgentPerRequest = "request"
+	UserAgentPerUser    = "user"
+
+	UserAgentOrderRoundRobin = "round-robin"
+	UserAgentOrderRandom     = "random"
+)
+
+// BuiltinUserAgents are the User-Agent headers of the common desktop and mobile browsers.
+var BuiltinUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+	"Mozilla/5.0 (Linux; Android 14; SM-S918B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+}
+
+// UserAgentConf is the list of the User-Agent headers sent by the HTTP steps that don't set their own one, so the
+// traffic looks like a mix of clients instead of a single one.
+type UserAgentConf struct {
+	// User-Agent values, appended to the BuiltinUserAgents if Builtin is set.
+	Values  []string
+	Builtin bool
+
+	// "round-robin" or "random", round-robin if empty.
+	Order string
+
+	// UserAgentPerRequest picks a value for each request, UserAgentPerUser keeps the value of a virtual user in all
+	// of its requests. Per request if empty.
+	Per string
+}
+
+// All returns the values to rotate, the built-in ones first.
+func (c UserAgentConf) All() []string {
+	if !c.Builtin {
+		return c.Values
+	}
+	all := make([]string, 0, len(BuiltinUserAgents)+len(c.Values))
+	all = append(all, BuiltinUserAgents...)
+	return append(all, c.Values...)
+}
+
+func (c UserAgentConf) validate() error {
+	if o := strings.ToLower(c.Order); o != "" && o != UserAgentOrderRoundRobin && o != UserAgentOrderRandom {
+		return fmt.Errorf("unsupported user agents order %s, should be round-robin|random", c.Order)
+	}
+	if p := strings.ToLower(c.Per); p != "" && p != UserAgentPerRequest && p != UserAgentPerUser {
+		return fmt.Errorf("unsupported user agents per %s, should be request|user", c.Per)
+	}
+	for _, v := range c.Values {
+		if strings.TrimSpace(v) == "" || !httpguts.ValidHeaderFieldValue(v) {
+			return fmt.Errorf("user agent is not a valid header value: %q", v)
+		}
+	}
+	return nil
+}
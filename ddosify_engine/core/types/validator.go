@@ -0,0 +1,84 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package types
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Validator is the ETag and Last-Modified validators of a cached response.
+type Validator struct {
+	ETag         string
+	LastModified string
+}
+
+// Validators keeps the validators of a virtual user by the request urls. Safe for concurrent use.
+type Validators struct {
+	mu    sync.Mutex
+	byURL map[string]Validator
+}
+
+// Get returns the validator of the url, false if the url has no validator.
+func (v *Validators) Get(url string) (Validator, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	val, ok := v.byURL[url]
+	return val, ok
+}
+
+// Set stores the validator of the url.
+func (v *Validators) Set(url string, val Validator) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.byURL[url] = val
+}
+
+// ValidatorStore keeps the Validators of the virtual users, so that the HTTP steps revalidate the responses
+// by the conditional requests like a client with a cache. Users are identified by their clients, nil is the
+// single user of the ddosify engine mode. Safe for concurrent use.
+type ValidatorStore struct {
+	mu    sync.Mutex
+	users map[*http.Client]*Validators
+}
+
+func NewValidatorStore() *ValidatorStore {
+	return &ValidatorStore{users: make(map[*http.Client]*Validators)}
+}
+
+// Of returns the Validators of the user of the client, creates them on the first call.
+func (s *ValidatorStore) Of(client *http.Client) *Validators {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.users[client]
+	if !ok {
+		v = &Validators{byURL: make(map[string]Validator)}
+		s.users[client] = v
+	}
+	return v
+}
+
+// Forget drops the Validators of the user of the client, like when the client is closed or passed to a new user.
+func (s *ValidatorStore) Forget(client *http.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, client)
+}
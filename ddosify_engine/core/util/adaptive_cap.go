@@ -0,0 +1,97 @@
+package util
+
+import "time"
+
+const (
+	// DefaultAdaptiveGrowAfter is the number of the Get() calls in a row missing an idle item that grow the capacity
+	DefaultAdaptiveGrowAfter = 3
+	// DefaultAdaptiveCoolDown is the time without misses that shrinks the capacity one step
+	DefaultAdaptiveCoolDown = 10 * time.Second
+)
+
+// AdaptiveCap sizes the idle items kept by a Pool by the demand, between Min and the capacity of the Items. The idle
+// capacity starts at Min. It is doubled once GrowAfter Get() calls in a row find no idle item, and after CoolDown
+// without a miss it shrinks by a quarter of its distance to Min, closing the idle items above it. It grows fast and
+// shrinks slowly after a quiet period, so it doesn't thrash under a bursty load. Guarded by the mutex of the pool.
+type AdaptiveCap struct {
+	Min       int
+	GrowAfter int           // DefaultAdaptiveGrowAfter if zero
+	CoolDown  time.Duration // DefaultAdaptiveCoolDown if zero
+
+	// returns the current time, time.Now if nil
+	now func() time.Time
+
+	started bool
+	cur     int
+	misses  int
+	// time of the last miss or resize, the cool-down starts from it
+	quietSince time.Time
+}
+
+// capacity returns the current idle capacity, limit is the capacity of the Items of the pool.
+func (a *AdaptiveCap) capacity(limit int) int {
+	if !a.started {
+		a.started = true
+		a.cur = a.Min
+		a.quietSince = a.clock()
+	}
+	if a.cur > limit {
+		a.cur = limit
+	}
+	if a.cur < 0 {
+		a.cur = 0
+	}
+	return a.cur
+}
+
+// record counts a Get() call, missed if it found no idle item. Returns the new idle capacity.
+func (a *AdaptiveCap) record(missed bool, limit int) int {
+	c := a.capacity(limit)
+	if !missed {
+		a.misses = 0
+		return c
+	}
+
+	a.quietSince = a.clock()
+	a.misses++
+	growAfter := a.GrowAfter
+	if growAfter <= 0 {
+		growAfter = DefaultAdaptiveGrowAfter
+	}
+	if a.misses >= growAfter {
+		a.misses = 0
+		a.cur = c * 2
+		if a.cur == 0 {
+			a.cur = 1
+		}
+		if a.cur > limit {
+			a.cur = limit
+		}
+	}
+	return a.cur
+}
+
+// shrink shrinks the idle capacity one step if the cool-down passed since the last miss or resize.
+// Returns the new idle capacity.
+func (a *AdaptiveCap) shrink(limit int) int {
+	c := a.capacity(limit)
+	coolDown := a.CoolDown
+	if coolDown <= 0 {
+		coolDown = DefaultAdaptiveCoolDown
+	}
+	now := a.clock()
+	if c <= a.Min || now.Sub(a.quietSince) < coolDown {
+		return c
+	}
+
+	a.cur = c - (c-a.Min+3)/4
+	a.quietSince = now
+	return a.cur
+}
+
+func (a *AdaptiveCap) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
@@ -20,12 +20,8 @@ func NewBufferPool(initialCap, maxCap int, factory BufferFactoryMethod, close Bu
 		Close:   close,
 	}
 
-	// create initial clients, if something goes wrong,
-	// just close the pool error out.
-	for i := 0; i < initialCap; i++ {
-		client := pool.Factory()
-		pool.Items <- client
-	}
+	// create initial clients
+	pool.Fill(initialCap)
 
 	return pool, nil
 }
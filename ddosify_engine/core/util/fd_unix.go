@@ -0,0 +1,58 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package util
+
+import (
+	"os"
+	"syscall"
+)
+
+// fdDirs list the open file descriptors of the process, /dev/fd is used if procfs is not mounted.
+var fdDirs = [...]string{"/proc/self/fd", "/dev/fd"}
+
+// OpenFDs returns the number of the open file descriptors of the process, ok is false if they can't be listed.
+func OpenFDs() (n int, ok bool) {
+	for _, dir := range fdDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		// the descriptor of the listed dir is included
+		return len(entries) - 1, true
+	}
+	return 0, false
+}
+
+// FDLimit returns the soft limit of the open file descriptors of the process, ok is false if it is unlimited.
+func FDLimit() (limit uint64, ok bool) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0, false
+	}
+	limit = uint64(rl.Cur)
+	if limit == 0 || limit >= 1<<62 { // RLIM_INFINITY
+		return 0, false
+	}
+	return limit, true
+}
@@ -1,51 +1,575 @@
 package util
 
-type Pool[T any] struct {
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pool keeps the idle items for reuse. The items are comparable, like the pointers and the connections, so the pool
+// can tell the items created before a Reset() apart.
+type Pool[T comparable] struct {
 	Items    chan T
 	Factory  func() T
 	Close    func(T)
 	AfterPut func(T)
+
+	// Valid is called on idle items pulled from the pool in Get(). If it returns false,
+	// the item is closed and the next one is tried. Optional.
+	Valid func(T) bool
+
+	// HealthCheck is a user provided liveness probe, applied like Valid after it. Optional.
+	HealthCheck func(T) bool
+
+	// SingleUse closes the items in Put() instead of putting them back, so each item is used once.
+	SingleUse bool
+
+	// StickySlots is the number of the items that GetSticky() maps the keys onto. Capacity of Items if zero.
+	StickySlots int
+
+	// OnCapExceeded is called once, the first time Get() creates an item while the number of the live items already
+	// reached the capacity of Items. The capacity only limits the idle items kept by Put(), Get() never blocks, so
+	// the live items can outnumber it. GetContext enforces the capacity instead. Optional.
+	OnCapExceeded func(live, capacity int)
+
+	// Adaptive sizes the idle items kept by Put() by the demand, up to the capacity of Items. All the idle items
+	// up to the capacity of Items are kept if nil. Optional.
+	Adaptive *AdaptiveCap
+
+	// mu guards Factory, Items, closed, live, peakLive, capExceeded, freed, sticky, fresh and generation
+	mu     sync.Mutex
+	closed bool
+	// live is the number of items created by the pool and not closed yet, peakLive is its maximum
+	live     int
+	peakLive int
+
+	capExceeded bool
+	// signaled when a live item is closed, wakes up the GetContext calls waiting at the capacity
+	freed chan struct{}
+
+	// sticky items by slot, created on the first GetSticky() of their slots
+	sticky map[int]T
+
+	// items created since the last Reset(), nil if the pool is never reset. Put() closes the items missing in it.
+	fresh map[T]struct{}
+	// incremented by Reset(), the items created by the Factory of an older generation are not fresh
+	generation uint64
+
+	// counters for Stats(), updated atomically
+	inUse      int64
+	created    int64
+	reused     int64
+	closedFull int64
+	closedBad  int64
+	waits      int64
+	waitTime   int64 // in nanoseconds
 }
 
+// PoolStats is a point-in-time snapshot of the pool counters.
+type PoolStats struct {
+	Idle       int   // items waiting in the pool
+	InUse      int64 // items taken by Get() and not returned by Put() yet
+	Created    int64 // items created by the Factory during Get()
+	Reused     int64 // items served from the pool during Get()
+	ClosedFull int64 // items closed in Put() because the pool was full, or by the shrinking of the Adaptive capacity
+	ClosedBad  int64 // items closed by PutBad()
+	MaxCap     int   // maximum number of idle items the pool can hold
+	IdleCap    int   // number of idle items the pool currently keeps, MaxCap unless the capacity is Adaptive
+
+	Waits    int64         // GetContext() calls that waited for an item at the live limit
+	WaitTime time.Duration // total time spent by the GetContext() calls waiting for an item
+}
+
+// Get returns an idle item from the pool, or creates a new one via the Factory if there is none.
+// After the pool is closed by Done(), a freshly created item is returned.
 func (p *Pool[T]) Get() T {
-	var item T
-	select {
-	case item = <-p.Items:
-	default:
+	p.mu.Lock()
+	items, closed := p.Items, p.closed
+	p.mu.Unlock()
+
+	item, ok := p.takeIdle(items, closed)
+	p.adapt(!ok)
+	if !ok {
+		item = p.create()
+	}
+	atomic.AddInt64(&p.inUse, 1)
+	return item
+}
+
+// takeIdle pulls a valid idle item from the pool without blocking. Invalid items are closed and skipped.
+func (p *Pool[T]) takeIdle(items chan T, closed bool) (item T, ok bool) {
+	if closed {
+		return
+	}
+	for {
+		select {
+		case item, ok = <-items:
+			if !ok { // closed by Done() in the meantime
+				return
+			}
+			if !p.usable(item) {
+				p.discard(item)
+				continue
+			}
+			atomic.AddInt64(&p.reused, 1)
+			return item, true
+		default:
+			return item, false
+		}
+	}
+}
+
+// GetContext is the blocking variant of Get. If there is no idle item in the pool and the number of live items
+// reached the capacity of the pool, it waits until an item is put back or closed. Returns ctx.Err() if ctx is done before that.
+// The waits are counted in the Stats.
+func (p *Pool[T]) GetContext(ctx context.Context) (T, error) {
+	var waitStart time.Time
+	defer func() {
+		if !waitStart.IsZero() {
+			atomic.AddInt64(&p.waits, 1)
+			atomic.AddInt64(&p.waitTime, int64(time.Since(waitStart)))
+		}
+	}()
+
+	for {
+		p.mu.Lock()
+		items, closed := p.Items, p.closed
+		if p.freed == nil && !closed {
+			p.freed = make(chan struct{}, cap(items)+1)
+		}
+		freed := p.freed
+		p.mu.Unlock()
+
+		if closed {
+			return p.Get(), nil
+		}
+
+		item, ok := p.takeIdle(items, closed)
+		p.adapt(!ok)
+		if ok {
+			atomic.AddInt64(&p.inUse, 1)
+			return item, nil
+		}
+
+		p.mu.Lock()
+		if p.live < cap(items) {
+			p.addLive()
+			factory, gen := p.Factory, p.generation
+			p.mu.Unlock()
+			atomic.AddInt64(&p.created, 1)
+			atomic.AddInt64(&p.inUse, 1)
+			return p.build(factory, gen), nil
+		}
+		p.mu.Unlock()
+
+		if waitStart.IsZero() {
+			waitStart = time.Now()
+		}
+		select {
+		case item, ok := <-items:
+			if !ok { // closed by Done() while waiting
+				return p.Get(), nil
+			}
+			if !p.usable(item) {
+				// a slot is freed, try again
+				p.discard(item)
+				continue
+			}
+			atomic.AddInt64(&p.reused, 1)
+			atomic.AddInt64(&p.inUse, 1)
+			return item, nil
+		case <-freed:
+			// an item is closed, a new one can be created
+			continue
+		case <-ctx.Done():
+			var item T
+			return item, ctx.Err()
+		}
+	}
+}
+
+// GetSticky returns the item of the sticky slot of the key, key % the sticky slots, so the same key always gets
+// the same item during the life of the pool. The keys are mapped by modulo, not by consistent hashing: the slots
+// are fixed for the life of the pool, so no key ever moves to another slot, and the dense keys like the virtual
+// user ids 0..n-1 get a slot each, where a hash would put some of them on the same slot and leave others unused.
+// Sticky items are shared by the keys mapped to the same slot, and by the concurrent callers of the same key, the
+// callers serialize their use of an item if it is not safe to share. They are not taken from or put back to the
+// idle items. Items are created via the Factory on the first use of their slots and closed by Done(). After the
+// pool is closed, a freshly created item is returned.
+func (p *Pool[T]) GetSticky(key int) T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		p.addLive()
+		atomic.AddInt64(&p.created, 1)
+		return p.Factory()
+	}
+
+	slot := p.stickySlot(key)
+	item, ok := p.sticky[slot]
+	if !ok {
+		p.addLive()
+		atomic.AddInt64(&p.created, 1)
 		item = p.Factory()
+		p.track(item, p.generation)
+		p.sticky[slot] = item
+	}
+	return item
+}
+
+// stickySlot returns the slot of the key, the keys are dense like the virtual user ids, so they are mapped to the
+// slots in turn. Should be called with mu held.
+func (p *Pool[T]) stickySlot(key int) int {
+	if p.sticky == nil {
+		p.sticky = make(map[int]T, p.stickySlots())
+	}
+	slot := key % p.stickySlots()
+	if slot < 0 {
+		slot += p.stickySlots()
+	}
+	return slot
+}
+
+// stickySlots returns the number of the sticky slots, StickySlots or the capacity of Items, at least 1.
+func (p *Pool[T]) stickySlots() int {
+	if p.StickySlots > 0 {
+		return p.StickySlots
+	}
+	if cap(p.Items) > 0 {
+		return cap(p.Items)
+	}
+	return 1
+}
+
+// Fill creates n items via the Factory and puts them into the pool.
+func (p *Pool[T]) Fill(n int) {
+	for i := 0; i < n; i++ {
+		p.mu.Lock()
+		p.addLive()
+		factory, gen := p.Factory, p.generation
+		p.mu.Unlock()
+		p.Items <- p.build(factory, gen)
+	}
+}
+
+// Warm calls warm on each idle item, at most parallel of them at a time, and puts them back into the pool.
+// It is for preparing the items before the pool is used, Get() doesn't see the items being warmed. The items put
+// by Put() or Fill() in the meantime are kept, the warmed items that don't fit anymore are closed, like the ones
+// warmed after Done().
+func (p *Pool[T]) Warm(parallel int, warm func(T)) {
+	p.mu.Lock()
+	items := make([]T, 0, len(p.Items))
+	for len(p.Items) > 0 {
+		items = append(items, <-p.Items)
+	}
+	p.mu.Unlock()
+
+	if parallel <= 0 {
+		parallel = 1
+	}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(item T) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			warm(item)
+		}(item)
+	}
+	wg.Wait()
+
+	var surplus []T
+	p.mu.Lock()
+	for _, item := range items {
+		if p.closed {
+			p.removeLive(item)
+			surplus = append(surplus, item)
+			continue
+		}
+		select {
+		case p.Items <- item:
+		default:
+			p.removeLive(item)
+			atomic.AddInt64(&p.closedFull, 1)
+			surplus = append(surplus, item)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, item := range surplus {
+		p.Close(item)
+	}
+}
+
+// adapt records a Get() call for the Adaptive capacity, and closes the idle items above the capacity if it shrinks.
+func (p *Pool[T]) adapt(missed bool) {
+	if p.Adaptive == nil {
+		return
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.Adaptive.record(missed, cap(p.Items))
+	capacity := p.Adaptive.shrink(cap(p.Items))
+	var surplus []T
+trim:
+	for len(p.Items) > capacity {
+		select {
+		case item := <-p.Items:
+			p.removeLive(item)
+			surplus = append(surplus, item)
+		default: // taken by a Get() in the meantime
+			break trim
+		}
+	}
+	p.mu.Unlock()
+
+	for _, item := range surplus {
+		atomic.AddInt64(&p.closedFull, 1)
+		p.Close(item)
+	}
+}
+
+// idleCap returns the number of the idle items that Put() keeps, should be called with mu held.
+func (p *Pool[T]) idleCap() int {
+	if p.Adaptive == nil {
+		return cap(p.Items)
+	}
+	return p.Adaptive.capacity(cap(p.Items))
+}
+
+func (p *Pool[T]) create() T {
+	p.mu.Lock()
+	p.addLive()
+	live, capacity := p.live, cap(p.Items)
+	exceeded := !p.capExceeded && !p.closed && live > capacity && p.OnCapExceeded != nil
+	if exceeded {
+		p.capExceeded = true
 	}
+	factory, gen := p.Factory, p.generation
+	p.mu.Unlock()
+
+	if exceeded {
+		p.OnCapExceeded(live, capacity)
+	}
+	atomic.AddInt64(&p.created, 1)
+	return p.build(factory, gen)
+}
+
+// build creates an item by the factory of the given generation, both read with mu held.
+func (p *Pool[T]) build(factory func() T, gen uint64) T {
+	item := factory()
+	p.mu.Lock()
+	p.track(item, gen)
+	p.mu.Unlock()
 	return item
 }
 
+// track records the item as fresh if the pool is not reset since its generation, should be called with mu held.
+func (p *Pool[T]) track(item T, gen uint64) {
+	if p.fresh != nil && gen == p.generation {
+		p.fresh[item] = struct{}{}
+	}
+}
+
+// stale returns true if the item is created before the last Reset(), should be called with mu held.
+func (p *Pool[T]) stale(item T) bool {
+	if p.fresh == nil {
+		return false
+	}
+	_, ok := p.fresh[item]
+	return !ok
+}
+
+// addLive counts a created item, should be called with mu held.
+func (p *Pool[T]) addLive() {
+	p.live++
+	if p.live > p.peakLive {
+		p.peakLive = p.live
+	}
+}
+
+// removeLive counts a closed item and wakes up a waiting GetContext, should be called with mu held.
+func (p *Pool[T]) removeLive(item T) {
+	p.live--
+	if p.fresh != nil {
+		delete(p.fresh, item)
+	}
+	select {
+	case p.freed <- struct{}{}:
+	default: // nil channel or enough wake-ups are pending
+	}
+}
+
+func (p *Pool[T]) usable(item T) bool {
+	if p.Valid != nil && !p.Valid(item) {
+		return false
+	}
+	if p.HealthCheck != nil && !p.HealthCheck(item) {
+		return false
+	}
+	return true
+}
+
+// discard closes an item that is pulled from the pool but not usable anymore.
+func (p *Pool[T]) discard(item T) {
+	p.mu.Lock()
+	p.removeLive(item)
+	p.mu.Unlock()
+	p.Close(item)
+}
+
 func (p *Pool[T]) Put(item T) error {
-	if p.Items == nil {
-		// pool is closed, close passed client
+	atomic.AddInt64(&p.inUse, -1)
+
+	p.mu.Lock()
+	if p.closed || p.SingleUse || p.stale(item) {
+		// pool is closed, items are not reused or the item is created before Reset(), close passed client
+		p.removeLive(item)
+		p.mu.Unlock()
 		p.Close(item)
 		return nil
 	}
 
+	if p.Adaptive != nil && len(p.Items) >= p.idleCap() {
+		// pool is at its adaptive capacity, close passed client
+		p.removeLive(item)
+		p.mu.Unlock()
+		atomic.AddInt64(&p.closedFull, 1)
+		p.Close(item)
+		return nil
+	}
+
+	// AfterPut runs before the item is back in the pool, so it is not handed out while it is being updated
+	if p.AfterPut != nil {
+		p.AfterPut(item)
+	}
+
 	// put the resource back into the pool. If the pool is full, this will
 	// block and the default case will be executed.
 	select {
 	case p.Items <- item:
-		if p.AfterPut != nil {
-			p.AfterPut(item)
-		}
+		p.mu.Unlock()
 		return nil
 	default:
 		// pool is full, close passed client
+		p.removeLive(item)
+		p.mu.Unlock()
+		atomic.AddInt64(&p.closedFull, 1)
 		p.Close(item)
 		return nil
 	}
 }
 
+// PutBad returns an item that is known to be broken, like a client whose last request failed with a connection error.
+// The item is closed instead of being put back, so it is not handed out again.
+func (p *Pool[T]) PutBad(item T) {
+	atomic.AddInt64(&p.inUse, -1)
+	atomic.AddInt64(&p.closedBad, 1)
+	p.discard(item)
+}
+
 func (p *Pool[T]) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return len(p.Items)
 }
 
+// Stats returns the live statistics of the pool. It is safe to call concurrently with Get() and Put().
+func (p *Pool[T]) Stats() PoolStats {
+	p.mu.Lock()
+	idle, maxCap, idleCap := len(p.Items), cap(p.Items), p.idleCap()
+	p.mu.Unlock()
+
+	return PoolStats{
+		Idle:       idle,
+		InUse:      atomic.LoadInt64(&p.inUse),
+		Created:    atomic.LoadInt64(&p.created),
+		Reused:     atomic.LoadInt64(&p.reused),
+		ClosedFull: atomic.LoadInt64(&p.closedFull),
+		ClosedBad:  atomic.LoadInt64(&p.closedBad),
+		MaxCap:     maxCap,
+		IdleCap:    idleCap,
+		Waits:      atomic.LoadInt64(&p.waits),
+		WaitTime:   time.Duration(atomic.LoadInt64(&p.waitTime)),
+	}
+}
+
+// PeakLive returns the maximum number of the items that are created and not closed at the same time, in use or idle.
+func (p *Pool[T]) PeakLive() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peakLive
+}
+
+// Reset closes all the idle and the sticky items, so the next Get() calls create fresh items via the Factory, like
+// after the target behind the connections is switched. If factory is not nil, it replaces the Factory before that.
+// Items in use are closed when they are put back instead of being pooled again. It is safe to call concurrently
+// with Get() and Put(), Reset does nothing after Done().
+func (p *Pool[T]) Reset(factory func() T) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	if factory != nil {
+		p.Factory = factory
+	}
+	p.generation++
+	p.fresh = make(map[T]struct{})
+
+	var stale []T
+drain:
+	for {
+		select {
+		case item := <-p.Items:
+			p.removeLive(item)
+			stale = append(stale, item)
+		default:
+			break drain
+		}
+	}
+	for slot, item := range p.sticky {
+		p.removeLive(item)
+		stale = append(stale, item)
+		delete(p.sticky, slot)
+	}
+	p.mu.Unlock()
+
+	for _, item := range stale {
+		p.Close(item)
+	}
+}
+
+// Done closes the pool and all the idle items in it. It is safe to call Done multiple times,
+// items returned by Put() after Done are closed immediately.
 func (p *Pool[T]) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+
 	close(p.Items)
 	for i := range p.Items {
+		p.live--
 		p.Close(i)
 	}
+	p.Items = nil
+
+	for slot, item := range p.sticky {
+		p.live--
+		p.Close(item)
+		delete(p.sticky, slot)
+	}
 }
@@ -0,0 +1,572 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestPool(initialCap, maxCap int) *Pool[*int] {
+	p := &Pool[*int]{
+		Items:   make(chan *int, maxCap),
+		Factory: func() *int { return new(int) },
+		Close:   func(*int) {},
+	}
+	p.Fill(initialCap)
+	return p
+}
+
+func TestPoolStats(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(1, 2)
+
+	a := p.Get() // reused
+	b := p.Get() // created
+	c := p.Get() // created
+
+	stats := p.Stats()
+	if stats.Idle != 0 || stats.InUse != 3 || stats.Created != 2 || stats.Reused != 1 || stats.MaxCap != 2 {
+		t.Errorf("Unexpected stats after Get: %+v", stats)
+	}
+
+	p.Put(a)
+	p.Put(b)
+	p.Put(c) // pool is full
+
+	stats = p.Stats()
+	expected := PoolStats{Idle: 2, InUse: 0, Created: 2, Reused: 1, ClosedFull: 1, MaxCap: 2, IdleCap: 2}
+	if stats != expected {
+		t.Errorf("Expected %+v, Found: %+v", expected, stats)
+	}
+}
+
+func TestPoolGetContextBlocksAtCapacity(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(1, 1)
+
+	first, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+
+	// pool is at capacity, GetContext should wait until the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, Found: %v", context.DeadlineExceeded, err)
+	}
+
+	// put back from another goroutine, waiting GetContext should receive the same item
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		p.Put(first)
+	}()
+	second, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	if second != first {
+		t.Errorf("Expected the released item to be reused")
+	}
+	if p.Stats().Created != 0 {
+		t.Errorf("Expected no new item to be created, Found: %d", p.Stats().Created)
+	}
+}
+
+func TestPoolGetContextWakesOnPutBad(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(1, 1)
+
+	first, _ := p.GetContext(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		p.PutBad(first) // not put back, but the slot is freed
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	second, err := p.GetContext(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	if second == first || p.Stats().Created != 1 {
+		t.Errorf("Expected a new item to be created, Found: %+v", p.Stats())
+	}
+}
+
+func TestPoolGetContextWaits(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(0, 2)
+
+	first, _ := p.GetContext(context.Background())
+	second, _ := p.GetContext(context.Background())
+	if first == second || p.Stats().Waits != 0 {
+		t.Fatalf("Expected two items without a wait, Found: %+v", p.Stats())
+	}
+
+	// both slots are in use, GetContext should wait until an item is put back
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		p.Put(first)
+	}()
+	third, err := p.GetContext(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, Found: %v", err)
+	}
+	if third != first {
+		t.Errorf("Expected the released item to be reused")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, Found: %v", context.DeadlineExceeded, err)
+	}
+
+	stats := p.Stats()
+	if stats.Waits != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, stats.Waits)
+	}
+	if stats.WaitTime < 40*time.Millisecond {
+		t.Errorf("Expected a wait time of at least %v, Found: %v", 40*time.Millisecond, stats.WaitTime)
+	}
+	if p.PeakLive() != 2 {
+		t.Errorf("Expected %v, Found: %v", 2, p.PeakLive())
+	}
+}
+
+func TestPoolOnCapExceeded(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(1, 2)
+	var calls [][]int
+	p.OnCapExceeded = func(live, capacity int) { calls = append(calls, []int{live, capacity}) }
+
+	items := []*int{p.Get(), p.Get(), p.Get(), p.Get()} // reused, created, exceeding, exceeding
+	for _, item := range items {
+		p.Put(item)
+	}
+
+	if len(calls) != 1 || calls[0][0] != 3 || calls[0][1] != 2 {
+		t.Errorf("Expected %v, Found: %v", [][]int{{3, 2}}, calls)
+	}
+	if peak := p.PeakLive(); peak != 4 {
+		t.Errorf("Expected %v, Found: %v", 4, peak)
+	}
+}
+
+func TestPoolDoneIdempotent(t *testing.T) {
+	t.Parallel()
+	closeCount := 0
+	p := &Pool[*int]{
+		Items:   make(chan *int, 2),
+		Factory: func() *int { return new(int) },
+		Close:   func(*int) { closeCount++ },
+	}
+	p.Fill(2)
+	inUse := p.Get()
+
+	p.Done()
+	p.Done() // should not panic
+
+	if closeCount != 1 {
+		t.Errorf("Expected 1 idle item to be closed, Found: %d", closeCount)
+	}
+
+	// items put after Done are closed, not pooled
+	p.Put(inUse)
+	if closeCount != 2 {
+		t.Errorf("Expected returned item to be closed, Found: %d", closeCount)
+	}
+
+	// Get on a closed pool creates a fresh item
+	if item := p.Get(); item == nil {
+		t.Errorf("Expected a fresh item from the closed pool")
+	}
+}
+
+func TestPoolConcurrentPutAndDone(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(0, 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Put(p.Get())
+		}()
+	}
+	p.Done()
+	wg.Wait()
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	t.Parallel()
+	closeCount := 0
+	p := &Pool[*int]{
+		Items:   make(chan *int, 2),
+		Factory: func() *int { return new(int) },
+		Close:   func(*int) { closeCount++ },
+	}
+	p.Fill(2)
+
+	unhealthy := p.Get()
+	*unhealthy = 1
+	p.Put(unhealthy)
+
+	p.HealthCheck = func(i *int) bool { return *i == 0 }
+
+	// first idle item is healthy, second one is not
+	if item := p.Get(); *item != 0 {
+		t.Errorf("Expected healthy item")
+	}
+	if item := p.Get(); item == unhealthy {
+		t.Errorf("Expected unhealthy item to be skipped")
+	}
+	if closeCount != 1 {
+		t.Errorf("Expected unhealthy item to be closed, Found: %d", closeCount)
+	}
+	if p.Stats().Created != 1 {
+		t.Errorf("Expected a new item to be created, Found: %d", p.Stats().Created)
+	}
+}
+
+func TestPoolSingleUse(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(1, 2)
+	p.SingleUse = true
+	closed := 0
+	p.Close = func(*int) { closed++ }
+
+	a := p.Get() // reused
+	p.Put(a)
+	b := p.Get() // created, a is not reused
+	p.Put(b)
+
+	if a == b || closed != 2 || p.Len() != 0 {
+		t.Errorf("Expected %v, Found: %v", "2 distinct items closed after use", []int{closed, p.Len()})
+	}
+}
+
+func TestPoolPutBad(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(1, 2)
+	closed := 0
+	p.Close = func(*int) { closed++ }
+
+	a := p.Get() // reused
+	p.PutBad(a)
+	b := p.Get() // created, a is not handed out again
+
+	expected := PoolStats{Idle: 0, InUse: 1, Created: 1, Reused: 1, ClosedBad: 1, MaxCap: 2, IdleCap: 2}
+	if stats := p.Stats(); a == b || closed != 1 || stats != expected {
+		t.Errorf("Expected %+v, Found: %+v", expected, stats)
+	}
+}
+
+func TestPoolGetSticky(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(0, 4)
+	closed := 0
+	p.Close = func(*int) { closed++ }
+
+	items := make(map[int]*int)
+	slots := make(map[*int]bool)
+	for key := 0; key < 100; key++ {
+		items[key] = p.GetSticky(key)
+		slots[items[key]] = true
+	}
+
+	// same key gets the same item
+	for key := 0; key < 100; key++ {
+		if c := p.GetSticky(key); c != items[key] {
+			t.Errorf("Expected %v, Found: %v", items[key], c)
+		}
+	}
+	if len(slots) != 4 || p.Stats().Created != 4 {
+		t.Errorf("Expected %v, Found: %v", 4, len(slots))
+	}
+
+	// sticky items are not handed out by Get
+	if c := p.Get(); slots[c] {
+		t.Errorf("Expected %v, Found: %v", "a new item", c)
+	}
+
+	p.Done()
+	if closed != 4 {
+		t.Errorf("Expected %v, Found: %v", 4, closed)
+	}
+	if c := p.GetSticky(1); c == items[1] {
+		t.Errorf("Expected %v, Found: %v", "a new item after Done", c)
+	}
+}
+
+func TestPoolGetStickySlots(t *testing.T) {
+	t.Parallel()
+
+	p := newTestPool(0, 1)
+	p.StickySlots = 10
+
+	// dense keys get their own slots, the keys are mapped in turn
+	items := make(map[*int]bool)
+	for key := 0; key < 10; key++ {
+		items[p.GetSticky(key)] = true
+	}
+	if len(items) != 10 {
+		t.Errorf("Expected %v, Found: %v", 10, len(items))
+	}
+	for key := 0; key < 10; key++ {
+		if p.GetSticky(key+10) != p.GetSticky(key) {
+			t.Errorf("Expected %v, Found: %v", p.GetSticky(key), p.GetSticky(key+10))
+		}
+	}
+}
+
+func TestPoolWarm(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(5, 5)
+
+	var mu sync.Mutex
+	warmed := make(map[*int]bool)
+	p.Warm(2, func(i *int) {
+		mu.Lock()
+		defer mu.Unlock()
+		warmed[i] = true
+		*i = 1
+	})
+
+	if len(warmed) != 5 {
+		t.Errorf("Expected %v, Found: %v", 5, len(warmed))
+	}
+	stats := p.Stats()
+	if stats.Idle != 5 || stats.Reused != 0 || stats.Created != 0 {
+		t.Errorf("Expected the warmed items to be idle in the pool, Found: %+v", stats)
+	}
+	if i := p.Get(); *i != 1 {
+		t.Errorf("Expected a warmed item, Found: %v", *i)
+	}
+}
+
+func TestPoolWarmConcurrentPut(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(5, 5)
+
+	// the pool is filled again while its items are warmed
+	filled := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Fill(5)
+		for i := 0; i < 20; i++ {
+			p.Put(p.Get())
+		}
+		close(filled)
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Warm(2, func(i *int) { <-filled })
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Warm to return after the pool is filled in the meantime")
+	}
+	wg.Wait()
+
+	stats := p.Stats()
+	if stats.Idle != 5 || stats.ClosedFull != 5 {
+		t.Errorf("Expected the warmed items not fitting to be closed, Found: %+v", stats)
+	}
+	p.Done()
+	if live := p.live; live != 0 {
+		t.Errorf("Expected %v, Found: %v", 0, live)
+	}
+}
+
+func TestPoolReset(t *testing.T) {
+	t.Parallel()
+	p := newTestPool(2, 2)
+	var closed []int
+	p.Close = func(i *int) { closed = append(closed, *i) }
+
+	inUse := p.Get()
+	sticky := p.GetSticky(1)
+	*sticky = 2
+
+	p.Reset(func() *int {
+		i := 1
+		return &i
+	})
+	if len(closed) != 2 || p.Len() != 0 {
+		t.Errorf("Expected %v, Found: %v", "the idle and the sticky items closed", closed)
+	}
+
+	// items are created by the new factory
+	fresh := p.Get()
+	if *fresh != 1 {
+		t.Errorf("Expected %v, Found: %v", 1, *fresh)
+	}
+	if c := p.GetSticky(1); c == sticky || *c != 1 {
+		t.Errorf("Expected %v, Found: %v", "a new sticky item", *c)
+	}
+
+	// the item taken before Reset is closed when it is put back, the fresh one is pooled
+	p.Put(inUse)
+	p.Put(fresh)
+	if len(closed) != 3 || p.Len() != 1 {
+		t.Errorf("Expected %v, Found: %v", "the item in use closed", closed)
+	}
+	if c := p.Get(); c != fresh {
+		t.Errorf("Expected %v, Found: %v", fresh, c)
+	}
+
+	p.Done()
+	p.Reset(nil) // should not panic
+}
+
+func TestPoolResetConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	live := 0
+	p := &Pool[*int]{
+		Items: make(chan *int, 4),
+		Factory: func() *int {
+			mu.Lock()
+			live++
+			mu.Unlock()
+			return new(int)
+		},
+		Close: func(*int) {
+			mu.Lock()
+			live--
+			mu.Unlock()
+		},
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				p.Put(p.Get())
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		p.Reset(nil)
+	}
+	wg.Wait()
+
+	p.Done()
+	if live != 0 {
+		t.Errorf("Expected all the items to be closed, Found: %d live", live)
+	}
+}
+
+func TestPoolAdaptive(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	p := newTestPool(1, 8)
+	p.Adaptive = &AdaptiveCap{Min: 1, GrowAfter: 2, CoolDown: time.Minute, now: func() time.Time { return now }}
+
+	getN := func(n int) []*int {
+		items := make([]*int, n)
+		for i := range items {
+			items[i] = p.Get()
+		}
+		return items
+	}
+	putAll := func(items []*int) {
+		for _, item := range items {
+			p.Put(item)
+		}
+	}
+
+	// 1 hit and 3 misses, the second miss grows the capacity to 2
+	putAll(getN(4))
+	if s := p.Stats(); s.IdleCap != 2 || s.Idle != 2 || s.ClosedFull != 2 {
+		t.Errorf("Expected %d idle cap, %d idle and %d closed, Found: %+v", 2, 2, 2, s)
+	}
+
+	// misses in a row keep growing it up to the capacity of the items
+	putAll(getN(16))
+	if s := p.Stats(); s.IdleCap != 8 || s.Idle != 8 {
+		t.Errorf("Expected %d idle cap and %d idle, Found: %+v", 8, 8, s)
+	}
+
+	// hits don't change it before the cool-down
+	now = now.Add(30 * time.Second)
+	putAll(getN(2))
+	if s := p.Stats(); s.IdleCap != 8 {
+		t.Errorf("Expected %d idle cap, Found: %+v", 8, s)
+	}
+
+	// after the cool-down it shrinks a step at a time, closing the idle items above it
+	now = now.Add(time.Minute)
+	p.Put(p.Get())
+	if s := p.Stats(); s.IdleCap != 6 || s.Idle != 6 {
+		t.Errorf("Expected %d idle cap and %d idle, Found: %+v", 6, 6, s)
+	}
+	p.Put(p.Get())
+	if s := p.Stats(); s.IdleCap != 6 {
+		t.Errorf("Expected no shrink before the next cool-down, Found: %+v", s)
+	}
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Minute)
+		p.Put(p.Get())
+	}
+	if s := p.Stats(); s.IdleCap != 1 || s.Idle != 1 {
+		t.Errorf("Expected %d idle cap and %d idle, Found: %+v", 1, 1, s)
+	}
+}
+
+func TestPoolAdaptiveConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	live := 0
+	p := &Pool[*int]{
+		Items: make(chan *int, 16),
+		Factory: func() *int {
+			mu.Lock()
+			live++
+			mu.Unlock()
+			return new(int)
+		},
+		Close: func(*int) {
+			mu.Lock()
+			live--
+			mu.Unlock()
+		},
+		Adaptive: &AdaptiveCap{Min: 2, CoolDown: time.Millisecond},
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				item := p.Get()
+				p.Put(item)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := p.Stats()
+	if s.IdleCap < 2 || s.IdleCap > 16 || s.Idle > s.IdleCap {
+		t.Errorf("Expected the idle items within the idle cap of 2-16, Found: %+v", s)
+	}
+	p.Done()
+	if live != 0 {
+		t.Errorf("Expected all the items closed, Found: %d live", live)
+	}
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+// RandFactory derives the deterministic random streams of the engine from a single seed, so a given seed
+// produces the same streams in every run. A stream is not safe for concurrent use, each goroutine should
+// use its own stream, like the stream of its iteration.
+type RandFactory struct {
+	seed int64
+}
+
+// NewRandFactory creates a factory of the given seed, a zero seed means a random seed.
+func NewRandFactory(seed int64) *RandFactory {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &RandFactory{seed: seed}
+}
+
+// Seed returns the seed of the factory, the random one if it is created with a zero seed.
+func (f *RandFactory) Seed() int64 {
+	return f.seed
+}
+
+// Stream returns the n'th stream of the named purpose. Streams of different names or indexes are independent.
+// A nil factory returns randomly seeded streams.
+func (f *RandFactory) Stream(name string, n uint64) *rand.Rand {
+	if f == nil {
+		return rand.New(&splitMix64{state: uint64(time.Now().UnixNano()) ^ mix64(n+1)})
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(&splitMix64{state: mix64(uint64(f.seed)^h.Sum64()) ^ mix64(n+1)})
+}
+
+// splitMix64 is a small and fast rand.Source64, streams are created for each iteration so the 4KB state
+// of the default source would be costly.
+type splitMix64 struct {
+	state uint64
+}
+
+func (s *splitMix64) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	return mix64(s.state)
+}
+
+func (s *splitMix64) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+func (s *splitMix64) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// mix64 is the finalizer of the splitmix64 generator.
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
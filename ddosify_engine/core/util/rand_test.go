@@ -0,0 +1,42 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRandFactoryStream(t *testing.T) {
+	t.Parallel()
+
+	draw := func(f *RandFactory, name string, n uint64) []int {
+		r := f.Stream(name, n)
+		values := make([]int, 20)
+		for i := range values {
+			values[i] = r.Intn(1000)
+		}
+		return values
+	}
+
+	f := NewRandFactory(42)
+	first := draw(f, "iteration", 7)
+	if second := draw(NewRandFactory(42), "iteration", 7); !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected %v, Found: %v", first, second)
+	}
+
+	for _, other := range [][]int{draw(f, "iteration", 8), draw(f, "engine", 7), draw(NewRandFactory(43), "iteration", 7)} {
+		if reflect.DeepEqual(first, other) {
+			t.Errorf("Expected independent streams, Found: %v", other)
+		}
+	}
+}
+
+func TestRandFactoryRandomSeed(t *testing.T) {
+	t.Parallel()
+
+	if f := NewRandFactory(0); f.Seed() == 0 {
+		t.Errorf("Expected a random seed, Found: %v", f.Seed())
+	}
+	if f := NewRandFactory(5); f.Seed() != 5 {
+		t.Errorf("Expected %v, Found: %v", 5, f.Seed())
+	}
+}
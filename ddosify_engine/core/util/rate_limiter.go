@@ -0,0 +1,64 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiter shared by the goroutines. Tokens are added at a constant rate, the bucket
+// holds one token, so the waiting goroutines are released evenly spaced instead of in bursts.
+type RateLimiter struct {
+	interval time.Duration // refill interval of a token
+
+	mu   sync.Mutex
+	next time.Time // time of the next available token
+}
+
+// NewRateLimiter returns a RateLimiter that releases rate tokens per second. rate should be greater than 0.
+func NewRateLimiter(rate int) *RateLimiter {
+	return &RateLimiter{interval: time.Second / time.Duration(rate)}
+}
+
+// Wait blocks until a token is available. Returns ctx.Err() if ctx is done before that.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// reserve the next token, waiting goroutines get the tokens in order
+	l.mu.Lock()
+	now := time.Now()
+	t := l.next
+	if t.Before(now) {
+		// the tokens missed while no goroutine is waiting are not kept, the rate is not exceeded by a burst
+		t = now
+	}
+	l.next = t.Add(l.interval)
+	l.mu.Unlock()
+
+	wait := t.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitHeld blocks like Wait and returns the time of the call, the intended send time of the request held back by
+// the limiter. The time since then is the delay of the request caused by the limiter, zero if a token is available.
+// Returns ctx.Err() if ctx is done before a token is available.
+func (l *RateLimiter) WaitHeld(ctx context.Context) (time.Time, error) {
+	ready := time.Now()
+	if err := l.Wait(ctx); err != nil {
+		return time.Time{}, err
+	}
+	return ready, nil
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterPacesConcurrentWaits(t *testing.T) {
+	t.Parallel()
+
+	rate := 100
+	l := NewRateLimiter(rate)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Wait(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	// first token is available immediately, 49 more tokens take 490ms
+	elapsed := time.Since(start)
+	if elapsed < 450*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected %v, Found: %v", 490*time.Millisecond, elapsed)
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(1)
+	l.Wait(context.Background()) // consume the available token
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected %v, Found: %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected wait to be interrupted, Found: %v", elapsed)
+	}
+}
+
+func TestRateLimiterWaitHeld(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(100)
+
+	// requests below the rate are not held back, the missed tokens don't add up to a delay
+	for i := 0; i < 5; i++ {
+		ready, err := l.WaitHeld(context.Background())
+		if err != nil {
+			t.Fatalf("TestRateLimiterWaitHeld error occurred: %v", err)
+		}
+		if lag := time.Since(ready); lag > 5*time.Millisecond {
+			t.Errorf("Expected %v, Found: %v", 0, lag)
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+
+	// requests above the rate are held back by the tokens before them
+	start := time.Now()
+	var lag time.Duration
+	for i := 0; i < 10; i++ {
+		ready, _ := l.WaitHeld(context.Background())
+		lag = time.Since(ready)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || lag < 5*time.Millisecond {
+		t.Errorf("Expected %v, Found: %v %v", "the requests paced by the rate", elapsed, lag)
+	}
+}
@@ -31,8 +31,11 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"go.ddosify.com/ddosify/core/distributed"
 	"go.ddosify.com/ddosify/core/proxy"
+	"go.ddosify.com/ddosify/core/report"
 	"go.ddosify.com/ddosify/core/types"
 )
 
@@ -49,6 +52,18 @@ func resetFlags() {
 	*iterCount = types.DefaultIterCount
 	*loadType = types.DefaultLoadType
 	*duration = types.DefaultDuration
+	*grace = 0
+	*maxDur = 0
+	*retryAft = 0
+	*sample = 0
+	*warmup = 0
+	*jitter = 0
+	*spread = 0
+	*rps = 0
+	*maxReqs = 0
+	*workers = 0
+	*listenAddr = distributed.DefaultListenAddr
+	*coordinator = ""
 
 	*method = types.DefaultMethod
 	*payload = ""
@@ -56,17 +71,57 @@ func resetFlags() {
 	headers = header{}
 
 	*target = ""
+	*targetsFile = ""
 	*timeout = types.DefaultTimeout
 
 	*proxyFlag = ""
 	*output = types.DefaultOutputType
+	*dashboard = false
+	*metricsAddr = ""
+	*controlAddr = ""
+	*outputFormat = ""
+	*outFile = ""
+	*influxURL = ""
+	*influxOrg = ""
+	*influxBucket = ""
+	*influxToken = ""
+	*influxBatch = report.DefaultInfluxBatchSize
+	*influxFlush = report.DefaultInfluxFlushInterval
+	*otelEndpoint = ""
+	*otelService = ""
+	otelHeaders = header{}
+	*timeSeriesFile = ""
+	*timeSeriesFormat = ""
+	*timeSeriesInterval = report.DefaultTimeSeriesInterval
+	*seed = 0
+	*saveBaseline = ""
+	*compareBaseline = ""
+	*baselineTolerance = ""
+	*dnsCacheTTL = 0
+	resolve = header{}
+	sourceAddrs = header{}
+	*maxConns = 0
+	stopOn = header{}
+	sla = header{}
+	onlyTags = header{}
+	*noKeepAlive = false
+	*revalidate = false
+	*preWarm = false
+	*requestID = ""
+	*successWhen = ""
+	*failureSamplesFile = ""
+	*failureSamples = types.DefaultFailureSamplesPerCategory
 
 	*configPath = ""
+	*importType = ""
+	*skipStatic = false
+	*importEnv = ""
 
 	*certPath = ""
 	*certKeyPath = ""
 
 	*debug = false
+	*dryRun = false
 }
 
 func TestDefaultFlagValues(t *testing.T) {
@@ -106,26 +161,756 @@ func TestDefaultFlagValues(t *testing.T) {
 	if *output != types.DefaultOutputType {
 		t.Errorf("TestDefaultFlagValues failed, expected %#v, found %#v", types.DefaultOutputType, *output)
 	}
+	if *metricsAddr != "" {
+		t.Errorf("TestDefaultFlagValues failed, expected %#v, found %#v", "", *metricsAddr)
+	}
 	if *configPath != "" {
 		t.Errorf("TestDefaultFlagValues failed, expected %#v, found %#v", "", *configPath)
 	}
-	if *certPath != "" {
-		t.Errorf("TestDefaultFlagValues failed, expected %#v, found %#v", "", *certPath)
+	if *certPath != "" {
+		t.Errorf("TestDefaultFlagValues failed, expected %#v, found %#v", "", *certPath)
+	}
+	if *certKeyPath != "" {
+		t.Errorf("TestDefaultFlagValues failed, expected %#v, found %#v", "", *certKeyPath)
+	}
+}
+
+func TestCreateHammer(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		fromFlags bool
+		fromFile  bool
+	}{
+		{"Flag", "-t=dummy.com -config=", true, false},
+		{"File", "-config=dummy.json -t=", false, true},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			oldFileFunc := createHammerFromConfigFile
+			oldFlagFunc := createHammerFromFlags
+			defer func() {
+				os.Args = oldArgs
+				createHammerFromConfigFile = oldFileFunc
+				createHammerFromFlags = oldFlagFunc
+			}()
+
+			fromFileCalled := false
+			fromFlagsCalled := false
+			createHammerFromConfigFile = func(debug bool) (h types.Hammer, err error) {
+				fromFileCalled = true
+				return
+			}
+			createHammerFromFlags = func() (h types.Hammer, err error) {
+				fromFlagsCalled = true
+				return
+			}
+
+			// Act
+			os.Args = []string{"cmd", test.args}
+			flag.Parse()
+			createHammer()
+
+			// Assert
+			if fromFileCalled != test.fromFile {
+				t.Errorf("createHammerFromConfigFileCalled expected %v found %v", test.fromFile, fromFileCalled)
+			}
+			if fromFlagsCalled != test.fromFlags {
+				t.Errorf("createHammerFromFlagsCalled expected %v found %v", test.fromFlags, fromFlagsCalled)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+}
+
+func TestDebugFlagOverridesConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"DebugFlagShouldOverrideConfig",
+			[]string{"-config", "config/config_testdata/config_debug_false.json", "-debug"}},
+		{"UseConfigDebugKeyWhenNoDebugFlagSpecified",
+			[]string{"-config", "config/config_testdata/config_debug_mode.json", "-debug", "false"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if h.Debug != *debug {
+				t.Errorf("debug flag did not override config file")
+			}
+
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestMetricsAddrFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-metrics-addr", ":9090"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-metrics-addr", ":9090"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if h.MetricsAddr != ":9090" {
+				t.Errorf("Expected %v, Found: %v", ":9090", h.MetricsAddr)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestDashboardFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-dashboard"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-dashboard"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if !h.Dashboard {
+				t.Errorf("Expected %v, Found: %v", true, h.Dashboard)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestSeedFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-seed", "42"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_weighted_scenarios.json", "-seed", "42"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if h.Seed != 42 {
+				t.Errorf("Expected %v, Found: %v", 42, h.Seed)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestWarmupFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-warmup", "10s"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-warmup", "10s"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if h.Warmup != 10*time.Second {
+				t.Errorf("Expected %v, Found: %v", 10*time.Second, h.Warmup)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestJitterFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-jitter", "500ms", "-startup-spread", "5s"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json",
+			"-jitter", "500ms", "-startup-spread", "5s"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if h.Jitter != 500*time.Millisecond {
+				t.Errorf("Expected %v, Found: %v", 500*time.Millisecond, h.Jitter)
+			}
+			if h.StartupSpread != 5*time.Second {
+				t.Errorf("Expected %v, Found: %v", 5*time.Second, h.StartupSpread)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestDisableKeepAliveFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-disable-keep-alive"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-disable-keep-alive"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if !h.DisableKeepAlive {
+				t.Errorf("Expected %v, Found: %v", true, h.DisableKeepAlive)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestRevalidateFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-revalidate"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-revalidate"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if !h.Revalidate {
+				t.Errorf("Expected %v, Found: %v", true, h.Revalidate)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestTimeSeriesFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected types.TimeSeriesConf
+	}{
+		{"WithConfig", []string{"-config", "config/config_testdata/config_timeseries.json", "-timeseries-interval", "10s"},
+			types.TimeSeriesConf{File: "series.csv", Format: "csv", Interval: 10 * time.Second}},
+		{"FromFlags", []string{"-t", "dummy.com", "-timeseries-file", "series.json", "-timeseries-interval", "2s"},
+			types.TimeSeriesConf{File: "series.json", Interval: 2 * time.Second}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if !reflect.DeepEqual(h.TimeSeries, test.expected) {
+				t.Errorf("Expected %v, Found: %v", test.expected, h.TimeSeries)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestPreWarmFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-pre-warm"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-pre-warm"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if !h.PreWarm {
+				t.Errorf("Expected %v, Found: %v", true, h.PreWarm)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestOTelFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected types.OTelConf
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-otel-endpoint", "http://collector:4318", "-otel-header", "X-Team: perf"},
+			types.OTelConf{Endpoint: "http://collector:4318", Headers: map[string]string{"X-Team": "perf"}}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_otel.json", "-otel-endpoint", "http://collector:4318",
+			"-otel-header", "X-Team: perf"},
+			types.OTelConf{Endpoint: "http://collector:4318", ServiceName: "checkout-load-test", BatchSize: 100,
+				FlushInterval: 2 * time.Second, Headers: map[string]string{"Authorization": "Bearer secret", "X-Team": "perf"}}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			if !reflect.DeepEqual(h.OTel, test.expected) {
+				t.Errorf("Expected %v, Found: %v", test.expected, h.OTel)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestRequestIDHeaderFlag(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_mode.json", "-request-id-header", "X-Request-Id"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.RequestIDHeader != "X-Request-Id" {
+		t.Errorf("Expected %v, Found: %v", "X-Request-Id", h.RequestIDHeader)
+	}
+}
+
+func TestSuccessWhenFlag(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_mode.json", "-success-when", "status_code < 500"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.SuccessWhen != "status_code < 500" {
+		t.Errorf("Expected %v, Found: %v", "status_code < 500", h.SuccessWhen)
+	}
+}
+
+func TestFailureSamplesFlags(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_mode.json",
+		"-failure-samples-file", "failures.jsonl", "-failure-samples", "3"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	expected := types.FailureSamplesConf{File: "failures.jsonl", PerCategory: 3}
+	if h.FailureSamples != expected {
+		t.Errorf("Expected %v, Found: %v", expected, h.FailureSamples)
+	}
+}
+
+func TestOnlyTagFlag(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_mode.json", "-only-tag", "payments",
+		"-only-tag", "critical"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if !reflect.DeepEqual(h.OnlyTags, []string{"payments", "critical"}) {
+		t.Errorf("Expected %v, Found: %v", []string{"payments", "critical"}, h.OnlyTags)
+	}
+}
+
+func TestDryRunFlag(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_false.json", "-dry-run"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if !h.Debug || !h.DryRun {
+		t.Errorf("Expected debug and dry run, Found: %v %v", h.Debug, h.DryRun)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-t", "https://test.com", "-dry-run"}
+	flag.Parse()
+	h, err = createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if !h.Debug || !h.DryRun {
+		t.Errorf("Expected debug and dry run, Found: %v %v", h.Debug, h.DryRun)
+	}
+}
+
+func TestMaxDurationFlag(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_false.json", "-max-duration", "30m"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.MaxDuration != 30*time.Minute {
+		t.Errorf("Expected %v, Found: %v", 30*time.Minute, h.MaxDuration)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-t", "https://test.com", "-max-duration", "90s"}
+	flag.Parse()
+	h, err = createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.MaxDuration != 90*time.Second {
+		t.Errorf("Expected %v, Found: %v", 90*time.Second, h.MaxDuration)
+	}
+}
+
+func TestSampleFlag(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_false.json", "-sample", "0.1"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.SampleRate != 0.1 {
+		t.Errorf("Expected %v, Found: %v", 0.1, h.SampleRate)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-t", "https://test.com", "-sample", "0.5"}
+	flag.Parse()
+	h, err = createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.SampleRate != 0.5 {
+		t.Errorf("Expected %v, Found: %v", 0.5, h.SampleRate)
+	}
+}
+
+func TestHonorRetryAfterFlag(t *testing.T) {
+	resetFlags()
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config_debug_false.json", "-honor-retry-after", "30s"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.HonorRetryAfter != 30*time.Second {
+		t.Errorf("Expected %v, Found: %v", 30*time.Second, h.HonorRetryAfter)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-t", "https://test.com", "-honor-retry-after", "1m"}
+	flag.Parse()
+	h, err = createHammer()
+	if err != nil {
+		t.Errorf("createHammer return %v", err)
+	}
+	if h.HonorRetryAfter != time.Minute {
+		t.Errorf("Expected %v, Found: %v", time.Minute, h.HonorRetryAfter)
 	}
-	if *certKeyPath != "" {
-		t.Errorf("TestDefaultFlagValues failed, expected %#v, found %#v", "", *certKeyPath)
+}
+
+func TestBaselineGate(t *testing.T) {
+	resetFlags()
+	defer resetFlags()
+
+	if g, err := newBaselineGate(types.Hammer{}); g != nil || err != nil {
+		t.Errorf("Expected %v, Found: %v, %v", nil, g, err)
+	}
+
+	*compareBaseline = "missing_baseline.json"
+	if _, err := newBaselineGate(types.Hammer{}); err == nil {
+		t.Errorf("Expected the missing baseline error")
+	}
+
+	// baseline of a result without errors is saved, a result with errors regresses against it
+	path := t.TempDir() + "/baseline.json"
+	*compareBaseline = ""
+	*saveBaseline = path
+	g, err := newBaselineGate(types.Hammer{})
+	if err != nil {
+		t.Fatalf("newBaselineGate error occurred: %v", err)
+	}
+	result := report.NewResult()
+	result.SuccessCount = 10
+	if regressed, err := g.check(result); regressed || err != nil {
+		t.Errorf("Expected %v, Found: %v, %v", false, regressed, err)
+	}
+
+	*saveBaseline = ""
+	*compareBaseline = path
+	g, err = newBaselineGate(types.Hammer{})
+	if err != nil {
+		t.Fatalf("newBaselineGate error occurred: %v", err)
+	}
+	result.ServerFailedCount = 10
+	if regressed, err := g.check(result); !regressed || err != nil {
+		t.Errorf("Expected %v, Found: %v, %v", true, regressed, err)
+	}
+
+	// baseline is not used in debug mode
+	if g, err := newBaselineGate(types.Hammer{Debug: true}); g != nil || err != nil {
+		t.Errorf("Expected %v, Found: %v, %v", nil, g, err)
 	}
 }
 
-func TestCreateHammer(t *testing.T) {
+func TestInfluxFlags(t *testing.T) {
 	tests := []struct {
-		name      string
-		args      string
-		fromFlags bool
-		fromFile  bool
+		name string
+		args []string
 	}{
-		{"Flag", "-t=dummy.com -config=", true, false},
-		{"File", "-config=dummy.json -t=", false, true},
+		{"FromFlags", []string{"-t", "dummy.com", "-output", "influxdb"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-output", "influxdb"}},
 	}
 
 	for _, test := range tests {
@@ -133,52 +918,126 @@ func TestCreateHammer(t *testing.T) {
 			// Arrange
 			resetFlags()
 			oldArgs := os.Args
-			oldFileFunc := createHammerFromConfigFile
-			oldFlagFunc := createHammerFromFlags
 			defer func() {
 				os.Args = oldArgs
-				createHammerFromConfigFile = oldFileFunc
-				createHammerFromFlags = oldFlagFunc
 			}()
 
-			fromFileCalled := false
-			fromFlagsCalled := false
-			createHammerFromConfigFile = func(debug bool) (h types.Hammer, err error) {
-				fromFileCalled = true
-				return
+			// Act
+			args := append(test.args, "-influx-url", "http://localhost:8086", "-influx-org", "ddosify",
+				"-influx-bucket", "loadtest", "-influx-token", "secret", "-influx-flush-interval", "5s")
+			os.Args = append([]string{"cmd"}, args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
 			}
-			createHammerFromFlags = func() (h types.Hammer, err error) {
-				fromFlagsCalled = true
-				return
+
+			// Assert
+			expected := types.InfluxConf{
+				URL:           "http://localhost:8086",
+				Org:           "ddosify",
+				Bucket:        "loadtest",
+				Token:         "secret",
+				BatchSize:     report.DefaultInfluxBatchSize,
+				FlushInterval: 5 * time.Second,
+			}
+			if h.OutputFormat != "influxdb" || h.Influx != expected {
+				t.Errorf("Expected %v, Found: %v", expected, h.Influx)
+			}
+			if err = h.Validate(); err != nil {
+				t.Errorf("Validate return %v", err)
 			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestRPSFlag(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedIter int
+	}{
+		{"DefaultIterationCount", []string{"-t", "dummy.com", "-rps", "100", "-d", "3"}, 300},
+		{"GivenIterationCount", []string{"-t", "dummy.com", "-rps", "100", "-d", "3", "-n", "20"}, 20},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
 
 			// Act
-			os.Args = []string{"cmd", test.args}
+			os.Args = append([]string{"cmd"}, test.args...)
 			flag.Parse()
-			createHammer()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
 
 			// Assert
-			if fromFileCalled != test.fromFile {
-				t.Errorf("createHammerFromConfigFileCalled expected %v found %v", test.fromFile, fromFileCalled)
+			if h.RPS != 100 {
+				t.Errorf("Expected %v, Found: %v", 100, h.RPS)
 			}
-			if fromFlagsCalled != test.fromFlags {
-				t.Errorf("createHammerFromFlagsCalled expected %v found %v", test.fromFlags, fromFlagsCalled)
+			if h.IterationCount != test.expectedIter {
+				t.Errorf("Expected %v, Found: %v", test.expectedIter, h.IterationCount)
 			}
 		}
 
 		t.Run(test.name, tf)
 	}
+	resetFlags()
 }
 
-func TestDebugFlagOverridesConfig(t *testing.T) {
+func TestMaxRequestsFlag(t *testing.T) {
 	tests := []struct {
 		name string
 		args []string
 	}{
-		{"DebugFlagShouldOverrideConfig",
-			[]string{"-config", "config/config_testdata/config_debug_false.json", "-debug"}},
-		{"UseConfigDebugKeyWhenNoDebugFlagSpecified",
-			[]string{"-config", "config/config_testdata/config_debug_mode.json", "-debug", "false"}},
+		{"FromFlags", []string{"-t", "dummy.com", "-max-requests", "1000"}},
+		{"OverridesConfig", []string{"-config", "config/config_testdata/config_debug_mode.json", "-max-requests", "1000"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+				resetFlags()
+			}()
+
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+			if h.MaxRequests != 1000 {
+				t.Errorf("Expected %v, Found: %v", 1000, h.MaxRequests)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+}
+
+func TestStopOnFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-stop-on", "error_rate > 50% over 10s", "-stop-on", "p99 > 5s"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_stop_on.json",
+			"-stop-on", "error_rate > 50% over 10s", "-stop-on", "p99 > 5s"}},
 	}
 
 	for _, test := range tests {
@@ -200,10 +1059,153 @@ func TestDebugFlagOverridesConfig(t *testing.T) {
 			}
 
 			// Assert
-			if h.Debug != *debug {
-				t.Errorf("debug flag did not override config file")
+			expected := []string{"error_rate > 50% over 10s", "p99 > 5s"}
+			if !reflect.DeepEqual(h.StopOn, expected) {
+				t.Errorf("Expected %v, Found: %v", expected, h.StopOn)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestSLAFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-sla", "p99 < 800ms", "-sla", "throughput >= 1000rps"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_sla.json",
+			"-sla", "p99 < 800ms", "-sla", "throughput >= 1000rps"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
+			}
+
+			// Assert
+			expected := []string{"p99 < 800ms", "throughput >= 1000rps"}
+			if !reflect.DeepEqual(h.SLA, expected) {
+				t.Errorf("Expected %v, Found: %v", expected, h.SLA)
+			}
+		}
+
+		t.Run(test.name, tf)
+	}
+	resetFlags()
+}
+
+func TestImportFlag(t *testing.T) {
+	oldArgs := os.Args
+	defer func() {
+		os.Args = oldArgs
+		resetFlags()
+	}()
+
+	resetFlags()
+	os.Args = []string{"cmd", "-n", "50", "-import", "har", "-import-skip-static", "config/config_testdata/session.har"}
+	flag.Parse()
+	h, err := createHammer()
+	if err != nil {
+		t.Fatalf("createHammer return %v", err)
+	}
+	if err = h.Validate(); err != nil {
+		t.Errorf("Expected %v, Found: %v", nil, err)
+	}
+	if h.IterationCount != 50 || len(h.Scenario.Steps) != 3 {
+		t.Errorf("Expected %v, Found: %v", 3, len(h.Scenario.Steps))
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-import", "postman", "-import-env", "config/config_testdata/collection_env.json",
+		"config/config_testdata/collection.json"}
+	flag.Parse()
+	h, err = createHammer()
+	if err != nil {
+		t.Fatalf("createHammer return %v", err)
+	}
+	if err = h.Validate(); err != nil {
+		t.Errorf("Expected %v, Found: %v", nil, err)
+	}
+	if len(h.Scenario.Steps) != 5 {
+		t.Errorf("Expected %v, Found: %v", 5, len(h.Scenario.Steps))
+	}
+
+	invalids := [][]string{
+		{"cmd", "-import", "har"},
+		{"cmd", "-import", "har", "-t", "dummy.com", "config/config_testdata/session.har"},
+		{"cmd", "-import", "har", "-config", "config/config_testdata/config_dns.json", "config/config_testdata/session.har"},
+	}
+	for _, args := range invalids {
+		resetFlags()
+		os.Args = args
+		flag.Parse()
+		if _, err := createHammer(); err == nil {
+			t.Errorf("%v Expected %v, Found: %v", args, "error", err)
+		}
+	}
+}
+
+func TestDNSFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"FromFlags", []string{"-t", "dummy.com", "-dns-cache-ttl", "1m", "-resolve", "dummy.com:80:127.0.0.1",
+			"-source-addr", "127.0.0.2", "-max-host-conns", "5"}},
+		{"WithConfig", []string{"-config", "config/config_testdata/config_dns.json",
+			"-dns-cache-ttl", "1m", "-resolve", "dummy.com:80:127.0.0.1", "-source-addr", "127.0.0.2",
+			"-max-host-conns", "5"}},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			// Arrange
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+
+			// Act
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+			h, err := createHammer()
+
+			if err != nil {
+				t.Errorf("createHammer return %v", err)
 			}
 
+			// Assert
+			if h.DNSCacheTTL != time.Minute {
+				t.Errorf("Expected %v, Found: %v", time.Minute, h.DNSCacheTTL)
+			}
+			expected := []string{"dummy.com:80:127.0.0.1"}
+			if !reflect.DeepEqual(h.Resolve, expected) {
+				t.Errorf("Expected %v, Found: %v", expected, h.Resolve)
+			}
+			expected = []string{"127.0.0.2"}
+			if !reflect.DeepEqual(h.SourceAddrs, expected) {
+				t.Errorf("Expected %v, Found: %v", expected, h.SourceAddrs)
+			}
+			if h.MaxHostConns != 5 {
+				t.Errorf("Expected %v, Found: %v", 5, h.MaxHostConns)
+			}
 		}
 
 		t.Run(test.name, tf)
@@ -351,6 +1353,57 @@ func TestCreateScenarioTLS(t *testing.T) {
 	}
 }
 
+func TestCreateScenarioTargetsFile(t *testing.T) {
+	path := t.TempDir() + "/urls.txt"
+	if err := os.WriteFile(path, []byte("https://test.com/a\nhttps://test.com/b\nhttps://test.com/a\n"), 0644); err != nil {
+		t.Fatalf("TestCreateScenarioTargetsFile write error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		shouldErr bool
+	}{
+		{"Valid", []string{"--targets=" + path}, false},
+		{"WithTarget", []string{"--targets=" + path, "-t=https://test.com"}, true},
+		{"MissingFile", []string{"--targets=" + path + ".missing"}, true},
+	}
+
+	for _, test := range tests {
+		tf := func(t *testing.T) {
+			resetFlags()
+			oldArgs := os.Args
+			defer func() {
+				os.Args = oldArgs
+			}()
+			os.Args = append([]string{"cmd"}, test.args...)
+			flag.Parse()
+
+			h, err := createHammerFromFlags()
+			if test.shouldErr {
+				if err == nil {
+					t.Errorf("Should be errored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Errored: %v", err)
+			}
+			expected := []types.WeightedTarget{
+				{Name: "https://test.com/a", URL: "https://test.com/a", Weight: 2},
+				{Name: "https://test.com/b", URL: "https://test.com/b", Weight: 1},
+			}
+			if targets := h.Scenario.Steps[0].Targets; !reflect.DeepEqual(expected, targets) {
+				t.Errorf("Expected %v, Found: %v", expected, targets)
+			}
+			if err := h.Validate(); err != nil {
+				t.Errorf("Errored: %v", err)
+			}
+		}
+		t.Run(test.name, tf)
+	}
+}
+
 func TestCreateProxy(t *testing.T) {
 	addr, _ := url.Parse("http://127.0.0.1:80")
 	withAddr := proxy.Proxy{
@@ -480,6 +1533,33 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestDistributedModes(t *testing.T) {
+	oldArgs, oldWorker, oldCoordinator := os.Args, runWorker, runCoordinator
+	defer func() { os.Args, runWorker, runCoordinator = oldArgs, oldWorker, oldCoordinator }()
+
+	var workerAddr string
+	var coordinatorCalled bool
+	runWorker = func(addr string) { workerAddr = addr }
+	runCoordinator = func(h types.Hammer) { coordinatorCalled = true }
+
+	resetFlags()
+	os.Args = []string{"cmd", "-coordinator", "10.0.0.1:7777"}
+	flag.Parse()
+	start()
+	if workerAddr != "10.0.0.1:7777" {
+		t.Errorf("Expected %v, Found: %v", "10.0.0.1:7777", workerAddr)
+	}
+
+	resetFlags()
+	os.Args = []string{"cmd", "-config", "config/config_testdata/config.json", "-workers", "2"}
+	flag.Parse()
+	start()
+	if !coordinatorCalled {
+		t.Errorf("Coordinator should be run")
+	}
+	resetFlags()
+}
+
 func TestTargetEmpty(t *testing.T) {
 	// Below cmd code triggers this block
 	if os.Getenv("TARGET_EMPTY") == "1" {
@@ -0,0 +1,278 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+// Package runner runs the load tests programmatically, e.g. from a go test of an integration test suite.
+// The ddosify CLI is a thin wrapper over it.
+//
+//	r := runner.New(runner.Config{Hammer: h})
+//	results, err := r.Run(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	for res := range results {
+//		// per request results
+//	}
+//	summary := r.Summary() // aggregated result, available once the results channel is closed
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go.ddosify.com/ddosify/config"
+	"go.ddosify.com/ddosify/core"
+	"go.ddosify.com/ddosify/core/proxy"
+	"go.ddosify.com/ddosify/core/report"
+	"go.ddosify.com/ddosify/core/types"
+)
+
+// DefaultResultBuffer is the number of results queued for the consumer of Run before they are dropped.
+const DefaultResultBuffer = 4096
+
+// Result is the result of a request of a step.
+type Result = types.ScenarioStepResult
+
+// Config is the configuration of a Runner.
+type Config struct {
+	// Test configuration, created by ConfigFromFile or manually. Strategy of the proxy, load type and
+	// report destination are defaulted to single, linear and report.OutputTypeNone if they are empty.
+	Hammer types.Hammer
+
+	// Number of the results queued for the consumer of Run, DefaultResultBuffer if zero.
+	// Results are dropped instead of slowing down the test if the consumer can't keep up, see Dropped.
+	ResultBuffer int
+
+	// Results are not passed to the channel of Run, for the callers using only the Summary.
+	NoResults bool
+}
+
+// ConfigFromFile reads the json config file like the --config flag of the CLI.
+func ConfigFromFile(path string) (c Config, err error) {
+	data, err := config.ReadConfigFile(path)
+	if err != nil {
+		return
+	}
+	if err = config.Validate(data); err != nil {
+		return
+	}
+	reader, err := config.NewConfigReader(data, config.ConfigTypeJson)
+	if err != nil {
+		return
+	}
+	c.Hammer, err = reader.CreateHammer()
+	return
+}
+
+// Runner runs a load test once.
+type Runner struct {
+	config Config
+
+	mu              sync.Mutex
+	started         bool
+	done            bool
+	summary         *report.Result
+	failed          bool
+	stopReason      string
+	hardTimeout     string
+	adaptiveResult  string
+	poolWarning     string
+	maxRequests     string
+	backpressure    string
+	teardownWarning string
+	dryRunFailures  []string
+
+	// results dropped by the channel and by the result hook of the engine
+	dropped     int64
+	hookDropped int64
+}
+
+// New returns the Runner of the given config, the config is validated by Run.
+func New(config Config) *Runner {
+	h := &config.Hammer
+	if h.Proxy.Strategy == "" {
+		h.Proxy.Strategy = proxy.ProxyTypeSingle
+	}
+	if h.LoadType == "" {
+		h.LoadType = types.DefaultLoadType
+	}
+	if h.ReportDestination == "" {
+		h.ReportDestination = report.OutputTypeNone
+	}
+	if config.ResultBuffer <= 0 {
+		config.ResultBuffer = DefaultResultBuffer
+	}
+	return &Runner{config: config}
+}
+
+// Run starts the test and returns the channel of the per request results. The channel is closed when the test
+// is done, then Summary returns the aggregated result. The test is stopped by canceling the ctx.
+// Returns an error, without starting the test, if the config is invalid or the engine can't be initialized.
+func (r *Runner) Run(ctx context.Context) (<-chan Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started {
+		return nil, fmt.Errorf("runner is already started")
+	}
+
+	h := r.config.Hammer
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+
+	results := make(chan Result, r.config.ResultBuffer)
+	if !r.config.NoResults {
+		onResult := h.OnResult
+		h.OnResult = func(res *types.ScenarioStepResult) {
+			if onResult != nil {
+				onResult(res)
+			}
+			select {
+			case results <- *res:
+			default:
+				atomic.AddInt64(&r.dropped, 1)
+			}
+		}
+	}
+
+	es, err := core.InitEngineServices(h)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := core.NewEngine(ctx, h, es)
+	if err != nil {
+		return nil, err
+	}
+	if err = engine.Init(); err != nil {
+		return nil, err
+	}
+	r.started = true
+
+	go func() {
+		engine.Start()
+
+		r.mu.Lock()
+		r.done = true
+		r.summary = engine.Result()
+		r.failed = engine.IsTestFailed()
+		r.stopReason = engine.StopReason()
+		r.hardTimeout = engine.HardTimeout()
+		r.adaptiveResult = engine.AdaptiveResult()
+		r.poolWarning = engine.ClientPoolWarning()
+		r.maxRequests = engine.MaxRequestsResult()
+		r.backpressure = engine.BackpressureResult()
+		r.teardownWarning = engine.TeardownWarning()
+		r.dryRunFailures = engine.DryRunFailures()
+		r.hookDropped = engine.DroppedResults()
+		r.mu.Unlock()
+
+		// the result hook is closed by the engine, no more results are sent
+		close(results)
+	}()
+	return results, nil
+}
+
+// Summary returns the aggregated result of the test, nil until the test is done
+// or if the report destination of the config doesn't keep it.
+func (r *Runner) Summary() *report.Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.summary
+}
+
+// Failed reports whether the assertions of the test failed, false until the test is done.
+func (r *Runner) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.done && r.failed
+}
+
+// StopReason returns the stop condition that aborted the test and its received value,
+// empty if the test isn't stopped by a stop condition.
+func (r *Runner) StopReason() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopReason
+}
+
+// HardTimeout returns the reason of the hard timeout if the test is stopped by the max duration of the config,
+// empty until the test is done or if it is not stopped by it.
+func (r *Runner) HardTimeout() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.hardTimeout
+}
+
+// AdaptiveResult returns the users at which the thresholds of the adaptive load are first crossed,
+// empty if the load is not adaptive.
+func (r *Runner) AdaptiveResult() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.adaptiveResult
+}
+
+// MaxRequestsResult returns the number of the requests sent if the test is stopped by the max requests of the
+// config, empty until the test is done or if it is not stopped by them.
+func (r *Runner) MaxRequestsResult() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxRequests
+}
+
+// BackpressureResult returns the backpressure policy of the config and the iterations dropped, queued or
+// blocked by it, empty until the test is done or if the config has no backpressure.
+func (r *Runner) BackpressureResult() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.backpressure
+}
+
+// ClientPoolWarning returns the warning about the live clients exceeding the capacity of the client pool,
+// empty until the test is done or if the capacity is not exceeded.
+func (r *Runner) ClientPoolWarning() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.poolWarning
+}
+
+// TeardownWarning returns the error of the first failed teardown step of the scenario, empty until the test is
+// done or if the teardown succeeded.
+func (r *Runner) TeardownWarning() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.teardownWarning
+}
+
+// DryRunFailures returns the failed steps of the Hammer.DryRun iteration with their reasons, empty until the test
+// is done or if the steps passed.
+func (r *Runner) DryRunFailures() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dryRunFailures
+}
+
+// Dropped returns the number of the results not passed to the channel of Run because the consumer
+// couldn't keep up with the load.
+func (r *Runner) Dropped() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return atomic.LoadInt64(&r.dropped) + r.hookDropped
+}
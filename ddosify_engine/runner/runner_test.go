@@ -0,0 +1,127 @@
+/*
+*
+*	Ddosify - Load testing tool for any web system.
+*   Copyright (C) 2021  Ddosify (https://ddosify.com)
+*
+*   This program is free software: you can redistribute it and/or modify
+*   it under the terms of the GNU Affero General Public License as published
+*   by the Free Software Foundation, either version 3 of the License, or
+*   (at your option) any later version.
+*
+*   This program is distributed in the hope that it will be useful,
+*   but WITHOUT ANY WARRANTY; without even the implied warranty of
+*   MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+*   GNU Affero General Public License for more details.
+*
+*   You should have received a copy of the GNU Affero General Public License
+*   along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*
+ */
+
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.ddosify.com/ddosify/core/types"
+)
+
+func newHammer(url string) types.Hammer {
+	return types.Hammer{
+		TestDuration:   1,
+		IterationCount: 5,
+		Scenario: types.Scenario{
+			Steps: []types.ScenarioStep{{
+				ID:      1,
+				Name:    "home",
+				Method:  http.MethodGet,
+				URL:     url,
+				Timeout: types.DefaultTimeout,
+			}},
+		},
+		SingleMode: true,
+	}
+}
+
+func TestRunner(t *testing.T) {
+	t.Parallel()
+
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+	}))
+	defer server.Close()
+
+	r := New(Config{Hammer: newHammer(server.URL)})
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r.Summary() != nil {
+		t.Errorf("Expected %v, Found: %v", nil, r.Summary())
+	}
+
+	count := 0
+	for res := range results {
+		if res.StepName != "home" || res.StatusCode != http.StatusOK {
+			t.Errorf("Expected %v, Found: %v", "home 200", res)
+		}
+		count++
+	}
+	if count != 5 || atomic.LoadInt64(&requests) != 5 {
+		t.Errorf("Expected %v, Found: %v", 5, []int64{int64(count), atomic.LoadInt64(&requests)})
+	}
+
+	summary := r.Summary()
+	if summary == nil || summary.SuccessCount != 5 || summary.StepResults[1].SuccessCount != 5 {
+		t.Fatalf("Expected %v, Found: %v", 5, summary)
+	}
+	if r.Failed() || r.Dropped() != 0 {
+		t.Errorf("Expected %v, Found: %v", false, []interface{}{r.Failed(), r.Dropped()})
+	}
+
+	if _, err = r.Run(context.Background()); err == nil {
+		t.Errorf("Expected %v, Found: %v", "already started error", err)
+	}
+}
+
+func TestRunnerNoResults(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	r := New(Config{Hammer: newHammer(server.URL), NoResults: true})
+	results, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for range results {
+		t.Errorf("Expected no results")
+	}
+	if s := r.Summary(); s == nil || s.SuccessCount != 5 {
+		t.Errorf("Expected %v, Found: %v", 5, s)
+	}
+}
+
+func TestRunnerInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	h := newHammer("http://127.0.0.1")
+	h.LoadType = "sawtooth"
+	if _, err := New(Config{Hammer: h}).Run(context.Background()); err == nil ||
+		!strings.Contains(err.Error(), "LoadType") {
+		t.Errorf("Expected %v, Found: %v", "unsupported LoadType error", err)
+	}
+
+	h = newHammer("http://127.0.0.1")
+	h.ReportDestination = "invalidReport"
+	if _, err := New(Config{Hammer: h}).Run(context.Background()); err == nil {
+		t.Errorf("Expected %v, Found: %v", "unsupported output type error", err)
+	}
+}